@@ -1,7 +1,14 @@
 package main
 
 import (
-	// "fmt"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
 	"github.com/wraient/pair/pkg/appcore"
 	"github.com/wraient/pair/pkg/config"
 	"github.com/wraient/pair/pkg/logger"
@@ -9,13 +16,74 @@ import (
 )
 
 func main() {
-	logger.Initialize(true)
-	logger.Info("Pair CLI started")
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run starts pair with the given command line arguments and returns its
+// exit code. Setup failures are reported on stderr instead of surfacing later
+// as a panic on a missing config or database.
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("pair", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	offline := flags.Bool("offline", false, "skip all tracker and scraper network calls")
+	dbPath := flags.String("db-path", "", "database file to use (overrides $PAIR_DB_PATH)")
+	configDir := flags.String("config-dir", "", "directory for config and data (overrides $PAIR_CONFIG_DIR)")
+	dataDir := flags.String("data-dir", "", "directory for the library and tracker logins (overrides $PAIR_DATA_DIR)")
+	profile := flags.String("profile", "", "use a separate library and tracker logins")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	if err := config.SetProfile(*profile); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if *configDir != "" {
+		config.SetConfigDir(*configDir)
+	}
+	if *dataDir != "" {
+		config.SetDataDir(*dataDir)
+	}
+	if *dbPath != "" {
+		config.SetDBPath(*dbPath)
+	}
+
+	if err := config.Initialize(); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	logger.SetLogDir(filepath.Join(config.GetConfigDir(), "logs"))
+	if err := logger.Initialize(config.Get().Development); err != nil {
+		fmt.Fprintf(stderr, "failed to start logging: %v\n", err)
+		return 1
+	}
+	if flags.NArg() == 0 {
+		logger.Info("Pair CLI started")
+	}
 
-	config.Initialize()
+	if *offline {
+		config.Update(func(c *config.Config) {
+			c.Network.Offline = true
+		})
+	}
 
 	// logger.Info("UI mode", zap.String("mode", string(config.Get().UI.Mode)))
 
-	appcore.Start()
+	// Subcommands like "list --json" run without the menus
+	if flags.NArg() > 0 {
+		if err := appcore.RunCommand(context.Background(), flags.Arg(0), flags.Args()[1:], stdout); err != nil {
+			fmt.Fprintln(stderr, err)
+			return appcore.ExitCode(err)
+		}
+		return 0
+	}
 
+	if err := appcore.Start(); err != nil {
+		fmt.Fprintln(stderr, err)
+		return appcore.ExitCode(err)
+	}
+	return 0
 }
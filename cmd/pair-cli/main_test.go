@@ -0,0 +1,33 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunReportsInitError(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	// A file where the config directory should be makes setup fail
+	blocked := filepath.Join(home, "blocked")
+	if err := os.WriteFile(blocked, nil, 0600); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	// Test that the failure is reported with a non-zero exit code
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"--config-dir", filepath.Join(blocked, "pair"), "list"}, &stdout, &stderr)
+	if code == 0 {
+		t.Errorf("Expected a non-zero exit code")
+	}
+	if !strings.Contains(stderr.String(), "failed to create config directory") {
+		t.Errorf("Expected the init error on stderr, got %q", stderr.String())
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("Expected no output, got %q", stdout.String())
+	}
+}
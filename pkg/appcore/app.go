@@ -2,12 +2,22 @@ package appcore
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/wraient/pair/pkg/config"
+	"github.com/wraient/pair/pkg/database"
+	"github.com/wraient/pair/pkg/httpclient"
+	"github.com/wraient/pair/pkg/logger"
+	"github.com/wraient/pair/pkg/notify"
+	"github.com/wraient/pair/pkg/scraper"
 	"github.com/wraient/pair/pkg/tracker"
 	"github.com/wraient/pair/pkg/ui"
 )
 
+// errOffline is returned by actions that need the network while in offline mode
+var errOffline = errors.New("not available in offline mode")
+
 // App represents the main application
 type App struct {
 	ctx         context.Context
@@ -15,6 +25,11 @@ type App struct {
 	trackerMgr  *tracker.TrackerManager
 	currentMenu *ui.Menu
 	config      *config.Config
+	db          *database.DB
+	prober      *httpclient.Prober
+	syncMgr     *tracker.SyncManager // nil in offline mode
+	// capabilities caches what each source supports for the session
+	capabilities scraper.Capabilities
 }
 
 // NewApp creates a new App instance
@@ -23,17 +38,88 @@ func NewApp(ctx context.Context) *App {
 		ctx:         ctx,
 		menuManager: ui.NewMenuManager(ctx),
 		config:      config.Get(),
+		db:          config.GetDB(),
 		trackerMgr:  tracker.NewTrackerManager(config.GetDB()),
+		prober:      httpclient.NewProber(2*time.Second, 30*time.Second),
 	}
 }
 
-// Start starts the application
-func Start() error {
-	app := NewApp(context.Background())
+// offline reports whether network calls are disabled
+func (a *App) offline() bool {
+	return a.config.Network.Offline
+}
+
+// trackerEnabled reports whether syncing with the named tracker is switched on
+func (a *App) trackerEnabled(name string) bool {
+	switch name {
+	case "anilist":
+		return a.config.Tracking.AnilistEnabled
+	case "mal":
+		return a.config.Tracking.MALEnabled
+	default:
+		return true
+	}
+}
+
+// setup creates the App with networking configured and trackers registered
+func setup(ctx context.Context) (*App, error) {
+	app := NewApp(ctx)
+
+	if err := httpclient.Configure(app.config.Network.Proxy); err != nil {
+		return nil, err
+	}
+	httpclient.SetUserAgent(app.config.Network.UserAgent)
+	ui.SetTitleLanguage(app.config.UI.TitleLanguage)
+
+	// Logins used to be kept with the config, move them to the data directory
+	if err := tracker.MigrateTokenFiles(config.GetConfigDir(), config.GetDataDir()); err != nil {
+		logger.Warn("Failed to move tracker logins: " + err.Error())
+	}
 
 	// Register trackers
-	anilistTracker := tracker.NewAnilistTracker(config.GetConfigDir())
+	anilistTracker := tracker.NewAnilistTracker(config.GetDataDir())
+	if app.config.API.EncryptTokens {
+		anilistTracker.SetTokenEncryptionKey(tracker.DeriveTokenKey(app.config.API.TokenEncryptionKey))
+	}
+	anilistTracker.SetRequestTimeout(time.Duration(app.config.API.RequestTimeout) * time.Second)
 	app.trackerMgr.RegisterTracker(anilistTracker)
+	app.trackerMgr.RegisterTracker(tracker.NewLocalTracker(config.GetDB()))
+
+	return app, nil
+}
+
+// Start starts the application
+func Start() error {
+	app, err := setup(context.Background())
+	if err != nil {
+		return err
+	}
+
+	for _, warning := range CheckDependencies(app.config) {
+		logger.Warn(warning.String())
+	}
+	// Checking repositories can be slow, don't hold up the menu
+	go app.autoUpdateExtensions(app.ctx)
+
+	// The sync manager only talks to trackers, so offline there is none
+	if !app.offline() {
+		syncMgr := tracker.NewSyncManager(app.db, app.trackerMgr)
+		syncMgr.SetTrackerEnabled("anilist", app.config.Tracking.AnilistEnabled)
+		syncMgr.SetTrackerEnabled("mal", app.config.Tracking.MALEnabled)
+		syncMgr.SetConnectivityCheck(app.prober, app.config.Network.Proxy)
+		syncMgr.SetReconcileProgress(app.config.Tracking.ReconcileProgress)
+		syncMgr.SetAutoComplete(app.config.Tracking.AutoComplete)
+		syncMgr.SetConflictRetention(app.conflictRetention())
+		app.syncMgr = syncMgr
+		defer app.stopWatching()
+
+		// Check for newly aired episodes in the background
+		if app.config.Notifications.Enabled {
+			syncMgr.SetNotifier(notify.Send)
+			syncMgr.Start()
+			defer syncMgr.Stop()
+		}
+	}
 
 	// Setup main menu
 	mainMenu := app.setupMainMenu()
@@ -0,0 +1,269 @@
+package appcore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/wraient/pair/pkg/database"
+	"github.com/wraient/pair/pkg/importers"
+	"github.com/wraient/pair/pkg/logger"
+	"github.com/wraient/pair/pkg/tracker"
+)
+
+// RunCommand runs a non-interactive subcommand (list, search, sync, export,
+// import, import-jellyfin, doctor) and writes its output to w. Every subcommand accepts --json to
+// print machine-readable output.
+func RunCommand(ctx context.Context, name string, args []string, w io.Writer) error {
+	app, err := setup(ctx)
+	if err != nil {
+		return err
+	}
+	return app.runCommand(ctx, name, args, w)
+}
+
+// ExitAuthExpired is the exit status of a subcommand that failed because a
+// tracker login expired, so scripts can tell it apart from other failures
+const ExitAuthExpired = 3
+
+// ExitCode returns the exit status for an error returned by RunCommand
+func ExitCode(err error) int {
+	if errors.Is(err, tracker.ErrAuthExpired) {
+		return ExitAuthExpired
+	}
+	return 1
+}
+
+// runCommand parses the flags of a subcommand and runs it
+func (a *App) runCommand(ctx context.Context, name string, args []string, w io.Writer) error {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print machine-readable JSON")
+	merge := flags.Bool("merge", false, "import: keep existing entries (default)")
+	replace := flags.Bool("replace", false, "import: clear the library first")
+	lenient := flags.Bool("lenient", false, "import: skip rows that fail instead of aborting")
+	since := flags.String("since", "", "export: only changes after this date or RFC 3339 time")
+	delta := flags.Bool("delta", false, "import: merge a delta export, keeping newer entries")
+	limit := flags.Int("limit", 0, "search: number of results (default search.result_limit)")
+	apiKey := flags.String("api-key", "", "import-jellyfin: API key of the Jellyfin server")
+	user := flags.String("user", "", "import-jellyfin: name or ID of the user whose played state is imported (default the first user)")
+	if err := flags.Parse(flagsFirst(flags, args)); err != nil {
+		return err
+	}
+
+	// Keep stdout clean for the JSON document
+	if *asJSON {
+		logger.SetConsoleOutput(false)
+		defer logger.SetConsoleOutput(true)
+	}
+
+	var result interface{}
+	var text []string
+	// failure is returned once the output is written, for commands that
+	// report what they did before failing
+	var failure error
+	switch name {
+	case "list":
+		animes, err := a.db.GetAllAnime()
+		if err != nil {
+			return fmt.Errorf("failed to get anime: %w", err)
+		}
+		result = animes
+		for _, anime := range animes {
+			text = append(text, fmt.Sprintf("%d\t%s", anime.ID, anime.Title))
+		}
+	case "search":
+		query := strings.TrimSpace(strings.Join(flags.Args(), " "))
+		if query == "" {
+			return fmt.Errorf("usage: search [--json] [--limit n] <query>")
+		}
+		t, err := a.activeTracker()
+		if err != nil {
+			return err
+		}
+		if *limit <= 0 {
+			*limit = a.searchLimit()
+		}
+		results, err := t.SearchAnime(ctx, query, *limit)
+		if err != nil {
+			return fmt.Errorf("failed to search anime: %w", err)
+		}
+		result = results
+		for _, anime := range results {
+			text = append(text, fmt.Sprintf("%s\t%s", anime.ID, anime.Title))
+		}
+	case "sync":
+		syncErrors := []tracker.SyncError{}
+		stats, err := a.syncWithTrackers(ctx, a.db, &syncErrors)
+		result = syncResult{Trackers: stats, Errors: syncErrors}
+		for name, s := range stats {
+			text = append(text, fmt.Sprintf("%s: %d added, %d updated, %d deleted, %d skipped, %d errors",
+				name, s.Added, s.Updated, s.Deleted, s.Skipped, s.Errors))
+		}
+		for _, err := range syncErrors {
+			text = append(text, "Error: "+err.Error())
+		}
+		switch {
+		case err != nil:
+			failure = fmt.Errorf("failed to sync with trackers: %w", err)
+		case len(syncErrors) > 0:
+			failure = fmt.Errorf("sync finished with %d errors", len(syncErrors))
+		}
+	case "export":
+		if flags.NArg() != 1 {
+			return fmt.Errorf("usage: export [--json] [--since time] <file>")
+		}
+		var summary database.BackupSummary
+		var err error
+		if *since != "" {
+			cutoff, parseErr := parseSince(*since)
+			if parseErr != nil {
+				return parseErr
+			}
+			summary, err = a.db.ExportChangedSince(cutoff, flags.Arg(0))
+		} else {
+			summary, err = a.db.ExportToJSON(flags.Arg(0))
+		}
+		if err != nil {
+			return err
+		}
+		result = summary
+		text = append(text, "Exported "+backupSummaryText(summary)+" to "+flags.Arg(0))
+	case "import":
+		if flags.NArg() != 1 {
+			return fmt.Errorf("usage: import [--json] [--merge|--replace|--delta] [--lenient] <file>")
+		}
+		if *merge && *replace {
+			return fmt.Errorf("--merge and --replace cannot be combined")
+		}
+		if *delta && (*replace || *lenient) {
+			return fmt.Errorf("--delta cannot be combined with --replace or --lenient")
+		}
+		opts := database.ImportOptions{Mode: database.ImportMerge, Lenient: *lenient}
+		if *replace {
+			opts.Mode = database.ImportReplace
+		}
+		var stats database.ImportStats
+		var err error
+		if *delta {
+			stats, err = a.db.ImportDelta(flags.Arg(0))
+		} else {
+			stats, err = a.db.ImportFromJSON(flags.Arg(0), opts)
+		}
+		if err != nil {
+			return err
+		}
+		result = stats
+		text = append(text, "Imported "+backupSummaryText(stats.Imported)+" from "+flags.Arg(0))
+		if stats.Skipped > 0 {
+			text = append(text, fmt.Sprintf("%d entries skipped because the library is newer", stats.Skipped))
+		}
+		if stats.Failed > 0 {
+			text = append(text, fmt.Sprintf("%d rows failed to import", stats.Failed))
+			for _, msg := range stats.Errors {
+				text = append(text, "Error: "+msg)
+			}
+		}
+
+		// Imported anime may only carry the fields the backup had
+		filled, err := a.BackfillMetadata(ctx)
+		if err != nil && !errors.Is(err, errOffline) {
+			logger.Warn("Failed to backfill metadata: " + err.Error())
+		}
+		if filled > 0 {
+			text = append(text, fmt.Sprintf("Filled in details of %d anime", filled))
+		}
+	case "import-jellyfin":
+		if flags.NArg() != 1 || *apiKey == "" {
+			return fmt.Errorf("usage: import-jellyfin [--json] --api-key key [--user name] <server-url>")
+		}
+		if a.offline() {
+			return errOffline
+		}
+		stats, err := importers.ImportWatchState(ctx, a.db, flags.Arg(0), *apiKey, *user)
+		if err != nil {
+			return err
+		}
+		result = stats
+		text = append(text, fmt.Sprintf("jellyfin: %d updated, %d skipped, %d errors", stats.Updated, stats.Skipped, stats.Errors))
+		text = append(text, stats.Details...)
+	case "doctor":
+		report := a.doctorReport(ctx)
+		result = report
+		text = report.Lines()
+	default:
+		return fmt.Errorf("unknown command %q", name)
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(result); err != nil {
+			return err
+		}
+		return failure
+	}
+	for _, line := range text {
+		fmt.Fprintln(w, line)
+	}
+	return failure
+}
+
+// syncResult is the JSON output of the sync command
+type syncResult struct {
+	Trackers map[string]tracker.SyncStats `json:"trackers"`
+	Errors   []tracker.SyncError          `json:"errors"`
+}
+
+// flagsFirst moves flags ahead of positional arguments so that
+// "import backup.json --replace" parses like "import --replace backup.json".
+// The value following a non-boolean flag such as "--limit 5" moves with it.
+func flagsFirst(fs *flag.FlagSet, args []string) []string {
+	var flags, positional []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			positional = append(positional, arg)
+			continue
+		}
+		flags = append(flags, arg)
+
+		name := strings.TrimLeft(arg, "-")
+		if strings.Contains(name, "=") {
+			continue
+		}
+		if f := fs.Lookup(name); f != nil && !isBoolFlag(f) && i+1 < len(args) {
+			i++
+			flags = append(flags, args[i])
+		}
+	}
+	return append(flags, positional...)
+}
+
+// isBoolFlag reports whether a flag is a switch that takes no value
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// backupSummaryText describes the counts of a backup summary
+func backupSummaryText(s database.BackupSummary) string {
+	return fmt.Sprintf("%d anime, %d tracking entries, %d progress entries, %d episodes, %d sources",
+		s.Anime, s.Tracking, s.Progress, s.Episodes, s.Sources)
+}
+
+// parseSince parses the cutoff of a delta export, a date or an RFC 3339 time
+func parseSince(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --since %q: use a date like 2024-01-31 or an RFC 3339 time", value)
+	}
+	return t, nil
+}
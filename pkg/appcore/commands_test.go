@@ -0,0 +1,260 @@
+package appcore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wraient/pair/pkg/config"
+	"github.com/wraient/pair/pkg/database"
+	"github.com/wraient/pair/pkg/tracker"
+)
+
+func TestListCommandJSON(t *testing.T) {
+	app, db := setupTestApp(t)
+
+	for _, title := range []string{"First Show", "Second Show"} {
+		if err := db.AddAnime(&database.Anime{Title: title, TotalEpisodes: 12, Genres: []string{"Action"}}); err != nil {
+			t.Fatalf("Failed to add anime: %v", err)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := app.runCommand(context.Background(), "list", []string{"--json"}, &out); err != nil {
+		t.Fatalf("Failed to run list: %v", err)
+	}
+
+	// Test that the output parses back into anime
+	var animes []database.Anime
+	if err := json.Unmarshal(out.Bytes(), &animes); err != nil {
+		t.Fatalf("Failed to parse list output: %v\n%s", err, out.String())
+	}
+	if len(animes) != 2 {
+		t.Fatalf("Expected 2 anime, got %d", len(animes))
+	}
+	titles := []string{animes[0].Title, animes[1].Title}
+	if !strings.Contains(strings.Join(titles, ","), "First Show") || animes[0].Genres[0] != "Action" {
+		t.Errorf("Unexpected anime in output: %+v", animes)
+	}
+
+	// Test that unknown commands are rejected
+	if err := app.runCommand(context.Background(), "frobnicate", nil, &out); err == nil {
+		t.Errorf("Expected an error for an unknown command")
+	}
+}
+
+func TestExportImportCommands(t *testing.T) {
+	source, sourceDB := setupTestApp(t)
+
+	anime := &database.Anime{Title: "Exported Show", TotalEpisodes: 24, Genres: []string{"Drama"}}
+	if err := sourceDB.AddAnime(anime); err != nil {
+		t.Fatalf("Failed to add anime: %v", err)
+	}
+	tracking := &database.AnimeTracking{
+		AnimeID: anime.ID, Tracker: "anilist", TrackerID: "42", Status: "watching",
+		Score: 8, CurrentEpisode: 5, TotalEpisodes: 24, LastUpdated: time.Now(),
+		Tags: []string{"favourite"},
+	}
+	if err := sourceDB.AddAnimeTracking(tracking); err != nil {
+		t.Fatalf("Failed to add tracking: %v", err)
+	}
+
+	backup := filepath.Join(t.TempDir(), "backup.json")
+	var out bytes.Buffer
+	if err := source.runCommand(context.Background(), "export", []string{backup}, &out); err != nil {
+		t.Fatalf("Failed to run export: %v", err)
+	}
+	if !strings.Contains(out.String(), "Exported 1 anime, 1 tracking entries") {
+		t.Errorf("Expected an export summary, got '%s'", out.String())
+	}
+
+	// Test that importing into a fresh database reproduces the library
+	target, targetDB := setupTestApp(t)
+	if err := targetDB.AddAnime(&database.Anime{Title: "Stale Show"}); err != nil {
+		t.Fatalf("Failed to add anime: %v", err)
+	}
+	out.Reset()
+	if err := target.runCommand(context.Background(), "import", []string{backup, "--replace", "--json"}, &out); err != nil {
+		t.Fatalf("Failed to run import: %v", err)
+	}
+	var stats database.ImportStats
+	if err := json.Unmarshal(out.Bytes(), &stats); err != nil {
+		t.Fatalf("Failed to parse import output: %v\n%s", err, out.String())
+	}
+	if stats.Imported.Anime != 1 || stats.Imported.Tracking != 1 || stats.Failed != 0 {
+		t.Errorf("Expected 1 anime and 1 tracking entry imported, got %+v", stats)
+	}
+
+	animes, err := targetDB.GetAllAnime()
+	if err != nil {
+		t.Fatalf("Failed to get anime: %v", err)
+	}
+	if len(animes) != 1 || animes[0].Title != "Exported Show" || animes[0].Genres[0] != "Drama" {
+		t.Fatalf("Expected only the exported anime after --replace, got %+v", animes)
+	}
+	restored, err := targetDB.GetAnimeTracking(animes[0].ID, "anilist")
+	if err != nil {
+		t.Fatalf("Failed to get restored tracking: %v", err)
+	}
+	if restored.TrackerID != "42" || restored.CurrentEpisode != 5 || len(restored.Tags) != 1 || restored.Tags[0] != "favourite" {
+		t.Errorf("Unexpected restored tracking: %+v", restored)
+	}
+
+	// Test that the two import modes cannot be combined
+	if err := target.runCommand(context.Background(), "import", []string{"--merge", "--replace", backup}, &out); err == nil {
+		t.Errorf("Expected an error when combining --merge and --replace")
+	}
+
+	// Test that --since exports a delta that --delta merges
+	delta := filepath.Join(t.TempDir(), "delta.json")
+	if err := source.runCommand(context.Background(), "export", []string{"--since", "yesterday", delta}, &out); err == nil {
+		t.Errorf("Expected an error for an invalid --since")
+	}
+	out.Reset()
+	if err := source.runCommand(context.Background(), "export", []string{"--since", "2000-01-01", delta}, &out); err != nil {
+		t.Fatalf("Failed to run delta export: %v", err)
+	}
+	if !strings.Contains(out.String(), "Exported 1 anime, 1 tracking entries, 0 progress entries, 0 episodes") {
+		t.Errorf("Expected a delta export summary, got '%s'", out.String())
+	}
+	out.Reset()
+	if err := target.runCommand(context.Background(), "import", []string{"--delta", delta}, &out); err != nil {
+		t.Fatalf("Failed to run delta import: %v", err)
+	}
+	if !strings.Contains(out.String(), "entries skipped because the library is newer") {
+		t.Errorf("Expected the unchanged entries to be skipped, got '%s'", out.String())
+	}
+}
+
+func TestSearchCommandLimit(t *testing.T) {
+	mock := newMockTracker("anilist")
+	app, _ := setupTestApp(t, mock)
+	app.config.Tracking.Service = config.TrackerAnilist
+
+	ctx := context.Background()
+	var out bytes.Buffer
+
+	// Test that the default limit is used when none is configured
+	if err := app.runCommand(ctx, "search", []string{"frieren"}, &out); err != nil {
+		t.Fatalf("Failed to run search: %v", err)
+	}
+
+	// Test that the configured limit is passed through to the tracker
+	app.config.Search.ResultLimit = 7
+	if err := app.runCommand(ctx, "search", []string{"frieren"}, &out); err != nil {
+		t.Fatalf("Failed to run search: %v", err)
+	}
+
+	// Test that --limit overrides it, even after the query
+	if err := app.runCommand(ctx, "search", []string{"sousou", "no", "frieren", "--limit", "120"}, &out); err != nil {
+		t.Fatalf("Failed to run search: %v", err)
+	}
+
+	want := []int{defaultSearchLimit, 7, 120}
+	if len(mock.searchLimits) != len(want) {
+		t.Fatalf("Expected %d searches, got %v", len(want), mock.searchLimits)
+	}
+	for i := range want {
+		if mock.searchLimits[i] != want[i] {
+			t.Errorf("Expected limit %d for search %d, got %d", want[i], i, mock.searchLimits[i])
+		}
+	}
+}
+
+func TestSyncCommandJSONErrors(t *testing.T) {
+	mock := newMockTracker("anilist")
+	mock.listErr = errors.New("server exploded")
+	app, _ := setupTestApp(t, mock)
+
+	// Test that the command fails, but only after writing its output
+	var out bytes.Buffer
+	if err := app.runCommand(context.Background(), "sync", []string{"--json"}, &out); err == nil {
+		t.Errorf("Expected sync errors to fail the command")
+	}
+
+	// Test that the errors are part of the output, with their messages
+	var result struct {
+		Trackers map[string]tracker.SyncStats `json:"trackers"`
+		Errors   []struct {
+			Tracker string `json:"tracker"`
+			Op      string `json:"op"`
+			Error   string `json:"error"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+		t.Fatalf("Failed to parse sync output: %v\n%s", err, out.String())
+	}
+	if len(result.Errors) != 1 || result.Errors[0].Tracker != "anilist" || !strings.Contains(result.Errors[0].Error, "server exploded") {
+		t.Errorf("Expected the anilist error in the output, got %s", out.String())
+	}
+}
+
+func TestSyncCommandAuthExpired(t *testing.T) {
+	mock := newMockTracker("anilist")
+	mock.listErr = fmt.Errorf("token refresh failed: %w", tracker.ErrAuthExpired)
+	app, _ := setupTestApp(t, mock)
+
+	// Test that an expired login fails the command with its own exit status
+	var out bytes.Buffer
+	err := app.runCommand(context.Background(), "sync", nil, &out)
+	if code := ExitCode(err); code != ExitAuthExpired {
+		t.Errorf("Expected exit status %d, got %d (%v)", ExitAuthExpired, code, err)
+	}
+}
+
+func TestDoctorCommand(t *testing.T) {
+	stubLookPath(t, "mpv")
+	loggedOut := newMockTracker("mal")
+	loggedOut.authenticated = false
+	app, db := setupTestApp(t, newMockTracker("anilist"), loggedOut)
+
+	var out bytes.Buffer
+	if err := app.runCommand(context.Background(), "doctor", []string{"--json"}, &out); err != nil {
+		t.Fatalf("Failed to run doctor: %v", err)
+	}
+
+	var report DoctorReport
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("Failed to parse doctor output: %v\n%s", err, out.String())
+	}
+
+	// Test that the report includes the schema version
+	version, err := db.GetDatabaseVersion()
+	if err != nil {
+		t.Fatalf("Failed to get database version: %v", err)
+	}
+	if report.Database.SchemaVersion != version || len(report.Database.Problems) != 0 {
+		t.Errorf("Expected schema version %d and no problems, got %+v", version, report.Database)
+	}
+
+	// Test that each tracker's login is reported
+	if len(report.Trackers) != 2 {
+		t.Fatalf("Expected 2 trackers, got %+v", report.Trackers)
+	}
+	if report.Trackers[0].Name != "anilist" || !report.Trackers[0].Valid {
+		t.Errorf("Expected a valid anilist login, got %+v", report.Trackers[0])
+	}
+	if report.Trackers[1].Name != "mal" || report.Trackers[1].Authenticated {
+		t.Errorf("Expected mal to be logged out, got %+v", report.Trackers[1])
+	}
+	if len(report.Dependencies) != 2 || report.Dependencies[0].Found || !report.Dependencies[1].Found {
+		t.Errorf("Expected only mpv to be found, got %+v", report.Dependencies)
+	}
+
+	// Test that the text report has the same sections
+	out.Reset()
+	if err := app.runCommand(context.Background(), "doctor", nil, &out); err != nil {
+		t.Fatalf("Failed to run doctor: %v", err)
+	}
+	for _, want := range []string{fmt.Sprintf("Schema version: %d", version), "Trackers:", "anilist: no token file, logged in", "mal: no token file, not logged in"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected '%s' in the report, got:\n%s", want, out.String())
+		}
+	}
+}
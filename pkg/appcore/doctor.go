@@ -0,0 +1,193 @@
+package appcore
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/wraient/pair/pkg/config"
+	"github.com/wraient/pair/pkg/scraper"
+	"github.com/wraient/pair/pkg/tracker"
+	"github.com/wraient/pair/pkg/version"
+)
+
+// DoctorReport summarises the state of an installation for bug reports
+type DoctorReport struct {
+	Version      string             `json:"version"`
+	ConfigDir    string             `json:"config_dir"`
+	DataDir      string             `json:"data_dir"`
+	Database     DatabaseHealth     `json:"database"`
+	Trackers     []TrackerHealth    `json:"trackers"`
+	Extensions   []ExtensionHealth  `json:"extensions"`
+	Dependencies []DependencyHealth `json:"dependencies"`
+}
+
+// DatabaseHealth describes the database file and its schema
+type DatabaseHealth struct {
+	Path          string   `json:"path"`
+	SchemaVersion int      `json:"schema_version"`
+	Problems      []string `json:"problems,omitempty"`
+	Error         string   `json:"error,omitempty"`
+}
+
+// TrackerHealth describes the login of a tracker. Valid is only checked
+// against the service when the tracker is logged in and not offline.
+type TrackerHealth struct {
+	Name          string `json:"name"`
+	TokenFile     bool   `json:"token_file"`
+	Authenticated bool   `json:"authenticated"`
+	Valid         bool   `json:"valid"`
+	Error         string `json:"error,omitempty"`
+}
+
+// ExtensionHealth describes an installed extension
+type ExtensionHealth struct {
+	Package  string `json:"package"`
+	Version  string `json:"version"`
+	Path     string `json:"path"`
+	Server   string `json:"server,omitempty"`
+	BinaryOK bool   `json:"binary_ok"`
+}
+
+// DependencyHealth describes an external binary
+type DependencyHealth struct {
+	Binary string `json:"binary"`
+	Found  bool   `json:"found"`
+}
+
+// doctorReport gathers the health summary. Failures are recorded in the
+// report rather than returned so one broken part doesn't hide the rest.
+func (a *App) doctorReport(ctx context.Context) DoctorReport {
+	report := DoctorReport{
+		Version:   version.String(),
+		ConfigDir: config.GetConfigDir(),
+		DataDir:   config.GetDataDir(),
+	}
+
+	report.Database = a.databaseHealth()
+
+	for _, t := range a.trackerMgr.Trackers() {
+		health := TrackerHealth{
+			Name:          t.Name(),
+			TokenFile:     tracker.HasTokenFile(t),
+			Authenticated: t.IsAuthenticated(),
+		}
+		health.Valid = health.Authenticated
+		if pinger, ok := t.(tracker.Pinger); ok && health.Authenticated {
+			if a.offline() {
+				health.Valid = false
+				health.Error = errOffline.Error()
+			} else if err := pinger.Ping(ctx); err != nil {
+				health.Valid = false
+				health.Error = err.Error()
+			}
+		}
+		report.Trackers = append(report.Trackers, health)
+	}
+
+	extensions, err := a.db.GetAllExtensions()
+	if err != nil {
+		report.Database.Error = strings.TrimPrefix(report.Database.Error+"; failed to get extensions: "+err.Error(), "; ")
+	}
+	for _, ext := range extensions {
+		report.Extensions = append(report.Extensions, ExtensionHealth{
+			Package:  ext.Package,
+			Version:  ext.Version,
+			Path:     ext.Path,
+			Server:   a.config.Extensions.Servers[ext.Package],
+			BinaryOK: scraper.CheckExtensionBinary(ext.Path, ext.Package) == nil,
+		})
+	}
+
+	for _, binary := range []string{"rofi", playerBinary} {
+		_, err := lookPath(binary)
+		report.Dependencies = append(report.Dependencies, DependencyHealth{Binary: binary, Found: err == nil})
+	}
+
+	return report
+}
+
+// databaseHealth reports where the database is and whether it is intact
+func (a *App) databaseHealth() DatabaseHealth {
+	var health DatabaseHealth
+	var errs []string
+
+	path, err := a.db.Path()
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+	health.Path = path
+
+	if health.SchemaVersion, err = a.db.GetDatabaseVersion(); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if health.Problems, err = a.db.IntegrityCheck(); err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	health.Error = strings.Join(errs, "; ")
+	return health
+}
+
+// Lines renders the report as readable text
+func (r DoctorReport) Lines() []string {
+	lines := []string{
+		"Version: " + r.Version,
+		"Config dir: " + r.ConfigDir,
+		"Data dir: " + r.DataDir,
+		"",
+		"Database:",
+		"  Path: " + r.Database.Path,
+		fmt.Sprintf("  Schema version: %d", r.Database.SchemaVersion),
+	}
+	switch {
+	case r.Database.Error != "":
+		lines = append(lines, "  Error: "+r.Database.Error)
+	case len(r.Database.Problems) > 0:
+		lines = append(lines, "  Integrity: "+strings.Join(r.Database.Problems, "; "))
+	default:
+		lines = append(lines, "  Integrity: ok")
+	}
+
+	lines = append(lines, "", "Trackers:")
+	for _, t := range r.Trackers {
+		token := "no token file"
+		if t.TokenFile {
+			token = "token file"
+		}
+		state := "not logged in"
+		switch {
+		case t.Authenticated && t.Valid:
+			state = "logged in"
+		case t.Authenticated:
+			state = "login not verified: " + t.Error
+		}
+		lines = append(lines, fmt.Sprintf("  %s: %s, %s", t.Name, token, state))
+	}
+
+	lines = append(lines, "", "Extensions:")
+	if len(r.Extensions) == 0 {
+		lines = append(lines, "  none installed")
+	}
+	for _, ext := range r.Extensions {
+		state := "binary missing"
+		if ext.BinaryOK {
+			state = "ok"
+		}
+		if ext.Server != "" {
+			state += ", served by " + ext.Server
+		}
+		lines = append(lines, fmt.Sprintf("  %s %s (%s): %s", ext.Package, ext.Version, ext.Path, state))
+	}
+
+	lines = append(lines, "", "Dependencies:")
+	for _, dep := range r.Dependencies {
+		state := "not found"
+		if dep.Found {
+			state = "found"
+		}
+		lines = append(lines, fmt.Sprintf("  %s: %s", dep.Binary, state))
+	}
+
+	return lines
+}
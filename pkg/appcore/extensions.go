@@ -0,0 +1,347 @@
+package appcore
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wraient/pair/pkg/database"
+	"github.com/wraient/pair/pkg/httpclient"
+	"github.com/wraient/pair/pkg/logger"
+	"github.com/wraient/pair/pkg/notify"
+	"github.com/wraient/pair/pkg/scraper"
+)
+
+const (
+	// extensionRequestTimeout bounds each download from an extension repository
+	extensionRequestTimeout = 60 * time.Second
+	// extensionUpdateInterval is how often extensions are checked for updates at startup
+	extensionUpdateInterval = 24 * time.Hour
+	// lastExtensionCheckKey stores when extensions were last checked for updates
+	lastExtensionCheckKey = "extensions.last_update_check"
+)
+
+// extensionUpdate describes an extension that was updated
+type extensionUpdate struct {
+	Name string
+	From string
+	To   string
+}
+
+// repoExtension is an extension listed in a repository
+type repoExtension struct {
+	scraper.ExtensionInfo
+	// Binaries maps "<GOOS>-<GOARCH>" to the build for that platform
+	Binaries map[string]repoBinary `json:"binaries"`
+}
+
+// repoBinary is a build of an extension for one platform
+type repoBinary struct {
+	URL    string `json:"url"` // Relative to the repository list
+	SHA256 string `json:"sha256"`
+}
+
+// platform names the build of an extension that runs here
+func platform() string {
+	return runtime.GOOS + "-" + runtime.GOARCH
+}
+
+// autoUpdateExtensions updates the installed extensions when auto-update is
+// on, at most once per extensionUpdateInterval. It is meant to run in the
+// background, so updates are logged and sent as a notification instead of
+// printed; an outdated extension still works.
+func (a *App) autoUpdateExtensions(ctx context.Context) {
+	if !a.config.Extensions.AutoUpdate || a.offline() {
+		return
+	}
+
+	if value, err := a.db.GetConfig(lastExtensionCheckKey); err == nil {
+		if last, err := time.Parse(time.RFC3339, value); err == nil && time.Since(last) < extensionUpdateInterval {
+			return
+		}
+	}
+
+	updates, errs := updateExtensions(ctx, a.db, a.config.Extensions.Directory)
+	for _, err := range errs {
+		logger.Warn("Extension update failed: " + err.Error())
+	}
+	var names []string
+	for _, update := range updates {
+		logger.Info(fmt.Sprintf("Updated %s from %s to %s", update.Name, update.From, update.To))
+		names = append(names, update.Name)
+	}
+	if len(names) > 0 && a.config.Notifications.Enabled {
+		if err := notify.Send("Extensions updated", strings.Join(names, ", ")); err != nil {
+			logger.Warn(err.Error())
+		}
+	}
+
+	if err := a.db.SetConfig(lastExtensionCheckKey, time.Now().Format(time.RFC3339)); err != nil {
+		logger.Warn("Failed to save extension update time: " + err.Error())
+	}
+}
+
+// handleUpdateExtensions checks every installed extension for an update and
+// prints the updates applied
+func (a *App) handleUpdateExtensions(ctx context.Context) error {
+	if a.offline() {
+		return errOffline
+	}
+
+	updates, errs := updateExtensions(ctx, a.db, a.config.Extensions.Directory)
+	for _, update := range updates {
+		fmt.Printf("Updated %s from %s to %s\n", update.Name, update.From, update.To)
+	}
+	for _, err := range errs {
+		fmt.Println(err)
+	}
+	if len(updates) == 0 && len(errs) == 0 {
+		fmt.Println("All extensions are up to date")
+	}
+	return nil
+}
+
+// updateExtensions checks the repository of every installed extension for a
+// newer version, and installs its build for this platform into dir when
+// there is one. Extensions without a repository are skipped. An error for one
+// extension doesn't stop the others.
+func updateExtensions(ctx context.Context, db *database.DB, dir string) ([]extensionUpdate, []error) {
+	extensions, err := db.GetAllExtensions()
+	if err != nil {
+		return nil, []error{fmt.Errorf("failed to get extensions: %w", err)}
+	}
+
+	var updates []extensionUpdate
+	var errs []error
+	indexes := make(map[string][]repoExtension)
+	for _, ext := range extensions {
+		if ext.RepositoryURL == "" {
+			continue
+		}
+
+		// A repository lists all its extensions, fetch it once
+		index, ok := indexes[ext.RepositoryURL]
+		if !ok {
+			if err := fetchJSON(ctx, ext.RepositoryURL, &index); err != nil {
+				errs = append(errs, fmt.Errorf("failed to get repository %s: %w", ext.RepositoryURL, err))
+				continue
+			}
+			indexes[ext.RepositoryURL] = index
+		}
+
+		var latest *repoExtension
+		for i := range index {
+			if index[i].Package == ext.Package {
+				latest = &index[i]
+				break
+			}
+		}
+		if latest == nil || compareVersions(latest.Version, ext.Version) <= 0 {
+			continue
+		}
+
+		if err := installExtension(ctx, db, ext, *latest, dir); err != nil {
+			errs = append(errs, fmt.Errorf("failed to update %s: %w", ext.Name, err))
+			continue
+		}
+		updates = append(updates, extensionUpdate{Name: latest.Name, From: ext.Version, To: latest.Version})
+	}
+
+	return updates, errs
+}
+
+// installExtension downloads the build of latest for this platform, checks
+// its checksum and that it reports the expected package and version, and
+// only then swaps it in and records it. A bad download leaves the installed
+// version in place and in the database, so the update is tried again.
+func installExtension(ctx context.Context, db *database.DB, ext *database.Extension, latest repoExtension, dir string) error {
+	binary, ok := latest.Binaries[platform()]
+	if !ok {
+		return fmt.Errorf("no build for %s", platform())
+	}
+	if binary.SHA256 == "" {
+		return fmt.Errorf("the build for %s has no checksum", platform())
+	}
+	binaryURL, err := resolveURL(ext.RepositoryURL, binary.URL)
+	if err != nil {
+		return err
+	}
+
+	path := ext.Path
+	if path == "" {
+		path = filepath.Join(dir, ext.Package)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create extension directory: %w", err)
+	}
+
+	// Download next to the old binary and swap it in once verified
+	tmp := path + ".download"
+	defer os.Remove(tmp)
+	sum, err := download(ctx, binaryURL, tmp)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(sum, binary.SHA256) {
+		return fmt.Errorf("checksum mismatch: expected %s, got %s", binary.SHA256, sum)
+	}
+
+	info, err := scraper.NewCLIScraper(tmp, "").GetExtensionInfo()
+	if err != nil {
+		return fmt.Errorf("the new build doesn't run: %w", err)
+	}
+	if info.Package != ext.Package || info.Version != latest.Version {
+		return fmt.Errorf("the new build reports %s %s, expected %s %s",
+			info.Package, info.Version, ext.Package, latest.Version)
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to install binary: %w", err)
+	}
+
+	updated := *ext
+	updated.Name = info.Name
+	updated.Language = info.Lang
+	updated.Version = info.Version
+	updated.NSFW = info.NSFW
+	updated.Path = path
+	if err := db.AddExtension(&updated); err != nil {
+		return fmt.Errorf("failed to save extension: %w", err)
+	}
+
+	for _, source := range info.Sources {
+		if err := db.AddSource(&database.Source{
+			SourceID:    source.ID,
+			ExtensionID: ext.ID,
+			Name:        source.Name,
+			Language:    source.Language,
+			BaseURL:     source.BaseURL,
+			NSFW:        source.NSFW,
+		}); err != nil {
+			return fmt.Errorf("failed to save source %s: %w", source.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// resolveURL resolves a URL from a repository list against the list's URL
+func resolveURL(repoURL, ref string) (string, error) {
+	base, err := url.Parse(repoURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid repository URL: %w", err)
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return "", fmt.Errorf("invalid binary URL: %w", err)
+	}
+	return base.ResolveReference(u).String(), nil
+}
+
+// fetchJSON downloads and decodes a JSON document into v
+func fetchJSON(ctx context.Context, rawURL string, v interface{}) error {
+	var buf bytes.Buffer
+	if err := fetch(ctx, rawURL, &buf); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(buf.Bytes(), v); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// download saves the file at rawURL to path as an executable and returns
+// its hex encoded SHA-256
+func download(ctx context.Context, rawURL, path string) (string, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+	if err != nil {
+		return "", fmt.Errorf("failed to create file: %w", err)
+	}
+	hash := sha256.New()
+	if err := fetch(ctx, rawURL, io.MultiWriter(file, hash)); err != nil {
+		file.Close()
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// fetch sends a GET request and copies the response body to w
+func fetch(ctx context.Context, rawURL string, w io.Writer) error {
+	ctx, cancel := context.WithTimeout(ctx, extensionRequestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	httpclient.Identify(req)
+
+	resp, err := httpclient.Default().Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("server returned %d for %s", resp.StatusCode, rawURL)
+	}
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("failed to download %s: %w", rawURL, err)
+	}
+	return nil
+}
+
+// compareVersions compares dotted version strings like "1.2.10", with an
+// optional "v" prefix, part by part. Numeric parts compare as numbers, others
+// as text. It returns -1, 0 or 1 like strings.Compare.
+func compareVersions(a, b string) int {
+	as := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	bs := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y string
+		if i < len(as) {
+			x = as[i]
+		}
+		if i < len(bs) {
+			y = bs[i]
+		}
+
+		xn, xErr := strconv.Atoi(x)
+		yn, yErr := strconv.Atoi(y)
+		if x == "" {
+			xn, xErr = 0, nil
+		}
+		if y == "" {
+			yn, yErr = 0, nil
+		}
+		switch {
+		case xErr == nil && yErr == nil:
+			if xn != yn {
+				if xn < yn {
+					return -1
+				}
+				return 1
+			}
+		default:
+			if c := strings.Compare(x, y); c != 0 {
+				return c
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,157 @@
+package appcore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wraient/pair/pkg/database"
+	"github.com/wraient/pair/pkg/scraper"
+)
+
+// fakeExtensionScript is an extension that reports the given version
+func fakeExtensionScript(version string) string {
+	return "#!/bin/sh\ncat <<'EOF'\n" + `{"status": "success", "data": {"name": "Fake", "pkg": "fake", "lang": "en", "version": "` + version + `",
+		"sources": [{"id": "fake-source", "name": "Fake Source", "language": "en", "baseUrl": "https://example.org"}]}}` + "\nEOF\n"
+}
+
+// fakeRepo serves a repository advertising version 1.10.0 of the fake
+// extension, with binary as its build for this platform and checksum as the
+// advertised SHA-256, or the binary's own when empty
+func fakeRepo(t *testing.T, binary, checksum string) string {
+	if checksum == "" {
+		sum := sha256.Sum256([]byte(binary))
+		checksum = hex.EncodeToString(sum[:])
+	}
+	index := []repoExtension{{
+		ExtensionInfo: scraper.ExtensionInfo{Name: "Fake", Package: "fake", Lang: "en", Version: "1.10.0"},
+		Binaries:      map[string]repoBinary{platform(): {URL: "bin/fake-" + platform(), SHA256: checksum}},
+	}}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repo/index.json":
+			json.NewEncoder(w).Encode(index)
+		case "/repo/bin/fake-" + platform():
+			w.Write([]byte(binary))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server.URL + "/repo/index.json"
+}
+
+// addInstalledExtension installs version 1.9.2 of the fake extension from repoURL
+func addInstalledExtension(t *testing.T, db *database.DB, repoURL string) (*database.Extension, string) {
+	path := filepath.Join(t.TempDir(), "fake")
+	if err := os.WriteFile(path, []byte(fakeExtensionScript("1.9.2")), 0755); err != nil {
+		t.Fatalf("Failed to write binary: %v", err)
+	}
+	ext := &database.Extension{Name: "Fake", Package: "fake", Language: "en", Version: "1.9.2", Path: path, RepositoryURL: repoURL}
+	if err := db.AddExtension(ext); err != nil {
+		t.Fatalf("Failed to add extension: %v", err)
+	}
+	return ext, path
+}
+
+func TestUpdateExtensions(t *testing.T) {
+	_, db := setupTestApp(t)
+	binary := fakeExtensionScript("1.10.0")
+	ext, path := addInstalledExtension(t, db, fakeRepo(t, binary, ""))
+
+	// Test that the newer version is downloaded, verified and recorded
+	updates, errs := updateExtensions(context.Background(), db, t.TempDir())
+	if len(errs) > 0 {
+		t.Fatalf("Failed to update extensions: %v", errs)
+	}
+	if len(updates) != 1 || updates[0].From != "1.9.2" || updates[0].To != "1.10.0" {
+		t.Fatalf("Expected an update from 1.9.2 to 1.10.0, got %+v", updates)
+	}
+
+	updated, err := db.GetExtensionByPackage("fake")
+	if err != nil {
+		t.Fatalf("Failed to get extension: %v", err)
+	}
+	if updated.Version != "1.10.0" {
+		t.Errorf("Expected version 1.10.0, got %s", updated.Version)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read binary: %v", err)
+	}
+	if string(data) != binary {
+		t.Errorf("Expected the binary to be replaced, got %q", data)
+	}
+	source, err := db.GetSourceByID("fake-source")
+	if err != nil {
+		t.Fatalf("Failed to get source: %v", err)
+	}
+	if source.ExtensionID != ext.ID || source.BaseURL != "https://example.org" {
+		t.Errorf("Expected the source to be recorded, got %+v", source)
+	}
+
+	// Test that an up to date extension is left alone
+	updates, errs = updateExtensions(context.Background(), db, t.TempDir())
+	if len(errs) > 0 || len(updates) != 0 {
+		t.Errorf("Expected no updates, got %+v and %v", updates, errs)
+	}
+}
+
+func TestUpdateExtensionsRejectsBadBuilds(t *testing.T) {
+	tests := []struct {
+		name     string
+		binary   string
+		checksum string
+	}{
+		{"checksum mismatch", fakeExtensionScript("1.10.0"), strings.Repeat("0", 64)},
+		{"error page", "<html>Not here</html>", ""},
+		{"wrong version", fakeExtensionScript("1.9.3"), ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, db := setupTestApp(t)
+			_, path := addInstalledExtension(t, db, fakeRepo(t, test.binary, test.checksum))
+
+			// Test that the update fails and the installed version is kept
+			updates, errs := updateExtensions(context.Background(), db, t.TempDir())
+			if len(updates) != 0 || len(errs) != 1 {
+				t.Fatalf("Expected the update to fail, got %+v and %v", updates, errs)
+			}
+			if ext, err := db.GetExtensionByPackage("fake"); err != nil || ext.Version != "1.9.2" {
+				t.Errorf("Expected version 1.9.2 to be kept, got %+v (%v)", ext, err)
+			}
+			if data, _ := os.ReadFile(path); string(data) != fakeExtensionScript("1.9.2") {
+				t.Errorf("Expected the installed binary to be kept, got %q", data)
+			}
+			if _, err := os.Stat(path + ".download"); !os.IsNotExist(err) {
+				t.Errorf("Expected the download to be removed")
+			}
+		})
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.10.0", "1.9.2", 1},
+		{"v1.2", "1.2.0", 0},
+		{"1.2", "1.2.1", -1},
+		{"2.0.0-beta", "2.0.0-alpha", 1},
+	}
+	for _, test := range tests {
+		if got := compareVersions(test.a, test.b); got != test.want {
+			t.Errorf("Expected compareVersions(%q, %q) = %d, got %d", test.a, test.b, test.want, got)
+		}
+	}
+}
@@ -3,11 +3,18 @@ package appcore
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/pkg/browser"
 	"github.com/wraient/pair/pkg/config"
 	"github.com/wraient/pair/pkg/database"
+	"github.com/wraient/pair/pkg/logger"
+	"github.com/wraient/pair/pkg/scraper"
 	"github.com/wraient/pair/pkg/tracker"
 	"github.com/wraient/pair/pkg/ui"
 )
@@ -17,12 +24,15 @@ func (a *App) handleCurrentlyWatching(ctx context.Context) error {
 	// Get the database connection
 	db := config.GetDB()
 
-	var syncErrors []error
+	var syncErrors []tracker.SyncError
 
 	// Sync with all available trackers (same as Show All)
-	err := a.syncWithTrackers(ctx, db, &syncErrors)
-	if err != nil {
-		return fmt.Errorf("failed to sync with trackers: %w", err)
+	stats, syncErr := a.syncWithTrackers(ctx, db, &syncErrors)
+	if err := showSyncReport(stats, syncErrors); err != nil {
+		return err
+	}
+	if syncErr != nil {
+		return fmt.Errorf("failed to sync with trackers: %w", syncErr)
 	}
 
 	// Get currently watching anime from the database after sync
@@ -31,6 +41,13 @@ func (a *App) handleCurrentlyWatching(ctx context.Context) error {
 		return fmt.Errorf("failed to get watching entries: %w", err)
 	}
 
+	// Tags are shown in the list, so load them all at once. The list still
+	// works without them.
+	tags, err := db.GetAllListTags()
+	if err != nil {
+		logger.Warn("Failed to load list tags: " + err.Error())
+	}
+
 	// Convert to UserAnimeEntry format
 	watchingEntries := make([]tracker.UserAnimeEntry, 0)
 
@@ -65,7 +82,7 @@ func (a *App) handleCurrentlyWatching(ctx context.Context) error {
 			AnimeInfo: tracker.AnimeInfo{
 				ID:                strconv.FormatInt(entry.ID, 10),
 				Title:             entry.Title,
-				EnglishTitle:      entry.OriginalTitle,
+				JapaneseTitle:     entry.OriginalTitle,
 				AlternativeTitles: entry.AlternativeTitles,
 				Synopsis:          entry.Description,
 				Type:              entry.Type,
@@ -85,6 +102,7 @@ func (a *App) handleCurrentlyWatching(ctx context.Context) error {
 			userEntry.Score = tracking.Score
 			userEntry.LastUpdated = tracking.LastUpdated
 		}
+		userEntry.Tags = tags[entry.ID]
 
 		watchingEntries = append(watchingEntries, userEntry)
 	}
@@ -115,44 +133,1416 @@ func (a *App) handleCurrentlyWatching(ctx context.Context) error {
 			return fmt.Errorf("failed to show update menu: %w", err)
 		}
 
-		// Get the active tracker
-		t, err := a.trackerMgr.GetTracker(string(a.config.Tracking.Service))
-		if err != nil {
-			return fmt.Errorf("failed to get tracker: %w", err)
+		return a.handleTrackerError(ctx, a.handleAnimeAction(ctx, db, action, selectedAnime))
+	} else {
+		fmt.Println("No currently watching anime found")
+	}
+
+	return nil
+}
+
+// handleRecentlyAdded lists the anime most recently added to the library
+func (a *App) handleRecentlyAdded(ctx context.Context) error {
+	db := config.GetDB()
+
+	animes, err := db.GetRecentlyAddedAnime(20)
+	if err != nil {
+		return fmt.Errorf("failed to get recently added anime: %w", err)
+	}
+	if len(animes) == 0 {
+		fmt.Println("No anime found in database")
+		return nil
+	}
+
+	entries := make([]tracker.UserAnimeEntry, 0, len(animes))
+	for _, anime := range animes {
+		entry := tracker.UserAnimeEntry{
+			AnimeInfo: animeInfoFromDB(anime),
+			Status:    tracker.Status(anime.Status),
+		}
+		if tracking, err := db.GetAnimeTracking(anime.ID, string(a.config.Tracking.Service)); err == nil {
+			entry.Progress = tracking.CurrentEpisode
+			entry.Score = tracking.Score
 		}
+		entries = append(entries, entry)
+	}
+
+	selectedID, err := ui.ShowAnimeList(entries)
+	if err != nil {
+		return fmt.Errorf("failed to show anime list: %w", err)
+	}
 
-		// Handle selected action
-		switch action {
-		case "status":
-			status, err := ui.ShowAnimeStatusSelection()
+	for _, entry := range entries {
+		if entry.ID == selectedID {
+			action, err := ui.ShowAnimeUpdateMenu(&entry.AnimeInfo)
 			if err != nil {
-				return err
+				return fmt.Errorf("failed to show update menu: %w", err)
 			}
-			return t.UpdateAnimeStatus(ctx, selectedID, status, 0, 0)
-		case "progress":
-			episode, err := ui.ShowEpisodeSelection(selectedAnime.Episodes)
-			if err != nil {
+			return a.handleTrackerError(ctx, a.handleAnimeAction(ctx, db, action, &entry.AnimeInfo))
+		}
+	}
+
+	return nil
+}
+
+// showConfirm asks the user a yes/no question, replaced in tests
+var showConfirm = ui.ShowConfirm
+
+// handleTrackerError reacts to errors from the trackers: an expired
+// login offers to log in again and rate limiting asks the user to wait.
+// Other errors are returned unchanged.
+func (a *App) handleTrackerError(ctx context.Context, err error) error {
+	switch {
+	case errors.Is(err, tracker.ErrAuthExpired):
+		// A sync says which tracker it was, anything else used the active one
+		t, trackerErr := a.activeTracker()
+		var syncErr tracker.SyncError
+		if errors.As(err, &syncErr) && syncErr.Tracker != "" {
+			t, trackerErr = a.trackerMgr.GetTracker(syncErr.Tracker)
+		}
+		if trackerErr != nil {
+			return err
+		}
+		prompt := fmt.Sprintf("Your %s session expired — log in again?", trackerDisplayName(t.Name()))
+		confirmed, confirmErr := showConfirm(prompt)
+		if confirmErr != nil || !confirmed {
+			return err
+		}
+		return t.Authenticate(ctx)
+	case errors.Is(err, tracker.ErrRateLimited):
+		return fmt.Errorf("too many requests, try again in a minute: %w", err)
+	}
+	return err
+}
+
+// handleAnimeAction performs an action picked from the anime update menu
+func (a *App) handleAnimeAction(ctx context.Context, db *database.DB, action string, anime *tracker.AnimeInfo) error {
+	animeID, err := strconv.ParseInt(anime.ID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid anime ID %q: %w", anime.ID, err)
+	}
+
+	switch action {
+	case "", "back":
+		return nil
+	case "fillers":
+		return a.refreshFillers(ctx, db, animeID)
+	case "episodes":
+		return a.handleFetchEpisodes(ctx, db, animeID)
+	case "watch":
+		return a.handleWatch(ctx, db, animeID, anime.Episodes)
+	case "source":
+		_, err := a.chooseSource(db, animeID, true)
+		if errors.Is(err, errNoSources) {
+			_, err = a.mapSource(db, animeID)
+		}
+		return err
+	case "offset":
+		return a.handleEpisodeOffset(db, animeID)
+	case "notes":
+		return a.handleEditNotes(db, animeID)
+	case "audio":
+		return a.handleAudioPreference(db, animeID)
+	case "browser":
+		return a.openAnimePage(db, animeID)
+	case "reset":
+		confirmed, err := showConfirm(fmt.Sprintf("Forget all watched episodes of %s", anime.Title))
+		if err != nil || !confirmed {
+			return err
+		}
+		if err := db.ResetAnimeProgress(animeID); err != nil {
+			return fmt.Errorf("failed to reset progress: %w", err)
+		}
+		return nil
+	case "remove":
+		confirmed, err := showConfirm(fmt.Sprintf("Remove %s from library", anime.Title))
+		if err != nil || !confirmed {
+			return err
+		}
+		return a.removeFromLibrary(ctx, db, animeID)
+	}
+
+	t, err := a.activeTracker()
+	if err != nil {
+		return err
+	}
+	remoteID := a.remoteID(db, animeID, t)
+
+	switch action {
+	case "related":
+		return a.handleRelatedAnime(ctx, db, t, remoteID)
+	case "relink":
+		return a.handleRelink(ctx, db, t, animeID, anime.Title)
+	case "status":
+		status, err := ui.ShowAnimeStatusSelection()
+		if err != nil {
+			return err
+		}
+		return t.UpdateAnimeStatus(ctx, remoteID, status, 0, 0)
+	case "progress":
+		fillers, err := db.GetFillerEpisodes(animeID)
+		if err != nil {
+			return fmt.Errorf("failed to get filler episodes: %w", err)
+		}
+		episode, err := a.selectEpisode(db, animeID, anime.Episodes, fillers)
+		if errors.Is(err, ui.ErrCancelled) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if a.config.Video.SkipFillers {
+			episode = scraper.AdvancePastFillers(episode, fillers)
+		}
+		return setProgress(ctx, db, t, animeID, remoteID, episode)
+	case "score":
+		score, err := ui.ShowAnimeScoreSelection(scoreFormat(ctx, t))
+		if errors.Is(err, ui.ErrCancelled) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return t.UpdateAnimeStatus(ctx, remoteID, "", 0, score)
+	case "complete":
+		score, err := ui.ShowAnimeScoreSelection(scoreFormat(ctx, t))
+		if errors.Is(err, ui.ErrCancelled) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := markCompleted(ctx, db, t, animeID, remoteID, anime.Episodes, score); err != nil {
+			return err
+		}
+		return a.offerNextInSeries(ctx, db, animeID)
+	case "drop", "hold":
+		status := tracker.StatusDropped
+		if action == "hold" {
+			status = tracker.StatusOnHold
+		}
+		note, err := ui.ShowTextInput("Reason (optional)", ui.UserInput, nil)
+		if errors.Is(err, ui.ErrCancelled) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return a.setStatusWithNote(ctx, db, t, animeID, remoteID, status, note)
+	}
+
+	return nil
+}
+
+// removeFromLibrary deletes an anime with everything stored about it. When
+// configured, the entries on the enabled trackers are deleted first so the
+// next sync doesn't bring the anime back.
+func (a *App) removeFromLibrary(ctx context.Context, db *database.DB, animeID int64) error {
+	if a.config.Tracking.DeleteRemoteOnRemove {
+		trackings, err := db.GetAllAnimeTracking(animeID)
+		if err != nil {
+			return fmt.Errorf("failed to get tracking: %w", err)
+		}
+		for _, tracking := range trackings {
+			if err := a.deleteRemoteEntry(ctx, tracking); err != nil {
 				return err
 			}
-			return t.UpdateAnimeStatus(ctx, selectedID, "", episode, 0)
-		case "score":
-			score, err := ui.ShowAnimeScoreSelection()
+		}
+	}
+
+	if err := db.DeleteAnime(animeID); err != nil {
+		return fmt.Errorf("failed to delete anime: %w", err)
+	}
+	return nil
+}
+
+// deleteRemoteEntry deletes a tracking entry from its tracker, skipping
+// trackers that are disabled or can't delete entries
+func (a *App) deleteRemoteEntry(ctx context.Context, tracking *database.AnimeTracking) error {
+	if !a.trackerEnabled(tracking.Tracker) {
+		return nil
+	}
+	t, err := a.trackerMgr.GetTracker(tracking.Tracker)
+	if err != nil {
+		return nil
+	}
+	deleter, ok := t.(tracker.RemoteEntryDeleter)
+	if !ok || !t.IsAuthenticated() {
+		return nil
+	}
+	if a.offline() {
+		return errOffline
+	}
+
+	err = deleter.DeleteRemoteEntry(ctx, tracking.TrackerID)
+	if err != nil && !errors.Is(err, tracker.ErrNotInList) {
+		return fmt.Errorf("failed to delete %s entry: %w", trackerDisplayName(tracking.Tracker), err)
+	}
+	return nil
+}
+
+// handleEditNotes lets the user rewrite the personal note of an anime.
+// The current note is offered as a suggestion so it can be kept. The note
+// stays local, separate from the notes of tracker list entries.
+func (a *App) handleEditNotes(db *database.DB, animeID int64) error {
+	anime, err := db.GetAnime(animeID)
+	if err != nil {
+		return fmt.Errorf("failed to get anime: %w", err)
+	}
+
+	var suggestions []ui.Pair
+	if anime.Notes != "" {
+		suggestions = append(suggestions, ui.Pair{Label: anime.Notes, Value: anime.Notes})
+	}
+	notes, err := ui.ShowTextInput("Notes", ui.UserInput, suggestions)
+	if errors.Is(err, ui.ErrCancelled) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return db.UpdateAnimeNotes(animeID, strings.TrimSpace(notes))
+}
+
+// scoreFormat returns the score format of t, falling back to whole scores out of 10
+func scoreFormat(ctx context.Context, t tracker.Tracker) tracker.ScoreFormat {
+	formatter, ok := t.(tracker.ScoreFormatter)
+	if !ok {
+		return tracker.ScorePoint10
+	}
+	format, err := formatter.ScoreFormat(ctx)
+	if err != nil {
+		return tracker.ScorePoint10
+	}
+	return format
+}
+
+// handleRelink searches the tracker and links the anime to the entry the user picks,
+// offering to merge the two when another anime is already linked to it
+func (a *App) handleRelink(ctx context.Context, db *database.DB, t tracker.Tracker, animeID int64, title string) error {
+	if t.Name() == "local" {
+		fmt.Println("Local entries aren't linked to a tracker")
+		return nil
+	}
+
+	results, err := t.SearchAnime(ctx, title, a.searchLimit())
+	if err != nil {
+		return fmt.Errorf("failed to search anime: %w", err)
+	}
+
+	selectedID, err := ui.ShowAnimeSearchResults(results, false)
+	if errors.Is(err, ui.ErrNoResults) {
+		fmt.Printf("No matches for %s on %s\n", title, t.Name())
+		return nil
+	}
+	if err != nil || selectedID == "" {
+		return err
+	}
+
+	return a.relinkTracking(db, t.Name(), animeID, selectedID)
+}
+
+// relinkTracking links an anime to a different entry on a tracker, creating the
+// link if there is none yet
+func (a *App) relinkTracking(db *database.DB, trackerName string, animeID int64, trackerID string) error {
+	err := db.RelinkTracking(animeID, trackerName, trackerID)
+	if errors.Is(err, database.ErrTrackingNotFound) {
+		return db.AddAnimeTracking(&database.AnimeTracking{
+			AnimeID:   animeID,
+			Tracker:   trackerName,
+			TrackerID: trackerID,
+			Status:    string(tracker.StatusWatching),
+		})
+	}
+
+	var conflict *database.TrackingConflictError
+	if !errors.As(err, &conflict) {
+		return err
+	}
+
+	other, err := db.GetAnime(conflict.AnimeID)
+	if err != nil {
+		return fmt.Errorf("failed to get linked anime: %w", err)
+	}
+	choice, err := ui.OpenMenu(ui.List, []ui.Pair{
+		{Label: fmt.Sprintf("Merge into %s (ID %d), which is already linked", other.Title, other.ID), Value: "merge"},
+		{Label: "Cancel", Value: "cancel"},
+	})
+	if err != nil || choice != "merge" {
+		return err
+	}
+
+	return db.MergeAnime(other.ID, []int64{animeID})
+}
+
+// selectEpisode lets the user pick an episode, showing titles and thumbnails
+// when the episode list has been fetched and plain numbers otherwise
+func (a *App) selectEpisode(db *database.DB, animeID int64, totalEpisodes int, fillers map[float64]bool) (float64, error) {
+	episodes, err := db.GetAllEpisodes(animeID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get episodes: %w", err)
+	}
+	progress, err := db.GetInProgressEpisodes(animeID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get episode progress: %w", err)
+	}
+	inProgress := make(map[float64]float64, len(progress))
+	for _, p := range progress {
+		inProgress[p.EpisodeNumber] = p.Percent()
+	}
+
+	if len(episodes) == 0 {
+		return ui.ShowEpisodeSelection(totalEpisodes, fillers, inProgress)
+	}
+	fresh, err := newEpisodes(db, animeID)
+	if err != nil {
+		return 0, err
+	}
+	return ui.ShowEpisodeList(episodes, inProgress, fresh, a.config.UI.ShowImagePreview)
+}
+
+// markCompleted marks an anime as completed in a single update, setting progress
+// to the last episode when the episode count is known and recording score if
+// given. The local tracking entry gets the same values once the tracker took
+// them; an anime not tracked locally only gets the update on the tracker.
+func markCompleted(ctx context.Context, db *database.DB, t tracker.Tracker, animeID int64, remoteID string, totalEpisodes int, score float64) error {
+	var progress float64
+	if totalEpisodes > 0 {
+		progress = float64(totalEpisodes)
+	}
+	if err := t.UpdateAnimeStatus(ctx, remoteID, tracker.StatusCompleted, progress, score); err != nil {
+		return err
+	}
+
+	tracking, err := db.GetAnimeTracking(animeID, t.Name())
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get tracking info: %w", err)
+	}
+	tracking.Status = string(tracker.StatusCompleted)
+	if progress > 0 {
+		tracking.CurrentEpisode = progress
+	}
+	if score > 0 {
+		tracking.Score = score
+	}
+	if err := db.UpdateAnimeTrackingObject(tracking); err != nil {
+		return fmt.Errorf("failed to save completion: %w", err)
+	}
+	return nil
+}
+
+// setProgress sets the progress of an anime on the tracker and on the local
+// tracking entry. Unlike watching, this can also lower the progress, to fix a
+// mistaken episode number. An anime not tracked locally only gets the update
+// on the tracker.
+func setProgress(ctx context.Context, db *database.DB, t tracker.Tracker, animeID int64, remoteID string, episode float64) error {
+	if err := t.UpdateAnimeStatus(ctx, remoteID, "", episode, 0); err != nil {
+		return err
+	}
+	if err := db.SetAnimeProgress(animeID, t.Name(), episode); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("failed to save progress: %w", err)
+	}
+	return nil
+}
+
+// setStatusWithNote updates the status of an anime on the tracker and stores
+// the status together with an optional note on the local tracking entry. The
+// note is also sent to trackers that keep notes.
+func (a *App) setStatusWithNote(ctx context.Context, db *database.DB, t tracker.Tracker, animeID int64, remoteID string, status tracker.Status, note string) error {
+	if err := t.UpdateAnimeStatus(ctx, remoteID, status, 0, 0); err != nil {
+		return err
+	}
+
+	note = strings.TrimSpace(note)
+	if writer, ok := t.(tracker.NoteWriter); ok && note != "" {
+		if err := writer.UpdateNotes(ctx, remoteID, note); err != nil {
+			return fmt.Errorf("failed to update notes: %w", err)
+		}
+	}
+	tracking, err := db.GetAnimeTracking(animeID, t.Name())
+	if errors.Is(err, sql.ErrNoRows) {
+		return db.AddAnimeTracking(&database.AnimeTracking{
+			AnimeID:   animeID,
+			Tracker:   t.Name(),
+			TrackerID: remoteID,
+			Status:    string(status),
+			Notes:     note,
+		})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get tracking info: %w", err)
+	}
+
+	tracking.Status = string(status)
+	if note != "" {
+		tracking.Notes = note
+	}
+	return db.UpdateAnimeTrackingObject(tracking)
+}
+
+// handleRelatedAnime shows the anime related to the given one and lets the user
+// jump to one already in the library or add it to the list
+func (a *App) handleRelatedAnime(ctx context.Context, db *database.DB, t tracker.Tracker, remoteID string) error {
+	details, err := t.GetAnimeDetails(ctx, remoteID)
+	if err != nil {
+		return fmt.Errorf("failed to get anime details: %w", err)
+	}
+
+	selectedID, err := ui.ShowRelatedAnime(details.Relations)
+	if err != nil {
+		return err
+	}
+	if selectedID == "" || selectedID == "back" {
+		return nil
+	}
+
+	var selected *tracker.RelatedAnime
+	for i := range details.Relations {
+		if details.Relations[i].ID == selectedID {
+			selected = &details.Relations[i]
+			break
+		}
+	}
+	if selected == nil {
+		return fmt.Errorf("selected anime not found")
+	}
+
+	// Jump to the anime if it is already in the library
+	existing, err := db.GetAnimeByExternalID(selected.ID, t.Name())
+	if err == nil {
+		info := animeInfoFromDB(existing)
+		action, err := ui.ShowAnimeUpdateMenu(&info)
+		if err != nil {
+			return fmt.Errorf("failed to show update menu: %w", err)
+		}
+		return a.handleAnimeAction(ctx, db, action, &info)
+	}
+	if !errors.Is(err, database.ErrAnimeNotFound) {
+		return fmt.Errorf("failed to check anime: %w", err)
+	}
+
+	status, err := ui.ShowAnimeStatusSelection()
+	if err != nil {
+		return err
+	}
+	if status == "" {
+		return nil
+	}
+
+	return a.addAnimeToList(ctx, db, t, &selected.AnimeInfo, status)
+}
+
+// SuggestNextInSeries returns the sequel of an anime if it is on the user's
+// plan-to-watch list, or nil if there is none. Sequels come from the anime's
+// Anilist relations and are matched against local plan-to-watch entries by
+// their Anilist or MyAnimeList ID.
+func (a *App) SuggestNextInSeries(ctx context.Context, animeID int64) (*database.Anime, error) {
+	tracking, err := a.db.GetAnimeTracking(animeID, string(config.TrackerAnilist))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil // Relations can only be looked up by Anilist ID
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tracking: %w", err)
+	}
+
+	t, err := a.trackerMgr.GetTracker(string(config.TrackerAnilist))
+	if err != nil {
+		return nil, nil
+	}
+	details, err := t.GetAnimeDetails(ctx, tracking.TrackerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get anime details: %w", err)
+	}
+
+	for _, related := range details.Relations {
+		if related.RelationType != "SEQUEL" {
+			continue
+		}
+		ids := map[config.TrackerType]string{
+			config.TrackerAnilist: related.ID,
+			config.TrackerMAL:     related.MalID,
+		}
+		for _, name := range []config.TrackerType{config.TrackerAnilist, config.TrackerMAL} {
+			if ids[name] == "" {
+				continue
+			}
+			sequel, err := a.db.GetAnimeByExternalID(ids[name], string(name))
+			if errors.Is(err, database.ErrAnimeNotFound) {
+				continue
+			}
 			if err != nil {
-				return err
+				return nil, fmt.Errorf("failed to check sequel: %w", err)
+			}
+			planned, err := a.db.GetAnimeTracking(sequel.ID, string(name))
+			if err == nil && tracker.Status(planned.Status) == tracker.StatusPlanToWatch {
+				return sequel, nil
 			}
-			return t.UpdateAnimeStatus(ctx, selectedID, "", 0, score)
 		}
-	} else {
-		fmt.Println("No currently watching anime found")
 	}
 
-	// Log any sync errors that occurred
-	if len(syncErrors) > 0 {
-		fmt.Println("\nSync errors occurred:")
-		for _, err := range syncErrors {
-			fmt.Printf("- %v\n", err)
+	return nil, nil
+}
+
+// offerNextInSeries suggests starting the planned sequel of a completed
+// anime. Failing to find one never fails the completion itself.
+func (a *App) offerNextInSeries(ctx context.Context, db *database.DB, animeID int64) error {
+	if a.offline() {
+		return nil
+	}
+	sequel, err := a.SuggestNextInSeries(ctx, animeID)
+	if err != nil {
+		logger.Debug("Failed to look for a sequel: " + err.Error())
+		return nil
+	}
+	if sequel == nil {
+		return nil
+	}
+
+	confirmed, err := showConfirm(fmt.Sprintf("%s is on your plan-to-watch list — start it now?", sequel.Title))
+	if err != nil || !confirmed {
+		return err
+	}
+
+	t, err := a.activeTracker()
+	if err != nil {
+		return err
+	}
+	if err := t.UpdateAnimeStatus(ctx, a.remoteID(db, sequel.ID, t), tracker.StatusWatching, 0, 0); err != nil {
+		return err
+	}
+	return a.handleWatch(ctx, db, sequel.ID, sequel.TotalEpisodes)
+}
+
+// animeInfoFromDB converts a local anime into the tracker display format
+func animeInfoFromDB(anime *database.Anime) tracker.AnimeInfo {
+	return tracker.AnimeInfo{
+		ID:                strconv.FormatInt(anime.ID, 10),
+		Title:             anime.Title,
+		JapaneseTitle:     anime.OriginalTitle,
+		AlternativeTitles: anime.AlternativeTitles,
+		Synopsis:          anime.Description,
+		Type:              anime.Type,
+		Episodes:          anime.TotalEpisodes,
+		Status:            anime.Status,
+		Year:              anime.Year,
+		Season:            anime.Season,
+		Genres:            anime.Genres,
+		ImageURL:          anime.ThumbnailURL,
+	}
+}
+
+// BackfillMetadata fills in the missing details of library anime linked to
+// the active tracker, fetching them in as few requests as the tracker allows.
+// Only empty fields are written. It returns how many anime were updated.
+func (a *App) BackfillMetadata(ctx context.Context) (int, error) {
+	if a.offline() {
+		return 0, errOffline
+	}
+	db := a.db
+	t, err := a.activeTracker()
+	if err != nil || t.Name() == "local" || !t.IsAuthenticated() {
+		return 0, nil
+	}
+
+	animes, err := db.GetAllAnime()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get anime: %w", err)
+	}
+
+	pending := make(map[string]*database.Anime)
+	var ids []string
+	for _, anime := range animes {
+		if !missingMetadata(anime) {
+			continue
+		}
+		tracking, err := db.GetAnimeTracking(anime.ID, t.Name())
+		if err != nil || tracking.TrackerID == "" {
+			continue
+		}
+		pending[tracking.TrackerID] = anime
+		ids = append(ids, tracking.TrackerID)
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	// Apply whatever was fetched even if some lookups failed
+	details, fetchErr := tracker.GetAnimeDetailsBatch(ctx, t, ids)
+	updated := 0
+	for id, info := range details {
+		anime := pending[id]
+		if anime == nil || !fillMetadata(anime, info) {
+			continue
+		}
+		if err := db.UpdateAnime(anime); err != nil {
+			return updated, fmt.Errorf("failed to update anime %s: %w", anime.Title, err)
+		}
+		updated++
+	}
+
+	return updated, fetchErr
+}
+
+// missingMetadata reports whether an anime lacks details a tracker could provide
+func missingMetadata(anime *database.Anime) bool {
+	return anime.Description == "" || len(anime.Genres) == 0 || anime.ThumbnailURL == "" || anime.TotalEpisodes == 0
+}
+
+// fillMetadata copies details from info into the fields of anime that are
+// empty, reporting whether anything changed
+func fillMetadata(anime *database.Anime, info *tracker.AnimeInfo) bool {
+	changed := false
+	fill := func(field *string, value string) {
+		if *field == "" && value != "" {
+			*field = value
+			changed = true
 		}
 	}
 
+	fill(&anime.Description, info.Synopsis)
+	fill(&anime.ThumbnailURL, info.ImageURL)
+	fill(&anime.Type, info.Type)
+	fill(&anime.Status, info.Status)
+	fill(&anime.Season, info.Season)
+	if anime.TotalEpisodes == 0 && info.Episodes > 0 {
+		anime.TotalEpisodes = info.Episodes
+		changed = true
+	}
+	if anime.Year == 0 && info.Year > 0 {
+		anime.Year = info.Year
+		changed = true
+	}
+	if len(anime.Genres) == 0 && len(info.Genres) > 0 {
+		anime.Genres = info.Genres
+		changed = true
+	}
+
+	return changed
+}
+
+// remoteID returns the ID the tracker knows the anime by, falling back to the local ID
+func (a *App) remoteID(db *database.DB, animeID int64, t tracker.Tracker) string {
+	tracking, err := db.GetAnimeTracking(animeID, t.Name())
+	if err == nil && tracking.TrackerID != "" {
+		return tracking.TrackerID
+	}
+	return strconv.FormatInt(animeID, 10)
+}
+
+// openURL opens a web page in the default browser, replaced in tests
+var openURL = browser.OpenURL
+
+// openAnimePage opens the page of an anime on the website of a tracker it
+// is linked to
+func (a *App) openAnimePage(db *database.DB, animeID int64) error {
+	pageURL, err := animePageURL(db, animeID, string(a.config.Tracking.Service))
+	if err != nil {
+		return err
+	}
+	if err := openURL(pageURL); err != nil {
+		return fmt.Errorf("failed to open browser: %w", err)
+	}
+	return nil
+}
+
+// animePageURL returns the web page of an anime on the preferred tracker,
+// or on the first other tracker it is linked to that has pages
+func animePageURL(db *database.DB, animeID int64, preferred string) (string, error) {
+	trackings, err := db.GetAllAnimeTracking(animeID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get tracking: %w", err)
+	}
+
+	var fallback string
+	for _, tracking := range trackings {
+		pageURL := tracker.AnimeURL(tracking.Tracker, tracking.TrackerID)
+		if pageURL == "" {
+			continue
+		}
+		if tracking.Tracker == preferred {
+			return pageURL, nil
+		}
+		if fallback == "" {
+			fallback = pageURL
+		}
+	}
+	if fallback == "" {
+		return "", fmt.Errorf("this anime isn't linked to Anilist or MyAnimeList")
+	}
+	return fallback, nil
+}
+
+// refreshFillers fetches the filler list for an anime and flags its episodes
+func (a *App) refreshFillers(ctx context.Context, db *database.DB, animeID int64) error {
+	if a.offline() {
+		return errOffline
+	}
+
+	tracking, err := db.GetAnimeTracking(animeID, "mal")
+	if err != nil {
+		return fmt.Errorf("no MyAnimeList ID known for this anime")
+	}
+
+	malID, err := strconv.Atoi(tracking.TrackerID)
+	if err != nil {
+		return fmt.Errorf("invalid MyAnimeList ID %q: %w", tracking.TrackerID, err)
+	}
+
+	fillers, err := scraper.FetchFillerList(ctx, malID)
+	if err != nil {
+		return err
+	}
+
+	for number, isFiller := range fillers {
+		if err := db.UpdateEpisodeFiller(animeID, number, isFiller); err != nil {
+			return fmt.Errorf("failed to update episode %v: %w", number, err)
+		}
+	}
+
+	return nil
+}
+
+// handleFetchEpisodes imports the episode list from one of the anime's mapped sources
+func (a *App) handleFetchEpisodes(ctx context.Context, db *database.DB, animeID int64) error {
+	sources, err := mappedSources(db, animeID)
+	if err != nil {
+		return err
+	}
+	if len(sources) == 0 {
+		fmt.Println("This anime isn't mapped to any installed source yet")
+		return nil
+	}
+
+	sourceID := sources[0].SourceID
+	if len(sources) > 1 {
+		sourceID, err = ui.ShowSourceSelection(sources)
+		if err != nil || sourceID == "" {
+			return err
+		}
+	}
+
+	count, err := a.importEpisodes(ctx, animeID, sourceID)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Imported %d episodes\n", count)
+	return nil
+}
+
+// importEpisodes pulls the episode list of an anime from a source into the database.
+// It returns the number of episodes that were added or changed.
+func (a *App) importEpisodes(ctx context.Context, animeID int64, sourceID string) (int, error) {
+	if a.offline() {
+		return 0, errOffline
+	}
+
+	sourceScraper, mapping, err := a.sourceScraper(animeID, sourceID)
+	if err != nil {
+		return 0, err
+	}
+
+	episodes, err := sourceScraper.GetEpisodeList(mapping.SourceAnimeID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get episode list: %w", err)
+	}
+
+	existing, err := a.db.GetAllEpisodes(animeID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get existing episodes: %w", err)
+	}
+	known := make(map[float64]string)
+	for _, episode := range existing {
+		known[episode.Number] = episode.Title
+	}
+
+	var fresh []database.Episode
+	for _, episode := range episodes {
+		// Sources report -1 for episodes they couldn't number
+		if episode.EpisodeNumber < 0 {
+			continue
+		}
+
+		// Store the episode as numbered by the anime, skipping those of
+		// earlier seasons the source lists too
+		number := mapping.AnimeEpisode(episode.EpisodeNumber)
+		if number < 0 {
+			continue
+		}
+		fresh = append(fresh, database.Episode{AnimeID: animeID, Number: number, Title: episode.Name})
+	}
+
+	// Episodes released since the last fetch are highlighted in the picker.
+	// On the first fetch every episode is new, so none are.
+	added, err := a.db.DiffEpisodes(animeID, fresh)
+	if err != nil {
+		return 0, fmt.Errorf("failed to compare episodes: %w", err)
+	}
+	if len(existing) > 0 && len(added) > 0 {
+		if err := a.markNewEpisodes(animeID, added); err != nil {
+			return 0, err
+		}
+	}
+
+	imported := 0
+	for i := range fresh {
+		if ctx.Err() != nil {
+			return imported, ctx.Err()
+		}
+		episode := &fresh[i]
+
+		// Skip episodes already stored unchanged, including repeats from other release groups
+		title, exists := known[episode.Number]
+		if exists && (title == episode.Title || episode.Title == "") {
+			continue
+		}
+
+		if err := a.db.AddEpisode(episode); err != nil {
+			return imported, fmt.Errorf("failed to add episode %v: %w", episode.Number, err)
+		}
+
+		known[episode.Number] = episode.Title
+		imported++
+	}
+
+	return imported, nil
+}
+
+// newEpisodesKey is the config key holding the episode numbers of an anime
+// that were new in its last episode fetch, as a JSON list
+func newEpisodesKey(animeID int64) string {
+	return fmt.Sprintf("anime.%d.new_episodes", animeID)
+}
+
+// markNewEpisodes remembers the episodes of an anime as newly released
+func (a *App) markNewEpisodes(animeID int64, added []database.Episode) error {
+	numbers := make([]float64, len(added))
+	for i, episode := range added {
+		numbers[i] = episode.Number
+	}
+	data, err := json.Marshal(numbers)
+	if err != nil {
+		return fmt.Errorf("failed to encode new episodes: %w", err)
+	}
+	if err := a.db.SetConfig(newEpisodesKey(animeID), string(data)); err != nil {
+		return fmt.Errorf("failed to remember new episodes: %w", err)
+	}
+	return nil
+}
+
+// newEpisodes returns the episodes of an anime that were new in its last
+// fetch and haven't been started yet. A stored list that can't be decoded
+// only loses the markers, so it is logged and treated as empty.
+func newEpisodes(db *database.DB, animeID int64) (map[float64]bool, error) {
+	value, err := db.GetConfig(newEpisodesKey(animeID))
+	if err != nil || value == "" {
+		return nil, err
+	}
+	var numbers []float64
+	if err := json.Unmarshal([]byte(value), &numbers); err != nil {
+		logger.Warn(fmt.Sprintf("Ignoring corrupt new episodes of anime %d: %v", animeID, err))
+		return nil, nil
+	}
+
+	progress, err := db.GetAllEpisodeProgress(animeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get episode progress: %w", err)
+	}
+	started := make(map[float64]bool, len(progress))
+	for _, p := range progress {
+		started[p.EpisodeNumber] = true
+	}
+
+	fresh := make(map[float64]bool)
+	for _, number := range numbers {
+		if !started[number] {
+			fresh[number] = true
+		}
+	}
+	return fresh, nil
+}
+
+// handleSearchAndAdd searches the active tracker and adds the selected anime to the list
+func (a *App) handleSearchAndAdd(ctx context.Context) error {
+	db := config.GetDB()
+
+	t, err := a.activeTracker()
+	if err != nil {
+		return err
+	}
+
+	// The history is only a convenience, so searching goes on without it
+	history, err := db.GetSearchHistory(database.MaxSearchHistory)
+	if err != nil {
+		logger.Warn("Failed to get search history: " + err.Error())
+		if errors.Is(err, database.ErrCorruptSearchHistory) {
+			if err := db.ClearSearchHistory(); err != nil {
+				logger.Warn("Failed to reset search history: " + err.Error())
+			}
+		}
+	}
+
+	// A search without matches asks for another query
+	for {
+		query, err := ui.ShowSearchPrompt(history)
+		if errors.Is(err, ui.ErrSearchCancelled) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := db.AddSearchHistory(query); err != nil {
+			logger.Warn("Failed to save search history: " + err.Error())
+		}
+
+		err = a.searchAndAdd(ctx, db, t, query)
+		if !errors.Is(err, ui.ErrNoResults) {
+			return err
+		}
+		fmt.Println("No matches — try another query")
+	}
+}
+
+// searchAndAdd searches the tracker for query and adds the anime the user
+// picks. A search without results returns ui.ErrNoResults.
+func (a *App) searchAndAdd(ctx context.Context, db *database.DB, t tracker.Tracker, query string) error {
+	// Each "load more" searches again for another page worth of results
+	for limit := a.searchLimit(); ; limit += a.searchLimit() {
+		results, err := t.SearchAnime(ctx, query, limit)
+		if err != nil {
+			return fmt.Errorf("failed to search anime: %w", err)
+		}
+
+		// A full page means the tracker may have more
+		err = a.pickAndAddAnime(ctx, db, t, results, len(results) >= limit)
+		if !errors.Is(err, errLoadMore) {
+			return err
+		}
+	}
+}
+
+// errLoadMore is returned by pickAndAddAnime when the user asks for more results
+var errLoadMore = errors.New("more results requested")
+
+// defaultSearchLimit is used when search.result_limit isn't set
+const defaultSearchLimit = 20
+
+// searchLimit returns the number of results a search starts with
+func (a *App) searchLimit() int {
+	if a.config.Search.ResultLimit > 0 {
+		return a.config.Search.ResultLimit
+	}
+	return defaultSearchLimit
+}
+
+// pickAndAddAnime lets the user pick one of the results and a status, then
+// adds it to the list. With more set the user can ask for more results
+// instead, which returns errLoadMore.
+func (a *App) pickAndAddAnime(ctx context.Context, db *database.DB, t tracker.Tracker, results []tracker.AnimeInfo, more bool) error {
+	selectedID, err := ui.ShowAnimeSearchResults(annotateTracked(db, t.Name(), results), more)
+	if err != nil {
+		return err
+	}
+	if selectedID == "" {
+		return nil
+	}
+	if selectedID == ui.LoadMore {
+		return errLoadMore
+	}
+
+	var selected *tracker.AnimeInfo
+	for i := range results {
+		if results[i].ID == selectedID {
+			selected = &results[i]
+			break
+		}
+	}
+	if selected == nil {
+		return fmt.Errorf("selected anime not found")
+	}
+
+	status, err := ui.ShowAnimeStatusSelection()
+	if err != nil {
+		return err
+	}
+	if status == "" {
+		return nil
+	}
+
+	if err := a.addAnimeToList(ctx, db, t, selected, status); err != nil {
+		return err
+	}
+
+	fmt.Printf("Added %s to your list\n", selected.Title)
+	return nil
+}
+
+// annotateTracked returns a copy of results with anime already in the list
+// marked as such, for display
+func annotateTracked(db *database.DB, trackerName string, results []tracker.AnimeInfo) []tracker.AnimeInfo {
+	annotated := make([]tracker.AnimeInfo, len(results))
+	copy(annotated, results)
+	for i := range annotated {
+		_, err := db.GetAnimeByExternalID(annotated[i].ID, trackerName)
+		if err == nil || errors.Is(err, database.ErrAmbiguousTracking) {
+			// Mark every title, whichever language is displayed
+			for _, title := range []*string{&annotated[i].Title, &annotated[i].RomajiTitle, &annotated[i].EnglishTitle, &annotated[i].JapaneseTitle} {
+				if *title != "" {
+					*title += " (in list)"
+				}
+			}
+		}
+	}
+	return annotated
+}
+
+// handleTrendingAnime lists the anime trending on the active tracker
+func (a *App) handleTrendingAnime(ctx context.Context) error {
+	db := config.GetDB()
+
+	t, err := a.activeTracker()
+	if err != nil {
+		return err
+	}
+	discoverer, ok := t.(tracker.Discoverer)
+	if !ok {
+		fmt.Printf("%s doesn't support trending listings\n", trackerDisplayName(t.Name()))
+		return nil
+	}
+
+	stopSpinner := ui.ShowSpinner(ctx, "Loading trending anime…")
+	results, err := discoverer.GetTrendingAnime(ctx, 30)
+	stopSpinner()
+	if err != nil {
+		return err
+	}
+
+	return a.pickAndAddAnime(ctx, db, t, results, false)
+}
+
+// handleAiringSchedule lists when the next episodes of the airing anime being
+// watched come out, soonest first
+func (a *App) handleAiringSchedule(ctx context.Context) error {
+	if a.offline() {
+		return errOffline
+	}
+
+	t, err := a.activeTracker()
+	if err != nil {
+		return err
+	}
+	scheduler, ok := t.(tracker.AiringScheduler)
+	if !ok {
+		fmt.Printf("%s doesn't provide an airing schedule\n", trackerDisplayName(t.Name()))
+		return nil
+	}
+
+	stopSpinner := ui.ShowSpinner(ctx, "Loading airing schedule…")
+	schedule, err := scheduler.GetAiringSchedule(ctx)
+	stopSpinner()
+	if err != nil {
+		return err
+	}
+	if len(schedule) == 0 {
+		fmt.Println("None of the anime you're watching have upcoming episodes")
+		return nil
+	}
+
+	return ui.ShowAiringSchedule(schedule, time.Now())
+}
+
+// handleSeasonalAnime lists the anime of a season from the active tracker
+func (a *App) handleSeasonalAnime(ctx context.Context) error {
+	db := config.GetDB()
+
+	t, err := a.activeTracker()
+	if err != nil {
+		return err
+	}
+	discoverer, ok := t.(tracker.Discoverer)
+	if !ok {
+		fmt.Printf("%s doesn't support seasonal listings\n", trackerDisplayName(t.Name()))
+		return nil
+	}
+
+	year, season, err := ui.ShowSeasonSelection(time.Now())
+	if err != nil {
+		return err
+	}
+	if season == "" {
+		return nil
+	}
+
+	stopSpinner := ui.ShowSpinner(ctx, fmt.Sprintf("Loading %s %d…", season, year))
+	results, err := discoverer.GetSeasonalAnime(ctx, year, season)
+	stopSpinner()
+	if err != nil {
+		return err
+	}
+
+	return a.pickAndAddAnime(ctx, db, t, results, false)
+}
+
+// addAnimeToList creates the list entry on the tracker and stores it locally
+func (a *App) addAnimeToList(ctx context.Context, db *database.DB, t tracker.Tracker, anime *tracker.AnimeInfo, status tracker.Status) error {
+	if err := t.UpdateAnimeStatus(ctx, anime.ID, status, 0, 0); err != nil {
+		return fmt.Errorf("failed to add anime to %s: %w", t.Name(), err)
+	}
+
+	localTrackings, err := db.GetAllAnimeTrackingByTracker(t.Name())
+	if err != nil {
+		return fmt.Errorf("failed to get local tracking: %w", err)
+	}
+
+	localTrackingMap := make(map[string]*database.AnimeTracking)
+	for _, tracking := range localTrackings {
+		localTrackingMap[tracking.TrackerID] = tracking
+	}
+
+	entry := tracker.UserAnimeEntry{
+		AnimeInfo:   *anime,
+		Status:      status,
+		LastUpdated: time.Now(),
+	}
+
+	var stats tracker.SyncStats
+	if err := a.processRemoteEntry(ctx, db, &entry, t.Name(), localTrackingMap, &stats); err != nil {
+		return fmt.Errorf("failed to store anime locally: %w", err)
+	}
+
+	return nil
+}
+
+// activeTracker returns the tracker selected in the configuration
+func (a *App) activeTracker() (tracker.Tracker, error) {
+	name := string(a.config.Tracking.Service)
+	if a.offline() {
+		// Remote trackers can't be reached, so work on the local list
+		name = string(config.TrackerLocal)
+	}
+
+	t, err := a.trackerMgr.GetTracker(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tracker: %w", err)
+	}
+	return t, nil
+}
+
+// customAnimeInput holds the raw answers to the custom anime prompts
+type customAnimeInput struct {
+	Title    string
+	Episodes string
+	Type     string
+	Year     string
+}
+
+// handleAddCustomAnime prompts for the details of a show and tracks it locally
+func (a *App) handleAddCustomAnime(ctx context.Context) error {
+	db := config.GetDB()
+
+	prompts := []struct {
+		label string
+		value *string
+	}{
+		{"Title", new(string)},
+		{"Total episodes (blank if unknown)", new(string)},
+		{"Type (TV, Movie, OVA, ONA, Special)", new(string)},
+		{"Year (blank if unknown)", new(string)},
+	}
+	for _, prompt := range prompts {
+		value, err := ui.ShowTextInput(prompt.label, ui.UserInput, nil)
+		if errors.Is(err, ui.ErrCancelled) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		*prompt.value = value
+	}
+
+	anime, err := parseCustomAnime(customAnimeInput{
+		Title:    *prompts[0].value,
+		Episodes: *prompts[1].value,
+		Type:     *prompts[2].value,
+		Year:     *prompts[3].value,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := a.createCustomAnime(ctx, db, anime); err != nil {
+		return err
+	}
+
+	fmt.Printf("Added %s to your list\n", anime.Title)
+	return nil
+}
+
+// parseCustomAnime validates the custom anime answers and builds the anime
+func parseCustomAnime(input customAnimeInput) (*database.Anime, error) {
+	anime := &database.Anime{
+		Title: strings.TrimSpace(input.Title),
+		Type:  strings.TrimSpace(input.Type),
+	}
+	if anime.Title == "" {
+		return nil, fmt.Errorf("title is required")
+	}
+	if anime.Type == "" {
+		anime.Type = "TV"
+	}
+
+	if episodes := strings.TrimSpace(input.Episodes); episodes != "" {
+		count, err := strconv.Atoi(episodes)
+		if err != nil || count <= 0 {
+			return nil, fmt.Errorf("total episodes must be a positive number, got %q", episodes)
+		}
+		anime.TotalEpisodes = count
+	}
+
+	if year := strings.TrimSpace(input.Year); year != "" {
+		value, err := strconv.Atoi(year)
+		if err != nil || value < 1900 || value > time.Now().Year()+5 {
+			return nil, fmt.Errorf("year must be a valid year, got %q", year)
+		}
+		anime.Year = value
+	}
+
+	return anime, nil
+}
+
+// createCustomAnime stores a custom anime and starts tracking it locally
+func (a *App) createCustomAnime(ctx context.Context, db *database.DB, anime *database.Anime) error {
+	if err := db.AddAnime(anime); err != nil {
+		return fmt.Errorf("failed to add anime: %w", err)
+	}
+
+	localTracker := tracker.NewLocalTracker(db)
+	if err := localTracker.UpdateAnimeStatus(ctx, strconv.FormatInt(anime.ID, 10), tracker.StatusWatching, 0, 0); err != nil {
+		return fmt.Errorf("failed to create local tracking: %w", err)
+	}
+
+	return nil
+}
+
+// handleDeduplicate offers to merge each group of duplicate anime into its oldest entry
+func (a *App) handleDeduplicate(ctx context.Context, db *database.DB) error {
+	groups, err := db.FindDuplicateAnime()
+	if err != nil {
+		return fmt.Errorf("failed to find duplicates: %w", err)
+	}
+	if len(groups) == 0 {
+		fmt.Println("No duplicate anime found")
+		return nil
+	}
+
+	merged := 0
+	for _, group := range groups {
+		keep := group[0]
+		ids := make([]string, len(group))
+		mergeIDs := make([]int64, 0, len(group)-1)
+		for i, anime := range group {
+			ids[i] = strconv.FormatInt(anime.ID, 10)
+			if i > 0 {
+				mergeIDs = append(mergeIDs, anime.ID)
+			}
+		}
+
+		choice, err := ui.OpenMenu(ui.List, []ui.Pair{
+			{Label: fmt.Sprintf("Merge %s (IDs %s) into ID %d", keep.Title, strings.Join(ids, ", "), keep.ID), Value: "merge"},
+			{Label: "Skip", Value: "skip"},
+			{Label: "Stop", Value: "stop"},
+		})
+		if err != nil {
+			return err
+		}
+		if choice == "" || choice == "stop" {
+			break
+		}
+		if choice != "merge" {
+			continue
+		}
+
+		if err := db.MergeAnime(keep.ID, mergeIDs); err != nil {
+			return err
+		}
+		merged++
+	}
+
+	fmt.Printf("Merged %d duplicate groups\n", merged)
+	return nil
+}
+
+// sourceItems lists the installed sources grouped by extension, marking
+// extensions whose binary is gone
+func sourceItems(db *database.DB) ([]ui.Pair, error) {
+	extensions, err := db.GetAllExtensions()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get extensions: %w", err)
+	}
+
+	var items []ui.Pair
+	for _, ext := range extensions {
+		sources, err := db.GetSourcesByExtension(ext.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get sources for %s: %w", ext.Package, err)
+		}
+
+		suffix := ""
+		if err := scraper.CheckExtensionBinary(ext.Path, ext.Package); err != nil {
+			suffix = " (binary missing — reinstall)"
+		}
+
+		if len(sources) == 0 {
+			items = append(items, ui.Pair{Label: ext.Name + suffix, Value: ext.Package})
+		}
+		for _, source := range sources {
+			label := fmt.Sprintf("%s [%s] - %s%s", source.Name, source.Language, ext.Name, suffix)
+			items = append(items, ui.Pair{Label: label, Value: ext.Package})
+		}
+	}
+
+	return items, nil
+}
+
+// handleSources lists the installed sources and offers to remove extensions whose binary is missing
+func (a *App) handleSources(ctx context.Context, db *database.DB) error {
+	items, err := sourceItems(db)
+	if err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		fmt.Println("No extensions installed")
+		return nil
+	}
+
+	pkg, err := ui.OpenMenu(ui.List, items)
+	if err != nil || pkg == "" {
+		return err
+	}
+
+	ext, err := db.GetExtensionByPackage(pkg)
+	if err != nil {
+		return fmt.Errorf("failed to get extension %s: %w", pkg, err)
+	}
+	if scraper.CheckExtensionBinary(ext.Path, ext.Package) == nil {
+		return nil
+	}
+
+	choice, err := ui.OpenMenu(ui.List, []ui.Pair{
+		{Label: fmt.Sprintf("Delete %s and its sources", ext.Name), Value: "delete"},
+		{Label: "Keep", Value: "keep"},
+	})
+	if err != nil || choice != "delete" {
+		return err
+	}
+
+	if err := db.DeleteExtension(ext.Package); err != nil {
+		return fmt.Errorf("failed to delete extension %s: %w", ext.Package, err)
+	}
+	fmt.Printf("Removed %s\n", ext.Name)
 	return nil
 }
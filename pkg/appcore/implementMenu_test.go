@@ -0,0 +1,1356 @@
+package appcore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/wraient/pair/pkg/config"
+	"github.com/wraient/pair/pkg/database"
+	"github.com/wraient/pair/pkg/httpclient"
+	"github.com/wraient/pair/pkg/logger"
+	"github.com/wraient/pair/pkg/tracker"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// statusUpdate records a call to UpdateAnimeStatus
+type statusUpdate struct {
+	ID      string
+	Status  tracker.Status
+	Episode float64
+	Score   float64
+}
+
+// mockTracker is an in-memory tracker used by the tests
+type mockTracker struct {
+	name          string
+	authenticated bool
+	anime         map[string]tracker.AnimeInfo
+	list          []tracker.UserAnimeEntry
+	updates       []statusUpdate
+	rewatches     []string // IDs whose rewatch was finished
+	calls         int
+	apiURL        string
+	updateErr     error
+	listErr       error
+	searchLimits  []int
+}
+
+func newMockTracker(name string) *mockTracker {
+	return &mockTracker{
+		name:          name,
+		authenticated: true,
+		anime:         make(map[string]tracker.AnimeInfo),
+	}
+}
+
+func (m *mockTracker) Name() string { return m.name }
+
+func (m *mockTracker) APIURL() string { return m.apiURL }
+
+func (m *mockTracker) IsAuthenticated() bool {
+	m.calls++
+	return m.authenticated
+}
+
+func (m *mockTracker) Authenticate(ctx context.Context) error {
+	m.calls++
+	m.authenticated = true
+	return nil
+}
+
+func (m *mockTracker) SearchAnime(ctx context.Context, query string, limit int) ([]tracker.AnimeInfo, error) {
+	m.calls++
+	m.searchLimits = append(m.searchLimits, limit)
+	var results []tracker.AnimeInfo
+	for _, info := range m.anime {
+		results = append(results, info)
+	}
+	return results, nil
+}
+
+func (m *mockTracker) GetAnimeDetails(ctx context.Context, id string) (*tracker.AnimeInfo, error) {
+	m.calls++
+	info, ok := m.anime[id]
+	if !ok {
+		return nil, database.ErrAnimeNotFound
+	}
+	return &info, nil
+}
+
+func (m *mockTracker) GetUserAnimeList(ctx context.Context) ([]tracker.UserAnimeEntry, error) {
+	m.calls++
+	return m.list, m.listErr
+}
+
+func (m *mockTracker) GetListEntry(ctx context.Context, id string) (*tracker.UserAnimeEntry, error) {
+	m.calls++
+	for i := range m.list {
+		if m.list[i].ID == id {
+			entry := m.list[i]
+			return &entry, nil
+		}
+	}
+	return nil, tracker.ErrNotInList
+}
+
+func (m *mockTracker) UpdateAnimeStatus(ctx context.Context, id string, status tracker.Status, episode float64, score float64) error {
+	m.calls++
+	if m.updateErr != nil {
+		return m.updateErr
+	}
+	m.updates = append(m.updates, statusUpdate{ID: id, Status: status, Episode: episode, Score: score})
+	return nil
+}
+
+func (m *mockTracker) FinishRewatch(ctx context.Context, id string) error {
+	m.calls++
+	if m.updateErr != nil {
+		return m.updateErr
+	}
+	m.rewatches = append(m.rewatches, id)
+	return nil
+}
+
+func (m *mockTracker) DeleteRemoteEntry(ctx context.Context, id string) error {
+	m.calls++
+	for i := range m.list {
+		if m.list[i].ID == id {
+			m.list = append(m.list[:i], m.list[i+1:]...)
+			return nil
+		}
+	}
+	return tracker.ErrNotInList
+}
+
+func (m *mockTracker) SyncFromRemote(ctx context.Context, db *database.DB) (tracker.SyncStats, error) {
+	m.calls++
+	return tracker.SyncStats{}, nil
+}
+
+func (m *mockTracker) SyncToRemote(ctx context.Context, db *database.DB) (tracker.SyncStats, error) {
+	m.calls++
+	return tracker.SyncStats{}, nil
+}
+
+// setupTestApp creates an App backed by a temporary database
+func setupTestApp(t *testing.T, trackers ...tracker.Tracker) (*App, *database.DB) {
+	db, err := database.New(filepath.Join(t.TempDir(), "pair-test.db"))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	app := &App{
+		ctx:        context.Background(),
+		trackerMgr: tracker.NewTrackerManager(db),
+		config:     &config.Config{},
+		db:         db,
+	}
+	app.config.Tracking.AnilistEnabled = true
+	app.config.Tracking.MALEnabled = true
+	for _, tr := range trackers {
+		app.trackerMgr.RegisterTracker(tr)
+	}
+
+	return app, db
+}
+
+func TestAddAnimeToList(t *testing.T) {
+	mock := newMockTracker("anilist")
+	mock.anime["101"] = tracker.AnimeInfo{ID: "101", Title: "Test Anime", Episodes: 12}
+	app, db := setupTestApp(t, mock)
+	app.config.Tracking.Service = config.TrackerAnilist
+
+	ctx := context.Background()
+
+	// Search the active tracker like the menu flow does
+	active, err := app.activeTracker()
+	if err != nil {
+		t.Fatalf("Failed to get active tracker: %v", err)
+	}
+	results, err := active.SearchAnime(ctx, "test", 20)
+	if err != nil {
+		t.Fatalf("Failed to search anime: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Expected 1 search result, got %d", len(results))
+	}
+
+	// Test adding the selected result
+	if err := app.addAnimeToList(ctx, db, active, &results[0], tracker.StatusPlanToWatch); err != nil {
+		t.Fatalf("Failed to add anime to list: %v", err)
+	}
+
+	// Verify the tracker received the new entry
+	if len(mock.updates) != 1 {
+		t.Fatalf("Expected 1 status update, got %d", len(mock.updates))
+	}
+	if mock.updates[0].ID != "101" || mock.updates[0].Status != tracker.StatusPlanToWatch {
+		t.Errorf("Expected update for 101 with plan_to_watch, got %+v", mock.updates[0])
+	}
+
+	// Verify the anime was stored locally
+	anime, err := db.GetAnimeByExternalID("101", "anilist")
+	if err != nil {
+		t.Fatalf("Failed to get anime by external ID: %v", err)
+	}
+	if anime.Title != "Test Anime" {
+		t.Errorf("Expected title 'Test Anime', got '%s'", anime.Title)
+	}
+
+	tracking, err := db.GetAnimeTracking(anime.ID, "anilist")
+	if err != nil {
+		t.Fatalf("Failed to get tracking info: %v", err)
+	}
+	if tracking.Status != string(tracker.StatusPlanToWatch) {
+		t.Errorf("Expected status 'plan_to_watch', got '%s'", tracking.Status)
+	}
+}
+
+func TestCreateCustomAnime(t *testing.T) {
+	app, db := setupTestApp(t)
+
+	// Test validation of required and numeric fields
+	invalid := []customAnimeInput{
+		{Title: "   "},
+		{Title: "Show", Episodes: "twelve"},
+		{Title: "Show", Episodes: "-1"},
+		{Title: "Show", Episodes: "0"},
+		{Title: "Show", Year: "20x4"},
+	}
+	for _, input := range invalid {
+		if _, err := parseCustomAnime(input); err == nil {
+			t.Errorf("Expected validation error for %+v", input)
+		}
+	}
+
+	anime, err := parseCustomAnime(customAnimeInput{Title: " Indie Show ", Episodes: "8", Year: "2024"})
+	if err != nil {
+		t.Fatalf("Failed to parse custom anime: %v", err)
+	}
+	if anime.Title != "Indie Show" || anime.TotalEpisodes != 8 || anime.Year != 2024 || anime.Type != "TV" {
+		t.Errorf("Unexpected parsed anime: %+v", anime)
+	}
+
+	// Test the creation path
+	if err := app.createCustomAnime(context.Background(), db, anime); err != nil {
+		t.Fatalf("Failed to create custom anime: %v", err)
+	}
+
+	retrieved, err := db.GetAnimeByID(anime.ID)
+	if err != nil {
+		t.Fatalf("Failed to get anime by ID: %v", err)
+	}
+	if retrieved.Title != "Indie Show" {
+		t.Errorf("Expected title 'Indie Show', got '%s'", retrieved.Title)
+	}
+
+	tracking, err := db.GetAnimeTracking(anime.ID, "local")
+	if err != nil {
+		t.Fatalf("Failed to get local tracking: %v", err)
+	}
+	if tracking.Status != string(tracker.StatusWatching) {
+		t.Errorf("Expected status 'watching', got '%s'", tracking.Status)
+	}
+
+	// Verify it shows up as currently watching
+	watching, err := db.GetWatchingAnime()
+	if err != nil {
+		t.Fatalf("Failed to get watching anime: %v", err)
+	}
+	if len(watching) != 1 || watching[0].ID != anime.ID {
+		t.Errorf("Expected custom anime in watching list, got %d entries", len(watching))
+	}
+}
+
+// writeFakeExtension writes a shell script that answers every scraper command with output
+func writeFakeExtension(t *testing.T, output string) string {
+	path := filepath.Join(t.TempDir(), "fake-extension")
+	script := "#!/bin/sh\ncat <<'EOF'\n" + output + "\nEOF\n"
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write fake extension: %v", err)
+	}
+	return path
+}
+
+// addFakeSource registers an extension and source backed by the binary at path
+func addFakeSource(t *testing.T, db *database.DB, path string) *database.Source {
+	ext := &database.Extension{Name: "Fake", Package: "fake", Language: "en", Version: "1.0", Path: path}
+	if err := db.AddExtension(ext); err != nil {
+		t.Fatalf("Failed to add extension: %v", err)
+	}
+	source := &database.Source{SourceID: "fake-source", ExtensionID: ext.ID, Name: "Fake Source", Language: "en", BaseURL: "https://example.com"}
+	if err := db.AddSource(source); err != nil {
+		t.Fatalf("Failed to add source: %v", err)
+	}
+	return source
+}
+
+func TestImportEpisodes(t *testing.T) {
+	app, db := setupTestApp(t)
+
+	path := writeFakeExtension(t, `{"status": "success", "data": [
+		{"anime_id": "ep-1", "name": "Beginnings", "episode_number": 1},
+		{"anime_id": "ep-2", "name": "Journey", "episode_number": 2},
+		{"anime_id": "ep-2b", "name": "Journey", "episode_number": 2},
+		{"anime_id": "ep-2.5", "name": "Recap", "episode_number": 2.5},
+		{"anime_id": "ep-x", "name": "Trailer", "episode_number": -1}
+	]}`)
+	source := addFakeSource(t, db, path)
+
+	anime := &database.Anime{Title: "Test Anime", TotalEpisodes: 2}
+	if err := db.AddAnime(anime); err != nil {
+		t.Fatalf("Failed to add anime: %v", err)
+	}
+	if err := db.AddAnimeSource(&database.AnimeSource{AnimeID: anime.ID, SourceID: source.ID, SourceAnimeID: "test-anime"}); err != nil {
+		t.Fatalf("Failed to map anime to source: %v", err)
+	}
+
+	// Test importing the episode list
+	count, err := app.importEpisodes(context.Background(), anime.ID, source.SourceID)
+	if err != nil {
+		t.Fatalf("Failed to import episodes: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("Expected 3 episodes imported, got %d", count)
+	}
+
+	episodes, err := db.GetAllEpisodes(anime.ID)
+	if err != nil {
+		t.Fatalf("Failed to get episodes: %v", err)
+	}
+	if len(episodes) != 3 {
+		t.Fatalf("Expected 3 episodes, got %d", len(episodes))
+	}
+	if episodes[2].Number != 2.5 || episodes[2].Title != "Recap" {
+		t.Errorf("Expected episode 2.5 'Recap', got %v '%s'", episodes[2].Number, episodes[2].Title)
+	}
+
+	// Test that a second import doesn't duplicate existing rows
+	count, err = app.importEpisodes(context.Background(), anime.ID, source.SourceID)
+	if err != nil {
+		t.Fatalf("Failed to re-import episodes: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("Expected no episodes on re-import, got %d", count)
+	}
+}
+
+func TestImportEpisodesMarksNew(t *testing.T) {
+	app, db := setupTestApp(t)
+
+	path := writeFakeExtension(t, `{"status": "success", "data": [
+		{"anime_id": "ep-1", "name": "Beginnings", "episode_number": 1},
+		{"anime_id": "ep-2", "name": "Journey", "episode_number": 2},
+		{"anime_id": "ep-3", "name": "Arrival", "episode_number": 3}
+	]}`)
+	source := addFakeSource(t, db, path)
+
+	anime := &database.Anime{Title: "Airing Anime", TotalEpisodes: 12}
+	if err := db.AddAnime(anime); err != nil {
+		t.Fatalf("Failed to add anime: %v", err)
+	}
+	if err := db.AddAnimeSource(&database.AnimeSource{AnimeID: anime.ID, SourceID: source.ID, SourceAnimeID: "test-anime"}); err != nil {
+		t.Fatalf("Failed to map anime to source: %v", err)
+	}
+	if err := db.AddEpisode(&database.Episode{AnimeID: anime.ID, Number: 1, Title: "Beginnings"}); err != nil {
+		t.Fatalf("Failed to add episode: %v", err)
+	}
+
+	if _, err := app.importEpisodes(context.Background(), anime.ID, source.SourceID); err != nil {
+		t.Fatalf("Failed to import episodes: %v", err)
+	}
+
+	// Test that the episodes released since the last fetch are new
+	fresh, err := newEpisodes(db, anime.ID)
+	if err != nil {
+		t.Fatalf("Failed to get new episodes: %v", err)
+	}
+	if len(fresh) != 2 || !fresh[2] || !fresh[3] {
+		t.Errorf("Expected episodes 2 and 3 to be new, got %v", fresh)
+	}
+
+	// Test that a started episode is no longer new
+	if err := db.MarkEpisodeWatched(anime.ID, 2, source.SourceID); err != nil {
+		t.Fatalf("Failed to mark episode watched: %v", err)
+	}
+	if fresh, err = newEpisodes(db, anime.ID); err != nil || fresh[2] || !fresh[3] {
+		t.Errorf("Expected only episode 3 to be new, got %v (%v)", fresh, err)
+	}
+
+	// Test that a corrupt list is treated as no new episodes and logged
+	core, logs := observer.New(zapcore.WarnLevel)
+	previous := logger.GetLogger()
+	logger.SetLogger(zap.New(core))
+	defer logger.SetLogger(previous)
+
+	if err := db.SetConfig(newEpisodesKey(anime.ID), "[3,"); err != nil {
+		t.Fatalf("Failed to set config: %v", err)
+	}
+	if fresh, err = newEpisodes(db, anime.ID); err != nil || len(fresh) != 0 {
+		t.Errorf("Expected no new episodes, got %v (%v)", fresh, err)
+	}
+	if logs.FilterMessageSnippet("corrupt new episodes").Len() != 1 {
+		t.Errorf("Expected the corrupt list to be logged")
+	}
+}
+
+func TestImportEpisodesWithOffset(t *testing.T) {
+	app, db := setupTestApp(t)
+
+	// The source numbers the second cour on from the first
+	path := writeFakeExtension(t, `{"status": "success", "data": [
+		{"anime_id": "ep-11", "name": "Last of the first cour", "episode_number": 11},
+		{"anime_id": "ep-13", "name": "Second cour", "episode_number": 13},
+		{"anime_id": "ep-14", "name": "Onwards", "episode_number": 14}
+	]}`)
+	source := addFakeSource(t, db, path)
+
+	anime := &database.Anime{Title: "Season 2", TotalEpisodes: 12}
+	if err := db.AddAnime(anime); err != nil {
+		t.Fatalf("Failed to add anime: %v", err)
+	}
+	if err := db.AddAnimeSource(&database.AnimeSource{AnimeID: anime.ID, SourceID: source.ID, SourceAnimeID: "test-anime", EpisodeOffset: 12}); err != nil {
+		t.Fatalf("Failed to map anime to source: %v", err)
+	}
+
+	if _, err := app.importEpisodes(context.Background(), anime.ID, source.SourceID); err != nil {
+		t.Fatalf("Failed to import episodes: %v", err)
+	}
+
+	// Test that source episodes are stored as numbered by the season
+	episodes, err := db.GetAllEpisodes(anime.ID)
+	if err != nil {
+		t.Fatalf("Failed to get episodes: %v", err)
+	}
+	if len(episodes) != 2 {
+		t.Fatalf("Expected 2 episodes, got %d", len(episodes))
+	}
+	if episodes[0].Number != 1 || episodes[0].Title != "Second cour" || episodes[1].Number != 2 {
+		t.Errorf("Expected episodes 1 and 2 of the season, got %v and %v", episodes[0].Number, episodes[1].Number)
+	}
+
+	// Test that the mapping used for playback converts back to the source
+	_, mapping, err := app.sourceScraper(anime.ID, source.SourceID)
+	if err != nil {
+		t.Fatalf("Failed to get source scraper: %v", err)
+	}
+	if got := mapping.SourceEpisode(1); got != 13 {
+		t.Errorf("Expected episode 1 to be played as source episode 13, got %v", got)
+	}
+}
+
+func TestParseEpisodeOffset(t *testing.T) {
+	// Test that blank means no offset and numbers are taken as typed
+	for input, want := range map[string]float64{"": 0, "  ": 0, "12": 12, "-1": -1, "12.5": 12.5} {
+		got, err := parseEpisodeOffset(input)
+		if err != nil {
+			t.Fatalf("Failed to parse offset %q: %v", input, err)
+		}
+		if got != want {
+			t.Errorf("Expected offset %v for %q, got %v", want, input, got)
+		}
+	}
+
+	// Test that anything else is rejected
+	for _, input := range []string{"twelve", "NaN", "Inf"} {
+		if _, err := parseEpisodeOffset(input); err == nil {
+			t.Errorf("Expected an error for %q", input)
+		}
+	}
+}
+
+func TestMarkCompleted(t *testing.T) {
+	mock := newMockTracker("anilist")
+	_, db := setupTestApp(t, mock)
+	ctx := context.Background()
+
+	tracked := &database.Anime{Title: "Tracked Anime", TotalEpisodes: 24}
+	untracked := &database.Anime{Title: "Untracked Anime"}
+	for _, anime := range []*database.Anime{tracked, untracked} {
+		if err := db.AddAnime(anime); err != nil {
+			t.Fatalf("Failed to add anime: %v", err)
+		}
+	}
+	tracking := &database.AnimeTracking{AnimeID: tracked.ID, Tracker: "anilist", TrackerID: "101", Status: "watching", CurrentEpisode: 20}
+	if err := db.AddAnimeTracking(tracking); err != nil {
+		t.Fatalf("Failed to add tracking: %v", err)
+	}
+
+	// Test completing an anime with a known episode count and a score
+	if err := markCompleted(ctx, db, mock, tracked.ID, "101", 24, 8); err != nil {
+		t.Fatalf("Failed to mark anime completed: %v", err)
+	}
+
+	// Test completing an anime with an unknown episode count and no score
+	if err := markCompleted(ctx, db, mock, untracked.ID, "102", 0, 0); err != nil {
+		t.Fatalf("Failed to mark anime completed: %v", err)
+	}
+
+	// Test that the local tracking entry is completed too
+	tracking, err := db.GetAnimeTracking(tracked.ID, "anilist")
+	if err != nil {
+		t.Fatalf("Failed to get tracking info: %v", err)
+	}
+	if tracking.Status != string(tracker.StatusCompleted) || tracking.CurrentEpisode != 24 || tracking.Score != 8 {
+		t.Errorf("Expected completed at episode 24 with score 8 locally, got %s at %v with %v",
+			tracking.Status, tracking.CurrentEpisode, tracking.Score)
+	}
+
+	expected := []statusUpdate{
+		{ID: "101", Status: tracker.StatusCompleted, Episode: 24, Score: 8},
+		{ID: "102", Status: tracker.StatusCompleted, Episode: 0, Score: 0},
+	}
+	if len(mock.updates) != len(expected) {
+		t.Fatalf("Expected %d status updates, got %d", len(expected), len(mock.updates))
+	}
+	for i, want := range expected {
+		if mock.updates[i] != want {
+			t.Errorf("Expected update %+v, got %+v", want, mock.updates[i])
+		}
+	}
+}
+
+func TestSetProgress(t *testing.T) {
+	mock := newMockTracker("anilist")
+	_, db := setupTestApp(t, mock)
+	ctx := context.Background()
+
+	anime := &database.Anime{Title: "Test Anime", TotalEpisodes: 12}
+	if err := db.AddAnime(anime); err != nil {
+		t.Fatalf("Failed to add anime: %v", err)
+	}
+	tracking := &database.AnimeTracking{AnimeID: anime.ID, Tracker: "anilist", TrackerID: "101", Status: "watching", CurrentEpisode: 10}
+	if err := db.AddAnimeTracking(tracking); err != nil {
+		t.Fatalf("Failed to add tracking: %v", err)
+	}
+
+	// Test that a mistaken high episode can be corrected downward
+	if err := setProgress(ctx, db, mock, anime.ID, "101", 4); err != nil {
+		t.Fatalf("Failed to set progress: %v", err)
+	}
+	tracking, err := db.GetAnimeTracking(anime.ID, "anilist")
+	if err != nil {
+		t.Fatalf("Failed to get tracking info: %v", err)
+	}
+	if tracking.CurrentEpisode != 4 {
+		t.Errorf("Expected local progress 4, got %v", tracking.CurrentEpisode)
+	}
+	if len(mock.updates) != 1 || mock.updates[0].Episode != 4 {
+		t.Errorf("Expected the tracker to get episode 4, got %+v", mock.updates)
+	}
+
+	// Test that a failed tracker update leaves the local progress alone
+	mock.updateErr = errors.New("server down")
+	if err := setProgress(ctx, db, mock, anime.ID, "101", 8); err == nil {
+		t.Errorf("Expected the tracker error to be returned")
+	}
+	tracking, err = db.GetAnimeTracking(anime.ID, "anilist")
+	if err != nil {
+		t.Fatalf("Failed to get tracking info: %v", err)
+	}
+	if tracking.CurrentEpisode != 4 {
+		t.Errorf("Expected local progress to stay 4, got %v", tracking.CurrentEpisode)
+	}
+}
+
+func TestSetStatusWithNote(t *testing.T) {
+	mock := newMockTracker("anilist")
+	app, db := setupTestApp(t, mock)
+	ctx := context.Background()
+
+	anime := &database.Anime{Title: "Test Anime", TotalEpisodes: 12}
+	if err := db.AddAnime(anime); err != nil {
+		t.Fatalf("Failed to add anime: %v", err)
+	}
+
+	// Test dropping an anime with a reason
+	if err := app.setStatusWithNote(ctx, db, mock, anime.ID, "101", tracker.StatusDropped, " Lost interest "); err != nil {
+		t.Fatalf("Failed to drop anime: %v", err)
+	}
+
+	tracking, err := db.GetAnimeTracking(anime.ID, "anilist")
+	if err != nil {
+		t.Fatalf("Failed to get tracking info: %v", err)
+	}
+	if tracking.Status != string(tracker.StatusDropped) || tracking.Notes != "Lost interest" {
+		t.Errorf("Expected dropped with note 'Lost interest', got %s with '%s'", tracking.Status, tracking.Notes)
+	}
+
+	// Test putting it on hold without a note keeps the previous one
+	if err := app.setStatusWithNote(ctx, db, mock, anime.ID, "101", tracker.StatusOnHold, ""); err != nil {
+		t.Fatalf("Failed to put anime on hold: %v", err)
+	}
+
+	tracking, err = db.GetAnimeTracking(anime.ID, "anilist")
+	if err != nil {
+		t.Fatalf("Failed to get tracking info: %v", err)
+	}
+	if tracking.Status != string(tracker.StatusOnHold) || tracking.Notes != "Lost interest" {
+		t.Errorf("Expected on_hold with note 'Lost interest', got %s with '%s'", tracking.Status, tracking.Notes)
+	}
+
+	if len(mock.updates) != 2 || mock.updates[1].Status != tracker.StatusOnHold {
+		t.Errorf("Expected 2 tracker updates ending with on_hold, got %+v", mock.updates)
+	}
+}
+
+func TestAnnotateTracked(t *testing.T) {
+	mock := newMockTracker("anilist")
+	app, db := setupTestApp(t, mock)
+
+	tracked := tracker.AnimeInfo{ID: "101", Title: "Tracked Show", Episodes: 12}
+	if err := app.addAnimeToList(context.Background(), db, mock, &tracked, tracker.StatusWatching); err != nil {
+		t.Fatalf("Failed to add anime to list: %v", err)
+	}
+
+	results := []tracker.AnimeInfo{
+		{ID: "101", Title: "Tracked Show"},
+		{ID: "202", Title: "New Show"},
+	}
+
+	// Test that only tracked entries are annotated
+	annotated := annotateTracked(db, "anilist", results)
+	if annotated[0].Title != "Tracked Show (in list)" {
+		t.Errorf("Expected 'Tracked Show (in list)', got '%s'", annotated[0].Title)
+	}
+	if annotated[1].Title != "New Show" {
+		t.Errorf("Expected 'New Show', got '%s'", annotated[1].Title)
+	}
+
+	// Verify the results themselves are left untouched
+	if results[0].Title != "Tracked Show" {
+		t.Errorf("Expected original title to be unchanged, got '%s'", results[0].Title)
+	}
+}
+
+func TestHandleAnimeListOffline(t *testing.T) {
+	mock := newMockTracker("anilist")
+	app, _ := setupTestApp(t, mock)
+	app.config.Network.Offline = true
+
+	// Test that listing anime doesn't touch the trackers
+	if err := app.handleAnimeList(context.Background()); err != nil {
+		t.Fatalf("Failed to list anime: %v", err)
+	}
+	if mock.calls != 0 {
+		t.Errorf("Expected no tracker calls, got %d", mock.calls)
+	}
+
+	// Test that the sync runs again once back online
+	app.config.Network.Offline = false
+	if err := app.handleAnimeList(context.Background()); err != nil {
+		t.Fatalf("Failed to list anime: %v", err)
+	}
+	if mock.calls == 0 {
+		t.Errorf("Expected tracker calls when online")
+	}
+}
+
+func TestSyncSkipsUnreachableTracker(t *testing.T) {
+	mock := newMockTracker("anilist")
+	mock.apiURL = "http://127.0.0.1:1"
+	app, db := setupTestApp(t, mock)
+	app.prober = httpclient.NewProber(2*time.Second, time.Minute)
+
+	// Test that an unreachable tracker is skipped without an error
+	start := time.Now()
+	var syncErrors []tracker.SyncError
+	if _, err := app.syncWithTrackers(context.Background(), db, &syncErrors); err != nil {
+		t.Fatalf("Failed to sync with trackers: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected sync to be skipped quickly, took %v", elapsed)
+	}
+	if len(syncErrors) != 0 {
+		t.Errorf("Expected no sync errors, got %v", syncErrors)
+	}
+
+	// Only the authentication check should have reached the tracker
+	if mock.calls != 1 {
+		t.Errorf("Expected 1 tracker call, got %d", mock.calls)
+	}
+}
+
+func TestSyncSkipsDisabledTracker(t *testing.T) {
+	anilist := newMockTracker("anilist")
+	mal := newMockTracker("mal")
+	app, db := setupTestApp(t, anilist, mal)
+	app.config.Tracking.MALEnabled = false
+
+	// Test that MAL is skipped even though it's authenticated
+	var syncErrors []tracker.SyncError
+	if _, err := app.syncWithTrackers(context.Background(), db, &syncErrors); err != nil {
+		t.Fatalf("Failed to sync with trackers: %v", err)
+	}
+	if mal.calls != 0 {
+		t.Errorf("Expected no MyAnimeList calls, got %d", mal.calls)
+	}
+	if anilist.calls == 0 {
+		t.Errorf("Expected Anilist to be synced")
+	}
+}
+
+func TestSettingsMenuResyncItems(t *testing.T) {
+	app, _ := setupTestApp(t, newMockTracker("anilist"))
+
+	// Test that only registered trackers get a resync item
+	values := make(map[string]bool)
+	for _, item := range app.setupSettingsMenu().Items {
+		values[item.Value] = true
+	}
+	if !values["resync_anilist"] {
+		t.Errorf("Expected a resync item for anilist")
+	}
+	if values["resync_mal"] {
+		t.Errorf("Expected no resync item for the unregistered mal tracker")
+	}
+}
+
+func TestSyncErrorsKeepContext(t *testing.T) {
+	mock := newMockTracker("anilist")
+	mock.list = []tracker.UserAnimeEntry{{
+		AnimeInfo:   tracker.AnimeInfo{ID: "401", Title: "Behind Remotely", Episodes: 12},
+		Status:      tracker.StatusWatching,
+		Progress:    3,
+		LastUpdated: time.Now().Add(-time.Hour),
+	}}
+	mock.updateErr = errors.New("server error")
+	app, db := setupTestApp(t, mock)
+
+	anime := &database.Anime{Title: "Behind Remotely", TotalEpisodes: 12}
+	if err := db.AddAnime(anime); err != nil {
+		t.Fatalf("Failed to add anime: %v", err)
+	}
+	if err := db.AddAnimeTracking(&database.AnimeTracking{AnimeID: anime.ID, Tracker: "anilist", TrackerID: "401", Status: "watching", CurrentEpisode: 8, LastUpdated: time.Now()}); err != nil {
+		t.Fatalf("Failed to add tracking: %v", err)
+	}
+
+	// Test that a failed push is recorded with its tracker, anime and operation
+	var syncErrors []tracker.SyncError
+	stats, err := app.syncWithTrackers(context.Background(), db, &syncErrors)
+	if err != nil {
+		t.Fatalf("Failed to sync with trackers: %v", err)
+	}
+	if len(syncErrors) != 1 {
+		t.Fatalf("Expected 1 sync error, got %v", syncErrors)
+	}
+	syncErr := syncErrors[0]
+	if syncErr.Tracker != "anilist" || syncErr.Title != "Behind Remotely" || syncErr.Op != "process remote entry" {
+		t.Errorf("Expected the error of Behind Remotely on anilist, got %+v", syncErr)
+	}
+	if !errors.Is(syncErr, mock.updateErr) {
+		t.Errorf("Expected the tracker error to be wrapped, got %v", syncErr.Err)
+	}
+	if stats["Anilist"].Errors != 1 {
+		t.Errorf("Expected 1 error counted, got %+v", stats["Anilist"])
+	}
+}
+
+func TestSyncExistingEntryConfirmsRemoteState(t *testing.T) {
+	mock := newMockTracker("anilist")
+	app, db := setupTestApp(t, mock)
+
+	anime := &database.Anime{Title: "Test Anime", TotalEpisodes: 12}
+	if err := db.AddAnime(anime); err != nil {
+		t.Fatalf("Failed to add anime: %v", err)
+	}
+
+	fetched := time.Now().Add(-2 * time.Hour)
+	local := &database.AnimeTracking{
+		AnimeID:        anime.ID,
+		Tracker:        "anilist",
+		TrackerID:      "101",
+		Status:         string(tracker.StatusWatching),
+		CurrentEpisode: 5,
+		LastUpdated:    fetched.Add(time.Hour),
+	}
+	if err := db.AddAnimeTracking(local); err != nil {
+		t.Fatalf("Failed to add tracking: %v", err)
+	}
+
+	// The remote entry moved on after the list was fetched
+	mock.list = []tracker.UserAnimeEntry{{
+		AnimeInfo:   tracker.AnimeInfo{ID: "101", Title: "Test Anime", Episodes: 12},
+		Status:      tracker.StatusWatching,
+		Progress:    8,
+		LastUpdated: time.Now().Truncate(time.Second),
+	}}
+	stale := &tracker.UserAnimeEntry{
+		AnimeInfo:   tracker.AnimeInfo{ID: "101", Title: "Test Anime", Episodes: 12},
+		Status:      tracker.StatusWatching,
+		Progress:    3,
+		LastUpdated: fetched,
+	}
+
+	localMap := map[string]*database.AnimeTracking{"101": local}
+	var stats tracker.SyncStats
+	if err := app.syncExistingEntry(context.Background(), db, anime, stale, "anilist", localMap, &stats); err != nil {
+		t.Fatalf("Failed to sync entry: %v", err)
+	}
+	if stats.Updated != 1 {
+		t.Errorf("Expected 1 updated entry, got %d", stats.Updated)
+	}
+
+	// Test that the newer remote progress wasn't overwritten
+	if len(mock.updates) != 0 {
+		t.Errorf("Expected no remote updates, got %+v", mock.updates)
+	}
+
+	tracking, err := db.GetAnimeTracking(anime.ID, "anilist")
+	if err != nil {
+		t.Fatalf("Failed to get tracking: %v", err)
+	}
+	if tracking.CurrentEpisode != 8 {
+		t.Errorf("Expected local progress 8 from the remote, got %v", tracking.CurrentEpisode)
+	}
+}
+
+func TestSyncExistingEntryFractionalProgress(t *testing.T) {
+	mock := newMockTracker("anilist")
+	app, db := setupTestApp(t, mock)
+
+	anime := &database.Anime{Title: "Test Anime", TotalEpisodes: 24}
+	if err := db.AddAnime(anime); err != nil {
+		t.Fatalf("Failed to add anime: %v", err)
+	}
+
+	pushed := time.Now().Add(-time.Hour).Truncate(time.Second)
+	local := &database.AnimeTracking{
+		AnimeID:        anime.ID,
+		Tracker:        "anilist",
+		TrackerID:      "101",
+		Status:         string(tracker.StatusWatching),
+		CurrentEpisode: 12.5,
+		LastUpdated:    pushed.Add(time.Minute),
+	}
+	if err := db.AddAnimeTracking(local); err != nil {
+		t.Fatalf("Failed to add tracking: %v", err)
+	}
+	localMap := map[string]*database.AnimeTracking{"101": local}
+
+	// Test that the recap isn't pushed again when the remote has episode 12
+	remote := &tracker.UserAnimeEntry{
+		AnimeInfo:   tracker.AnimeInfo{ID: "101", Title: "Test Anime", Episodes: 24},
+		Status:      tracker.StatusWatching,
+		Progress:    12,
+		LastUpdated: pushed,
+	}
+	var stats tracker.SyncStats
+	if err := app.syncExistingEntry(context.Background(), db, anime, remote, "anilist", localMap, &stats); err != nil {
+		t.Fatalf("Failed to sync entry: %v", err)
+	}
+	if len(mock.updates) != 0 {
+		t.Errorf("Expected no remote updates, got %+v", mock.updates)
+	}
+
+	// Test that a newer remote at episode 12 doesn't clobber local 12.5
+	remote.LastUpdated = time.Now().Truncate(time.Second)
+	if err := app.syncExistingEntry(context.Background(), db, anime, remote, "anilist", localMap, &stats); err != nil {
+		t.Fatalf("Failed to sync entry: %v", err)
+	}
+	tracking, err := db.GetAnimeTracking(anime.ID, "anilist")
+	if err != nil {
+		t.Fatalf("Failed to get tracking: %v", err)
+	}
+	if tracking.CurrentEpisode != 12.5 {
+		t.Errorf("Expected local progress 12.5 to be kept, got %v", tracking.CurrentEpisode)
+	}
+}
+
+func TestSyncExistingEntryLogsScoreConflict(t *testing.T) {
+	mock := newMockTracker("anilist")
+	app, db := setupTestApp(t, mock)
+
+	anime := &database.Anime{Title: "Test Anime", TotalEpisodes: 12}
+	if err := db.AddAnime(anime); err != nil {
+		t.Fatalf("Failed to add anime: %v", err)
+	}
+
+	local := &database.AnimeTracking{
+		AnimeID:        anime.ID,
+		Tracker:        "anilist",
+		TrackerID:      "101",
+		Status:         string(tracker.StatusWatching),
+		Score:          9,
+		CurrentEpisode: 5,
+		LastUpdated:    time.Now().Add(-time.Hour).Truncate(time.Second),
+	}
+	if err := db.AddAnimeTracking(local); err != nil {
+		t.Fatalf("Failed to add tracking: %v", err)
+	}
+
+	// The remote was scored differently later on
+	remote := &tracker.UserAnimeEntry{
+		AnimeInfo:   tracker.AnimeInfo{ID: "101", Title: "Test Anime", Episodes: 12},
+		Status:      tracker.StatusWatching,
+		Score:       6,
+		Progress:    5,
+		LastUpdated: time.Now().Truncate(time.Second),
+	}
+	localMap := map[string]*database.AnimeTracking{"101": local}
+	var stats tracker.SyncStats
+
+	// Test that nothing is logged when only the remote changed since the last sync
+	setLastSync := func(at time.Time) {
+		if err := db.SetConfig(database.LastSyncKey("anilist"), at.Format(time.RFC3339)); err != nil {
+			t.Fatalf("Failed to set last sync: %v", err)
+		}
+	}
+	setLastSync(time.Now().Add(-30 * time.Minute))
+	if err := app.syncExistingEntry(context.Background(), db, anime, remote, "anilist", localMap, &stats); err != nil {
+		t.Fatalf("Failed to sync entry: %v", err)
+	}
+	if conflicts, err := db.GetSyncConflicts(10); err != nil || len(conflicts) != 0 {
+		t.Fatalf("Expected no conflicts for a one-sided change, got %d (%v)", len(conflicts), err)
+	}
+
+	// Both sides changed since the last sync
+	setLastSync(time.Now().Add(-2 * time.Hour))
+	if err := app.syncExistingEntry(context.Background(), db, anime, remote, "anilist", localMap, &stats); err != nil {
+		t.Fatalf("Failed to sync entry: %v", err)
+	}
+
+	// Test that the overwritten score is logged with both values
+	conflicts, err := db.GetSyncConflicts(10)
+	if err != nil {
+		t.Fatalf("Failed to get sync conflicts: %v", err)
+	}
+	if len(conflicts) != 1 {
+		t.Fatalf("Expected 1 conflict, got %d", len(conflicts))
+	}
+	c := conflicts[0]
+	if c.Field != "score" || c.LocalValue != "9" || c.RemoteValue != "6" || c.Winner != database.ConflictRemote {
+		t.Errorf("Expected score conflict 9 vs 6 won by remote, got %+v", c)
+	}
+	if c.AnimeTitle != "Test Anime" || c.Tracker != "anilist" {
+		t.Errorf("Expected conflict for Test Anime on anilist, got %+v", c)
+	}
+}
+
+func TestOpenAnimePage(t *testing.T) {
+	app, db := setupTestApp(t)
+	app.config.Tracking.Service = config.TrackerAnilist
+
+	anime := &database.Anime{Title: "Linked"}
+	if err := db.AddAnime(anime); err != nil {
+		t.Fatalf("Failed to add anime: %v", err)
+	}
+	if err := db.AddAnimeTracking(&database.AnimeTracking{AnimeID: anime.ID, Tracker: "mal", TrackerID: "5114", LastUpdated: time.Now()}); err != nil {
+		t.Fatalf("Failed to add tracking: %v", err)
+	}
+
+	var opened []string
+	previous := openURL
+	openURL = func(url string) error {
+		opened = append(opened, url)
+		return nil
+	}
+	defer func() { openURL = previous }()
+
+	// Test that another tracker's page is used when the active one isn't linked
+	if err := app.openAnimePage(db, anime.ID); err != nil {
+		t.Fatalf("Failed to open anime page: %v", err)
+	}
+
+	// Test that the active tracker's page is preferred
+	if err := db.AddAnimeTracking(&database.AnimeTracking{AnimeID: anime.ID, Tracker: "anilist", TrackerID: "21", LastUpdated: time.Now()}); err != nil {
+		t.Fatalf("Failed to add tracking: %v", err)
+	}
+	if err := app.openAnimePage(db, anime.ID); err != nil {
+		t.Fatalf("Failed to open anime page: %v", err)
+	}
+
+	expected := []string{"https://myanimelist.net/anime/5114", "https://anilist.co/anime/21"}
+	if len(opened) != len(expected) {
+		t.Fatalf("Expected %d pages opened, got %v", len(expected), opened)
+	}
+	for i, url := range expected {
+		if opened[i] != url {
+			t.Errorf("Expected page %d to be %s, got %s", i, url, opened[i])
+		}
+	}
+
+	// Test that an anime without tracker pages is reported
+	unlinked := &database.Anime{Title: "Unlinked"}
+	if err := db.AddAnime(unlinked); err != nil {
+		t.Fatalf("Failed to add anime: %v", err)
+	}
+	if err := app.openAnimePage(db, unlinked.ID); err == nil {
+		t.Errorf("Expected an error for an anime without tracker links")
+	}
+}
+
+func TestRemoveFromLibrary(t *testing.T) {
+	anilist := newMockTracker("anilist")
+	anilist.list = []tracker.UserAnimeEntry{{AnimeInfo: tracker.AnimeInfo{ID: "101", Title: "Removed Show"}}}
+	mal := newMockTracker("mal")
+	app, db := setupTestApp(t, anilist, mal)
+	app.config.Tracking.MALEnabled = false
+
+	anime := &database.Anime{Title: "Removed Show", TotalEpisodes: 12}
+	if err := db.AddAnime(anime); err != nil {
+		t.Fatalf("Failed to add anime: %v", err)
+	}
+	for _, tr := range []string{"anilist", "mal"} {
+		if err := db.AddAnimeTracking(&database.AnimeTracking{AnimeID: anime.ID, Tracker: tr, TrackerID: "101", Status: "watching", LastUpdated: time.Now()}); err != nil {
+			t.Fatalf("Failed to add tracking: %v", err)
+		}
+	}
+
+	// Test that remote entries are kept unless configured
+	if err := app.removeFromLibrary(context.Background(), db, anime.ID); err != nil {
+		t.Fatalf("Failed to remove anime: %v", err)
+	}
+	if len(anilist.list) != 1 {
+		t.Errorf("Expected the Anilist entry to be kept, got %d entries", len(anilist.list))
+	}
+	if _, err := db.GetAnime(anime.ID); !errors.Is(err, database.ErrAnimeNotFound) {
+		t.Errorf("Expected anime to be removed, got %v", err)
+	}
+
+	// Test that enabled trackers lose their entry when configured
+	app.config.Tracking.DeleteRemoteOnRemove = true
+	anime.ID = 0
+	if err := db.AddAnime(anime); err != nil {
+		t.Fatalf("Failed to add anime: %v", err)
+	}
+	for _, tr := range []string{"anilist", "mal"} {
+		if err := db.AddAnimeTracking(&database.AnimeTracking{AnimeID: anime.ID, Tracker: tr, TrackerID: "101", Status: "watching", LastUpdated: time.Now()}); err != nil {
+			t.Fatalf("Failed to add tracking: %v", err)
+		}
+	}
+	mal.calls = 0
+	if err := app.removeFromLibrary(context.Background(), db, anime.ID); err != nil {
+		t.Fatalf("Failed to remove anime: %v", err)
+	}
+	if len(anilist.list) != 0 {
+		t.Errorf("Expected the Anilist entry to be deleted, got %d entries", len(anilist.list))
+	}
+	if mal.calls != 0 {
+		t.Errorf("Expected the disabled MAL tracker to be left alone, got %d calls", mal.calls)
+	}
+	if trackings, err := db.GetAllAnimeTracking(anime.ID); err != nil || len(trackings) != 0 {
+		t.Errorf("Expected no tracking left, got %d (%v)", len(trackings), err)
+	}
+}
+
+func TestBackfillMetadata(t *testing.T) {
+	mock := newMockTracker("anilist")
+	mock.anime["101"] = tracker.AnimeInfo{
+		ID: "101", Title: "Sparse Show", Synopsis: "A long story", Type: "TV",
+		Genres: []string{"Drama"}, ImageURL: "https://example.com/cover.jpg", Episodes: 24,
+	}
+	app, db := setupTestApp(t, mock)
+	app.config.Tracking.Service = config.TrackerAnilist
+
+	sparse := &database.Anime{Title: "Sparse Show", Type: "Movie"}
+	if err := db.AddAnime(sparse); err != nil {
+		t.Fatalf("Failed to add anime: %v", err)
+	}
+	if err := db.AddAnimeTracking(&database.AnimeTracking{AnimeID: sparse.ID, Tracker: "anilist", TrackerID: "101", Status: "watching", LastUpdated: time.Now()}); err != nil {
+		t.Fatalf("Failed to add tracking: %v", err)
+	}
+
+	// An anime without a tracker ID can't be looked up
+	unlinked := &database.Anime{Title: "Unlinked Show"}
+	if err := db.AddAnime(unlinked); err != nil {
+		t.Fatalf("Failed to add anime: %v", err)
+	}
+
+	filled, err := app.BackfillMetadata(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to backfill metadata: %v", err)
+	}
+	if filled != 1 {
+		t.Errorf("Expected 1 anime to be filled in, got %d", filled)
+	}
+
+	// Test that blanks are filled and existing values are kept
+	got, err := db.GetAnime(sparse.ID)
+	if err != nil {
+		t.Fatalf("Failed to get anime: %v", err)
+	}
+	if got.Description != "A long story" || got.ThumbnailURL != "https://example.com/cover.jpg" || got.TotalEpisodes != 24 {
+		t.Errorf("Expected blanks to be filled, got %+v", got)
+	}
+	if len(got.Genres) != 1 || got.Genres[0] != "Drama" {
+		t.Errorf("Expected genres [Drama], got %v", got.Genres)
+	}
+	if got.Type != "Movie" {
+		t.Errorf("Expected type 'Movie' to be kept, got '%s'", got.Type)
+	}
+
+	// Test that a second run has nothing left to do
+	if filled, err := app.BackfillMetadata(context.Background()); err != nil || filled != 0 {
+		t.Errorf("Expected nothing to fill on the second run, got %d (%v)", filled, err)
+	}
+}
+
+func TestReauthOnExpiredSession(t *testing.T) {
+	mock := newMockTracker("anilist")
+	mock.updateErr = fmt.Errorf("token refresh failed: no refresh token available: %w", tracker.ErrAuthExpired)
+	app, _ := setupTestApp(t, mock)
+	app.config.Tracking.Service = config.TrackerAnilist
+
+	var prompts []string
+	answer := true
+	previous := showConfirm
+	showConfirm = func(prompt string) (bool, error) {
+		prompts = append(prompts, prompt)
+		return answer, nil
+	}
+	defer func() { showConfirm = previous }()
+
+	ctx := context.Background()
+	mock.authenticated = false
+
+	// Test that a failed refresh prompts and logs in again
+	err := app.handleTrackerError(ctx, mock.UpdateAnimeStatus(ctx, "1", tracker.StatusWatching, 1, 0))
+	if err != nil {
+		t.Fatalf("Failed to log in again: %v", err)
+	}
+	if len(prompts) != 1 || prompts[0] != "Your Anilist session expired — log in again?" {
+		t.Errorf("Expected the re-auth prompt, got %v", prompts)
+	}
+	if !mock.authenticated {
+		t.Error("Expected Authenticate to be called")
+	}
+
+	// Test that declining keeps the original error
+	answer = false
+	mock.authenticated = false
+	err = app.handleTrackerError(ctx, mock.UpdateAnimeStatus(ctx, "1", tracker.StatusWatching, 1, 0))
+	if !errors.Is(err, tracker.ErrAuthExpired) {
+		t.Errorf("Expected ErrAuthExpired, got %v", err)
+	}
+	if mock.authenticated {
+		t.Error("Expected no login after declining")
+	}
+
+	// Test that other errors don't prompt
+	prompts = nil
+	other := errors.New("boom")
+	if err := app.handleTrackerError(ctx, other); err != other {
+		t.Errorf("Expected the error unchanged, got %v", err)
+	}
+	if len(prompts) != 0 {
+		t.Errorf("Expected no prompt, got %v", prompts)
+	}
+
+	// Test the exit status of non-interactive commands
+	if code := ExitCode(mock.updateErr); code != ExitAuthExpired {
+		t.Errorf("Expected exit code %d, got %d", ExitAuthExpired, code)
+	}
+	if code := ExitCode(other); code != 1 {
+		t.Errorf("Expected exit code 1, got %d", code)
+	}
+}
+
+func TestSyncAddsRemoteOnlyWatchingEntry(t *testing.T) {
+	mock := newMockTracker("anilist")
+	mock.list = []tracker.UserAnimeEntry{{
+		AnimeInfo:   tracker.AnimeInfo{ID: "301", Title: "Remote Only", Episodes: 24},
+		Status:      tracker.StatusWatching,
+		Progress:    5,
+		LastUpdated: time.Now(),
+	}}
+	app, db := setupTestApp(t, mock)
+	app.config.Tracking.Service = config.TrackerAnilist
+
+	// Test that the currently watching sync creates the anime before its tracking
+	var syncErrors []tracker.SyncError
+	if _, err := app.syncWithTrackers(context.Background(), db, &syncErrors); err != nil {
+		t.Fatalf("Failed to sync with trackers: %v", err)
+	}
+	if len(syncErrors) != 0 {
+		t.Fatalf("Expected no sync errors, got %v", syncErrors)
+	}
+
+	// Test that the sync is recorded for the sync status menu
+	if last, err := db.GetLastSync("anilist"); err != nil || last.IsZero() {
+		t.Errorf("Expected the last sync time to be recorded, got %v (%v)", last, err)
+	}
+
+	anime, err := db.GetAnimeByExternalID("301", "anilist")
+	if err != nil {
+		t.Fatalf("Failed to get synced anime: %v", err)
+	}
+	if anime.Title != "Remote Only" {
+		t.Errorf("Expected title Remote Only, got %s", anime.Title)
+	}
+	tracking, err := db.GetAnimeTracking(anime.ID, "anilist")
+	if err != nil {
+		t.Fatalf("Failed to get tracking: %v", err)
+	}
+	if tracking.CurrentEpisode != 5 || tracking.Status != string(tracker.StatusWatching) {
+		t.Errorf("Expected watching at episode 5, got %s at %g", tracking.Status, tracking.CurrentEpisode)
+	}
+
+	// Test that the entry is listed as currently watching without being pushed back
+	watching, err := db.GetCurrentlyWatchingAnime()
+	if err != nil {
+		t.Fatalf("Failed to get watching anime: %v", err)
+	}
+	if len(watching) != 1 || watching[0].ID != anime.ID {
+		t.Errorf("Expected the synced anime to be watching, got %+v", watching)
+	}
+	if len(mock.updates) != 0 {
+		t.Errorf("Expected no updates sent to the tracker, got %+v", mock.updates)
+	}
+
+	// Test that syncing again doesn't insert the anime twice
+	if _, err := app.syncWithTrackers(context.Background(), db, &syncErrors); err != nil {
+		t.Fatalf("Failed to sync with trackers: %v", err)
+	}
+	all, err := db.GetAllAnime()
+	if err != nil {
+		t.Fatalf("Failed to get anime: %v", err)
+	}
+	if len(all) != 1 {
+		t.Errorf("Expected 1 anime after syncing twice, got %d", len(all))
+	}
+}
+
+func TestSyncMatchesAnimeByMalID(t *testing.T) {
+	anilist := newMockTracker("anilist")
+	anilist.list = []tracker.UserAnimeEntry{{
+		AnimeInfo:   tracker.AnimeInfo{ID: "301", Title: "Shared Show", Episodes: 12, MalID: "90"},
+		Status:      tracker.StatusWatching,
+		Progress:    2,
+		LastUpdated: time.Now(),
+	}}
+	mal := newMockTracker("mal")
+	mal.list = []tracker.UserAnimeEntry{{
+		AnimeInfo:   tracker.AnimeInfo{ID: "90", Title: "Shared Show", Episodes: 12},
+		Status:      tracker.StatusWatching,
+		Progress:    2,
+		LastUpdated: time.Now(),
+	}}
+	app, db := setupTestApp(t, anilist, mal)
+
+	// Test that the MAL entry joins the anime Anilist added instead of adding it again
+	var syncErrors []tracker.SyncError
+	if _, err := app.syncWithTrackers(context.Background(), db, &syncErrors); err != nil {
+		t.Fatalf("Failed to sync with trackers: %v", err)
+	}
+	if len(syncErrors) != 0 {
+		t.Fatalf("Expected no sync errors, got %v", syncErrors)
+	}
+	all, err := db.GetAllAnime()
+	if err != nil {
+		t.Fatalf("Failed to get anime: %v", err)
+	}
+	if len(all) != 1 {
+		t.Fatalf("Expected 1 anime for both trackers, got %d", len(all))
+	}
+	if _, err := db.GetAnimeTracking(all[0].ID, "mal"); err != nil {
+		t.Errorf("Expected MAL tracking on the Anilist anime: %v", err)
+	}
+}
+
+func TestSyncMarksRemoteProgressWatched(t *testing.T) {
+	mock := newMockTracker("anilist")
+	mock.list = []tracker.UserAnimeEntry{{
+		AnimeInfo:   tracker.AnimeInfo{ID: "302", Title: "Remote Progress", Episodes: 12},
+		Status:      tracker.StatusWatching,
+		Progress:    5,
+		LastUpdated: time.Now(),
+	}}
+	app, db := setupTestApp(t, mock)
+	app.config.Tracking.ReconcileProgress = true
+
+	var syncErrors []tracker.SyncError
+	if _, err := app.syncWithTrackers(context.Background(), db, &syncErrors); err != nil {
+		t.Fatalf("Failed to sync with trackers: %v", err)
+	}
+	if len(syncErrors) != 0 {
+		t.Fatalf("Expected no sync errors, got %v", syncErrors)
+	}
+
+	anime, err := db.GetAnimeByExternalID("302", "anilist")
+	if err != nil {
+		t.Fatalf("Failed to get synced anime: %v", err)
+	}
+
+	// Test that episodes 1-5 are watched locally and the rest aren't
+	progress, err := db.GetAllEpisodeProgress(anime.ID)
+	if err != nil {
+		t.Fatalf("Failed to get episode progress: %v", err)
+	}
+	if len(progress) != 5 {
+		t.Fatalf("Expected 5 episodes with progress, got %d", len(progress))
+	}
+	for _, p := range progress {
+		if !p.Watched || p.EpisodeNumber < 1 || p.EpisodeNumber > 5 {
+			t.Errorf("Expected episodes 1-5 watched, got %+v", p)
+		}
+	}
+	next, ok, err := db.GetNextUnwatchedEpisode(anime.ID, 0, 12)
+	if err != nil || !ok || next != 6 {
+		t.Errorf("Expected episode 6 next, got %g (ok %v, err %v)", next, ok, err)
+	}
+}
+
+func TestSuggestNextInSeries(t *testing.T) {
+	mock := newMockTracker("anilist")
+	mock.anime["1"] = tracker.AnimeInfo{ID: "1", Title: "First Season", Relations: []tracker.RelatedAnime{
+		{AnimeInfo: tracker.AnimeInfo{ID: "9", Title: "Prequel", MalID: "90"}, RelationType: "PREQUEL"},
+		{AnimeInfo: tracker.AnimeInfo{ID: "2", Title: "Second Season", MalID: "20"}, RelationType: "SEQUEL"},
+	}}
+	app, db := setupTestApp(t, mock)
+	ctx := context.Background()
+
+	addTracked := func(title, trackerName, trackerID string, status tracker.Status) *database.Anime {
+		anime := &database.Anime{Title: title, TotalEpisodes: 12}
+		if err := db.AddAnime(anime); err != nil {
+			t.Fatalf("Failed to add anime: %v", err)
+		}
+		tracking := &database.AnimeTracking{AnimeID: anime.ID, Tracker: trackerName, TrackerID: trackerID, Status: string(status)}
+		if err := db.AddAnimeTracking(tracking); err != nil {
+			t.Fatalf("Failed to add tracking: %v", err)
+		}
+		return anime
+	}
+	completed := addTracked("First Season", "anilist", "1", tracker.StatusCompleted)
+	addTracked("Prequel", "mal", "90", tracker.StatusPlanToWatch)
+
+	// Test that nothing is suggested while the sequel isn't in the library
+	suggestion, err := app.SuggestNextInSeries(ctx, completed.ID)
+	if err != nil {
+		t.Fatalf("Failed to suggest the next anime: %v", err)
+	}
+	if suggestion != nil {
+		t.Errorf("Expected no suggestion, got %s", suggestion.Title)
+	}
+
+	// Test that a planned sequel is found by its MyAnimeList ID
+	sequel := addTracked("Second Season", "mal", "20", tracker.StatusPlanToWatch)
+	suggestion, err = app.SuggestNextInSeries(ctx, completed.ID)
+	if err != nil {
+		t.Fatalf("Failed to suggest the next anime: %v", err)
+	}
+	if suggestion == nil || suggestion.ID != sequel.ID {
+		t.Errorf("Expected the second season to be suggested, got %+v", suggestion)
+	}
+
+	// Test that a sequel already being watched isn't suggested
+	tracking, err := db.GetAnimeTracking(sequel.ID, "mal")
+	if err != nil {
+		t.Fatalf("Failed to get tracking: %v", err)
+	}
+	tracking.Status = string(tracker.StatusWatching)
+	if err := db.UpdateAnimeTrackingObject(tracking); err != nil {
+		t.Fatalf("Failed to update tracking: %v", err)
+	}
+	if suggestion, err = app.SuggestNextInSeries(ctx, completed.ID); err != nil || suggestion != nil {
+		t.Errorf("Expected no suggestion, got %+v (%v)", suggestion, err)
+	}
+}
@@ -2,35 +2,77 @@ package appcore
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/wraient/pair/pkg/cache"
 	"github.com/wraient/pair/pkg/config"
 	"github.com/wraient/pair/pkg/database"
+	"github.com/wraient/pair/pkg/logger"
 	"github.com/wraient/pair/pkg/tracker"
 	"github.com/wraient/pair/pkg/ui"
 )
 
 // setupMainMenu creates and configures the main menu
 func (a *App) setupMainMenu() *ui.Menu {
-	mainMenu := ui.NewMenu("Main Menu", ui.List)
+	title := "Main Menu"
+	if a.offline() {
+		title += " (offline)"
+	}
+	mainMenu := ui.NewMenu(title, ui.List)
+
+	// Status counts for the list badges, a failed query just leaves them off
+	counts, _ := a.db.CountAnimeByStatus()
 
 	// Continue watching
 	mainMenu.AddItem("Continue watching", "continue", func(ctx context.Context) error {
-		// TODO: Implement continue watching functionality
-		return nil
+		return a.handleContinueWatching(ctx, a.db)
 	}).SetDescription("Continue watching your last anime")
 
 	// Currently watching
-	mainMenu.AddItem("Currently watching", "watching", func(ctx context.Context) error {
-		return a.handleCurrentlyWatching(ctx)
+	mainMenu.AddItem(withCount("Currently watching", counts[string(tracker.StatusWatching)]), "watching", func(ctx context.Context) error {
+		return a.handleTrackerError(ctx, a.handleCurrentlyWatching(ctx))
 	}).SetDescription("Show your currently watching anime")
 
 	// Show all anime
 	mainMenu.AddItem("Show all anime", "list", func(ctx context.Context) error {
-		return a.handleAnimeList(ctx)
+		return a.handleTrackerError(ctx, a.handleAnimeList(ctx))
 	}).SetDescription("Browse your complete anime list")
 
+	// Recently added
+	mainMenu.AddItem("Recently added", "recent", func(ctx context.Context) error {
+		return a.handleRecentlyAdded(ctx)
+	}).SetDescription("Show anime recently added to your library")
+
+	// Search & Add
+	mainMenu.AddItem("Search & Add", "search", func(ctx context.Context) error {
+		return a.handleSearchAndAdd(ctx)
+	}).SetDescription("Search the active tracker and add anime to your list")
+
+	// Seasonal anime
+	mainMenu.AddItem("Seasonal anime", "seasonal", func(ctx context.Context) error {
+		return a.handleSeasonalAnime(ctx)
+	}).SetDescription("Browse what's airing this season")
+
+	// Trending anime
+	mainMenu.AddItem("Trending", "trending", func(ctx context.Context) error {
+		return a.handleTrendingAnime(ctx)
+	}).SetDescription("Browse what's popular right now")
+
+	// Airing schedule
+	mainMenu.AddItem("Airing schedule", "schedule", func(ctx context.Context) error {
+		return a.handleAiringSchedule(ctx)
+	}).SetDescription("See when the next episodes of watched shows air")
+
+	// Add custom anime
+	mainMenu.AddItem("Add custom anime", "custom", func(ctx context.Context) error {
+		return a.handleAddCustomAnime(ctx)
+	}).SetDescription("Track a show that isn't on any tracker")
+
 	// Settings submenu
 	settingsMenu := a.setupSettingsMenu()
 	mainMenu.AddItem("Settings", "settings", nil).
@@ -57,6 +99,9 @@ func (a *App) setupSettingsMenu() *ui.Menu {
 
 	// Anilist settings
 	settingsMenu.AddItem("Login Anilist", "login_anilist", func(ctx context.Context) error {
+		if a.offline() {
+			return errOffline
+		}
 		t, err := a.trackerMgr.GetTracker("anilist")
 		if err != nil {
 			return fmt.Errorf("failed to get Anilist tracker: %w", err)
@@ -64,15 +109,126 @@ func (a *App) setupSettingsMenu() *ui.Menu {
 		return t.Authenticate(ctx)
 	}).SetDescription("Configure Anilist integration")
 
+	// Full resync per registered remote tracker
+	for _, trackerName := range []string{"anilist", "mal"} {
+		if _, err := a.trackerMgr.GetTracker(trackerName); err != nil {
+			continue
+		}
+		trackerName := trackerName
+		label := fmt.Sprintf("Force full resync (%s)", trackerDisplayName(trackerName))
+		settingsMenu.AddItem(label, "resync_"+trackerName, func(ctx context.Context) error {
+			return a.handleForceResync(ctx, trackerName)
+		}).SetDescription("Reconcile every entry regardless of the last sync time")
+	}
+
+	// Background sync status and control
+	settingsMenu.AddItem("Sync status", "sync_status", func(ctx context.Context) error {
+		return a.handleSyncStatus(ctx)
+	}).SetDescription("See the last background sync, start or cancel one")
+
+	settingsMenu.AddItem("Sync conflicts", "sync_conflicts", func(ctx context.Context) error {
+		return a.handleSyncConflicts()
+	}).SetDescription("Review values a sync overwrote")
+
+	// Sync toggles per remote tracker
+	for _, trackerName := range []string{"anilist", "mal"} {
+		trackerName := trackerName
+		label := fmt.Sprintf("Toggle %s sync", trackerDisplayName(trackerName))
+		settingsMenu.AddItem(label, "toggle_"+trackerName, func(ctx context.Context) error {
+			return a.toggleTracker(trackerName)
+		}).SetDescription("Pause or resume syncing without logging out")
+	}
+
+	// Maintenance submenu
+	maintenanceMenu := a.setupMaintenanceMenu()
+	settingsMenu.AddItem("Maintenance", "maintenance", nil).
+		AddSubMenu(maintenanceMenu).
+		SetDescription("Check and repair the local database")
+
 	// Add more settings items here...
 
 	return settingsMenu
 }
 
+// setupMaintenanceMenu creates and configures the database maintenance menu
+func (a *App) setupMaintenanceMenu() *ui.Menu {
+	maintenanceMenu := ui.NewMenu("Maintenance", ui.List)
+
+	maintenanceMenu.AddItem("Check database integrity", "integrity_check", func(ctx context.Context) error {
+		problems, err := config.GetDB().IntegrityCheck()
+		if err != nil {
+			return err
+		}
+		if len(problems) == 0 {
+			fmt.Println("Database integrity check passed")
+			return nil
+		}
+		fmt.Println("Database integrity problems found:")
+		for _, problem := range problems {
+			fmt.Printf("- %s\n", problem)
+		}
+		return nil
+	}).SetDescription("Run SQLite's integrity check")
+
+	maintenanceMenu.AddItem("Rebuild indexes", "reindex", func(ctx context.Context) error {
+		if err := config.GetDB().Reindex(); err != nil {
+			return err
+		}
+		fmt.Println("Database indexes rebuilt")
+		return nil
+	}).SetDescription("Rebuild all database indexes")
+
+	maintenanceMenu.AddItem("Clean orphaned rows", "clean_orphans", func(ctx context.Context) error {
+		removed, err := config.GetDB().CleanOrphans()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Removed %d orphaned rows\n", removed)
+		return nil
+	}).SetDescription("Remove rows that point at deleted anime or sources")
+
+	maintenanceMenu.AddItem("Deduplicate library", "dedupe", func(ctx context.Context) error {
+		return a.handleDeduplicate(ctx, config.GetDB())
+	}).SetDescription("Merge anime that were added more than once")
+
+	maintenanceMenu.AddItem("Fill in missing details", "backfill", func(ctx context.Context) error {
+		filled, err := a.BackfillMetadata(ctx)
+		if filled > 0 {
+			fmt.Printf("Filled in details of %d anime\n", filled)
+		}
+		if err != nil {
+			return err
+		}
+		if filled == 0 {
+			fmt.Println("No anime needed details filled in")
+		}
+		return nil
+	}).SetDescription("Fetch synopsis, genres and covers that are missing from the tracker")
+
+	maintenanceMenu.AddItem("Clear caches", "clear_cache", func(ctx context.Context) error {
+		freed, err := cache.ClearCaches()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Freed %s\n", formatBytes(freed))
+		return nil
+	}).SetDescription("Remove cached thumbnails and other downloaded files")
+
+	return maintenanceMenu
+}
+
 // setupExtensionsMenu creates and configures the extensions menu
 func (a *App) setupExtensionsMenu() *ui.Menu {
 	extensionsMenu := ui.NewMenu("Extensions", ui.List)
 
+	extensionsMenu.AddItem("Sources", "sources", func(ctx context.Context) error {
+		return a.handleSources(ctx, config.GetDB())
+	}).SetDescription("List installed sources")
+
+	extensionsMenu.AddItem("Update Extensions", "update", func(ctx context.Context) error {
+		return a.handleUpdateExtensions(ctx)
+	}).SetDescription("Install newer versions of extensions from their repositories")
+
 	// Add extension-related menu items here...
 
 	return extensionsMenu
@@ -80,38 +236,45 @@ func (a *App) setupExtensionsMenu() *ui.Menu {
 
 // handleAnimeList handles the anime list view with comprehensive sync
 func (a *App) handleAnimeList(ctx context.Context) error {
-	// Get the database connection
-	db := config.GetDB()
+	db := a.db
 
-	var syncErrors []error
+	var syncErrors []tracker.SyncError
 
-	// Sync with all available trackers
-	err := a.syncWithTrackers(ctx, db, &syncErrors)
-	if err != nil {
-		return fmt.Errorf("failed to sync with trackers: %w", err)
+	// Sync with all available trackers, showing what happened before an
+	// expired login stops the list
+	stats, syncErr := a.syncWithTrackers(ctx, db, &syncErrors)
+	if err := showSyncReport(stats, syncErrors); err != nil {
+		return err
+	}
+	if syncErr != nil {
+		return fmt.Errorf("failed to sync with trackers: %w", syncErr)
 	}
 
-	// Get all anime from local database for display
-	allAnime, err := db.GetAllAnime()
+	// Get all anime from local database for display, with their tracking
+	// info for the primary service
+	allAnime, err := db.GetAllAnimeWithTracking(string(a.config.Tracking.Service))
 	if err != nil {
 		return fmt.Errorf("failed to get anime from database: %w", err)
 	}
 
+	// Tags are shown in the list, so load them all at once. The list still
+	// works without them.
+	tags, err := db.GetAllListTags()
+	if err != nil {
+		logger.Warn("Failed to load list tags: " + err.Error())
+	}
+
 	// Convert to display format
 	var displayEntries []tracker.UserAnimeEntry
-	for _, anime := range allAnime {
-		// Get tracking info for the primary service
-		var primaryTracking *database.AnimeTracking
-		if a.config.Tracking.Service != "" {
-			primaryTracking, _ = db.GetAnimeTracking(anime.ID, string(a.config.Tracking.Service))
-		}
+	for _, entry := range allAnime {
+		anime, primaryTracking := entry.Anime, entry.Tracking
 
 		// Create display entry
 		userEntry := tracker.UserAnimeEntry{
 			AnimeInfo: tracker.AnimeInfo{
 				ID:                fmt.Sprintf("%d", anime.ID),
 				Title:             anime.Title,
-				EnglishTitle:      anime.OriginalTitle,
+				JapaneseTitle:     anime.OriginalTitle,
 				AlternativeTitles: anime.AlternativeTitles,
 				Synopsis:          anime.Description,
 				Type:              anime.Type,
@@ -130,6 +293,7 @@ func (a *App) handleAnimeList(ctx context.Context) error {
 			userEntry.Score = primaryTracking.Score
 			userEntry.LastUpdated = primaryTracking.LastUpdated
 		}
+		userEntry.Tags = tags[anime.ID]
 
 		displayEntries = append(displayEntries, userEntry)
 	}
@@ -139,23 +303,33 @@ func (a *App) handleAnimeList(ctx context.Context) error {
 		// Create menu items
 		menuItems := make([]ui.Pair, 0, len(displayEntries)+1)
 
+		// Download thumbnails up front so the menu doesn't stall on them
+		menuType := ui.List
+		var thumbnails map[string]string
+		if a.config.UI.ShowImagePreview {
+			menuType = ui.ListWithImage
+			thumbnails = a.prefetchThumbnails(ctx, displayEntries)
+		}
+
 		// Add all anime entries
 		for _, entry := range displayEntries {
 			// Create a display string with title and additional info
-			displayInfo := []string{entry.Title}
-			if entry.Episodes > 0 {
-				displayInfo = append(displayInfo, fmt.Sprintf("%d/%d eps", int(entry.Progress), entry.Episodes))
-			}
+			displayInfo := []string{entry.DisplayTitle(a.config.UI.TitleLanguage)}
+			displayInfo = append(displayInfo, ui.FormatProgress(entry.Progress, entry.Episodes)+" eps")
 			if entry.Score > 0 {
 				displayInfo = append(displayInfo, fmt.Sprintf("Score: %.1f", entry.Score))
 			}
 			if entry.Status != "" {
 				displayInfo = append(displayInfo, string(entry.Status))
 			}
+			if len(entry.Tags) > 0 {
+				displayInfo = append(displayInfo, "Tags: "+strings.Join(entry.Tags, ", "))
+			}
 
 			menuItems = append(menuItems, ui.Pair{
 				Label: strings.Join(displayInfo, " - "),
 				Value: entry.ID,
+				Image: thumbnails[entry.ID],
 			})
 		}
 
@@ -166,7 +340,7 @@ func (a *App) handleAnimeList(ctx context.Context) error {
 		})
 
 		// Show menu
-		selectedID, err := ui.OpenMenu(ui.List, menuItems)
+		selectedID, err := ui.OpenMenu(menuType, menuItems)
 		if err != nil {
 			return fmt.Errorf("failed to show menu: %w", err)
 		}
@@ -195,56 +369,44 @@ func (a *App) handleAnimeList(ctx context.Context) error {
 			return fmt.Errorf("failed to show update menu: %w", err)
 		}
 
-		// Get the active tracker
-		if a.config.Tracking.Service != "" {
-			t, err := a.trackerMgr.GetTracker(string(a.config.Tracking.Service))
-			if err != nil {
-				return fmt.Errorf("failed to get tracker: %w", err)
-			}
-
-			// Handle selected action
-			switch action {
-			case "status":
-				status, err := ui.ShowAnimeStatusSelection()
-				if err != nil {
-					return err
-				}
-				return t.UpdateAnimeStatus(ctx, selectedID, status, 0, 0)
-			case "progress":
-				episode, err := ui.ShowEpisodeSelection(selectedAnime.Episodes)
-				if err != nil {
-					return err
-				}
-				return t.UpdateAnimeStatus(ctx, selectedID, "", episode, 0)
-			case "score":
-				score, err := ui.ShowAnimeScoreSelection()
-				if err != nil {
-					return err
-				}
-				return t.UpdateAnimeStatus(ctx, selectedID, "", 0, score)
-			case "back":
-				return nil
-			}
-		}
+		return a.handleAnimeAction(ctx, db, action, selectedAnime)
 	} else {
 		fmt.Println("No anime found in database")
 	}
 
-	// Log any sync errors that occurred
-	if len(syncErrors) > 0 {
-		fmt.Println("\nSync errors occurred:")
-		for _, err := range syncErrors {
-			fmt.Printf("- %v\n", err)
-		}
+	return nil
+}
+
+// syncWithTrackers syncs anime data with all authenticated trackers and
+// returns what changed, keyed by tracker name. It waits for a background
+// sync in progress to finish first, the trackers can't serve both at once.
+// Failures are added to syncErrors, and the first expired login is also
+// returned so callers can ask the user to log in again.
+func (a *App) syncWithTrackers(ctx context.Context, db *database.DB, syncErrors *[]tracker.SyncError) (map[string]tracker.SyncStats, error) {
+	if a.syncMgr == nil {
+		return a.syncTrackers(ctx, db, syncErrors)
 	}
 
-	return nil
+	var stats map[string]tracker.SyncStats
+	err := a.syncMgr.Exclusive(func() error {
+		var err error
+		stats, err = a.syncTrackers(ctx, db, syncErrors)
+		return err
+	})
+	return stats, err
 }
 
-// syncWithTrackers syncs anime data with all authenticated trackers
-func (a *App) syncWithTrackers(ctx context.Context, db *database.DB, syncErrors *[]error) error {
+// syncTrackers does the work of syncWithTrackers
+func (a *App) syncTrackers(ctx context.Context, db *database.DB, syncErrors *[]tracker.SyncError) (map[string]tracker.SyncStats, error) {
+	stats := make(map[string]tracker.SyncStats)
+	before := len(*syncErrors)
+	if a.offline() {
+		return stats, nil
+	}
+
 	// Get all available trackers
 	availableTrackers := []string{"anilist", "mal"}
+	unreachable := false
 
 	for _, trackerName := range availableTrackers {
 		animeTracker, err := a.trackerMgr.GetTracker(trackerName)
@@ -252,25 +414,254 @@ func (a *App) syncWithTrackers(ctx context.Context, db *database.DB, syncErrors
 			continue // Skip if tracker not available
 		}
 
+		// Skip if sync with the tracker is switched off
+		if !a.trackerEnabled(trackerName) {
+			continue
+		}
+
 		// Check if tracker is authenticated
 		if !animeTracker.IsAuthenticated() {
 			continue // Skip if not authenticated
 		}
 
-		err = a.syncWithSingleTracker(ctx, db, animeTracker, trackerName, syncErrors)
+		// Skip if the network is down rather than waiting for each request to time out
+		if !tracker.Reachable(ctx, a.prober, animeTracker, a.config.Network.Proxy) {
+			unreachable = true
+			continue
+		}
+
+		// Paginated syncs outlast the per-request timeout, so give them their own deadline
+		syncCtx, cancel := context.WithTimeout(ctx, a.syncTimeout())
+		stopSpinner := ui.ShowSpinner(syncCtx, fmt.Sprintf("Syncing with %s…", trackerDisplayName(trackerName)))
+		var trackerStats tracker.SyncStats
+		err = a.syncWithSingleTracker(syncCtx, db, animeTracker, trackerName, &trackerStats, syncErrors)
+		stopSpinner()
+		cancel()
 		if err != nil {
-			*syncErrors = append(*syncErrors, fmt.Errorf("failed to sync with %s: %w", trackerName, err))
+			trackerStats.Errors++
+			*syncErrors = append(*syncErrors, tracker.SyncError{Tracker: trackerName, Op: "sync", Err: err})
+		} else if a.config.Tracking.ReconcileProgress {
+			if _, err := db.ReconcileWatchedEpisodes(trackerName); err != nil {
+				*syncErrors = append(*syncErrors, tracker.SyncError{Tracker: trackerName, Op: "mark episodes watched", Err: err})
+			}
+		}
+		stats[trackerDisplayName(trackerName)] = trackerStats
+	}
+
+	if unreachable {
+		fmt.Fprintln(os.Stderr, "Network unavailable, using local data")
+	}
+	if err := tracker.PruneConflicts(db, a.conflictRetention()); err != nil {
+		*syncErrors = append(*syncErrors, tracker.SyncError{Op: "prune sync conflicts", Err: err})
+	}
+
+	for _, syncErr := range (*syncErrors)[before:] {
+		if errors.Is(syncErr, tracker.ErrAuthExpired) {
+			return stats, syncErr
+		}
+	}
+	return stats, nil
+}
+
+// handleSyncStatus shows the state of the background sync and lets the user
+// start one or cancel the one in progress
+func (a *App) handleSyncStatus(ctx context.Context) error {
+	if a.syncMgr == nil {
+		return errOffline
+	}
+
+	choice, err := ui.ShowSyncStatus(a.syncMgr.Status())
+	if err != nil {
+		return err
+	}
+	switch choice {
+	case ui.SyncNow:
+		// Sync while the menu waits, the trackers are shared with the rest of
+		// the UI. The outcome shows in the status menu it returns to.
+		stopSpinner := ui.ShowSpinner(ctx, "Syncing…")
+		err := a.syncMgr.TriggerNow(ctx)
+		stopSpinner()
+		if err != nil && !errors.Is(err, tracker.ErrSyncRunning) && !errors.Is(err, context.Canceled) {
+			return err
+		}
+		return a.handleSyncStatus(ctx)
+	case ui.CancelSync:
+		if a.syncMgr.CancelCurrent() {
+			fmt.Println("Sync cancelled")
+		}
+	}
+	return nil
+}
+
+// syncConflictLimit is the number of sync conflicts shown for review
+const syncConflictLimit = 200
+
+// handleSyncConflicts shows the values recent syncs overwrote and lets the
+// user clear the log
+func (a *App) handleSyncConflicts() error {
+	conflicts, err := a.db.GetSyncConflicts(syncConflictLimit)
+	if err != nil {
+		return err
+	}
+
+	choice, err := ui.ShowSyncConflicts(conflicts)
+	if err != nil {
+		return err
+	}
+	if choice == ui.ClearConflicts {
+		if err := a.db.ClearSyncConflicts(); err != nil {
+			return fmt.Errorf("failed to clear sync conflicts: %w", err)
 		}
+		fmt.Println("Sync conflict log cleared")
+	}
+	return nil
+}
+
+// showSyncReport shows the outcome of a sync when it changed something or failed
+func showSyncReport(stats map[string]tracker.SyncStats, syncErrors []tracker.SyncError) error {
+	notable := len(syncErrors) > 0
+	for _, s := range stats {
+		notable = notable || s.Added > 0 || s.Updated > 0 || s.Deleted > 0 || s.Errors > 0
+	}
+	if !notable {
+		return nil
+	}
+	return ui.ShowSyncReport(stats, syncErrors)
+}
+
+// toggleTracker switches syncing with a tracker on or off and saves the choice
+func (a *App) toggleTracker(trackerName string) error {
+	if trackerName != "anilist" && trackerName != "mal" {
+		return fmt.Errorf("unknown tracker %q", trackerName)
+	}
+	enabled := !a.trackerEnabled(trackerName)
+
+	// The config is shared, replace it rather than writing to it in place
+	config.Update(func(c *config.Config) {
+		if trackerName == "anilist" {
+			c.Tracking.AnilistEnabled = enabled
+		} else {
+			c.Tracking.MALEnabled = enabled
+		}
+	})
+	a.config = config.Get()
+	if a.syncMgr != nil {
+		a.syncMgr.SetTrackerEnabled(trackerName, enabled)
+	}
+	if err := config.Set(fmt.Sprintf("tracking.%s_enabled", trackerName), enabled); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	state := "disabled"
+	if enabled {
+		state = "enabled"
+	}
+	fmt.Printf("%s sync %s\n", trackerDisplayName(trackerName), state)
+	return nil
+}
+
+// handleForceResync runs a full sync with a tracker and prints the result
+func (a *App) handleForceResync(ctx context.Context, trackerName string) error {
+	if a.offline() {
+		return errOffline
+	}
+
+	stopSpinner := ui.ShowSpinner(ctx, fmt.Sprintf("Resyncing with %s…", trackerDisplayName(trackerName)))
+	stats, err := a.trackerMgr.ForceResync(ctx, trackerName)
+	stopSpinner()
+	if err != nil {
+		return err
 	}
 
+	fmt.Printf("Resync with %s finished: %d added, %d updated, %d skipped, %d errors\n",
+		trackerDisplayName(trackerName), stats.Added, stats.Updated, stats.Skipped, stats.Errors)
 	return nil
 }
 
+// prefetchThumbnails caches the thumbnails of the entries, keyed by anime ID,
+// and returns the local paths of those that are available
+func (a *App) prefetchThumbnails(ctx context.Context, entries []tracker.UserAnimeEntry) map[string]string {
+	thumbnails := make([]cache.Thumbnail, 0, len(entries))
+	for _, entry := range entries {
+		thumbnails = append(thumbnails, cache.Thumbnail{Key: entry.ID, URL: entry.ImageURL})
+	}
+
+	thumbnailCache := cache.NewThumbnailCache(filepath.Join(cache.DefaultDir(), "thumbnails"))
+	if a.offline() {
+		// Only show what's already cached
+		paths := make(map[string]string)
+		for _, thumbnail := range thumbnails {
+			if path, ok := thumbnailCache.Get(thumbnail.Key); ok {
+				paths[thumbnail.Key] = path
+			}
+		}
+		return paths
+	}
+
+	timeout := time.Duration(a.config.UI.ThumbnailTimeout) * time.Second
+	return thumbnailCache.Prefetch(ctx, thumbnails, a.config.UI.ThumbnailConcurrency, timeout)
+}
+
+// conflictRetention returns how long logged sync conflicts are kept
+func (a *App) conflictRetention() time.Duration {
+	return time.Duration(a.config.Tracking.ConflictRetentionDays) * 24 * time.Hour
+}
+
+// syncTimeout returns the deadline for syncing with a single tracker
+func (a *App) syncTimeout() time.Duration {
+	if a.config.API.SyncTimeout <= 0 {
+		return 10 * time.Minute
+	}
+	return time.Duration(a.config.API.SyncTimeout) * time.Second
+}
+
+// withCount appends a count to a menu label when there is anything to count
+func withCount(label string, count int) string {
+	if count <= 0 {
+		return label
+	}
+	return fmt.Sprintf("%s (%d)", label, count)
+}
+
+// formatBytes formats a size in bytes for display
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	value := float64(size)
+	for _, suffix := range []string{"KiB", "MiB", "GiB"} {
+		value /= unit
+		if value < unit || suffix == "GiB" {
+			return fmt.Sprintf("%.1f %s", value, suffix)
+		}
+	}
+	return ""
+}
+
+// trackerDisplayName returns the user-facing name of a tracker
+func trackerDisplayName(trackerName string) string {
+	switch trackerName {
+	case "anilist":
+		return "Anilist"
+	case "mal":
+		return "MyAnimeList"
+	case "local":
+		return "Local"
+	default:
+		return trackerName
+	}
+}
+
 // syncWithSingleTracker syncs anime data with a single tracker
-func (a *App) syncWithSingleTracker(ctx context.Context, db *database.DB, animeTracker tracker.Tracker, trackerName string, syncErrors *[]error) error {
+func (a *App) syncWithSingleTracker(ctx context.Context, db *database.DB, animeTracker tracker.Tracker, trackerName string, stats *tracker.SyncStats, syncErrors *[]tracker.SyncError) error {
 	// Get remote entries from tracker
 	remoteEntries, err := animeTracker.GetUserAnimeList(ctx)
-	if err != nil {
+	var partial *tracker.PartialListError
+	if errors.As(err, &partial) {
+		*syncErrors = append(*syncErrors, tracker.SyncError{Tracker: trackerName, Op: "get all of the anime list", Err: err})
+	} else if err != nil {
 		return fmt.Errorf("failed to get anime list: %w", err)
 	}
 
@@ -294,31 +685,63 @@ func (a *App) syncWithSingleTracker(ctx context.Context, db *database.DB, animeT
 
 	// Process each remote entry
 	for _, entry := range remoteEntries {
-		err := a.processRemoteEntry(ctx, db, &entry, trackerName, localTrackingMap, syncErrors)
+		err := a.processRemoteEntry(ctx, db, &entry, trackerName, localTrackingMap, stats)
 		if err != nil {
-			*syncErrors = append(*syncErrors, fmt.Errorf("failed to process remote entry %s: %w", entry.Title, err))
+			stats.Errors++
+			*syncErrors = append(*syncErrors, tracker.SyncError{Tracker: trackerName, Title: entry.Title, Op: "process remote entry", Err: err})
 		}
 	}
 
+	// Entries missing from a partial list may just be on the pages that failed
+	if partial != nil {
+		return nil
+	}
+
 	// Check for local entries that are not in remote (deleted from remote)
 	for trackerID, localTracking := range localTrackingMap {
 		if _, exists := remoteEntriesMap[trackerID]; !exists {
 			// Entry was deleted from remote, delete from local
 			err := a.deleteLocalEntry(db, localTracking, trackerName)
 			if err != nil {
-				*syncErrors = append(*syncErrors, fmt.Errorf("failed to delete local entry for tracker ID %s: %w", trackerID, err))
+				stats.Errors++
+				*syncErrors = append(*syncErrors, tracker.SyncError{Tracker: trackerName, Title: trackedTitle(db, localTracking), Op: "delete local entry", Err: err})
+				continue
 			}
+			stats.Deleted++
 		}
 	}
 
+	// A partial list returned above, so the sync menu keeps showing it as due
+	if err := db.SetConfig(database.LastSyncKey(trackerName), time.Now().Format(time.RFC3339)); err != nil {
+		return fmt.Errorf("failed to update last sync time: %w", err)
+	}
 	return nil
 }
 
+// trackedTitle returns the title of the anime a tracking entry is for,
+// falling back to its tracker ID when the anime can't be read
+func trackedTitle(db *database.DB, tracking *database.AnimeTracking) string {
+	if anime, err := db.GetAnime(tracking.AnimeID); err == nil {
+		return anime.Title
+	}
+	return "tracker ID " + tracking.TrackerID
+}
+
 // processRemoteEntry processes a single remote anime entry
-func (a *App) processRemoteEntry(ctx context.Context, db *database.DB, entry *tracker.UserAnimeEntry, trackerName string, localTrackingMap map[string]*database.AnimeTracking, syncErrors *[]error) error {
-	// Check if anime exists in database
-	anime, err := db.GetAnimeByExternalID(entry.ID, trackerName)
-	if err != nil && err != database.ErrAnimeNotFound {
+func (a *App) processRemoteEntry(ctx context.Context, db *database.DB, entry *tracker.UserAnimeEntry, trackerName string, localTrackingMap map[string]*database.AnimeTracking, stats *tracker.SyncStats) error {
+	// Check if anime exists in database, also as an entry of the other
+	// tracker with the same MAL ID
+	var anime *database.Anime
+	var err error
+	if trackerName == "mal" {
+		anime, err = db.GetAnimeByMalID(entry.ID)
+	} else {
+		anime, err = db.GetAnimeByExternalID(entry.ID, trackerName)
+		if errors.Is(err, database.ErrAnimeNotFound) && entry.MalID != "" {
+			anime, err = db.GetAnimeByMalID(entry.MalID)
+		}
+	}
+	if err != nil && !errors.Is(err, database.ErrAnimeNotFound) {
 		return fmt.Errorf("failed to check anime: %w", err)
 	}
 
@@ -326,7 +749,7 @@ func (a *App) processRemoteEntry(ctx context.Context, db *database.DB, entry *tr
 		// Anime doesn't exist, add it
 		animeData := &database.Anime{
 			Title:             entry.Title,
-			OriginalTitle:     entry.EnglishTitle,
+			OriginalTitle:     entry.JapaneseTitle,
 			AlternativeTitles: entry.AlternativeTitles,
 			Description:       entry.Synopsis,
 			TotalEpisodes:     entry.Episodes,
@@ -341,6 +764,7 @@ func (a *App) processRemoteEntry(ctx context.Context, db *database.DB, entry *tr
 		if err := db.AddAnime(animeData); err != nil {
 			return fmt.Errorf("failed to add anime to local db: %w", err)
 		}
+		recordMalID(db, animeData.ID, entry, stats)
 
 		// Add tracking information
 		tracking := &database.AnimeTracking{
@@ -351,15 +775,21 @@ func (a *App) processRemoteEntry(ctx context.Context, db *database.DB, entry *tr
 			Score:          entry.Score,
 			CurrentEpisode: entry.Progress,
 			TotalEpisodes:  entry.Episodes,
+			Priority:       entry.Priority,
+			Tags:           entry.Tags,
+			IsRewatching:   entry.Rewatching,
 			LastUpdated:    entry.LastUpdated,
 		}
 
 		if err := db.AddAnimeTracking(tracking); err != nil {
 			return fmt.Errorf("failed to add tracking: %w", err)
 		}
+		stats.Added++
 	} else {
+		recordMalID(db, anime.ID, entry, stats)
+
 		// Anime exists, handle sync
-		err := a.syncExistingEntry(ctx, db, anime, entry, trackerName, localTrackingMap)
+		err := a.syncExistingEntry(ctx, db, anime, entry, trackerName, localTrackingMap, stats)
 		if err != nil {
 			return fmt.Errorf("failed to sync existing entry: %w", err)
 		}
@@ -368,8 +798,19 @@ func (a *App) processRemoteEntry(ctx context.Context, db *database.DB, entry *tr
 	return nil
 }
 
+// recordMalID stores the MAL ID an Anilist entry gave, so a later MAL sync
+// finds the same anime instead of adding it again
+func recordMalID(db *database.DB, animeID int64, entry *tracker.UserAnimeEntry, stats *tracker.SyncStats) {
+	if entry.MalID == "" {
+		return
+	}
+	if err := db.SetAnimeMalID(animeID, entry.MalID); err != nil {
+		stats.Details = append(stats.Details, fmt.Sprintf("Failed to record the MAL ID of %s: %v", entry.Title, err))
+	}
+}
+
 // syncExistingEntry syncs an existing anime entry between local and remote
-func (a *App) syncExistingEntry(ctx context.Context, db *database.DB, anime *database.Anime, remoteEntry *tracker.UserAnimeEntry, trackerName string, localTrackingMap map[string]*database.AnimeTracking) error {
+func (a *App) syncExistingEntry(ctx context.Context, db *database.DB, anime *database.Anime, remoteEntry *tracker.UserAnimeEntry, trackerName string, localTrackingMap map[string]*database.AnimeTracking, stats *tracker.SyncStats) error {
 	localTracking := localTrackingMap[remoteEntry.ID]
 
 	if localTracking == nil {
@@ -382,41 +823,99 @@ func (a *App) syncExistingEntry(ctx context.Context, db *database.DB, anime *dat
 			Score:          remoteEntry.Score,
 			CurrentEpisode: remoteEntry.Progress,
 			TotalEpisodes:  remoteEntry.Episodes,
+			Priority:       remoteEntry.Priority,
+			Tags:           remoteEntry.Tags,
+			IsRewatching:   remoteEntry.Rewatching,
 			LastUpdated:    remoteEntry.LastUpdated,
 		}
 
-		return db.AddAnimeTracking(tracking)
+		if err := db.AddAnimeTracking(tracking); err != nil {
+			return err
+		}
+		stats.Added++
+		return nil
 	}
 
 	// Both local and remote exist, sync based on timestamps and progress
 	if remoteEntry.LastUpdated.After(localTracking.LastUpdated) {
-		// Remote is newer, update local
+		// Remote is newer, update local without losing fractional progress
+		// the remote can't store
 		tracking := &database.AnimeTracking{
 			AnimeID:        anime.ID,
 			Tracker:        trackerName,
 			TrackerID:      remoteEntry.ID,
 			Status:         string(remoteEntry.Status),
 			Score:          remoteEntry.Score,
-			CurrentEpisode: remoteEntry.Progress,
+			CurrentEpisode: tracker.MergeProgress(localTracking.CurrentEpisode, remoteEntry.Progress),
 			TotalEpisodes:  remoteEntry.Episodes,
+			Priority:       remoteEntry.Priority,
+			Tags:           remoteEntry.Tags,
+			IsRewatching:   remoteEntry.Rewatching,
 			LastUpdated:    remoteEntry.LastUpdated,
 		}
 
-		return db.AddAnimeTracking(tracking)
-	} else if localTracking.LastUpdated.After(remoteEntry.LastUpdated) && localTracking.CurrentEpisode > remoteEntry.Progress {
+		if err := db.AddAnimeTracking(tracking); err != nil {
+			return err
+		}
+		if err := tracker.RecordConflicts(db, localTracking, remoteEntry, database.ConflictRemote); err != nil {
+			logger.Debug("Failed to log sync conflicts: " + err.Error())
+		}
+		stats.Updated++
+		return nil
+	} else if localTracking.LastUpdated.After(remoteEntry.LastUpdated) && float64(tracker.RemoteProgress(localTracking.CurrentEpisode)) > remoteEntry.Progress {
 		// Local is newer and has higher progress, update remote
 		t, err := a.trackerMgr.GetTracker(trackerName)
 		if err != nil {
 			return fmt.Errorf("failed to get tracker for update: %w", err)
 		}
 
-		return t.UpdateAnimeStatus(ctx, remoteEntry.ID, tracker.Status(localTracking.Status), localTracking.CurrentEpisode, localTracking.Score)
+		// The list may have been fetched a while ago, so confirm the remote
+		// state hasn't changed before overwriting it
+		current, err := t.GetListEntry(ctx, remoteEntry.ID)
+		if errors.Is(err, tracker.ErrNotInList) {
+			stats.Skipped++
+			return nil // Removed in the meantime, the next sync will handle it
+		}
+		if err != nil {
+			return fmt.Errorf("failed to confirm remote state: %w", err)
+		}
+		if current.LastUpdated.After(remoteEntry.LastUpdated) {
+			return a.syncExistingEntry(ctx, db, anime, current, trackerName, localTrackingMap, stats)
+		}
+
+		if err := t.UpdateAnimeStatus(ctx, remoteEntry.ID, tracker.Status(localTracking.Status), localTracking.CurrentEpisode, localTracking.Score); err != nil {
+			return err
+		}
+		if err := tracker.RecordConflicts(db, localTracking, current, database.ConflictLocal); err != nil {
+			logger.Debug("Failed to log sync conflicts: " + err.Error())
+		}
+		if updater, ok := t.(tracker.ListDetailsUpdater); ok && listDetailsChanged(localTracking, current) {
+			if err := updater.UpdateListDetails(ctx, remoteEntry.ID, localTracking.Priority, localTracking.Tags); err != nil {
+				return err
+			}
+		}
+		stats.Updated++
+		return nil
 	}
 	// If remote is newer but has lower progress, or if timestamps are equal, keep local as-is
 
+	stats.Skipped++
 	return nil
 }
 
+// listDetailsChanged reports whether the local priority or tags differ from the remote entry
+func listDetailsChanged(local *database.AnimeTracking, remote *tracker.UserAnimeEntry) bool {
+	if local.Priority != remote.Priority || len(local.Tags) != len(remote.Tags) {
+		return true
+	}
+	for i, tag := range local.Tags {
+		if remote.Tags[i] != tag {
+			return true
+		}
+	}
+	return false
+}
+
 // deleteLocalEntry deletes a local anime entry that was removed from remote
 func (a *App) deleteLocalEntry(db *database.DB, localTracking *database.AnimeTracking, trackerName string) error {
 	// Delete the tracking entry
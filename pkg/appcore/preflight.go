@@ -0,0 +1,55 @@
+package appcore
+
+import (
+	"fmt"
+	"os/exec"
+
+	"github.com/wraient/pair/pkg/config"
+)
+
+// lookPath finds binaries on PATH, replaced in tests
+var lookPath = exec.LookPath
+
+// playerBinary is the video player used for playback
+const playerBinary = "mpv"
+
+// DependencyWarning describes a missing external binary and how to work around it
+type DependencyWarning struct {
+	Binary     string
+	Purpose    string
+	Suggestion string
+}
+
+func (w DependencyWarning) String() string {
+	message := fmt.Sprintf("%s not found, %s won't work", w.Binary, w.Purpose)
+	if w.Suggestion != "" {
+		message += " (" + w.Suggestion + ")"
+	}
+	return message
+}
+
+// CheckDependencies looks for the external binaries the configuration relies on.
+// Missing ones are reported rather than treated as fatal.
+func CheckDependencies(cfg *config.Config) []DependencyWarning {
+	var warnings []DependencyWarning
+
+	if cfg.UI.Mode == config.UIModeRofi {
+		if _, err := lookPath("rofi"); err != nil {
+			warnings = append(warnings, DependencyWarning{
+				Binary:     "rofi",
+				Purpose:    "the rofi menus",
+				Suggestion: "install rofi or set ui.mode = \"cli\"",
+			})
+		}
+	}
+
+	if _, err := lookPath(playerBinary); err != nil {
+		warnings = append(warnings, DependencyWarning{
+			Binary:     playerBinary,
+			Purpose:    "playback",
+			Suggestion: "install mpv to watch episodes",
+		})
+	}
+
+	return warnings
+}
@@ -0,0 +1,52 @@
+package appcore
+
+import (
+	"os/exec"
+	"testing"
+
+	"github.com/wraient/pair/pkg/config"
+)
+
+// stubLookPath makes only the given binaries appear installed
+func stubLookPath(t *testing.T, installed ...string) {
+	original := lookPath
+	t.Cleanup(func() { lookPath = original })
+
+	lookPath = func(name string) (string, error) {
+		for _, binary := range installed {
+			if binary == name {
+				return "/usr/bin/" + name, nil
+			}
+		}
+		return "", &exec.Error{Name: name, Err: exec.ErrNotFound}
+	}
+}
+
+func TestCheckDependencies(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.UI.Mode = config.UIModeRofi
+
+	// Test that nothing is reported when everything is installed
+	stubLookPath(t, "rofi", "mpv")
+	if warnings := CheckDependencies(cfg); len(warnings) != 0 {
+		t.Errorf("Expected no warnings, got %v", warnings)
+	}
+
+	// Test that a missing rofi suggests the CLI mode
+	stubLookPath(t, "mpv")
+	warnings := CheckDependencies(cfg)
+	if len(warnings) != 1 || warnings[0].Binary != "rofi" {
+		t.Fatalf("Expected a rofi warning, got %v", warnings)
+	}
+	if warnings[0].Suggestion != `install rofi or set ui.mode = "cli"` {
+		t.Errorf("Expected a ui.mode suggestion, got '%s'", warnings[0].Suggestion)
+	}
+
+	// Test that rofi isn't needed in CLI mode but the player still is
+	cfg.UI.Mode = config.UIModeCLI
+	stubLookPath(t)
+	warnings = CheckDependencies(cfg)
+	if len(warnings) != 1 || warnings[0].Binary != "mpv" {
+		t.Errorf("Expected only an mpv warning, got %v", warnings)
+	}
+}
@@ -0,0 +1,1000 @@
+package appcore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wraient/pair/pkg/database"
+	"github.com/wraient/pair/pkg/httpclient"
+	"github.com/wraient/pair/pkg/logger"
+	"github.com/wraient/pair/pkg/matcher"
+	"github.com/wraient/pair/pkg/scraper"
+	"github.com/wraient/pair/pkg/tracker"
+	"github.com/wraient/pair/pkg/ui"
+)
+
+// errNoSources is returned when an anime isn't mapped to any installed source
+var errNoSources = errors.New("anime isn't mapped to any installed source")
+
+// lastSourceKey is the config key remembering the source an anime was last watched from
+func lastSourceKey(animeID int64) string {
+	return fmt.Sprintf("anime.%d.last_source", animeID)
+}
+
+// audioPreferenceKey is the config key holding whether an anime is watched
+// "sub" or "dub", overriding video.prefer_dub
+func audioPreferenceKey(animeID int64) string {
+	return fmt.Sprintf("anime.%d.audio", animeID)
+}
+
+// prefersDub reports whether an anime should be watched dubbed, going by its
+// own audio preference and falling back to video.prefer_dub
+func (a *App) prefersDub(db *database.DB, animeID int64) bool {
+	switch preference, _ := db.GetConfig(audioPreferenceKey(animeID)); preference {
+	case "dub":
+		return true
+	case "sub":
+		return false
+	}
+	return a.config.Video.PreferDub
+}
+
+// handleAudioPreference sets whether an anime is watched subbed or dubbed,
+// or goes back to video.prefer_dub
+func (a *App) handleAudioPreference(db *database.DB, animeID int64) error {
+	fallback := "sub"
+	if a.config.Video.PreferDub {
+		fallback = "dub"
+	}
+	items := []ui.Pair{
+		{Label: "Sub (original audio)", Value: "sub"},
+		{Label: "Dub", Value: "dub"},
+		{Label: fmt.Sprintf("Use default (%s)", fallback), Value: "default"},
+	}
+	choice, err := ui.OpenMenu(ui.List, items)
+	if err != nil || choice == "" {
+		return err
+	}
+
+	if choice == "default" {
+		err = db.DeleteConfig(audioPreferenceKey(animeID))
+	} else {
+		err = db.SetConfig(audioPreferenceKey(animeID), choice)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save audio preference: %w", err)
+	}
+	return nil
+}
+
+// mappedSources returns the installed sources an anime is mapped to
+func mappedSources(db *database.DB, animeID int64) ([]*database.Source, error) {
+	animeSources, err := db.GetAnimeSources(animeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get anime sources: %w", err)
+	}
+	if len(animeSources) == 0 {
+		return nil, nil
+	}
+
+	sources, err := db.GetAllSources()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sources: %w", err)
+	}
+	byID := make(map[int64]*database.Source)
+	for _, source := range sources {
+		byID[source.ID] = source
+	}
+
+	var mapped []*database.Source
+	for _, animeSource := range animeSources {
+		if source, ok := byID[animeSource.SourceID]; ok {
+			mapped = append(mapped, source)
+		}
+	}
+
+	return mapped, nil
+}
+
+// chooseSource returns the source to watch an anime from. The source used last
+// time is picked without asking unless change is set; a source picked by the
+// user is remembered for next time. It returns "" if the user backed out.
+func (a *App) chooseSource(db *database.DB, animeID int64, change bool) (string, error) {
+	sources, err := mappedSources(db, animeID)
+	if err != nil {
+		return "", err
+	}
+	if len(sources) == 0 {
+		return "", errNoSources
+	}
+
+	if !change {
+		if last, err := db.GetConfig(lastSourceKey(animeID)); err == nil {
+			for _, source := range sources {
+				if source.SourceID == last {
+					return last, nil
+				}
+			}
+		}
+		if len(sources) == 1 {
+			return sources[0].SourceID, nil
+		}
+	}
+
+	sourceID, err := ui.ShowSourceSelection(sources)
+	if err != nil || sourceID == "" {
+		return "", err
+	}
+
+	if err := db.SetConfig(lastSourceKey(animeID), sourceID); err != nil {
+		return "", fmt.Errorf("failed to remember source: %w", err)
+	}
+
+	return sourceID, nil
+}
+
+// sourceScraper returns a scraper for a source together with the anime's
+// mapping to it, which holds its ID and episode offset on the source
+func (a *App) sourceScraper(animeID int64, sourceID string) (scraper.Scraper, *database.AnimeSource, error) {
+	source, err := a.db.GetSourceByID(sourceID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get source %s: %w", sourceID, err)
+	}
+
+	animeSources, err := a.db.GetAnimeSources(animeID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get anime sources: %w", err)
+	}
+	var mapping *database.AnimeSource
+	for _, animeSource := range animeSources {
+		if animeSource.SourceID == source.ID {
+			mapping = animeSource
+			break
+		}
+	}
+	if mapping == nil || mapping.SourceAnimeID == "" {
+		return nil, nil, fmt.Errorf("anime is not mapped to source %s", sourceID)
+	}
+
+	sourceScraper, err := a.scraperFor(source)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sourceScraper, mapping, nil
+}
+
+// scraperFor returns a scraper for a source of an installed extension. The
+// optional operations of the scraper are checked against what the source
+// supports.
+func (a *App) scraperFor(source *database.Source) (scraper.Scraper, error) {
+	ext, err := a.db.GetExtensionByID(source.ExtensionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get extension for source %s: %w", source.SourceID, err)
+	}
+	// Installing another extension with the same source ID takes the source
+	// over, and its capabilities mustn't come from the old binary
+	key := ext.Package + "/" + source.SourceID
+
+	// Extensions with a configured plugin server are reached over HTTP
+	if serverURL := a.config.Extensions.Servers[ext.Package]; serverURL != "" {
+		return a.capabilities.Checked(key, scraper.NewHTTPScraper(serverURL, source.SourceID)), nil
+	}
+
+	cliScraper := scraper.NewCLIScraper(ext.Path, source.SourceID)
+	cliScraper.Package = ext.Package
+	return a.capabilities.Checked(key, cliScraper), nil
+}
+
+// searchableSources returns the IDs of the sources that support searching
+func (a *App) searchableSources(sources []*database.Source) map[string]bool {
+	searchable := make(map[string]bool, len(sources))
+	for _, source := range sources {
+		sourceScraper, err := a.scraperFor(source)
+		if err != nil {
+			continue
+		}
+		info, err := sourceScraper.GetSourceInfo()
+		searchable[source.SourceID] = err == nil && info.SupportsSearch
+	}
+	return searchable
+}
+
+// mapSource links an anime to its entry on a source picked by the user, or
+// on any source when the user searches all of them. The sources are searched
+// for the anime's title and the closest result is used when it matches well
+// enough; otherwise the user confirms the suggestion or picks another result.
+// It reports whether a link was made.
+func (a *App) mapSource(db *database.DB, animeID int64) (bool, error) {
+	anime, err := db.GetAnime(animeID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get anime: %w", err)
+	}
+
+	sources, filtered, err := a.browsableSources(db)
+	if err != nil {
+		return false, err
+	}
+	if len(sources) == 0 {
+		fmt.Println("No sources installed, add an extension first")
+		return false, nil
+	}
+	searchable := a.searchableSources(sources)
+	choice, err := ui.ShowSourceSearchSelection(sources, searchable, filtered)
+	if err != nil || choice == "" {
+		return false, err
+	}
+
+	var searched []*database.Source
+	for _, source := range sources {
+		if searchable[source.SourceID] {
+			searched = append(searched, source)
+		}
+	}
+	switch choice {
+	case ui.AllLanguages, ui.PreferredLanguage:
+		if err := db.SetConfig(database.ConfigAllSourceLanguages, strconv.FormatBool(choice == ui.AllLanguages)); err != nil {
+			return false, fmt.Errorf("failed to save language preference: %w", err)
+		}
+		return a.mapSource(db, animeID)
+	case ui.AllSources:
+	default:
+		searched = nil
+		for _, source := range sources {
+			if source.SourceID == choice && searchable[source.SourceID] {
+				searched = append(searched, source)
+			}
+		}
+	}
+
+	if len(searched) == 0 {
+		fmt.Println("None of the sources support searching")
+		return false, nil
+	}
+
+	results, more, err := a.searchSources(searched, anime.Title, 1)
+	if err != nil {
+		return false, err
+	}
+	if len(results) == 0 {
+		fmt.Printf("No results for %s\n", anime.Title)
+		return false, nil
+	}
+
+	dub := a.prefersDub(db, animeID)
+	best, score := sourceMatch(anime, resultAnime(results))
+	best = preferAudioVersion(results, best, dub)
+	picked := best
+	for page := 1; score < a.matchThreshold(); {
+		// Suggest the closest result first and let the user confirm it
+		items := []ui.Pair{{Label: results[best].label(len(searched) > 1) + " (suggested)", Value: strconv.Itoa(best)}}
+		for i, result := range results {
+			if i != best {
+				items = append(items, ui.Pair{Label: result.label(len(searched) > 1), Value: strconv.Itoa(i)})
+			}
+		}
+		if more {
+			items = append(items, ui.Pair{Label: "Load more results", Value: ui.LoadMore})
+		}
+		choice, err := ui.OpenMenu(ui.List, items)
+		if err != nil || choice == "" {
+			return false, err
+		}
+		if choice != ui.LoadMore {
+			if picked, err = strconv.Atoi(choice); err != nil {
+				return false, fmt.Errorf("invalid choice %q: %w", choice, err)
+			}
+			break
+		}
+
+		// Match again against everything found so far
+		page++
+		next, nextMore, err := a.searchSources(searched, anime.Title, page)
+		if err != nil {
+			return false, err
+		}
+		results, more = append(results, next...), nextMore
+		best, score = sourceMatch(anime, resultAnime(results))
+		best = preferAudioVersion(results, best, dub)
+		picked = best
+	}
+
+	match := results[picked]
+	offset, err := askEpisodeOffset(match.source.Name)
+	if err != nil {
+		return false, err
+	}
+	if err := db.AddAnimeSource(&database.AnimeSource{
+		AnimeID:       animeID,
+		SourceID:      match.source.ID,
+		SourceAnimeID: match.anime.ID,
+		EpisodeOffset: offset,
+	}); err != nil {
+		return false, fmt.Errorf("failed to map source: %w", err)
+	}
+	fmt.Printf("Mapped %s to %s on %s\n", anime.Title, match.anime.Title, match.source.Name)
+	return true, nil
+}
+
+// askEpisodeOffset asks how far the episode numbers of a source are ahead of
+// the anime's. Leaving the prompt blank or dismissing it means none.
+func askEpisodeOffset(sourceName string) (float64, error) {
+	prompt := fmt.Sprintf("Episode offset on %s, e.g. 12 if it numbers this season 13-24 (blank for none)", sourceName)
+	input, err := ui.ShowTextInput(prompt, ui.UserInput, nil)
+	if errors.Is(err, ui.ErrCancelled) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return parseEpisodeOffset(input)
+}
+
+// parseEpisodeOffset reads an episode offset typed by the user, blank being 0
+func parseEpisodeOffset(input string) (float64, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return 0, nil
+	}
+	offset, err := strconv.ParseFloat(input, 64)
+	if err != nil || math.IsNaN(offset) || math.IsInf(offset, 0) {
+		return 0, fmt.Errorf("invalid episode offset %q", input)
+	}
+	return offset, nil
+}
+
+// handleEpisodeOffset changes the episode offset of the source an anime is
+// watched from
+func (a *App) handleEpisodeOffset(db *database.DB, animeID int64) error {
+	sourceID, err := a.chooseSource(db, animeID, false)
+	if errors.Is(err, errNoSources) {
+		fmt.Println("This anime isn't mapped to any installed source yet")
+		return nil
+	}
+	if err != nil || sourceID == "" {
+		return err
+	}
+	source, err := db.GetSourceByID(sourceID)
+	if err != nil {
+		return fmt.Errorf("failed to get source %s: %w", sourceID, err)
+	}
+
+	offset, err := askEpisodeOffset(source.Name)
+	if err != nil {
+		return err
+	}
+	if err := db.SetAnimeSourceOffset(animeID, source.ID, offset); err != nil {
+		return fmt.Errorf("failed to set episode offset: %w", err)
+	}
+	fmt.Printf("Episode offset on %s set to %g\n", source.Name, offset)
+	return nil
+}
+
+// browsableSources returns the installed sources to search and whether they
+// were limited to the preferred language (video.default_language). Sources
+// of every language are returned when the user asked for all languages or
+// none match the preference.
+func (a *App) browsableSources(db *database.DB) ([]*database.Source, bool, error) {
+	sources, err := db.GetAllSources()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get sources: %w", err)
+	}
+
+	preferred := a.config.Video.DefaultLanguage
+	all, err := db.GetConfigOrDefault(database.ConfigAllSourceLanguages)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get language preference: %w", err)
+	}
+	if preferred == "" || all == "true" {
+		return sources, false, nil
+	}
+
+	var matching []*database.Source
+	for _, source := range sources {
+		if sourceLanguageMatches(source.Language, preferred) {
+			matching = append(matching, source)
+		}
+	}
+	if len(matching) == 0 {
+		return sources, false, nil
+	}
+	return matching, true, nil
+}
+
+// sourceLanguageMatches reports whether a source in language serves the
+// preferred language. Regional variants like "en-US" match "en", and sources
+// marked "all" or "multi" match every language.
+func sourceLanguageMatches(language, preferred string) bool {
+	switch baseLanguage(language) {
+	case "all", "multi":
+		return true
+	default:
+		return baseLanguage(language) == baseLanguage(preferred)
+	}
+}
+
+// baseLanguage lowercases a language code and drops its region, so "en-US"
+// becomes "en"
+func baseLanguage(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if i := strings.IndexAny(lang, "-_"); i >= 0 {
+		lang = lang[:i]
+	}
+	return lang
+}
+
+// sourceResult is an anime found on a source
+type sourceResult struct {
+	source *database.Source
+	anime  scraper.Anime
+}
+
+// label names the result in a menu, with its source when several were searched
+func (r sourceResult) label(withSource bool) string {
+	if withSource {
+		return fmt.Sprintf("%s [%s]", r.anime.Title, r.source.Name)
+	}
+	return r.anime.Title
+}
+
+// resultAnime returns the anime of each result
+func resultAnime(results []sourceResult) []scraper.Anime {
+	animes := make([]scraper.Anime, len(results))
+	for i, result := range results {
+		animes[i] = result.anime
+	}
+	return animes
+}
+
+// searchSources searches one page of each source for query and gathers the
+// results, reporting whether any source has another page. Sources that
+// can't search are skipped, and a source that fails is logged and skipped
+// unless every source fails.
+func (a *App) searchSources(sources []*database.Source, query string, page int) ([]sourceResult, bool, error) {
+	var results []sourceResult
+	var more bool
+	var lastErr error
+	for _, source := range sources {
+		sourceScraper, err := a.scraperFor(source)
+		if err == nil {
+			var found scraper.AnimePage
+			if found, err = sourceScraper.SearchAnime(query, page, ""); err == nil {
+				for _, anime := range found.Animes {
+					results = append(results, sourceResult{source: source, anime: anime})
+				}
+				more = more || found.HasNextPage
+				continue
+			}
+			if errors.Is(err, scraper.ErrUnsupported) {
+				continue
+			}
+		}
+		lastErr = fmt.Errorf("failed to search %s: %w", source.Name, err)
+		logger.Warn(lastErr.Error())
+	}
+
+	if len(results) == 0 && lastErr != nil {
+		return nil, false, lastErr
+	}
+	return results, more, nil
+}
+
+// matchThreshold is the score from which a source result is mapped without asking
+func (a *App) matchThreshold() float64 {
+	if a.config.Extensions.MatchThreshold > 0 {
+		return a.config.Extensions.MatchThreshold
+	}
+	return matcher.DefaultThreshold
+}
+
+// sourceMatch returns the index of the source result closest to any of the
+// anime's titles, and its score
+func sourceMatch(anime *database.Anime, results []scraper.Anime) (int, float64) {
+	// Flatten the titles of all results, remembering which result each is from
+	var candidates []string
+	var owners []int
+	for i, result := range results {
+		for _, title := range append([]string{result.Title}, result.AlternativeTitles...) {
+			candidates = append(candidates, title)
+			owners = append(owners, i)
+		}
+	}
+
+	best, bestScore := 0, -1.0
+	for _, title := range append([]string{anime.Title, anime.OriginalTitle}, anime.AlternativeTitles...) {
+		if title == "" {
+			continue
+		}
+		if index, score := matcher.BestMatch(title, candidates); index >= 0 && score > bestScore {
+			best, bestScore = owners[index], score
+		}
+	}
+	return best, bestScore
+}
+
+// audioVersionMarker matches the "(Dub)" or "[Sub]" sources add to the titles
+// of the separate dubbed and subbed entries of an anime
+var audioVersionMarker = regexp.MustCompile(`(?i)\s*[(\[](sub|subbed|dub|dubbed)[)\]]`)
+
+// preferAudioVersion returns the result to use instead of results[best] when
+// its source lists the sub and dub of the anime as separate entries and
+// results[best] is the one not preferred
+func preferAudioVersion(results []sourceResult, best int, dub bool) int {
+	wanted := "sub"
+	if dub {
+		wanted = "dub"
+	}
+	match := results[best]
+	if match.anime.SubDub == "" || match.anime.SubDub == "both" || match.anime.SubDub == wanted {
+		return best
+	}
+
+	title := strings.ToLower(strings.TrimSpace(audioVersionMarker.ReplaceAllString(match.anime.Title, "")))
+	for i, result := range results {
+		if result.source.ID != match.source.ID || (result.anime.SubDub != wanted && result.anime.SubDub != "both") {
+			continue
+		}
+		if strings.ToLower(strings.TrimSpace(audioVersionMarker.ReplaceAllString(result.anime.Title, ""))) == title {
+			return i
+		}
+	}
+	return best
+}
+
+// handleWatch plays an episode of an anime from its remembered source and
+// records the episode as watched on the active tracker. Afterwards the next
+// unwatched episode is offered, or played right away with video.autoplay_next,
+// until the series runs out.
+func (a *App) handleWatch(ctx context.Context, db *database.DB, animeID int64, totalEpisodes int) error {
+	if a.offline() {
+		return errOffline
+	}
+
+	sourceID, err := a.watchSource(db, animeID)
+	if err != nil || sourceID == "" {
+		return err
+	}
+
+	fillers, err := db.GetFillerEpisodes(animeID)
+	if err != nil {
+		return fmt.Errorf("failed to get filler episodes: %w", err)
+	}
+	episode, err := a.selectEpisode(db, animeID, totalEpisodes, fillers)
+	if errors.Is(err, ui.ErrCancelled) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return a.watchFrom(ctx, db, animeID, sourceID, totalEpisodes, fillers, episode)
+}
+
+// handleContinueWatching plays the episode that was playing when pair last
+// exited without the player stopping, or otherwise lets the user pick an
+// episode of the anime watched last
+func (a *App) handleContinueWatching(ctx context.Context, db *database.DB) error {
+	if a.offline() {
+		return errOffline
+	}
+
+	var now *tracker.WatchingNow
+	if a.syncMgr != nil {
+		var err error
+		if now, err = a.syncMgr.WatchingNow(); err != nil {
+			return err
+		}
+	}
+	if now == nil {
+		anime, err := db.GetLastWatchedAnime()
+		if errors.Is(err, sql.ErrNoRows) {
+			fmt.Println("Nothing watched yet")
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get last watched anime: %w", err)
+		}
+		return a.handleWatch(ctx, db, anime.ID, anime.TotalEpisodes)
+	}
+
+	anime, err := db.GetAnimeByID(now.AnimeID)
+	if err != nil {
+		return fmt.Errorf("failed to get anime: %w", err)
+	}
+	sourceID, err := a.watchSource(db, anime.ID)
+	if err != nil || sourceID == "" {
+		return err
+	}
+	fillers, err := db.GetFillerEpisodes(anime.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get filler episodes: %w", err)
+	}
+	fmt.Printf("Continuing %s episode %g\n", anime.Title, now.Episode)
+	return a.watchFrom(ctx, db, anime.ID, sourceID, anime.TotalEpisodes, fillers, now.Episode)
+}
+
+// watchSource returns the source to watch an anime from, offering to map
+// the anime to a source when it isn't yet. It returns "" when the user
+// picks none.
+func (a *App) watchSource(db *database.DB, animeID int64) (string, error) {
+	sourceID, err := a.chooseSource(db, animeID, false)
+	if errors.Is(err, errNoSources) {
+		fmt.Println("This anime isn't mapped to any installed source yet")
+		mapped, mapErr := a.mapSource(db, animeID)
+		if mapErr != nil || !mapped {
+			return "", mapErr
+		}
+		sourceID, err = a.chooseSource(db, animeID, false)
+	}
+	return sourceID, err
+}
+
+// watchFrom plays episodes of an anime from a source, starting with episode
+func (a *App) watchFrom(ctx context.Context, db *database.DB, animeID int64, sourceID string, totalEpisodes int, fillers map[float64]bool, episode float64) error {
+	t, err := a.activeTracker()
+	if err != nil {
+		return err
+	}
+
+	for {
+		if err := a.playEpisode(ctx, db, t, animeID, sourceID, episode); err != nil {
+			return err
+		}
+
+		next, ok, err := a.nextEpisode(db, animeID, episode, totalEpisodes, fillers)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			if err := a.finishRewatch(ctx, db, t, animeID); err != nil {
+				return err
+			}
+			confirmed, err := showConfirm("Series complete — mark completed?")
+			if err != nil || !confirmed {
+				return err
+			}
+			if err := markCompleted(ctx, db, t, animeID, a.remoteID(db, animeID, t), totalEpisodes, 0); err != nil {
+				return err
+			}
+			return a.offerNextInSeries(ctx, db, animeID)
+		}
+
+		if !a.config.Video.AutoplayNext {
+			confirmed, err := showConfirm(fmt.Sprintf("Play next episode (%g)", next))
+			if err != nil || !confirmed {
+				return err
+			}
+		}
+		episode = next
+	}
+}
+
+// playEpisode resolves the streams of an episode on a source, plays the
+// preferred one and records the episode as watched
+func (a *App) playEpisode(ctx context.Context, db *database.DB, t tracker.Tracker, animeID int64, sourceID string, episode float64) error {
+	sourceScraper, mapping, err := a.sourceScraper(animeID, sourceID)
+	if err != nil {
+		return err
+	}
+	sourceAnimeID, sourceEpisode := mapping.SourceAnimeID, mapping.SourceEpisode(episode)
+
+	stream, err := a.resolveStream(ctx, sourceScraper, sourceAnimeID, sourceEpisode)
+	if err != nil {
+		return err
+	}
+
+	start, err := a.chooseStart(db, t, animeID, episode)
+	if err != nil {
+		return err
+	}
+
+	// The user may have taken a while to answer, by which time the stream
+	// URL can have expired
+	stream, err = a.refreshStream(ctx, sourceScraper, sourceAnimeID, sourceEpisode, stream)
+	if err != nil {
+		return err
+	}
+
+	audio := pickAudioTrack(stream.video.AudioTracks, a.prefersDub(db, animeID), a.config.Video.DefaultLanguage)
+
+	a.startWatching(ctx, animeID, episode)
+	err = playStream(ctx, stream.video, audio, stream.subtitles, start)
+	a.stopWatching()
+	if err != nil {
+		return err
+	}
+
+	if err := db.MarkEpisodeWatched(animeID, episode, sourceID); err != nil {
+		return fmt.Errorf("failed to mark episode watched: %w", err)
+	}
+
+	// Every tracker the anime is on gets the episode, and its local record
+	// once it took it. Without a sync manager only the active tracker is told.
+	if a.syncMgr == nil {
+		if err := t.UpdateAnimeStatus(ctx, a.remoteID(db, animeID, t), "", episode, 0); err != nil {
+			return err
+		}
+		return db.AdvanceAnimeProgress(animeID, t.Name(), episode)
+	}
+	return a.syncMgr.SyncEpisodeProgress(animeID, episode)
+}
+
+// startWatching records the episode that is about to play. Failures only
+// lose the watching now state, so they are logged rather than returned.
+func (a *App) startWatching(ctx context.Context, animeID int64, episode float64) {
+	if a.syncMgr == nil {
+		return
+	}
+	if err := a.syncMgr.StartWatching(ctx, animeID, episode); err != nil {
+		logger.Debug("Failed to record watching state: " + err.Error())
+	}
+}
+
+// stopWatching clears the episode recorded by startWatching
+func (a *App) stopWatching() {
+	if a.syncMgr == nil {
+		return
+	}
+	if err := a.syncMgr.StopWatching(); err != nil {
+		logger.Debug("Failed to clear watching state: " + err.Error())
+	}
+}
+
+// chooseStart returns where to start an episode, offering to resume it from
+// its stored position
+func (a *App) chooseStart(db *database.DB, t tracker.Tracker, animeID int64, episode float64) (int, error) {
+	progress, err := db.GetEpisodeProgress(animeID, episode)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get episode progress: %w", err)
+	}
+
+	position := resumePosition(progress, a.rewatching(db, t, animeID), a.config.Video.ResumeOnRewatch)
+	if position == 0 {
+		return 0, nil
+	}
+	confirmed, err := showConfirm(fmt.Sprintf("Resume from %d:%02d?", position/60, position%60))
+	if err != nil || !confirmed {
+		return 0, err
+	}
+	return position, nil
+}
+
+// resumePosition returns the position in seconds to resume an episode from,
+// or 0 to start over. Watched episodes start over unless the anime is being
+// rewatched and video.resume_on_rewatch is set.
+func resumePosition(progress *database.EpisodeProgress, rewatching, resumeOnRewatch bool) int {
+	if progress == nil || progress.Position <= 0 {
+		return 0
+	}
+	if progress.Duration > 0 && progress.Position >= progress.Duration {
+		return 0
+	}
+	if progress.Watched && !(rewatching && resumeOnRewatch) {
+		return 0
+	}
+	return progress.Position
+}
+
+// rewatching reports whether the anime is being rewatched on a tracker
+func (a *App) rewatching(db *database.DB, t tracker.Tracker, animeID int64) bool {
+	tracking, err := db.GetAnimeTracking(animeID, t.Name())
+	return err == nil && tracking != nil && tracking.IsRewatching
+}
+
+// finishRewatch ends a rewatch once the series is complete, on the tracker
+// first so a failure leaves both sides rewatching. The resume positions kept
+// for it are cleared so the next rewatch starts over.
+func (a *App) finishRewatch(ctx context.Context, db *database.DB, t tracker.Tracker, animeID int64) error {
+	tracking, err := db.GetAnimeTracking(animeID, t.Name())
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !tracking.IsRewatching) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get tracking: %w", err)
+	}
+
+	if finisher, ok := t.(tracker.RewatchFinisher); ok {
+		if err := finisher.FinishRewatch(ctx, tracking.TrackerID); err != nil {
+			return fmt.Errorf("failed to finish rewatch on %s: %w", t.Name(), err)
+		}
+	}
+
+	if err := db.ClearEpisodePositions(animeID); err != nil {
+		return fmt.Errorf("failed to clear resume positions: %w", err)
+	}
+	tracking.IsRewatching = false
+	return db.UpdateAnimeTrackingObject(tracking)
+}
+
+// nextEpisode returns the episode to play after the given one, skipping
+// fillers when configured. ok is false when the series is finished.
+func (a *App) nextEpisode(db *database.DB, animeID int64, episode float64, totalEpisodes int, fillers map[float64]bool) (float64, bool, error) {
+	if a.config.Video.SkipFillers {
+		episode = scraper.AdvancePastFillers(episode, fillers)
+	}
+	next, ok, err := db.GetNextUnwatchedEpisode(animeID, episode, totalEpisodes)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to find the next episode: %w", err)
+	}
+	return next, ok, nil
+}
+
+// resolvedStream is the stream picked for an episode and when its URL was
+// resolved
+type resolvedStream struct {
+	video      *scraper.Video
+	subtitles  []scraper.Track
+	resolvedAt time.Time
+}
+
+// resolveStream gets the streams of an episode from a source and picks the
+// preferred one
+func (a *App) resolveStream(ctx context.Context, s scraper.Scraper, sourceAnimeID string, episode float64) (*resolvedStream, error) {
+	stopSpinner := ui.ShowSpinner(ctx, "Resolving streams…")
+	response, err := s.GetVideoList(sourceAnimeID, episode)
+	stopSpinner()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get streams: %w", err)
+	}
+
+	video := pickStream(response.Streams, a.config.Video.QualityPrefer)
+	if video == nil {
+		return nil, fmt.Errorf("no streams found for episode %g", episode)
+	}
+	return &resolvedStream{video: video, subtitles: response.Subtitles, resolvedAt: time.Now()}, nil
+}
+
+// refreshStream returns stream, or a newly resolved one when it is older
+// than video.stream_ttl or its URL no longer works
+func (a *App) refreshStream(ctx context.Context, s scraper.Scraper, sourceAnimeID string, episode float64, stream *resolvedStream) (*resolvedStream, error) {
+	ttl := time.Duration(a.config.Video.StreamTTL) * time.Second
+	if !streamExpired(ctx, stream, ttl, time.Now()) {
+		return stream, nil
+	}
+	logger.Debug(fmt.Sprintf("Stream of episode %g expired, resolving it again", episode))
+	return a.resolveStream(ctx, s, sourceAnimeID, episode)
+}
+
+// streamProbeTimeout bounds the request checking that a stream URL works
+const streamProbeTimeout = 5 * time.Second
+
+// streamExpired reports whether a stream was resolved more than ttl before
+// now, or a HEAD request to it is refused as forbidden or missing. A ttl of
+// zero or less only probes. Probe failures other than those statuses don't
+// count, the player reports them better.
+func streamExpired(ctx context.Context, stream *resolvedStream, ttl time.Duration, now time.Time) bool {
+	if ttl > 0 && now.Sub(stream.resolvedAt) > ttl {
+		return true
+	}
+
+	if !strings.HasPrefix(stream.video.VideoURL, "http://") && !strings.HasPrefix(stream.video.VideoURL, "https://") {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(ctx, streamProbeTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, stream.video.VideoURL, nil)
+	if err != nil {
+		return false
+	}
+	httpclient.Identify(req)
+	for name, value := range stream.video.Headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := httpclient.Default().Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusForbidden, http.StatusNotFound, http.StatusGone:
+		return true
+	}
+	return false
+}
+
+// pickStream returns the first stream matching the preferred quality, falling
+// back to the first stream. It returns nil if there are no streams.
+func pickStream(streams []scraper.Video, quality string) *scraper.Video {
+	if len(streams) == 0 {
+		return nil
+	}
+
+	quality = strings.ToLower(strings.TrimSpace(quality))
+	if quality != "" {
+		for i := range streams {
+			if strings.Contains(strings.ToLower(streams[i].Quality), quality) {
+				return &streams[i]
+			}
+		}
+	}
+
+	return &streams[0]
+}
+
+// originalAudio holds the language codes of the original, Japanese, audio
+var originalAudio = map[string]bool{"ja": true, "jp": true, "jpn": true, "japanese": true}
+
+// pickAudioTrack returns the audio track to play. With dub it is the first
+// track in language, or else the first that isn't the original audio;
+// otherwise it is the first original audio track. It returns nil when no
+// track fits the preference, so the stream plays its own audio.
+func pickAudioTrack(tracks []scraper.Track, dub bool, language string) *scraper.Track {
+	language = baseLanguage(language)
+
+	if dub {
+		if language != "" {
+			for i := range tracks {
+				if strings.HasPrefix(baseLanguage(tracks[i].Lang), language) {
+					return &tracks[i]
+				}
+			}
+		}
+		for i := range tracks {
+			if !originalAudio[baseLanguage(tracks[i].Lang)] {
+				return &tracks[i]
+			}
+		}
+	} else {
+		for i := range tracks {
+			if originalAudio[baseLanguage(tracks[i].Lang)] {
+				return &tracks[i]
+			}
+		}
+	}
+
+	return nil
+}
+
+// playStream plays a stream in mpv from start seconds in, with audio as its
+// sound when set, and waits for the player to exit
+func playStream(ctx context.Context, video *scraper.Video, audio *scraper.Track, subtitles []scraper.Track, start int) error {
+	cmd := exec.CommandContext(ctx, playerBinary, mpvArgs(video, audio, subtitles, start)...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to play stream: %w", err)
+	}
+	return nil
+}
+
+// mpvArgs builds the mpv arguments for a stream with its headers, audio
+// track, subtitles and start position
+func mpvArgs(video *scraper.Video, audio *scraper.Track, subtitles []scraper.Track, start int) []string {
+	var args []string
+	if start > 0 {
+		args = append(args, fmt.Sprintf("--start=%d", start))
+	}
+
+	headers := make([]string, 0, len(video.Headers))
+	for name, value := range video.Headers {
+		headers = append(headers, name+": "+value)
+	}
+	sort.Strings(headers)
+	for _, header := range headers {
+		args = append(args, "--http-header-fields-append="+header)
+	}
+
+	if audio != nil {
+		args = append(args, "--audio-file="+audio.URL)
+	}
+	if video.SubtitleTrack != nil {
+		args = append(args, "--sub-file="+video.SubtitleTrack.URL)
+	}
+	for _, track := range subtitles {
+		args = append(args, "--sub-file="+track.URL)
+	}
+
+	return append(args, video.VideoURL)
+}
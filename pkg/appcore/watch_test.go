@@ -0,0 +1,385 @@
+package appcore
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/wraient/pair/pkg/database"
+	"github.com/wraient/pair/pkg/matcher"
+	"github.com/wraient/pair/pkg/scraper"
+)
+
+func TestChooseSourceRemembersLast(t *testing.T) {
+	app, db := setupTestApp(t)
+
+	first := addFakeSource(t, db, writeFakeExtension(t, `{"status": "success", "data": []}`))
+	second := &database.Source{SourceID: "other-source", ExtensionID: first.ExtensionID, Name: "Other Source", Language: "en"}
+	if err := db.AddSource(second); err != nil {
+		t.Fatalf("Failed to add source: %v", err)
+	}
+
+	anime := &database.Anime{Title: "Test Anime"}
+	if err := db.AddAnime(anime); err != nil {
+		t.Fatalf("Failed to add anime: %v", err)
+	}
+	for _, source := range []*database.Source{first, second} {
+		if err := db.AddAnimeSource(&database.AnimeSource{AnimeID: anime.ID, SourceID: source.ID, SourceAnimeID: "test-anime"}); err != nil {
+			t.Fatalf("Failed to map anime to source: %v", err)
+		}
+	}
+
+	// Test that the remembered source is picked without asking
+	if err := db.SetConfig(lastSourceKey(anime.ID), second.SourceID); err != nil {
+		t.Fatalf("Failed to set config: %v", err)
+	}
+	sourceID, err := app.chooseSource(db, anime.ID, false)
+	if err != nil {
+		t.Fatalf("Failed to choose source: %v", err)
+	}
+	if sourceID != second.SourceID {
+		t.Errorf("Expected source %s, got %s", second.SourceID, sourceID)
+	}
+
+	// Test that the remembered source is kept per anime
+	other := &database.Anime{Title: "Other Anime"}
+	if err := db.AddAnime(other); err != nil {
+		t.Fatalf("Failed to add anime: %v", err)
+	}
+	if err := db.AddAnimeSource(&database.AnimeSource{AnimeID: other.ID, SourceID: first.ID, SourceAnimeID: "other-anime"}); err != nil {
+		t.Fatalf("Failed to map anime to source: %v", err)
+	}
+	sourceID, err = app.chooseSource(db, other.ID, false)
+	if err != nil {
+		t.Fatalf("Failed to choose source: %v", err)
+	}
+	if sourceID != first.SourceID {
+		t.Errorf("Expected source %s, got %s", first.SourceID, sourceID)
+	}
+
+	// Test that an unmapped anime reports no sources
+	unmapped := &database.Anime{Title: "Unmapped Anime"}
+	if err := db.AddAnime(unmapped); err != nil {
+		t.Fatalf("Failed to add anime: %v", err)
+	}
+	if _, err := app.chooseSource(db, unmapped.ID, false); err != errNoSources {
+		t.Errorf("Expected errNoSources, got %v", err)
+	}
+}
+
+func TestSourceMatch(t *testing.T) {
+	anime := &database.Anime{
+		Title:             "Sousou no Frieren",
+		AlternativeTitles: []string{"Frieren: Beyond Journey's End"},
+	}
+	results := []scraper.Anime{
+		{ID: "1", Title: "Frieren Season 2"},
+		{ID: "2", Title: "Sōsō no Frieren", AlternativeTitles: []string{"Frieren"}},
+	}
+
+	// Test that the result matching any of the titles is suggested
+	index, score := sourceMatch(anime, results)
+	if index != 1 {
+		t.Errorf("Expected result 1, got %d", index)
+	}
+	if score < matcher.DefaultThreshold {
+		t.Errorf("Expected a trusted match, got %.2f", score)
+	}
+
+	// Test that a weak match stays below the threshold
+	app, _ := setupTestApp(t)
+	_, score = sourceMatch(anime, []scraper.Anime{{ID: "3", Title: "Dungeon Meshi"}})
+	if score >= app.matchThreshold() {
+		t.Errorf("Expected an untrusted match, got %.2f", score)
+	}
+
+	// Test that the configured threshold is used
+	app.config.Extensions.MatchThreshold = 0.5
+	if app.matchThreshold() != 0.5 {
+		t.Errorf("Expected threshold 0.5, got %.2f", app.matchThreshold())
+	}
+}
+
+func TestNextEpisodeSkipsFillers(t *testing.T) {
+	app, db := setupTestApp(t)
+
+	anime := &database.Anime{Title: "Long Show", TotalEpisodes: 6}
+	if err := db.AddAnime(anime); err != nil {
+		t.Fatalf("Failed to add anime: %v", err)
+	}
+	fillers := map[float64]bool{3: true, 4: true, 6: true}
+
+	// Test that fillers are played unless they are skipped
+	next, ok, err := app.nextEpisode(db, anime.ID, 2, anime.TotalEpisodes, fillers)
+	if err != nil || !ok || next != 3 {
+		t.Errorf("Expected episode 3, got %g (ok %v, err %v)", next, ok, err)
+	}
+	app.config.Video.SkipFillers = true
+	next, ok, err = app.nextEpisode(db, anime.ID, 2, anime.TotalEpisodes, fillers)
+	if err != nil || !ok || next != 5 {
+		t.Errorf("Expected episode 5, got %g (ok %v, err %v)", next, ok, err)
+	}
+
+	// Test that trailing fillers end the series when skipped
+	if _, ok, err = app.nextEpisode(db, anime.ID, 5, anime.TotalEpisodes, fillers); err != nil || ok {
+		t.Errorf("Expected the series to end, got ok %v, err %v", ok, err)
+	}
+}
+
+func TestSearchSourcesFiltersLanguage(t *testing.T) {
+	app, db := setupTestApp(t)
+	app.config.Video.DefaultLanguage = "en"
+
+	// One source per language, each answering with a single result
+	for _, lang := range []string{"en-US", "ja"} {
+		path := writeFakeExtension(t, `{"status": "success", "data": [{"anime_id": "`+lang+`-1", "title": "Show `+lang+`"}]}`)
+		ext := &database.Extension{Name: "Fake " + lang, Package: "fake." + lang, Language: lang, Version: "1.0", Path: path}
+		if err := db.AddExtension(ext); err != nil {
+			t.Fatalf("Failed to add extension: %v", err)
+		}
+		source := &database.Source{SourceID: "source-" + lang, ExtensionID: ext.ID, Name: "Source " + lang, Language: lang}
+		if err := db.AddSource(source); err != nil {
+			t.Fatalf("Failed to add source: %v", err)
+		}
+	}
+
+	search := func() ([]sourceResult, bool) {
+		sources, filtered, err := app.browsableSources(db)
+		if err != nil {
+			t.Fatalf("Failed to get sources: %v", err)
+		}
+		results, _, err := app.searchSources(sources, "show", 1)
+		if err != nil {
+			t.Fatalf("Failed to search sources: %v", err)
+		}
+		return results, filtered
+	}
+
+	// Test that the source in another language isn't searched
+	results, filtered := search()
+	if !filtered {
+		t.Errorf("Expected the sources to be filtered")
+	}
+	if len(results) != 1 || results[0].source.Language != "en-US" {
+		t.Fatalf("Expected only the English result, got %+v", results)
+	}
+
+	// Test that the all languages override searches every source
+	if err := db.SetConfig(database.ConfigAllSourceLanguages, "true"); err != nil {
+		t.Fatalf("Failed to set config: %v", err)
+	}
+	results, filtered = search()
+	if filtered || len(results) != 2 {
+		t.Errorf("Expected results from both sources, got %+v", results)
+	}
+}
+
+func TestResumePosition(t *testing.T) {
+	started := &database.EpisodeProgress{Position: 600, Duration: 1440}
+	watched := &database.EpisodeProgress{Position: 600, Duration: 1440, Watched: true}
+	finished := &database.EpisodeProgress{Position: 1440, Duration: 1440}
+
+	tests := []struct {
+		name            string
+		progress        *database.EpisodeProgress
+		rewatching      bool
+		resumeOnRewatch bool
+		want            int
+	}{
+		{"no progress", nil, false, false, 0},
+		{"started", started, false, false, 600},
+		{"played to the end", finished, false, false, 0},
+		{"watched", watched, false, false, 0},
+		{"watched while rewatching", watched, true, false, 0},
+		{"watched with resume on rewatch but not rewatching", watched, false, true, 0},
+		{"watched while rewatching with resume on rewatch", watched, true, true, 600},
+	}
+
+	for _, tt := range tests {
+		if got := resumePosition(tt.progress, tt.rewatching, tt.resumeOnRewatch); got != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestRewatchResume(t *testing.T) {
+	mock := newMockTracker("local")
+	app, db := setupTestApp(t, mock)
+	app.config.Video.ResumeOnRewatch = true
+
+	anime := &database.Anime{Title: "Rewatched Show", TotalEpisodes: 2}
+	if err := db.AddAnime(anime); err != nil {
+		t.Fatalf("Failed to add anime: %v", err)
+	}
+	tracking := &database.AnimeTracking{AnimeID: anime.ID, Tracker: "local", TrackerID: "101", Status: "watching", IsRewatching: true}
+	if err := db.AddAnimeTracking(tracking); err != nil {
+		t.Fatalf("Failed to add tracking: %v", err)
+	}
+	progress := &database.EpisodeProgress{AnimeID: anime.ID, EpisodeNumber: 1, Position: 754, Duration: 1440, PlaybackSpeed: 1, Watched: true}
+	if err := db.AddEpisodeProgress(progress); err != nil {
+		t.Fatalf("Failed to add episode progress: %v", err)
+	}
+
+	var prompts []string
+	previous := showConfirm
+	showConfirm = func(prompt string) (bool, error) {
+		prompts = append(prompts, prompt)
+		return true, nil
+	}
+	defer func() { showConfirm = previous }()
+
+	// Test that a watched episode is offered to resume while rewatching
+	start, err := app.chooseStart(db, mock, anime.ID, 1)
+	if err != nil {
+		t.Fatalf("Failed to choose start: %v", err)
+	}
+	if start != 754 {
+		t.Errorf("Expected to resume at 754s, got %d", start)
+	}
+	if len(prompts) != 1 || prompts[0] != "Resume from 12:34?" {
+		t.Errorf("Expected a resume prompt, got %v", prompts)
+	}
+
+	// Test that a failure on the tracker keeps the rewatch going locally
+	mock.updateErr = errors.New("server down")
+	if err := app.finishRewatch(context.Background(), db, mock, anime.ID); err == nil {
+		t.Errorf("Expected the tracker error to be returned")
+	}
+	stored, err := db.GetAnimeTracking(anime.ID, "local")
+	if err != nil {
+		t.Fatalf("Failed to get tracking: %v", err)
+	}
+	if !stored.IsRewatching {
+		t.Errorf("Expected the rewatch to continue")
+	}
+
+	// Test that finishing the rewatch clears it on both sides and its positions
+	mock.updateErr = nil
+	if err := app.finishRewatch(context.Background(), db, mock, anime.ID); err != nil {
+		t.Fatalf("Failed to finish rewatch: %v", err)
+	}
+	stored, err = db.GetAnimeTracking(anime.ID, "local")
+	if err != nil {
+		t.Fatalf("Failed to get tracking: %v", err)
+	}
+	if stored.IsRewatching {
+		t.Errorf("Expected the rewatch to be finished")
+	}
+	if len(mock.rewatches) != 1 || mock.rewatches[0] != "101" {
+		t.Errorf("Expected the rewatch of 101 to be finished on the tracker, got %v", mock.rewatches)
+	}
+	if start, err = app.chooseStart(db, mock, anime.ID, 1); err != nil || start != 0 {
+		t.Errorf("Expected to start over, got %d (%v)", start, err)
+	}
+	if len(prompts) != 1 {
+		t.Errorf("Expected no more prompts, got %v", prompts)
+	}
+}
+
+// videoListScraper answers GetVideoList with a single stream and counts the calls
+type videoListScraper struct {
+	scraper.Scraper
+	url   string
+	calls int
+}
+
+func (s *videoListScraper) GetVideoList(animeID string, episodeNumber float64) (scraper.VideoResponse, error) {
+	s.calls++
+	return scraper.VideoResponse{Streams: []scraper.Video{{Quality: "1080p", VideoURL: s.url}}}, nil
+}
+
+func TestRefreshStream(t *testing.T) {
+	app, _ := setupTestApp(t)
+	app.config.Video.StreamTTL = 60
+
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	source := &videoListScraper{url: server.URL + "/episode.m3u8"}
+	ctx := context.Background()
+	stream, err := app.resolveStream(ctx, source, "show", 1)
+	if err != nil {
+		t.Fatalf("Failed to resolve stream: %v", err)
+	}
+
+	// Test that a fresh working stream isn't resolved again
+	refreshed, err := app.refreshStream(ctx, source, "show", 1, stream)
+	if err != nil {
+		t.Fatalf("Failed to refresh stream: %v", err)
+	}
+	if refreshed != stream || source.calls != 1 {
+		t.Errorf("Expected the fresh stream to be kept, got %d resolves", source.calls)
+	}
+
+	// Test that a stream older than the TTL is resolved again
+	stream.resolvedAt = time.Now().Add(-2 * time.Minute)
+	refreshed, err = app.refreshStream(ctx, source, "show", 1, stream)
+	if err != nil {
+		t.Fatalf("Failed to refresh stream: %v", err)
+	}
+	if refreshed == stream || source.calls != 2 {
+		t.Errorf("Expected the stale stream to be resolved again, got %d resolves", source.calls)
+	}
+
+	// Test that a fresh stream refused by the server is resolved again
+	status = http.StatusForbidden
+	if _, err := app.refreshStream(ctx, source, "show", 1, refreshed); err != nil {
+		t.Fatalf("Failed to refresh stream: %v", err)
+	}
+	if source.calls != 3 {
+		t.Errorf("Expected the refused stream to be resolved again, got %d resolves", source.calls)
+	}
+}
+
+func TestPickAudioTrack(t *testing.T) {
+	tracks := []scraper.Track{
+		{URL: "https://cdn.example/ja.m4a", Lang: "ja"},
+		{URL: "https://cdn.example/de.m4a", Lang: "de"},
+		{URL: "https://cdn.example/en.m4a", Lang: "en-US"},
+	}
+
+	// Test that the dub in the preferred language is picked
+	if track := pickAudioTrack(tracks, true, "en"); track == nil || track.Lang != "en-US" {
+		t.Errorf("Expected the English dub, got %+v", track)
+	}
+
+	// Test that another dub is picked when the language has none
+	if track := pickAudioTrack(tracks, true, "fr"); track == nil || track.Lang != "de" {
+		t.Errorf("Expected the German dub, got %+v", track)
+	}
+
+	// Test that the original audio is picked without the dub preference
+	if track := pickAudioTrack(tracks, false, "en"); track == nil || track.Lang != "ja" {
+		t.Errorf("Expected the original audio, got %+v", track)
+	}
+
+	// Test that the stream's own audio is kept when no track fits
+	if track := pickAudioTrack(tracks[1:], false, "en"); track != nil {
+		t.Errorf("Expected no track when there's no original audio, got %+v", track)
+	}
+	if track := pickAudioTrack(tracks[:1], true, "en"); track != nil {
+		t.Errorf("Expected no track when there's no dub, got %+v", track)
+	}
+	if track := pickAudioTrack(nil, true, "en"); track != nil {
+		t.Errorf("Expected no track, got %+v", track)
+	}
+
+	// Test that an anime's own preference overrides video.prefer_dub
+	app, db := setupTestApp(t)
+	app.config.Video.PreferDub = true
+	if !app.prefersDub(db, 1) {
+		t.Errorf("Expected the global dub preference to be used")
+	}
+	if err := db.SetConfig(audioPreferenceKey(1), "sub"); err != nil {
+		t.Fatalf("Failed to set audio preference: %v", err)
+	}
+	if app.prefersDub(db, 1) {
+		t.Errorf("Expected the anime's sub preference to win")
+	}
+}
@@ -0,0 +1,64 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ClearCaches wipes everything cached under DefaultDir. It returns the number
+// of bytes freed.
+func ClearCaches() (int64, error) {
+	return Clear(DefaultDir())
+}
+
+// Clear removes the files in the subdirectories of dir, returning the number of
+// bytes freed. The directories themselves are
+// kept and files still being written are skipped, so it's safe to run while
+// caches are in use.
+func Clear(dir string) (int64, error) {
+	var freed int64
+	var errs []error
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		// Files directly in dir aren't caches, and dot files are downloads in progress
+		if d.IsDir() || filepath.Dir(path) == dir || strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+
+		info, err := d.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			errs = append(errs, err)
+			return nil
+		}
+
+		if err := os.Remove(path); err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				errs = append(errs, err)
+			}
+			return nil
+		}
+		freed += info.Size()
+		return nil
+	})
+	if err != nil {
+		return freed, fmt.Errorf("failed to clear cache: %w", err)
+	}
+
+	if len(errs) > 0 {
+		return freed, fmt.Errorf("failed to remove some cached files: %w", errors.Join(errs...))
+	}
+	return freed, nil
+}
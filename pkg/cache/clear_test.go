@@ -0,0 +1,56 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestClear(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]int{
+		filepath.Join(dir, "thumbnails", "101"):            100,
+		filepath.Join(dir, "thumbnails", "202"):            250,
+		filepath.Join(dir, "subtitles", "show", "ep1.vtt"): 40,
+	}
+	for path, size := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	// Downloads in progress are left alone
+	partial := filepath.Join(dir, "thumbnails", ".thumbnail-123")
+	if err := os.WriteFile(partial, []byte("partial"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	freed, err := Clear(dir)
+	if err != nil {
+		t.Fatalf("Failed to clear cache: %v", err)
+	}
+	if freed != 390 {
+		t.Errorf("Expected 390 bytes freed, got %d", freed)
+	}
+
+	for path := range files {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be removed", path)
+		}
+	}
+	if _, err := os.Stat(partial); err != nil {
+		t.Errorf("Expected partial download to be kept: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "thumbnails")); err != nil {
+		t.Errorf("Expected cache directory to be kept: %v", err)
+	}
+
+	// Test clearing a cache that doesn't exist yet
+	if freed, err := Clear(filepath.Join(dir, "missing")); err != nil || freed != 0 {
+		t.Errorf("Expected nothing to clear, got %d bytes and %v", freed, err)
+	}
+}
@@ -0,0 +1,172 @@
+// Package cache stores downloaded artwork on disk.
+package cache
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wraient/pair/pkg/httpclient"
+)
+
+// DefaultDir returns the directory pair caches files in
+func DefaultDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = filepath.Join(os.ExpandEnv("$HOME"), ".cache")
+	}
+	return filepath.Join(dir, "pair")
+}
+
+// Thumbnail identifies an image to cache under Key
+type Thumbnail struct {
+	Key string
+	URL string
+}
+
+// ThumbnailCache downloads thumbnails once and keeps them on disk
+type ThumbnailCache struct {
+	dir    string
+	client *http.Client
+}
+
+// NewThumbnailCache creates a thumbnail cache stored in dir
+func NewThumbnailCache(dir string) *ThumbnailCache {
+	return &ThumbnailCache{
+		dir:    dir,
+		client: httpclient.Default(),
+	}
+}
+
+// Path returns where the thumbnail for key is stored
+func (c *ThumbnailCache) Path(key string) string {
+	safe := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, key)
+	return filepath.Join(c.dir, safe)
+}
+
+// Get returns the cached thumbnail for key if it has been downloaded
+func (c *ThumbnailCache) Get(key string) (string, bool) {
+	path := c.Path(key)
+	if info, err := os.Stat(path); err != nil || info.Size() == 0 {
+		return "", false
+	}
+	return path, true
+}
+
+// Fetch returns the cached thumbnail for key, downloading it from url if needed
+func (c *ThumbnailCache) Fetch(ctx context.Context, key, url string) (string, error) {
+	if path, ok := c.Get(key); ok {
+		return path, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	httpclient.Identify(req)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to download thumbnail: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download thumbnail: status %d", resp.StatusCode)
+	}
+
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	// Write to a temporary file so readers never see a partial image
+	tmp, err := os.CreateTemp(c.dir, ".thumbnail-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create thumbnail file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, resp.Body); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to write thumbnail: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to write thumbnail: %w", err)
+	}
+
+	path := c.Path(key)
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", fmt.Errorf("failed to store thumbnail: %w", err)
+	}
+
+	return path, nil
+}
+
+// Prefetch downloads the given thumbnails with at most concurrency downloads
+// in flight, giving up on the rest once timeout has passed. It returns the
+// local paths of the thumbnails that are cached, keyed by Thumbnail.Key.
+func (c *ThumbnailCache) Prefetch(ctx context.Context, thumbnails []Thumbnail, concurrency int, timeout time.Duration) map[string]string {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	var (
+		mu    sync.Mutex
+		wg    sync.WaitGroup
+		paths = make(map[string]string)
+		slots = make(chan struct{}, concurrency)
+	)
+
+	for _, thumbnail := range thumbnails {
+		if thumbnail.URL == "" {
+			continue
+		}
+		if path, ok := c.Get(thumbnail.Key); ok {
+			// Workers started for earlier thumbnails may be writing too
+			mu.Lock()
+			paths[thumbnail.Key] = path
+			mu.Unlock()
+			continue
+		}
+
+		wg.Add(1)
+		go func(thumbnail Thumbnail) {
+			defer wg.Done()
+
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-slots }()
+
+			path, err := c.Fetch(ctx, thumbnail.Key, thumbnail.URL)
+			if err != nil {
+				return
+			}
+
+			mu.Lock()
+			paths[thumbnail.Key] = path
+			mu.Unlock()
+		}(thumbnail)
+	}
+
+	wg.Wait()
+	return paths
+}
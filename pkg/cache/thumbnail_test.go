@@ -0,0 +1,123 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestThumbnailPrefetch(t *testing.T) {
+	var active, maxActive, requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		current := atomic.AddInt32(&active, 1)
+		defer atomic.AddInt32(&active, -1)
+		for {
+			seen := atomic.LoadInt32(&maxActive)
+			if current <= seen || atomic.CompareAndSwapInt32(&maxActive, seen, current) {
+				break
+			}
+		}
+
+		time.Sleep(20 * time.Millisecond)
+		fmt.Fprintf(w, "image %s", r.URL.Path)
+	}))
+	defer server.Close()
+
+	cache := NewThumbnailCache(t.TempDir())
+
+	var thumbnails []Thumbnail
+	for i := 1; i <= 10; i++ {
+		thumbnails = append(thumbnails, Thumbnail{
+			Key: fmt.Sprintf("anime-%d", i),
+			URL: fmt.Sprintf("%s/%d.jpg", server.URL, i),
+		})
+	}
+	thumbnails = append(thumbnails, Thumbnail{Key: "no-image"})
+
+	// Test that downloads are bounded and the cache is populated
+	paths := cache.Prefetch(context.Background(), thumbnails, 3, 5*time.Second)
+	if max := atomic.LoadInt32(&maxActive); max > 3 {
+		t.Errorf("Expected at most 3 concurrent downloads, got %d", max)
+	}
+	if len(paths) != 10 {
+		t.Fatalf("Expected 10 cached thumbnails, got %d", len(paths))
+	}
+
+	data, err := os.ReadFile(paths["anime-4"])
+	if err != nil {
+		t.Fatalf("Failed to read cached thumbnail: %v", err)
+	}
+	if string(data) != "image /4.jpg" {
+		t.Errorf("Expected cached content 'image /4.jpg', got '%s'", data)
+	}
+
+	// Test that a second prefetch is served from the cache
+	paths = cache.Prefetch(context.Background(), thumbnails, 3, 5*time.Second)
+	if len(paths) != 10 {
+		t.Errorf("Expected 10 cached thumbnails, got %d", len(paths))
+	}
+	if got := atomic.LoadInt32(&requests); got != 10 {
+		t.Errorf("Expected 10 downloads in total, got %d", got)
+	}
+}
+
+func TestThumbnailPrefetchTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	cache := NewThumbnailCache(t.TempDir())
+
+	// Test that a stalled server doesn't block past the deadline
+	start := time.Now()
+	paths := cache.Prefetch(context.Background(), []Thumbnail{{Key: "slow", URL: server.URL}}, 2, 50*time.Millisecond)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected prefetch to stop near the deadline, took %v", elapsed)
+	}
+	if len(paths) != 0 {
+		t.Errorf("Expected no cached thumbnails, got %d", len(paths))
+	}
+}
+
+// Test that cache hits and downloads can fill the result together; run with
+// -race to catch unguarded writes
+func TestThumbnailPrefetchMixedHits(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "image %s", r.URL.Path)
+	}))
+	defer server.Close()
+
+	cache := NewThumbnailCache(t.TempDir())
+
+	var thumbnails []Thumbnail
+	for i := 1; i <= 40; i++ {
+		thumbnails = append(thumbnails, Thumbnail{
+			Key: fmt.Sprintf("anime-%d", i),
+			URL: fmt.Sprintf("%s/%d.jpg", server.URL, i),
+		})
+	}
+
+	// Cache every other thumbnail so hits and downloads interleave
+	for i := 0; i < len(thumbnails); i += 2 {
+		if _, err := cache.Fetch(context.Background(), thumbnails[i].Key, thumbnails[i].URL); err != nil {
+			t.Fatalf("Failed to fetch thumbnail: %v", err)
+		}
+	}
+
+	paths := cache.Prefetch(context.Background(), thumbnails, 8, 5*time.Second)
+	if len(paths) != len(thumbnails) {
+		t.Errorf("Expected %d cached thumbnails, got %d", len(thumbnails), len(paths))
+	}
+}
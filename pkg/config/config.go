@@ -1,9 +1,11 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 
 	"github.com/wraient/pair/pkg/database"
@@ -11,11 +13,35 @@ import (
 )
 
 var (
+	// cfg is replaced as a whole under mu rather than modified in place, so
+	// a *Config returned by Get is never written to concurrently
 	cfg  *Config
+	mu   sync.RWMutex
 	once sync.Once
 	db   *database.DB
+
+	// configDir relocates the config and data files when set
+	configDir string
+	// dataDirOverride relocates only the data files when set
+	dataDirOverride string
+	// dbPathOverride is the database file set by SetDBPath. It is kept out
+	// of viper so saving the config never writes it to the config file.
+	dbPathOverride string
+	// profile keeps a separate library and tokens under profiles/<name>
+	profile string
 )
 
+// Environment variables that relocate pair's files
+const (
+	envConfigDir = "PAIR_CONFIG_DIR"
+	envDataDir   = "PAIR_DATA_DIR"
+	envDBPath    = "PAIR_DB_PATH"
+)
+
+// DefaultConflictRetentionDays is how many days logged sync conflicts are
+// kept for review unless configured otherwise
+const DefaultConflictRetentionDays = 30
+
 // UIMode represents the UI mode to use
 type UIMode string
 
@@ -40,20 +66,54 @@ type Config struct {
 		Mode             UIMode `mapstructure:"mode"`
 		ShowImagePreview bool   `mapstructure:"show_image_preview"`
 		ShowEpPrompt     bool   `mapstructure:"show_episode_prompt"`
+		// ThumbnailConcurrency is the number of thumbnails downloaded at once
+		ThumbnailConcurrency int `mapstructure:"thumbnail_concurrency"`
+		// ThumbnailTimeout bounds how long a list waits for thumbnails, in seconds
+		ThumbnailTimeout int `mapstructure:"thumbnail_timeout"`
+		// TitleLanguage picks the title shown for anime: romaji, english,
+		// native or preferred (the tracker's choice)
+		TitleLanguage string `mapstructure:"title_language"`
 	} `mapstructure:"ui"`
 
+	// Search settings
+	Search struct {
+		// ResultLimit is how many results a search returns at first; more
+		// can be loaded from the results menu
+		ResultLimit int `mapstructure:"result_limit"`
+	} `mapstructure:"search"`
+
 	// Anime tracking settings
 	Tracking struct {
 		Service   TrackerType `mapstructure:"service"`
 		AutoSync  bool        `mapstructure:"auto_sync"`
 		SyncDelay int         `mapstructure:"sync_delay"` // in minutes
+		// AnilistEnabled and MALEnabled allow pausing sync with a service while keeping its token
+		AnilistEnabled bool `mapstructure:"anilist_enabled"`
+		MALEnabled     bool `mapstructure:"mal_enabled"`
+		// DeleteRemoteOnRemove also deletes tracker list entries when removing anime from the library
+		DeleteRemoteOnRemove bool `mapstructure:"delete_remote_on_remove"`
+		// ReconcileProgress marks episodes watched up to each tracker's progress after syncing
+		ReconcileProgress bool `mapstructure:"reconcile_progress"`
+		// AutoComplete marks an anime completed when its last episode is watched
+		AutoComplete bool `mapstructure:"auto_complete"`
+		// ConflictRetentionDays is how long overwritten sync values are kept for
+		// review, 0 keeps them all
+		ConflictRetentionDays int `mapstructure:"conflict_retention_days"`
 	} `mapstructure:"tracking"`
 
 	// Extension settings
 	Extensions struct {
+		// AutoUpdate installs newer extension versions from their
+		// repositories in the background, once a day. On by default.
 		AutoUpdate bool     `mapstructure:"auto_update"`
 		Repos      []string `mapstructure:"repos"`
 		Directory  string   `mapstructure:"directory"`
+		// Servers maps extension packages to the URL of a running plugin
+		// server; other extensions run as CLI binaries
+		Servers map[string]string `mapstructure:"servers"`
+		// MatchThreshold is the title similarity, from 0 to 1, from which an
+		// anime is mapped to a source result without asking
+		MatchThreshold float64 `mapstructure:"match_threshold"`
 	} `mapstructure:"extensions"`
 
 	// Discord RPC settings
@@ -69,14 +129,58 @@ type Config struct {
 		DefaultLanguage string   `mapstructure:"default_language"`
 		SubtitleLangs   []string `mapstructure:"subtitle_languages"`
 		QualityPrefer   string   `mapstructure:"quality_prefer"`
+		SkipFillers     bool     `mapstructure:"skip_fillers"`
+		// AutoplayNext plays the next episode without asking
+		AutoplayNext bool `mapstructure:"autoplay_next"`
+		// ResumeOnRewatch offers to resume watched episodes while rewatching
+		ResumeOnRewatch bool `mapstructure:"resume_on_rewatch"`
+		// StreamTTL is how long, in seconds, a resolved stream URL is trusted
+		// before it is resolved again for playback
+		StreamTTL int `mapstructure:"stream_ttl"`
+		// PreferDub picks dubbed audio in video.default_language over the
+		// original audio, unless an anime is set otherwise
+		PreferDub bool `mapstructure:"prefer_dub"`
 	} `mapstructure:"video"`
 
 	// API settings
 	API struct {
 		MALClientID     string `mapstructure:"mal_client_id"`
 		AnilistClientID string `mapstructure:"anilist_client_id"`
+		// EncryptTokens stores OAuth tokens encrypted with AES-GCM
+		EncryptTokens bool `mapstructure:"encrypt_tokens"`
+		// TokenEncryptionKey is the passphrase used to derive the token key.
+		// When empty, a machine-specific secret is used.
+		TokenEncryptionKey string `mapstructure:"token_encryption_key"`
+		// RequestTimeout bounds a single tracker API request, in seconds
+		RequestTimeout int `mapstructure:"request_timeout"`
+		// SyncTimeout bounds a full sync with one tracker, in seconds
+		SyncTimeout int `mapstructure:"sync_timeout"`
 	} `mapstructure:"api"`
 
+	// Network settings
+	Network struct {
+		// Proxy routes outbound requests through an http, https or socks5 proxy
+		Proxy string `mapstructure:"proxy"`
+		// Offline skips every tracker and scraper call and serves from the local database
+		Offline bool `mapstructure:"offline"`
+		// UserAgent identifies pair to trackers and extensions, pair/<version> when empty
+		UserAgent string `mapstructure:"user_agent"`
+	} `mapstructure:"network"`
+
+	// Notification settings
+	Notifications struct {
+		// Enabled sends a desktop notification when a watched anime airs a new episode
+		Enabled bool `mapstructure:"enabled"`
+	} `mapstructure:"notifications"`
+
+	// Keybindings for the CLI menus
+	Keybindings struct {
+		Episode struct {
+			// Jump switches episode lists between jumping to a typed number and filtering titles
+			Jump string `mapstructure:"jump"`
+		} `mapstructure:"episode"`
+	} `mapstructure:"keybindings"`
+
 	// Development settings
 	Development bool `mapstructure:"development"`
 
@@ -92,13 +196,13 @@ func Initialize() error {
 	once.Do(func() {
 		viper.SetConfigName("config")
 		viper.SetConfigType("toml")
-		viper.AddConfigPath(filepath.Join(os.ExpandEnv("$HOME"), ".config", "pair"))
+		configDir := GetConfigDir()
+		viper.AddConfigPath(configDir)
 
 		// Set defaults
 		setDefaults()
 
 		// Create config directory if it doesn't exist
-		configDir := filepath.Join(os.ExpandEnv("$HOME"), ".config", "pair")
 		if err := os.MkdirAll(configDir, 0755); err != nil {
 			initErr = fmt.Errorf("failed to create config directory: %w", err)
 			return
@@ -113,24 +217,21 @@ func Initialize() error {
 			}
 		}
 
-		// Read config
-		if err := viper.ReadInConfig(); err != nil {
-			initErr = fmt.Errorf("failed to read config: %w", err)
+		// Read config, falling back to the defaults when it's malformed
+		if err := readConfig(configFile); err != nil {
+			initErr = err
 			return
 		}
 
 		// Parse config into struct
-		cfg = &Config{}
-		if err := viper.Unmarshal(cfg); err != nil {
+		parsed := &Config{}
+		if err := viper.Unmarshal(parsed); err != nil {
 			initErr = fmt.Errorf("failed to parse config: %w", err)
 			return
 		}
-
-		// Initialize database
-		dbPath := viper.GetString("database.path")
-		if dbPath == "" {
-			dbPath = filepath.Join(os.ExpandEnv("$HOME"), ".local", "share", "pair", "pair.db")
-		}
+		dbPath := resolveDBPath()
+		parsed.DatabaseConfig.Path = dbPath
+		set(parsed)
 
 		// Ensure directory exists
 		dbDir := filepath.Dir(dbPath)
@@ -147,6 +248,12 @@ func Initialize() error {
 			return
 		}
 
+		// Store defaults for settings that haven't been set yet
+		if err := db.SeedDefaultConfig(); err != nil {
+			initErr = fmt.Errorf("failed to seed default config: %w", err)
+			return
+		}
+
 		// Migrate config values to database
 		migrateConfigToDatabase()
 	})
@@ -154,8 +261,35 @@ func Initialize() error {
 	return initErr
 }
 
+// readConfig reads the config file into viper. A file that isn't valid TOML
+// is copied to config.toml.bak and the defaults are used instead, so a typo
+// doesn't keep pair from starting and the settings can still be reached.
+func readConfig(configFile string) error {
+	err := viper.ReadInConfig()
+	var parseErr viper.ConfigParseError
+	if !errors.As(err, &parseErr) {
+		if err != nil {
+			return fmt.Errorf("failed to read config: %w", err)
+		}
+		return nil
+	}
+
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		return fmt.Errorf("failed to read malformed config: %w", err)
+	}
+	backup := configFile + ".bak"
+	if err := os.WriteFile(backup, data, 0600); err != nil {
+		return fmt.Errorf("failed to back up malformed config: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Warning: %s is malformed, using the default settings (%v). A copy was saved to %s\n", configFile, parseErr, backup)
+	return nil
+}
+
 // migrateConfigToDatabase migrates configuration values from TOML to the database
 func migrateConfigToDatabase() {
+	cfg := Get()
+
 	// Check if we've already migrated
 	migrated, err := db.GetConfig("config_migrated")
 	if err == nil && migrated == "true" {
@@ -193,39 +327,93 @@ func setDefaults() {
 	viper.SetDefault("ui.mode", UIModeRofi)
 	viper.SetDefault("ui.show_image_preview", true)
 	viper.SetDefault("ui.show_episode_prompt", true)
+	viper.SetDefault("ui.thumbnail_concurrency", 6)
+	viper.SetDefault("ui.thumbnail_timeout", 5)
+	viper.SetDefault("ui.title_language", "preferred")
 
 	viper.SetDefault("tracking.service", TrackerLocal)
 	viper.SetDefault("tracking.auto_sync", true)
 	viper.SetDefault("tracking.sync_delay", 30)
+	viper.SetDefault("tracking.anilist_enabled", true)
+	viper.SetDefault("tracking.mal_enabled", true)
+	viper.SetDefault("tracking.delete_remote_on_remove", false)
+	viper.SetDefault("tracking.reconcile_progress", false)
+	viper.SetDefault("tracking.auto_complete", false)
+	viper.SetDefault("tracking.conflict_retention_days", DefaultConflictRetentionDays)
 
 	viper.SetDefault("extensions.auto_update", true)
 	viper.SetDefault("extensions.repos", []string{})
+	viper.SetDefault("extensions.servers", map[string]string{})
+	viper.SetDefault("extensions.match_threshold", 0.9)
 
 	viper.SetDefault("discord_rpc.enabled", true)
 	viper.SetDefault("discord_rpc.show_progress", true)
 	viper.SetDefault("discord_rpc.show_buttons", true)
 	viper.SetDefault("discord_rpc.show_timestamp", true)
 
+	viper.SetDefault("search.result_limit", 20)
+
 	viper.SetDefault("video.default_language", "en")
 	viper.SetDefault("video.subtitle_languages", []string{"en"})
 	viper.SetDefault("video.quality_prefer", "1080p")
+	viper.SetDefault("video.skip_fillers", false)
+	viper.SetDefault("video.autoplay_next", false)
+	viper.SetDefault("video.resume_on_rewatch", false)
+	viper.SetDefault("video.stream_ttl", 300)
+	viper.SetDefault("video.prefer_dub", false)
+
+	viper.SetDefault("extensions.directory", filepath.Join(GetDataDir(), "extensions"))
+
+	viper.SetDefault("api.encrypt_tokens", false)
+	viper.SetDefault("api.token_encryption_key", "")
+	viper.SetDefault("api.request_timeout", 15)
+	viper.SetDefault("api.sync_timeout", 600)
 
-	viper.SetDefault("extensions.directory", filepath.Join(os.ExpandEnv("$HOME"), ".local", "share", "pair", "extensions"))
+	viper.SetDefault("network.proxy", "")
+	viper.SetDefault("network.offline", false)
+	viper.SetDefault("network.user_agent", "")
+
+	viper.SetDefault("notifications.enabled", false)
+
+	viper.SetDefault("keybindings.episode.jump", "ctrl+g")
 
 	viper.SetDefault("development", false)
 
 	// Database settings
-	viper.SetDefault("database.path", filepath.Join(os.ExpandEnv("$HOME"), ".local", "share", "pair", "pair.db"))
+	viper.SetDefault("database.path", defaultDBPath())
 }
 
-// Get returns the current configuration
+// Get returns the current configuration. The returned value must be treated
+// as read-only; use Update to change it.
 func Get() *Config {
+	mu.RLock()
+	defer mu.RUnlock()
 	if cfg == nil {
 		panic("config not initialized")
 	}
 	return cfg
 }
 
+// set replaces the current configuration
+func set(c *Config) {
+	mu.Lock()
+	defer mu.Unlock()
+	cfg = c
+}
+
+// Update applies fn to a copy of the current configuration and makes the
+// copy current. The change only lives in memory; use Set to persist it.
+func Update(fn func(*Config)) {
+	mu.Lock()
+	defer mu.Unlock()
+	if cfg == nil {
+		panic("config not initialized")
+	}
+	next := *cfg
+	fn(&next)
+	cfg = &next
+}
+
 // GetDB returns the database connection
 func GetDB() *database.DB {
 	if db == nil {
@@ -234,9 +422,96 @@ func GetDB() *database.DB {
 	return db
 }
 
-// GetConfigDir returns the configuration directory
+// GetConfigDir returns the configuration directory. It can be relocated
+// with SetConfigDir or the PAIR_CONFIG_DIR environment variable, and is
+// nested under profiles/<name> when a profile is selected.
 func GetConfigDir() string {
-	return filepath.Join(os.ExpandEnv("$HOME"), ".config", "pair")
+	if dir := relocatedDir(); dir != "" {
+		return dir
+	}
+	dir := filepath.Join(os.ExpandEnv("$HOME"), ".config", "pair")
+	if profile != "" {
+		dir = filepath.Join(dir, "profiles", profile)
+	}
+	return dir
+}
+
+// SetProfile selects a named profile with its own config, library and
+// tokens. The empty name is the default profile. It must be called before
+// Initialize.
+func SetProfile(name string) error {
+	if name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("invalid profile name %q", name)
+	}
+	profile = name
+	return nil
+}
+
+// SetConfigDir relocates the config and data files to dir.
+// It must be called before Initialize.
+func SetConfigDir(dir string) {
+	configDir = dir
+}
+
+// SetDBPath makes path take precedence over PAIR_DB_PATH and the config
+// file, like a command-line flag. It is never saved to the config file.
+// It must be called before Initialize.
+func SetDBPath(path string) {
+	dbPathOverride = path
+}
+
+// resolveDBPath returns the database file: the one set by SetDBPath, else
+// PAIR_DB_PATH, else the config file's, else the default
+func resolveDBPath() string {
+	if dbPathOverride != "" {
+		return dbPathOverride
+	}
+	if path := os.Getenv(envDBPath); path != "" {
+		return path
+	}
+	if path := viper.GetString("database.path"); path != "" {
+		return path
+	}
+	return defaultDBPath()
+}
+
+// relocatedDir returns the directory set by SetConfigDir or PAIR_CONFIG_DIR
+func relocatedDir() string {
+	if configDir != "" {
+		return configDir
+	}
+	return os.Getenv(envConfigDir)
+}
+
+// SetDataDir relocates the data files, the database, extensions and tracker
+// logins, to dir. It must be called before Initialize.
+func SetDataDir(dir string) {
+	dataDirOverride = dir
+}
+
+// GetDataDir returns where the database, extensions and tracker logins live:
+// the directory set by SetDataDir or PAIR_DATA_DIR, else a relocated config
+// directory so it stays self-contained, else ~/.local/share/pair
+func GetDataDir() string {
+	if dataDirOverride != "" {
+		return dataDirOverride
+	}
+	if dir := os.Getenv(envDataDir); dir != "" {
+		return dir
+	}
+	if dir := relocatedDir(); dir != "" {
+		return dir
+	}
+	dir := filepath.Join(os.ExpandEnv("$HOME"), ".local", "share", "pair")
+	if profile != "" {
+		dir = filepath.Join(dir, "profiles", profile)
+	}
+	return dir
+}
+
+// defaultDBPath returns the database file used when none is configured
+func defaultDBPath() string {
+	return filepath.Join(GetDataDir(), "pair.db")
 }
 
 // Save writes the current configuration to disk
@@ -245,4 +520,10 @@ func Save() error {
 		viper.Set(k, v)
 	}
 	return viper.WriteConfig()
-}
\ No newline at end of file
+}
+
+// Set updates a single setting and writes the configuration to disk
+func Set(key string, value interface{}) error {
+	viper.Set(key, value)
+	return viper.WriteConfig()
+}
@@ -0,0 +1,184 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestDBPathFromEnv(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(envConfigDir, filepath.Join(home, "profile"))
+	dbPath := filepath.Join(home, "elsewhere", "custom.db")
+	t.Setenv(envDBPath, dbPath)
+
+	if err := Initialize(); err != nil {
+		t.Fatalf("Failed to initialize config: %v", err)
+	}
+	defer GetDB().Close()
+
+	// Test that the database is opened at the path from the environment
+	if _, err := os.Stat(dbPath); err != nil {
+		t.Errorf("Expected database at %s: %v", dbPath, err)
+	}
+	if Get().DatabaseConfig.Path != dbPath {
+		t.Errorf("Expected database path '%s', got '%s'", dbPath, Get().DatabaseConfig.Path)
+	}
+
+	// Test that the config file lives in the relocated directory
+	if _, err := os.Stat(filepath.Join(home, "profile", "config.toml")); err != nil {
+		t.Errorf("Expected config file in relocated directory: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(home, ".config", "pair")); !os.IsNotExist(err) {
+		t.Errorf("Expected the default config directory to be left alone, got %v", err)
+	}
+}
+
+func TestProfilePaths(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(envConfigDir, "")
+	defer SetProfile("")
+
+	paths := func(name string) (string, string) {
+		if err := SetProfile(name); err != nil {
+			t.Fatalf("Failed to set profile %q: %v", name, err)
+		}
+		return GetConfigDir(), defaultDBPath()
+	}
+
+	defaultConfig, defaultDB := paths("")
+	aliceConfig, aliceDB := paths("alice")
+	bobConfig, bobDB := paths("bob")
+
+	// Test that the default profile keeps today's locations
+	if defaultConfig != filepath.Join(home, ".config", "pair") {
+		t.Errorf("Unexpected default config dir '%s'", defaultConfig)
+	}
+	if defaultDB != filepath.Join(home, ".local", "share", "pair", "pair.db") {
+		t.Errorf("Unexpected default database '%s'", defaultDB)
+	}
+
+	// Test that each profile gets its own database and token directory
+	if aliceConfig != filepath.Join(home, ".config", "pair", "profiles", "alice") {
+		t.Errorf("Unexpected profile config dir '%s'", aliceConfig)
+	}
+	if aliceDB != filepath.Join(home, ".local", "share", "pair", "profiles", "alice", "pair.db") {
+		t.Errorf("Unexpected profile database '%s'", aliceDB)
+	}
+	if aliceConfig == bobConfig || aliceDB == bobDB || aliceDB == defaultDB {
+		t.Errorf("Expected independent profiles, got %s/%s and %s/%s", aliceConfig, aliceDB, bobConfig, bobDB)
+	}
+
+	// Test that names escaping the profiles directory are rejected
+	for _, name := range []string{"..", "a/b"} {
+		if err := SetProfile(name); err == nil {
+			t.Errorf("Expected profile name %q to be rejected", name)
+		}
+	}
+}
+
+func TestConcurrentConfigAccess(t *testing.T) {
+	previous := cfg
+	set(&Config{})
+	defer set(previous)
+
+	// Test that readers and writers can share the config; run with -race
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				_ = Get().Network.Offline
+			}
+		}()
+	}
+	for i := 0; i < 1000; i++ {
+		if i%2 == 0 {
+			set(&Config{})
+		} else {
+			Update(func(c *Config) {
+				c.Network.Offline = true
+			})
+		}
+	}
+	wg.Wait()
+
+	Update(func(c *Config) {
+		c.Network.Offline = true
+	})
+	if !Get().Network.Offline {
+		t.Error("Expected Update to change the current config")
+	}
+}
+
+func TestMalformedConfigUsesDefaults(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config.toml")
+	malformed := "[video\nquality_prefer = 720p\n"
+	if err := os.WriteFile(configFile, []byte(malformed), 0600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	viper.Reset()
+	defer viper.Reset()
+	viper.SetConfigFile(configFile)
+	setDefaults()
+
+	// Test that the broken file doesn't stop the config from loading
+	if err := readConfig(configFile); err != nil {
+		t.Fatalf("Failed to read malformed config: %v", err)
+	}
+	parsed := &Config{}
+	if err := viper.Unmarshal(parsed); err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+	if parsed.Video.QualityPrefer != "1080p" || parsed.UI.Mode != UIModeRofi {
+		t.Errorf("Expected the default settings, got quality %q and mode %q", parsed.Video.QualityPrefer, parsed.UI.Mode)
+	}
+
+	// Test that the broken file is backed up as it was
+	backup, err := os.ReadFile(configFile + ".bak")
+	if err != nil {
+		t.Fatalf("Failed to read backup: %v", err)
+	}
+	if string(backup) != malformed {
+		t.Errorf("Expected the backup to hold the malformed config, got %q", backup)
+	}
+}
+
+func TestDBPathOverrideNotSaved(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.toml")
+	override := filepath.Join(t.TempDir(), "override.db")
+	t.Setenv(envDBPath, "")
+
+	viper.Reset()
+	defer viper.Reset()
+	viper.SetConfigFile(configFile)
+	setDefaults()
+	SetDBPath(override)
+	defer SetDBPath("")
+
+	// Test that the override wins over the configured path
+	if got := resolveDBPath(); got != override {
+		t.Errorf("Expected database path '%s', got '%s'", override, got)
+	}
+
+	// Test that saving the config leaves the override out of the file
+	if err := Save(); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+	content, err := os.ReadFile(configFile)
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	if strings.Contains(string(content), override) {
+		t.Errorf("Expected the override not to be saved, got:\n%s", content)
+	}
+}
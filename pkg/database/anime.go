@@ -3,16 +3,36 @@ package database
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 )
 
 // Errors
 var (
-	ErrAnimeNotFound    = fmt.Errorf("anime not found")
-	ErrTrackingNotFound = fmt.Errorf("tracking not found")
+	ErrAnimeNotFound     = fmt.Errorf("anime not found")
+	ErrTrackingNotFound  = fmt.Errorf("tracking not found")
+	ErrAmbiguousTracking = fmt.Errorf("tracker ID maps to multiple anime")
+	ErrTrackingConflict  = fmt.Errorf("tracker ID is already linked to another anime")
 )
 
+// TrackingConflictError is returned when a tracker ID is already linked to another anime.
+// It wraps ErrTrackingConflict.
+type TrackingConflictError struct {
+	AnimeID   int64 // The anime the tracker ID is linked to
+	Tracker   string
+	TrackerID string
+}
+
+func (e *TrackingConflictError) Error() string {
+	return fmt.Sprintf("%s ID %s is already linked to anime %d", e.Tracker, e.TrackerID, e.AnimeID)
+}
+
+func (e *TrackingConflictError) Unwrap() error {
+	return ErrTrackingConflict
+}
+
 // Anime represents an anime in the database
 type Anime struct {
 	ID                int64
@@ -27,6 +47,8 @@ type Anime struct {
 	Status            string
 	Genres            []string
 	ThumbnailURL      string
+	Notes             string
+	MalID             string // MyAnimeList ID, empty until a sync learns it
 	CreatedAt         time.Time
 	UpdatedAt         time.Time
 }
@@ -42,6 +64,19 @@ type AnimeTracking struct {
 	CurrentEpisode float64
 	TotalEpisodes  int
 	LastUpdated    time.Time
+	Notes          string
+	// Priority and Tags mirror the list entry on trackers that support them
+	Priority int
+	Tags     []string
+	// IsRewatching is set while the anime is being watched again after completing it
+	IsRewatching bool
+}
+
+// AnimeWithTracking is an anime together with its tracking entry on one
+// tracker. Tracking is nil when the anime isn't tracked there.
+type AnimeWithTracking struct {
+	Anime    *Anime
+	Tracking *AnimeTracking
 }
 
 // EpisodeProgress represents a user's episode viewing progress
@@ -57,6 +92,18 @@ type EpisodeProgress struct {
 	LastWatched   time.Time
 }
 
+// Percent returns how far into the episode playback got, from 0 to 100.
+// It is 0 when the duration is unknown.
+func (p *EpisodeProgress) Percent() float64 {
+	if p.Duration <= 0 || p.Position <= 0 {
+		return 0
+	}
+	if p.Position >= p.Duration {
+		return 100
+	}
+	return float64(p.Position) / float64(p.Duration) * 100
+}
+
 // Episode represents an episode in the database
 type Episode struct {
 	ID           int64
@@ -111,13 +158,13 @@ func (db *DB) GetAnimeByID(id int64) (*Anime, error) {
 	err := db.conn.QueryRow(
 		`SELECT 
 			id, title, original_title, alternative_titles, description, 
-			total_episodes, type, year, season, status, genres, thumbnail_url,
+			total_episodes, type, year, season, status, genres, thumbnail_url, notes, mal_id,
 			created_at, updated_at
 		FROM anime WHERE id = ?`, id,
 	).Scan(
 		&anime.ID, &anime.Title, &anime.OriginalTitle, &alternativeTitlesJSON,
 		&anime.Description, &anime.TotalEpisodes, &anime.Type, &anime.Year,
-		&anime.Season, &anime.Status, &genresJSON, &anime.ThumbnailURL,
+		&anime.Season, &anime.Status, &genresJSON, &anime.ThumbnailURL, &anime.Notes, &anime.MalID,
 		&anime.CreatedAt, &anime.UpdatedAt,
 	)
 	if err != nil {
@@ -144,13 +191,13 @@ func (db *DB) GetAnimeByTitle(title string) (*Anime, error) {
 	err := db.conn.QueryRow(
 		`SELECT 
 			id, title, original_title, alternative_titles, description, 
-			total_episodes, type, year, season, status, genres, thumbnail_url,
+			total_episodes, type, year, season, status, genres, thumbnail_url, notes, mal_id,
 			created_at, updated_at
 		FROM anime WHERE title = ?`, title,
 	).Scan(
 		&anime.ID, &anime.Title, &anime.OriginalTitle, &alternativeTitlesJSON,
 		&anime.Description, &anime.TotalEpisodes, &anime.Type, &anime.Year,
-		&anime.Season, &anime.Status, &genresJSON, &anime.ThumbnailURL,
+		&anime.Season, &anime.Status, &genresJSON, &anime.ThumbnailURL, &anime.Notes, &anime.MalID,
 		&anime.CreatedAt, &anime.UpdatedAt,
 	)
 	if err != nil {
@@ -174,7 +221,7 @@ func (db *DB) SearchAnime(query string) ([]*Anime, error) {
 	rows, err := db.conn.Query(
 		`SELECT 
 			id, title, original_title, alternative_titles, description, 
-			total_episodes, type, year, season, status, genres, thumbnail_url,
+			total_episodes, type, year, season, status, genres, thumbnail_url, notes, mal_id,
 			created_at, updated_at
 		FROM anime 
 		WHERE title LIKE ? OR original_title LIKE ?
@@ -194,7 +241,7 @@ func (db *DB) SearchAnime(query string) ([]*Anime, error) {
 		err := rows.Scan(
 			&anime.ID, &anime.Title, &anime.OriginalTitle, &alternativeTitlesJSON,
 			&anime.Description, &anime.TotalEpisodes, &anime.Type, &anime.Year,
-			&anime.Season, &anime.Status, &genresJSON, &anime.ThumbnailURL,
+			&anime.Season, &anime.Status, &genresJSON, &anime.ThumbnailURL, &anime.Notes, &anime.MalID,
 			&anime.CreatedAt, &anime.UpdatedAt,
 		)
 		if err != nil {
@@ -243,26 +290,80 @@ func (db *DB) UpdateAnime(anime *Anime) error {
 	return err
 }
 
-// DeleteAnime deletes an anime by ID
+// UpdateAnimeNotes sets the personal note of an anime
+func (db *DB) UpdateAnimeNotes(id int64, notes string) error {
+	result, err := db.conn.Exec(
+		"UPDATE anime SET notes = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?",
+		notes, id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update anime notes: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrAnimeNotFound
+	}
+	return nil
+}
+
+// animeChildTables hold rows belonging to an anime
+var animeChildTables = []string{"anime_source", "episode_progress", "episode", "anime_tracking", "sync_conflict_log"}
+
+// DeleteAnime deletes an anime by ID along with its trackings, episodes,
+// progress, source mappings and per-anime settings ("anime.<id>.*" config
+// keys). Foreign keys aren't enforced, so the children are deleted explicitly.
 func (db *DB) DeleteAnime(id int64) error {
-	_, err := db.conn.Exec("DELETE FROM anime WHERE id = ?", id)
-	return err
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, table := range animeChildTables {
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE anime_id = ?", id); err != nil {
+			return fmt.Errorf("failed to delete from %s: %w", table, err)
+		}
+	}
+	if _, err := tx.Exec("DELETE FROM config WHERE key LIKE 'anime.' || ? || '.%'", id); err != nil {
+		return fmt.Errorf("failed to delete anime settings: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM anime WHERE id = ?", id); err != nil {
+		return fmt.Errorf("failed to delete anime: %w", err)
+	}
+
+	return tx.Commit()
 }
 
-// AddAnimeTracking adds or updates tracking information for an anime
+// AddAnimeTracking adds or updates tracking information for an anime.
+// Existing notes are kept when the new entry has none, and the existing
+// priority and tags are kept when the new entry's Tags is nil.
 func (db *DB) AddAnimeTracking(tracking *AnimeTracking) error {
+	// A NULL tags value leaves the stored priority and tags untouched
+	var tags interface{}
+	if tracking.Tags != nil {
+		tagsJSON, err := json.Marshal(tracking.Tags)
+		if err != nil {
+			return err
+		}
+		tags = string(tagsJSON)
+	}
+
 	result, err := db.conn.Exec(
 		`INSERT INTO anime_tracking (
 			anime_id, tracker, tracker_id, status, score, 
-			current_episode, total_episodes, last_updated
-		) VALUES (?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+			current_episode, total_episodes, notes, priority, tags, is_rewatching, last_updated
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, COALESCE(?, '[]'), ?, CURRENT_TIMESTAMP)
 		ON CONFLICT(anime_id, tracker) DO UPDATE SET
 			tracker_id = ?, status = ?, score = ?, 
-			current_episode = ?, total_episodes = ?, last_updated = CURRENT_TIMESTAMP`,
+			current_episode = ?, total_episodes = ?,
+			notes = COALESCE(NULLIF(?, ''), notes),
+			priority = CASE WHEN ? IS NULL THEN priority ELSE ? END,
+			tags = COALESCE(?, tags), is_rewatching = ?, last_updated = CURRENT_TIMESTAMP`,
 		tracking.AnimeID, tracking.Tracker, tracking.TrackerID, tracking.Status,
-		tracking.Score, tracking.CurrentEpisode, tracking.TotalEpisodes,
+		tracking.Score, tracking.CurrentEpisode, tracking.TotalEpisodes, tracking.Notes,
+		tracking.Priority, tags, tracking.IsRewatching,
 		tracking.TrackerID, tracking.Status, tracking.Score,
-		tracking.CurrentEpisode, tracking.TotalEpisodes,
+		tracking.CurrentEpisode, tracking.TotalEpisodes, tracking.Notes,
+		tags, tracking.Priority, tags, tracking.IsRewatching,
 	)
 	if err != nil {
 		return err
@@ -283,22 +384,27 @@ func (db *DB) AddAnimeTracking(tracking *AnimeTracking) error {
 // GetAnimeTracking retrieves tracking information for an anime
 func (db *DB) GetAnimeTracking(animeID int64, tracker string) (*AnimeTracking, error) {
 	var tracking AnimeTracking
+	var tagsJSON []byte
 
 	err := db.conn.QueryRow(
 		`SELECT 
 			id, anime_id, tracker, tracker_id, status, score, 
-			current_episode, total_episodes, last_updated
+			current_episode, total_episodes, last_updated, notes, priority, tags, is_rewatching
 		FROM anime_tracking 
 		WHERE anime_id = ? AND tracker = ?`,
 		animeID, tracker,
 	).Scan(
 		&tracking.ID, &tracking.AnimeID, &tracking.Tracker, &tracking.TrackerID,
 		&tracking.Status, &tracking.Score, &tracking.CurrentEpisode,
-		&tracking.TotalEpisodes, &tracking.LastUpdated,
+		&tracking.TotalEpisodes, &tracking.LastUpdated, &tracking.Notes,
+		&tracking.Priority, &tagsJSON, &tracking.IsRewatching,
 	)
 	if err != nil {
 		return nil, err
 	}
+	if err := json.Unmarshal(tagsJSON, &tracking.Tags); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal tags: %w", err)
+	}
 
 	return &tracking, nil
 }
@@ -308,7 +414,7 @@ func (db *DB) GetAllAnimeTracking(animeID int64) ([]*AnimeTracking, error) {
 	rows, err := db.conn.Query(
 		`SELECT 
 			id, anime_id, tracker, tracker_id, status, score, 
-			current_episode, total_episodes, last_updated
+			current_episode, total_episodes, last_updated, notes, priority, tags, is_rewatching
 		FROM anime_tracking 
 		WHERE anime_id = ?`,
 		animeID,
@@ -321,20 +427,59 @@ func (db *DB) GetAllAnimeTracking(animeID int64) ([]*AnimeTracking, error) {
 	var trackings []*AnimeTracking
 	for rows.Next() {
 		var tracking AnimeTracking
+		var tagsJSON []byte
 		err := rows.Scan(
 			&tracking.ID, &tracking.AnimeID, &tracking.Tracker, &tracking.TrackerID,
 			&tracking.Status, &tracking.Score, &tracking.CurrentEpisode,
-			&tracking.TotalEpisodes, &tracking.LastUpdated,
+			&tracking.TotalEpisodes, &tracking.LastUpdated, &tracking.Notes,
+			&tracking.Priority, &tagsJSON, &tracking.IsRewatching,
 		)
 		if err != nil {
 			return nil, err
 		}
+		if err := json.Unmarshal(tagsJSON, &tracking.Tags); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal tags: %w", err)
+		}
 		trackings = append(trackings, &tracking)
 	}
 
 	return trackings, rows.Err()
 }
 
+// GetAllListTags returns the list tags of every anime that has some, keyed
+// by anime ID. An anime tracked on several trackers gets the tags of the
+// first one that keeps them.
+func (db *DB) GetAllListTags() (map[int64][]string, error) {
+	rows, err := db.conn.Query(
+		"SELECT anime_id, tags FROM anime_tracking WHERE tags != '[]' ORDER BY anime_id, id",
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := make(map[int64][]string)
+	for rows.Next() {
+		var animeID int64
+		var tagsJSON []byte
+		if err := rows.Scan(&animeID, &tagsJSON); err != nil {
+			return nil, err
+		}
+		if _, ok := tags[animeID]; ok {
+			continue
+		}
+		var list []string
+		if err := json.Unmarshal(tagsJSON, &list); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal tags: %w", err)
+		}
+		if len(list) > 0 {
+			tags[animeID] = list
+		}
+	}
+
+	return tags, rows.Err()
+}
+
 // DeleteAnimeTracking deletes tracking information for an anime
 func (db *DB) DeleteAnimeTracking(animeID int64, tracker string) error {
 	_, err := db.conn.Exec(
@@ -344,6 +489,50 @@ func (db *DB) DeleteAnimeTracking(animeID int64, tracker string) error {
 	return err
 }
 
+// RelinkTracking points the tracking entry of an anime on a tracker at a different
+// remote entry. It returns ErrTrackingNotFound if the anime isn't tracked there and
+// a *TrackingConflictError if another anime is already linked to newTrackerID.
+func (db *DB) RelinkTracking(animeID int64, tracker, newTrackerID string) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var otherID int64
+	err = tx.QueryRow(
+		`SELECT anime_id FROM anime_tracking
+		WHERE tracker = ? AND tracker_id = ? AND anime_id != ?
+		LIMIT 1`,
+		tracker, newTrackerID, animeID,
+	).Scan(&otherID)
+	if err == nil {
+		return &TrackingConflictError{AnimeID: otherID, Tracker: tracker, TrackerID: newTrackerID}
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("failed to check existing links: %w", err)
+	}
+
+	result, err := tx.Exec(
+		`UPDATE anime_tracking
+		SET tracker_id = ?, last_updated = CURRENT_TIMESTAMP
+		WHERE anime_id = ? AND tracker = ?`,
+		newTrackerID, animeID, tracker,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to relink tracking: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to relink tracking: %w", err)
+	}
+	if affected == 0 {
+		return ErrTrackingNotFound
+	}
+
+	return tx.Commit()
+}
+
 // GetEpisodeProgress retrieves progress information for an episode
 func (db *DB) GetEpisodeProgress(animeID int64, episodeNumber float64) (*EpisodeProgress, error) {
 	var progress EpisodeProgress
@@ -403,6 +592,40 @@ func (db *DB) GetAllEpisodeProgress(animeID int64) ([]*EpisodeProgress, error) {
 	return progressList, rows.Err()
 }
 
+// GetInProgressEpisodes retrieves progress for the episodes of an anime that
+// were started but neither finished nor marked as watched
+func (db *DB) GetInProgressEpisodes(animeID int64) ([]*EpisodeProgress, error) {
+	rows, err := db.conn.Query(
+		`SELECT 
+			id, anime_id, episode_number, position, duration, 
+			playback_speed, watched, source_id, last_watched
+		FROM episode_progress 
+		WHERE anime_id = ? AND position > 0 AND position < duration AND NOT watched
+		ORDER BY episode_number`,
+		animeID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var progressList []*EpisodeProgress
+	for rows.Next() {
+		var progress EpisodeProgress
+		err := rows.Scan(
+			&progress.ID, &progress.AnimeID, &progress.EpisodeNumber, &progress.Position,
+			&progress.Duration, &progress.PlaybackSpeed, &progress.Watched,
+			&progress.SourceID, &progress.LastWatched,
+		)
+		if err != nil {
+			return nil, err
+		}
+		progressList = append(progressList, &progress)
+	}
+
+	return progressList, rows.Err()
+}
+
 // DeleteEpisodeProgress deletes progress information for an episode
 func (db *DB) DeleteEpisodeProgress(animeID int64, episodeNumber float64) error {
 	_, err := db.conn.Exec(
@@ -431,7 +654,10 @@ func (db *DB) GetLastWatchedAnime() (*Anime, error) {
 // GetWatchingAnime retrieves all anime that the user is currently watching
 func (db *DB) GetWatchingAnime() ([]*Anime, error) {
 	rows, err := db.conn.Query(`
-		SELECT DISTINCT a.* FROM anime a
+		SELECT DISTINCT a.id, a.title, a.original_title, a.alternative_titles, a.description,
+		       a.total_episodes, a.type, a.year, a.season, a.status, a.genres,
+		       a.thumbnail_url, a.notes, a.mal_id, a.created_at, a.updated_at
+		FROM anime a
 		JOIN anime_tracking t ON a.id = t.anime_id
 		WHERE t.status = 'watching'
 		ORDER BY t.last_updated DESC
@@ -449,7 +675,7 @@ func (db *DB) GetWatchingAnime() ([]*Anime, error) {
 		err := rows.Scan(
 			&anime.ID, &anime.Title, &anime.OriginalTitle, &alternativeTitlesJSON,
 			&anime.Description, &anime.TotalEpisodes, &anime.Type, &anime.Year,
-			&anime.Season, &anime.Status, &genresJSON, &anime.ThumbnailURL,
+			&anime.Season, &anime.Status, &genresJSON, &anime.ThumbnailURL, &anime.Notes, &anime.MalID,
 			&anime.CreatedAt, &anime.UpdatedAt,
 		)
 		if err != nil {
@@ -477,8 +703,9 @@ func (db *DB) GetEpisode(animeID int64, number float64) (*Episode, error) {
 
 	err := db.conn.QueryRow(
 		`SELECT 
-			id, anime_id, number, title, description, duration, 
-			thumbnail_url, air_date, is_filler, created_at
+			id, anime_id, number, COALESCE(title, ''), COALESCE(description, ''),
+			COALESCE(duration, 0), COALESCE(thumbnail_url, ''), COALESCE(air_date, ''),
+			is_filler, created_at
 		FROM episode 
 		WHERE anime_id = ? AND number = ?`,
 		animeID, number,
@@ -498,8 +725,9 @@ func (db *DB) GetEpisode(animeID int64, number float64) (*Episode, error) {
 func (db *DB) GetAllEpisodes(animeID int64) ([]*Episode, error) {
 	rows, err := db.conn.Query(
 		`SELECT 
-			id, anime_id, number, title, description, duration, 
-			thumbnail_url, air_date, is_filler, created_at
+			id, anime_id, number, COALESCE(title, ''), COALESCE(description, ''),
+			COALESCE(duration, 0), COALESCE(thumbnail_url, ''), COALESCE(air_date, ''),
+			is_filler, created_at
 		FROM episode 
 		WHERE anime_id = ?
 		ORDER BY number`,
@@ -542,25 +770,87 @@ func (db *DB) GetAnimeByExternalID(externalID string, tracker string) (*Anime, e
 	query := `
 		SELECT anime_id FROM anime_tracking
 		WHERE tracker = ? AND tracker_id = ?
+		ORDER BY anime_id
 	`
 
-	var animeID int64
-	err := db.conn.QueryRow(query, tracker, externalID).Scan(&animeID)
+	rows, err := db.conn.Query(query, tracker, externalID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, ErrAnimeNotFound
+		return nil, fmt.Errorf("failed to query anime tracking: %w", err)
+	}
+	defer rows.Close()
+
+	var animeIDs []int64
+	for rows.Next() {
+		var animeID int64
+		if err := rows.Scan(&animeID); err != nil {
+			return nil, fmt.Errorf("failed to scan anime tracking: %w", err)
 		}
+		animeIDs = append(animeIDs, animeID)
+	}
+	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("failed to query anime tracking: %w", err)
 	}
 
-	// Now get the anime by ID
+	switch len(animeIDs) {
+	case 0:
+		return nil, ErrAnimeNotFound
+	case 1:
+		// Now get the anime by ID
+		return db.GetAnime(animeIDs[0])
+	default:
+		return nil, fmt.Errorf("%w: %s ID %s is linked to anime %v", ErrAmbiguousTracking, tracker, externalID, animeIDs)
+	}
+}
+
+// SetAnimeMalID records the MyAnimeList ID of an anime
+func (db *DB) SetAnimeMalID(animeID int64, malID string) error {
+	result, err := db.conn.Exec("UPDATE anime SET mal_id = ? WHERE id = ?", malID, animeID)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrAnimeNotFound
+	}
+	return nil
+}
+
+// GetAnimeMalID returns the recorded MyAnimeList ID of an anime, or an empty
+// string if none is known
+func (db *DB) GetAnimeMalID(animeID int64) (string, error) {
+	var malID string
+	err := db.conn.QueryRow("SELECT mal_id FROM anime WHERE id = ?", animeID).Scan(&malID)
+	if err == sql.ErrNoRows {
+		return "", ErrAnimeNotFound
+	}
+	return malID, err
+}
+
+// GetAnimeByMalID gets an anime by its MyAnimeList ID, whether it is tracked
+// on MyAnimeList or its ID was learnt from Anilist
+func (db *DB) GetAnimeByMalID(malID string) (*Anime, error) {
+	if malID == "" {
+		return nil, ErrAnimeNotFound
+	}
+	anime, err := db.GetAnimeByExternalID(malID, "mal")
+	if !errors.Is(err, ErrAnimeNotFound) {
+		return anime, err
+	}
+
+	var animeID int64
+	err = db.conn.QueryRow("SELECT id FROM anime WHERE mal_id = ? ORDER BY id LIMIT 1", malID).Scan(&animeID)
+	if err == sql.ErrNoRows {
+		return nil, ErrAnimeNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query anime: %w", err)
+	}
 	return db.GetAnime(animeID)
 }
 
 // GetAllAnimeTrackingByTracker gets all anime tracking entries for a specific tracker
 func (db *DB) GetAllAnimeTrackingByTracker(tracker string) ([]*AnimeTracking, error) {
 	query := `
-		SELECT id, anime_id, tracker, tracker_id, status, score, current_episode, total_episodes, last_updated
+		SELECT id, anime_id, tracker, tracker_id, status, score, current_episode, total_episodes, last_updated, notes, priority, tags, is_rewatching
 		FROM anime_tracking
 		WHERE tracker = ?
 	`
@@ -574,6 +864,7 @@ func (db *DB) GetAllAnimeTrackingByTracker(tracker string) ([]*AnimeTracking, er
 	var trackings []*AnimeTracking
 	for rows.Next() {
 		tracking := &AnimeTracking{}
+		var tagsJSON []byte
 		err := rows.Scan(
 			&tracking.ID,
 			&tracking.AnimeID,
@@ -584,10 +875,17 @@ func (db *DB) GetAllAnimeTrackingByTracker(tracker string) ([]*AnimeTracking, er
 			&tracking.CurrentEpisode,
 			&tracking.TotalEpisodes,
 			&tracking.LastUpdated,
+			&tracking.Notes,
+			&tracking.Priority,
+			&tagsJSON,
+			&tracking.IsRewatching,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan anime tracking: %w", err)
 		}
+		if err := json.Unmarshal(tagsJSON, &tracking.Tags); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal tags: %w", err)
+		}
 		trackings = append(trackings, tracking)
 	}
 
@@ -601,7 +899,7 @@ func (db *DB) GetAllAnimeTrackingByTracker(tracker string) ([]*AnimeTracking, er
 // GetAllAnimeTrackingByAnimeID gets all anime tracking entries for a specific anime
 func (db *DB) GetAllAnimeTrackingByAnimeID(animeID int64) ([]*AnimeTracking, error) {
 	query := `
-		SELECT id, anime_id, tracker, tracker_id, status, score, current_episode, total_episodes, last_updated
+		SELECT id, anime_id, tracker, tracker_id, status, score, current_episode, total_episodes, last_updated, notes, priority, tags, is_rewatching
 		FROM anime_tracking
 		WHERE anime_id = ?
 	`
@@ -615,6 +913,7 @@ func (db *DB) GetAllAnimeTrackingByAnimeID(animeID int64) ([]*AnimeTracking, err
 	var trackings []*AnimeTracking
 	for rows.Next() {
 		tracking := &AnimeTracking{}
+		var tagsJSON []byte
 		err := rows.Scan(
 			&tracking.ID,
 			&tracking.AnimeID,
@@ -625,10 +924,17 @@ func (db *DB) GetAllAnimeTrackingByAnimeID(animeID int64) ([]*AnimeTracking, err
 			&tracking.CurrentEpisode,
 			&tracking.TotalEpisodes,
 			&tracking.LastUpdated,
+			&tracking.Notes,
+			&tracking.Priority,
+			&tagsJSON,
+			&tracking.IsRewatching,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan anime tracking: %w", err)
 		}
+		if err := json.Unmarshal(tagsJSON, &tracking.Tags); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal tags: %w", err)
+		}
 		trackings = append(trackings, tracking)
 	}
 
@@ -643,7 +949,7 @@ func (db *DB) GetAllAnimeTrackingByAnimeID(animeID int64) ([]*AnimeTracking, err
 func (db *DB) GetAnime(id int64) (*Anime, error) {
 	query := `
 		SELECT id, title, original_title, alternative_titles, description, total_episodes,
-		       type, year, season, status, genres, thumbnail_url, created_at, updated_at
+		       type, year, season, status, genres, thumbnail_url, notes, mal_id, created_at, updated_at
 		FROM anime
 		WHERE id = ?
 	`
@@ -667,6 +973,7 @@ func (db *DB) GetAnime(id int64) (*Anime, error) {
 		&anime.Status,
 		&genresJSON,
 		&anime.ThumbnailURL,
+		&anime.Notes, &anime.MalID,
 		&anime.CreatedAt,
 		&anime.UpdatedAt,
 	)
@@ -698,7 +1005,7 @@ func (db *DB) GetAnime(id int64) (*Anime, error) {
 func (db *DB) GetAllAnime() ([]*Anime, error) {
 	rows, err := db.conn.Query(`
 		SELECT id, title, original_title, alternative_titles, description, 
-		       total_episodes, type, year, season, status, genres, thumbnail_url,
+		       total_episodes, type, year, season, status, genres, thumbnail_url, notes, mal_id,
 		       created_at, updated_at
 		FROM anime 
 		ORDER BY title
@@ -716,7 +1023,141 @@ func (db *DB) GetAllAnime() ([]*Anime, error) {
 		err := rows.Scan(
 			&anime.ID, &anime.Title, &anime.OriginalTitle, &alternativeTitlesJSON,
 			&anime.Description, &anime.TotalEpisodes, &anime.Type, &anime.Year,
-			&anime.Season, &anime.Status, &genresJSON, &anime.ThumbnailURL,
+			&anime.Season, &anime.Status, &genresJSON, &anime.ThumbnailURL, &anime.Notes, &anime.MalID,
+			&anime.CreatedAt, &anime.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		// Parse JSON fields
+		if err := json.Unmarshal(alternativeTitlesJSON, &anime.AlternativeTitles); err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(genresJSON, &anime.Genres); err != nil {
+			return nil, err
+		}
+
+		animes = append(animes, &anime)
+	}
+
+	return animes, rows.Err()
+}
+
+// GetAllAnimeWithTracking gets every anime with its tracking entry on a
+// tracker in one query, ordered by title
+func (db *DB) GetAllAnimeWithTracking(tracker string) ([]AnimeWithTracking, error) {
+	rows, err := db.conn.Query(`
+		SELECT a.id, a.title, a.original_title, a.alternative_titles, a.description,
+		       a.total_episodes, a.type, a.year, a.season, a.status, a.genres, a.thumbnail_url, a.notes, a.mal_id,
+		       a.created_at, a.updated_at,
+		       t.id, t.tracker_id, t.status, t.score, t.current_episode, t.total_episodes,
+		       t.last_updated, t.notes, t.priority, t.tags, t.is_rewatching
+		FROM anime a
+		LEFT JOIN anime_tracking t ON t.anime_id = a.id AND t.tracker = ?
+		ORDER BY a.title
+	`, tracker)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []AnimeWithTracking
+	for rows.Next() {
+		var anime Anime
+		var alternativeTitlesJSON, genresJSON, tagsJSON []byte
+		var (
+			trackingID               sql.NullInt64
+			trackerID, status, notes sql.NullString
+			score, currentEpisode    sql.NullFloat64
+			totalEpisodes, priority  sql.NullInt64
+			lastUpdated              sql.NullTime
+			isRewatching             sql.NullBool
+		)
+
+		err := rows.Scan(
+			&anime.ID, &anime.Title, &anime.OriginalTitle, &alternativeTitlesJSON,
+			&anime.Description, &anime.TotalEpisodes, &anime.Type, &anime.Year,
+			&anime.Season, &anime.Status, &genresJSON, &anime.ThumbnailURL, &anime.Notes, &anime.MalID,
+			&anime.CreatedAt, &anime.UpdatedAt,
+			&trackingID, &trackerID, &status, &score, &currentEpisode, &totalEpisodes,
+			&lastUpdated, &notes, &priority, &tagsJSON, &isRewatching,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(alternativeTitlesJSON, &anime.AlternativeTitles); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(genresJSON, &anime.Genres); err != nil {
+			return nil, err
+		}
+
+		result := AnimeWithTracking{Anime: &anime}
+		if trackingID.Valid {
+			result.Tracking = &AnimeTracking{
+				ID:             trackingID.Int64,
+				AnimeID:        anime.ID,
+				Tracker:        tracker,
+				TrackerID:      trackerID.String,
+				Status:         status.String,
+				Score:          score.Float64,
+				CurrentEpisode: currentEpisode.Float64,
+				TotalEpisodes:  int(totalEpisodes.Int64),
+				LastUpdated:    lastUpdated.Time,
+				Notes:          notes.String,
+				Priority:       int(priority.Int64),
+				IsRewatching:   isRewatching.Bool,
+			}
+			if err := json.Unmarshal(tagsJSON, &result.Tracking.Tags); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal tags: %w", err)
+			}
+		}
+
+		results = append(results, result)
+	}
+
+	return results, rows.Err()
+}
+
+// GetAnimeByIDs gets the anime with the given IDs in one query. IDs that
+// don't exist are left out.
+func (db *DB) GetAnimeByIDs(ids []int64) ([]*Anime, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	placeholders := make([]string, len(ids))
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		placeholders[i] = "?"
+		args[i] = id
+	}
+
+	rows, err := db.conn.Query(`
+		SELECT id, title, original_title, alternative_titles, description, 
+		       total_episodes, type, year, season, status, genres, thumbnail_url, notes, mal_id,
+		       created_at, updated_at
+		FROM anime 
+		WHERE id IN (`+strings.Join(placeholders, ", ")+`)
+		ORDER BY id
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var animes []*Anime
+	for rows.Next() {
+		var anime Anime
+		var alternativeTitlesJSON, genresJSON []byte
+
+		err := rows.Scan(
+			&anime.ID, &anime.Title, &anime.OriginalTitle, &alternativeTitlesJSON,
+			&anime.Description, &anime.TotalEpisodes, &anime.Type, &anime.Year,
+			&anime.Season, &anime.Status, &genresJSON, &anime.ThumbnailURL, &anime.Notes, &anime.MalID,
 			&anime.CreatedAt, &anime.UpdatedAt,
 		)
 		if err != nil {
@@ -2,6 +2,10 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 )
 
@@ -12,6 +16,62 @@ type ConfigEntry struct {
 	UpdatedAt time.Time
 }
 
+// Configuration keys stored in the database
+const (
+	ConfigTrackerSyncInterval = "tracker_sync_interval" // minutes between background syncs
+	// ConfigTrackerMinSyncInterval is the floor of tracker_sync_interval in
+	// minutes. Lowering it syncs more often at the risk of the services rate
+	// limiting or blocking the account's requests.
+	ConfigTrackerMinSyncInterval = "tracker_min_sync_interval"
+	ConfigTrackerAutoSync        = "tracker_auto_sync"    // "true" to sync in the background
+	ConfigActiveTracker          = "active_tracker"       // name of the tracker in use
+	ConfigAllSourceLanguages     = "all_source_languages" // "true" to search sources of every language
+)
+
+// DefaultConfig holds the value of each configuration key that hasn't been set
+var DefaultConfig = map[string]string{
+	ConfigTrackerSyncInterval:    "60",
+	ConfigTrackerMinSyncInterval: "15",
+	ConfigTrackerAutoSync:        "false",
+	ConfigActiveTracker:          "local",
+	ConfigAllSourceLanguages:     "false",
+}
+
+// SeedDefaultConfig stores the default of every configuration key that
+// isn't set yet. Values that are already set are left alone.
+func (db *DB) SeedDefaultConfig() error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for key, value := range DefaultConfig {
+		_, err := tx.Exec(
+			"INSERT OR IGNORE INTO config (key, value, updated_at) VALUES (?, ?, CURRENT_TIMESTAMP)",
+			key, value,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to seed config %s: %w", key, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetConfigOrDefault retrieves a configuration value, falling back to its
+// entry in DefaultConfig when it isn't set
+func (db *DB) GetConfigOrDefault(key string) (string, error) {
+	value, err := db.GetConfig(key)
+	if err != nil {
+		return "", err
+	}
+	if value == "" {
+		return DefaultConfig[key], nil
+	}
+	return value, nil
+}
+
 // GetConfig retrieves a configuration value
 func (db *DB) GetConfig(key string) (string, error) {
 	var value string
@@ -32,6 +92,25 @@ func (db *DB) SetConfig(key, value string) error {
 	return err
 }
 
+// LastSyncKey returns the config key holding the last sync time of a tracker
+func LastSyncKey(tracker string) string {
+	return tracker + "_last_sync"
+}
+
+// GetLastSync returns when a tracker last synced, or the zero time if it
+// never has
+func (db *DB) GetLastSync(tracker string) (time.Time, error) {
+	value, err := db.GetConfig(LastSyncKey(tracker))
+	if err != nil || value == "" {
+		return time.Time{}, err
+	}
+	last, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid last sync time of %s: %w", tracker, err)
+	}
+	return last, nil
+}
+
 // GetAllConfig retrieves all configuration entries
 func (db *DB) GetAllConfig() ([]ConfigEntry, error) {
 	rows, err := db.conn.Query("SELECT key, value, updated_at FROM config")
@@ -57,3 +136,67 @@ func (db *DB) DeleteConfig(key string) error {
 	_, err := db.conn.Exec("DELETE FROM config WHERE key = ?", key)
 	return err
 }
+
+// searchHistoryKey is the config key holding recent search queries as a JSON list
+const searchHistoryKey = "search_history"
+
+// MaxSearchHistory is how many search queries are remembered
+const MaxSearchHistory = 10
+
+// ErrCorruptSearchHistory is returned when the stored search history can't be
+// decoded. Adding a query replaces it with a fresh history.
+var ErrCorruptSearchHistory = errors.New("search history is corrupt")
+
+// AddSearchHistory remembers a search query as the most recent one. A query
+// searched before, ignoring case, moves to the front instead of repeating,
+// and the oldest queries are dropped past MaxSearchHistory.
+func (db *DB) AddSearchHistory(query string) error {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil
+	}
+
+	history, err := db.GetSearchHistory(MaxSearchHistory)
+	if err != nil && !errors.Is(err, ErrCorruptSearchHistory) {
+		return err
+	}
+
+	updated := []string{query}
+	for _, previous := range history {
+		if !strings.EqualFold(previous, query) && len(updated) < MaxSearchHistory {
+			updated = append(updated, previous)
+		}
+	}
+
+	data, err := json.Marshal(updated)
+	if err != nil {
+		return fmt.Errorf("failed to encode search history: %w", err)
+	}
+	return db.SetConfig(searchHistoryKey, string(data))
+}
+
+// GetSearchHistory returns up to limit recent search queries, most recent first
+func (db *DB) GetSearchHistory(limit int) ([]string, error) {
+	value, err := db.GetConfig(searchHistoryKey)
+	if err != nil {
+		return nil, err
+	}
+	if value == "" {
+		return nil, nil
+	}
+
+	var history []string
+	if err := json.Unmarshal([]byte(value), &history); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorruptSearchHistory, err)
+	}
+	if len(history) > limit {
+		history = history[:limit]
+	}
+	return history, nil
+}
+
+// ClearSearchHistory forgets all search queries
+func (db *DB) ClearSearchHistory() error {
+	_, err := db.conn.Exec("DELETE FROM config WHERE key = ?", searchHistoryKey)
+	return err
+}
@@ -0,0 +1,103 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// Sides of a sync conflict
+const (
+	ConflictLocal  = "local"
+	ConflictRemote = "remote"
+)
+
+// SyncConflict records a field that differed between the local database and
+// a tracker, and which side's value was kept
+type SyncConflict struct {
+	ID          int64
+	AnimeID     int64
+	AnimeTitle  string // filled in when read back
+	Tracker     string
+	Field       string
+	LocalValue  string
+	RemoteValue string
+	Winner      string // ConflictLocal or ConflictRemote
+	CreatedAt   time.Time
+}
+
+// AddSyncConflict records a sync conflict. Times are stored in UTC so they
+// compare correctly when pruning.
+func (db *DB) AddSyncConflict(conflict *SyncConflict) error {
+	if conflict.CreatedAt.IsZero() {
+		conflict.CreatedAt = time.Now()
+	}
+	conflict.CreatedAt = conflict.CreatedAt.UTC()
+
+	result, err := db.conn.Exec(
+		`INSERT INTO sync_conflict_log (
+			anime_id, tracker, field, local_value, remote_value, winner, created_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		conflict.AnimeID, conflict.Tracker, conflict.Field, conflict.LocalValue,
+		conflict.RemoteValue, conflict.Winner, conflict.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record sync conflict: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	conflict.ID = id
+	return nil
+}
+
+// GetSyncConflicts returns up to limit sync conflicts, newest first
+func (db *DB) GetSyncConflicts(limit int) ([]*SyncConflict, error) {
+	rows, err := db.conn.Query(
+		`SELECT
+			c.id, c.anime_id, a.title, c.tracker, c.field,
+			c.local_value, c.remote_value, c.winner, c.created_at
+		FROM sync_conflict_log c
+		JOIN anime a ON a.id = c.anime_id
+		ORDER BY c.created_at DESC, c.id DESC
+		LIMIT ?`,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sync conflicts: %w", err)
+	}
+	defer rows.Close()
+
+	var conflicts []*SyncConflict
+	for rows.Next() {
+		var conflict SyncConflict
+		err := rows.Scan(
+			&conflict.ID, &conflict.AnimeID, &conflict.AnimeTitle, &conflict.Tracker,
+			&conflict.Field, &conflict.LocalValue, &conflict.RemoteValue,
+			&conflict.Winner, &conflict.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		conflicts = append(conflicts, &conflict)
+	}
+
+	return conflicts, rows.Err()
+}
+
+// PruneSyncConflicts deletes the sync conflicts recorded before cutoff and
+// returns how many were deleted
+func (db *DB) PruneSyncConflicts(cutoff time.Time) (int64, error) {
+	result, err := db.conn.Exec("DELETE FROM sync_conflict_log WHERE created_at < ?", cutoff.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune sync conflicts: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// ClearSyncConflicts deletes every recorded sync conflict
+func (db *DB) ClearSyncConflicts() error {
+	_, err := db.conn.Exec("DELETE FROM sync_conflict_log")
+	return err
+}
@@ -40,6 +40,13 @@ func New(dbPath string) (*DB, error) {
 	// Run migrations
 	migrations := []Migration{
 		InitialMigration(),
+		AddTrackingNotesMigration(),
+		AddTrackingListDetailsMigration(),
+		AddAnimeNotesMigration(),
+		AddTrackingRewatchMigration(),
+		AddSyncConflictLogMigration(),
+		AddSourceEpisodeOffsetMigration(),
+		AddAnimeMalIDMigration(),
 		// Add new migrations here
 	}
 
@@ -61,7 +68,7 @@ func (db *DB) GetRecentlyWatchedAnime(limit int) ([]*Anime, error) {
 	rows, err := db.conn.Query(`
 		SELECT DISTINCT a.id, a.title, a.original_title, a.alternative_titles, a.description, 
 		       a.total_episodes, a.type, a.year, a.season, a.status, a.genres, 
-		       a.thumbnail_url, a.created_at, a.updated_at
+		       a.thumbnail_url, a.notes, a.mal_id, a.created_at, a.updated_at
 		FROM anime a
 		JOIN episode_progress ep ON a.id = ep.anime_id
 		ORDER BY ep.last_watched DESC
@@ -80,7 +87,7 @@ func (db *DB) GetRecentlyWatchedAnime(limit int) ([]*Anime, error) {
 		err := rows.Scan(
 			&anime.ID, &anime.Title, &anime.OriginalTitle, &alternativeTitlesJSON,
 			&anime.Description, &anime.TotalEpisodes, &anime.Type, &anime.Year,
-			&anime.Season, &anime.Status, &genresJSON, &anime.ThumbnailURL,
+			&anime.Season, &anime.Status, &genresJSON, &anime.ThumbnailURL, &anime.Notes, &anime.MalID,
 			&anime.CreatedAt, &anime.UpdatedAt,
 		)
 		if err != nil {
@@ -102,12 +109,97 @@ func (db *DB) GetRecentlyWatchedAnime(limit int) ([]*Anime, error) {
 	return animes, rows.Err()
 }
 
+// GetRecentlyAddedAnime returns the anime most recently added to the library
+func (db *DB) GetRecentlyAddedAnime(limit int) ([]*Anime, error) {
+	rows, err := db.conn.Query(`
+		SELECT a.id, a.title, a.original_title, a.alternative_titles, a.description, 
+		       a.total_episodes, a.type, a.year, a.season, a.status, a.genres, 
+		       a.thumbnail_url, a.notes, a.mal_id, a.created_at, a.updated_at
+		FROM anime a
+		ORDER BY a.created_at DESC, a.id DESC
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query recently added anime: %w", err)
+	}
+	defer rows.Close()
+
+	var animes []*Anime
+	for rows.Next() {
+		var anime Anime
+		var alternativeTitlesJSON, genresJSON []byte
+
+		err := rows.Scan(
+			&anime.ID, &anime.Title, &anime.OriginalTitle, &alternativeTitlesJSON,
+			&anime.Description, &anime.TotalEpisodes, &anime.Type, &anime.Year,
+			&anime.Season, &anime.Status, &genresJSON, &anime.ThumbnailURL, &anime.Notes, &anime.MalID,
+			&anime.CreatedAt, &anime.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan anime: %w", err)
+		}
+
+		// Parse JSON fields
+		if err := json.Unmarshal(alternativeTitlesJSON, &anime.AlternativeTitles); err != nil {
+			return nil, fmt.Errorf("failed to parse alternative titles: %w", err)
+		}
+
+		if err := json.Unmarshal(genresJSON, &anime.Genres); err != nil {
+			return nil, fmt.Errorf("failed to parse genres: %w", err)
+		}
+
+		animes = append(animes, &anime)
+	}
+
+	return animes, rows.Err()
+}
+
+// CountAnimeByStatus returns the number of anime in each tracking status.
+// An anime tracked on several services is counted once per status.
+func (db *DB) CountAnimeByStatus() (map[string]int, error) {
+	rows, err := db.conn.Query(`
+		SELECT status, COUNT(DISTINCT anime_id)
+		FROM anime_tracking
+		GROUP BY status
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count anime by status: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan status count: %w", err)
+		}
+		counts[status] = count
+	}
+
+	return counts, rows.Err()
+}
+
+// CountWatchingAnime returns the number of anime currently being watched
+func (db *DB) CountWatchingAnime() (int, error) {
+	var count int
+	err := db.conn.QueryRow(`
+		SELECT COUNT(DISTINCT anime_id)
+		FROM anime_tracking
+		WHERE status = 'watching'
+	`).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count watching anime: %w", err)
+	}
+	return count, nil
+}
+
 // GetCurrentlyWatchingAnime returns anime that the user is currently watching
 func (db *DB) GetCurrentlyWatchingAnime() ([]*Anime, error) {
 	rows, err := db.conn.Query(`
 		SELECT DISTINCT a.id, a.title, a.original_title, a.alternative_titles, a.description, 
 		       a.total_episodes, a.type, a.year, a.season, a.status, a.genres, 
-		       a.thumbnail_url, a.created_at, a.updated_at
+		       a.thumbnail_url, a.notes, a.mal_id, a.created_at, a.updated_at
 		FROM anime a
 		JOIN anime_tracking at ON a.id = at.anime_id
 		WHERE at.status = 'watching'
@@ -126,7 +218,7 @@ func (db *DB) GetCurrentlyWatchingAnime() ([]*Anime, error) {
 		err := rows.Scan(
 			&anime.ID, &anime.Title, &anime.OriginalTitle, &alternativeTitlesJSON,
 			&anime.Description, &anime.TotalEpisodes, &anime.Type, &anime.Year,
-			&anime.Season, &anime.Status, &genresJSON, &anime.ThumbnailURL,
+			&anime.Season, &anime.Status, &genresJSON, &anime.ThumbnailURL, &anime.Notes, &anime.MalID,
 			&anime.CreatedAt, &anime.UpdatedAt,
 		)
 		if err != nil {
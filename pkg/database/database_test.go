@@ -1,7 +1,13 @@
 package database
 
 import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -203,7 +209,7 @@ func TestAnimeTrackingOperations(t *testing.T) {
 	}
 
 	// Test updating tracking info
-	err = db.UpdateAnimeTracking(anime.ID, "local", 13.0)
+	err = db.AdvanceAnimeProgress(anime.ID, "local", 13.0)
 	if err != nil {
 		t.Fatalf("Failed to update tracking info: %v", err)
 	}
@@ -216,6 +222,50 @@ func TestAnimeTrackingOperations(t *testing.T) {
 	if retrievedTracking.CurrentEpisode != 13.0 {
 		t.Errorf("Expected updated current episode 13.0, got %f", retrievedTracking.CurrentEpisode)
 	}
+
+	// Test that advancing never moves progress back
+	if err := db.AdvanceAnimeProgress(anime.ID, "local", 5.0); err != nil {
+		t.Fatalf("Failed to advance progress: %v", err)
+	}
+	retrievedTracking, err = db.GetAnimeTracking(anime.ID, "local")
+	if err != nil {
+		t.Fatalf("Failed to get tracking info: %v", err)
+	}
+	if retrievedTracking.CurrentEpisode != 13.0 {
+		t.Errorf("Expected current episode to stay 13.0, got %f", retrievedTracking.CurrentEpisode)
+	}
+}
+
+func TestSetAnimeProgress(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	anime := &Anime{Title: "Test Anime", TotalEpisodes: 24}
+	if err := db.AddAnime(anime); err != nil {
+		t.Fatalf("Failed to add anime: %v", err)
+	}
+	if err := db.AddAnimeTracking(&AnimeTracking{AnimeID: anime.ID, Tracker: "local", Status: "watching", CurrentEpisode: 12}); err != nil {
+		t.Fatalf("Failed to add tracking info: %v", err)
+	}
+
+	// Test both increasing and correcting progress downwards
+	for _, episode := range []float64{20, 7} {
+		if err := db.SetAnimeProgress(anime.ID, "local", episode); err != nil {
+			t.Fatalf("Failed to set progress to %v: %v", episode, err)
+		}
+		tracking, err := db.GetAnimeTracking(anime.ID, "local")
+		if err != nil {
+			t.Fatalf("Failed to get tracking info: %v", err)
+		}
+		if tracking.CurrentEpisode != episode {
+			t.Errorf("Expected current episode %v, got %v", episode, tracking.CurrentEpisode)
+		}
+	}
+
+	// Test that a missing entry is reported
+	if err := db.SetAnimeProgress(anime.ID, "mal", 3); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows, got %v", err)
+	}
 }
 
 func TestEpisodeOperations(t *testing.T) {
@@ -248,6 +298,18 @@ func TestEpisodeOperations(t *testing.T) {
 		t.Errorf("Expected non-zero ID after adding episode")
 	}
 
+	// Test that adding it again without details keeps the stored ones
+	if err := db.AddEpisode(&Episode{AnimeID: anime.ID, Number: 1.0}); err != nil {
+		t.Fatalf("Failed to add episode again: %v", err)
+	}
+	episodes, err := db.GetAllEpisodes(anime.ID)
+	if err != nil {
+		t.Fatalf("Failed to get episodes: %v", err)
+	}
+	if len(episodes) != 1 || episodes[0].Title != "Episode 1" || episodes[0].ThumbnailURL != "https://example.com/ep1.jpg" {
+		t.Errorf("Expected the stored title and thumbnail to be kept, got %+v", episodes)
+	}
+
 	// Create episode progress
 	progress := &EpisodeProgress{
 		AnimeID:       anime.ID,
@@ -410,3 +472,1577 @@ func TestMigrations(t *testing.T) {
 		t.Errorf("Failed to insert into migrated table: %v", err)
 	}
 }
+
+func TestEpisodeFillerOperations(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	anime := &Anime{Title: "Test Anime", TotalEpisodes: 3}
+	if err := db.AddAnime(anime); err != nil {
+		t.Fatalf("Failed to add anime: %v", err)
+	}
+	for _, number := range []float64{1, 2, 3} {
+		if err := db.AddEpisode(&Episode{AnimeID: anime.ID, Number: number}); err != nil {
+			t.Fatalf("Failed to add episode: %v", err)
+		}
+	}
+
+	// Test flagging an episode as filler
+	if err := db.UpdateEpisodeFiller(anime.ID, 2, true); err != nil {
+		t.Fatalf("Failed to update episode filler: %v", err)
+	}
+
+	fillers, err := db.GetFillerEpisodes(anime.ID)
+	if err != nil {
+		t.Fatalf("Failed to get filler episodes: %v", err)
+	}
+	if len(fillers) != 3 {
+		t.Errorf("Expected 3 episodes, got %d", len(fillers))
+	}
+	if !fillers[2] || fillers[1] || fillers[3] {
+		t.Errorf("Expected only episode 2 to be a filler, got %v", fillers)
+	}
+}
+
+func TestGetRecentlyAddedAnime(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	// Seed anime with staggered creation times
+	titles := []string{"Oldest", "Newest", "Middle"}
+	offsets := []time.Duration{-72 * time.Hour, -1 * time.Hour, -24 * time.Hour}
+	for i, title := range titles {
+		anime := &Anime{Title: title}
+		if err := db.AddAnime(anime); err != nil {
+			t.Fatalf("Failed to add anime: %v", err)
+		}
+		createdAt := time.Now().UTC().Add(offsets[i]).Format("2006-01-02 15:04:05")
+		if _, err := db.conn.Exec("UPDATE anime SET created_at = ? WHERE id = ?", createdAt, anime.ID); err != nil {
+			t.Fatalf("Failed to set created_at: %v", err)
+		}
+	}
+
+	// Test ordering by creation time
+	animes, err := db.GetRecentlyAddedAnime(10)
+	if err != nil {
+		t.Fatalf("Failed to get recently added anime: %v", err)
+	}
+	expected := []string{"Newest", "Middle", "Oldest"}
+	if len(animes) != len(expected) {
+		t.Fatalf("Expected %d anime, got %d", len(expected), len(animes))
+	}
+	for i, title := range expected {
+		if animes[i].Title != title {
+			t.Errorf("Expected anime %d to be '%s', got '%s'", i, title, animes[i].Title)
+		}
+	}
+
+	// Test the limit
+	animes, err = db.GetRecentlyAddedAnime(1)
+	if err != nil {
+		t.Fatalf("Failed to get recently added anime: %v", err)
+	}
+	if len(animes) != 1 || animes[0].Title != "Newest" {
+		t.Errorf("Expected only 'Newest', got %d results", len(animes))
+	}
+}
+
+func TestMaintenanceOperations(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	// Test integrity check on a healthy database
+	problems, err := db.IntegrityCheck()
+	if err != nil {
+		t.Fatalf("Failed to run integrity check: %v", err)
+	}
+	if len(problems) != 0 {
+		t.Errorf("Expected no integrity problems, got %v", problems)
+	}
+
+	// Test reindexing
+	if err := db.Reindex(); err != nil {
+		t.Fatalf("Failed to reindex: %v", err)
+	}
+
+	anime := &Anime{Title: "Test Anime"}
+	if err := db.AddAnime(anime); err != nil {
+		t.Fatalf("Failed to add anime: %v", err)
+	}
+	if err := db.AddAnimeTracking(&AnimeTracking{AnimeID: anime.ID, Tracker: "local", Status: "watching"}); err != nil {
+		t.Fatalf("Failed to add tracking info: %v", err)
+	}
+
+	// Seed orphan rows pointing at an anime that doesn't exist
+	if _, err := db.conn.Exec(
+		"INSERT INTO anime_tracking (anime_id, tracker, tracker_id, status) VALUES (999, 'anilist', '1', 'watching')",
+	); err != nil {
+		t.Fatalf("Failed to insert orphan tracking: %v", err)
+	}
+	if _, err := db.conn.Exec(
+		"INSERT INTO episode (anime_id, number, title) VALUES (999, 1, 'Orphan')",
+	); err != nil {
+		t.Fatalf("Failed to insert orphan episode: %v", err)
+	}
+
+	// Test cleaning the orphans
+	removed, err := db.CleanOrphans()
+	if err != nil {
+		t.Fatalf("Failed to clean orphans: %v", err)
+	}
+	if removed != 2 {
+		t.Errorf("Expected 2 orphaned rows removed, got %d", removed)
+	}
+
+	// Verify valid rows are kept
+	if _, err := db.GetAnimeTracking(anime.ID, "local"); err != nil {
+		t.Errorf("Expected valid tracking to be kept: %v", err)
+	}
+
+	// Test that a second run finds nothing
+	removed, err = db.CleanOrphans()
+	if err != nil {
+		t.Fatalf("Failed to clean orphans: %v", err)
+	}
+	if removed != 0 {
+		t.Errorf("Expected no orphaned rows on second run, got %d", removed)
+	}
+}
+
+func TestGetAnimeByExternalIDAmbiguous(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	first := &Anime{Title: "First"}
+	second := &Anime{Title: "Second"}
+	for _, anime := range []*Anime{first, second} {
+		if err := db.AddAnime(anime); err != nil {
+			t.Fatalf("Failed to add anime: %v", err)
+		}
+	}
+
+	// Test the single-match path
+	if err := db.AddAnimeTracking(&AnimeTracking{AnimeID: first.ID, Tracker: "anilist", TrackerID: "42"}); err != nil {
+		t.Fatalf("Failed to add tracking info: %v", err)
+	}
+	anime, err := db.GetAnimeByExternalID("42", "anilist")
+	if err != nil {
+		t.Fatalf("Failed to get anime by external ID: %v", err)
+	}
+	if anime.ID != first.ID {
+		t.Errorf("Expected anime ID %d, got %d", first.ID, anime.ID)
+	}
+
+	// Test the not-found path
+	if _, err := db.GetAnimeByExternalID("43", "anilist"); !errors.Is(err, ErrAnimeNotFound) {
+		t.Errorf("Expected ErrAnimeNotFound, got %v", err)
+	}
+
+	// Test two anime linked to the same tracker ID
+	if err := db.AddAnimeTracking(&AnimeTracking{AnimeID: second.ID, Tracker: "anilist", TrackerID: "42"}); err != nil {
+		t.Fatalf("Failed to add tracking info: %v", err)
+	}
+	_, err = db.GetAnimeByExternalID("42", "anilist")
+	if !errors.Is(err, ErrAmbiguousTracking) {
+		t.Fatalf("Expected ErrAmbiguousTracking, got %v", err)
+	}
+	expectedIDs := fmt.Sprintf("[%d %d]", first.ID, second.ID)
+	if !strings.Contains(err.Error(), expectedIDs) {
+		t.Errorf("Expected error to list anime IDs %s, got '%v'", expectedIDs, err)
+	}
+}
+
+func TestFindDuplicateAnime(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	titles := []struct {
+		title string
+		year  int
+	}{
+		{"Cowboy Bebop", 1998},
+		{"cowboy bebop!", 1998},
+		{"Cowboy Bebop", 2021},
+		{"Trigun", 1998},
+	}
+	for _, entry := range titles {
+		if err := db.AddAnime(&Anime{Title: entry.title, Year: entry.year}); err != nil {
+			t.Fatalf("Failed to add anime: %v", err)
+		}
+	}
+
+	// Test that only same title and year are grouped
+	groups, err := db.FindDuplicateAnime()
+	if err != nil {
+		t.Fatalf("Failed to find duplicates: %v", err)
+	}
+	if len(groups) != 1 || len(groups[0]) != 2 {
+		t.Fatalf("Expected 1 group of 2 duplicates, got %d groups", len(groups))
+	}
+	if groups[0][0].Title != "Cowboy Bebop" || groups[0][1].Title != "cowboy bebop!" {
+		t.Errorf("Unexpected duplicate group: %s, %s", groups[0][0].Title, groups[0][1].Title)
+	}
+}
+
+func TestMergeAnime(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	keep := &Anime{Title: "Test Anime", TotalEpisodes: 12}
+	dup := &Anime{Title: "Test Anime", TotalEpisodes: 12}
+	for _, anime := range []*Anime{keep, dup} {
+		if err := db.AddAnime(anime); err != nil {
+			t.Fatalf("Failed to add anime: %v", err)
+		}
+	}
+	if err := db.UpdateAnimeNotes(dup.ID, "Rewatch the finale"); err != nil {
+		t.Fatalf("Failed to update notes: %v", err)
+	}
+	if err := db.SetAnimeMalID(dup.ID, "5114"); err != nil {
+		t.Fatalf("Failed to set MAL ID: %v", err)
+	}
+
+	ext := &Extension{Name: "Ext", Package: "ext", Language: "en", Version: "1.0", Path: "/bin/true"}
+	if err := db.AddExtension(ext); err != nil {
+		t.Fatalf("Failed to add extension: %v", err)
+	}
+	source := &Source{SourceID: "src", ExtensionID: ext.ID, Name: "Source", Language: "en", BaseURL: "https://example.com"}
+	if err := db.AddSource(source); err != nil {
+		t.Fatalf("Failed to add source: %v", err)
+	}
+
+	// Conflicting tracking, the duplicate's entry is newer
+	if err := db.AddAnimeTracking(&AnimeTracking{AnimeID: keep.ID, Tracker: "anilist", TrackerID: "1", Status: "watching", CurrentEpisode: 2}); err != nil {
+		t.Fatalf("Failed to add tracking: %v", err)
+	}
+	newer := &AnimeTracking{AnimeID: dup.ID, Tracker: "anilist", TrackerID: "1", Status: "watching"}
+	if err := db.AddAnimeTracking(newer); err != nil {
+		t.Fatalf("Failed to add tracking: %v", err)
+	}
+	newer.CurrentEpisode = 7
+	time.Sleep(1100 * time.Millisecond) // last_updated has second precision
+	if err := db.UpdateAnimeTrackingObject(newer); err != nil {
+		t.Fatalf("Failed to update tracking: %v", err)
+	}
+
+	// Non-conflicting rows only on the duplicate
+	if err := db.AddAnimeTracking(&AnimeTracking{AnimeID: dup.ID, Tracker: "mal", TrackerID: "2", Status: "watching"}); err != nil {
+		t.Fatalf("Failed to add tracking: %v", err)
+	}
+	if err := db.AddEpisode(&Episode{AnimeID: dup.ID, Number: 1, Title: "Duplicate"}); err != nil {
+		t.Fatalf("Failed to add episode: %v", err)
+	}
+	if err := db.AddEpisode(&Episode{AnimeID: keep.ID, Number: 1, Title: "Kept"}); err != nil {
+		t.Fatalf("Failed to add episode: %v", err)
+	}
+	if err := db.AddEpisode(&Episode{AnimeID: dup.ID, Number: 2, Title: "Second"}); err != nil {
+		t.Fatalf("Failed to add episode: %v", err)
+	}
+	if err := db.AddEpisodeProgress(&EpisodeProgress{AnimeID: dup.ID, EpisodeNumber: 2, Position: 60, Duration: 1400, PlaybackSpeed: 1, LastWatched: time.Now()}); err != nil {
+		t.Fatalf("Failed to add progress: %v", err)
+	}
+	if err := db.AddAnimeSource(&AnimeSource{AnimeID: dup.ID, SourceID: source.ID, SourceAnimeID: "dup"}); err != nil {
+		t.Fatalf("Failed to add anime source: %v", err)
+	}
+	if err := db.AddSyncConflict(&SyncConflict{AnimeID: dup.ID, Tracker: "mal", Field: "score", LocalValue: "7", RemoteValue: "8", Winner: ConflictRemote}); err != nil {
+		t.Fatalf("Failed to add sync conflict: %v", err)
+	}
+
+	// Per-anime settings, the kept anime's win
+	settings := map[string]string{
+		fmt.Sprintf("anime.%d.audio", keep.ID):      "sub",
+		fmt.Sprintf("anime.%d.audio", dup.ID):       "dub",
+		fmt.Sprintf("anime.%d.last_source", dup.ID): "src",
+	}
+	for key, value := range settings {
+		if err := db.SetConfig(key, value); err != nil {
+			t.Fatalf("Failed to set config: %v", err)
+		}
+	}
+
+	// Test merging the duplicate
+	if err := db.MergeAnime(keep.ID, []int64{dup.ID}); err != nil {
+		t.Fatalf("Failed to merge anime: %v", err)
+	}
+
+	if _, err := db.GetAnime(dup.ID); err == nil {
+		t.Errorf("Expected merged anime to be deleted")
+	}
+
+	trackings, err := db.GetAllAnimeTracking(keep.ID)
+	if err != nil {
+		t.Fatalf("Failed to get tracking: %v", err)
+	}
+	if len(trackings) != 2 {
+		t.Fatalf("Expected 2 tracking entries, got %d", len(trackings))
+	}
+	anilist, err := db.GetAnimeTracking(keep.ID, "anilist")
+	if err != nil {
+		t.Fatalf("Failed to get anilist tracking: %v", err)
+	}
+	if anilist.CurrentEpisode != 7 {
+		t.Errorf("Expected the newer tracking to win with episode 7, got %v", anilist.CurrentEpisode)
+	}
+
+	episodes, err := db.GetAllEpisodes(keep.ID)
+	if err != nil {
+		t.Fatalf("Failed to get episodes: %v", err)
+	}
+	if len(episodes) != 2 || episodes[0].Title != "Kept" || episodes[1].Title != "Second" {
+		t.Errorf("Expected kept episode 1 and moved episode 2, got %d episodes", len(episodes))
+	}
+
+	if _, err := db.GetEpisodeProgress(keep.ID, 2); err != nil {
+		t.Errorf("Expected progress to be moved: %v", err)
+	}
+
+	sources, err := db.GetAnimeSources(keep.ID)
+	if err != nil {
+		t.Fatalf("Failed to get anime sources: %v", err)
+	}
+	if len(sources) != 1 || sources[0].SourceAnimeID != "dup" {
+		t.Errorf("Expected the source mapping to be moved, got %d", len(sources))
+	}
+
+	kept, err := db.GetAnime(keep.ID)
+	if err != nil {
+		t.Fatalf("Failed to get anime: %v", err)
+	}
+	if kept.Notes != "Rewatch the finale" || kept.MalID != "5114" {
+		t.Errorf("Expected notes and MAL ID to be moved, got %q and %q", kept.Notes, kept.MalID)
+	}
+
+	conflicts, err := db.GetSyncConflicts(10)
+	if err != nil {
+		t.Fatalf("Failed to get sync conflicts: %v", err)
+	}
+	if len(conflicts) != 1 || conflicts[0].AnimeID != keep.ID {
+		t.Errorf("Expected the sync conflict to be moved, got %+v", conflicts)
+	}
+
+	for key, want := range map[string]string{
+		fmt.Sprintf("anime.%d.audio", keep.ID):       "sub",
+		fmt.Sprintf("anime.%d.last_source", keep.ID): "src",
+		fmt.Sprintf("anime.%d.audio", dup.ID):        "",
+		fmt.Sprintf("anime.%d.last_source", dup.ID):  "",
+	} {
+		if value, err := db.GetConfig(key); err != nil || value != want {
+			t.Errorf("Expected %s to be %q, got %q (%v)", key, want, value, err)
+		}
+	}
+
+	// Test that merging into itself is rejected
+	if err := db.MergeAnime(keep.ID, []int64{keep.ID}); err == nil {
+		t.Errorf("Expected error when merging anime into itself")
+	}
+}
+
+// writeBackup writes backup data to a temporary file and returns its path
+func writeBackup(t *testing.T, data BackupData) string {
+	path := filepath.Join(t.TempDir(), "backup.json")
+	content, err := json.Marshal(data)
+	if err != nil {
+		t.Fatalf("Failed to marshal backup: %v", err)
+	}
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatalf("Failed to write backup: %v", err)
+	}
+	return path
+}
+
+func TestImportBackupVersions(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	now := time.Now()
+	old := BackupData{
+		Version:   1,
+		CreatedAt: now,
+		Anime: []Anime{{
+			ID: 1, Title: "Old Backup Anime", AlternativeTitles: []string{}, Genres: []string{},
+			CreatedAt: now, UpdatedAt: now,
+		}},
+		AnimeTracking: []AnimeTracking{{
+			ID: 1, AnimeID: 1, Tracker: "local", TrackerID: "1", Status: "watching", LastUpdated: now,
+		}},
+	}
+
+	// Test importing a backup from an older version
+	if _, err := db.ImportFromJSON(writeBackup(t, old), ImportOptions{}); err != nil {
+		t.Fatalf("Failed to import older backup: %v", err)
+	}
+	tracking, err := db.GetAnimeTracking(1, "local")
+	if err != nil {
+		t.Fatalf("Failed to get imported tracking: %v", err)
+	}
+	if tracking.Status != "watching" || tracking.Notes != "" {
+		t.Errorf("Expected watching with empty notes, got %s with '%s'", tracking.Status, tracking.Notes)
+	}
+
+	// Test refusing a backup from a newer version
+	newer := old
+	newer.Version = currentBackupVersion + 1
+	_, err = db.ImportFromJSON(writeBackup(t, newer), ImportOptions{})
+	if !errors.Is(err, ErrBackupTooNew) {
+		t.Errorf("Expected ErrBackupTooNew, got %v", err)
+	}
+}
+
+func TestCountAnimeByStatus(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	statuses := []string{"watching", "watching", "completed", "dropped", "watching"}
+	for i, status := range statuses {
+		anime := &Anime{Title: fmt.Sprintf("Anime %d", i)}
+		if err := db.AddAnime(anime); err != nil {
+			t.Fatalf("Failed to add anime: %v", err)
+		}
+		if err := db.AddAnimeTracking(&AnimeTracking{AnimeID: anime.ID, Tracker: "local", Status: status}); err != nil {
+			t.Fatalf("Failed to add tracking info: %v", err)
+		}
+		// Anime tracked on a second service must not be counted twice
+		if i == 0 {
+			if err := db.AddAnimeTracking(&AnimeTracking{AnimeID: anime.ID, Tracker: "anilist", TrackerID: "1", Status: status}); err != nil {
+				t.Fatalf("Failed to add tracking info: %v", err)
+			}
+		}
+	}
+
+	counts, err := db.CountAnimeByStatus()
+	if err != nil {
+		t.Fatalf("Failed to count anime by status: %v", err)
+	}
+	expected := map[string]int{"watching": 3, "completed": 1, "dropped": 1}
+	for status, count := range expected {
+		if counts[status] != count {
+			t.Errorf("Expected %d %s anime, got %d", count, status, counts[status])
+		}
+	}
+	if len(counts) != len(expected) {
+		t.Errorf("Expected %d statuses, got %v", len(expected), counts)
+	}
+
+	watching, err := db.CountWatchingAnime()
+	if err != nil {
+		t.Fatalf("Failed to count watching anime: %v", err)
+	}
+	if watching != 3 {
+		t.Errorf("Expected 3 watching anime, got %d", watching)
+	}
+}
+
+func TestRelinkTracking(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	first := &Anime{Title: "First Anime"}
+	second := &Anime{Title: "Second Anime"}
+	for _, anime := range []*Anime{first, second} {
+		if err := db.AddAnime(anime); err != nil {
+			t.Fatalf("Failed to add anime: %v", err)
+		}
+	}
+	if err := db.AddAnimeTracking(&AnimeTracking{AnimeID: first.ID, Tracker: "anilist", TrackerID: "100", Status: "watching"}); err != nil {
+		t.Fatalf("Failed to add tracking info: %v", err)
+	}
+	if err := db.AddAnimeTracking(&AnimeTracking{AnimeID: second.ID, Tracker: "anilist", TrackerID: "200", Status: "watching"}); err != nil {
+		t.Fatalf("Failed to add tracking info: %v", err)
+	}
+
+	// Test relinking to a free remote entry
+	if err := db.RelinkTracking(first.ID, "anilist", "101"); err != nil {
+		t.Fatalf("Failed to relink tracking: %v", err)
+	}
+	tracking, err := db.GetAnimeTracking(first.ID, "anilist")
+	if err != nil {
+		t.Fatalf("Failed to get tracking info: %v", err)
+	}
+	if tracking.TrackerID != "101" {
+		t.Errorf("Expected tracker ID 101, got %s", tracking.TrackerID)
+	}
+
+	// Test relinking to an entry another anime is linked to
+	err = db.RelinkTracking(first.ID, "anilist", "200")
+	var conflict *TrackingConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("Expected TrackingConflictError, got %v", err)
+	}
+	if conflict.AnimeID != second.ID {
+		t.Errorf("Expected conflict with anime %d, got %d", second.ID, conflict.AnimeID)
+	}
+	if !errors.Is(err, ErrTrackingConflict) {
+		t.Errorf("Expected error to wrap ErrTrackingConflict")
+	}
+	tracking, err = db.GetAnimeTracking(first.ID, "anilist")
+	if err != nil {
+		t.Fatalf("Failed to get tracking info: %v", err)
+	}
+	if tracking.TrackerID != "101" {
+		t.Errorf("Expected tracker ID to stay 101, got %s", tracking.TrackerID)
+	}
+
+	// Test relinking an anime that isn't tracked there
+	if err := db.RelinkTracking(first.ID, "mal", "5"); !errors.Is(err, ErrTrackingNotFound) {
+		t.Errorf("Expected ErrTrackingNotFound, got %v", err)
+	}
+}
+
+func TestEpisodeProgressPercent(t *testing.T) {
+	tests := []struct {
+		position, duration int
+		expected           float64
+	}{
+		{position: 504, duration: 1440, expected: 35},
+		{position: 0, duration: 1440, expected: 0},
+		{position: 1500, duration: 1440, expected: 100},
+		{position: 300, duration: 0, expected: 0}, // Unknown duration
+	}
+
+	for _, tt := range tests {
+		p := &EpisodeProgress{Position: tt.position, Duration: tt.duration}
+		if got := p.Percent(); got != tt.expected {
+			t.Errorf("Expected %d/%d to be %g%%, got %g%%", tt.position, tt.duration, tt.expected, got)
+		}
+	}
+}
+
+func TestGetInProgressEpisodes(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	anime := &Anime{Title: "Test Anime", TotalEpisodes: 12}
+	if err := db.AddAnime(anime); err != nil {
+		t.Fatalf("Failed to add anime: %v", err)
+	}
+
+	progress := []*EpisodeProgress{
+		{EpisodeNumber: 1, Position: 1440, Duration: 1440, Watched: true},
+		{EpisodeNumber: 2, Position: 500, Duration: 1440},
+		{EpisodeNumber: 3, Position: 0, Duration: 1440},
+		{EpisodeNumber: 4, Position: 1400, Duration: 1440, Watched: true},
+	}
+	for _, p := range progress {
+		p.AnimeID = anime.ID
+		p.PlaybackSpeed = 1
+		p.LastWatched = time.Now()
+		if err := db.AddEpisodeProgress(p); err != nil {
+			t.Fatalf("Failed to add episode progress: %v", err)
+		}
+	}
+
+	// Test that only started, unfinished and unwatched episodes are returned
+	inProgress, err := db.GetInProgressEpisodes(anime.ID)
+	if err != nil {
+		t.Fatalf("Failed to get in-progress episodes: %v", err)
+	}
+	if len(inProgress) != 1 || inProgress[0].EpisodeNumber != 2 {
+		t.Errorf("Expected only episode 2 in progress, got %+v", inProgress)
+	}
+}
+
+func TestAnimeNotes(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	anime := &Anime{Title: "Noted Show", TotalEpisodes: 12}
+	if err := db.AddAnime(anime); err != nil {
+		t.Fatalf("Failed to add anime: %v", err)
+	}
+
+	// Test that notes persist and survive a metadata update
+	if err := db.UpdateAnimeNotes(anime.ID, "Rewatch with friends"); err != nil {
+		t.Fatalf("Failed to update notes: %v", err)
+	}
+	anime.TotalEpisodes = 13
+	if err := db.UpdateAnime(anime); err != nil {
+		t.Fatalf("Failed to update anime: %v", err)
+	}
+	got, err := db.GetAnime(anime.ID)
+	if err != nil {
+		t.Fatalf("Failed to get anime: %v", err)
+	}
+	if got.Notes != "Rewatch with friends" {
+		t.Errorf("Expected notes 'Rewatch with friends', got '%s'", got.Notes)
+	}
+
+	// Test that unknown anime are reported
+	if err := db.UpdateAnimeNotes(anime.ID+100, "missing"); !errors.Is(err, ErrAnimeNotFound) {
+		t.Errorf("Expected ErrAnimeNotFound, got %v", err)
+	}
+
+	// Test that notes round-trip through export and import
+	backup := filepath.Join(t.TempDir(), "backup.json")
+	if _, err := db.ExportToJSON(backup); err != nil {
+		t.Fatalf("Failed to export: %v", err)
+	}
+	restored, restoreCleanup := setupTestDB(t)
+	defer restoreCleanup()
+	if _, err := restored.ImportFromJSON(backup, ImportOptions{Mode: ImportReplace}); err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+	got, err = restored.GetAnime(anime.ID)
+	if err != nil {
+		t.Fatalf("Failed to get restored anime: %v", err)
+	}
+	if got.Notes != "Rewatch with friends" {
+		t.Errorf("Expected restored notes 'Rewatch with friends', got '%s'", got.Notes)
+	}
+}
+
+func TestExportChangedSince(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	var animes []*Anime
+	for _, title := range []string{"Unchanged Show", "Changed Show"} {
+		anime := &Anime{Title: title, TotalEpisodes: 12}
+		if err := db.AddAnime(anime); err != nil {
+			t.Fatalf("Failed to add anime: %v", err)
+		}
+		tracking := &AnimeTracking{AnimeID: anime.ID, Tracker: "anilist", Status: "watching", CurrentEpisode: 3}
+		if err := db.AddAnimeTracking(tracking); err != nil {
+			t.Fatalf("Failed to add tracking: %v", err)
+		}
+		animes = append(animes, anime)
+	}
+
+	// Date the library before the cutoff
+	old := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := db.conn.Exec("UPDATE anime SET updated_at = ?", old); err != nil {
+		t.Fatalf("Failed to date anime: %v", err)
+	}
+	if _, err := db.conn.Exec("UPDATE anime_tracking SET last_updated = ?", old); err != nil {
+		t.Fatalf("Failed to date tracking: %v", err)
+	}
+	base := filepath.Join(t.TempDir(), "base.json")
+	if _, err := db.ExportToJSON(base); err != nil {
+		t.Fatalf("Failed to export: %v", err)
+	}
+
+	changed := animes[1]
+	changed.TotalEpisodes = 24
+	if err := db.UpdateAnime(changed); err != nil {
+		t.Fatalf("Failed to update anime: %v", err)
+	}
+
+	// Test that only the changed anime is exported
+	delta := filepath.Join(t.TempDir(), "delta.json")
+	summary, err := db.ExportChangedSince(old.AddDate(1, 0, 0), delta)
+	if err != nil {
+		t.Fatalf("Failed to export changes: %v", err)
+	}
+	if summary != (BackupSummary{Anime: 1}) {
+		t.Errorf("Expected only one anime exported, got %+v", summary)
+	}
+	content, err := os.ReadFile(delta)
+	if err != nil {
+		t.Fatalf("Failed to read delta: %v", err)
+	}
+	var data BackupData
+	if err := json.Unmarshal(content, &data); err != nil {
+		t.Fatalf("Failed to decode delta: %v", err)
+	}
+	if data.ChangedSince == nil || len(data.Anime) != 1 || data.Anime[0].ID != changed.ID {
+		t.Errorf("Expected a delta holding %s, got %+v", changed.Title, data)
+	}
+
+	// Test that a delta can't replace the library and a full backup isn't a delta
+	restored, restoreCleanup := setupTestDB(t)
+	defer restoreCleanup()
+	if _, err := restored.ImportFromJSON(delta, ImportOptions{Mode: ImportReplace}); !errors.Is(err, ErrDeltaBackup) {
+		t.Errorf("Expected ErrDeltaBackup, got %v", err)
+	}
+	if _, err := restored.ImportDelta(base); !errors.Is(err, ErrDeltaBackup) {
+		t.Errorf("Expected ErrDeltaBackup, got %v", err)
+	}
+
+	// Test that the delta applies on top of the full backup
+	if _, err := restored.ImportFromJSON(base, ImportOptions{}); err != nil {
+		t.Fatalf("Failed to import base: %v", err)
+	}
+	stats, err := restored.ImportDelta(delta)
+	if err != nil {
+		t.Fatalf("Failed to import delta: %v", err)
+	}
+	if stats.Imported.Anime != 1 || stats.Skipped != 0 {
+		t.Errorf("Expected one anime imported, got %+v", stats)
+	}
+	got, err := restored.GetAnime(changed.ID)
+	if err != nil {
+		t.Fatalf("Failed to get anime: %v", err)
+	}
+	if got.TotalEpisodes != 24 {
+		t.Errorf("Expected 24 episodes, got %d", got.TotalEpisodes)
+	}
+
+	// Test that a delta doesn't overwrite newer changes
+	if _, err := restored.conn.Exec("UPDATE anime SET total_episodes = 25, updated_at = ? WHERE id = ?", time.Now().AddDate(0, 0, 1), changed.ID); err != nil {
+		t.Fatalf("Failed to change anime: %v", err)
+	}
+	if stats, err = restored.ImportDelta(delta); err != nil {
+		t.Fatalf("Failed to import delta: %v", err)
+	}
+	if stats.Imported.Anime != 0 || stats.Skipped != 1 {
+		t.Errorf("Expected the anime to be skipped, got %+v", stats)
+	}
+	if got, _ = restored.GetAnime(changed.ID); got.TotalEpisodes != 25 {
+		t.Errorf("Expected the newer 25 episodes to be kept, got %d", got.TotalEpisodes)
+	}
+}
+
+func TestDeleteAnimeRemovesRelatedRows(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	anime := &Anime{Title: "Removed Show", TotalEpisodes: 12}
+	if err := db.AddAnime(anime); err != nil {
+		t.Fatalf("Failed to add anime: %v", err)
+	}
+	if err := db.AddAnimeTracking(&AnimeTracking{AnimeID: anime.ID, Tracker: "local", TrackerID: "1", Status: "watching", LastUpdated: time.Now()}); err != nil {
+		t.Fatalf("Failed to add tracking: %v", err)
+	}
+	if err := db.AddEpisode(&Episode{AnimeID: anime.ID, Number: 1}); err != nil {
+		t.Fatalf("Failed to add episode: %v", err)
+	}
+	if err := db.AddEpisodeProgress(&EpisodeProgress{AnimeID: anime.ID, EpisodeNumber: 1, Position: 60, Duration: 1440, PlaybackSpeed: 1, LastWatched: time.Now()}); err != nil {
+		t.Fatalf("Failed to add episode progress: %v", err)
+	}
+	ext := &Extension{Name: "Test Extension", Package: "extension", Language: "en", Version: "1.0.0"}
+	if err := db.AddExtension(ext); err != nil {
+		t.Fatalf("Failed to add extension: %v", err)
+	}
+	source := &Source{SourceID: "source", ExtensionID: ext.ID, Name: "Test Source", Language: "en"}
+	if err := db.AddSource(source); err != nil {
+		t.Fatalf("Failed to add source: %v", err)
+	}
+	if err := db.AddAnimeSource(&AnimeSource{AnimeID: anime.ID, SourceID: source.ID, SourceAnimeID: "remote"}); err != nil {
+		t.Fatalf("Failed to add anime source: %v", err)
+	}
+	settings := []string{
+		fmt.Sprintf("anime.%d.audio", anime.ID),
+		fmt.Sprintf("anime.%d.new_episodes", anime.ID),
+		fmt.Sprintf("anime.%d1.audio", anime.ID), // another anime
+	}
+	for _, key := range settings {
+		if err := db.SetConfig(key, "dub"); err != nil {
+			t.Fatalf("Failed to set config: %v", err)
+		}
+	}
+
+	if err := db.DeleteAnime(anime.ID); err != nil {
+		t.Fatalf("Failed to delete anime: %v", err)
+	}
+
+	for _, table := range append([]string{"anime"}, animeChildTables...) {
+		column := "anime_id"
+		if table == "anime" {
+			column = "id"
+		}
+		var count int
+		if err := db.conn.QueryRow("SELECT COUNT(*) FROM "+table+" WHERE "+column+" = ?", anime.ID).Scan(&count); err != nil {
+			t.Fatalf("Failed to count %s rows: %v", table, err)
+		}
+		if count != 0 {
+			t.Errorf("Expected no %s rows, got %d", table, count)
+		}
+	}
+
+	// Test that only the settings of the deleted anime are removed
+	for i, want := range []string{"", "", "dub"} {
+		if value, err := db.GetConfig(settings[i]); err != nil || value != want {
+			t.Errorf("Expected %s to be %q, got %q (%v)", settings[i], want, value, err)
+		}
+	}
+}
+
+func TestSeedDefaultConfig(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	// Test that unset keys fall back to their defaults before seeding
+	if value, err := db.GetConfigOrDefault(ConfigActiveTracker); err != nil || value != "local" {
+		t.Errorf("Expected default active tracker 'local', got '%s' (%v)", value, err)
+	}
+
+	if err := db.SeedDefaultConfig(); err != nil {
+		t.Fatalf("Failed to seed config: %v", err)
+	}
+	for key, want := range DefaultConfig {
+		if value, err := db.GetConfig(key); err != nil || value != want {
+			t.Errorf("Expected %s to be seeded with '%s', got '%s' (%v)", key, want, value, err)
+		}
+	}
+
+	// Test that seeding again keeps values the user changed
+	if err := db.SetConfig(ConfigTrackerSyncInterval, "30"); err != nil {
+		t.Fatalf("Failed to set config: %v", err)
+	}
+	if err := db.SeedDefaultConfig(); err != nil {
+		t.Fatalf("Failed to seed config again: %v", err)
+	}
+	if value, _ := db.GetConfig(ConfigTrackerSyncInterval); value != "30" {
+		t.Errorf("Expected sync interval to stay '30', got '%s'", value)
+	}
+}
+
+func TestImportLenient(t *testing.T) {
+	now := time.Now()
+	backup := writeBackup(t, BackupData{
+		Version: currentBackupVersion,
+		Anime: []Anime{
+			{ID: 1, Title: "First Show", CreatedAt: now, UpdatedAt: now},
+			{ID: 2, Title: "", CreatedAt: now, UpdatedAt: now},
+			{ID: 3, Title: "Third Show", CreatedAt: now, UpdatedAt: now},
+		},
+		AnimeTracking: []AnimeTracking{
+			{ID: 1, AnimeID: 1, Tracker: "local", TrackerID: "1", Status: "watching", LastUpdated: now},
+			{ID: 2, AnimeID: 2, Tracker: "local", TrackerID: "2", Status: "watching", LastUpdated: now},
+		},
+	})
+
+	// Test that a strict import rolls back everything on the malformed row
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+	if _, err := db.ImportFromJSON(backup, ImportOptions{}); err == nil {
+		t.Errorf("Expected strict import to fail on the malformed anime")
+	}
+	if animes, _ := db.GetAllAnime(); len(animes) != 0 {
+		t.Errorf("Expected no anime after a failed strict import, got %d", len(animes))
+	}
+
+	// Test that a lenient import keeps the valid rows and reports the rest
+	stats, err := db.ImportFromJSON(backup, ImportOptions{Lenient: true})
+	if err != nil {
+		t.Fatalf("Failed to import leniently: %v", err)
+	}
+	if stats.Imported.Anime != 2 || stats.Imported.Tracking != 1 {
+		t.Errorf("Expected 2 anime and 1 tracking imported, got %+v", stats.Imported)
+	}
+	if stats.Failed != 2 || len(stats.Errors) != 2 {
+		t.Errorf("Expected the anime and its tracking to fail, got %d failures: %v", stats.Failed, stats.Errors)
+	}
+	animes, err := db.GetAllAnime()
+	if err != nil {
+		t.Fatalf("Failed to get anime: %v", err)
+	}
+	if len(animes) != 2 {
+		t.Errorf("Expected 2 anime after a lenient import, got %d", len(animes))
+	}
+	if _, err := db.GetAnimeTracking(1, "local"); err != nil {
+		t.Errorf("Expected tracking of the valid anime to be imported: %v", err)
+	}
+}
+
+func TestGetNextUnwatchedEpisode(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	anime := &Anime{Title: "Short Show", TotalEpisodes: 3}
+	if err := db.AddAnime(anime); err != nil {
+		t.Fatalf("Failed to add anime: %v", err)
+	}
+
+	// Test counting episodes when the episode list hasn't been fetched
+	next, ok, err := db.GetNextUnwatchedEpisode(anime.ID, 1, anime.TotalEpisodes)
+	if err != nil {
+		t.Fatalf("Failed to get next episode: %v", err)
+	}
+	if !ok || next != 2 {
+		t.Errorf("Expected episode 2, got %g (ok %v)", next, ok)
+	}
+
+	// Test that watched episodes are skipped
+	if err := db.MarkEpisodeWatched(anime.ID, 2, "test-source"); err != nil {
+		t.Fatalf("Failed to mark episode watched: %v", err)
+	}
+	next, ok, err = db.GetNextUnwatchedEpisode(anime.ID, 1, anime.TotalEpisodes)
+	if err != nil {
+		t.Fatalf("Failed to get next episode: %v", err)
+	}
+	if !ok || next != 3 {
+		t.Errorf("Expected episode 3, got %g (ok %v)", next, ok)
+	}
+
+	// Test the end of the series
+	if _, ok, err = db.GetNextUnwatchedEpisode(anime.ID, 3, anime.TotalEpisodes); err != nil || ok {
+		t.Errorf("Expected no episode after the last one, got ok %v, err %v", ok, err)
+	}
+
+	// Test that a fetched episode list, including specials, is followed
+	for _, number := range []float64{1, 2, 2.5, 3} {
+		if err := db.AddEpisode(&Episode{AnimeID: anime.ID, Number: number}); err != nil {
+			t.Fatalf("Failed to add episode: %v", err)
+		}
+	}
+	next, ok, err = db.GetNextUnwatchedEpisode(anime.ID, 1, anime.TotalEpisodes)
+	if err != nil {
+		t.Fatalf("Failed to get next episode: %v", err)
+	}
+	if !ok || next != 2.5 {
+		t.Errorf("Expected episode 2.5, got %g (ok %v)", next, ok)
+	}
+	if _, ok, err = db.GetNextUnwatchedEpisode(anime.ID, 3, anime.TotalEpisodes); err != nil || ok {
+		t.Errorf("Expected no episode after the last one, got ok %v, err %v", ok, err)
+	}
+}
+
+func TestMarkEpisodesWatchedThrough(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	anime := &Anime{Title: "Remote Show", TotalEpisodes: 12}
+	if err := db.AddAnime(anime); err != nil {
+		t.Fatalf("Failed to add anime: %v", err)
+	}
+	started := &EpisodeProgress{AnimeID: anime.ID, EpisodeNumber: 2, Position: 300, Duration: 1440, PlaybackSpeed: 1, LastWatched: time.Now()}
+	if err := db.AddEpisodeProgress(started); err != nil {
+		t.Fatalf("Failed to add episode progress: %v", err)
+	}
+
+	// Test that every episode up to the progress is marked once
+	marked, err := db.MarkEpisodesWatchedThrough(anime.ID, 3, time.Now())
+	if err != nil {
+		t.Fatalf("Failed to mark episodes watched: %v", err)
+	}
+	if marked != 3 {
+		t.Errorf("Expected 3 episodes marked, got %d", marked)
+	}
+	if marked, err = db.MarkEpisodesWatchedThrough(anime.ID, 3, time.Now()); err != nil || marked != 0 {
+		t.Errorf("Expected nothing marked again, got %d (%v)", marked, err)
+	}
+
+	// Test that a started episode keeps its resume position
+	progress, err := db.GetEpisodeProgress(anime.ID, 2)
+	if err != nil {
+		t.Fatalf("Failed to get episode progress: %v", err)
+	}
+	if !progress.Watched || progress.Position != 300 {
+		t.Errorf("Expected episode 2 watched at 300s, got %+v", progress)
+	}
+	if progress, _ := db.GetEpisodeProgress(anime.ID, 4); progress != nil {
+		t.Errorf("Expected no progress past episode 3, got %+v", progress)
+	}
+}
+
+func TestDiffEpisodes(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	anime := &Anime{Title: "Airing Show", TotalEpisodes: 12}
+	if err := db.AddAnime(anime); err != nil {
+		t.Fatalf("Failed to add anime: %v", err)
+	}
+	for _, number := range []float64{1, 2, 3} {
+		if err := db.AddEpisode(&Episode{AnimeID: anime.ID, Number: number}); err != nil {
+			t.Fatalf("Failed to add episode %g: %v", number, err)
+		}
+	}
+
+	fresh := []Episode{
+		{AnimeID: anime.ID, Number: 1},
+		{AnimeID: anime.ID, Number: 2},
+		{AnimeID: anime.ID, Number: 3, Title: "Renamed"},
+		{AnimeID: anime.ID, Number: 4, Title: "New Episode"},
+		{AnimeID: anime.ID, Number: 4, Title: "Other Release"},
+		{AnimeID: anime.ID, Number: 5},
+	}
+
+	// Test that only the numbers not stored yet are returned, once each
+	added, err := db.DiffEpisodes(anime.ID, fresh)
+	if err != nil {
+		t.Fatalf("Failed to diff episodes: %v", err)
+	}
+	if len(added) != 2 || added[0].Number != 4 || added[1].Number != 5 {
+		t.Fatalf("Expected episodes 4 and 5, got %+v", added)
+	}
+	if added[0].Title != "New Episode" {
+		t.Errorf("Expected the first listing of episode 4, got %q", added[0].Title)
+	}
+
+	// Test that an unchanged list has nothing new
+	if added, err := db.DiffEpisodes(anime.ID, fresh[:3]); err != nil || len(added) != 0 {
+		t.Errorf("Expected no new episodes, got %+v (%v)", added, err)
+	}
+}
+
+func TestResetAnimeProgress(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	anime := &Anime{Title: "Rewatched Show", TotalEpisodes: 12}
+	if err := db.AddAnime(anime); err != nil {
+		t.Fatalf("Failed to add anime: %v", err)
+	}
+	other := &Anime{Title: "Other Show", TotalEpisodes: 12}
+	if err := db.AddAnime(other); err != nil {
+		t.Fatalf("Failed to add anime: %v", err)
+	}
+	for _, a := range []*Anime{anime, other} {
+		if _, err := db.MarkEpisodesWatchedThrough(a.ID, 12, time.Now()); err != nil {
+			t.Fatalf("Failed to mark episodes watched: %v", err)
+		}
+		tracking := &AnimeTracking{AnimeID: a.ID, Tracker: "anilist", TrackerID: fmt.Sprint(a.ID), Status: "completed", CurrentEpisode: 12}
+		if err := db.AddAnimeTracking(tracking); err != nil {
+			t.Fatalf("Failed to add tracking: %v", err)
+		}
+	}
+
+	if err := db.ResetAnimeProgress(anime.ID); err != nil {
+		t.Fatalf("Failed to reset progress: %v", err)
+	}
+
+	// Test that the progress rows of the anime are removed
+	if progress, err := db.GetEpisodeProgress(anime.ID, 1); err != nil || progress != nil {
+		t.Errorf("Expected no progress after a reset, got %+v (%v)", progress, err)
+	}
+
+	// Test that its tracking starts over as watching
+	tracking, err := db.GetAnimeTracking(anime.ID, "anilist")
+	if err != nil {
+		t.Fatalf("Failed to get tracking: %v", err)
+	}
+	if tracking.CurrentEpisode != 0 || tracking.Status != "watching" {
+		t.Errorf("Expected episode 0 and watching, got %g and %s", tracking.CurrentEpisode, tracking.Status)
+	}
+
+	// Test that other anime are left alone
+	if progress, err := db.GetEpisodeProgress(other.ID, 1); err != nil || progress == nil || !progress.Watched {
+		t.Errorf("Expected the other anime to keep its progress, got %+v (%v)", progress, err)
+	}
+	if tracking, err := db.GetAnimeTracking(other.ID, "anilist"); err != nil || tracking.CurrentEpisode != 12 {
+		t.Errorf("Expected the other anime to stay at episode 12, got %+v (%v)", tracking, err)
+	}
+}
+
+func TestRewatchTracking(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	anime := &Anime{Title: "Rewatched Show", TotalEpisodes: 12}
+	if err := db.AddAnime(anime); err != nil {
+		t.Fatalf("Failed to add anime: %v", err)
+	}
+	tracking := &AnimeTracking{AnimeID: anime.ID, Tracker: "local", Status: "watching", IsRewatching: true, LastUpdated: time.Now()}
+	if err := db.AddAnimeTracking(tracking); err != nil {
+		t.Fatalf("Failed to add tracking: %v", err)
+	}
+
+	// Test that the rewatch flag is stored
+	stored, err := db.GetAnimeTracking(anime.ID, "local")
+	if err != nil {
+		t.Fatalf("Failed to get tracking: %v", err)
+	}
+	if !stored.IsRewatching {
+		t.Errorf("Expected tracking to be a rewatch")
+	}
+
+	for _, number := range []float64{1, 2} {
+		progress := &EpisodeProgress{AnimeID: anime.ID, EpisodeNumber: number, Position: 600, Duration: 1440, PlaybackSpeed: 1, Watched: true, LastWatched: time.Now()}
+		if err := db.AddEpisodeProgress(progress); err != nil {
+			t.Fatalf("Failed to add episode progress: %v", err)
+		}
+	}
+
+	// Test that clearing positions keeps the episodes watched
+	if err := db.ClearEpisodePositions(anime.ID); err != nil {
+		t.Fatalf("Failed to clear episode positions: %v", err)
+	}
+	for _, number := range []float64{1, 2} {
+		progress, err := db.GetEpisodeProgress(anime.ID, number)
+		if err != nil {
+			t.Fatalf("Failed to get episode progress: %v", err)
+		}
+		if !progress.Watched || progress.Position != 0 {
+			t.Errorf("Expected episode %g watched without a position, got %+v", number, progress)
+		}
+	}
+}
+
+func TestGetAllAnimeWithTracking(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	for i, title := range []string{"Charlie", "Alpha", "Bravo"} {
+		anime := &Anime{Title: title, TotalEpisodes: 12, Genres: []string{"Action"}}
+		if err := db.AddAnime(anime); err != nil {
+			t.Fatalf("Failed to add anime: %v", err)
+		}
+		// Alpha is only tracked elsewhere, Bravo and Charlie on anilist
+		tracker := "anilist"
+		if title == "Alpha" {
+			tracker = "mal"
+		}
+		tracking := &AnimeTracking{
+			AnimeID:        anime.ID,
+			Tracker:        tracker,
+			TrackerID:      fmt.Sprint(100 + i),
+			Status:         "watching",
+			Score:          7.5,
+			CurrentEpisode: float64(i + 1),
+			TotalEpisodes:  12,
+			LastUpdated:    time.Now().Truncate(time.Second),
+			Tags:           []string{"tag"},
+			IsRewatching:   title == "Bravo",
+		}
+		if err := db.AddAnimeTracking(tracking); err != nil {
+			t.Fatalf("Failed to add tracking: %v", err)
+		}
+	}
+
+	results, err := db.GetAllAnimeWithTracking("anilist")
+	if err != nil {
+		t.Fatalf("Failed to get anime with tracking: %v", err)
+	}
+	allAnime, err := db.GetAllAnime()
+	if err != nil {
+		t.Fatalf("Failed to get all anime: %v", err)
+	}
+	if len(results) != len(allAnime) {
+		t.Fatalf("Expected %d results, got %d", len(allAnime), len(results))
+	}
+
+	// Test that the join matches looking up each anime's tracking
+	for i, anime := range allAnime {
+		result := results[i]
+		if fmt.Sprintf("%+v", *result.Anime) != fmt.Sprintf("%+v", *anime) {
+			t.Errorf("Expected anime %+v, got %+v", *anime, *result.Anime)
+		}
+
+		tracking, err := db.GetAnimeTracking(anime.ID, "anilist")
+		if errors.Is(err, sql.ErrNoRows) {
+			if result.Tracking != nil {
+				t.Errorf("Expected no tracking for %s, got %+v", anime.Title, *result.Tracking)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Failed to get tracking: %v", err)
+		}
+		if result.Tracking == nil {
+			t.Errorf("Expected tracking for %s", anime.Title)
+		} else if fmt.Sprintf("%+v", *result.Tracking) != fmt.Sprintf("%+v", *tracking) {
+			t.Errorf("Expected tracking %+v, got %+v", *tracking, *result.Tracking)
+		}
+	}
+}
+
+func TestSearchHistory(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	// Test that there's no history at first
+	history, err := db.GetSearchHistory(MaxSearchHistory)
+	if err != nil {
+		t.Fatalf("Failed to get search history: %v", err)
+	}
+	if len(history) != 0 {
+		t.Errorf("Expected empty history, got %v", history)
+	}
+
+	for _, query := range []string{"frieren", "dandadan", "  ", "Frieren"} {
+		if err := db.AddSearchHistory(query); err != nil {
+			t.Fatalf("Failed to add search history: %v", err)
+		}
+	}
+
+	// Test that the most recent query comes first and repeats are merged
+	history, err = db.GetSearchHistory(MaxSearchHistory)
+	if err != nil {
+		t.Fatalf("Failed to get search history: %v", err)
+	}
+	if strings.Join(history, ",") != "Frieren,dandadan" {
+		t.Errorf("Expected Frieren,dandadan, got %v", history)
+	}
+
+	// Test that the history is capped
+	for i := 0; i < MaxSearchHistory+5; i++ {
+		if err := db.AddSearchHistory(fmt.Sprintf("query %d", i)); err != nil {
+			t.Fatalf("Failed to add search history: %v", err)
+		}
+	}
+	history, err = db.GetSearchHistory(MaxSearchHistory + 5)
+	if err != nil {
+		t.Fatalf("Failed to get search history: %v", err)
+	}
+	if len(history) != MaxSearchHistory {
+		t.Errorf("Expected %d queries, got %d", MaxSearchHistory, len(history))
+	}
+	if history[0] != fmt.Sprintf("query %d", MaxSearchHistory+4) {
+		t.Errorf("Expected the latest query first, got %s", history[0])
+	}
+
+	// Test that the limit is applied
+	history, err = db.GetSearchHistory(3)
+	if err != nil {
+		t.Fatalf("Failed to get search history: %v", err)
+	}
+	if len(history) != 3 {
+		t.Errorf("Expected 3 queries, got %d", len(history))
+	}
+}
+
+func TestCorruptSearchHistory(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if err := db.SetConfig(searchHistoryKey, "{not json"); err != nil {
+		t.Fatalf("Failed to set config: %v", err)
+	}
+
+	// Test that a corrupt history is reported as such
+	if _, err := db.GetSearchHistory(MaxSearchHistory); !errors.Is(err, ErrCorruptSearchHistory) {
+		t.Errorf("Expected ErrCorruptSearchHistory, got %v", err)
+	}
+
+	// Test that adding a query starts a fresh history
+	if err := db.AddSearchHistory("frieren"); err != nil {
+		t.Fatalf("Failed to add search history: %v", err)
+	}
+	history, err := db.GetSearchHistory(MaxSearchHistory)
+	if err != nil {
+		t.Fatalf("Failed to get search history: %v", err)
+	}
+	if strings.Join(history, ",") != "frieren" {
+		t.Errorf("Expected frieren, got %v", history)
+	}
+
+	// Test clearing the history
+	if err := db.ClearSearchHistory(); err != nil {
+		t.Fatalf("Failed to clear search history: %v", err)
+	}
+	if history, err := db.GetSearchHistory(MaxSearchHistory); err != nil || len(history) != 0 {
+		t.Errorf("Expected empty history, got %v (%v)", history, err)
+	}
+}
+
+func TestSyncConflictLog(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	anime := &Anime{Title: "Conflicted"}
+	if err := db.AddAnime(anime); err != nil {
+		t.Fatalf("Failed to add anime: %v", err)
+	}
+
+	old := &SyncConflict{
+		AnimeID: anime.ID, Tracker: "mal", Field: "status",
+		LocalValue: "watching", RemoteValue: "dropped", Winner: ConflictRemote,
+		CreatedAt: time.Now().Add(-40 * 24 * time.Hour),
+	}
+	recent := &SyncConflict{
+		AnimeID: anime.ID, Tracker: "anilist", Field: "score",
+		LocalValue: "8", RemoteValue: "6", Winner: ConflictLocal,
+	}
+	for _, c := range []*SyncConflict{old, recent} {
+		if err := db.AddSyncConflict(c); err != nil {
+			t.Fatalf("Failed to add sync conflict: %v", err)
+		}
+	}
+
+	// Test that conflicts are listed newest first with the anime title
+	conflicts, err := db.GetSyncConflicts(10)
+	if err != nil {
+		t.Fatalf("Failed to get sync conflicts: %v", err)
+	}
+	if len(conflicts) != 2 {
+		t.Fatalf("Expected 2 conflicts, got %d", len(conflicts))
+	}
+	if conflicts[0].ID != recent.ID || conflicts[0].AnimeTitle != "Conflicted" {
+		t.Errorf("Expected the recent conflict first, got %+v", conflicts[0])
+	}
+
+	// Test that pruning only removes conflicts older than the cutoff
+	pruned, err := db.PruneSyncConflicts(time.Now().Add(-30 * 24 * time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to prune sync conflicts: %v", err)
+	}
+	if pruned != 1 {
+		t.Errorf("Expected 1 pruned conflict, got %d", pruned)
+	}
+	conflicts, err = db.GetSyncConflicts(10)
+	if err != nil {
+		t.Fatalf("Failed to get sync conflicts: %v", err)
+	}
+	if len(conflicts) != 1 || conflicts[0].ID != recent.ID {
+		t.Errorf("Expected only the recent conflict to remain, got %d", len(conflicts))
+	}
+
+	// Test that clearing empties the log
+	if err := db.ClearSyncConflicts(); err != nil {
+		t.Fatalf("Failed to clear sync conflicts: %v", err)
+	}
+	if conflicts, _ := db.GetSyncConflicts(10); len(conflicts) != 0 {
+		t.Errorf("Expected no conflicts after clearing, got %d", len(conflicts))
+	}
+}
+
+func TestAnimeSourceEpisodeOffset(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	anime := &Anime{Title: "Season 2"}
+	if err := db.AddAnime(anime); err != nil {
+		t.Fatalf("Failed to add anime: %v", err)
+	}
+	ext := &Extension{Name: "Ext", Package: "ext", Language: "en", Version: "1.0", Path: "/bin/true"}
+	if err := db.AddExtension(ext); err != nil {
+		t.Fatalf("Failed to add extension: %v", err)
+	}
+	source := &Source{SourceID: "src", ExtensionID: ext.ID, Name: "Source", Language: "en", BaseURL: "https://example.com"}
+	if err := db.AddSource(source); err != nil {
+		t.Fatalf("Failed to add source: %v", err)
+	}
+	if err := db.AddAnimeSource(&AnimeSource{AnimeID: anime.ID, SourceID: source.ID, SourceAnimeID: "show", EpisodeOffset: 12}); err != nil {
+		t.Fatalf("Failed to add anime source: %v", err)
+	}
+
+	// Test that the offset is stored with the mapping
+	mappings, err := db.GetAnimeSources(anime.ID)
+	if err != nil {
+		t.Fatalf("Failed to get anime sources: %v", err)
+	}
+	if len(mappings) != 1 || mappings[0].EpisodeOffset != 12 {
+		t.Fatalf("Expected a mapping with offset 12, got %+v", mappings)
+	}
+
+	// Test that episodes convert in both directions
+	mapping := mappings[0]
+	if got := mapping.SourceEpisode(1); got != 13 {
+		t.Errorf("Expected episode 1 to be source episode 13, got %v", got)
+	}
+	if got := mapping.AnimeEpisode(24); got != 12 {
+		t.Errorf("Expected source episode 24 to be episode 12, got %v", got)
+	}
+
+	// Test changing the offset, and that unmapped sources are reported
+	if err := db.SetAnimeSourceOffset(anime.ID, source.ID, 0); err != nil {
+		t.Fatalf("Failed to set episode offset: %v", err)
+	}
+	mappings, err = db.GetAnimeSources(anime.ID)
+	if err != nil {
+		t.Fatalf("Failed to get anime sources: %v", err)
+	}
+	if mappings[0].EpisodeOffset != 0 || mappings[0].SourceEpisode(5) != 5 {
+		t.Errorf("Expected no offset after clearing it, got %v", mappings[0].EpisodeOffset)
+	}
+	if err := db.SetAnimeSourceOffset(anime.ID+1, source.ID, 3); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows for an unmapped anime, got %v", err)
+	}
+}
+
+func TestGetLastSync(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	// Test that a tracker that never synced has no last sync time
+	last, err := db.GetLastSync("anilist")
+	if err != nil {
+		t.Fatalf("Failed to get last sync: %v", err)
+	}
+	if !last.IsZero() {
+		t.Errorf("Expected no last sync, got %v", last)
+	}
+
+	// Test that the stored RFC 3339 time is parsed
+	if err := db.SetConfig(LastSyncKey("anilist"), "2024-05-01T09:00:00Z"); err != nil {
+		t.Fatalf("Failed to set config: %v", err)
+	}
+	last, err = db.GetLastSync("anilist")
+	if err != nil {
+		t.Fatalf("Failed to get last sync: %v", err)
+	}
+	if expected := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC); !last.Equal(expected) {
+		t.Errorf("Expected %v, got %v", expected, last)
+	}
+
+	// Test that a malformed value is reported
+	if err := db.SetConfig(LastSyncKey("mal"), "yesterday"); err != nil {
+		t.Fatalf("Failed to set config: %v", err)
+	}
+	if _, err := db.GetLastSync("mal"); err == nil {
+		t.Errorf("Expected an error for a malformed last sync time")
+	}
+}
+
+// addAnimeWithChildren adds an anime with tracking, progress, an episode and
+// a source mapping
+func addAnimeWithChildren(t *testing.T, db *DB, title string) *Anime {
+	anime := &Anime{Title: title, TotalEpisodes: 12}
+	if err := db.AddAnime(anime); err != nil {
+		t.Fatalf("Failed to add anime: %v", err)
+	}
+	if err := db.AddAnimeTracking(&AnimeTracking{AnimeID: anime.ID, Tracker: "local", TrackerID: "1", Status: "watching", CurrentEpisode: 3}); err != nil {
+		t.Fatalf("Failed to add tracking: %v", err)
+	}
+	if err := db.AddEpisode(&Episode{AnimeID: anime.ID, Number: 1}); err != nil {
+		t.Fatalf("Failed to add episode: %v", err)
+	}
+	if err := db.AddEpisodeProgress(&EpisodeProgress{AnimeID: anime.ID, EpisodeNumber: 1, Position: 60, Duration: 1440, PlaybackSpeed: 1, LastWatched: time.Now()}); err != nil {
+		t.Fatalf("Failed to add episode progress: %v", err)
+	}
+	ext := &Extension{Name: "Test Extension", Package: "extension", Language: "en", Version: "1.0.0"}
+	if err := db.AddExtension(ext); err != nil {
+		t.Fatalf("Failed to add extension: %v", err)
+	}
+	source := &Source{SourceID: "source", ExtensionID: ext.ID, Name: "Test Source", Language: "en"}
+	if err := db.AddSource(source); err != nil {
+		t.Fatalf("Failed to add source: %v", err)
+	}
+	if err := db.AddAnimeSource(&AnimeSource{AnimeID: anime.ID, SourceID: source.ID, SourceAnimeID: "remote"}); err != nil {
+		t.Fatalf("Failed to add anime source: %v", err)
+	}
+	return anime
+}
+
+// checkChildRows fails the test when any of the rows added by
+// addAnimeWithChildren is gone
+func checkChildRows(t *testing.T, db *DB, animeID int64) {
+	t.Helper()
+	for _, table := range []string{"anime_tracking", "episode_progress", "episode", "anime_source"} {
+		var count int
+		if err := db.conn.QueryRow("SELECT COUNT(*) FROM "+table+" WHERE anime_id = ?", animeID).Scan(&count); err != nil {
+			t.Fatalf("Failed to count %s rows: %v", table, err)
+		}
+		if count != 1 {
+			t.Errorf("Expected 1 %s row to be kept, got %d", table, count)
+		}
+	}
+	if tracking, err := db.GetAnimeTracking(animeID, "local"); err != nil || tracking.CurrentEpisode != 3 {
+		t.Errorf("Expected progress to stay at 3, got %+v (%v)", tracking, err)
+	}
+}
+
+func TestImportMergeKeepsChildRows(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	anime := addAnimeWithChildren(t, db, "Kept Show")
+
+	// Test that importing the anime row over an existing one updates it in place
+	now := time.Now()
+	backup := writeBackup(t, BackupData{
+		Version: currentBackupVersion,
+		Anime:   []Anime{{ID: anime.ID, Title: "Renamed Show", TotalEpisodes: 24, CreatedAt: now, UpdatedAt: now}},
+	})
+	if _, err := db.ImportFromJSON(backup, ImportOptions{Mode: ImportMerge}); err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+
+	got, err := db.GetAnime(anime.ID)
+	if err != nil {
+		t.Fatalf("Failed to get anime: %v", err)
+	}
+	if got.Title != "Renamed Show" || got.TotalEpisodes != 24 {
+		t.Errorf("Expected the anime to be updated, got %s with %d episodes", got.Title, got.TotalEpisodes)
+	}
+	checkChildRows(t, db, anime.ID)
+}
+
+func TestImportDeltaKeepsChildRows(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	anime := addAnimeWithChildren(t, db, "Kept Show")
+	old := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := db.conn.Exec("UPDATE anime SET updated_at = ?", old); err != nil {
+		t.Fatalf("Failed to date anime: %v", err)
+	}
+
+	// Test that a delta holding only the anime row leaves its children alone
+	since := old.AddDate(1, 0, 0)
+	now := time.Now()
+	delta := writeBackup(t, BackupData{
+		Version:      currentBackupVersion,
+		ChangedSince: &since,
+		Anime:        []Anime{{ID: anime.ID, Title: "Renamed Show", TotalEpisodes: 24, CreatedAt: old, UpdatedAt: now}},
+	})
+	stats, err := db.ImportDelta(delta)
+	if err != nil {
+		t.Fatalf("Failed to import delta: %v", err)
+	}
+	if stats.Imported.Anime != 1 {
+		t.Errorf("Expected 1 anime imported, got %+v", stats.Imported)
+	}
+
+	if got, err := db.GetAnime(anime.ID); err != nil || got.Title != "Renamed Show" {
+		t.Errorf("Expected the anime to be renamed, got %+v (%v)", got, err)
+	}
+	checkChildRows(t, db, anime.ID)
+}
+
+func TestExportKeepsMalID(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	anime := &Anime{Title: "Anilist Show", TotalEpisodes: 12}
+	if err := db.AddAnime(anime); err != nil {
+		t.Fatalf("Failed to add anime: %v", err)
+	}
+	if err := db.SetAnimeMalID(anime.ID, "90"); err != nil {
+		t.Fatalf("Failed to set MAL ID: %v", err)
+	}
+
+	// Test that the MAL ID round-trips through export and import
+	backup := filepath.Join(t.TempDir(), "backup.json")
+	if _, err := db.ExportToJSON(backup); err != nil {
+		t.Fatalf("Failed to export: %v", err)
+	}
+	restored, restoreCleanup := setupTestDB(t)
+	defer restoreCleanup()
+	if _, err := restored.ImportFromJSON(backup, ImportOptions{Mode: ImportReplace}); err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+	got, err := restored.GetAnimeByMalID("90")
+	if err != nil {
+		t.Fatalf("Failed to get anime by MAL ID: %v", err)
+	}
+	if got.ID != anime.ID || got.MalID != "90" {
+		t.Errorf("Expected anime %d with MAL ID 90, got %d with '%s'", anime.ID, got.ID, got.MalID)
+	}
+
+	// Test that merging a backup without the ID keeps the known one
+	now := time.Now()
+	merge := writeBackup(t, BackupData{
+		Version: currentBackupVersion,
+		Anime:   []Anime{{ID: anime.ID, Title: "Anilist Show", TotalEpisodes: 12, CreatedAt: now, UpdatedAt: now}},
+	})
+	if _, err := restored.ImportFromJSON(merge, ImportOptions{Mode: ImportMerge}); err != nil {
+		t.Fatalf("Failed to import: %v", err)
+	}
+	if malID, err := restored.GetAnimeMalID(anime.ID); err != nil || malID != "90" {
+		t.Errorf("Expected MAL ID 90 to be kept, got '%s' (%v)", malID, err)
+	}
+}
+
+func TestGetAllListTags(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	tagged := &Anime{Title: "Tagged Show"}
+	untagged := &Anime{Title: "Untagged Show"}
+	for _, anime := range []*Anime{tagged, untagged} {
+		if err := db.AddAnime(anime); err != nil {
+			t.Fatalf("Failed to add anime: %v", err)
+		}
+	}
+	trackings := []*AnimeTracking{
+		{AnimeID: tagged.ID, Tracker: "anilist", TrackerID: "1", Status: "watching", Tags: []string{}},
+		{AnimeID: tagged.ID, Tracker: "mal", TrackerID: "2", Status: "watching", Tags: []string{"cozy", "rewatch"}},
+		{AnimeID: untagged.ID, Tracker: "anilist", TrackerID: "3", Status: "watching"},
+	}
+	for _, tracking := range trackings {
+		if err := db.AddAnimeTracking(tracking); err != nil {
+			t.Fatalf("Failed to add tracking: %v", err)
+		}
+	}
+
+	// Test that tags come from whichever tracker keeps them, and untagged anime are left out
+	tags, err := db.GetAllListTags()
+	if err != nil {
+		t.Fatalf("Failed to get list tags: %v", err)
+	}
+	if len(tags) != 1 || strings.Join(tags[tagged.ID], ",") != "cozy,rewatch" {
+		t.Errorf("Expected only the tags cozy and rewatch, got %v", tags)
+	}
+}
@@ -1,6 +1,10 @@
 package database
 
 import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
 	"time"
 )
 
@@ -8,7 +12,7 @@ import (
 func (db *DB) GetAllAnimeSources(sourceID int64) ([]*AnimeSource, error) {
 	rows, err := db.conn.Query(
 		`SELECT 
-			id, anime_id, source_id, source_anime_id
+			id, anime_id, source_id, source_anime_id, episode_offset
 		FROM anime_source WHERE source_id = ?`,
 		sourceID,
 	)
@@ -21,7 +25,7 @@ func (db *DB) GetAllAnimeSources(sourceID int64) ([]*AnimeSource, error) {
 	for rows.Next() {
 		var source AnimeSource
 		err := rows.Scan(
-			&source.ID, &source.AnimeID, &source.SourceID, &source.SourceAnimeID,
+			&source.ID, &source.AnimeID, &source.SourceID, &source.SourceAnimeID, &source.EpisodeOffset,
 		)
 		if err != nil {
 			return nil, err
@@ -33,16 +37,18 @@ func (db *DB) GetAllAnimeSources(sourceID int64) ([]*AnimeSource, error) {
 	return sources, rows.Err()
 }
 
-// AddEpisode adds a new episode to the database
+// AddEpisode adds a new episode to the database. Adding an episode that is
+// already stored updates it, but an empty title or thumbnail doesn't replace
+// a stored one.
 func (db *DB) AddEpisode(episode *Episode) error {
 	result, err := db.conn.Exec(
 		`INSERT INTO episode (
 			anime_id, number, title, thumbnail_url
 		) VALUES (?, ?, ?, ?)
 		ON CONFLICT(anime_id, number) DO UPDATE SET
-			title = ?, thumbnail_url = ?`,
+			title = COALESCE(NULLIF(excluded.title, ''), title),
+			thumbnail_url = COALESCE(NULLIF(excluded.thumbnail_url, ''), thumbnail_url)`,
 		episode.AnimeID, episode.Number, episode.Title, episode.ThumbnailURL,
-		episode.Title, episode.ThumbnailURL,
 	)
 	if err != nil {
 		return err
@@ -60,6 +66,38 @@ func (db *DB) AddEpisode(episode *Episode) error {
 	return nil
 }
 
+// DiffEpisodes compares a freshly fetched episode list of an anime against
+// the stored one and returns the episodes whose numbers aren't stored yet,
+// each number once and in the order of fresh
+func (db *DB) DiffEpisodes(animeID int64, fresh []Episode) (added []Episode, err error) {
+	rows, err := db.conn.Query("SELECT number FROM episode WHERE anime_id = ?", animeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	known := make(map[float64]bool)
+	for rows.Next() {
+		var number float64
+		if err := rows.Scan(&number); err != nil {
+			return nil, err
+		}
+		known[number] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, episode := range fresh {
+		if known[episode.Number] {
+			continue
+		}
+		known[episode.Number] = true
+		added = append(added, episode)
+	}
+	return added, nil
+}
+
 // AddEpisodeProgress adds or updates episode progress
 func (db *DB) AddEpisodeProgress(progress *EpisodeProgress) error {
 	result, err := db.conn.Exec(
@@ -91,8 +129,9 @@ func (db *DB) AddEpisodeProgress(progress *EpisodeProgress) error {
 	return nil
 }
 
-// UpdateAnimeTracking updates the current episode for anime tracking
-func (db *DB) UpdateAnimeTracking(animeID int64, tracker string, currentEpisode float64) error {
+// AdvanceAnimeProgress moves the current episode of an anime's tracking entry forward.
+// It leaves the entry alone if it is already at or past currentEpisode.
+func (db *DB) AdvanceAnimeProgress(animeID int64, tracker string, currentEpisode float64) error {
 	_, err := db.conn.Exec(
 		`UPDATE anime_tracking 
 		SET current_episode = ?, last_updated = CURRENT_TIMESTAMP
@@ -102,16 +141,274 @@ func (db *DB) UpdateAnimeTracking(animeID int64, tracker string, currentEpisode
 	return err
 }
 
+// SetAnimeProgress sets the current episode of an anime's tracking entry, also
+// allowing it to go back. It returns sql.ErrNoRows if there is no such entry.
+func (db *DB) SetAnimeProgress(animeID int64, tracker string, currentEpisode float64) error {
+	result, err := db.conn.Exec(
+		`UPDATE anime_tracking 
+		SET current_episode = ?, last_updated = CURRENT_TIMESTAMP
+		WHERE anime_id = ? AND tracker = ?`,
+		currentEpisode, animeID, tracker,
+	)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
 // UpdateAnimeTrackingObject updates a full anime tracking object
 func (db *DB) UpdateAnimeTrackingObject(tracking *AnimeTracking) error {
-	_, err := db.conn.Exec(
+	tags := tracking.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	tagsJSON, err := json.Marshal(tags)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.conn.Exec(
 		`UPDATE anime_tracking
 		SET tracker_id = ?, status = ?, score = ?, 
-		    current_episode = ?, total_episodes = ?, last_updated = ?
+		    current_episode = ?, total_episodes = ?, notes = ?,
+		    priority = ?, tags = ?, is_rewatching = ?, last_updated = ?
 		WHERE id = ?`,
 		tracking.TrackerID, tracking.Status, tracking.Score,
-		tracking.CurrentEpisode, tracking.TotalEpisodes, time.Now(),
+		tracking.CurrentEpisode, tracking.TotalEpisodes, tracking.Notes,
+		tracking.Priority, string(tagsJSON), tracking.IsRewatching, time.Now(),
 		tracking.ID,
 	)
 	return err
 }
+
+// UpdateAnimeTrackingNotes sets the notes of an anime's tracking entry
+func (db *DB) UpdateAnimeTrackingNotes(animeID int64, tracker string, notes string) error {
+	result, err := db.conn.Exec(
+		`UPDATE anime_tracking SET notes = ? WHERE anime_id = ? AND tracker = ?`,
+		notes, animeID, tracker,
+	)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// UpdateEpisodeFiller sets the filler flag of an episode
+func (db *DB) UpdateEpisodeFiller(animeID int64, number float64, isFiller bool) error {
+	_, err := db.conn.Exec(
+		`UPDATE episode SET is_filler = ? WHERE anime_id = ? AND number = ?`,
+		isFiller, animeID, number,
+	)
+	return err
+}
+
+// GetFillerEpisodes returns the filler flags of all episodes of an anime
+func (db *DB) GetFillerEpisodes(animeID int64) (map[float64]bool, error) {
+	rows, err := db.conn.Query(
+		`SELECT number, is_filler FROM episode WHERE anime_id = ?`,
+		animeID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	fillers := make(map[float64]bool)
+	for rows.Next() {
+		var number float64
+		var isFiller bool
+		if err := rows.Scan(&number, &isFiller); err != nil {
+			return nil, err
+		}
+		fillers[number] = isFiller
+	}
+
+	return fillers, rows.Err()
+}
+
+// MarkEpisodeWatched records an episode as watched from a source, keeping
+// any stored playback position
+func (db *DB) MarkEpisodeWatched(animeID int64, number float64, sourceID string) error {
+	now := time.Now()
+	_, err := db.conn.Exec(
+		`INSERT INTO episode_progress (
+			anime_id, episode_number, position, duration,
+			playback_speed, watched, source_id, last_watched
+		) VALUES (?, ?, 0, 0, 1.0, 1, ?, ?)
+		ON CONFLICT(anime_id, episode_number) DO UPDATE SET
+			watched = 1, source_id = ?, last_watched = ?`,
+		animeID, number, sourceID, now,
+		sourceID, now,
+	)
+	return err
+}
+
+// ClearEpisodePositions forgets the resume position of every episode of an
+// anime, keeping whether they were watched
+func (db *DB) ClearEpisodePositions(animeID int64) error {
+	_, err := db.conn.Exec(
+		"UPDATE episode_progress SET position = 0 WHERE anime_id = ?",
+		animeID,
+	)
+	return err
+}
+
+// ResetAnimeProgress forgets everything watched of an anime for a rewatch:
+// its episode progress is deleted and every tracking entry goes back to
+// episode 0, with completed entries set to watching again
+func (db *DB) ResetAnimeProgress(animeID int64) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM episode_progress WHERE anime_id = ?", animeID); err != nil {
+		return fmt.Errorf("failed to delete episode progress: %w", err)
+	}
+	if _, err := tx.Exec(
+		`UPDATE anime_tracking
+		SET current_episode = 0,
+		    status = CASE WHEN status = 'completed' THEN 'watching' ELSE status END,
+		    last_updated = ?
+		WHERE anime_id = ?`,
+		time.Now(), animeID,
+	); err != nil {
+		return fmt.Errorf("failed to reset tracking: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// MarkEpisodesWatchedThrough marks episodes 1 to through as watched, for
+// progress made outside pair. Episodes without a row get one without a
+// resume position, dated watchedAt; existing rows keep their position. It
+// returns how many episodes weren't marked watched before.
+func (db *DB) MarkEpisodesWatchedThrough(animeID int64, through float64, watchedAt time.Time) (int, error) {
+	var numbers []float64
+	for number := 1; float64(number) <= through; number++ {
+		numbers = append(numbers, float64(number))
+	}
+	return db.MarkEpisodesWatched(animeID, numbers, watchedAt)
+}
+
+// MarkEpisodesWatched marks the given episodes as watched like
+// MarkEpisodesWatchedThrough does, for episodes that needn't be consecutive
+func (db *DB) MarkEpisodesWatched(animeID int64, numbers []float64, watchedAt time.Time) (int, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(
+		`INSERT INTO episode_progress (
+			anime_id, episode_number, position, duration,
+			playback_speed, watched, source_id, last_watched
+		) VALUES (?, ?, 0, 0, 1.0, 1, '', ?)
+		ON CONFLICT(anime_id, episode_number) DO UPDATE SET watched = 1
+		WHERE NOT watched`,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	marked := 0
+	for _, number := range numbers {
+		result, err := stmt.Exec(animeID, number, watchedAt)
+		if err != nil {
+			return 0, fmt.Errorf("failed to mark episode %g watched: %w", number, err)
+		}
+		if n, err := result.RowsAffected(); err == nil {
+			marked += int(n)
+		}
+	}
+
+	return marked, tx.Commit()
+}
+
+// ReconcileWatchedEpisodes marks the episodes of every anime tracked with
+// tracker as watched up to the tracker's progress, so the episode list agrees
+// with it. It returns how many episodes were newly marked.
+func (db *DB) ReconcileWatchedEpisodes(tracker string) (int, error) {
+	trackings, err := db.GetAllAnimeTrackingByTracker(tracker)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get tracking: %w", err)
+	}
+
+	marked := 0
+	for _, tracking := range trackings {
+		n, err := db.MarkEpisodesWatchedThrough(tracking.AnimeID, tracking.CurrentEpisode, tracking.LastUpdated)
+		if err != nil {
+			return marked, fmt.Errorf("failed to reconcile anime %d: %w", tracking.AnimeID, err)
+		}
+		marked += n
+	}
+	return marked, nil
+}
+
+// GetNextUnwatchedEpisode returns the first episode after the given one that
+// hasn't been watched. The fetched episode list is used when there is one;
+// otherwise episodes are counted up to totalEpisodes, or without limit when
+// the count is 0. ok is false when no episode is left.
+func (db *DB) GetNextUnwatchedEpisode(animeID int64, after float64, totalEpisodes int) (next float64, ok bool, err error) {
+	rows, err := db.conn.Query(
+		`SELECT episode_number FROM episode_progress WHERE anime_id = ? AND watched`,
+		animeID,
+	)
+	if err != nil {
+		return 0, false, err
+	}
+	defer rows.Close()
+
+	watched := make(map[float64]bool)
+	for rows.Next() {
+		var number float64
+		if err := rows.Scan(&number); err != nil {
+			return 0, false, err
+		}
+		watched[number] = true
+	}
+	if err := rows.Err(); err != nil {
+		return 0, false, err
+	}
+
+	episodes, err := db.GetAllEpisodes(animeID)
+	if err != nil {
+		return 0, false, err
+	}
+	if len(episodes) > 0 {
+		for _, episode := range episodes {
+			if episode.Number > after && !watched[episode.Number] {
+				return episode.Number, true, nil
+			}
+		}
+		return 0, false, nil
+	}
+
+	for next = math.Floor(after) + 1; totalEpisodes == 0 || next <= float64(totalEpisodes); next++ {
+		if !watched[next] {
+			return next, true, nil
+		}
+	}
+	return 0, false, nil
+}
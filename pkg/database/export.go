@@ -1,17 +1,100 @@
 package database
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 )
 
+// currentBackupVersion is the backup format written by ExportToJSON.
+// Version 2 added tracking notes, version 3 tracking priority and tags,
+// version 4 personal anime notes, version 5 the tracking rewatch flag,
+// version 6 source episode offsets, version 7 MyAnimeList IDs learnt from
+// Anilist.
+const currentBackupVersion = 7
+
+// ErrBackupTooNew is returned when importing a backup written by a newer version of pair
+var ErrBackupTooNew = errors.New("backup created by a newer pair version")
+
+// ErrDeltaBackup is returned when a delta export is used where a full backup
+// is needed, or the other way round
+var ErrDeltaBackup = errors.New("delta backups only hold changes and can only be merged")
+
+// backupUpgrades convert a backup from the version it is keyed by to the next one
+var backupUpgrades = map[int]func(data *BackupData){
+	// Version 1 had no tracking notes, they import as empty
+	1: func(data *BackupData) {
+		for i := range data.AnimeTracking {
+			data.AnimeTracking[i].Notes = ""
+		}
+	},
+	// Version 2 had no tracking priority or tags
+	2: func(data *BackupData) {
+		for i := range data.AnimeTracking {
+			data.AnimeTracking[i].Priority = 0
+			data.AnimeTracking[i].Tags = []string{}
+		}
+	},
+	// Version 3 had no personal anime notes
+	3: func(data *BackupData) {
+		for i := range data.Anime {
+			data.Anime[i].Notes = ""
+		}
+	},
+	// Version 4 had no rewatch flag
+	4: func(data *BackupData) {
+		for i := range data.AnimeTracking {
+			data.AnimeTracking[i].IsRewatching = false
+		}
+	},
+	// Version 5 had no episode offsets, sources numbered episodes like trackers
+	5: func(data *BackupData) {
+		for i := range data.AnimeSources {
+			data.AnimeSources[i].EpisodeOffset = 0
+		}
+	},
+	// Version 6 had no MAL IDs, the next Anilist sync learns them again
+	6: func(data *BackupData) {
+		for i := range data.Anime {
+			data.Anime[i].MalID = ""
+		}
+	},
+}
+
+// upgradeBackup brings a decoded backup up to currentBackupVersion
+func upgradeBackup(data *BackupData) error {
+	// Backups from before versioning was checked may omit the field
+	if data.Version == 0 {
+		data.Version = 1
+	}
+	if data.Version > currentBackupVersion {
+		return fmt.Errorf("%w: backup version %d, supported up to %d", ErrBackupTooNew, data.Version, currentBackupVersion)
+	}
+
+	for data.Version < currentBackupVersion {
+		upgrade, ok := backupUpgrades[data.Version]
+		if !ok {
+			return fmt.Errorf("no upgrade path from backup version %d", data.Version)
+		}
+		upgrade(data)
+		data.Version++
+	}
+
+	return nil
+}
+
 // BackupData represents the structure of a database backup
 type BackupData struct {
-	Version         int               `json:"version"`
-	CreatedAt       time.Time         `json:"created_at"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	// ChangedSince is the cutoff of a delta export, which only holds the
+	// anime, tracking entries and progress changed after it
+	ChangedSince    *time.Time        `json:"changed_since,omitempty"`
 	Config          []ConfigEntry     `json:"config"`
 	Anime           []Anime           `json:"anime"`
 	AnimeTracking   []AnimeTracking   `json:"anime_tracking"`
@@ -22,30 +105,115 @@ type BackupData struct {
 	AnimeSources    []AnimeSource     `json:"anime_sources"`
 }
 
+// ImportMode controls how an import treats data already in the database
+type ImportMode int
+
+const (
+	// ImportMerge keeps existing rows and overwrites those present in the backup
+	ImportMerge ImportMode = iota
+	// ImportReplace clears the library before restoring the backup
+	ImportReplace
+)
+
+// replaceTables are cleared by ImportReplace, children before parents
+var replaceTables = []string{
+	"anime_source", "episode_progress", "episode", "anime_tracking",
+	"anime", "source", "extension", "config",
+}
+
+// BackupSummary counts the rows written to or read from a backup
+type BackupSummary struct {
+	Anime    int `json:"anime"`
+	Tracking int `json:"tracking"`
+	Progress int `json:"progress"`
+	Episodes int `json:"episodes"`
+	Sources  int `json:"sources"`
+}
+
+// Summary counts the entries held by the backup
+func (d *BackupData) Summary() BackupSummary {
+	return BackupSummary{
+		Anime:    len(d.Anime),
+		Tracking: len(d.AnimeTracking),
+		Progress: len(d.EpisodeProgress),
+		Episodes: len(d.Episodes),
+		Sources:  len(d.Sources),
+	}
+}
+
 // ExportToJSON exports the database to a JSON file
-func (db *DB) ExportToJSON(filePath string) error {
+func (db *DB) ExportToJSON(filePath string) (BackupSummary, error) {
+	data, err := db.collectBackup()
+	if err != nil {
+		return BackupSummary{}, err
+	}
+	if err := saveBackup(data, filePath); err != nil {
+		return BackupSummary{}, err
+	}
+	return data.Summary(), nil
+}
+
+// ExportChangedSince exports only the anime, tracking entries and episode
+// progress changed after since, for incremental backups. The cutoff is kept
+// in the backup so it can be told apart from a full one and applied with
+// ImportDelta.
+func (db *DB) ExportChangedSince(since time.Time, filePath string) (BackupSummary, error) {
+	full, err := db.collectBackup()
+	if err != nil {
+		return BackupSummary{}, err
+	}
+
+	data := BackupData{
+		Version:      full.Version,
+		CreatedAt:    full.CreatedAt,
+		ChangedSince: &since,
+	}
+	for _, anime := range full.Anime {
+		if anime.UpdatedAt.After(since) {
+			data.Anime = append(data.Anime, anime)
+		}
+	}
+	for _, tracking := range full.AnimeTracking {
+		if tracking.LastUpdated.After(since) {
+			data.AnimeTracking = append(data.AnimeTracking, tracking)
+		}
+	}
+	for _, progress := range full.EpisodeProgress {
+		if progress.LastWatched.After(since) {
+			data.EpisodeProgress = append(data.EpisodeProgress, progress)
+		}
+	}
+
+	if err := saveBackup(data, filePath); err != nil {
+		return BackupSummary{}, err
+	}
+	return data.Summary(), nil
+}
+
+// collectBackup reads the whole database into a backup
+func (db *DB) collectBackup() (BackupData, error) {
 	var err error
 	data := BackupData{
-		Version:   1,
+		Version:   currentBackupVersion,
 		CreatedAt: time.Now(),
 	}
 
 	// Get all config entries
 	data.Config, err = db.GetAllConfig()
 	if err != nil {
-		return fmt.Errorf("failed to export config: %w", err)
+		return BackupData{}, fmt.Errorf("failed to export config: %w", err)
 	}
 
 	// Get all anime
 	rows, err := db.conn.Query(`
 		SELECT 
 			id, title, original_title, alternative_titles, description, 
-			total_episodes, type, year, season, status, genres, thumbnail_url,
+			total_episodes, type, year, season, status, genres, thumbnail_url, notes, mal_id,
 			created_at, updated_at
 		FROM anime
 	`)
 	if err != nil {
-		return fmt.Errorf("failed to query anime: %w", err)
+		return BackupData{}, fmt.Errorf("failed to query anime: %w", err)
 	}
 	defer rows.Close()
 
@@ -56,55 +224,60 @@ func (db *DB) ExportToJSON(filePath string) error {
 		err := rows.Scan(
 			&anime.ID, &anime.Title, &anime.OriginalTitle, &alternativeTitlesJSON,
 			&anime.Description, &anime.TotalEpisodes, &anime.Type, &anime.Year,
-			&anime.Season, &anime.Status, &genresJSON, &anime.ThumbnailURL,
+			&anime.Season, &anime.Status, &genresJSON, &anime.ThumbnailURL, &anime.Notes, &anime.MalID,
 			&anime.CreatedAt, &anime.UpdatedAt,
 		)
 		if err != nil {
-			return fmt.Errorf("failed to scan anime: %w", err)
+			return BackupData{}, fmt.Errorf("failed to scan anime: %w", err)
 		}
 
 		// Parse JSON fields
 		if err := json.Unmarshal(alternativeTitlesJSON, &anime.AlternativeTitles); err != nil {
-			return fmt.Errorf("failed to unmarshal alternative titles: %w", err)
+			return BackupData{}, fmt.Errorf("failed to unmarshal alternative titles: %w", err)
 		}
 
 		if err := json.Unmarshal(genresJSON, &anime.Genres); err != nil {
-			return fmt.Errorf("failed to unmarshal genres: %w", err)
+			return BackupData{}, fmt.Errorf("failed to unmarshal genres: %w", err)
 		}
 
 		data.Anime = append(data.Anime, anime)
 	}
 	if err := rows.Err(); err != nil {
-		return fmt.Errorf("error iterating anime rows: %w", err)
+		return BackupData{}, fmt.Errorf("error iterating anime rows: %w", err)
 	}
 
 	// Get all anime tracking entries
 	rows, err = db.conn.Query(`
 		SELECT 
 			id, anime_id, tracker, tracker_id, status, score, 
-			current_episode, total_episodes, last_updated
+			current_episode, total_episodes, last_updated, notes, priority, tags, is_rewatching
 		FROM anime_tracking
 	`)
 	if err != nil {
-		return fmt.Errorf("failed to query anime tracking: %w", err)
+		return BackupData{}, fmt.Errorf("failed to query anime tracking: %w", err)
 	}
 	defer rows.Close()
 
 	for rows.Next() {
 		var tracking AnimeTracking
+		var tagsJSON []byte
 		err := rows.Scan(
 			&tracking.ID, &tracking.AnimeID, &tracking.Tracker, &tracking.TrackerID,
 			&tracking.Status, &tracking.Score, &tracking.CurrentEpisode,
-			&tracking.TotalEpisodes, &tracking.LastUpdated,
+			&tracking.TotalEpisodes, &tracking.LastUpdated, &tracking.Notes,
+			&tracking.Priority, &tagsJSON, &tracking.IsRewatching,
 		)
 		if err != nil {
-			return fmt.Errorf("failed to scan anime tracking: %w", err)
+			return BackupData{}, fmt.Errorf("failed to scan anime tracking: %w", err)
+		}
+		if err := json.Unmarshal(tagsJSON, &tracking.Tags); err != nil {
+			return BackupData{}, fmt.Errorf("failed to unmarshal tracking tags: %w", err)
 		}
 
 		data.AnimeTracking = append(data.AnimeTracking, tracking)
 	}
 	if err := rows.Err(); err != nil {
-		return fmt.Errorf("error iterating anime tracking rows: %w", err)
+		return BackupData{}, fmt.Errorf("error iterating anime tracking rows: %w", err)
 	}
 
 	// Get all episode progress entries
@@ -115,7 +288,7 @@ func (db *DB) ExportToJSON(filePath string) error {
 		FROM episode_progress
 	`)
 	if err != nil {
-		return fmt.Errorf("failed to query episode progress: %w", err)
+		return BackupData{}, fmt.Errorf("failed to query episode progress: %w", err)
 	}
 	defer rows.Close()
 
@@ -127,13 +300,13 @@ func (db *DB) ExportToJSON(filePath string) error {
 			&progress.SourceID, &progress.LastWatched,
 		)
 		if err != nil {
-			return fmt.Errorf("failed to scan episode progress: %w", err)
+			return BackupData{}, fmt.Errorf("failed to scan episode progress: %w", err)
 		}
 
 		data.EpisodeProgress = append(data.EpisodeProgress, progress)
 	}
 	if err := rows.Err(); err != nil {
-		return fmt.Errorf("error iterating episode progress rows: %w", err)
+		return BackupData{}, fmt.Errorf("error iterating episode progress rows: %w", err)
 	}
 
 	// Get all episodes
@@ -144,7 +317,7 @@ func (db *DB) ExportToJSON(filePath string) error {
 		FROM episode
 	`)
 	if err != nil {
-		return fmt.Errorf("failed to query episodes: %w", err)
+		return BackupData{}, fmt.Errorf("failed to query episodes: %w", err)
 	}
 	defer rows.Close()
 
@@ -156,13 +329,13 @@ func (db *DB) ExportToJSON(filePath string) error {
 			&episode.AirDate, &episode.IsFiller, &episode.CreatedAt,
 		)
 		if err != nil {
-			return fmt.Errorf("failed to scan episode: %w", err)
+			return BackupData{}, fmt.Errorf("failed to scan episode: %w", err)
 		}
 
 		data.Episodes = append(data.Episodes, episode)
 	}
 	if err := rows.Err(); err != nil {
-		return fmt.Errorf("error iterating episode rows: %w", err)
+		return BackupData{}, fmt.Errorf("error iterating episode rows: %w", err)
 	}
 
 	// Get all extensions
@@ -173,7 +346,7 @@ func (db *DB) ExportToJSON(filePath string) error {
 		FROM extension
 	`)
 	if err != nil {
-		return fmt.Errorf("failed to query extensions: %w", err)
+		return BackupData{}, fmt.Errorf("failed to query extensions: %w", err)
 	}
 	defer rows.Close()
 
@@ -184,13 +357,13 @@ func (db *DB) ExportToJSON(filePath string) error {
 			&ext.NSFW, &ext.Path, &ext.RepositoryURL, &ext.InstalledAt, &ext.UpdatedAt,
 		)
 		if err != nil {
-			return fmt.Errorf("failed to scan extension: %w", err)
+			return BackupData{}, fmt.Errorf("failed to scan extension: %w", err)
 		}
 
 		data.Extensions = append(data.Extensions, ext)
 	}
 	if err := rows.Err(); err != nil {
-		return fmt.Errorf("error iterating extension rows: %w", err)
+		return BackupData{}, fmt.Errorf("error iterating extension rows: %w", err)
 	}
 
 	// Get all sources
@@ -200,7 +373,7 @@ func (db *DB) ExportToJSON(filePath string) error {
 		FROM source
 	`)
 	if err != nil {
-		return fmt.Errorf("failed to query sources: %w", err)
+		return BackupData{}, fmt.Errorf("failed to query sources: %w", err)
 	}
 	defer rows.Close()
 
@@ -211,23 +384,23 @@ func (db *DB) ExportToJSON(filePath string) error {
 			&source.Language, &source.BaseURL, &source.NSFW,
 		)
 		if err != nil {
-			return fmt.Errorf("failed to scan source: %w", err)
+			return BackupData{}, fmt.Errorf("failed to scan source: %w", err)
 		}
 
 		data.Sources = append(data.Sources, source)
 	}
 	if err := rows.Err(); err != nil {
-		return fmt.Errorf("error iterating source rows: %w", err)
+		return BackupData{}, fmt.Errorf("error iterating source rows: %w", err)
 	}
 
 	// Get all anime sources
 	rows, err = db.conn.Query(`
 		SELECT 
-			id, anime_id, source_id, source_anime_id
+			id, anime_id, source_id, source_anime_id, episode_offset
 		FROM anime_source
 	`)
 	if err != nil {
-		return fmt.Errorf("failed to query anime sources: %w", err)
+		return BackupData{}, fmt.Errorf("failed to query anime sources: %w", err)
 	}
 	defer rows.Close()
 
@@ -235,18 +408,23 @@ func (db *DB) ExportToJSON(filePath string) error {
 		var animeSource AnimeSource
 		err := rows.Scan(
 			&animeSource.ID, &animeSource.AnimeID, &animeSource.SourceID,
-			&animeSource.SourceAnimeID,
+			&animeSource.SourceAnimeID, &animeSource.EpisodeOffset,
 		)
 		if err != nil {
-			return fmt.Errorf("failed to scan anime source: %w", err)
+			return BackupData{}, fmt.Errorf("failed to scan anime source: %w", err)
 		}
 
 		data.AnimeSources = append(data.AnimeSources, animeSource)
 	}
 	if err := rows.Err(); err != nil {
-		return fmt.Errorf("error iterating anime source rows: %w", err)
+		return BackupData{}, fmt.Errorf("error iterating anime source rows: %w", err)
 	}
 
+	return data, nil
+}
+
+// saveBackup writes a backup to a JSON file
+func saveBackup(data BackupData, filePath string) error {
 	// Ensure directory exists
 	dir := filepath.Dir(filePath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
@@ -269,163 +447,455 @@ func (db *DB) ExportToJSON(filePath string) error {
 	return nil
 }
 
-// ImportFromJSON imports data from a JSON file into the database
-func (db *DB) ImportFromJSON(filePath string) error {
-	// Read the file
-	file, err := os.Open(filePath)
+// ImportOptions controls how ImportFromJSON restores a backup
+type ImportOptions struct {
+	Mode ImportMode
+	// Lenient imports table by table and skips rows that fail instead of
+	// rolling back the whole import
+	Lenient bool
+}
+
+// ImportStats reports the outcome of an import
+type ImportStats struct {
+	Imported BackupSummary `json:"imported"`
+	// Skipped counts delta rows left alone because the database was newer
+	Skipped int      `json:"skipped,omitempty"`
+	Failed  int      `json:"failed"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
+// importTable is the rows of one table in a backup, in import order
+type importTable struct {
+	name string
+	rows []func(tx *sql.Tx) error
+}
+
+// count records a successfully imported row of the given table
+func (s *BackupSummary) count(table string) {
+	switch table {
+	case "anime":
+		s.Anime++
+	case "anime_tracking":
+		s.Tracking++
+	case "episode_progress":
+		s.Progress++
+	case "episode":
+		s.Episodes++
+	case "source":
+		s.Sources++
+	}
+}
+
+// ImportFromJSON imports data from a JSON file into the database.
+// ImportReplace removes the existing library first. A strict import runs in
+// one transaction and stops at the first failing row; a lenient one commits
+// each table separately and reports the rows that failed in ImportStats.
+func (db *DB) ImportFromJSON(filePath string, opts ImportOptions) (ImportStats, error) {
+	data, err := readBackup(filePath)
 	if err != nil {
-		return fmt.Errorf("failed to open import file: %w", err)
+		return ImportStats{}, err
+	}
+	// Replacing the library with a delta would lose everything it doesn't hold
+	if data.ChangedSince != nil && opts.Mode == ImportReplace {
+		return ImportStats{}, ErrDeltaBackup
 	}
-	defer file.Close()
 
-	// Decode the JSON
-	var data BackupData
-	decoder := json.NewDecoder(file)
-	if err := decoder.Decode(&data); err != nil {
-		return fmt.Errorf("failed to decode import data: %w", err)
+	tables := importTables(&data)
+	if opts.Mode == ImportReplace {
+		tables = append([]importTable{clearTables()}, tables...)
 	}
 
-	// Start a transaction
-	tx, err := db.conn.Begin()
+	if opts.Lenient {
+		return db.importLenient(tables)
+	}
+	return db.importStrict(tables)
+}
+
+// ImportDelta merges a delta export from ExportChangedSince. Each row is only
+// applied when it is newer than the matching row in the database, so older
+// deltas never undo later changes.
+// Anime rows are updated in place, keeping the tracking, progress and
+// episodes the delta doesn't hold.
+func (db *DB) ImportDelta(filePath string) (ImportStats, error) {
+	data, err := readBackup(filePath)
 	if err != nil {
-		return fmt.Errorf("failed to start transaction: %w", err)
+		return ImportStats{}, err
+	}
+	if data.ChangedSince == nil {
+		return ImportStats{}, fmt.Errorf("%w: %s is a full backup", ErrDeltaBackup, filePath)
 	}
-	defer tx.Rollback()
 
-	// Import config entries
-	for _, cfg := range data.Config {
-		_, err := tx.Exec(
-			`INSERT INTO config (key, value, updated_at) VALUES (?, ?, ?)
-			ON CONFLICT(key) DO UPDATE SET value = ?, updated_at = ?`,
-			cfg.Key, cfg.Value, cfg.UpdatedAt,
-			cfg.Value, cfg.UpdatedAt,
-		)
+	var skipped int
+	newer := func(query string, changed time.Time, args ...interface{}) (bool, error) {
+		var current time.Time
+		err := db.conn.QueryRow(query, args...).Scan(&current)
+		if errors.Is(err, sql.ErrNoRows) {
+			return true, nil
+		}
 		if err != nil {
-			return fmt.Errorf("failed to import config entry %s: %w", cfg.Key, err)
+			return false, err
 		}
+		if !changed.After(current) {
+			skipped++
+			return false, nil
+		}
+		return true, nil
 	}
 
-	// Import anime
+	var animes []Anime
 	for _, anime := range data.Anime {
-		alternativeTitles, err := json.Marshal(anime.AlternativeTitles)
+		ok, err := newer("SELECT updated_at FROM anime WHERE id = ?", anime.UpdatedAt, anime.ID)
 		if err != nil {
-			return fmt.Errorf("failed to marshal alternative titles: %w", err)
+			return ImportStats{}, fmt.Errorf("failed to check anime %d: %w", anime.ID, err)
 		}
-
-		genres, err := json.Marshal(anime.Genres)
-		if err != nil {
-			return fmt.Errorf("failed to marshal genres: %w", err)
-		}
-
-		// Import with original ID
-		_, err = tx.Exec(
-			`INSERT OR REPLACE INTO anime (
-				id, title, original_title, alternative_titles, description, 
-				total_episodes, type, year, season, status, genres, thumbnail_url,
-				created_at, updated_at
-			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-			anime.ID, anime.Title, anime.OriginalTitle, alternativeTitles, anime.Description,
-			anime.TotalEpisodes, anime.Type, anime.Year, anime.Season, anime.Status,
-			genres, anime.ThumbnailURL, anime.CreatedAt, anime.UpdatedAt,
-		)
-		if err != nil {
-			return fmt.Errorf("failed to import anime %s: %w", anime.Title, err)
+		if ok {
+			animes = append(animes, anime)
 		}
 	}
-
-	// Import anime tracking
+	var trackings []AnimeTracking
 	for _, tracking := range data.AnimeTracking {
-		_, err := tx.Exec(
-			`INSERT OR REPLACE INTO anime_tracking (
-				id, anime_id, tracker, tracker_id, status, score, 
-				current_episode, total_episodes, last_updated
-			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-			tracking.ID, tracking.AnimeID, tracking.Tracker, tracking.TrackerID, tracking.Status,
-			tracking.Score, tracking.CurrentEpisode, tracking.TotalEpisodes, tracking.LastUpdated,
-		)
+		ok, err := newer("SELECT last_updated FROM anime_tracking WHERE anime_id = ? AND tracker = ?",
+			tracking.LastUpdated, tracking.AnimeID, tracking.Tracker)
 		if err != nil {
-			return fmt.Errorf("failed to import anime tracking for anime %d: %w", tracking.AnimeID, err)
+			return ImportStats{}, fmt.Errorf("failed to check tracking for anime %d: %w", tracking.AnimeID, err)
+		}
+		if ok {
+			trackings = append(trackings, tracking)
 		}
 	}
-
-	// Import episode progress
-	for _, progress := range data.EpisodeProgress {
-		_, err := tx.Exec(
-			`INSERT OR REPLACE INTO episode_progress (
-				id, anime_id, episode_number, position, duration, 
-				playback_speed, watched, source_id, last_watched
-			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-			progress.ID, progress.AnimeID, progress.EpisodeNumber, progress.Position, progress.Duration,
-			progress.PlaybackSpeed, progress.Watched, progress.SourceID, progress.LastWatched,
-		)
+	var progress []EpisodeProgress
+	for _, p := range data.EpisodeProgress {
+		ok, err := newer("SELECT last_watched FROM episode_progress WHERE anime_id = ? AND episode_number = ?",
+			p.LastWatched, p.AnimeID, p.EpisodeNumber)
 		if err != nil {
-			return fmt.Errorf("failed to import episode progress for anime %d episode %f: %w",
-				progress.AnimeID, progress.EpisodeNumber, err)
+			return ImportStats{}, fmt.Errorf("failed to check progress for anime %d: %w", p.AnimeID, err)
+		}
+		if ok {
+			progress = append(progress, p)
 		}
 	}
 
-	// Import episodes
-	for _, episode := range data.Episodes {
-		_, err := tx.Exec(
-			`INSERT OR REPLACE INTO episode (
-				id, anime_id, number, title, description, duration, 
-				thumbnail_url, air_date, is_filler, created_at
-			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-			episode.ID, episode.AnimeID, episode.Number, episode.Title, episode.Description,
-			episode.Duration, episode.ThumbnailURL, episode.AirDate, episode.IsFiller, episode.CreatedAt,
-		)
-		if err != nil {
-			return fmt.Errorf("failed to import episode %f for anime %d: %w",
-				episode.Number, episode.AnimeID, err)
-		}
+	delta := BackupData{Anime: animes, AnimeTracking: trackings, EpisodeProgress: progress}
+	stats, err := db.importStrict(importTables(&delta))
+	if err != nil {
+		return ImportStats{}, err
 	}
+	stats.Skipped = skipped
+	return stats, nil
+}
 
-	// Import extensions
-	for _, ext := range data.Extensions {
-		_, err := tx.Exec(
-			`INSERT OR REPLACE INTO extension (
-				id, name, package, language, version, nsfw, path, repository_url,
-				installed_at, updated_at
-			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-			ext.ID, ext.Name, ext.Package, ext.Language, ext.Version, ext.NSFW, ext.Path,
-			ext.RepositoryURL, ext.InstalledAt, ext.UpdatedAt,
-		)
-		if err != nil {
-			return fmt.Errorf("failed to import extension %s: %w", ext.Name, err)
-		}
+// readBackup reads a backup file and upgrades it to currentBackupVersion
+func readBackup(filePath string) (BackupData, error) {
+	var data BackupData
+
+	// Read the file
+	file, err := os.Open(filePath)
+	if err != nil {
+		return data, fmt.Errorf("failed to open import file: %w", err)
 	}
+	defer file.Close()
 
-	// Import sources
-	for _, source := range data.Sources {
-		_, err := tx.Exec(
-			`INSERT OR REPLACE INTO source (
-				id, source_id, extension_id, name, language, base_url, nsfw
-			) VALUES (?, ?, ?, ?, ?, ?, ?)`,
-			source.ID, source.SourceID, source.ExtensionID, source.Name, source.Language,
-			source.BaseURL, source.NSFW,
-		)
-		if err != nil {
-			return fmt.Errorf("failed to import source %s: %w", source.Name, err)
-		}
+	// Decode the JSON
+	if err := json.NewDecoder(file).Decode(&data); err != nil {
+		return data, fmt.Errorf("failed to decode import data: %w", err)
 	}
 
-	// Import anime sources
-	for _, animeSource := range data.AnimeSources {
-		_, err := tx.Exec(
-			`INSERT OR REPLACE INTO anime_source (
-				id, anime_id, source_id, source_anime_id
-			) VALUES (?, ?, ?, ?)`,
-			animeSource.ID, animeSource.AnimeID, animeSource.SourceID, animeSource.SourceAnimeID,
-		)
-		if err != nil {
-			return fmt.Errorf("failed to import anime source mapping: %w", err)
+	if err := upgradeBackup(&data); err != nil {
+		return data, err
+	}
+	return data, nil
+}
+
+// importStrict imports every table in one transaction, all or nothing
+func (db *DB) importStrict(tables []importTable) (ImportStats, error) {
+	var stats ImportStats
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return ImportStats{}, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, table := range tables {
+		for _, row := range table.rows {
+			if err := row(tx); err != nil {
+				return ImportStats{}, err
+			}
+			stats.Imported.count(table.name)
 		}
 	}
 
-	// Commit the transaction
 	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit import transaction: %w", err)
+		return ImportStats{}, fmt.Errorf("failed to commit import transaction: %w", err)
+	}
+	return stats, nil
+}
+
+// importLenient imports each table in its own transaction, skipping rows
+// that fail. Only failing to start or commit a transaction stops the import.
+func (db *DB) importLenient(tables []importTable) (ImportStats, error) {
+	var stats ImportStats
+
+	for _, table := range tables {
+		tx, err := db.conn.Begin()
+		if err != nil {
+			return stats, fmt.Errorf("failed to start transaction for %s: %w", table.name, err)
+		}
+
+		for _, row := range table.rows {
+			// SQLite only undoes the failing statement, so the rest of the table is kept
+			if err := row(tx); err != nil {
+				stats.Failed++
+				stats.Errors = append(stats.Errors, err.Error())
+				continue
+			}
+			stats.Imported.count(table.name)
+		}
+
+		if err := tx.Commit(); err != nil {
+			tx.Rollback()
+			return stats, fmt.Errorf("failed to commit %s: %w", table.name, err)
+		}
 	}
 
+	return stats, nil
+}
+
+// clearTables empties the library for ImportReplace
+func clearTables() importTable {
+	table := importTable{name: "clear"}
+	for _, name := range replaceTables {
+		table.rows = append(table.rows, func(tx *sql.Tx) error {
+			if _, err := tx.Exec("DELETE FROM " + name); err != nil {
+				return fmt.Errorf("failed to clear %s: %w", name, err)
+			}
+			return nil
+		})
+	}
+	return table
+}
+
+// requireParent checks that the row a child row points at exists. Foreign
+// keys aren't enforced, so this keeps an import from leaving orphans.
+func requireParent(tx *sql.Tx, table string, id int64) error {
+	var count int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM "+table+" WHERE id = ?", id).Scan(&count); err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("%s %d doesn't exist", table, id)
+	}
 	return nil
 }
+
+// importTables turns a backup into the rows to insert, parents before children
+func importTables(data *BackupData) []importTable {
+	var config, animes, trackings, progress, episodes, extensions, sources, animeSources importTable
+	config.name = "config"
+	animes.name = "anime"
+	trackings.name = "anime_tracking"
+	progress.name = "episode_progress"
+	episodes.name = "episode"
+	extensions.name = "extension"
+	sources.name = "source"
+	animeSources.name = "anime_source"
+
+	for _, cfg := range data.Config {
+		config.rows = append(config.rows, func(tx *sql.Tx) error {
+			_, err := tx.Exec(
+				`INSERT INTO config (key, value, updated_at) VALUES (?, ?, ?)
+				ON CONFLICT(key) DO UPDATE SET value = ?, updated_at = ?`,
+				cfg.Key, cfg.Value, cfg.UpdatedAt,
+				cfg.Value, cfg.UpdatedAt,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to import config entry %s: %w", cfg.Key, err)
+			}
+			return nil
+		})
+	}
+
+	for _, anime := range data.Anime {
+		animes.rows = append(animes.rows, func(tx *sql.Tx) error {
+			if strings.TrimSpace(anime.Title) == "" {
+				return fmt.Errorf("failed to import anime %d: missing title", anime.ID)
+			}
+
+			alternativeTitles, err := json.Marshal(anime.AlternativeTitles)
+			if err != nil {
+				return fmt.Errorf("failed to marshal alternative titles: %w", err)
+			}
+
+			genres, err := json.Marshal(anime.Genres)
+			if err != nil {
+				return fmt.Errorf("failed to marshal genres: %w", err)
+			}
+
+			// Import with original ID. An upsert rather than a REPLACE, which
+			// would delete the existing row and leave its children behind.
+			_, err = tx.Exec(
+				`INSERT INTO anime (
+					id, title, original_title, alternative_titles, description, 
+					total_episodes, type, year, season, status, genres, thumbnail_url, notes, mal_id,
+					created_at, updated_at
+				) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+				ON CONFLICT(id) DO UPDATE SET
+					title = excluded.title, original_title = excluded.original_title,
+					alternative_titles = excluded.alternative_titles, description = excluded.description,
+					total_episodes = excluded.total_episodes, type = excluded.type, year = excluded.year,
+					season = excluded.season, status = excluded.status, genres = excluded.genres,
+					thumbnail_url = excluded.thumbnail_url, notes = excluded.notes,
+					mal_id = COALESCE(NULLIF(excluded.mal_id, ''), mal_id),
+					created_at = excluded.created_at, updated_at = excluded.updated_at`,
+				anime.ID, anime.Title, anime.OriginalTitle, alternativeTitles, anime.Description,
+				anime.TotalEpisodes, anime.Type, anime.Year, anime.Season, anime.Status,
+				genres, anime.ThumbnailURL, anime.Notes, anime.MalID, anime.CreatedAt, anime.UpdatedAt,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to import anime %s: %w", anime.Title, err)
+			}
+			return nil
+		})
+	}
+
+	for _, tracking := range data.AnimeTracking {
+		trackings.rows = append(trackings.rows, func(tx *sql.Tx) error {
+			tags := tracking.Tags
+			if tags == nil {
+				tags = []string{}
+			}
+			tagsJSON, err := json.Marshal(tags)
+			if err != nil {
+				return fmt.Errorf("failed to marshal tags for anime %d: %w", tracking.AnimeID, err)
+			}
+			if err := requireParent(tx, "anime", tracking.AnimeID); err != nil {
+				return fmt.Errorf("failed to import anime tracking for anime %d: %w", tracking.AnimeID, err)
+			}
+
+			_, err = tx.Exec(
+				`INSERT OR REPLACE INTO anime_tracking (
+					id, anime_id, tracker, tracker_id, status, score, 
+					current_episode, total_episodes, last_updated, notes, priority, tags, is_rewatching
+				) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				tracking.ID, tracking.AnimeID, tracking.Tracker, tracking.TrackerID, tracking.Status,
+				tracking.Score, tracking.CurrentEpisode, tracking.TotalEpisodes, tracking.LastUpdated,
+				tracking.Notes, tracking.Priority, string(tagsJSON), tracking.IsRewatching,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to import anime tracking for anime %d: %w", tracking.AnimeID, err)
+			}
+			return nil
+		})
+	}
+
+	for _, p := range data.EpisodeProgress {
+		progress.rows = append(progress.rows, func(tx *sql.Tx) error {
+			if err := requireParent(tx, "anime", p.AnimeID); err != nil {
+				return fmt.Errorf("failed to import episode progress for anime %d: %w", p.AnimeID, err)
+			}
+			_, err := tx.Exec(
+				`INSERT OR REPLACE INTO episode_progress (
+					id, anime_id, episode_number, position, duration, 
+					playback_speed, watched, source_id, last_watched
+				) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				p.ID, p.AnimeID, p.EpisodeNumber, p.Position, p.Duration,
+				p.PlaybackSpeed, p.Watched, p.SourceID, p.LastWatched,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to import episode progress for anime %d episode %f: %w",
+					p.AnimeID, p.EpisodeNumber, err)
+			}
+			return nil
+		})
+	}
+
+	for _, episode := range data.Episodes {
+		episodes.rows = append(episodes.rows, func(tx *sql.Tx) error {
+			if err := requireParent(tx, "anime", episode.AnimeID); err != nil {
+				return fmt.Errorf("failed to import episode %f for anime %d: %w", episode.Number, episode.AnimeID, err)
+			}
+			_, err := tx.Exec(
+				`INSERT OR REPLACE INTO episode (
+					id, anime_id, number, title, description, duration, 
+					thumbnail_url, air_date, is_filler, created_at
+				) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				episode.ID, episode.AnimeID, episode.Number, episode.Title, episode.Description,
+				episode.Duration, episode.ThumbnailURL, episode.AirDate, episode.IsFiller, episode.CreatedAt,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to import episode %f for anime %d: %w",
+					episode.Number, episode.AnimeID, err)
+			}
+			return nil
+		})
+	}
+
+	for _, ext := range data.Extensions {
+		extensions.rows = append(extensions.rows, func(tx *sql.Tx) error {
+			_, err := tx.Exec(
+				`INSERT INTO extension (
+					id, name, package, language, version, nsfw, path, repository_url,
+					installed_at, updated_at
+				) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+				ON CONFLICT(id) DO UPDATE SET
+					name = excluded.name, package = excluded.package, language = excluded.language,
+					version = excluded.version, nsfw = excluded.nsfw, path = excluded.path,
+					repository_url = excluded.repository_url, installed_at = excluded.installed_at,
+					updated_at = excluded.updated_at`,
+				ext.ID, ext.Name, ext.Package, ext.Language, ext.Version, ext.NSFW, ext.Path,
+				ext.RepositoryURL, ext.InstalledAt, ext.UpdatedAt,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to import extension %s: %w", ext.Name, err)
+			}
+			return nil
+		})
+	}
+
+	for _, source := range data.Sources {
+		sources.rows = append(sources.rows, func(tx *sql.Tx) error {
+			if err := requireParent(tx, "extension", source.ExtensionID); err != nil {
+				return fmt.Errorf("failed to import source %s: %w", source.Name, err)
+			}
+			_, err := tx.Exec(
+				`INSERT INTO source (
+					id, source_id, extension_id, name, language, base_url, nsfw
+				) VALUES (?, ?, ?, ?, ?, ?, ?)
+				ON CONFLICT(id) DO UPDATE SET
+					source_id = excluded.source_id, extension_id = excluded.extension_id,
+					name = excluded.name, language = excluded.language, base_url = excluded.base_url,
+					nsfw = excluded.nsfw`,
+				source.ID, source.SourceID, source.ExtensionID, source.Name, source.Language,
+				source.BaseURL, source.NSFW,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to import source %s: %w", source.Name, err)
+			}
+			return nil
+		})
+	}
+
+	for _, animeSource := range data.AnimeSources {
+		animeSources.rows = append(animeSources.rows, func(tx *sql.Tx) error {
+			if err := requireParent(tx, "anime", animeSource.AnimeID); err != nil {
+				return fmt.Errorf("failed to import anime source mapping: %w", err)
+			}
+			if err := requireParent(tx, "source", animeSource.SourceID); err != nil {
+				return fmt.Errorf("failed to import anime source mapping: %w", err)
+			}
+			_, err := tx.Exec(
+				`INSERT OR REPLACE INTO anime_source (
+					id, anime_id, source_id, source_anime_id, episode_offset
+				) VALUES (?, ?, ?, ?, ?)`,
+				animeSource.ID, animeSource.AnimeID, animeSource.SourceID, animeSource.SourceAnimeID,
+				animeSource.EpisodeOffset,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to import anime source mapping: %w", err)
+			}
+			return nil
+		})
+	}
+
+	return []importTable{config, animes, trackings, progress, episodes, extensions, sources, animeSources}
+}
@@ -1,6 +1,8 @@
 package database
 
 import (
+	"database/sql"
+	"fmt"
 	"time"
 )
 
@@ -35,6 +37,20 @@ type AnimeSource struct {
 	AnimeID       int64
 	SourceID      int64
 	SourceAnimeID string
+	// EpisodeOffset is added to the anime's episode numbers to get the
+	// source's, as when a source numbers a second cour 13-24 while trackers
+	// list it as its own season from 1
+	EpisodeOffset float64
+}
+
+// SourceEpisode converts an episode number of the anime to the source's numbering
+func (s *AnimeSource) SourceEpisode(episode float64) float64 {
+	return episode + s.EpisodeOffset
+}
+
+// AnimeEpisode converts an episode number of the source to the anime's numbering
+func (s *AnimeSource) AnimeEpisode(sourceEpisode float64) float64 {
+	return sourceEpisode - s.EpisodeOffset
 }
 
 // AddExtension adds a new extension to the database
@@ -86,6 +102,26 @@ func (db *DB) GetExtensionByPackage(pkg string) (*Extension, error) {
 	return &ext, nil
 }
 
+// GetExtensionByID retrieves an extension by its database ID
+func (db *DB) GetExtensionByID(id int64) (*Extension, error) {
+	var ext Extension
+
+	err := db.conn.QueryRow(
+		`SELECT 
+			id, name, package, language, version, nsfw, path, repository_url,
+			installed_at, updated_at
+		FROM extension WHERE id = ?`, id,
+	).Scan(
+		&ext.ID, &ext.Name, &ext.Package, &ext.Language, &ext.Version, &ext.NSFW,
+		&ext.Path, &ext.RepositoryURL, &ext.InstalledAt, &ext.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ext, nil
+}
+
 // GetAllExtensions retrieves all extensions
 func (db *DB) GetAllExtensions() ([]*Extension, error) {
 	rows, err := db.conn.Query(
@@ -116,10 +152,28 @@ func (db *DB) GetAllExtensions() ([]*Extension, error) {
 	return extensions, rows.Err()
 }
 
-// DeleteExtension deletes an extension by package name
+// DeleteExtension deletes an extension by package name along with its
+// sources and the anime mapped to them
 func (db *DB) DeleteExtension(pkg string) error {
-	_, err := db.conn.Exec("DELETE FROM extension WHERE package = ?", pkg)
-	return err
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	queries := []string{
+		`DELETE FROM anime_source WHERE source_id IN (
+			SELECT s.id FROM source s JOIN extension e ON s.extension_id = e.id WHERE e.package = ?)`,
+		`DELETE FROM source WHERE extension_id IN (SELECT id FROM extension WHERE package = ?)`,
+		`DELETE FROM extension WHERE package = ?`,
+	}
+	for _, query := range queries {
+		if _, err := tx.Exec(query, pkg); err != nil {
+			return fmt.Errorf("failed to delete extension %s: %w", pkg, err)
+		}
+	}
+
+	return tx.Commit()
 }
 
 // AddSource adds a new source to the database
@@ -227,22 +281,36 @@ func (db *DB) GetAllSources() ([]*Source, error) {
 	return sources, rows.Err()
 }
 
-// DeleteSource deletes a source by its unique ID
+// DeleteSource deletes a source by its unique ID along with its anime mappings
 func (db *DB) DeleteSource(sourceID string) error {
-	_, err := db.conn.Exec("DELETE FROM source WHERE source_id = ?", sourceID)
-	return err
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(
+		"DELETE FROM anime_source WHERE source_id IN (SELECT id FROM source WHERE source_id = ?)", sourceID,
+	); err != nil {
+		return fmt.Errorf("failed to delete mappings of source %s: %w", sourceID, err)
+	}
+	if _, err := tx.Exec("DELETE FROM source WHERE source_id = ?", sourceID); err != nil {
+		return fmt.Errorf("failed to delete source %s: %w", sourceID, err)
+	}
+
+	return tx.Commit()
 }
 
 // AddAnimeSource links an anime to a source
 func (db *DB) AddAnimeSource(animeSource *AnimeSource) error {
 	result, err := db.conn.Exec(
 		`INSERT INTO anime_source (
-			anime_id, source_id, source_anime_id
-		) VALUES (?, ?, ?)
+			anime_id, source_id, source_anime_id, episode_offset
+		) VALUES (?, ?, ?, ?)
 		ON CONFLICT(anime_id, source_id) DO UPDATE SET
-			source_anime_id = ?`,
-		animeSource.AnimeID, animeSource.SourceID, animeSource.SourceAnimeID,
-		animeSource.SourceAnimeID,
+			source_anime_id = ?, episode_offset = ?`,
+		animeSource.AnimeID, animeSource.SourceID, animeSource.SourceAnimeID, animeSource.EpisodeOffset,
+		animeSource.SourceAnimeID, animeSource.EpisodeOffset,
 	)
 	if err != nil {
 		return err
@@ -264,7 +332,7 @@ func (db *DB) AddAnimeSource(animeSource *AnimeSource) error {
 func (db *DB) GetAnimeSources(animeID int64) ([]*AnimeSource, error) {
 	rows, err := db.conn.Query(
 		`SELECT 
-			id, anime_id, source_id, source_anime_id
+			id, anime_id, source_id, source_anime_id, episode_offset
 		FROM anime_source WHERE anime_id = ?`,
 		animeID,
 	)
@@ -277,7 +345,7 @@ func (db *DB) GetAnimeSources(animeID int64) ([]*AnimeSource, error) {
 	for rows.Next() {
 		var source AnimeSource
 		err := rows.Scan(
-			&source.ID, &source.AnimeID, &source.SourceID, &source.SourceAnimeID,
+			&source.ID, &source.AnimeID, &source.SourceID, &source.SourceAnimeID, &source.EpisodeOffset,
 		)
 		if err != nil {
 			return nil, err
@@ -294,11 +362,11 @@ func (db *DB) GetAnimeSourceBySourceAnimeID(sourceID int64, sourceAnimeID string
 	var source AnimeSource
 	err := db.conn.QueryRow(
 		`SELECT 
-			id, anime_id, source_id, source_anime_id
+			id, anime_id, source_id, source_anime_id, episode_offset
 		FROM anime_source WHERE source_id = ? AND source_anime_id = ?`,
 		sourceID, sourceAnimeID,
 	).Scan(
-		&source.ID, &source.AnimeID, &source.SourceID, &source.SourceAnimeID,
+		&source.ID, &source.AnimeID, &source.SourceID, &source.SourceAnimeID, &source.EpisodeOffset,
 	)
 	if err != nil {
 		return nil, err
@@ -307,6 +375,26 @@ func (db *DB) GetAnimeSourceBySourceAnimeID(sourceID int64, sourceAnimeID string
 	return &source, nil
 }
 
+// SetAnimeSourceOffset sets the episode offset of the link between an anime
+// and a source. It returns sql.ErrNoRows if they aren't linked.
+func (db *DB) SetAnimeSourceOffset(animeID int64, sourceID int64, offset float64) error {
+	result, err := db.conn.Exec(
+		"UPDATE anime_source SET episode_offset = ? WHERE anime_id = ? AND source_id = ?",
+		offset, animeID, sourceID,
+	)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
 // DeleteAnimeSource removes the link between an anime and a source
 func (db *DB) DeleteAnimeSource(animeID int64, sourceID int64) error {
 	_, err := db.conn.Exec(
@@ -0,0 +1,207 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// IntegrityCheck runs SQLite's integrity check and returns any problems found
+func (db *DB) IntegrityCheck() ([]string, error) {
+	rows, err := db.conn.Query("PRAGMA integrity_check")
+	if err != nil {
+		return nil, fmt.Errorf("failed to run integrity check: %w", err)
+	}
+	defer rows.Close()
+
+	var problems []string
+	for rows.Next() {
+		var result string
+		if err := rows.Scan(&result); err != nil {
+			return nil, fmt.Errorf("failed to scan integrity check result: %w", err)
+		}
+		if result != "ok" {
+			problems = append(problems, result)
+		}
+	}
+
+	return problems, rows.Err()
+}
+
+// Path returns the file the database is stored in
+func (db *DB) Path() (string, error) {
+	var file string
+	err := db.conn.QueryRow("SELECT file FROM pragma_database_list WHERE name = 'main'").Scan(&file)
+	if err != nil {
+		return "", fmt.Errorf("failed to get database path: %w", err)
+	}
+	return file, nil
+}
+
+// Reindex rebuilds all indexes in the database
+func (db *DB) Reindex() error {
+	if _, err := db.conn.Exec("REINDEX"); err != nil {
+		return fmt.Errorf("failed to reindex database: %w", err)
+	}
+	return nil
+}
+
+// orphanQueries delete rows whose parent row no longer exists
+var orphanQueries = []string{
+	`DELETE FROM anime_tracking WHERE anime_id NOT IN (SELECT id FROM anime)`,
+	`DELETE FROM episode_progress WHERE anime_id NOT IN (SELECT id FROM anime)`,
+	`DELETE FROM episode WHERE anime_id NOT IN (SELECT id FROM anime)`,
+	`DELETE FROM source WHERE extension_id NOT IN (SELECT id FROM extension)`,
+	`DELETE FROM anime_source WHERE anime_id NOT IN (SELECT id FROM anime)
+		OR source_id NOT IN (SELECT id FROM source)`,
+}
+
+// CleanOrphans removes rows pointing at anime, extensions or sources that no
+// longer exist and returns the number of rows removed
+func (db *DB) CleanOrphans() (int, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	removed := 0
+	for _, query := range orphanQueries {
+		result, err := tx.Exec(query)
+		if err != nil {
+			return 0, fmt.Errorf("failed to clean orphaned rows: %w", err)
+		}
+		count, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		removed += int(count)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return removed, nil
+}
+
+// normalizeTitle lowercases a title and drops everything but letters and digits
+func normalizeTitle(title string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, title)
+}
+
+// FindDuplicateAnime groups anime that share a normalized title and year.
+// Each group is ordered by ID and only groups with more than one anime are returned.
+func (db *DB) FindDuplicateAnime() ([][]*Anime, error) {
+	animes, err := db.GetAllAnime()
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string][]*Anime)
+	var keys []string
+	for _, anime := range animes {
+		title := normalizeTitle(anime.Title)
+		if title == "" {
+			continue
+		}
+
+		key := fmt.Sprintf("%s|%d", title, anime.Year)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], anime)
+	}
+
+	var duplicates [][]*Anime
+	for _, key := range keys {
+		group := groups[key]
+		if len(group) < 2 {
+			continue
+		}
+		sort.Slice(group, func(i, j int) bool { return group[i].ID < group[j].ID })
+		duplicates = append(duplicates, group)
+	}
+
+	sort.Slice(duplicates, func(i, j int) bool { return duplicates[i][0].ID < duplicates[j][0].ID })
+	return duplicates, nil
+}
+
+// mergeQueries move the rows of a merged anime (second argument) onto the kept
+// anime (first argument). Tracking and progress rows that conflict keep
+// whichever side was updated last, for the other tables and the per-anime
+// config keys ("anime.<id>.*") the kept anime wins. Notes and the MyAnimeList
+// ID are only taken from the merged anime when the kept one has none. Rows
+// left behind by a conflict are deleted with the merged anime.
+var mergeQueries = []string{
+	`UPDATE anime SET
+		notes = CASE WHEN notes = '' THEN (SELECT notes FROM anime WHERE id = ?2) ELSE notes END,
+		mal_id = CASE WHEN mal_id = '' THEN (SELECT mal_id FROM anime WHERE id = ?2) ELSE mal_id END
+	WHERE id = ?1`,
+	`DELETE FROM anime_tracking WHERE anime_id = ?1 AND EXISTS (
+		SELECT 1 FROM anime_tracking m
+		WHERE m.anime_id = ?2 AND m.tracker = anime_tracking.tracker
+			AND m.last_updated > anime_tracking.last_updated)`,
+	`UPDATE OR IGNORE anime_tracking SET anime_id = ?1 WHERE anime_id = ?2`,
+	`DELETE FROM episode_progress WHERE anime_id = ?1 AND EXISTS (
+		SELECT 1 FROM episode_progress m
+		WHERE m.anime_id = ?2 AND m.episode_number = episode_progress.episode_number
+			AND m.last_watched > episode_progress.last_watched)`,
+	`UPDATE OR IGNORE episode_progress SET anime_id = ?1 WHERE anime_id = ?2`,
+	`UPDATE OR IGNORE episode SET anime_id = ?1 WHERE anime_id = ?2`,
+	`UPDATE OR IGNORE anime_source SET anime_id = ?1 WHERE anime_id = ?2`,
+	`UPDATE sync_conflict_log SET anime_id = ?1 WHERE anime_id = ?2`,
+	`UPDATE OR IGNORE config SET key = 'anime.' || ?1 || substr(key, length('anime.' || ?2) + 1)
+	WHERE key LIKE 'anime.' || ?2 || '.%'`,
+	`DELETE FROM anime_tracking WHERE anime_id = ?2`,
+	`DELETE FROM episode_progress WHERE anime_id = ?2`,
+	`DELETE FROM episode WHERE anime_id = ?2`,
+	`DELETE FROM anime_source WHERE anime_id = ?2`,
+	`DELETE FROM config WHERE key LIKE 'anime.' || ?2 || '.%'`,
+	`DELETE FROM anime WHERE id = ?2`,
+}
+
+// MergeAnime moves tracking, progress, episodes, source mappings, sync
+// conflicts and per-anime settings of the anime in mergeIDs onto keepID and
+// deletes the merged anime
+func (db *DB) MergeAnime(keepID int64, mergeIDs []int64) error {
+	for _, id := range mergeIDs {
+		if id == keepID {
+			return fmt.Errorf("cannot merge anime %d into itself", keepID)
+		}
+	}
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var exists int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM anime WHERE id = ?", keepID).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to check anime %d: %w", keepID, err)
+	}
+	if exists == 0 {
+		return ErrAnimeNotFound
+	}
+
+	for _, mergeID := range mergeIDs {
+		for _, query := range mergeQueries {
+			if _, err := tx.Exec(query, keepID, mergeID); err != nil {
+				return fmt.Errorf("failed to merge anime %d into %d: %w", mergeID, keepID, err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
@@ -3,6 +3,7 @@ package database
 import (
 	"database/sql"
 	"fmt"
+	"os"
 	"sort"
 	"time"
 )
@@ -74,7 +75,8 @@ func (db *DB) RunMigrations(migrations []Migration) error {
 			return fmt.Errorf("failed to commit migration %d: %w", migration.Version, err)
 		}
 
-		fmt.Printf("Applied migration %d: %s\n", migration.Version, migration.Description)
+		// Stderr keeps the output of subcommands like "list --json" parseable
+		fmt.Fprintf(os.Stderr, "Applied migration %d: %s\n", migration.Version, migration.Description)
 	}
 
 	return nil
@@ -241,3 +243,96 @@ func InitialMigration() Migration {
 		`,
 	}
 }
+
+// AddTrackingNotesMigration adds a notes column to anime tracking entries
+func AddTrackingNotesMigration() Migration {
+	return Migration{
+		Version:     2,
+		Description: "Add notes to anime tracking",
+		SQL: `
+			ALTER TABLE anime_tracking ADD COLUMN notes TEXT NOT NULL DEFAULT '';
+		`,
+	}
+}
+
+// AddTrackingListDetailsMigration adds the priority and tags of list entries
+// to anime tracking entries
+func AddTrackingListDetailsMigration() Migration {
+	return Migration{
+		Version:     3,
+		Description: "Add priority and tags to anime tracking",
+		SQL: `
+			ALTER TABLE anime_tracking ADD COLUMN priority INTEGER NOT NULL DEFAULT 0;
+			ALTER TABLE anime_tracking ADD COLUMN tags TEXT NOT NULL DEFAULT '[]';
+		`,
+	}
+}
+
+// AddAnimeNotesMigration adds a personal note to anime, independent of trackers
+func AddAnimeNotesMigration() Migration {
+	return Migration{
+		Version:     4,
+		Description: "Add notes to anime",
+		SQL: `
+			ALTER TABLE anime ADD COLUMN notes TEXT NOT NULL DEFAULT '';
+		`,
+	}
+}
+
+// AddTrackingRewatchMigration records whether an anime is being watched again
+func AddTrackingRewatchMigration() Migration {
+	return Migration{
+		Version:     5,
+		Description: "Add rewatch flag to anime tracking",
+		SQL: `
+			ALTER TABLE anime_tracking ADD COLUMN is_rewatching BOOLEAN NOT NULL DEFAULT 0;
+		`,
+	}
+}
+
+// AddSyncConflictLogMigration adds a log of the values a sync overwrote
+func AddSyncConflictLogMigration() Migration {
+	return Migration{
+		Version:     6,
+		Description: "Add sync conflict log",
+		SQL: `
+			CREATE TABLE IF NOT EXISTS sync_conflict_log (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				anime_id INTEGER NOT NULL,
+				tracker TEXT NOT NULL,
+				field TEXT NOT NULL, -- status, score or progress
+				local_value TEXT NOT NULL,
+				remote_value TEXT NOT NULL,
+				winner TEXT NOT NULL, -- local or remote
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY (anime_id) REFERENCES anime(id) ON DELETE CASCADE
+			);
+			CREATE INDEX IF NOT EXISTS idx_sync_conflict_log_created_at ON sync_conflict_log (created_at);
+		`,
+	}
+}
+
+// AddSourceEpisodeOffsetMigration adds the difference between the episode
+// numbers of a source and those of the anime, for split-cour seasons
+func AddSourceEpisodeOffsetMigration() Migration {
+	return Migration{
+		Version:     7,
+		Description: "Add episode offset to anime sources",
+		SQL: `
+			ALTER TABLE anime_source ADD COLUMN episode_offset REAL NOT NULL DEFAULT 0;
+		`,
+	}
+}
+
+// AddAnimeMalIDMigration adds the MyAnimeList ID of an anime, as learnt
+// from Anilist, so the same show synced from both services is one anime
+func AddAnimeMalIDMigration() Migration {
+	return Migration{
+		Version:     8,
+		Description: "Add MyAnimeList ID to anime",
+		SQL: `
+			ALTER TABLE anime ADD COLUMN mal_id TEXT NOT NULL DEFAULT '';
+			CREATE INDEX IF NOT EXISTS idx_anime_mal_id ON anime (mal_id);
+		`,
+	}
+}
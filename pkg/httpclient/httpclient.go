@@ -0,0 +1,164 @@
+// Package httpclient provides the HTTP client shared by trackers and scrapers.
+package httpclient
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/wraient/pair/pkg/version"
+)
+
+var (
+	mu     sync.RWMutex
+	shared = &http.Client{}
+
+	// userAgent is sent with requests that don't set their own
+	userAgent = DefaultUserAgent()
+)
+
+// DefaultUserAgent identifies pair and its build version, as in pair/v1.2.3
+func DefaultUserAgent() string {
+	return "pair/" + version.Version
+}
+
+// UserAgent returns the User-Agent sent with requests
+func UserAgent() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return userAgent
+}
+
+// SetUserAgent sets the User-Agent sent with requests. An empty ua restores
+// DefaultUserAgent.
+func SetUserAgent(ua string) {
+	if ua == "" {
+		ua = DefaultUserAgent()
+	}
+	mu.Lock()
+	userAgent = ua
+	mu.Unlock()
+}
+
+// Identify sets the User-Agent of a request pair sends, unless the request
+// already has one
+func Identify(req *http.Request) {
+	if req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", UserAgent())
+	}
+}
+
+// Default returns the shared HTTP client
+func Default() *http.Client {
+	mu.RLock()
+	defer mu.RUnlock()
+	return shared
+}
+
+// Configure replaces the shared HTTP client with one routed through proxyURL
+func Configure(proxyURL string) error {
+	client, err := New(proxyURL)
+	if err != nil {
+		return err
+	}
+
+	mu.Lock()
+	shared = client
+	mu.Unlock()
+	return nil
+}
+
+// New builds an HTTP client that sends requests through proxyURL.
+// An empty proxyURL falls back to the HTTP_PROXY/HTTPS_PROXY environment.
+// The client has no overall timeout, callers bound requests with their context.
+func New(proxyURL string) (*http.Client, error) {
+	proxy, err := ProxyFunc(proxyURL)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxy
+
+	return &http.Client{Transport: transport}, nil
+}
+
+// ProxyFunc returns a transport proxy func for proxyURL that honors NO_PROXY
+func ProxyFunc(proxyURL string) (func(*http.Request) (*url.URL, error), error) {
+	if proxyURL == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+
+	proxy, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL %q: %w", proxyURL, err)
+	}
+	switch proxy.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q, expected http, https or socks5", proxy.Scheme)
+	}
+	if proxy.Host == "" {
+		return nil, fmt.Errorf("proxy URL %q has no host", proxyURL)
+	}
+
+	noProxy := noProxyList()
+	return func(req *http.Request) (*url.URL, error) {
+		if bypassProxy(req.URL.Hostname(), noProxy) {
+			return nil, nil
+		}
+		return proxy, nil
+	}, nil
+}
+
+// noProxyList reads the hosts excluded from proxying from the environment
+func noProxyList() []string {
+	value := os.Getenv("NO_PROXY")
+	if value == "" {
+		value = os.Getenv("no_proxy")
+	}
+
+	var entries []string
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// bypassProxy reports whether host matches one of the NO_PROXY entries
+func bypassProxy(host string, noProxy []string) bool {
+	host = strings.ToLower(host)
+	ip := net.ParseIP(host)
+
+	for _, entry := range noProxy {
+		if entry == "*" {
+			return true
+		}
+
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && network.Contains(ip) {
+				return true
+			}
+			continue
+		}
+
+		// Ports in NO_PROXY entries are ignored
+		if h, _, err := net.SplitHostPort(entry); err == nil {
+			entry = h
+		}
+
+		domain := strings.TrimPrefix(entry, ".")
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,132 @@
+package httpclient
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/wraient/pair/pkg/version"
+)
+
+func TestNewWithProxy(t *testing.T) {
+	t.Setenv("NO_PROXY", "localhost,.internal.example,10.0.0.0/8")
+
+	client, err := New("socks5://127.0.0.1:1080")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	transport, ok := client.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("Expected *http.Transport, got %T", client.Transport)
+	}
+
+	tests := []struct {
+		url     string
+		proxied bool
+	}{
+		{"https://graphql.anilist.co", true},
+		{"http://localhost:8000/callback", false},
+		{"https://api.internal.example/v1", false},
+		{"http://10.1.2.3/stream", false},
+		{"http://192.168.1.1/stream", true},
+	}
+
+	for _, tt := range tests {
+		req, err := http.NewRequest(http.MethodGet, tt.url, nil)
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+
+		proxy, err := transport.Proxy(req)
+		if err != nil {
+			t.Fatalf("Failed to resolve proxy for %s: %v", tt.url, err)
+		}
+		if tt.proxied && (proxy == nil || proxy.String() != "socks5://127.0.0.1:1080") {
+			t.Errorf("Expected %s to use the proxy, got %v", tt.url, proxy)
+		}
+		if !tt.proxied && proxy != nil {
+			t.Errorf("Expected %s to bypass the proxy, got %v", tt.url, proxy)
+		}
+	}
+}
+
+func TestNewInvalidProxy(t *testing.T) {
+	// Test rejecting unsupported schemes and missing hosts
+	for _, proxy := range []string{"ftp://proxy:21", "http://", "://bad"} {
+		if _, err := New(proxy); err == nil {
+			t.Errorf("Expected error for proxy %q", proxy)
+		}
+	}
+}
+
+func TestProber(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	addr := listener.Addr().String()
+
+	prober := NewProber(time.Second, time.Minute)
+	if !prober.Reachable(context.Background(), addr) {
+		t.Errorf("Expected %s to be reachable", addr)
+	}
+
+	// Test that the result is cached after the host goes away
+	listener.Close()
+	if !prober.Reachable(context.Background(), addr) {
+		t.Errorf("Expected cached result for %s", addr)
+	}
+
+	// Test that a fresh prober notices the host is down
+	if NewProber(time.Second, time.Minute).Reachable(context.Background(), addr) {
+		t.Errorf("Expected %s to be unreachable", addr)
+	}
+}
+
+func TestProbeAddr(t *testing.T) {
+	tests := []struct {
+		url, proxy, expected string
+	}{
+		{"https://graphql.anilist.co", "", "graphql.anilist.co:443"},
+		{"http://127.0.0.1:8080/v2", "", "127.0.0.1:8080"},
+		{"https://api.myanimelist.net/v2", "socks5://proxy.local", "proxy.local:1080"},
+		{"", "", ""},
+	}
+
+	for _, tt := range tests {
+		if got := ProbeAddr(tt.url, tt.proxy); got != tt.expected {
+			t.Errorf("Expected %q for %q via %q, got %q", tt.expected, tt.url, tt.proxy, got)
+		}
+	}
+}
+
+func TestIdentify(t *testing.T) {
+	defer SetUserAgent("")
+
+	req, err := http.NewRequest(http.MethodGet, "https://graphql.anilist.co", nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+
+	// Test that requests are sent as pair with its version
+	Identify(req)
+	if agent := req.Header.Get("User-Agent"); agent != "pair/"+version.Version {
+		t.Errorf("Expected User-Agent pair/%s, got %q", version.Version, agent)
+	}
+
+	// Test that a configured User-Agent is used and one already set is kept
+	SetUserAgent("custom/1.0")
+	req.Header.Del("User-Agent")
+	Identify(req)
+	if agent := req.Header.Get("User-Agent"); agent != "custom/1.0" {
+		t.Errorf("Expected User-Agent custom/1.0, got %q", agent)
+	}
+	req.Header.Set("User-Agent", "stream-headers")
+	Identify(req)
+	if agent := req.Header.Get("User-Agent"); agent != "stream-headers" {
+		t.Errorf("Expected User-Agent to be kept, got %q", agent)
+	}
+}
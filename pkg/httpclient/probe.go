@@ -0,0 +1,85 @@
+package httpclient
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// probeResult is a cached connectivity check
+type probeResult struct {
+	reachable bool
+	checkedAt time.Time
+}
+
+// Prober checks whether hosts can be reached with a short TCP dial.
+// Results are cached for a while so repeated checks don't stall the UI.
+type Prober struct {
+	timeout time.Duration
+	ttl     time.Duration
+
+	mu      sync.Mutex
+	results map[string]probeResult
+}
+
+// NewProber creates a Prober that gives up dialing after timeout and reuses results for ttl
+func NewProber(timeout, ttl time.Duration) *Prober {
+	return &Prober{
+		timeout: timeout,
+		ttl:     ttl,
+		results: make(map[string]probeResult),
+	}
+}
+
+// Reachable reports whether a TCP connection to addr (host:port) can be opened
+func (p *Prober) Reachable(ctx context.Context, addr string) bool {
+	p.mu.Lock()
+	result, ok := p.results[addr]
+	p.mu.Unlock()
+	if ok && time.Since(result.checkedAt) < p.ttl {
+		return result.reachable
+	}
+
+	dialCtx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(dialCtx, "tcp", addr)
+	if err == nil {
+		conn.Close()
+	}
+
+	p.mu.Lock()
+	p.results[addr] = probeResult{reachable: err == nil, checkedAt: time.Now()}
+	p.mu.Unlock()
+
+	return err == nil
+}
+
+// ProbeAddr returns the address to probe for reaching rawURL. When a proxy is
+// configured, connections go through it, so the proxy itself is probed instead.
+func ProbeAddr(rawURL, proxyURL string) string {
+	target := rawURL
+	if proxyURL != "" {
+		target = proxyURL
+	}
+
+	u, err := url.Parse(target)
+	if err != nil || u.Hostname() == "" {
+		return ""
+	}
+	if port := u.Port(); port != "" {
+		return net.JoinHostPort(u.Hostname(), port)
+	}
+
+	port := "443"
+	switch u.Scheme {
+	case "http":
+		port = "80"
+	case "socks5":
+		port = "1080"
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
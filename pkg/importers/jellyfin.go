@@ -0,0 +1,252 @@
+// Package importers brings watch history from other apps into the library.
+package importers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/wraient/pair/pkg/database"
+	"github.com/wraient/pair/pkg/httpclient"
+	"github.com/wraient/pair/pkg/matcher"
+	"github.com/wraient/pair/pkg/tracker"
+)
+
+// jellyfinTimeout bounds each request to a Jellyfin server
+const jellyfinTimeout = 30 * time.Second
+
+// jellyfinUser is a user account on a Jellyfin server
+type jellyfinUser struct {
+	ID   string `json:"Id"`
+	Name string `json:"Name"`
+}
+
+// jellyfinEpisode is a played episode as listed by Jellyfin
+type jellyfinEpisode struct {
+	SeriesName        string `json:"SeriesName"`
+	IndexNumber       int    `json:"IndexNumber"`       // Episode number within the season
+	ParentIndexNumber *int   `json:"ParentIndexNumber"` // Season number, 0 for specials
+	UserData          struct {
+		Played         bool      `json:"Played"`
+		LastPlayedDate time.Time `json:"LastPlayedDate"`
+	} `json:"UserData"`
+}
+
+// jellyfinSeries collects the watched episodes of one season of a show
+type jellyfinSeries struct {
+	title      string
+	episodes   []float64
+	lastPlayed time.Time
+}
+
+// ImportWatchState marks the episodes played on a Jellyfin server as watched
+// in the library and advances the tracking of each anime to its last played
+// episode. Shows are matched to the library by title; seasons after the
+// first are matched as "<show> Season N", and specials are left out. Shows
+// without a confident match are skipped and listed in the details.
+//
+// Played state is per user. user picks one by name or ID; when it is empty
+// the first user, the server's owner, is used.
+func ImportWatchState(ctx context.Context, db *database.DB, serverURL, apiKey, user string) (tracker.SyncStats, error) {
+	var stats tracker.SyncStats
+	serverURL = strings.TrimRight(serverURL, "/")
+
+	var users []jellyfinUser
+	if err := jellyfinGet(ctx, serverURL, apiKey, "/Users", nil, &users); err != nil {
+		return stats, fmt.Errorf("failed to get Jellyfin users: %w", err)
+	}
+	selected, err := findUser(users, user)
+	if err != nil {
+		return stats, err
+	}
+
+	var played struct {
+		Items []jellyfinEpisode `json:"Items"`
+	}
+	query := url.Values{
+		"Recursive":        {"true"},
+		"IncludeItemTypes": {"Episode"},
+		"Filters":          {"IsPlayed"},
+	}
+	if err := jellyfinGet(ctx, serverURL, apiKey, "/Users/"+url.PathEscape(selected.ID)+"/Items", query, &played); err != nil {
+		return stats, fmt.Errorf("failed to get played episodes: %w", err)
+	}
+
+	animes, err := db.GetAllAnime()
+	if err != nil {
+		return stats, fmt.Errorf("failed to get anime: %w", err)
+	}
+
+	for _, series := range groupSeries(played.Items) {
+		anime := matchAnime(series.title, animes)
+		if anime == nil {
+			stats.Skipped++
+			stats.Details = append(stats.Details, "No match for "+series.title)
+			continue
+		}
+
+		marked, err := db.MarkEpisodesWatched(anime.ID, series.episodes, series.lastPlayed)
+		if err != nil {
+			stats.Errors++
+			stats.Details = append(stats.Details, fmt.Sprintf("Failed to mark episodes of %s: %s", anime.Title, err))
+			continue
+		}
+
+		advanced, err := advanceTracking(db, anime.ID, series.episodes[len(series.episodes)-1])
+		if err != nil {
+			stats.Errors++
+			stats.Details = append(stats.Details, fmt.Sprintf("Failed to update tracking of %s: %s", anime.Title, err))
+			continue
+		}
+
+		if marked > 0 || advanced {
+			stats.Updated++
+			stats.Details = append(stats.Details, fmt.Sprintf("%s: %d episodes marked watched", anime.Title, marked))
+		}
+	}
+
+	return stats, nil
+}
+
+// findUser returns the user named or with the ID user, or the first one when
+// user is empty
+func findUser(users []jellyfinUser, user string) (jellyfinUser, error) {
+	if len(users) == 0 {
+		return jellyfinUser{}, fmt.Errorf("the Jellyfin server has no users")
+	}
+	if user == "" {
+		return users[0], nil
+	}
+
+	names := make([]string, len(users))
+	for i, u := range users {
+		if u.ID == user || strings.EqualFold(u.Name, user) {
+			return u, nil
+		}
+		names[i] = u.Name
+	}
+	return jellyfinUser{}, fmt.Errorf("no Jellyfin user %q, the server has %s", user, strings.Join(names, ", "))
+}
+
+// jellyfinGet sends an authenticated GET request and decodes the JSON answer into v
+func jellyfinGet(ctx context.Context, serverURL, apiKey, path string, query url.Values, v interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, jellyfinTimeout)
+	defer cancel()
+
+	u := serverURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	httpclient.Identify(req)
+	req.Header.Set("X-Emby-Token", apiKey)
+
+	resp, err := httpclient.Default().Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("server returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// groupSeries groups played episodes by show and season, with the episodes
+// of each in order. Specials are skipped, their numbers would mark regular
+// episodes watched. Episodes without a season count as the first.
+func groupSeries(episodes []jellyfinEpisode) []*jellyfinSeries {
+	byTitle := make(map[string]*jellyfinSeries)
+	var order []string
+	for _, episode := range episodes {
+		if episode.SeriesName == "" || episode.IndexNumber <= 0 {
+			continue
+		}
+		season := 1
+		if episode.ParentIndexNumber != nil {
+			season = *episode.ParentIndexNumber
+		}
+		if season == 0 {
+			continue
+		}
+		title := episode.SeriesName
+		if season > 1 {
+			title = fmt.Sprintf("%s Season %d", title, season)
+		}
+
+		series, ok := byTitle[title]
+		if !ok {
+			series = &jellyfinSeries{title: title}
+			byTitle[title] = series
+			order = append(order, title)
+		}
+		series.episodes = append(series.episodes, float64(episode.IndexNumber))
+		if episode.UserData.LastPlayedDate.After(series.lastPlayed) {
+			series.lastPlayed = episode.UserData.LastPlayedDate
+		}
+	}
+
+	grouped := make([]*jellyfinSeries, len(order))
+	for i, title := range order {
+		series := byTitle[title]
+		sort.Float64s(series.episodes)
+		if series.lastPlayed.IsZero() {
+			series.lastPlayed = time.Now()
+		}
+		grouped[i] = series
+	}
+	return grouped
+}
+
+// matchAnime returns the library anime whose titles are closest to title,
+// or nil when none scores above matcher.DefaultThreshold
+func matchAnime(title string, animes []*database.Anime) *database.Anime {
+	var best *database.Anime
+	bestScore := 0.0
+	for _, anime := range animes {
+		titles := append([]string{anime.Title, anime.OriginalTitle}, anime.AlternativeTitles...)
+		if _, score := matcher.BestMatch(title, titles); score > bestScore {
+			best, bestScore = anime, score
+		}
+	}
+	if bestScore < matcher.DefaultThreshold {
+		return nil
+	}
+	return best
+}
+
+// advanceTracking raises the progress of every tracking entry of an anime
+// that is behind episode. It reports whether any entry changed.
+func advanceTracking(db *database.DB, animeID int64, episode float64) (bool, error) {
+	trackings, err := db.GetAllAnimeTracking(animeID)
+	if err != nil {
+		return false, err
+	}
+
+	advanced := false
+	for _, tracking := range trackings {
+		if tracking.CurrentEpisode >= episode {
+			continue
+		}
+		tracking.CurrentEpisode = episode
+		if err := db.UpdateAnimeTrackingObject(tracking); err != nil {
+			return advanced, err
+		}
+		advanced = true
+	}
+	return advanced, nil
+}
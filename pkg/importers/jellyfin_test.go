@@ -0,0 +1,99 @@
+package importers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/wraient/pair/pkg/database"
+)
+
+// jellyfinPlayed is the played episodes of the fake Jellyfin server
+const jellyfinPlayed = `{"Items":[
+	{"SeriesName":"Frieren: Beyond Journey's End","IndexNumber":2,"ParentIndexNumber":1,"UserData":{"Played":true,"LastPlayedDate":"2024-03-02T20:00:00Z"}},
+	{"SeriesName":"Frieren: Beyond Journey's End","IndexNumber":1,"ParentIndexNumber":1,"UserData":{"Played":true,"LastPlayedDate":"2024-03-01T20:00:00Z"}},
+	{"SeriesName":"Frieren: Beyond Journey's End","IndexNumber":5,"ParentIndexNumber":0,"UserData":{"Played":true,"LastPlayedDate":"2024-03-03T20:00:00Z"}},
+	{"SeriesName":"Some Live Action Show","IndexNumber":1,"ParentIndexNumber":1,"UserData":{"Played":true}}
+],"TotalRecordCount":4}`
+
+func TestImportWatchState(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "pair-test.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	anime := &database.Anime{Title: "Sousou no Frieren", AlternativeTitles: []string{"Frieren: Beyond Journey's End"}, TotalEpisodes: 28}
+	if err := db.AddAnime(anime); err != nil {
+		t.Fatalf("Failed to add anime: %v", err)
+	}
+	if err := db.AddAnimeTracking(&database.AnimeTracking{AnimeID: anime.ID, Tracker: "anilist", TrackerID: "154587", Status: "watching", CurrentEpisode: 1}); err != nil {
+		t.Fatalf("Failed to add tracking: %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Emby-Token") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/Users":
+			io.WriteString(w, `[{"Id":"user-0","Name":"owner"},{"Id":"user-1","Name":"Viewer"}]`)
+		case "/Users/user-0/Items":
+			io.WriteString(w, `{"Items":[],"TotalRecordCount":0}`)
+		case "/Users/user-1/Items":
+			if r.URL.Query().Get("Filters") != "IsPlayed" {
+				t.Errorf("Expected played episodes to be requested, got %s", r.URL.RawQuery)
+			}
+			io.WriteString(w, jellyfinPlayed)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	// Test that the matched show of the chosen user is imported and the other one skipped
+	stats, err := ImportWatchState(context.Background(), db, server.URL+"/", "secret", "viewer")
+	if err != nil {
+		t.Fatalf("Failed to import watch state: %v", err)
+	}
+	if stats.Updated != 1 || stats.Skipped != 1 {
+		t.Errorf("Expected 1 updated and 1 skipped, got %+v", stats)
+	}
+
+	for _, number := range []float64{1, 2} {
+		progress, err := db.GetEpisodeProgress(anime.ID, number)
+		if err != nil {
+			t.Fatalf("Failed to get progress of episode %g: %v", number, err)
+		}
+		if !progress.Watched {
+			t.Errorf("Expected episode %g to be watched", number)
+		}
+	}
+
+	// Test that the special didn't mark the regular episode 5 watched
+	if progress, _ := db.GetEpisodeProgress(anime.ID, 5); progress != nil && progress.Watched {
+		t.Errorf("Expected episode 5 not to be watched")
+	}
+
+	tracking, err := db.GetAnimeTracking(anime.ID, "anilist")
+	if err != nil {
+		t.Fatalf("Failed to get tracking: %v", err)
+	}
+	if tracking.CurrentEpisode != 2 {
+		t.Errorf("Expected progress 2, got %g", tracking.CurrentEpisode)
+	}
+
+	// Test that a wrong API key is reported
+	if _, err := ImportWatchState(context.Background(), db, server.URL, "wrong", ""); err == nil {
+		t.Errorf("Expected an error for a wrong API key")
+	}
+
+	// Test that an unknown user is reported
+	if _, err := ImportWatchState(context.Background(), db, server.URL, "secret", "nobody"); err == nil {
+		t.Errorf("Expected an error for an unknown user")
+	}
+}
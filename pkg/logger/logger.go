@@ -11,16 +11,36 @@ import (
 
 var log *zap.Logger
 
+// console controls whether messages are also printed to the console
+var console = true
+
+// logDir overrides where the log file is written when set
+var logDir string
+
+// SetLogDir makes Initialize write the log file to dir
+func SetLogDir(dir string) {
+	logDir = dir
+}
+
+// SetConsoleOutput switches printing log messages to the console on or off.
+// Messages are still written to the log file.
+func SetConsoleOutput(enabled bool) {
+	console = enabled
+}
+
 // Initialize creates a new logger instance with the given configuration
 func Initialize(development bool) error {
 	// Ensure the logs directory exists
-	logDir := filepath.Join(os.ExpandEnv("$HOME"), ".config", "pair", "logs")
-	if err := os.MkdirAll(logDir, 0755); err != nil {
+	dir := logDir
+	if dir == "" {
+		dir = filepath.Join(os.ExpandEnv("$HOME"), ".config", "pair", "logs")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
 		return fmt.Errorf("failed to create log directory: %v", err)
 	}
 
 	// Configure the file logger
-	logPath := filepath.Join(logDir, "app.log")
+	logPath := filepath.Join(dir, "app.log")
 
 	// Remove old log file if it exists
 	if err := os.Remove(logPath); err != nil && !os.IsNotExist(err) {
@@ -69,8 +89,17 @@ func GetLogger() *zap.Logger {
 	return log
 }
 
-// Debug logs a debug message to file only
+// SetLogger replaces the global logger instance, e.g. with an observer in tests
+func SetLogger(l *zap.Logger) {
+	log = l
+}
+
+// Debug logs a debug message to file only. Debug output is dropped before
+// Initialize is called.
 func Debug(msg string, fields ...zap.Field) {
+	if log == nil {
+		return
+	}
 	log.Debug(msg, fields...)
 }
 
@@ -127,6 +156,9 @@ func formatFields(fields []zap.Field) []string {
 
 // printConsoleInfo prints a nicely formatted info message to the console
 func printConsoleInfo(symbol, msg string, fields ...zap.Field) {
+	if !console {
+		return
+	}
 	fmt.Printf("\x1b[36mInfo:\x1b[0m %s\n", msg)
 	if len(fields) > 0 {
 		for _, line := range formatFields(fields) {
@@ -137,6 +169,9 @@ func printConsoleInfo(symbol, msg string, fields ...zap.Field) {
 
 // printConsoleWarn prints a nicely formatted warning message to the console
 func printConsoleWarn(msg string, fields ...zap.Field) {
+	if !console {
+		return
+	}
 	fmt.Printf("\x1b[33mWarn:\x1b[0m %s\n", msg)
 	if len(fields) > 0 {
 		for _, line := range formatFields(fields) {
@@ -147,6 +182,9 @@ func printConsoleWarn(msg string, fields ...zap.Field) {
 
 // printConsoleError prints a nicely formatted error message to the console
 func printConsoleError(msg string, fields ...zap.Field) {
+	if !console {
+		return
+	}
 	fmt.Printf("\x1b[31m✗ Error:\x1b[0m %s\n", msg)
 	if len(fields) > 0 {
 		fmt.Println("\x1b[90mDetails:\x1b[0m")
@@ -158,6 +196,9 @@ func printConsoleError(msg string, fields ...zap.Field) {
 
 // printConsoleFatal prints a nicely formatted fatal message to the console
 func printConsoleFatal(msg string, fields ...zap.Field) {
+	if !console {
+		return
+	}
 	fmt.Printf("\x1b[31;1m✗ FATAL:\x1b[0m %s\n", msg)
 	if len(fields) > 0 {
 		fmt.Println("\x1b[90mDetails:\x1b[0m")
@@ -0,0 +1,122 @@
+// Package matcher finds the same anime under the slightly different titles
+// used by trackers and scraper sources.
+package matcher
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// DefaultThreshold is the score from which a match is trusted without asking
+const DefaultThreshold = 0.9
+
+// numberMismatchPenalty scales down titles whose season or part numbers
+// differ, so a sequel doesn't match the first season
+const numberMismatchPenalty = 0.8
+
+// macrons maps long vowels to the plain vowel, so Shōjo matches Shojo
+var macrons = strings.NewReplacer(
+	"ā", "a", "ē", "e", "ī", "i", "ō", "o", "ū", "u",
+	"â", "a", "ê", "e", "î", "i", "ô", "o", "û", "u",
+)
+
+// longVowels spells out long vowels written with a doubled letter the way
+// the macron variant normalizes, so Shoujo and Shōjo both become shojo
+var longVowels = strings.NewReplacer("ou", "o", "uu", "u", "oo", "o", "aa", "a", "ii", "i", "ee", "e")
+
+var (
+	// "2nd Season" and "Season 2" both become "2"
+	ordinalSeason = regexp.MustCompile(`\b(\d+)(?:st|nd|rd|th) season\b`)
+	namedSeason   = regexp.MustCompile(`\b(?:season|part|cour) (\d+)\b`)
+	shortSeason   = regexp.MustCompile(`\bs(\d+)\b`)
+	numbers       = regexp.MustCompile(`\d+`)
+)
+
+// Normalize reduces a title to a canonical form for comparison: lower case,
+// without macrons, punctuation and season wording, and with the particle
+// "wo" written as "o".
+func Normalize(title string) string {
+	title = macrons.Replace(strings.ToLower(title))
+
+	// Punctuation separates words like spaces do
+	title = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return ' '
+	}, title)
+	title = strings.Join(strings.Fields(title), " ")
+
+	title = ordinalSeason.ReplaceAllString(title, "$1")
+	title = namedSeason.ReplaceAllString(title, "$1")
+	title = shortSeason.ReplaceAllString(title, "$1")
+
+	words := strings.Fields(title)
+	for i, word := range words {
+		if word == "wo" {
+			words[i] = "o"
+			continue
+		}
+		words[i] = longVowels.Replace(word)
+	}
+	return strings.Join(words, " ")
+}
+
+// Score rates how similar two titles are, from 0 for nothing in common to 1
+// for titles that are the same once normalized
+func Score(a, b string) float64 {
+	a, b = Normalize(a), Normalize(b)
+	if a == "" || b == "" {
+		return 0
+	}
+	if a == b {
+		return 1
+	}
+
+	ra, rb := []rune(a), []rune(b)
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	score := 1 - float64(levenshtein(ra, rb))/float64(longest)
+
+	if strings.Join(numbers.FindAllString(a, -1), " ") != strings.Join(numbers.FindAllString(b, -1), " ") {
+		score *= numberMismatchPenalty
+	}
+	return score
+}
+
+// BestMatch returns the index of the candidate most similar to target and its
+// score. It returns -1 when there are no candidates.
+func BestMatch(target string, candidates []string) (index int, score float64) {
+	index = -1
+	for i, candidate := range candidates {
+		if s := Score(target, candidate); index == -1 || s > score {
+			index, score = i, s
+		}
+	}
+	return index, score
+}
+
+// levenshtein returns the edit distance between two strings
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,76 @@
+package matcher
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]string{
+		"Kimetsu no Yaiba: Yūkaku-hen":           "kimetsu no yaiba yukaku hen",
+		"Kimetsu no Yaiba: Yuukaku-hen":          "kimetsu no yaiba yukaku hen",
+		"Shoujo Shūmatsu Ryokou":                 "shojo shumatsu ryoko",
+		"Kono Subarashii Sekai ni Shukufuku wo!": "kono subarashi sekai ni shukufuku o",
+		"Attack on Titan Season 2":               "attack on titan 2",
+		"Attack on Titan 2nd Season":             "attack on titan 2",
+		"Attack on Titan S2":                     "attack on titan 2",
+	}
+	for title, want := range tests {
+		if got := Normalize(title); got != want {
+			t.Errorf("Expected %q for %q, got %q", want, title, got)
+		}
+	}
+}
+
+func TestBestMatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		target     string
+		candidates []string
+		want       int
+	}{
+		{
+			name:       "macrons",
+			target:     "Kimetsu no Yaiba: Yūkaku-hen",
+			candidates: []string{"Kimetsu no Yaiba", "Kimetsu no Yaiba: Yuukaku-hen", "Kimetsu no Yaiba: Mugen Ressha-hen"},
+			want:       1,
+		},
+		{
+			name:       "wo and o",
+			target:     "Kono Subarashii Sekai ni Shukufuku wo!",
+			candidates: []string{"Kono Subarashii Sekai ni Bakuen wo!", "Kono Subarashii Sekai ni Shukufuku o!"},
+			want:       1,
+		},
+		{
+			name:       "season suffixes",
+			target:     "Kaguya-sama wa Kokurasetai Season 2",
+			candidates: []string{"Kaguya-sama wa Kokurasetai", "Kaguya-sama wa Kokurasetai 2nd Season", "Kaguya-sama wa Kokurasetai: Ultra Romantic"},
+			want:       1,
+		},
+		{
+			name:       "first season over sequel",
+			target:     "Mushoku Tensei",
+			candidates: []string{"Mushoku Tensei II", "Mushoku Tensei Season 2", "Mushoku Tensei"},
+			want:       2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			index, score := BestMatch(tt.target, tt.candidates)
+			if index != tt.want {
+				t.Errorf("Expected candidate %d, got %d (score %.2f)", tt.want, index, score)
+			}
+			if score < DefaultThreshold {
+				t.Errorf("Expected a score above the threshold, got %.2f", score)
+			}
+		})
+	}
+
+	// Test that a sequel alone isn't trusted as a match for the first season
+	if _, score := BestMatch("Attack on Titan", []string{"Attack on Titan Season 3"}); score >= DefaultThreshold {
+		t.Errorf("Expected a score below the threshold for a sequel, got %.2f", score)
+	}
+
+	// Test that there is no match without candidates
+	if index, _ := BestMatch("Anything", nil); index != -1 {
+		t.Errorf("Expected -1 without candidates, got %d", index)
+	}
+}
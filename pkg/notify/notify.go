@@ -0,0 +1,28 @@
+package notify
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strconv"
+)
+
+// Send shows a desktop notification, using notify-send on Linux and the BSDs
+// and osascript on macOS
+func Send(title, message string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		script := fmt.Sprintf("display notification %s with title %s", strconv.Quote(message), strconv.Quote(title))
+		cmd = exec.Command("osascript", "-e", script)
+	case "windows":
+		return fmt.Errorf("desktop notifications are not supported on %s", runtime.GOOS)
+	default:
+		cmd = exec.Command("notify-send", "--app-name=pair", title, message)
+	}
+
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to send notification: %w: %s", err, output)
+	}
+	return nil
+}
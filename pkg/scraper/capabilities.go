@@ -0,0 +1,82 @@
+package scraper
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrUnsupported is returned for operations a source says it doesn't support
+var ErrUnsupported = errors.New("not supported by this source")
+
+// Capabilities caches the SourceInfo of sources so each one is only asked
+// what it supports once. The zero value is ready to use.
+type Capabilities struct {
+	mu    sync.Mutex
+	infos map[string]SourceInfo
+}
+
+// Get returns the source info of the source known as key, asking the scraper
+// the first time. Sources whose info can't be read are assumed to support
+// every operation so older extensions keep working.
+func (c *Capabilities) Get(key string, s Scraper) SourceInfo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if info, ok := c.infos[key]; ok {
+		return info
+	}
+
+	info, err := s.GetSourceInfo()
+	if err != nil {
+		info = SourceInfo{SupportsLatest: true, SupportsSearch: true, SupportsRelatedAnime: true}
+	}
+	if c.infos == nil {
+		c.infos = make(map[string]SourceInfo)
+	}
+	c.infos[key] = info
+	return info
+}
+
+// Checked wraps a scraper so the optional operations its source doesn't
+// support fail with ErrUnsupported instead of calling the extension
+func (c *Capabilities) Checked(key string, s Scraper) Scraper {
+	return &checkedScraper{Scraper: s, key: key, caps: c}
+}
+
+// checkedScraper is a Scraper that checks the capabilities of its source
+// before latest updates, searches and related anime. Its source info comes
+// from the cache.
+type checkedScraper struct {
+	Scraper
+	key  string
+	caps *Capabilities
+}
+
+// GetSourceInfo returns the cached source info
+func (s *checkedScraper) GetSourceInfo() (SourceInfo, error) {
+	return s.caps.Get(s.key, s.Scraper), nil
+}
+
+// GetLatestUpdates retrieves the latest anime updates if the source has them
+func (s *checkedScraper) GetLatestUpdates(page int) (AnimePage, error) {
+	if !s.caps.Get(s.key, s.Scraper).SupportsLatest {
+		return AnimePage{}, ErrUnsupported
+	}
+	return s.Scraper.GetLatestUpdates(page)
+}
+
+// SearchAnime searches the source if it supports searching
+func (s *checkedScraper) SearchAnime(query string, page int, filters string) (AnimePage, error) {
+	if !s.caps.Get(s.key, s.Scraper).SupportsSearch {
+		return AnimePage{}, ErrUnsupported
+	}
+	return s.Scraper.SearchAnime(query, page, filters)
+}
+
+// GetRelatedAnime retrieves related anime if the source has them
+func (s *checkedScraper) GetRelatedAnime(animeID string, page int) ([]Anime, error) {
+	if !s.caps.Get(s.key, s.Scraper).SupportsRelatedAnime {
+		return nil, ErrUnsupported
+	}
+	return s.Scraper.GetRelatedAnime(animeID, page)
+}
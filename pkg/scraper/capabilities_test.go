@@ -0,0 +1,59 @@
+package scraper
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCapabilitiesSkipUnsupported(t *testing.T) {
+	calls := filepath.Join(t.TempDir(), "calls")
+	binary := writeFakeExtension(t,
+		"echo \"$1\" >> "+calls+"\n"+
+			"case \"$1\" in\n"+
+			"  source-info) echo '{\"status\":\"success\",\"data\":{\"id\":\"source\",\"supportsLatest\":true,\"supportsSearch\":true,\"supportsRelatedAnime\":false}}' ;;\n"+
+			"  *) echo '{\"status\":\"success\",\"data\":[{\"anime_id\":\"a-1\",\"title\":\"Found\"}]}' ;;\n"+
+			"esac\n")
+
+	var caps Capabilities
+	s := caps.Checked("fake/source", NewCLIScraper(binary, "source"))
+
+	// Test that related anime are skipped for a source without them
+	if _, err := s.GetRelatedAnime("a-1", 1); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Expected ErrUnsupported, got %v", err)
+	}
+
+	// Test that supported operations still reach the extension
+	page, err := s.SearchAnime("found", 1, "")
+	if err != nil {
+		t.Fatalf("Failed to search: %v", err)
+	}
+	if len(page.Animes) != 1 {
+		t.Errorf("Expected one result, got %+v", page.Animes)
+	}
+	if _, err := s.GetLatestUpdates(1); err != nil {
+		t.Errorf("Failed to get latest updates: %v", err)
+	}
+
+	// Test that the source info is only asked for once
+	content, err := os.ReadFile(calls)
+	if err != nil {
+		t.Fatalf("Failed to read calls: %v", err)
+	}
+	if got := strings.Fields(string(content)); strings.Join(got, " ") != "source-info search latest" {
+		t.Errorf("Expected source-info, search and latest calls, got %v", got)
+	}
+}
+
+func TestCapabilitiesWithoutSourceInfo(t *testing.T) {
+	binary := writeFakeExtension(t, "echo '{\"status\":\"error\",\"error\":\"unknown command\"}'\n")
+
+	// Test that a source without source info is assumed to support everything
+	var caps Capabilities
+	info := caps.Get("fake/source", NewCLIScraper(binary, "source"))
+	if !info.SupportsLatest || !info.SupportsSearch || !info.SupportsRelatedAnime {
+		t.Errorf("Expected every operation to be supported, got %+v", info)
+	}
+}
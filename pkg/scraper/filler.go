@@ -0,0 +1,87 @@
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/wraient/pair/pkg/httpclient"
+)
+
+// fillerListURL is the Jikan endpoint listing a show's episodes with their filler flags.
+// Jikan mirrors MyAnimeList, so shows are keyed by MAL ID.
+var fillerListURL = "https://api.jikan.moe/v4/anime/%d/episodes?page=%d"
+
+// fillerResponse is a single page of the filler list
+type fillerResponse struct {
+	Pagination struct {
+		HasNextPage bool `json:"has_next_page"`
+	} `json:"pagination"`
+	Data []struct {
+		Number float64 `json:"mal_id"`
+		Filler bool    `json:"filler"`
+	} `json:"data"`
+}
+
+// FetchFillerList fetches the filler flags of every episode of the anime with the given MAL ID.
+// The returned map has an entry for every known episode, set to true for fillers.
+func FetchFillerList(ctx context.Context, malID int) (map[float64]bool, error) {
+	fillers := make(map[float64]bool)
+
+	for page := 1; ; page++ {
+		req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf(fillerListURL, malID, page), nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		httpclient.Identify(req)
+
+		resp, err := httpclient.Default().Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch filler list: %w", err)
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read filler list: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("filler list request failed with status %d", resp.StatusCode)
+		}
+
+		hasNext, err := parseFillerList(body, fillers)
+		if err != nil {
+			return nil, err
+		}
+		if !hasNext {
+			break
+		}
+	}
+
+	return fillers, nil
+}
+
+// parseFillerList adds the episodes of a filler list page to fillers and
+// reports whether there are more pages
+func parseFillerList(data []byte, fillers map[float64]bool) (bool, error) {
+	var response fillerResponse
+	if err := json.Unmarshal(data, &response); err != nil {
+		return false, fmt.Errorf("failed to parse filler list: %w", err)
+	}
+
+	for _, episode := range response.Data {
+		fillers[episode.Number] = episode.Filler
+	}
+
+	return response.Pagination.HasNextPage, nil
+}
+
+// AdvancePastFillers moves an episode number forward over any filler episodes directly after it
+func AdvancePastFillers(episode float64, fillers map[float64]bool) float64 {
+	for fillers[episode+1] {
+		episode++
+	}
+	return episode
+}
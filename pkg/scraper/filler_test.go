@@ -0,0 +1,52 @@
+package scraper
+
+import (
+	"testing"
+)
+
+const sampleFillerPage = `{
+	"pagination": {"last_visible_page": 2, "has_next_page": true},
+	"data": [
+		{"mal_id": 1, "title": "Enter: Naruto Uzumaki!", "filler": false, "recap": false},
+		{"mal_id": 26, "title": "Special Report", "filler": true, "recap": true},
+		{"mal_id": 27, "title": "The Chunin Exam Begins", "filler": false, "recap": false}
+	]
+}`
+
+func TestParseFillerList(t *testing.T) {
+	fillers := make(map[float64]bool)
+
+	hasNext, err := parseFillerList([]byte(sampleFillerPage), fillers)
+	if err != nil {
+		t.Fatalf("Failed to parse filler list: %v", err)
+	}
+	if !hasNext {
+		t.Errorf("Expected another page to be reported")
+	}
+
+	if len(fillers) != 3 {
+		t.Errorf("Expected 3 episodes, got %d", len(fillers))
+	}
+	if !fillers[26] {
+		t.Errorf("Expected episode 26 to be a filler")
+	}
+	if fillers[1] || fillers[27] {
+		t.Errorf("Expected episodes 1 and 27 not to be fillers")
+	}
+
+	// Test rejecting a malformed response
+	if _, err := parseFillerList([]byte("<html>"), fillers); err == nil {
+		t.Errorf("Expected error for malformed filler list")
+	}
+}
+
+func TestAdvancePastFillers(t *testing.T) {
+	fillers := map[float64]bool{10: false, 11: true, 12: true, 13: false}
+
+	if got := AdvancePastFillers(10, fillers); got != 12 {
+		t.Errorf("Expected episode 12, got %v", got)
+	}
+	if got := AdvancePastFillers(12, fillers); got != 12 {
+		t.Errorf("Expected episode 12, got %v", got)
+	}
+}
@@ -0,0 +1,184 @@
+package scraper
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/wraient/pair/pkg/httpclient"
+)
+
+// pluginRequestTimeout bounds a single call to a plugin server
+const pluginRequestTimeout = 60 * time.Second
+
+// pluginRequest is the JSON body sent to a plugin server. It carries the
+// same arguments the CLI extensions take as flags.
+type pluginRequest struct {
+	Source  string   `json:"source,omitempty"`
+	Page    int      `json:"page,omitempty"`
+	Query   string   `json:"query,omitempty"`
+	Filters string   `json:"filters,omitempty"`
+	Anime   string   `json:"anime,omitempty"`
+	Episode *float64 `json:"episode,omitempty"` // a pointer so episode 0 is sent
+}
+
+// HTTPScraper talks to an extension running as a long-lived local plugin
+// server, which avoids starting a process for every call. Each call POSTs a
+// pluginRequest to BaseURL/<command>, where the commands are those of the
+// CLI extensions, and the server answers with the same CLIOutput envelope.
+type HTTPScraper struct {
+	BaseURL  string
+	SourceID string
+	Client   *http.Client
+}
+
+// NewHTTPScraper creates a scraper for a source served by the plugin server at baseURL
+func NewHTTPScraper(baseURL string, sourceID string) *HTTPScraper {
+	return &HTTPScraper{
+		BaseURL:  strings.TrimRight(baseURL, "/"),
+		SourceID: sourceID,
+		Client:   &http.Client{Timeout: pluginRequestTimeout},
+	}
+}
+
+// call sends a command to the plugin server and returns its response
+func (h *HTTPScraper) call(command string, req pluginRequest) (CLIOutput, error) {
+	output := CLIOutput{source: h.SourceID, command: command}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return output, fmt.Errorf("failed to encode request: %s", err)
+	}
+
+	httpReq, err := http.NewRequest("POST", h.BaseURL+"/"+command, bytes.NewReader(body))
+	if err != nil {
+		return output, fmt.Errorf("failed to create request: %s", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpclient.Identify(httpReq)
+
+	resp, err := h.Client.Do(httpReq)
+	if err != nil {
+		return output, fmt.Errorf("plugin request failed: %s", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return output, fmt.Errorf("failed to read plugin response: %s", err)
+	}
+	if err := json.Unmarshal(data, &output); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return output, fmt.Errorf("plugin request failed: %s (%d)", strings.TrimSpace(string(data)), resp.StatusCode)
+		}
+		return output, fmt.Errorf("failed to parse output: %s", err)
+	}
+
+	if output.Status == "error" {
+		return output, fmt.Errorf("command returned error: %s", output.Error)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return output, fmt.Errorf("plugin request failed with status %d", resp.StatusCode)
+	}
+
+	return output, nil
+}
+
+// callPage sends a command that answers with a page of anime
+func (h *HTTPScraper) callPage(command string, req pluginRequest) (AnimePage, error) {
+	output, err := h.call(command, req)
+	if err != nil {
+		return AnimePage{}, err
+	}
+	return decodeAnimePage(output)
+}
+
+// callInto sends a command and decodes the response data into v
+func (h *HTTPScraper) callInto(command string, req pluginRequest, v interface{}, what string) error {
+	output, err := h.call(command, req)
+	if err != nil {
+		return err
+	}
+	return decodeData(output, v, what)
+}
+
+// GetExtensionInfo retrieves metadata about the extension
+func (h *HTTPScraper) GetExtensionInfo() (ExtensionInfo, error) {
+	var info ExtensionInfo
+	err := h.callInto("extension-info", pluginRequest{}, &info, "extension info")
+	return info, err
+}
+
+// GetSourceInfo retrieves metadata about a specific source
+func (h *HTTPScraper) GetSourceInfo() (SourceInfo, error) {
+	var info SourceInfo
+	err := h.callInto("source-info", pluginRequest{Source: h.SourceID}, &info, "source info")
+	return info, err
+}
+
+// GetPopularAnime retrieves popular anime from the source
+func (h *HTTPScraper) GetPopularAnime(page int) (AnimePage, error) {
+	return h.callPage("popular", pluginRequest{Source: h.SourceID, Page: page})
+}
+
+// GetLatestUpdates retrieves the latest anime updates from the source
+func (h *HTTPScraper) GetLatestUpdates(page int) (AnimePage, error) {
+	return h.callPage("latest", pluginRequest{Source: h.SourceID, Page: page})
+}
+
+// SearchAnime searches for anime with the given query and filters
+func (h *HTTPScraper) SearchAnime(query string, page int, filters string) (AnimePage, error) {
+	req := pluginRequest{Source: h.SourceID, Query: query, Page: page, Filters: filters}
+	return h.callPage("search", req)
+}
+
+// GetAnimeDetails retrieves detailed information about an anime
+func (h *HTTPScraper) GetAnimeDetails(animeID string) (Anime, error) {
+	var anime Anime
+	err := h.callInto("details", pluginRequest{Source: h.SourceID, Anime: animeID}, &anime, "anime details")
+	return anime, err
+}
+
+// GetEpisodeList retrieves the list of episodes for an anime
+func (h *HTTPScraper) GetEpisodeList(animeID string) ([]Episode, error) {
+	var episodes []Episode
+	err := h.callInto("episodes", pluginRequest{Source: h.SourceID, Anime: animeID}, &episodes, "episode list")
+	return episodes, err
+}
+
+// GetVideoList retrieves stream information for an episode
+func (h *HTTPScraper) GetVideoList(animeID string, episodeNumber float64) (VideoResponse, error) {
+	var response VideoResponse
+	req := pluginRequest{Source: h.SourceID, Anime: animeID, Episode: &episodeNumber}
+	err := h.callInto("stream-url", req, &response, "video list")
+	return response, err
+}
+
+// GetMagnetLink retrieves a magnet link for a torrent episode
+func (h *HTTPScraper) GetMagnetLink(animeID string, episodeNumber float64) (string, error) {
+	var response MagnetResponse
+	req := pluginRequest{Source: h.SourceID, Anime: animeID, Episode: &episodeNumber}
+	if err := h.callInto("magnet-link", req, &response, "magnet link"); err != nil {
+		return "", err
+	}
+	return response.MagnetLink, nil
+}
+
+// GetFilterList retrieves the available filters for a source
+func (h *HTTPScraper) GetFilterList() (FilterResponse, error) {
+	var response FilterResponse
+	err := h.callInto("filters", pluginRequest{Source: h.SourceID}, &response, "filter list")
+	return response, err
+}
+
+// GetRelatedAnime retrieves anime related to the given anime
+func (h *HTTPScraper) GetRelatedAnime(animeID string, page int) ([]Anime, error) {
+	var animes []Anime
+	req := pluginRequest{Source: h.SourceID, Anime: animeID, Page: page}
+	err := h.callInto("related", req, &animes, "related anime list")
+	return animes, err
+}
@@ -0,0 +1,103 @@
+package scraper
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+var (
+	_ Scraper = (*CLIScraper)(nil)
+	_ Scraper = (*HTTPScraper)(nil)
+)
+
+// fakeEpisodes is the episode list both fake backends answer with
+const fakeEpisodes = `{"status":"success","data":[` +
+	`{"anime_id":"ep-1","name":"Episode 1","date_upload":1700000000,"episode_number":1},` +
+	`{"anime_id":"ep-2","name":"Episode 2","date_upload":1700086400,"episode_number":2}]}`
+
+func TestHTTPScraperMatchesCLIScraper(t *testing.T) {
+	// A CLI extension that prints the fixture for the episodes command
+	binary := writeFakeExtension(t,
+		"if [ \"$1\" = episodes ]; then\n"+
+			"  echo '"+fakeEpisodes+"'\n"+
+			"else\n"+
+			"  echo '{\"status\":\"error\",\"error\":\"unknown command\"}'\n"+
+			"fi\n")
+
+	// A plugin server answering the same command with the same fixture
+	var got pluginRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Errorf("Failed to decode plugin request: %v", err)
+		}
+		if r.URL.Path != "/episodes" {
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, `{"status":"error","error":"unknown command"}`)
+			return
+		}
+		io.WriteString(w, fakeEpisodes)
+	}))
+	defer server.Close()
+
+	cli := NewCLIScraper(binary, "source")
+	plugin := NewHTTPScraper(server.URL+"/", "source")
+
+	cliEpisodes, err := cli.GetEpisodeList("anime-1")
+	if err != nil {
+		t.Fatalf("Failed to get CLI episodes: %v", err)
+	}
+	httpEpisodes, err := plugin.GetEpisodeList("anime-1")
+	if err != nil {
+		t.Fatalf("Failed to get plugin episodes: %v", err)
+	}
+
+	// Test that both transports decode the same result
+	if len(httpEpisodes) != 2 {
+		t.Fatalf("Expected 2 episodes, got %d", len(httpEpisodes))
+	}
+	if !reflect.DeepEqual(cliEpisodes, httpEpisodes) {
+		t.Errorf("Expected %+v, got %+v", cliEpisodes, httpEpisodes)
+	}
+
+	// Test that the plugin receives the call's arguments
+	if got.Source != "source" || got.Anime != "anime-1" {
+		t.Errorf("Expected source and anime in request, got %+v", got)
+	}
+
+	// Test that errors are reported the same way by both transports
+	_, cliErr := cli.GetFilterList()
+	_, httpErr := plugin.GetFilterList()
+	if cliErr == nil || httpErr == nil {
+		t.Fatalf("Expected errors from both scrapers, got %v and %v", cliErr, httpErr)
+	}
+	if cliErr.Error() != httpErr.Error() {
+		t.Errorf("Expected error %q, got %q", cliErr, httpErr)
+	}
+}
+
+func TestHTTPScraperSendsEpisodeZero(t *testing.T) {
+	var raw map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&raw)
+		io.WriteString(w, `{"status":"success","data":{"magnetLink":"magnet:?xt=1"}}`)
+	}))
+	defer server.Close()
+
+	link, err := NewHTTPScraper(server.URL, "source").GetMagnetLink("anime-1", 0)
+	if err != nil {
+		t.Fatalf("Failed to get magnet link: %v", err)
+	}
+	if link != "magnet:?xt=1" {
+		t.Errorf("Expected magnet:?xt=1, got %s", link)
+	}
+
+	// Test that episode 0 is not dropped from the request
+	if episode, ok := raw["episode"]; !ok || episode != float64(0) {
+		t.Errorf("Expected episode 0 in request, got %v", raw)
+	}
+}
@@ -1,12 +1,21 @@
 package scraper
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"os"
 	"os/exec"
 	"strconv"
+	"strings"
+
+	"github.com/wraient/pair/pkg/logger"
+	"go.uber.org/zap"
 )
 
+// stderrTailLimit is how much of an extension's stderr is kept in errors
+const stderrTailLimit = 512
+
 // Status enum for anime sources
 const (
 	StatusUnknown            = "unknown"
@@ -105,6 +114,9 @@ type CLIOutput struct {
 	Data    interface{} `json:"data,omitempty"`    // Contains the actual result data
 	Error   string      `json:"error,omitempty"`   // Error message if status is "error"
 	Message string      `json:"message,omitempty"` // Optional informational message
+
+	source  string // Source that answered, used in error messages
+	command string // Command that was run, used in error messages
 }
 
 // ExtensionInfo represents metadata about an extension
@@ -130,10 +142,60 @@ type SourceInfo struct {
 	SupportsRelatedAnime bool   `json:"supportsRelatedAnime"` // Whether source supports related anime
 }
 
-// CLIScraper implements scraping functionality using the CLI tool interface
+// ErrExtensionMissing is returned when an extension's binary is no longer on disk or can't be executed
+type ErrExtensionMissing struct {
+	Path    string
+	Package string
+}
+
+func (e *ErrExtensionMissing) Error() string {
+	if e.Package == "" {
+		return fmt.Sprintf("extension binary %s is missing or not executable", e.Path)
+	}
+	return fmt.Sprintf("extension %s: binary %s is missing or not executable, reinstall it", e.Package, e.Path)
+}
+
+// CheckExtensionBinary reports an *ErrExtensionMissing if path is not an executable file
+func CheckExtensionBinary(path, pkg string) error {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() || info.Mode().Perm()&0111 == 0 {
+		return &ErrExtensionMissing{Path: path, Package: pkg}
+	}
+	return nil
+}
+
+// Scraper is implemented by every way of talking to an extension
+type Scraper interface {
+	// GetExtensionInfo retrieves metadata about the extension
+	GetExtensionInfo() (ExtensionInfo, error)
+	// GetSourceInfo retrieves metadata about the scraper's source
+	GetSourceInfo() (SourceInfo, error)
+	// GetPopularAnime retrieves popular anime from the source
+	GetPopularAnime(page int) (AnimePage, error)
+	// GetLatestUpdates retrieves the latest anime updates from the source
+	GetLatestUpdates(page int) (AnimePage, error)
+	// SearchAnime searches for anime with the given query and filters
+	SearchAnime(query string, page int, filters string) (AnimePage, error)
+	// GetAnimeDetails retrieves detailed information about an anime
+	GetAnimeDetails(animeID string) (Anime, error)
+	// GetEpisodeList retrieves the list of episodes for an anime
+	GetEpisodeList(animeID string) ([]Episode, error)
+	// GetVideoList retrieves stream information for an episode
+	GetVideoList(animeID string, episodeNumber float64) (VideoResponse, error)
+	// GetMagnetLink retrieves a magnet link for a torrent episode
+	GetMagnetLink(animeID string, episodeNumber float64) (string, error)
+	// GetFilterList retrieves the available filters for the source
+	GetFilterList() (FilterResponse, error)
+	// GetRelatedAnime retrieves anime related to the given anime
+	GetRelatedAnime(animeID string, page int) ([]Anime, error)
+}
+
+// CLIScraper implements scraping functionality using the CLI tool interface.
+// Every call runs the extension binary once.
 type CLIScraper struct {
 	BinaryPath string
 	SourceID   string
+	Package    string // Package of the extension, used in error messages
 }
 
 // NewCLIScraper creates a new CLI-based scraper
@@ -146,30 +208,150 @@ func NewCLIScraper(extensionPath string, sourceID string) *CLIScraper {
 
 // runCommand executes a command and parses the JSON output
 func (c *CLIScraper) runCommand(args ...string) (CLIOutput, error) {
-	var output CLIOutput
+	output := CLIOutput{source: c.SourceID}
+	if len(args) > 0 {
+		output.command = args[0]
+	}
+
+	if err := CheckExtensionBinary(c.BinaryPath, c.Package); err != nil {
+		return output, err
+	}
 
+	var stdout, stderr bytes.Buffer
 	cmd := exec.Command(c.BinaryPath, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
 
-	stdout, err := cmd.Output()
-	if err != nil {
-		// Try to extract stderr if available
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			return output, fmt.Errorf("command failed: %s, stderr: %s", err, exitErr.Stderr)
-		}
-		return output, fmt.Errorf("command failed: %s", err)
+	runErr := cmd.Run()
+
+	// Extensions write diagnostics to stderr even when they succeed
+	if stderr.Len() > 0 {
+		logger.Debug("Extension wrote to stderr",
+			zap.String("source", c.SourceID),
+			zap.Strings("args", args),
+			zap.String("stderr", stderr.String()))
 	}
+	tail := stderrTail(stderr.String())
 
-	if err := json.Unmarshal(stdout, &output); err != nil {
-		return output, fmt.Errorf("failed to parse output: %s", err)
+	if runErr != nil {
+		return output, fmt.Errorf("command failed: %s%s", runErr, tail)
+	}
+
+	if err := json.Unmarshal(stdout.Bytes(), &output); err != nil {
+		return output, fmt.Errorf("failed to parse output: %s%s", err, tail)
 	}
 
 	if output.Status == "error" {
-		return output, fmt.Errorf("command returned error: %s", output.Error)
+		return output, fmt.Errorf("command returned error: %s%s", output.Error, tail)
 	}
 
 	return output, nil
 }
 
+// stderrTail formats the end of an extension's stderr for an error message,
+// or returns an empty string when there was none
+func stderrTail(stderr string) string {
+	stderr = strings.TrimSpace(stderr)
+	if stderr == "" {
+		return ""
+	}
+	if len(stderr) > stderrTailLimit {
+		stderr = "..." + stderr[len(stderr)-stderrTailLimit:]
+	}
+	return ", stderr: " + stderr
+}
+
+// decodeData converts the data of a successful extension response into v
+// and checks the fields pair relies on are there, so an extension answering
+// with the wrong shape fails loudly instead of giving empty results. what
+// names the expected data in error messages.
+func decodeData(output CLIOutput, v interface{}, what string) error {
+	// Convert the data to JSON and then unmarshal to our struct
+	data, err := json.Marshal(output.Data)
+	if err != nil {
+		return fmt.Errorf("failed to re-marshal data: %s", err)
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to unmarshal %s from %s: %s", what, output.origin(), err)
+	}
+	if err := validateData(data, v); err != nil {
+		return fmt.Errorf("invalid %s from %s: %s", what, output.origin(), err)
+	}
+	return nil
+}
+
+// origin names the source and command a response came from
+func (o CLIOutput) origin() string {
+	return fmt.Sprintf("source %q, command %q", o.source, o.command)
+}
+
+// validateData checks the required fields of decoded data. data is the raw
+// JSON v was decoded from, for fields whose zero value is valid.
+func validateData(data []byte, v interface{}) error {
+	switch v := v.(type) {
+	case *Anime:
+		if v.ID == "" {
+			return fmt.Errorf("anime has no anime_id")
+		}
+	case *[]Anime:
+		return validateAnimes(*v)
+	case *AnimePage:
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return err
+		}
+		if _, ok := fields["animes"]; !ok {
+			return fmt.Errorf("page has no animes")
+		}
+		return validateAnimes(v.Animes)
+	case *[]Episode:
+		// Episode 0 is valid, so check the number was sent at all
+		var episodes []map[string]json.RawMessage
+		if err := json.Unmarshal(data, &episodes); err != nil {
+			return err
+		}
+		for i, episode := range episodes {
+			if _, ok := episode["episode_number"]; !ok {
+				return fmt.Errorf("episode %d has no episode_number", i+1)
+			}
+		}
+	case *VideoResponse:
+		for i, stream := range v.Streams {
+			if stream.VideoURL == "" {
+				return fmt.Errorf("stream %d has no videourl", i+1)
+			}
+		}
+	case *MagnetResponse:
+		if v.MagnetLink == "" {
+			return fmt.Errorf("response has no magnetLink")
+		}
+	}
+	return nil
+}
+
+// validateAnimes checks every anime in a list has an ID
+func validateAnimes(animes []Anime) error {
+	for i, anime := range animes {
+		if anime.ID == "" {
+			return fmt.Errorf("anime %d has no anime_id", i+1)
+		}
+	}
+	return nil
+}
+
+// decodeAnimePage decodes a page of anime. Extensions that answer with a
+// bare list rather than an AnimePage are taken to have no further pages.
+func decodeAnimePage(output CLIOutput) (AnimePage, error) {
+	var page AnimePage
+	if _, ok := output.Data.([]interface{}); ok {
+		err := decodeData(output, &page.Animes, "anime list")
+		return page, err
+	}
+	err := decodeData(output, &page, "anime page")
+	return page, err
+}
+
 // GetExtensionInfo retrieves metadata about the extension
 func (c *CLIScraper) GetExtensionInfo() (ExtensionInfo, error) {
 	var info ExtensionInfo
@@ -179,14 +361,8 @@ func (c *CLIScraper) GetExtensionInfo() (ExtensionInfo, error) {
 		return info, err
 	}
 
-	// Convert the data to JSON and then unmarshal to our struct
-	data, err := json.Marshal(output.Data)
-	if err != nil {
-		return info, fmt.Errorf("failed to re-marshal data: %s", err)
-	}
-
-	if err := json.Unmarshal(data, &info); err != nil {
-		return info, fmt.Errorf("failed to unmarshal extension info: %s", err)
+	if err := decodeData(output, &info, "extension info"); err != nil {
+		return info, err
 	}
 
 	return info, nil
@@ -201,67 +377,35 @@ func (c *CLIScraper) GetSourceInfo() (SourceInfo, error) {
 		return info, err
 	}
 
-	// Convert the data to JSON and then unmarshal to our struct
-	data, err := json.Marshal(output.Data)
-	if err != nil {
-		return info, fmt.Errorf("failed to re-marshal data: %s", err)
-	}
-
-	if err := json.Unmarshal(data, &info); err != nil {
-		return info, fmt.Errorf("failed to unmarshal source info: %s", err)
+	if err := decodeData(output, &info, "source info"); err != nil {
+		return info, err
 	}
 
 	return info, nil
 }
 
 // GetPopularAnime retrieves popular anime from the source
-func (c *CLIScraper) GetPopularAnime(page int) ([]Anime, error) {
-	var animes []Anime
-
+func (c *CLIScraper) GetPopularAnime(page int) (AnimePage, error) {
 	output, err := c.runCommand("popular", c.SourceID, "--page", strconv.Itoa(page))
 	if err != nil {
-		return animes, err
+		return AnimePage{}, err
 	}
 
-	// Convert the data to JSON and then unmarshal to our struct
-	data, err := json.Marshal(output.Data)
-	if err != nil {
-		return animes, fmt.Errorf("failed to re-marshal data: %s", err)
-	}
-
-	if err := json.Unmarshal(data, &animes); err != nil {
-		return animes, fmt.Errorf("failed to unmarshal anime list: %s", err)
-	}
-
-	return animes, nil
+	return decodeAnimePage(output)
 }
 
 // GetLatestUpdates retrieves the latest anime updates from the source
-func (c *CLIScraper) GetLatestUpdates(page int) ([]Anime, error) {
-	var animes []Anime
-
+func (c *CLIScraper) GetLatestUpdates(page int) (AnimePage, error) {
 	output, err := c.runCommand("latest", c.SourceID, "--page", strconv.Itoa(page))
 	if err != nil {
-		return animes, err
-	}
-
-	// Convert the data to JSON and then unmarshal to our struct
-	data, err := json.Marshal(output.Data)
-	if err != nil {
-		return animes, fmt.Errorf("failed to re-marshal data: %s", err)
+		return AnimePage{}, err
 	}
 
-	if err := json.Unmarshal(data, &animes); err != nil {
-		return animes, fmt.Errorf("failed to unmarshal anime list: %s", err)
-	}
-
-	return animes, nil
+	return decodeAnimePage(output)
 }
 
 // SearchAnime searches for anime with the given query and filters
-func (c *CLIScraper) SearchAnime(query string, page int, filters string) ([]Anime, error) {
-	var animes []Anime
-
+func (c *CLIScraper) SearchAnime(query string, page int, filters string) (AnimePage, error) {
 	args := []string{"search", c.SourceID, "--query", query, "--page", strconv.Itoa(page)}
 	if filters != "" {
 		args = append(args, "--filters", filters)
@@ -269,20 +413,10 @@ func (c *CLIScraper) SearchAnime(query string, page int, filters string) ([]Anim
 
 	output, err := c.runCommand(args...)
 	if err != nil {
-		return animes, err
+		return AnimePage{}, err
 	}
 
-	// Convert the data to JSON and then unmarshal to our struct
-	data, err := json.Marshal(output.Data)
-	if err != nil {
-		return animes, fmt.Errorf("failed to re-marshal data: %s", err)
-	}
-
-	if err := json.Unmarshal(data, &animes); err != nil {
-		return animes, fmt.Errorf("failed to unmarshal anime list: %s", err)
-	}
-
-	return animes, nil
+	return decodeAnimePage(output)
 }
 
 // GetAnimeDetails retrieves detailed information about an anime
@@ -294,14 +428,8 @@ func (c *CLIScraper) GetAnimeDetails(animeID string) (Anime, error) {
 		return anime, err
 	}
 
-	// Convert the data to JSON and then unmarshal to our struct
-	data, err := json.Marshal(output.Data)
-	if err != nil {
-		return anime, fmt.Errorf("failed to re-marshal data: %s", err)
-	}
-
-	if err := json.Unmarshal(data, &anime); err != nil {
-		return anime, fmt.Errorf("failed to unmarshal anime details: %s", err)
+	if err := decodeData(output, &anime, "anime details"); err != nil {
+		return anime, err
 	}
 
 	return anime, nil
@@ -316,14 +444,8 @@ func (c *CLIScraper) GetEpisodeList(animeID string) ([]Episode, error) {
 		return episodes, err
 	}
 
-	// Convert the data to JSON and then unmarshal to our struct
-	data, err := json.Marshal(output.Data)
-	if err != nil {
-		return episodes, fmt.Errorf("failed to re-marshal data: %s", err)
-	}
-
-	if err := json.Unmarshal(data, &episodes); err != nil {
-		return episodes, fmt.Errorf("failed to unmarshal episode list: %s", err)
+	if err := decodeData(output, &episodes, "episode list"); err != nil {
+		return episodes, err
 	}
 
 	return episodes, nil
@@ -338,14 +460,8 @@ func (c *CLIScraper) GetVideoList(animeID string, episodeNumber float64) (VideoR
 		return response, err
 	}
 
-	// Convert the data to JSON and then unmarshal to our struct
-	data, err := json.Marshal(output.Data)
-	if err != nil {
-		return response, fmt.Errorf("failed to re-marshal data: %s", err)
-	}
-
-	if err := json.Unmarshal(data, &response); err != nil {
-		return response, fmt.Errorf("failed to unmarshal video list: %s", err)
+	if err := decodeData(output, &response, "video list"); err != nil {
+		return response, err
 	}
 
 	return response, nil
@@ -360,14 +476,8 @@ func (c *CLIScraper) GetMagnetLink(animeID string, episodeNumber float64) (strin
 		return "", err
 	}
 
-	// Convert the data to JSON and then unmarshal to our struct
-	data, err := json.Marshal(output.Data)
-	if err != nil {
-		return "", fmt.Errorf("failed to re-marshal data: %s", err)
-	}
-
-	if err := json.Unmarshal(data, &response); err != nil {
-		return "", fmt.Errorf("failed to unmarshal magnet link: %s", err)
+	if err := decodeData(output, &response, "magnet link"); err != nil {
+		return "", err
 	}
 
 	return response.MagnetLink, nil
@@ -382,14 +492,8 @@ func (c *CLIScraper) GetFilterList() (FilterResponse, error) {
 		return response, err
 	}
 
-	// Convert the data to JSON and then unmarshal to our struct
-	data, err := json.Marshal(output.Data)
-	if err != nil {
-		return response, fmt.Errorf("failed to re-marshal data: %s", err)
-	}
-
-	if err := json.Unmarshal(data, &response); err != nil {
-		return response, fmt.Errorf("failed to unmarshal filter list: %s", err)
+	if err := decodeData(output, &response, "filter list"); err != nil {
+		return response, err
 	}
 
 	return response, nil
@@ -404,14 +508,8 @@ func (c *CLIScraper) GetRelatedAnime(animeID string, page int) ([]Anime, error)
 		return animes, err
 	}
 
-	// Convert the data to JSON and then unmarshal to our struct
-	data, err := json.Marshal(output.Data)
-	if err != nil {
-		return animes, fmt.Errorf("failed to re-marshal data: %s", err)
-	}
-
-	if err := json.Unmarshal(data, &animes); err != nil {
-		return animes, fmt.Errorf("failed to unmarshal related anime list: %s", err)
+	if err := decodeData(output, &animes, "related anime list"); err != nil {
+		return animes, err
 	}
 
 	return animes, nil
@@ -0,0 +1,167 @@
+package scraper
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wraient/pair/pkg/logger"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// writeFakeExtension writes a shell script standing in for an extension binary
+func writeFakeExtension(t *testing.T, script string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "extension")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatalf("Failed to write fake extension: %v", err)
+	}
+	return path
+}
+
+func TestMissingExtensionBinary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gone")
+	c := NewCLIScraper(path, "source")
+	c.Package = "pair.extension.gone"
+
+	// Test that a missing binary reports a typed error
+	_, err := c.GetEpisodeList("anime")
+	var missing *ErrExtensionMissing
+	if !errors.As(err, &missing) {
+		t.Fatalf("Expected ErrExtensionMissing, got %v", err)
+	}
+	if missing.Path != path {
+		t.Errorf("Expected path %s, got %s", path, missing.Path)
+	}
+	if missing.Package != "pair.extension.gone" {
+		t.Errorf("Expected package pair.extension.gone, got %s", missing.Package)
+	}
+}
+
+func TestExtensionStderrIsLogged(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	previous := logger.GetLogger()
+	logger.SetLogger(zap.New(core))
+	defer logger.SetLogger(previous)
+
+	path := writeFakeExtension(t,
+		"echo 'rate limited, retrying' >&2\n"+
+			"echo '{\"status\":\"success\",\"data\":[]}'\n")
+	c := NewCLIScraper(path, "source")
+
+	if _, err := c.GetEpisodeList("anime"); err != nil {
+		t.Fatalf("Failed to get episodes: %v", err)
+	}
+
+	// Test that stderr of a successful call is logged with its context
+	entries := logs.FilterMessage("Extension wrote to stderr").All()
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 stderr log entry, got %d", len(entries))
+	}
+	fields := entries[0].ContextMap()
+	if fields["source"] != "source" {
+		t.Errorf("Expected source field source, got %v", fields["source"])
+	}
+	if !strings.Contains(fields["stderr"].(string), "rate limited") {
+		t.Errorf("Expected stderr to be logged, got %v", fields["stderr"])
+	}
+	if args, ok := fields["args"].([]interface{}); !ok || len(args) == 0 || args[0] != "episodes" {
+		t.Errorf("Expected args starting with episodes, got %v", fields["args"])
+	}
+}
+
+func TestExtensionErrorIncludesStderrTail(t *testing.T) {
+	path := writeFakeExtension(t,
+		"echo 'first line' >&2\n"+
+			"head -c 2000 /dev/zero | tr '\\0' x >&2\n"+
+			"echo 'panic: source changed' >&2\n"+
+			"exit 2\n")
+	c := NewCLIScraper(path, "source")
+
+	_, err := c.GetEpisodeList("anime")
+	if err == nil {
+		t.Fatal("Expected an error from a failing extension")
+	}
+
+	// Test that the error keeps only the end of stderr
+	if !strings.Contains(err.Error(), "panic: source changed") {
+		t.Errorf("Expected stderr tail in error, got %v", err)
+	}
+	if strings.Contains(err.Error(), "first line") {
+		t.Errorf("Expected stderr to be truncated, got %d bytes", len(err.Error()))
+	}
+}
+
+func TestAnimePagePagination(t *testing.T) {
+	binary := writeFakeExtension(t,
+		"if [ \"$1\" = search ]; then\n"+
+			"  echo '{\"status\":\"success\",\"data\":{\"animes\":[{\"anime_id\":\"a-1\",\"title\":\"First\"}],\"hasNextPage\":true}}'\n"+
+			"else\n"+
+			"  echo '{\"status\":\"success\",\"data\":[{\"anime_id\":\"a-2\",\"title\":\"Second\"}]}'\n"+
+			"fi\n")
+	c := NewCLIScraper(binary, "source")
+
+	// Test that the paging info of a search is surfaced
+	page, err := c.SearchAnime("first", 1, "")
+	if err != nil {
+		t.Fatalf("Failed to search: %v", err)
+	}
+	if !page.HasNextPage {
+		t.Errorf("Expected a next page")
+	}
+	if len(page.Animes) != 1 || page.Animes[0].Title != "First" {
+		t.Errorf("Expected the first anime, got %+v", page.Animes)
+	}
+
+	// Test that a bare list is read as the last page
+	page, err = c.GetPopularAnime(1)
+	if err != nil {
+		t.Fatalf("Failed to get popular anime: %v", err)
+	}
+	if page.HasNextPage || len(page.Animes) != 1 || page.Animes[0].Title != "Second" {
+		t.Errorf("Expected a single last page, got %+v", page)
+	}
+}
+
+func TestMalformedExtensionData(t *testing.T) {
+	binary := writeFakeExtension(t,
+		"case \"$1\" in\n"+
+			"  search) echo '{\"status\":\"success\",\"data\":{\"results\":[]}}' ;;\n"+
+			"  popular) echo '{\"status\":\"success\",\"data\":[{\"name\":\"No ID\"}]}' ;;\n"+
+			"  details) echo '{\"status\":\"success\"}' ;;\n"+
+			"  episodes) echo '{\"status\":\"success\",\"data\":[{\"anime_id\":\"ep-0\",\"episode_number\":0},{\"anime_id\":\"ep-x\",\"name\":\"Extra\"}]}' ;;\n"+
+			"  stream-url) echo '{\"status\":\"success\",\"data\":{\"streams\":[{\"quality\":\"1080p\"}]}}' ;;\n"+
+			"  related) echo '{\"status\":\"success\",\"data\":{\"animes\":[]}}' ;;\n"+
+			"esac\n")
+	c := NewCLIScraper(binary, "fake-source")
+
+	// Test that each malformed payload fails with an error naming the source and command
+	checks := []struct {
+		command string
+		want    string
+		call    func() error
+	}{
+		{"search", "page has no animes", func() error { _, err := c.SearchAnime("q", 1, ""); return err }},
+		{"popular", "anime 1 has no anime_id", func() error { _, err := c.GetPopularAnime(1); return err }},
+		{"details", "anime has no anime_id", func() error { _, err := c.GetAnimeDetails("a"); return err }},
+		{"episodes", "episode 2 has no episode_number", func() error { _, err := c.GetEpisodeList("a"); return err }},
+		{"stream-url", "stream 1 has no videourl", func() error { _, err := c.GetVideoList("a", 1); return err }},
+		{"related", "cannot unmarshal object", func() error { _, err := c.GetRelatedAnime("a", 1); return err }},
+	}
+	for _, check := range checks {
+		err := check.call()
+		if err == nil {
+			t.Errorf("Expected an error for %s, got none", check.command)
+			continue
+		}
+		for _, want := range []string{check.want, `source "fake-source"`, `command "` + check.command + `"`} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("Expected %s error to contain %q, got %q", check.command, want, err)
+			}
+		}
+	}
+}
@@ -5,18 +5,21 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/pkg/browser"
 	"github.com/wraient/pair/pkg/database"
+	"github.com/wraient/pair/pkg/httpclient"
 )
 
 const (
@@ -43,17 +46,25 @@ type AnilistTracker struct {
 	token      *AnilistToken
 	tokenPath  string
 	httpClient *http.Client
+	tokenKey   []byte
+	apiURL     string
+	// requestTimeout bounds each API request
+	requestTimeout time.Duration
+	// scoreFormat caches the user's score format once fetched
+	scoreFormat ScoreFormat
 }
 
-// NewAnilistTracker creates a new AnilistTracker
-func NewAnilistTracker(configDir string) *AnilistTracker {
-	if err := os.MkdirAll(configDir, 0755); err != nil {
-		fmt.Printf("Failed to create config directory: %v\n", err)
+// NewAnilistTracker creates a new AnilistTracker that keeps its login in dataDir
+func NewAnilistTracker(dataDir string) *AnilistTracker {
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		fmt.Printf("Failed to create data directory: %v\n", err)
 	}
 
 	return &AnilistTracker{
-		tokenPath:  filepath.Join(configDir, anilistTokenFilename),
-		httpClient: &http.Client{Timeout: 30 * time.Second},
+		tokenPath:      filepath.Join(dataDir, anilistTokenFilename),
+		httpClient:     httpclient.Default(),
+		apiURL:         anilistAPIURL,
+		requestTimeout: DefaultRequestTimeout,
 	}
 }
 
@@ -72,9 +83,26 @@ func (t *AnilistTracker) IsAuthenticated() bool {
 	return t.token != nil && t.token.AccessToken != "" && time.Now().Before(t.token.ExpiresAt)
 }
 
+// APIURL returns the URL of the Anilist GraphQL API
+func (t *AnilistTracker) APIURL() string {
+	return t.apiURL
+}
+
+// SetRequestTimeout sets how long a single API request may take.
+// Zero disables the per-request limit.
+func (t *AnilistTracker) SetRequestTimeout(timeout time.Duration) {
+	t.requestTimeout = timeout
+}
+
+// SetTokenEncryptionKey enables encryption of the stored token with the given key.
+// A nil key stores the token as plaintext.
+func (t *AnilistTracker) SetTokenEncryptionKey(key []byte) {
+	t.tokenKey = key
+}
+
 // loadToken loads the token from the token file
 func (t *AnilistTracker) loadToken() error {
-	data, err := os.ReadFile(t.tokenPath)
+	data, err := readTokenFile(t.tokenPath, t.tokenKey)
 	if err != nil {
 		return fmt.Errorf("failed to read token file: %w", err)
 	}
@@ -95,11 +123,11 @@ func (t *AnilistTracker) saveToken() error {
 		return fmt.Errorf("failed to marshal token: %w", err)
 	}
 
-	return os.WriteFile(t.tokenPath, data, 0600)
+	return writeTokenFile(t.tokenPath, data, t.tokenKey)
 }
 
 // refreshToken refreshes the access token using the refresh token
-func (t *AnilistTracker) refreshToken() error {
+func (t *AnilistTracker) refreshToken(ctx context.Context) error {
 	if t.token == nil || t.token.RefreshToken == "" {
 		return fmt.Errorf("no refresh token available")
 	}
@@ -110,10 +138,14 @@ func (t *AnilistTracker) refreshToken() error {
 	data.Set("client_secret", anilistClientSecret)
 	data.Set("refresh_token", t.token.RefreshToken)
 
-	req, err := http.NewRequest("POST", anilistOAuthURL+"/token", strings.NewReader(data.Encode()))
+	ctx, cancel := withRequestTimeout(ctx, t.requestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", anilistOAuthURL+"/token", strings.NewReader(data.Encode()))
 	if err != nil {
 		return fmt.Errorf("failed to create refresh token request: %w", err)
 	}
+	httpclient.Identify(req)
 
 	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
 	resp, err := t.httpClient.Do(req)
@@ -147,7 +179,7 @@ func (t *AnilistTracker) Authenticate(ctx context.Context) error {
 
 	// Try to refresh token if available
 	if t.token != nil && t.token.RefreshToken != "" {
-		if err := t.refreshToken(); err == nil {
+		if err := t.refreshToken(ctx); err == nil {
 			return nil
 		}
 	}
@@ -212,10 +244,14 @@ func (t *AnilistTracker) Authenticate(ctx context.Context) error {
 	data.Set("redirect_uri", anilistRedirectURI)
 	data.Set("code", code)
 
-	req, err := http.NewRequest("POST", anilistOAuthURL+"/token", strings.NewReader(data.Encode()))
+	tokenCtx, cancel := withRequestTimeout(ctx, t.requestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(tokenCtx, "POST", anilistOAuthURL+"/token", strings.NewReader(data.Encode()))
 	if err != nil {
 		return fmt.Errorf("failed to create token request: %w", err)
 	}
+	httpclient.Identify(req)
 
 	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
 	resp, err := t.httpClient.Do(req)
@@ -261,10 +297,14 @@ func (t *AnilistTracker) graphqlRequest(ctx context.Context, query string, varia
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", anilistAPIURL, bytes.NewBuffer(jsonBody))
+	ctx, cancel := withRequestTimeout(ctx, t.requestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", t.apiURL, bytes.NewBuffer(jsonBody))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
+	httpclient.Identify(req)
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Accept", "application/json")
@@ -278,8 +318,8 @@ func (t *AnilistTracker) graphqlRequest(ctx context.Context, query string, varia
 
 	// Handle unauthorized response by refreshing token and retrying
 	if resp.StatusCode == http.StatusUnauthorized {
-		if err := t.refreshToken(); err != nil {
-			return nil, fmt.Errorf("token refresh failed: %w", err)
+		if err := t.refreshToken(ctx); err != nil {
+			return nil, fmt.Errorf("token refresh failed: %w: %w", err, ErrAuthExpired)
 		}
 
 		// Retry request with new token
@@ -300,125 +340,164 @@ func (t *AnilistTracker) graphqlRequest(ctx context.Context, query string, varia
 	var errorResp struct {
 		Errors []struct {
 			Message string `json:"message"`
+			Status  int    `json:"status"`
 		} `json:"errors"`
 	}
 	if err := json.Unmarshal(body, &errorResp); err == nil && len(errorResp.Errors) > 0 {
 		var messages []string
+		var kind error
 		for _, e := range errorResp.Errors {
 			messages = append(messages, e.Message)
+			if kind == nil {
+				kind = anilistErrorKind(e.Status, e.Message)
+			}
+		}
+		if kind == nil {
+			kind = statusError(resp.StatusCode)
+		}
+		if kind != nil {
+			return nil, fmt.Errorf("GraphQL errors: %s: %w", strings.Join(messages, "; "), kind)
 		}
 		return nil, fmt.Errorf("GraphQL errors: %s", strings.Join(messages, "; "))
 	}
 
+	if resp.StatusCode != http.StatusOK {
+		return nil, apiError("request failed", resp.StatusCode, body)
+	}
+
 	return body, nil
 }
 
-// SearchAnime searches for anime on Anilist
-func (t *AnilistTracker) SearchAnime(ctx context.Context, query string, limit int) ([]AnimeInfo, error) {
-	gqlQuery := `
-	query ($search: String, $perPage: Int) {
-		Page(page: 1, perPage: $perPage) {
-			media(search: $search, type: ANIME) {
-				id
-				title {
-					romaji
-					english
-					native
-					userPreferred
-				}
-				synonyms
-				description
-				format
-				status
-				episodes
-				duration
-				genres
-				studios {
-					nodes {
-						name
-					}
-				}
-				seasonYear
-				season
-				averageScore
-				coverImage {
-					large
-				}
-				startDate {
-					year
-					month
-					day
-				}
-				endDate {
-					year
-					month
-					day
-				}
-			}
-		}
+// anilistErrorKind maps a GraphQL error to one of the tracker errors. Anilist
+// reports some conditions only in the message, with a status of 400 or 500.
+func anilistErrorKind(status int, message string) error {
+	if kind := statusError(status); kind != nil {
+		return kind
 	}
-	`
-
-	variables := map[string]interface{}{
-		"search":  query,
-		"perPage": limit,
+	message = strings.ToLower(message)
+	switch {
+	case strings.Contains(message, "invalid token"), strings.Contains(message, "unauthorized"):
+		return ErrAuthExpired
+	case strings.Contains(message, "too many requests"):
+		return ErrRateLimited
 	}
+	return nil
+}
 
-	resp, err := t.graphqlRequest(ctx, gqlQuery, variables)
-	if err != nil {
-		return nil, fmt.Errorf("failed to search anime: %w", err)
+// anilistListStatus maps an Anilist list status to ours. REPEATING has no
+// equivalent and counts as watching, with the entry marked as a rewatch.
+func anilistListStatus(status string) Status {
+	switch strings.ToLower(status) {
+	case "completed":
+		return StatusCompleted
+	case "paused":
+		return StatusOnHold
+	case "dropped":
+		return StatusDropped
+	case "planning":
+		return StatusPlanToWatch
+	default:
+		return StatusWatching
 	}
+}
 
-	var result struct {
-		Data struct {
-			Page struct {
-				Media []struct {
-					ID    int `json:"id"`
-					Title struct {
-						Romaji        string `json:"romaji"`
-						English       string `json:"english"`
-						Native        string `json:"native"`
-						UserPreferred string `json:"userPreferred"`
-					} `json:"title"`
-					Synonyms    []string `json:"synonyms"`
-					Description string   `json:"description"`
-					Format      string   `json:"format"`
-					Status      string   `json:"status"`
-					Episodes    int      `json:"episodes"`
-					Duration    int      `json:"duration"`
-					Genres      []string `json:"genres"`
-					Studios     struct {
-						Nodes []struct {
-							Name string `json:"name"`
-						} `json:"nodes"`
-					} `json:"studios"`
-					SeasonYear   int     `json:"seasonYear"`
-					Season       string  `json:"season"`
-					AverageScore float64 `json:"averageScore"`
-					CoverImage   struct {
-						Large string `json:"large"`
-					} `json:"coverImage"`
-					StartDate struct {
-						Year  int `json:"year"`
-						Month int `json:"month"`
-						Day   int `json:"day"`
-					} `json:"startDate"`
-					EndDate struct {
-						Year  int `json:"year"`
-						Month int `json:"month"`
-						Day   int `json:"day"`
-					} `json:"endDate"`
-				} `json:"media"`
-			} `json:"Page"`
-		} `json:"data"`
+// anilistMediaFields are the media fields requested for anime listings
+const anilistMediaFields = `
+	id
+	idMal
+	title {
+		romaji
+		english
+		native
+		userPreferred
+	}
+	synonyms
+	description
+	format
+	status
+	episodes
+	duration
+	genres
+	studios {
+		nodes {
+			name
+		}
 	}
+	seasonYear
+	season
+	averageScore
+	popularity
+	favourites
+	coverImage {
+		large
+	}
+	startDate {
+		year
+		month
+		day
+	}
+	endDate {
+		year
+		month
+		day
+	}
+`
+
+// anilistMedia is a media entry of an Anilist anime listing
+type anilistMedia struct {
+	ID    int `json:"id"`
+	IDMal int `json:"idMal"`
+	Title struct {
+		Romaji        string `json:"romaji"`
+		English       string `json:"english"`
+		Native        string `json:"native"`
+		UserPreferred string `json:"userPreferred"`
+	} `json:"title"`
+	Synonyms    []string `json:"synonyms"`
+	Description string   `json:"description"`
+	Format      string   `json:"format"`
+	Status      string   `json:"status"`
+	Episodes    int      `json:"episodes"`
+	Duration    int      `json:"duration"`
+	Genres      []string `json:"genres"`
+	Studios     struct {
+		Nodes []struct {
+			Name string `json:"name"`
+		} `json:"nodes"`
+	} `json:"studios"`
+	SeasonYear   int     `json:"seasonYear"`
+	Season       string  `json:"season"`
+	AverageScore float64 `json:"averageScore"`
+	Popularity   int     `json:"popularity"`
+	Favourites   int     `json:"favourites"`
+	CoverImage   struct {
+		Large string `json:"large"`
+	} `json:"coverImage"`
+	StartDate struct {
+		Year  int `json:"year"`
+		Month int `json:"month"`
+		Day   int `json:"day"`
+	} `json:"startDate"`
+	EndDate struct {
+		Year  int `json:"year"`
+		Month int `json:"month"`
+		Day   int `json:"day"`
+	} `json:"endDate"`
+}
 
-	if err := json.Unmarshal(resp, &result); err != nil {
-		return nil, fmt.Errorf("failed to parse search results: %w", err)
+// anilistMalID formats the MyAnimeList ID of an Anilist media, which is 0
+// when Anilist doesn't know it
+func anilistMalID(idMal int) string {
+	if idMal <= 0 {
+		return ""
 	}
+	return strconv.Itoa(idMal)
+}
 
-	animes := make([]AnimeInfo, 0, len(result.Data.Page.Media))
-	for _, media := range result.Data.Page.Media {
+// anilistMediaToAnimeInfo converts Anilist media entries to AnimeInfo
+func anilistMediaToAnimeInfo(entries []anilistMedia) []AnimeInfo {
+	animes := make([]AnimeInfo, 0, len(entries))
+	for _, media := range entries {
 		studios := make([]string, 0, len(media.Studios.Nodes))
 		for _, studio := range media.Studios.Nodes {
 			studios = append(studios, studio.Name)
@@ -458,6 +537,7 @@ func (t *AnilistTracker) SearchAnime(ctx context.Context, query string, limit in
 		anime := AnimeInfo{
 			ID:                strconv.Itoa(media.ID),
 			Title:             media.Title.UserPreferred,
+			RomajiTitle:       media.Title.Romaji,
 			EnglishTitle:      media.Title.English,
 			JapaneseTitle:     media.Title.Native,
 			AlternativeTitles: alternativeTitles,
@@ -470,15 +550,285 @@ func (t *AnilistTracker) SearchAnime(ctx context.Context, query string, limit in
 			Year:              media.SeasonYear,
 			Season:            strings.ToLower(media.Season),
 			Rating:            media.AverageScore / 10.0, // Convert to 10-point scale
+			MembersCount:      media.Popularity,
+			Favourites:        media.Favourites,
 			Genres:            media.Genres,
 			Studios:           studios,
 			ImageURL:          media.CoverImage.Large,
+			MalID:             anilistMalID(media.IDMal),
 		}
 
 		animes = append(animes, anime)
 	}
 
-	return animes, nil
+	return animes
+}
+
+// SearchAnime searches for anime on Anilist, requesting as many pages as limit needs
+func (t *AnilistTracker) SearchAnime(ctx context.Context, query string, limit int) ([]AnimeInfo, error) {
+	gqlQuery := `
+	query ($search: String, $page: Int, $perPage: Int) {
+		Page(page: $page, perPage: $perPage) {
+			media(search: $search, type: ANIME) {
+				` + anilistMediaFields + `
+			}
+		}
+	}
+	`
+
+	return searchPages(limit, func(page, perPage int) ([]AnimeInfo, error) {
+		variables := map[string]interface{}{
+			"search":  query,
+			"page":    page,
+			"perPage": perPage,
+		}
+
+		resp, err := t.graphqlRequest(ctx, gqlQuery, variables)
+		if err != nil {
+			return nil, fmt.Errorf("failed to search anime: %w", err)
+		}
+
+		var result struct {
+			Data struct {
+				Page struct {
+					Media []anilistMedia `json:"media"`
+				} `json:"Page"`
+			} `json:"data"`
+		}
+
+		if err := json.Unmarshal(resp, &result); err != nil {
+			return nil, fmt.Errorf("failed to parse search results: %w", err)
+		}
+
+		return anilistMediaToAnimeInfo(result.Data.Page.Media), nil
+	})
+}
+
+// anilistBatchSize is the most anime Anilist returns on one page
+const anilistBatchSize = 50
+
+// GetAnimeDetailsBatch gets the details of many anime, fetching up to
+// anilistBatchSize per request. Relations aren't included.
+func (t *AnilistTracker) GetAnimeDetailsBatch(ctx context.Context, ids []string) (map[string]*AnimeInfo, error) {
+	mediaIDs := make([]int, 0, len(ids))
+	for _, id := range ids {
+		mediaID, err := strconv.Atoi(id)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ID %q: %w", id, err)
+		}
+		mediaIDs = append(mediaIDs, mediaID)
+	}
+
+	gqlQuery := `
+	query ($ids: [Int], $perPage: Int) {
+		Page(page: 1, perPage: $perPage) {
+			media(id_in: $ids, type: ANIME) {
+				` + anilistMediaFields + `
+			}
+		}
+	}
+	`
+
+	details := make(map[string]*AnimeInfo, len(ids))
+	for start := 0; start < len(mediaIDs); start += anilistBatchSize {
+		end := start + anilistBatchSize
+		if end > len(mediaIDs) {
+			end = len(mediaIDs)
+		}
+
+		variables := map[string]interface{}{
+			"ids":     mediaIDs[start:end],
+			"perPage": anilistBatchSize,
+		}
+
+		resp, err := t.graphqlRequest(ctx, gqlQuery, variables)
+		if err != nil {
+			return details, fmt.Errorf("failed to get anime details: %w", err)
+		}
+
+		var result struct {
+			Data struct {
+				Page struct {
+					Media []anilistMedia `json:"media"`
+				} `json:"Page"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(resp, &result); err != nil {
+			return details, fmt.Errorf("failed to parse anime details: %w", err)
+		}
+
+		for _, anime := range anilistMediaToAnimeInfo(result.Data.Page.Media) {
+			details[anime.ID] = &anime
+		}
+	}
+
+	return details, nil
+}
+
+// GetSeasonalAnime lists the most popular anime of a season
+func (t *AnilistTracker) GetSeasonalAnime(ctx context.Context, year int, season string) ([]AnimeInfo, error) {
+	season, err := normalizeSeason(season)
+	if err != nil {
+		return nil, err
+	}
+
+	gqlQuery := `
+	query ($season: MediaSeason, $seasonYear: Int, $perPage: Int) {
+		Page(page: 1, perPage: $perPage) {
+			media(season: $season, seasonYear: $seasonYear, type: ANIME, sort: POPULARITY_DESC) {
+				` + anilistMediaFields + `
+			}
+		}
+	}
+	`
+
+	variables := map[string]interface{}{
+		"season":     strings.ToUpper(season),
+		"seasonYear": year,
+		"perPage":    seasonalAnimeLimit,
+	}
+
+	resp, err := t.graphqlRequest(ctx, gqlQuery, variables)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get seasonal anime: %w", err)
+	}
+
+	var result struct {
+		Data struct {
+			Page struct {
+				Media []anilistMedia `json:"media"`
+			} `json:"Page"`
+		} `json:"data"`
+	}
+
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse seasonal anime: %w", err)
+	}
+
+	return anilistMediaToAnimeInfo(result.Data.Page.Media), nil
+}
+
+// GetTrendingAnime lists the anime currently trending on Anilist
+func (t *AnilistTracker) GetTrendingAnime(ctx context.Context, limit int) ([]AnimeInfo, error) {
+	gqlQuery := `
+	query ($perPage: Int) {
+		Page(page: 1, perPage: $perPage) {
+			media(type: ANIME, sort: TRENDING_DESC) {
+				` + anilistMediaFields + `
+			}
+		}
+	}
+	`
+
+	variables := map[string]interface{}{
+		"perPage": limit,
+	}
+
+	resp, err := t.graphqlRequest(ctx, gqlQuery, variables)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trending anime: %w", err)
+	}
+
+	var result struct {
+		Data struct {
+			Page struct {
+				Media []anilistMedia `json:"media"`
+			} `json:"Page"`
+		} `json:"data"`
+	}
+
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse trending anime: %w", err)
+	}
+
+	return anilistMediaToAnimeInfo(result.Data.Page.Media), nil
+}
+
+// GetAiringSchedule lists the next episode of each airing anime on the user's
+// watching list, soonest first
+func (t *AnilistTracker) GetAiringSchedule(ctx context.Context) ([]AiringEntry, error) {
+	userID, err := t.getCurrentUser(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user ID: %w", err)
+	}
+
+	query := `
+	query ($userId: Int) {
+		MediaListCollection(userId: $userId, type: ANIME, status: CURRENT) {
+			lists {
+				entries {
+					media {
+						id
+						idMal
+						title {
+							userPreferred
+						}
+						nextAiringEpisode {
+							airingAt
+							episode
+						}
+					}
+				}
+			}
+		}
+	}`
+
+	variables := map[string]interface{}{
+		"userId": userID,
+	}
+
+	resp, err := t.graphqlRequest(ctx, query, variables)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get airing schedule: %w", err)
+	}
+
+	var result struct {
+		Data struct {
+			MediaListCollection struct {
+				Lists []struct {
+					Entries []struct {
+						Media struct {
+							ID    int `json:"id"`
+							IDMal int `json:"idMal"`
+							Title struct {
+								UserPreferred string `json:"userPreferred"`
+							} `json:"title"`
+							NextAiringEpisode *struct {
+								AiringAt int64 `json:"airingAt"`
+								Episode  int   `json:"episode"`
+							} `json:"nextAiringEpisode"`
+						} `json:"media"`
+					} `json:"entries"`
+				} `json:"lists"`
+			} `json:"MediaListCollection"`
+		} `json:"data"`
+	}
+
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse airing schedule: %w", err)
+	}
+
+	var schedule []AiringEntry
+	for _, list := range result.Data.MediaListCollection.Lists {
+		for _, entry := range list.Entries {
+			next := entry.Media.NextAiringEpisode
+			if next == nil {
+				continue // Finished or not yet scheduled
+			}
+			schedule = append(schedule, AiringEntry{
+				AnimeID:  strconv.Itoa(entry.Media.ID),
+				Title:    entry.Media.Title.UserPreferred,
+				Episode:  next.Episode,
+				AiringAt: time.Unix(next.AiringAt, 0),
+			})
+		}
+	}
+
+	sort.Slice(schedule, func(i, j int) bool {
+		return schedule[i].AiringAt.Before(schedule[j].AiringAt)
+	})
+
+	return schedule, nil
 }
 
 // GetAnimeDetails gets detailed information about an anime
@@ -487,6 +837,7 @@ func (t *AnilistTracker) GetAnimeDetails(ctx context.Context, id string) (*Anime
 	query ($id: Int) {
 		Media(id: $id, type: ANIME) {
 			id
+			idMal
 			title {
 				romaji
 				english
@@ -508,6 +859,8 @@ func (t *AnilistTracker) GetAnimeDetails(ctx context.Context, id string) (*Anime
 			seasonYear
 			season
 			averageScore
+			popularity
+			favourites
 			coverImage {
 				large
 			}
@@ -521,6 +874,27 @@ func (t *AnilistTracker) GetAnimeDetails(ctx context.Context, id string) (*Anime
 				month
 				day
 			}
+			relations {
+				edges {
+					relationType
+					node {
+						id
+						idMal
+						type
+						format
+						episodes
+						seasonYear
+						title {
+							userPreferred
+							english
+							native
+						}
+						coverImage {
+							large
+						}
+					}
+				}
+			}
 		}
 	}
 	`
@@ -543,6 +917,7 @@ func (t *AnilistTracker) GetAnimeDetails(ctx context.Context, id string) (*Anime
 		Data struct {
 			Media struct {
 				ID    int `json:"id"`
+				IDMal int `json:"idMal"`
 				Title struct {
 					Romaji        string `json:"romaji"`
 					English       string `json:"english"`
@@ -564,6 +939,8 @@ func (t *AnilistTracker) GetAnimeDetails(ctx context.Context, id string) (*Anime
 				SeasonYear   int     `json:"seasonYear"`
 				Season       string  `json:"season"`
 				AverageScore float64 `json:"averageScore"`
+				Popularity   int     `json:"popularity"`
+				Favourites   int     `json:"favourites"`
 				CoverImage   struct {
 					Large string `json:"large"`
 				} `json:"coverImage"`
@@ -577,6 +954,27 @@ func (t *AnilistTracker) GetAnimeDetails(ctx context.Context, id string) (*Anime
 					Month int `json:"month"`
 					Day   int `json:"day"`
 				} `json:"endDate"`
+				Relations struct {
+					Edges []struct {
+						RelationType string `json:"relationType"`
+						Node         struct {
+							ID         int    `json:"id"`
+							IDMal      int    `json:"idMal"`
+							Type       string `json:"type"`
+							Format     string `json:"format"`
+							Episodes   int    `json:"episodes"`
+							SeasonYear int    `json:"seasonYear"`
+							Title      struct {
+								UserPreferred string `json:"userPreferred"`
+								English       string `json:"english"`
+								Native        string `json:"native"`
+							} `json:"title"`
+							CoverImage struct {
+								Large string `json:"large"`
+							} `json:"coverImage"`
+						} `json:"node"`
+					} `json:"edges"`
+				} `json:"relations"`
 			} `json:"Media"`
 		} `json:"data"`
 	}
@@ -626,6 +1024,7 @@ func (t *AnilistTracker) GetAnimeDetails(ctx context.Context, id string) (*Anime
 	anime := &AnimeInfo{
 		ID:                strconv.Itoa(media.ID),
 		Title:             media.Title.UserPreferred,
+		RomajiTitle:       media.Title.Romaji,
 		EnglishTitle:      media.Title.English,
 		JapaneseTitle:     media.Title.Native,
 		AlternativeTitles: alternativeTitles,
@@ -638,14 +1037,50 @@ func (t *AnilistTracker) GetAnimeDetails(ctx context.Context, id string) (*Anime
 		Year:              media.SeasonYear,
 		Season:            strings.ToLower(media.Season),
 		Rating:            media.AverageScore / 10.0, // Convert to 10-point scale
+		MembersCount:      media.Popularity,
+		Favourites:        media.Favourites,
 		Genres:            media.Genres,
 		Studios:           studios,
 		ImageURL:          media.CoverImage.Large,
+		MalID:             anilistMalID(media.IDMal),
 	}
 
+	// Collect related anime, skipping manga and novels
+	for _, edge := range media.Relations.Edges {
+		if edge.Node.Type != "ANIME" {
+			continue
+		}
+		anime.Relations = append(anime.Relations, RelatedAnime{
+			AnimeInfo: AnimeInfo{
+				ID:            strconv.Itoa(edge.Node.ID),
+				Title:         edge.Node.Title.UserPreferred,
+				EnglishTitle:  edge.Node.Title.English,
+				JapaneseTitle: edge.Node.Title.Native,
+				Type:          edge.Node.Format,
+				Episodes:      edge.Node.Episodes,
+				Year:          edge.Node.SeasonYear,
+				ImageURL:      edge.Node.CoverImage.Large,
+				MalID:         anilistMalID(edge.Node.IDMal),
+			},
+			RelationType: edge.RelationType,
+		})
+	}
+	SortRelations(anime.Relations)
+
 	return anime, nil
 }
 
+// Ping checks the stored login by asking Anilist who the user is
+func (t *AnilistTracker) Ping(ctx context.Context) error {
+	_, err := t.getCurrentUser(ctx)
+	return err
+}
+
+// tokenFile returns the file the login token is stored in
+func (t *AnilistTracker) tokenFile() string {
+	return t.tokenPath
+}
+
 // getCurrentUser gets the current user's information
 func (t *AnilistTracker) getCurrentUser(ctx context.Context) (int, error) {
 	query := `
@@ -677,6 +1112,47 @@ func (t *AnilistTracker) getCurrentUser(ctx context.Context) (int, error) {
 	return result.Data.Viewer.ID, nil
 }
 
+// ScoreFormat returns the score format the user picked in their Anilist settings
+func (t *AnilistTracker) ScoreFormat(ctx context.Context) (ScoreFormat, error) {
+	if t.scoreFormat != "" {
+		return t.scoreFormat, nil
+	}
+
+	query := `
+	query {
+		Viewer {
+			mediaListOptions {
+				scoreFormat
+			}
+		}
+	}`
+
+	resp, err := t.graphqlRequest(ctx, query, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to get score format: %w", err)
+	}
+
+	var result struct {
+		Data struct {
+			Viewer struct {
+				MediaListOptions struct {
+					ScoreFormat ScoreFormat `json:"scoreFormat"`
+				} `json:"mediaListOptions"`
+			} `json:"Viewer"`
+		} `json:"data"`
+	}
+
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return "", fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	t.scoreFormat = result.Data.Viewer.MediaListOptions.ScoreFormat
+	if t.scoreFormat == "" {
+		t.scoreFormat = ScorePoint10
+	}
+	return t.scoreFormat, nil
+}
+
 // GetUserAnimeList gets the user's anime list
 func (t *AnilistTracker) GetUserAnimeList(ctx context.Context) ([]UserAnimeEntry, error) {
 	// Get current user's ID
@@ -692,6 +1168,7 @@ func (t *AnilistTracker) GetUserAnimeList(ctx context.Context) ([]UserAnimeEntry
 				entries {
 					media {
 						id
+						idMal
 						title {
 							userPreferred
 							english
@@ -752,6 +1229,7 @@ func (t *AnilistTracker) GetUserAnimeList(ctx context.Context) ([]UserAnimeEntry
 					Entries []struct {
 						Media struct {
 							ID    int `json:"id"`
+							IDMal int `json:"idMal"`
 							Title struct {
 								UserPreferred string `json:"userPreferred"`
 								English       string `json:"english"`
@@ -821,20 +1299,7 @@ func (t *AnilistTracker) GetUserAnimeList(ctx context.Context) ([]UserAnimeEntry
 				studios = append(studios, studio.Name)
 			}
 
-			// Map status
-			status := StatusWatching
-			switch strings.ToLower(item.Status) {
-			case "current":
-				status = StatusWatching
-			case "completed":
-				status = StatusCompleted
-			case "paused":
-				status = StatusOnHold
-			case "dropped":
-				status = StatusDropped
-			case "planning":
-				status = StatusPlanToWatch
-			}
+			status := anilistListStatus(item.Status)
 
 			entry := UserAnimeEntry{
 				AnimeInfo: AnimeInfo{
@@ -852,10 +1317,12 @@ func (t *AnilistTracker) GetUserAnimeList(ctx context.Context) ([]UserAnimeEntry
 					Genres:        media.Genres,
 					Studios:       studios,
 					ImageURL:      media.CoverImage.Large,
+					MalID:         anilistMalID(media.IDMal),
 				},
 				Status:      status,
 				Score:       item.Score,
 				Progress:    float64(item.Progress),
+				Rewatching:  strings.EqualFold(item.Status, "repeating"),
 				StartDate:   startDate,
 				EndDate:     endDate,
 				Notes:       item.Notes,
@@ -869,6 +1336,150 @@ func (t *AnilistTracker) GetUserAnimeList(ctx context.Context) ([]UserAnimeEntry
 	return entries, nil
 }
 
+// GetListEntry gets the current state of a single entry of the user's list
+func (t *AnilistTracker) GetListEntry(ctx context.Context, id string) (*UserAnimeEntry, error) {
+	mediaID, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID: %w", err)
+	}
+
+	userID, err := t.getCurrentUser(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user ID: %w", err)
+	}
+
+	query := `
+	query ($mediaId: Int, $userId: Int) {
+		MediaList(mediaId: $mediaId, userId: $userId) {
+			media {
+				id
+				title {
+					userPreferred
+				}
+				episodes
+			}
+			score
+			progress
+			status
+			updatedAt
+			notes
+		}
+	}`
+
+	variables := map[string]interface{}{
+		"mediaId": mediaID,
+		"userId":  userID,
+	}
+
+	resp, err := t.graphqlRequest(ctx, query, variables)
+	if errors.Is(err, ErrRemoteNotFound) {
+		return nil, ErrNotInList
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get list entry: %w", err)
+	}
+
+	var result struct {
+		Data struct {
+			MediaList *struct {
+				Media struct {
+					ID    int `json:"id"`
+					Title struct {
+						UserPreferred string `json:"userPreferred"`
+					} `json:"title"`
+					Episodes int `json:"episodes"`
+				} `json:"media"`
+				Score     float64 `json:"score"`
+				Progress  int     `json:"progress"`
+				Status    string  `json:"status"`
+				UpdatedAt int64   `json:"updatedAt"`
+				Notes     string  `json:"notes"`
+			} `json:"MediaList"`
+		} `json:"data"`
+	}
+
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	item := result.Data.MediaList
+	if item == nil {
+		return nil, ErrNotInList
+	}
+
+	return &UserAnimeEntry{
+		AnimeInfo: AnimeInfo{
+			ID:       strconv.Itoa(item.Media.ID),
+			Title:    item.Media.Title.UserPreferred,
+			Episodes: item.Media.Episodes,
+		},
+		Status:      anilistListStatus(item.Status),
+		Score:       item.Score,
+		Progress:    float64(item.Progress),
+		Rewatching:  strings.EqualFold(item.Status, "repeating"),
+		Notes:       item.Notes,
+		LastUpdated: time.Unix(item.UpdatedAt, 0),
+	}, nil
+}
+
+// DeleteRemoteEntry removes an anime from the user's Anilist list
+func (t *AnilistTracker) DeleteRemoteEntry(ctx context.Context, id string) error {
+	mediaID, err := strconv.Atoi(id)
+	if err != nil {
+		return fmt.Errorf("invalid ID: %w", err)
+	}
+
+	userID, err := t.getCurrentUser(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get user ID: %w", err)
+	}
+
+	// Entries are deleted by their list entry ID, not the media ID
+	query := `
+	query ($mediaId: Int, $userId: Int) {
+		MediaList(mediaId: $mediaId, userId: $userId) {
+			id
+		}
+	}`
+
+	resp, err := t.graphqlRequest(ctx, query, map[string]interface{}{
+		"mediaId": mediaID,
+		"userId":  userID,
+	})
+	if errors.Is(err, ErrRemoteNotFound) {
+		return ErrNotInList
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get list entry: %w", err)
+	}
+
+	var result struct {
+		Data struct {
+			MediaList *struct {
+				ID int `json:"id"`
+			} `json:"MediaList"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	if result.Data.MediaList == nil {
+		return ErrNotInList
+	}
+
+	mutation := `
+	mutation ($id: Int) {
+		DeleteMediaListEntry(id: $id) {
+			deleted
+		}
+	}`
+
+	if _, err := t.graphqlRequest(ctx, mutation, map[string]interface{}{"id": result.Data.MediaList.ID}); err != nil {
+		return fmt.Errorf("failed to delete list entry: %w", err)
+	}
+	return nil
+}
+
 // UpdateAnimeStatus updates the watch status of an anime
 func (t *AnilistTracker) UpdateAnimeStatus(ctx context.Context, id string, status Status, episode float64, score float64) error {
 	mediaID, err := strconv.Atoi(id)
@@ -904,15 +1515,20 @@ func (t *AnilistTracker) UpdateAnimeStatus(ctx context.Context, id string, statu
 
 	variables := map[string]interface{}{
 		"mediaId": mediaID,
-		"status":  anilistStatus,
 	}
 
-	if episode > 0 {
-		variables["progress"] = int(episode)
+	// An empty status only updates progress or score
+	if status != "" {
+		variables["status"] = anilistStatus
+	}
+
+	if progress := RemoteProgress(episode); progress > 0 {
+		variables["progress"] = progress
 	}
 
 	if score > 0 {
-		// Anilist uses a 10-point scale
+		// score is in the user's configured score format, which Anilist
+		// reads the score argument in
 		variables["score"] = score
 	}
 
@@ -924,6 +1540,78 @@ func (t *AnilistTracker) UpdateAnimeStatus(ctx context.Context, id string, statu
 	return nil
 }
 
+// UpdateNotes sets the notes of an anime on the user's Anilist list
+func (t *AnilistTracker) UpdateNotes(ctx context.Context, id string, notes string) error {
+	mediaID, err := strconv.Atoi(id)
+	if err != nil {
+		return fmt.Errorf("invalid ID: %w", err)
+	}
+
+	gqlQuery := `
+	mutation ($mediaId: Int, $notes: String) {
+		SaveMediaListEntry(mediaId: $mediaId, notes: $notes) {
+			id
+		}
+	}
+	`
+	variables := map[string]interface{}{
+		"mediaId": mediaID,
+		"notes":   notes,
+	}
+
+	if _, err := t.graphqlRequest(ctx, gqlQuery, variables); err != nil {
+		return fmt.Errorf("failed to update notes: %w", err)
+	}
+	return nil
+}
+
+// FinishRewatch ends the rewatch of an anime on the user's Anilist list.
+// Anilist keeps a rewatch as the REPEATING status, so it goes back to
+// COMPLETED.
+func (t *AnilistTracker) FinishRewatch(ctx context.Context, id string) error {
+	return t.UpdateAnimeStatus(ctx, id, StatusCompleted, 0, 0)
+}
+
+// SetFinishDate sets the day an anime on the user's Anilist list was completed
+func (t *AnilistTracker) SetFinishDate(ctx context.Context, id string, date time.Time) error {
+	mediaID, err := strconv.Atoi(id)
+	if err != nil {
+		return fmt.Errorf("invalid ID: %w", err)
+	}
+
+	gqlQuery := `
+	mutation ($mediaId: Int, $completedAt: FuzzyDateInput) {
+		SaveMediaListEntry(mediaId: $mediaId, completedAt: $completedAt) {
+			id
+		}
+	}
+	`
+	variables := map[string]interface{}{
+		"mediaId": mediaID,
+		"completedAt": map[string]int{
+			"year":  date.Year(),
+			"month": int(date.Month()),
+			"day":   date.Day(),
+		},
+	}
+
+	if _, err := t.graphqlRequest(ctx, gqlQuery, variables); err != nil {
+		return fmt.Errorf("failed to set finish date: %w", err)
+	}
+	return nil
+}
+
+// recordMalID stores the MAL ID Anilist gave for an entry, so a later MAL
+// sync finds the same anime instead of adding it again
+func (t *AnilistTracker) recordMalID(db *database.DB, animeID int64, entry UserAnimeEntry, stats *SyncStats) {
+	if entry.MalID == "" {
+		return
+	}
+	if err := db.SetAnimeMalID(animeID, entry.MalID); err != nil {
+		stats.Details = append(stats.Details, fmt.Sprintf("Failed to record the MAL ID of %s: %v", entry.Title, err))
+	}
+}
+
 // SyncFromRemote synchronizes the local database with Anilist
 func (t *AnilistTracker) SyncFromRemote(ctx context.Context, db *database.DB) (SyncStats, error) {
 	stats := SyncStats{
@@ -939,7 +1627,11 @@ func (t *AnilistTracker) SyncFromRemote(ctx context.Context, db *database.DB) (S
 	for _, entry := range entries {
 		// Check if anime exists in database
 		anime, err := db.GetAnimeByExternalID(entry.ID, t.Name())
-		if err != nil && err != sql.ErrNoRows {
+		if errors.Is(err, database.ErrAnimeNotFound) && entry.MalID != "" {
+			// The same show may already be in the library from MAL
+			anime, err = db.GetAnimeByMalID(entry.MalID)
+		}
+		if err != nil && !errors.Is(err, database.ErrAnimeNotFound) {
 			stats.Errors++
 			stats.Details = append(stats.Details, fmt.Sprintf("Error checking anime %s: %v", entry.Title, err))
 			continue
@@ -966,6 +1658,7 @@ func (t *AnilistTracker) SyncFromRemote(ctx context.Context, db *database.DB) (S
 				stats.Details = append(stats.Details, fmt.Sprintf("Failed to add anime %s: %v", entry.Title, err))
 				continue
 			}
+			t.recordMalID(db, animeData.ID, entry, &stats)
 
 			// Add tracking information
 			tracking := &database.AnimeTracking{
@@ -976,6 +1669,7 @@ func (t *AnilistTracker) SyncFromRemote(ctx context.Context, db *database.DB) (S
 				Score:          entry.Score,
 				CurrentEpisode: entry.Progress,
 				TotalEpisodes:  entry.Episodes,
+				IsRewatching:   entry.Rewatching,
 				LastUpdated:    entry.LastUpdated,
 			}
 
@@ -988,6 +1682,8 @@ func (t *AnilistTracker) SyncFromRemote(ctx context.Context, db *database.DB) (S
 			stats.Added++
 			stats.Details = append(stats.Details, fmt.Sprintf("Added anime: %s", entry.Title))
 		} else {
+			t.recordMalID(db, anime.ID, entry, &stats)
+
 			// Anime exists, update tracking
 			tracking, err := db.GetAnimeTracking(anime.ID, t.Name())
 			if err != nil && err != sql.ErrNoRows {
@@ -1006,6 +1702,7 @@ func (t *AnilistTracker) SyncFromRemote(ctx context.Context, db *database.DB) (S
 					Score:          entry.Score,
 					CurrentEpisode: entry.Progress,
 					TotalEpisodes:  entry.Episodes,
+					IsRewatching:   entry.Rewatching,
 					LastUpdated:    entry.LastUpdated,
 				}
 
@@ -1020,10 +1717,15 @@ func (t *AnilistTracker) SyncFromRemote(ctx context.Context, db *database.DB) (S
 			} else {
 				// Tracking exists, update if remote is newer
 				if entry.LastUpdated.After(tracking.LastUpdated) {
+					if err := RecordConflicts(db, tracking, &entry, database.ConflictRemote); err != nil {
+						stats.Details = append(stats.Details, fmt.Sprintf("Failed to log sync conflicts for %s: %v", entry.Title, err))
+					}
+
 					tracking.Status = string(entry.Status)
 					tracking.Score = entry.Score
 					tracking.CurrentEpisode = entry.Progress
 					tracking.TotalEpisodes = entry.Episodes
+					tracking.IsRewatching = entry.Rewatching
 					tracking.LastUpdated = entry.LastUpdated
 
 					if err := db.UpdateAnimeTrackingObject(tracking); err != nil {
@@ -1042,7 +1744,7 @@ func (t *AnilistTracker) SyncFromRemote(ctx context.Context, db *database.DB) (S
 	}
 
 	// Update last sync time
-	if err := db.SetConfig("anilist_last_sync", time.Now().Format(time.RFC3339)); err != nil {
+	if err := db.SetConfig(lastSyncKey(t.Name()), time.Now().Format(time.RFC3339)); err != nil {
 		return stats, fmt.Errorf("failed to update last sync time: %w", err)
 	}
 
@@ -1056,7 +1758,7 @@ func (t *AnilistTracker) SyncToRemote(ctx context.Context, db *database.DB) (Syn
 	}
 
 	// Get last sync time
-	lastSyncStr, err := db.GetConfig("anilist_last_sync")
+	lastSyncStr, err := db.GetConfig(lastSyncKey(t.Name()))
 	if err != nil && err != sql.ErrNoRows {
 		return stats, fmt.Errorf("failed to get last sync time: %w", err)
 	}
@@ -1109,7 +1811,7 @@ func (t *AnilistTracker) SyncToRemote(ctx context.Context, db *database.DB) (Syn
 	}
 
 	// Update last sync time
-	if err := db.SetConfig("anilist_last_sync", time.Now().Format(time.RFC3339)); err != nil {
+	if err := db.SetConfig(lastSyncKey(t.Name()), time.Now().Format(time.RFC3339)); err != nil {
 		return stats, fmt.Errorf("failed to update last sync time: %w", err)
 	}
 
@@ -0,0 +1,69 @@
+package tracker
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/wraient/pair/pkg/database"
+)
+
+// RecordConflicts logs the status, score and progress that differ between a
+// local tracking entry and a remote entry before one overwrites the other.
+// winner is the side whose values are kept, database.ConflictLocal or
+// database.ConflictRemote. Only entries changed on both sides since the last
+// sync with the tracker are logged; when just one side changed, the other
+// still holds the synced values and nothing is lost. Progress only counts as
+// a conflict when the whole episodes differ, since remotes can't store
+// fractional episodes.
+func RecordConflicts(db *database.DB, local *database.AnimeTracking, remote *UserAnimeEntry, winner string) error {
+	lastSync, err := db.GetLastSync(local.Tracker)
+	if err != nil {
+		return err
+	}
+	if !local.LastUpdated.After(lastSync) || !remote.LastUpdated.After(lastSync) {
+		return nil
+	}
+
+	for _, conflict := range conflictingFields(local, remote) {
+		conflict.Winner = winner
+		if err := db.AddSyncConflict(conflict); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// conflictingFields returns a conflict for each field that differs
+func conflictingFields(local *database.AnimeTracking, remote *UserAnimeEntry) []*database.SyncConflict {
+	newConflict := func(field, localValue, remoteValue string) *database.SyncConflict {
+		return &database.SyncConflict{
+			AnimeID:     local.AnimeID,
+			Tracker:     local.Tracker,
+			Field:       field,
+			LocalValue:  localValue,
+			RemoteValue: remoteValue,
+		}
+	}
+
+	var conflicts []*database.SyncConflict
+	if local.Status != string(remote.Status) {
+		conflicts = append(conflicts, newConflict("status", local.Status, string(remote.Status)))
+	}
+	if local.Score != remote.Score {
+		conflicts = append(conflicts, newConflict("score", fmt.Sprintf("%g", local.Score), fmt.Sprintf("%g", remote.Score)))
+	}
+	if float64(RemoteProgress(local.CurrentEpisode)) != remote.Progress {
+		conflicts = append(conflicts, newConflict("progress", fmt.Sprintf("%g", local.CurrentEpisode), fmt.Sprintf("%g", remote.Progress)))
+	}
+	return conflicts
+}
+
+// PruneConflicts deletes sync conflicts older than retention. A retention of
+// zero or less keeps them all.
+func PruneConflicts(db *database.DB, retention time.Duration) error {
+	if retention <= 0 {
+		return nil
+	}
+	_, err := db.PruneSyncConflicts(time.Now().Add(-retention))
+	return err
+}
@@ -3,7 +3,9 @@ package tracker
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/wraient/pair/pkg/database"
@@ -81,8 +83,12 @@ func (t *LocalTracker) GetAnimeDetails(ctx context.Context, id string) (*AnimeIn
 		return nil, fmt.Errorf("failed to get anime details: %w", err)
 	}
 
-	// Convert database.Anime to tracker.AnimeInfo
-	info := &AnimeInfo{
+	return localAnimeInfo(anime), nil
+}
+
+// localAnimeInfo converts a database anime to AnimeInfo
+func localAnimeInfo(anime *database.Anime) *AnimeInfo {
+	return &AnimeInfo{
 		ID:                fmt.Sprintf("%d", anime.ID),
 		Title:             anime.Title,
 		EnglishTitle:      anime.Title, // Use title as English title by default
@@ -97,8 +103,30 @@ func (t *LocalTracker) GetAnimeDetails(ctx context.Context, id string) (*AnimeIn
 		Genres:            anime.Genres,
 		ImageURL:          anime.ThumbnailURL,
 	}
+}
+
+// GetAnimeDetailsBatch gets the details of many anime with one query
+func (t *LocalTracker) GetAnimeDetailsBatch(ctx context.Context, ids []string) (map[string]*AnimeInfo, error) {
+	animeIDs := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		animeID, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ID %q: %w", id, err)
+		}
+		animeIDs = append(animeIDs, animeID)
+	}
 
-	return info, nil
+	animes, err := t.db.GetAnimeByIDs(animeIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get anime details: %w", err)
+	}
+
+	details := make(map[string]*AnimeInfo, len(animes))
+	for _, anime := range animes {
+		info := localAnimeInfo(anime)
+		details[info.ID] = info
+	}
+	return details, nil
 }
 
 // GetUserAnimeList gets the user's anime list
@@ -160,6 +188,37 @@ func (t *LocalTracker) GetUserAnimeList(ctx context.Context) ([]UserAnimeEntry,
 	return entries, nil
 }
 
+// GetListEntry gets the local tracking entry of an anime
+func (t *LocalTracker) GetListEntry(ctx context.Context, id string) (*UserAnimeEntry, error) {
+	animeID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID: %w", err)
+	}
+
+	tracking, err := t.db.GetAnimeTracking(animeID, t.Name())
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotInList
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tracking entry: %w", err)
+	}
+
+	entry := &UserAnimeEntry{
+		AnimeInfo:   AnimeInfo{ID: id},
+		Status:      Status(tracking.Status),
+		Score:       tracking.Score,
+		Progress:    tracking.CurrentEpisode,
+		Notes:       tracking.Notes,
+		LastUpdated: tracking.LastUpdated,
+	}
+	if anime, err := t.db.GetAnime(animeID); err == nil {
+		entry.Title = anime.Title
+		entry.Episodes = anime.TotalEpisodes
+	}
+
+	return entry, nil
+}
+
 // UpdateAnimeStatus updates the watch status of an anime
 func (t *LocalTracker) UpdateAnimeStatus(ctx context.Context, id string, status Status, episode float64, score float64) error {
 	// Get anime by ID
@@ -192,8 +251,10 @@ func (t *LocalTracker) UpdateAnimeStatus(ctx context.Context, id string, status
 		return fmt.Errorf("failed to get tracking entry: %w", err)
 	}
 
-	// Update tracking entry
-	tracking.Status = string(status)
+	// Update tracking entry, an empty status keeps the current one
+	if status != "" {
+		tracking.Status = string(status)
+	}
 	if episode > 0 {
 		tracking.CurrentEpisode = episode
 	}
@@ -209,6 +270,12 @@ func (t *LocalTracker) UpdateAnimeStatus(ctx context.Context, id string, status
 	return nil
 }
 
+// ScoreFormat returns the score format of the local tracker, which keeps
+// scores out of 10 with a decimal place
+func (t *LocalTracker) ScoreFormat(ctx context.Context) (ScoreFormat, error) {
+	return ScorePoint10Decimal, nil
+}
+
 // SyncFromRemote synchronizes the local database with the remote tracker
 // Local tracker doesn't need to sync from remote
 func (t *LocalTracker) SyncFromRemote(ctx context.Context, db *database.DB) (SyncStats, error) {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -12,10 +13,12 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/pkg/browser"
 	"github.com/wraient/pair/pkg/database"
+	"github.com/wraient/pair/pkg/httpclient"
 )
 
 const (
@@ -46,19 +49,31 @@ type MALTracker struct {
 	tokenPath  string
 	statePath  string
 	httpClient *http.Client
+	tokenKey   []byte
+	baseURL    string
+	// requestTimeout bounds each API request
+	requestTimeout time.Duration
+	// strictPaging fails a list fetch when a page keeps failing instead of
+	// returning the pages fetched so far
+	strictPaging bool
+	// pageRetryDelay is the wait before retrying a failed page
+	pageRetryDelay time.Duration
 }
 
-// NewMALTracker creates a new MALTracker
-func NewMALTracker(configDir string) *MALTracker {
-	// Create config directory if it doesn't exist
-	if err := os.MkdirAll(configDir, 0755); err != nil {
-		fmt.Printf("Failed to create config directory: %v\n", err)
+// NewMALTracker creates a new MALTracker that keeps its login in dataDir
+func NewMALTracker(dataDir string) *MALTracker {
+	// Create data directory if it doesn't exist
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		fmt.Printf("Failed to create data directory: %v\n", err)
 	}
 
 	return &MALTracker{
-		tokenPath:  filepath.Join(configDir, malTokenFilename),
-		statePath:  filepath.Join(configDir, malStateFilename),
-		httpClient: &http.Client{Timeout: 30 * time.Second},
+		tokenPath:      filepath.Join(dataDir, malTokenFilename),
+		statePath:      filepath.Join(dataDir, malStateFilename),
+		httpClient:     httpclient.Default(),
+		baseURL:        malAPIBaseURL,
+		requestTimeout: DefaultRequestTimeout,
+		pageRetryDelay: malPageRetryDelay,
 	}
 }
 
@@ -75,9 +90,26 @@ func (t *MALTracker) IsAuthenticated() bool {
 	return t.token != nil && t.token.AccessToken != "" && time.Now().Before(t.token.ExpiresAt)
 }
 
+// APIURL returns the base URL of the MyAnimeList API
+func (t *MALTracker) APIURL() string {
+	return t.baseURL
+}
+
+// SetRequestTimeout sets how long a single API request may take.
+// Zero disables the per-request limit.
+func (t *MALTracker) SetRequestTimeout(timeout time.Duration) {
+	t.requestTimeout = timeout
+}
+
+// SetTokenEncryptionKey enables encryption of the stored token with the given key.
+// A nil key stores the token as plaintext.
+func (t *MALTracker) SetTokenEncryptionKey(key []byte) {
+	t.tokenKey = key
+}
+
 // loadToken loads the token from the token file
 func (t *MALTracker) loadToken() error {
-	data, err := os.ReadFile(t.tokenPath)
+	data, err := readTokenFile(t.tokenPath, t.tokenKey)
 	if err != nil {
 		return err
 	}
@@ -98,11 +130,11 @@ func (t *MALTracker) saveToken() error {
 		return err
 	}
 
-	return os.WriteFile(t.tokenPath, data, 0600)
+	return writeTokenFile(t.tokenPath, data, t.tokenKey)
 }
 
 // refreshToken refreshes the access token using the refresh token
-func (t *MALTracker) refreshToken() error {
+func (t *MALTracker) refreshToken(ctx context.Context) error {
 	if t.token == nil || t.token.RefreshToken == "" {
 		return fmt.Errorf("no refresh token available")
 	}
@@ -113,10 +145,14 @@ func (t *MALTracker) refreshToken() error {
 	data.Set("grant_type", "refresh_token")
 	data.Set("refresh_token", t.token.RefreshToken)
 
-	req, err := http.NewRequest("POST", malOAuthTokenURL, strings.NewReader(data.Encode()))
+	ctx, cancel := withRequestTimeout(ctx, t.requestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", malOAuthTokenURL, strings.NewReader(data.Encode()))
 	if err != nil {
 		return fmt.Errorf("failed to create refresh token request: %w", err)
 	}
+	httpclient.Identify(req)
 
 	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
 	resp, err := t.httpClient.Do(req)
@@ -150,7 +186,7 @@ func (t *MALTracker) Authenticate(ctx context.Context) error {
 
 	// Try to refresh token if available
 	if t.token != nil && t.token.RefreshToken != "" {
-		if err := t.refreshToken(); err == nil {
+		if err := t.refreshToken(ctx); err == nil {
 			return nil
 		}
 		// If refresh fails, continue with new authentication
@@ -248,10 +284,14 @@ func (t *MALTracker) Authenticate(ctx context.Context) error {
 	data.Set("redirect_uri", malRedirectURI)
 	data.Set("code_verifier", malClientID)
 
-	req, err := http.NewRequest("POST", malOAuthTokenURL, strings.NewReader(data.Encode()))
+	tokenCtx, cancel := withRequestTimeout(ctx, t.requestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(tokenCtx, "POST", malOAuthTokenURL, strings.NewReader(data.Encode()))
 	if err != nil {
 		return fmt.Errorf("failed to create token request: %w", err)
 	}
+	httpclient.Identify(req)
 
 	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
 	resp, err := t.httpClient.Do(req)
@@ -276,13 +316,22 @@ func (t *MALTracker) Authenticate(ctx context.Context) error {
 	return t.saveToken()
 }
 
+// identifyMAL sets the User-Agent of an API request and, when pair has
+// one, the client ID MAL expects
+func identifyMAL(req *http.Request) {
+	httpclient.Identify(req)
+	if malClientID != "" {
+		req.Header.Set("X-MAL-Client-ID", malClientID)
+	}
+}
+
 // apiRequest makes an authenticated request to the MAL API
 func (t *MALTracker) apiRequest(ctx context.Context, method, path string, query url.Values, body io.Reader) (*http.Response, error) {
 	if !t.IsAuthenticated() {
-		return nil, fmt.Errorf("not authenticated")
+		return nil, fmt.Errorf("not authenticated: %w", ErrAuthExpired)
 	}
 
-	u, err := url.Parse(malAPIBaseURL + path)
+	u, err := url.Parse(t.baseURL + path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse URL: %w", err)
 	}
@@ -291,91 +340,93 @@ func (t *MALTracker) apiRequest(ctx context.Context, method, path string, query
 		u.RawQuery = query.Encode()
 	}
 
+	// The caller reads the body after we return, so the timeout is released on Close
+	ctx, cancel := withRequestTimeout(ctx, t.requestTimeout)
+
 	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
 	if err != nil {
+		cancel()
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
+	identifyMAL(req)
 
 	req.Header.Add("Authorization", fmt.Sprintf("%s %s", t.token.TokenType, t.token.AccessToken))
 
 	resp, err := t.httpClient.Do(req)
 	if err != nil {
+		cancel()
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
 
 	if resp.StatusCode == http.StatusUnauthorized {
 		// Token expired, try to refresh
-		if err := t.refreshToken(); err != nil {
+		if err := t.refreshToken(ctx); err != nil {
 			resp.Body.Close()
-			return nil, fmt.Errorf("failed to refresh token: %w", err)
+			cancel()
+			return nil, fmt.Errorf("failed to refresh token: %w: %w", err, ErrAuthExpired)
 		}
 
 		// Retry the request with the new token
 		resp.Body.Close()
 		req.Header.Set("Authorization", fmt.Sprintf("%s %s", t.token.TokenType, t.token.AccessToken))
-		return t.httpClient.Do(req)
+		resp, err = t.httpClient.Do(req)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
 	}
 
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
 	return resp, nil
 }
 
-// SearchAnime searches for anime on MyAnimeList
-func (t *MALTracker) SearchAnime(ctx context.Context, query string, limit int) ([]AnimeInfo, error) {
-	q := url.Values{}
-	q.Set("q", query)
-	q.Set("limit", strconv.Itoa(limit))
-	q.Set("fields", "id,title,alternative_titles,main_picture,synopsis,mean,status,genres,media_type,num_episodes,start_season,studios")
-
-	resp, err := t.apiRequest(ctx, "GET", "/anime", q, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to search anime: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("failed to search anime: %s (%d)", string(body), resp.StatusCode)
-	}
-
-	var result struct {
-		Data []struct {
-			Node struct {
-				ID                int    `json:"id"`
-				Title             string `json:"title"`
-				AlternativeTitles struct {
-					English  string   `json:"en"`
-					Japanese string   `json:"ja"`
-					Synonyms []string `json:"synonyms"`
-				} `json:"alternative_titles"`
-				MainPicture struct {
-					Medium string `json:"medium"`
-					Large  string `json:"large"`
-				} `json:"main_picture"`
-				Synopsis string  `json:"synopsis"`
-				Mean     float64 `json:"mean"`
-				Status   string  `json:"status"`
-				Genres   []struct {
-					ID   int    `json:"id"`
-					Name string `json:"name"`
-				} `json:"genres"`
-				MediaType   string `json:"media_type"`
-				NumEpisodes int    `json:"num_episodes"`
-				StartSeason struct {
-					Year   int    `json:"year"`
-					Season string `json:"season"`
-				} `json:"start_season"`
-				Studios []struct {
-					ID   int    `json:"id"`
-					Name string `json:"name"`
-				} `json:"studios"`
-			} `json:"node"`
-		} `json:"data"`
-	}
+// malListFields are the anime fields requested for anime listings
+const malListFields = "id,title,alternative_titles,main_picture,synopsis,mean,rank,popularity,num_list_users,status,genres,media_type,num_episodes,start_season,studios"
+
+// malAnimeNode is an anime entry of a MyAnimeList anime listing
+type malAnimeNode struct {
+	ID                int    `json:"id"`
+	Title             string `json:"title"`
+	AlternativeTitles struct {
+		English  string   `json:"en"`
+		Japanese string   `json:"ja"`
+		Synonyms []string `json:"synonyms"`
+	} `json:"alternative_titles"`
+	MainPicture struct {
+		Medium string `json:"medium"`
+		Large  string `json:"large"`
+	} `json:"main_picture"`
+	Synopsis     string  `json:"synopsis"`
+	Mean         float64 `json:"mean"`
+	Rank         int     `json:"rank"`
+	Popularity   int     `json:"popularity"`
+	NumListUsers int     `json:"num_list_users"`
+	Status       string  `json:"status"`
+	Genres       []struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	} `json:"genres"`
+	MediaType   string `json:"media_type"`
+	NumEpisodes int    `json:"num_episodes"`
+	StartSeason struct {
+		Year   int    `json:"year"`
+		Season string `json:"season"`
+	} `json:"start_season"`
+	Studios []struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	} `json:"studios"`
+}
 
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode search results: %w", err)
-	}
+// malAnimeList is the paged response of MyAnimeList anime listings
+type malAnimeList struct {
+	Data []struct {
+		Node malAnimeNode `json:"node"`
+	} `json:"data"`
+}
 
+// toAnimeInfo converts a MyAnimeList anime listing to AnimeInfo
+func (result malAnimeList) toAnimeInfo() []AnimeInfo {
 	animes := make([]AnimeInfo, 0, len(result.Data))
 	for _, item := range result.Data {
 		node := item.Node
@@ -401,6 +452,7 @@ func (t *MALTracker) SearchAnime(ctx context.Context, query string, limit int) (
 		anime := AnimeInfo{
 			ID:                strconv.Itoa(node.ID),
 			Title:             node.Title,
+			RomajiTitle:       node.Title,
 			EnglishTitle:      node.AlternativeTitles.English,
 			JapaneseTitle:     node.AlternativeTitles.Japanese,
 			AlternativeTitles: alternativeTitles,
@@ -411,6 +463,9 @@ func (t *MALTracker) SearchAnime(ctx context.Context, query string, limit int) (
 			Year:              node.StartSeason.Year,
 			Season:            node.StartSeason.Season,
 			Rating:            node.Mean,
+			Rank:              node.Rank,
+			Popularity:        node.Popularity,
+			MembersCount:      node.NumListUsers,
 			Genres:            genres,
 			Studios:           studios,
 			ImageURL:          node.MainPicture.Large,
@@ -419,13 +474,145 @@ func (t *MALTracker) SearchAnime(ctx context.Context, query string, limit int) (
 		animes = append(animes, anime)
 	}
 
+	return animes
+}
+
+// getAnimeList requests an anime listing from the MAL API
+func (t *MALTracker) getAnimeList(ctx context.Context, path string, q url.Values) ([]AnimeInfo, error) {
+	resp, err := t.apiRequest(ctx, "GET", path, q, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, apiError("", resp.StatusCode, body)
+	}
+
+	var result malAnimeList
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode anime list: %w", err)
+	}
+
+	return result.toAnimeInfo(), nil
+}
+
+// SearchAnime searches for anime on MyAnimeList, requesting as many pages as limit needs
+func (t *MALTracker) SearchAnime(ctx context.Context, query string, limit int) ([]AnimeInfo, error) {
+	return searchPages(limit, func(page, perPage int) ([]AnimeInfo, error) {
+		q := url.Values{}
+		q.Set("q", query)
+		q.Set("limit", strconv.Itoa(perPage))
+		q.Set("offset", strconv.Itoa((page-1)*perPage))
+		q.Set("fields", malListFields)
+
+		animes, err := t.getAnimeList(ctx, "/anime", q)
+		if err != nil {
+			return nil, fmt.Errorf("failed to search anime: %w", err)
+		}
+
+		return animes, nil
+	})
+}
+
+// GetSeasonalAnime lists the most popular anime of a season
+func (t *MALTracker) GetSeasonalAnime(ctx context.Context, year int, season string) ([]AnimeInfo, error) {
+	season, err := normalizeSeason(season)
+	if err != nil {
+		return nil, err
+	}
+
+	q := url.Values{}
+	q.Set("sort", "anime_num_list_users")
+	q.Set("limit", strconv.Itoa(seasonalAnimeLimit))
+	q.Set("fields", malListFields)
+
+	animes, err := t.getAnimeList(ctx, fmt.Sprintf("/anime/season/%d/%s", year, season), q)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get seasonal anime: %w", err)
+	}
+
+	return animes, nil
+}
+
+// GetTrendingAnime lists the top airing anime on MyAnimeList
+func (t *MALTracker) GetTrendingAnime(ctx context.Context, limit int) ([]AnimeInfo, error) {
+	q := url.Values{}
+	q.Set("ranking_type", "airing")
+	q.Set("limit", strconv.Itoa(limit))
+	q.Set("fields", malListFields)
+
+	animes, err := t.getAnimeList(ctx, "/anime/ranking", q)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trending anime: %w", err)
+	}
+
 	return animes, nil
 }
 
+// malBatchWorkers is how many detail requests GetAnimeDetailsBatch runs at once
+const malBatchWorkers = 4
+
+// malRequestInterval spaces out batched requests to stay under MAL's rate limit
+var malRequestInterval = 250 * time.Millisecond
+
+// GetAnimeDetailsBatch gets the details of many anime. MAL has no batch
+// lookup, so the requests are fanned out and rate limited.
+func (t *MALTracker) GetAnimeDetailsBatch(ctx context.Context, ids []string) (map[string]*AnimeInfo, error) {
+	limiter := time.NewTicker(malRequestInterval)
+	defer limiter.Stop()
+
+	jobs := make(chan string)
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+		details  = make(map[string]*AnimeInfo, len(ids))
+	)
+
+	for i := 0; i < malBatchWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range jobs {
+				info, err := t.GetAnimeDetails(ctx, id)
+
+				mu.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = err
+				} else if err == nil {
+					details[id] = info
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+feed:
+	for _, id := range ids {
+		select {
+		case <-limiter.C:
+			jobs <- id
+		case <-ctx.Done():
+			mu.Lock()
+			if firstErr == nil {
+				firstErr = ctx.Err()
+			}
+			mu.Unlock()
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return details, firstErr
+}
+
 // GetAnimeDetails gets detailed information about an anime
 func (t *MALTracker) GetAnimeDetails(ctx context.Context, id string) (*AnimeInfo, error) {
 	q := url.Values{}
-	q.Set("fields", "id,title,alternative_titles,main_picture,synopsis,mean,status,genres,media_type,num_episodes,start_season,studios,start_date,end_date")
+	q.Set("fields", "id,title,alternative_titles,main_picture,synopsis,mean,rank,popularity,num_list_users,status,genres,media_type,num_episodes,start_season,studios,start_date,end_date")
 
 	resp, err := t.apiRequest(ctx, "GET", "/anime/"+id, q, nil)
 	if err != nil {
@@ -435,7 +622,7 @@ func (t *MALTracker) GetAnimeDetails(ctx context.Context, id string) (*AnimeInfo
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("failed to get anime details: %s (%d)", string(body), resp.StatusCode)
+		return nil, apiError("failed to get anime details", resp.StatusCode, body)
 	}
 
 	var result struct {
@@ -450,10 +637,13 @@ func (t *MALTracker) GetAnimeDetails(ctx context.Context, id string) (*AnimeInfo
 			Medium string `json:"medium"`
 			Large  string `json:"large"`
 		} `json:"main_picture"`
-		Synopsis string  `json:"synopsis"`
-		Mean     float64 `json:"mean"`
-		Status   string  `json:"status"`
-		Genres   []struct {
+		Synopsis     string  `json:"synopsis"`
+		Mean         float64 `json:"mean"`
+		Rank         int     `json:"rank"`
+		Popularity   int     `json:"popularity"`
+		NumListUsers int     `json:"num_list_users"`
+		Status       string  `json:"status"`
+		Genres       []struct {
 			ID   int    `json:"id"`
 			Name string `json:"name"`
 		} `json:"genres"`
@@ -505,6 +695,7 @@ func (t *MALTracker) GetAnimeDetails(ctx context.Context, id string) (*AnimeInfo
 	anime := &AnimeInfo{
 		ID:                strconv.Itoa(result.ID),
 		Title:             result.Title,
+		RomajiTitle:       result.Title,
 		EnglishTitle:      result.AlternativeTitles.English,
 		JapaneseTitle:     result.AlternativeTitles.Japanese,
 		AlternativeTitles: alternativeTitles,
@@ -517,6 +708,9 @@ func (t *MALTracker) GetAnimeDetails(ctx context.Context, id string) (*AnimeInfo
 		Year:              result.StartSeason.Year,
 		Season:            result.StartSeason.Season,
 		Rating:            result.Mean,
+		Rank:              result.Rank,
+		Popularity:        result.Popularity,
+		MembersCount:      result.NumListUsers,
 		Genres:            genres,
 		Studios:           studios,
 		ImageURL:          result.MainPicture.Large,
@@ -525,7 +719,23 @@ func (t *MALTracker) GetAnimeDetails(ctx context.Context, id string) (*AnimeInfo
 	return anime, nil
 }
 
-// GetUserAnimeList gets the user's anime list
+// malPageRetries is how many times a failed page of the user's list is
+// retried before giving up on it
+const malPageRetries = 2
+
+// malPageRetryDelay is the default wait before retrying a failed page
+const malPageRetryDelay = 2 * time.Second
+
+// SetStrictPaging sets whether a page of the user's list that keeps failing
+// fails the whole fetch. By default the pages fetched before it are
+// returned with a *PartialListError.
+func (t *MALTracker) SetStrictPaging(strict bool) {
+	t.strictPaging = strict
+}
+
+// GetUserAnimeList gets the user's anime list. Each page is retried a couple
+// of times; if one still fails, the entries fetched so far are returned with
+// a *PartialListError unless strict paging is set.
 func (t *MALTracker) GetUserAnimeList(ctx context.Context) ([]UserAnimeEntry, error) {
 	entries := []UserAnimeEntry{}
 	offset := 0
@@ -533,9 +743,12 @@ func (t *MALTracker) GetUserAnimeList(ctx context.Context) ([]UserAnimeEntry, er
 	hasNextPage := true
 
 	for hasNextPage {
-		list, nextOffset, err := t.getUserAnimeListPage(ctx, offset, limit)
+		list, nextOffset, err := t.getUserAnimeListPageWithRetry(ctx, offset, limit)
 		if err != nil {
-			return nil, err
+			if t.strictPaging || len(entries) == 0 {
+				return nil, err
+			}
+			return entries, &PartialListError{Fetched: len(entries), Err: err}
 		}
 
 		entries = append(entries, list...)
@@ -550,12 +763,30 @@ func (t *MALTracker) GetUserAnimeList(ctx context.Context) ([]UserAnimeEntry, er
 	return entries, nil
 }
 
+// getUserAnimeListPageWithRetry gets a page of the user's list, retrying it
+// at the same offset when it fails. Expired logins and cancellations aren't
+// retried.
+func (t *MALTracker) getUserAnimeListPageWithRetry(ctx context.Context, offset, limit int) ([]UserAnimeEntry, int, error) {
+	for attempt := 0; ; attempt++ {
+		list, nextOffset, err := t.getUserAnimeListPage(ctx, offset, limit)
+		if err == nil || attempt == malPageRetries || errors.Is(err, ErrAuthExpired) || ctx.Err() != nil {
+			return list, nextOffset, err
+		}
+
+		select {
+		case <-time.After(t.pageRetryDelay):
+		case <-ctx.Done():
+			return nil, 0, ctx.Err()
+		}
+	}
+}
+
 // getUserAnimeListPage gets a page of the user's anime list
 func (t *MALTracker) getUserAnimeListPage(ctx context.Context, offset, limit int) ([]UserAnimeEntry, int, error) {
 	q := url.Values{}
 	q.Set("offset", strconv.Itoa(offset))
 	q.Set("limit", strconv.Itoa(limit))
-	q.Set("fields", "list_status,title,alternative_titles,main_picture,synopsis,mean,status,genres,media_type,num_episodes,start_season,studios,start_date,end_date")
+	q.Set("fields", "list_status{comments,priority,tags},title,alternative_titles,main_picture,synopsis,mean,status,genres,media_type,num_episodes,start_season,studios,start_date,end_date")
 	q.Set("nsfw", "true")
 
 	resp, err := t.apiRequest(ctx, "GET", "/users/@me/animelist", q, nil)
@@ -566,7 +797,7 @@ func (t *MALTracker) getUserAnimeListPage(ctx context.Context, offset, limit int
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, 0, fmt.Errorf("failed to get user anime list: %s (%d)", string(body), resp.StatusCode)
+		return nil, 0, apiError("failed to get user anime list", resp.StatusCode, body)
 	}
 
 	var result struct {
@@ -604,16 +835,18 @@ func (t *MALTracker) getUserAnimeListPage(ctx context.Context, offset, limit int
 				EndDate   string `json:"end_date"`
 			} `json:"node"`
 			ListStatus struct {
-				Status       string  `json:"status"`
-				Score        float64 `json:"score"`
-				NumEpisodes  int     `json:"num_episodes_watched"`
-				StartDate    string  `json:"start_date"`
-				FinishDate   string  `json:"finish_date"`
-				Comments     string  `json:"comments"`
-				UpdatedAt    string  `json:"updated_at"`
-				IsRewatching bool    `json:"is_rewatching"`
-				RewatchCount int     `json:"num_times_rewatched"`
-				RewatchValue int     `json:"rewatch_value"`
+				Status       string   `json:"status"`
+				Score        float64  `json:"score"`
+				NumEpisodes  int      `json:"num_episodes_watched"`
+				StartDate    string   `json:"start_date"`
+				FinishDate   string   `json:"finish_date"`
+				Comments     string   `json:"comments"`
+				UpdatedAt    string   `json:"updated_at"`
+				IsRewatching bool     `json:"is_rewatching"`
+				RewatchCount int      `json:"num_times_rewatched"`
+				RewatchValue int      `json:"rewatch_value"`
+				Priority     int      `json:"priority"`
+				Tags         []string `json:"tags"`
 			} `json:"list_status"`
 		} `json:"data"`
 		Paging struct {
@@ -666,20 +899,7 @@ func (t *MALTracker) getUserAnimeListPage(ctx context.Context, offset, limit int
 			updatedAt, _ = time.Parse(time.RFC3339, status.UpdatedAt)
 		}
 
-		// Map MAL status to our status enum
-		animeStatus := StatusWatching
-		switch status.Status {
-		case "watching":
-			animeStatus = StatusWatching
-		case "completed":
-			animeStatus = StatusCompleted
-		case "on_hold":
-			animeStatus = StatusOnHold
-		case "dropped":
-			animeStatus = StatusDropped
-		case "plan_to_watch":
-			animeStatus = StatusPlanToWatch
-		}
+		animeStatus := malListStatus(status.Status)
 
 		entry := UserAnimeEntry{
 			AnimeInfo: AnimeInfo{
@@ -704,9 +924,12 @@ func (t *MALTracker) getUserAnimeListPage(ctx context.Context, offset, limit int
 			Status:      animeStatus,
 			Score:       status.Score,
 			Progress:    float64(status.NumEpisodes),
+			Rewatching:  status.IsRewatching,
 			StartDate:   listStartDate,
 			EndDate:     listFinishDate,
 			Notes:       status.Comments,
+			Priority:    status.Priority,
+			Tags:        status.Tags,
 			LastUpdated: updatedAt,
 		}
 
@@ -727,6 +950,110 @@ func (t *MALTracker) getUserAnimeListPage(ctx context.Context, offset, limit int
 	return entries, nextOffset, nil
 }
 
+// malListStatus maps a MyAnimeList list status to ours
+func malListStatus(status string) Status {
+	switch status {
+	case "completed":
+		return StatusCompleted
+	case "on_hold":
+		return StatusOnHold
+	case "dropped":
+		return StatusDropped
+	case "plan_to_watch":
+		return StatusPlanToWatch
+	default:
+		return StatusWatching
+	}
+}
+
+// GetListEntry gets the current state of a single entry of the user's list
+func (t *MALTracker) GetListEntry(ctx context.Context, id string) (*UserAnimeEntry, error) {
+	q := url.Values{}
+	q.Set("fields", "title,num_episodes,my_list_status{comments,priority,tags}")
+
+	resp, err := t.apiRequest(ctx, "GET", "/anime/"+url.PathEscape(id), q, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get list entry: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, apiError("failed to get list entry", resp.StatusCode, body)
+	}
+
+	var result struct {
+		ID           int    `json:"id"`
+		Title        string `json:"title"`
+		NumEpisodes  int    `json:"num_episodes"`
+		MyListStatus *struct {
+			Status       string   `json:"status"`
+			Score        float64  `json:"score"`
+			NumEpisodes  int      `json:"num_episodes_watched"`
+			Comments     string   `json:"comments"`
+			Priority     int      `json:"priority"`
+			Tags         []string `json:"tags"`
+			UpdatedAt    string   `json:"updated_at"`
+			IsRewatching bool     `json:"is_rewatching"`
+		} `json:"my_list_status"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode list entry: %w", err)
+	}
+
+	status := result.MyListStatus
+	if status == nil {
+		return nil, ErrNotInList
+	}
+
+	var updatedAt time.Time
+	if status.UpdatedAt != "" {
+		updatedAt, _ = time.Parse(time.RFC3339, status.UpdatedAt)
+	}
+
+	return &UserAnimeEntry{
+		AnimeInfo: AnimeInfo{
+			ID:       strconv.Itoa(result.ID),
+			Title:    result.Title,
+			Episodes: result.NumEpisodes,
+		},
+		Status:      malListStatus(status.Status),
+		Score:       status.Score,
+		Progress:    float64(status.NumEpisodes),
+		Rewatching:  status.IsRewatching,
+		Notes:       status.Comments,
+		Priority:    status.Priority,
+		Tags:        status.Tags,
+		LastUpdated: updatedAt,
+	}, nil
+}
+
+// Ping checks the stored login by asking MAL who the user is
+func (t *MALTracker) Ping(ctx context.Context) error {
+	resp, err := t.apiRequest(ctx, "GET", "/users/@me", url.Values{"fields": {"id"}}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to get current user: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return apiError("failed to get current user", resp.StatusCode, body)
+	}
+	return nil
+}
+
+// tokenFile returns the file the login token is stored in
+func (t *MALTracker) tokenFile() string {
+	return t.tokenPath
+}
+
+// ScoreFormat returns the score format of MAL, which only takes whole scores out of 10
+func (t *MALTracker) ScoreFormat(ctx context.Context) (ScoreFormat, error) {
+	return ScorePoint10, nil
+}
+
 // UpdateAnimeStatus updates the watch status of an anime
 func (t *MALTracker) UpdateAnimeStatus(ctx context.Context, id string, status Status, episode float64, score float64) error {
 	data := url.Values{}
@@ -746,20 +1073,85 @@ func (t *MALTracker) UpdateAnimeStatus(ctx context.Context, id string, status St
 		malStatus = "plan_to_watch"
 	}
 
-	data.Set("status", malStatus)
+	// An empty status only updates progress or score
+	if status != "" {
+		data.Set("status", malStatus)
+	}
 
-	if episode > 0 {
-		data.Set("num_watched_episodes", strconv.Itoa(int(episode)))
+	if progress := RemoteProgress(episode); progress > 0 {
+		data.Set("num_watched_episodes", strconv.Itoa(progress))
 	}
 
 	if score > 0 {
 		data.Set("score", strconv.Itoa(int(score)))
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "PATCH", fmt.Sprintf("%s/anime/%s/my_list_status", malAPIBaseURL, id), strings.NewReader(data.Encode()))
+	return t.patchListStatus(ctx, id, data)
+}
+
+// UpdateListDetails sets the priority and tags of a list entry
+func (t *MALTracker) UpdateListDetails(ctx context.Context, id string, priority int, tags []string) error {
+	data := url.Values{}
+	data.Set("priority", strconv.Itoa(priority))
+	data.Set("tags", strings.Join(tags, ","))
+
+	return t.patchListStatus(ctx, id, data)
+}
+
+// UpdateNotes sets the comments of an anime on the user's MAL list
+func (t *MALTracker) UpdateNotes(ctx context.Context, id string, notes string) error {
+	data := url.Values{}
+	data.Set("comments", notes)
+
+	return t.patchListStatus(ctx, id, data)
+}
+
+// FinishRewatch ends the rewatch of an anime on the user's MAL list
+func (t *MALTracker) FinishRewatch(ctx context.Context, id string) error {
+	data := url.Values{}
+	data.Set("status", "completed")
+	data.Set("is_rewatching", "false")
+
+	return t.patchListStatus(ctx, id, data)
+}
+
+// SetFinishDate sets the day an anime on the user's MAL list was finished
+func (t *MALTracker) SetFinishDate(ctx context.Context, id string, date time.Time) error {
+	data := url.Values{}
+	data.Set("finish_date", date.Format("2006-01-02"))
+
+	return t.patchListStatus(ctx, id, data)
+}
+
+// DeleteRemoteEntry removes an anime from the user's MAL list
+func (t *MALTracker) DeleteRemoteEntry(ctx context.Context, id string) error {
+	resp, err := t.apiRequest(ctx, "DELETE", "/anime/"+url.PathEscape(id)+"/my_list_status", nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete list entry: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+		return ErrNotInList
+	default:
+		body, _ := io.ReadAll(resp.Body)
+		return apiError("failed to delete list entry", resp.StatusCode, body)
+	}
+}
+
+// patchListStatus sends changed fields of a list entry to MAL
+func (t *MALTracker) patchListStatus(ctx context.Context, id string, data url.Values) error {
+	ctx, cancel := withRequestTimeout(ctx, t.requestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "PATCH", fmt.Sprintf("%s/anime/%s/my_list_status", t.baseURL, id), strings.NewReader(data.Encode()))
 	if err != nil {
 		return fmt.Errorf("failed to create update request: %w", err)
 	}
+	identifyMAL(req)
 
 	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
 	req.Header.Add("Authorization", fmt.Sprintf("%s %s", t.token.TokenType, t.token.AccessToken))
@@ -772,7 +1164,7 @@ func (t *MALTracker) UpdateAnimeStatus(ctx context.Context, id string, status St
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to update anime status: %s (%d)", string(body), resp.StatusCode)
+		return apiError("failed to update anime status", resp.StatusCode, body)
 	}
 
 	return nil
@@ -784,16 +1176,21 @@ func (t *MALTracker) SyncFromRemote(ctx context.Context, db *database.DB) (SyncS
 		Details: []string{},
 	}
 
-	// Get user's anime list from MAL
+	// Get user's anime list from MAL, syncing what could be fetched of it
 	entries, err := t.GetUserAnimeList(ctx)
-	if err != nil {
+	var partial *PartialListError
+	if errors.As(err, &partial) {
+		stats.Errors++
+		stats.Details = append(stats.Details, fmt.Sprintf("Synced a partial list: %v", err))
+	} else if err != nil {
 		return stats, fmt.Errorf("failed to get user anime list: %w", err)
 	}
 
 	for _, entry := range entries {
-		// Check if anime exists in database
-		anime, err := db.GetAnimeByExternalID(entry.ID, t.Name())
-		if err != nil && err != sql.ErrNoRows {
+		// Check if anime exists in database, also as an Anilist entry that
+		// knows this MAL ID
+		anime, err := db.GetAnimeByMalID(entry.ID)
+		if err != nil && !errors.Is(err, database.ErrAnimeNotFound) {
 			stats.Errors++
 			stats.Details = append(stats.Details, fmt.Sprintf("Error checking anime %s: %v", entry.Title, err))
 			continue
@@ -830,6 +1227,7 @@ func (t *MALTracker) SyncFromRemote(ctx context.Context, db *database.DB) (SyncS
 				Score:          entry.Score,
 				CurrentEpisode: entry.Progress,
 				TotalEpisodes:  entry.Episodes,
+				IsRewatching:   entry.Rewatching,
 				LastUpdated:    entry.LastUpdated,
 			}
 
@@ -860,6 +1258,7 @@ func (t *MALTracker) SyncFromRemote(ctx context.Context, db *database.DB) (SyncS
 					Score:          entry.Score,
 					CurrentEpisode: entry.Progress,
 					TotalEpisodes:  entry.Episodes,
+					IsRewatching:   entry.Rewatching,
 					LastUpdated:    entry.LastUpdated,
 				}
 
@@ -874,10 +1273,15 @@ func (t *MALTracker) SyncFromRemote(ctx context.Context, db *database.DB) (SyncS
 			} else {
 				// Tracking exists, update if remote is newer
 				if entry.LastUpdated.After(tracking.LastUpdated) {
+					if err := RecordConflicts(db, tracking, &entry, database.ConflictRemote); err != nil {
+						stats.Details = append(stats.Details, fmt.Sprintf("Failed to log sync conflicts for %s: %v", entry.Title, err))
+					}
+
 					tracking.Status = string(entry.Status)
 					tracking.Score = entry.Score
 					tracking.CurrentEpisode = entry.Progress
 					tracking.TotalEpisodes = entry.Episodes
+					tracking.IsRewatching = entry.Rewatching
 					tracking.LastUpdated = entry.LastUpdated
 
 					if err := db.UpdateAnimeTrackingObject(tracking); err != nil {
@@ -896,7 +1300,7 @@ func (t *MALTracker) SyncFromRemote(ctx context.Context, db *database.DB) (SyncS
 	}
 
 	// Update last sync time
-	if err := db.SetConfig("mal_last_sync", time.Now().Format(time.RFC3339)); err != nil {
+	if err := db.SetConfig(lastSyncKey(t.Name()), time.Now().Format(time.RFC3339)); err != nil {
 		return stats, fmt.Errorf("failed to update last sync time: %w", err)
 	}
 
@@ -910,7 +1314,7 @@ func (t *MALTracker) SyncToRemote(ctx context.Context, db *database.DB) (SyncSta
 	}
 
 	// Get all anime with MAL tracking that have been updated since last sync
-	lastSyncStr, err := db.GetConfig("mal_last_sync")
+	lastSyncStr, err := db.GetConfig(lastSyncKey(t.Name()))
 	if err != nil && err != sql.ErrNoRows {
 		return stats, fmt.Errorf("failed to get last sync time: %w", err)
 	}
@@ -963,7 +1367,7 @@ func (t *MALTracker) SyncToRemote(ctx context.Context, db *database.DB) (SyncSta
 	}
 
 	// Update last sync time
-	if err := db.SetConfig("mal_last_sync", time.Now().Format(time.RFC3339)); err != nil {
+	if err := db.SetConfig(lastSyncKey(t.Name()), time.Now().Format(time.RFC3339)); err != nil {
 		return stats, fmt.Errorf("failed to update last sync time: %w", err)
 	}
 
@@ -0,0 +1,103 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Notifier shows a notification to the user
+type Notifier func(title, message string) error
+
+// notifyInterval is how often airing schedules are checked for new episodes
+const notifyInterval = 15 * time.Minute
+
+// SetNotifier makes the sync loop check the airing schedule of watched anime
+// and notify about newly aired episodes. A nil notifier disables the check.
+func (s *SyncManager) SetNotifier(notifier Notifier) {
+	s.notifier = notifier
+}
+
+// notifiedKey is the config key holding the last episode notified for an anime
+func notifiedKey(trackerName, animeID string) string {
+	return fmt.Sprintf("notify.%s.%s.episode", trackerName, animeID)
+}
+
+// CheckNewEpisodes notifies about episodes that aired since the last check
+// and returns how many notifications were sent. The first check of an anime
+// only records its latest aired episode. It returns ErrSyncRunning without
+// checking while a sync is using the trackers.
+func (s *SyncManager) CheckNewEpisodes(ctx context.Context, now time.Time) (int, error) {
+	if s.notifier == nil {
+		return 0, nil
+	}
+	if !s.busy.TryLock() {
+		return 0, ErrSyncRunning
+	}
+	defer s.busy.Unlock()
+
+	sent := 0
+	for name, t := range s.manager.trackers {
+		scheduler, ok := t.(AiringScheduler)
+		if !ok || !s.trackerEnabled(name) || !t.IsAuthenticated() {
+			continue
+		}
+		if !Reachable(ctx, s.prober, t, s.proxyURL) {
+			continue
+		}
+
+		schedule, err := scheduler.GetAiringSchedule(ctx)
+		if err != nil {
+			continue // Failing trackers are retried on the next check
+		}
+
+		for _, entry := range schedule {
+			// Once an episode airs the schedule moves on to the next one
+			aired := entry.Episode - 1
+			if !entry.AiringAt.After(now) {
+				aired = entry.Episode
+			}
+
+			key := notifiedKey(name, entry.AnimeID)
+			last, err := s.db.GetConfig(key)
+			if err != nil {
+				return sent, fmt.Errorf("failed to get notified episode: %w", err)
+			}
+			lastEpisode, parseErr := strconv.Atoi(last)
+			seen := parseErr == nil
+			if seen && aired <= lastEpisode {
+				continue
+			}
+
+			if seen {
+				if err := s.notifier(entry.Title, fmt.Sprintf("Episode %d is out", aired)); err != nil {
+					return sent, err
+				}
+				sent++
+			}
+			if err := s.db.SetConfig(key, strconv.Itoa(aired)); err != nil {
+				return sent, fmt.Errorf("failed to record notified episode: %w", err)
+			}
+		}
+	}
+
+	return sent, nil
+}
+
+// checkNewEpisodes runs CheckNewEpisodes from the sync loop. A check that
+// finds the trackers busy is skipped until the next tick, and failures are
+// added to the errors of the last sync for the sync menu to show.
+func (s *SyncManager) checkNewEpisodes(now time.Time) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	_, err := s.CheckNewEpisodes(ctx, now)
+	if err == nil || errors.Is(err, ErrSyncRunning) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.LastErrors = append(s.status.LastErrors, fmt.Sprintf("Error checking for new episodes: %v", err))
+}
@@ -0,0 +1,70 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrInvalidScore is returned for a score that doesn't fit a score format
+var ErrInvalidScore = errors.New("invalid score")
+
+// ScoreFormat is the scale a tracker keeps scores in, named after Anilist's formats
+type ScoreFormat string
+
+// Supported score formats
+const (
+	ScorePoint100       ScoreFormat = "POINT_100"
+	ScorePoint10Decimal ScoreFormat = "POINT_10_DECIMAL"
+	ScorePoint10        ScoreFormat = "POINT_10"
+	ScorePoint5         ScoreFormat = "POINT_5"
+	ScorePoint3         ScoreFormat = "POINT_3"
+)
+
+// ScoreFormatter is implemented by trackers that know the user's score format
+type ScoreFormatter interface {
+	// ScoreFormat returns the scale scores are given in
+	ScoreFormat(ctx context.Context) (ScoreFormat, error)
+}
+
+// Max returns the highest score of the format
+func (f ScoreFormat) Max() float64 {
+	switch f {
+	case ScorePoint100:
+		return 100
+	case ScorePoint5:
+		return 5
+	case ScorePoint3:
+		return 3
+	default:
+		return 10
+	}
+}
+
+// Decimal reports whether scores may have a decimal place
+func (f ScoreFormat) Decimal() bool {
+	return f == ScorePoint10Decimal
+}
+
+// Validate checks that score is within the range of the format and has no
+// more precision than it allows. 0 means no score and is always valid.
+func (f ScoreFormat) Validate(score float64) error {
+	if score < 0 || score > f.Max() {
+		return fmt.Errorf("%w: %g is outside 0-%g", ErrInvalidScore, score, f.Max())
+	}
+
+	precision := 1.0
+	if f.Decimal() {
+		precision = 10
+	}
+	scaled := score * precision
+	if math.Abs(scaled-math.Round(scaled)) > 1e-9 {
+		if f.Decimal() {
+			return fmt.Errorf("%w: %g has more than one decimal place", ErrInvalidScore, score)
+		}
+		return fmt.Errorf("%w: %g must be a whole number", ErrInvalidScore, score)
+	}
+
+	return nil
+}
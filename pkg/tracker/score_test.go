@@ -0,0 +1,38 @@
+package tracker
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestScoreFormatValidate(t *testing.T) {
+	tests := []struct {
+		format ScoreFormat
+		score  float64
+		valid  bool
+	}{
+		{ScorePoint10, 7, true},
+		{ScorePoint10, 7.5, false},
+		{ScorePoint10, 11, false},
+		{ScorePoint10Decimal, 7.5, true},
+		{ScorePoint10Decimal, 7.55, false},
+		{ScorePoint10Decimal, 10.5, false},
+		{ScorePoint100, 85, true},
+		{ScorePoint100, 85.5, false},
+		{ScorePoint100, 101, false},
+		{ScorePoint5, 5, true},
+		{ScorePoint5, 6, false},
+		{ScorePoint3, 0, true},
+		{ScorePoint3, -1, false},
+	}
+
+	for _, tt := range tests {
+		err := tt.format.Validate(tt.score)
+		if tt.valid && err != nil {
+			t.Errorf("Expected %g to be valid for %s, got %v", tt.score, tt.format, err)
+		}
+		if !tt.valid && !errors.Is(err, ErrInvalidScore) {
+			t.Errorf("Expected %g to be rejected for %s, got %v", tt.score, tt.format, err)
+		}
+	}
+}
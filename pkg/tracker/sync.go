@@ -2,10 +2,14 @@ package tracker
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math/rand"
+	"sync"
 	"time"
 
 	"github.com/wraient/pair/pkg/database"
+	"github.com/wraient/pair/pkg/httpclient"
 )
 
 // SyncManager handles automatic synchronization between local database and external trackers
@@ -13,15 +17,63 @@ type SyncManager struct {
 	db        *database.DB
 	manager   *TrackerManager
 	isRunning bool
+	prober    *httpclient.Prober
+	proxyURL  string
+	reconcile bool
+	complete  bool
+	retention time.Duration
+	notifier  Notifier
+	presence  Presence
 	stopCh    chan struct{}
+
+	// busy is held by everything that talks to the trackers, which aren't
+	// safe for concurrent use: syncs, new episode checks and Exclusive
+	busy sync.Mutex
+
+	// mu guards the state of the sync in progress, the last result and the
+	// trackers switched off, which the menus change while syncs run
+	mu       sync.Mutex
+	status   SyncStatus
+	cancel   context.CancelFunc
+	disabled map[string]bool
+}
+
+// ErrSyncRunning is returned when a sync is requested while one is in progress
+var ErrSyncRunning = errors.New("a sync is already running")
+
+// SyncState says whether a sync is in progress
+type SyncState string
+
+const (
+	SyncIdle    SyncState = "idle"
+	SyncRunning SyncState = "running"
+)
+
+// SyncStatus describes the sync in progress or the last one that finished
+type SyncStatus struct {
+	State SyncState
+	// LastRun is when the last sync finished, zero if none has
+	LastRun time.Time
+	// LastStats are the changes made by the last sync, keyed by tracker name
+	LastStats map[string]SyncStats
+	// LastErrors are the problems the last sync ran into
+	LastErrors []string
+	// LastSynced is when each logged-in tracker last synced, zero if it
+	// never has. Unlike LastRun it survives restarts.
+	LastSynced map[string]time.Time
+	// Interval is the configured time between syncs, after which the last
+	// sync of a tracker is stale
+	Interval time.Duration
 }
 
 // NewSyncManager creates a new SyncManager
 func NewSyncManager(db *database.DB, manager *TrackerManager) *SyncManager {
 	return &SyncManager{
-		db:      db,
-		manager: manager,
-		stopCh:  make(chan struct{}),
+		db:       db,
+		manager:  manager,
+		disabled: make(map[string]bool),
+		stopCh:   make(chan struct{}),
+		status:   SyncStatus{State: SyncIdle},
 	}
 }
 
@@ -35,57 +87,223 @@ func (s *SyncManager) Start() {
 	go s.syncLoop()
 }
 
+// SetTrackerEnabled switches syncing with the named tracker on or off
+func (s *SyncManager) SetTrackerEnabled(name string, enabled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.disabled[name] = !enabled
+}
+
+// trackerEnabled reports whether syncing with the named tracker is switched on
+func (s *SyncManager) trackerEnabled(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.disabled[name]
+}
+
+// SetReconcileProgress makes syncs mark episodes watched up to each
+// tracker's progress after pulling the list
+func (s *SyncManager) SetReconcileProgress(reconcile bool) {
+	s.reconcile = reconcile
+}
+
+// SetAutoComplete makes finishing the last episode of an anime also mark it
+// completed, with today as the finish date on trackers that keep one
+func (s *SyncManager) SetAutoComplete(complete bool) {
+	s.complete = complete
+}
+
+// SetConflictRetention sets how long logged sync conflicts are kept. They
+// are pruned after each sync. Until it is set, or with zero, they are all
+// kept.
+func (s *SyncManager) SetConflictRetention(retention time.Duration) {
+	s.retention = retention
+}
+
+// SetConnectivityCheck makes syncs skip trackers whose API the prober can't reach.
+// proxyURL is the configured proxy, if any.
+func (s *SyncManager) SetConnectivityCheck(prober *httpclient.Prober, proxyURL string) {
+	s.prober = prober
+	s.proxyURL = proxyURL
+}
+
 // Stop stops the automatic synchronization process
 func (s *SyncManager) Stop() {
 	if !s.isRunning {
 		return
 	}
 
+	// Don't wait for a sync in progress to finish
+	s.CancelCurrent()
 	s.stopCh <- struct{}{}
 	s.isRunning = false
 }
 
+// syncJitter is the fraction by which each sync interval is randomly
+// lengthened or shortened, so clients started together drift apart
+const syncJitter = 0.1
+
+// trackerSyncStagger is the pause between syncing one tracker and the next,
+// so their requests don't all start on the same tick
+const trackerSyncStagger = 3 * time.Second
+
 // syncLoop periodically syncs data with external trackers
 func (s *SyncManager) syncLoop() {
-	// Get sync interval from config
-	syncInterval := 60 // Default to 60 minutes
-	intervalStr, err := s.db.GetConfig("tracker_sync_interval")
-	if err == nil && intervalStr != "" {
-		fmt.Sscanf(intervalStr, "%d", &syncInterval)
-	}
+	interval := s.syncInterval()
+	timer := time.NewTimer(nextSyncDelay(interval, rand.Float64))
+	defer timer.Stop()
 
-	// Minimum interval is 15 minutes to avoid rate limiting
-	if syncInterval < 15 {
-		syncInterval = 15
-	}
-
-	ticker := time.NewTicker(time.Duration(syncInterval) * time.Minute)
-	defer ticker.Stop()
+	// Look for newly aired episodes more often than full syncs
+	notifyTicker := time.NewTicker(notifyInterval)
+	defer notifyTicker.Stop()
 
 	for {
 		select {
-		case <-ticker.C:
+		case <-timer.C:
 			s.performSync()
+			timer.Reset(nextSyncDelay(interval, rand.Float64))
+		case now := <-notifyTicker.C:
+			s.checkNewEpisodes(now)
 		case <-s.stopCh:
 			return
 		}
 	}
 }
 
-// performSync performs synchronization with all trackers
+// syncInterval returns the configured time between syncs, raised to the
+// configured minimum
+func (s *SyncManager) syncInterval() time.Duration {
+	interval := 60 // Used if the stored value can't be read
+	if value, err := s.db.GetConfigOrDefault(database.ConfigTrackerSyncInterval); err == nil {
+		fmt.Sscanf(value, "%d", &interval)
+	}
+
+	// The floor avoids rate limiting, and can be lowered at the user's risk
+	minimum := 15
+	if value, err := s.db.GetConfigOrDefault(database.ConfigTrackerMinSyncInterval); err == nil {
+		fmt.Sscanf(value, "%d", &minimum)
+	}
+	if minimum < 1 {
+		minimum = 1
+	}
+
+	return time.Duration(max(interval, minimum)) * time.Minute
+}
+
+// nextSyncDelay returns interval adjusted by up to syncJitter either way.
+// random returns a number in [0, 1).
+func nextSyncDelay(interval time.Duration, random func() float64) time.Duration {
+	offset := (random()*2 - 1) * syncJitter
+	return time.Duration(float64(interval) * (1 + offset))
+}
+
+// performSync runs a scheduled sync when auto sync is switched on
 func (s *SyncManager) performSync() {
 	// Check if auto sync is enabled
-	autoSyncStr, err := s.db.GetConfig("tracker_auto_sync")
+	autoSyncStr, err := s.db.GetConfigOrDefault(database.ConfigTrackerAutoSync)
 	if err != nil || autoSyncStr != "true" {
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	// Problems are kept in the status for the sync menu rather than printed
+	// over whatever the user is looking at
+	s.runSync(context.Background())
+}
+
+// Exclusive runs fn once no sync or new episode check is using the
+// trackers, and keeps them from starting until fn returns. Syncs run from
+// the foreground go through it so they don't overlap the background ones.
+func (s *SyncManager) Exclusive(fn func() error) error {
+	s.busy.Lock()
+	defer s.busy.Unlock()
+	return fn()
+}
+
+// TriggerNow syncs with every tracker right away, whether or not auto sync
+// is switched on, and waits for it to finish. It returns ErrSyncRunning if a
+// sync is already in progress.
+func (s *SyncManager) TriggerNow(ctx context.Context) error {
+	return s.runSync(ctx)
+}
+
+// CancelCurrent stops the sync in progress, reporting whether there was one
+func (s *SyncManager) CancelCurrent() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cancel == nil {
+		return false
+	}
+	s.cancel()
+	return true
+}
+
+// Status returns the state of the sync in progress and the last result
+func (s *SyncManager) Status() SyncStatus {
+	s.mu.Lock()
+	status := s.status
+	s.mu.Unlock()
+
+	status.LastSynced = make(map[string]time.Time)
+	for _, name := range []string{"mal", "anilist"} {
+		tracker, err := s.manager.GetTracker(name)
+		if err != nil || !tracker.IsAuthenticated() {
+			continue
+		}
+		// An unreadable time shows as never synced, which the next sync fixes
+		last, _ := s.db.GetLastSync(name)
+		status.LastSynced[name] = last
+	}
+	status.Interval = s.syncInterval()
+	return status
+}
+
+// runSync pulls and pushes every tracker that is switched on, logged in and
+// reachable, recording the outcome in the status. It stops early with the
+// context's error when the sync is cancelled.
+func (s *SyncManager) runSync(ctx context.Context) error {
+	if !s.busy.TryLock() {
+		return ErrSyncRunning
+	}
+	defer s.busy.Unlock()
+
+	s.mu.Lock()
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
 	defer cancel()
+	s.status.State = SyncRunning
+	s.cancel = cancel
+	s.mu.Unlock()
+
+	stats := make(map[string]SyncStats)
+	var syncErrors []string
+	fail := func(format string, args ...interface{}) {
+		syncErrors = append(syncErrors, fmt.Sprintf(format, args...))
+	}
+
+	defer func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.status = SyncStatus{
+			State:      SyncIdle,
+			LastRun:    time.Now(),
+			LastStats:  stats,
+			LastErrors: syncErrors,
+		}
+		s.cancel = nil
+	}()
 
 	// Get trackers that should be synced
 	trackers := []string{"mal", "anilist"}
+	unreachable := false
+	synced := 0
 	for _, name := range trackers {
+		if err := ctx.Err(); err != nil {
+			fail("Sync cancelled before %s", name)
+			return err
+		}
+		if !s.trackerEnabled(name) {
+			continue
+		}
+
 		tracker, err := s.manager.GetTracker(name)
 		if err != nil {
 			continue
@@ -96,22 +314,78 @@ func (s *SyncManager) performSync() {
 			continue
 		}
 
+		// Skip if the network is down rather than waiting for each request to time out
+		if !Reachable(ctx, s.prober, tracker, s.proxyURL) {
+			unreachable = true
+			continue
+		}
+
+		// Start each tracker a little after the previous one
+		if synced > 0 {
+			select {
+			case <-time.After(trackerSyncStagger):
+			case <-ctx.Done():
+				fail("Sync cancelled before %s", name)
+				return ctx.Err()
+			}
+		}
+		synced++
+
 		// Sync from tracker to local
-		_, err = tracker.SyncFromRemote(ctx, s.db)
-		if err != nil {
-			fmt.Printf("Error syncing from %s: %v\n", name, err)
+		var trackerStats SyncStats
+		fromStats, err := tracker.SyncFromRemote(ctx, s.db)
+		trackerStats.add(fromStats)
+		switch {
+		case ctx.Err() != nil:
+			stats[name] = trackerStats
+			fail("Sync with %s cancelled", name)
+			return ctx.Err()
+		case errors.Is(err, ErrAuthExpired):
+			// Pushing would fail the same way until the user logs in again
+			fail("Login to %s expired, log in again from the settings menu", name)
+			continue
+		case errors.Is(err, ErrRateLimited):
+			fail("%s is rate limiting requests, retrying on the next sync", name)
+			continue
+		case err != nil:
+			fail("Error syncing from %s: %v", name, err)
+		case s.reconcile:
+			if _, err := s.db.ReconcileWatchedEpisodes(name); err != nil {
+				fail("Error marking episodes watched from %s: %v", name, err)
+			}
 		}
 
 		// Sync from local to tracker
-		_, err = tracker.SyncToRemote(ctx, s.db)
+		toStats, err := tracker.SyncToRemote(ctx, s.db)
+		trackerStats.add(toStats)
+		stats[name] = trackerStats
 		if err != nil {
-			fmt.Printf("Error syncing to %s: %v\n", name, err)
+			fail("Error syncing to %s: %v", name, err)
 		}
 	}
+
+	if unreachable {
+		fail("Network unavailable, using local data")
+	}
+	if err := PruneConflicts(s.db, s.retention); err != nil {
+		fail("Error pruning sync conflicts: %v", err)
+	}
+	return ctx.Err()
 }
 
-// SyncEpisodeProgress syncs episode progress to all trackers
+// episodeSyncConcurrency is the number of trackers updated at once when an episode is finished
+const episodeSyncConcurrency = 3
+
+// SyncEpisodeProgress syncs episode progress to all trackers concurrently.
+// Trackers that already have the episode are skipped, and failures are
+// returned together once every tracker has been tried. Only the local
+// records of trackers that took the update are changed, so the next sync
+// pushes the others again. With auto-complete on, the last episode also
+// marks the anime completed. It waits for a sync in progress to finish.
 func (s *SyncManager) SyncEpisodeProgress(animeID int64, episodeNumber float64) error {
+	s.busy.Lock()
+	defer s.busy.Unlock()
+
 	// Get anime tracking entries
 	trackings, err := s.db.GetAllAnimeTrackingByAnimeID(animeID)
 	if err != nil {
@@ -122,12 +396,27 @@ func (s *SyncManager) SyncEpisodeProgress(animeID int64, episodeNumber float64)
 		return nil // No tracking entries to sync
 	}
 
+	complete := false
+	if s.complete {
+		complete, err = s.finishesSeries(animeID, trackings, episodeNumber)
+		if err != nil {
+			return err
+		}
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		errs    []error
+		updated = make(map[string]float64) // progress each tracker took
+		sem     = make(chan struct{}, episodeSyncConcurrency)
+	)
 	for _, tracking := range trackings {
-		// Skip local tracker
-		if tracking.Tracker == "local" {
+		// Skip local tracker and trackers that are switched off
+		if tracking.Tracker == "local" || !s.trackerEnabled(tracking.Tracker) {
 			continue
 		}
 
@@ -141,35 +430,106 @@ func (s *SyncManager) SyncEpisodeProgress(animeID int64, episodeNumber float64)
 			continue
 		}
 
-		// Map status string to enum
-		status := StatusWatching
-		switch tracking.Status {
-		case "watching":
-			status = StatusWatching
-		case "completed":
-			status = StatusCompleted
-		case "on_hold":
-			status = StatusOnHold
-		case "dropped":
-			status = StatusDropped
-		case "plan_to_watch":
-			status = StatusPlanToWatch
-		}
+		wg.Add(1)
+		go func(tracker Tracker, tracking *database.AnimeTracking) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
 
-		// Update episode progress on tracker
-		err = tracker.UpdateAnimeStatus(ctx, tracking.TrackerID, status, episodeNumber, tracking.Score)
-		if err != nil {
-			fmt.Printf("Error updating progress on %s: %v\n", tracking.Tracker, err)
+			progress, err := updateEpisodeProgress(ctx, tracker, tracking, episodeNumber, complete)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to update progress on %s: %w", tracking.Tracker, err))
+				return
+			}
+			updated[tracking.Tracker] = progress
+		}(tracker, tracking)
+	}
+	wg.Wait()
+
+	// Update the local records one at a time, SQLite doesn't like concurrent writers
+	for _, tracking := range trackings {
+		progress, ok := updated[tracking.Tracker]
+		if !ok {
+			continue
 		}
 
-		// Update local tracking record
-		tracking.CurrentEpisode = episodeNumber
+		tracking.CurrentEpisode = progress
+		if complete {
+			tracking.Status = string(StatusCompleted)
+		}
 		tracking.LastUpdated = time.Now()
-		err = s.db.UpdateAnimeTracking(tracking.AnimeID, tracking.Tracker, episodeNumber)
+		if err := s.db.UpdateAnimeTrackingObject(tracking); err != nil {
+			errs = append(errs, fmt.Errorf("failed to update local tracking for %s: %w", tracking.Tracker, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// finishesSeries reports whether episodeNumber is the last episode of an
+// anime. Anime whose episode count isn't known are never finished.
+func (s *SyncManager) finishesSeries(animeID int64, trackings []*database.AnimeTracking, episodeNumber float64) (bool, error) {
+	total := 0
+	for _, tracking := range trackings {
+		total = max(total, tracking.TotalEpisodes)
+	}
+	if total == 0 {
+		anime, err := s.db.GetAnime(animeID)
 		if err != nil {
-			fmt.Printf("Error updating local tracking: %v\n", err)
+			return false, fmt.Errorf("failed to get anime: %w", err)
 		}
+		total = anime.TotalEpisodes
+	}
+	return total > 0 && RemoteProgress(episodeNumber) >= total, nil
+}
+
+// updateEpisodeProgress sets the progress of an entry on a tracker unless the
+// tracker already has that episode or a later one. Specials between regular
+// episodes count as the episode before them. complete marks the entry
+// completed and, where the tracker keeps one, sets the finish date, even
+// when the tracker's progress is already there. It returns the progress to
+// record locally, which is the tracker's when completing kept it higher.
+func updateEpisodeProgress(ctx context.Context, tracker Tracker, tracking *database.AnimeTracking, episodeNumber float64, complete bool) (float64, error) {
+	entry, err := tracker.GetListEntry(ctx, tracking.TrackerID)
+	if err != nil && !errors.Is(err, ErrNotInList) {
+		return 0, err
+	}
+	if err == nil && entry.Progress >= float64(RemoteProgress(episodeNumber)) {
+		if !complete || entry.Status == StatusCompleted {
+			return episodeNumber, nil
+		}
+		// Completing mustn't lower the progress the tracker has
+		episodeNumber = max(episodeNumber, entry.Progress)
+	}
+
+	// Map status string to enum
+	status := StatusWatching
+	switch tracking.Status {
+	case "watching":
+		status = StatusWatching
+	case "completed":
+		status = StatusCompleted
+	case "on_hold":
+		status = StatusOnHold
+	case "dropped":
+		status = StatusDropped
+	case "plan_to_watch":
+		status = StatusPlanToWatch
+	}
+
+	if complete {
+		status = StatusCompleted
 	}
 
-	return nil
+	if err := tracker.UpdateAnimeStatus(ctx, tracking.TrackerID, status, episodeNumber, tracking.Score); err != nil {
+		return 0, err
+	}
+	if setter, ok := tracker.(FinishDateSetter); ok && complete {
+		if err := setter.SetFinishDate(ctx, tracking.TrackerID, time.Now()); err != nil {
+			return 0, err
+		}
+	}
+	return episodeNumber, nil
 }
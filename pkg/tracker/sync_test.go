@@ -0,0 +1,567 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wraient/pair/pkg/database"
+)
+
+// fakeTracker is a remote tracker that takes delay to answer each update
+type fakeTracker struct {
+	name      string
+	delay     time.Duration
+	progress  float64
+	updateErr error
+
+	mu       sync.Mutex
+	updates  []float64
+	statuses []Status
+}
+
+func (f *fakeTracker) Name() string                           { return f.name }
+func (f *fakeTracker) IsAuthenticated() bool                  { return true }
+func (f *fakeTracker) Authenticate(ctx context.Context) error { return nil }
+func (f *fakeTracker) SearchAnime(ctx context.Context, query string, limit int) ([]AnimeInfo, error) {
+	return nil, nil
+}
+func (f *fakeTracker) GetAnimeDetails(ctx context.Context, id string) (*AnimeInfo, error) {
+	return &AnimeInfo{ID: id}, nil
+}
+func (f *fakeTracker) GetUserAnimeList(ctx context.Context) ([]UserAnimeEntry, error) {
+	return nil, nil
+}
+func (f *fakeTracker) GetListEntry(ctx context.Context, id string) (*UserAnimeEntry, error) {
+	return &UserAnimeEntry{AnimeInfo: AnimeInfo{ID: id}, Progress: f.progress}, nil
+}
+func (f *fakeTracker) UpdateAnimeStatus(ctx context.Context, id string, status Status, episode float64, score float64) error {
+	time.Sleep(f.delay)
+	if f.updateErr != nil {
+		return f.updateErr
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.updates = append(f.updates, episode)
+	f.statuses = append(f.statuses, status)
+	return nil
+}
+func (f *fakeTracker) SyncFromRemote(ctx context.Context, db *database.DB) (SyncStats, error) {
+	return SyncStats{}, nil
+}
+func (f *fakeTracker) SyncToRemote(ctx context.Context, db *database.DB) (SyncStats, error) {
+	return SyncStats{}, nil
+}
+
+func TestSyncEpisodeProgress(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "pair-test.db"))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	delay := 200 * time.Millisecond
+	anilist := &fakeTracker{name: "anilist", delay: delay, progress: 3}
+	mal := &fakeTracker{name: "mal", delay: delay, progress: 3}
+	upToDate := &fakeTracker{name: "kitsu", progress: 5}
+
+	manager := NewTrackerManager(db)
+	for _, tracker := range []*fakeTracker{anilist, mal, upToDate} {
+		manager.RegisterTracker(tracker)
+	}
+
+	anime := &database.Anime{Title: "Test Anime", TotalEpisodes: 12}
+	if err := db.AddAnime(anime); err != nil {
+		t.Fatalf("Failed to add anime: %v", err)
+	}
+	for _, name := range []string{"anilist", "mal", "kitsu"} {
+		tracking := &database.AnimeTracking{AnimeID: anime.ID, Tracker: name, TrackerID: "101", Status: "on_hold", Score: 8, CurrentEpisode: 3}
+		if err := db.AddAnimeTracking(tracking); err != nil {
+			t.Fatalf("Failed to add tracking: %v", err)
+		}
+	}
+
+	// Test that both trackers are updated concurrently
+	start := time.Now()
+	if err := NewSyncManager(db, manager).SyncEpisodeProgress(anime.ID, 4); err != nil {
+		t.Fatalf("Failed to sync episode progress: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= 2*delay {
+		t.Errorf("Expected updates to run concurrently, took %v", elapsed)
+	}
+
+	for _, tracker := range []*fakeTracker{anilist, mal} {
+		if len(tracker.updates) != 1 || tracker.updates[0] != 4 {
+			t.Errorf("Expected %s to be updated to episode 4, got %v", tracker.name, tracker.updates)
+		}
+	}
+
+	// Test that a tracker already past the episode is skipped
+	if len(upToDate.updates) != 0 {
+		t.Errorf("Expected no update for up to date tracker, got %v", upToDate.updates)
+	}
+
+	// Test that the local tracking keeps its status and score
+	tracking, err := db.GetAnimeTracking(anime.ID, "mal")
+	if err != nil {
+		t.Fatalf("Failed to get tracking: %v", err)
+	}
+	if tracking.CurrentEpisode != 4 || tracking.Status != "on_hold" || tracking.Score != 8 {
+		t.Errorf("Unexpected local tracking: %+v", tracking)
+	}
+}
+
+func TestSyncEpisodeProgressKeepsFailedTrackers(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "pair-test.db"))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	manager := NewTrackerManager(db)
+	manager.RegisterTracker(&fakeTracker{name: "anilist", progress: 3})
+	manager.RegisterTracker(&fakeTracker{name: "mal", progress: 3, updateErr: errors.New("server down")})
+
+	anime := &database.Anime{Title: "Test Anime", TotalEpisodes: 12}
+	if err := db.AddAnime(anime); err != nil {
+		t.Fatalf("Failed to add anime: %v", err)
+	}
+	for _, name := range []string{"anilist", "mal"} {
+		tracking := &database.AnimeTracking{AnimeID: anime.ID, Tracker: name, TrackerID: "101", Status: "watching", CurrentEpisode: 3}
+		if err := db.AddAnimeTracking(tracking); err != nil {
+			t.Fatalf("Failed to add tracking: %v", err)
+		}
+	}
+
+	if err := NewSyncManager(db, manager).SyncEpisodeProgress(anime.ID, 4); err == nil {
+		t.Errorf("Expected the MAL failure to be returned")
+	}
+
+	// Test that only the tracker that took the update is advanced locally
+	for name, want := range map[string]float64{"anilist": 4, "mal": 3} {
+		tracking, err := db.GetAnimeTracking(anime.ID, name)
+		if err != nil {
+			t.Fatalf("Failed to get tracking: %v", err)
+		}
+		if tracking.CurrentEpisode != want {
+			t.Errorf("Expected %s at episode %g, got %g", name, want, tracking.CurrentEpisode)
+		}
+	}
+}
+
+// finishTracker is a fakeTracker that keeps finish dates
+type finishTracker struct {
+	fakeTracker
+	finished []time.Time
+}
+
+func (f *finishTracker) SetFinishDate(ctx context.Context, id string, date time.Time) error {
+	f.finished = append(f.finished, date)
+	return nil
+}
+
+func TestSyncEpisodeProgressCompletesCaughtUpTrackers(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "pair-test.db"))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	// Anilist is already past the last episode, counting a recap, and MAL is down
+	anilist := &finishTracker{fakeTracker: fakeTracker{name: "anilist", progress: 13}}
+	mal := &fakeTracker{name: "mal", progress: 11, updateErr: errors.New("server down")}
+	manager := NewTrackerManager(db)
+	manager.RegisterTracker(anilist)
+	manager.RegisterTracker(mal)
+
+	anime := &database.Anime{Title: "Finished Show", TotalEpisodes: 12}
+	if err := db.AddAnime(anime); err != nil {
+		t.Fatalf("Failed to add anime: %v", err)
+	}
+	for _, name := range []string{"anilist", "mal"} {
+		tracking := &database.AnimeTracking{AnimeID: anime.ID, Tracker: name, TrackerID: "101", Status: "watching", CurrentEpisode: 11}
+		if err := db.AddAnimeTracking(tracking); err != nil {
+			t.Fatalf("Failed to add tracking: %v", err)
+		}
+	}
+
+	syncMgr := NewSyncManager(db, manager)
+	syncMgr.SetAutoComplete(true)
+	if err := syncMgr.SyncEpisodeProgress(anime.ID, 12); err == nil {
+		t.Errorf("Expected the MAL failure to be returned")
+	}
+
+	// Test that the tracker already past the last episode is still completed,
+	// keeping its progress
+	if len(anilist.statuses) != 1 || anilist.statuses[0] != StatusCompleted || anilist.updates[0] != 13 {
+		t.Errorf("Expected anilist to be completed at episode 13, got %v at %v", anilist.statuses, anilist.updates)
+	}
+	if len(anilist.finished) != 1 {
+		t.Errorf("Expected a finish date on anilist, got %v", anilist.finished)
+	}
+
+	// Test that only the tracker that took the update is completed locally,
+	// with the progress it was sent
+	for name, want := range map[string]struct {
+		status  string
+		episode float64
+	}{"anilist": {"completed", 13}, "mal": {"watching", 11}} {
+		tracking, err := db.GetAnimeTracking(anime.ID, name)
+		if err != nil {
+			t.Fatalf("Failed to get tracking: %v", err)
+		}
+		if tracking.Status != want.status || tracking.CurrentEpisode != want.episode {
+			t.Errorf("Expected %s to be %s at %v locally, got %s at %v",
+				name, want.status, want.episode, tracking.Status, tracking.CurrentEpisode)
+		}
+	}
+}
+
+func TestSyncEpisodeProgressAutoComplete(t *testing.T) {
+	for _, autoComplete := range []bool{false, true} {
+		db, err := database.New(filepath.Join(t.TempDir(), "pair-test.db"))
+		if err != nil {
+			t.Fatalf("Failed to initialize database: %v", err)
+		}
+		defer db.Close()
+
+		anilist := &finishTracker{fakeTracker: fakeTracker{name: "anilist", progress: 11}}
+		manager := NewTrackerManager(db)
+		manager.RegisterTracker(anilist)
+
+		finished := &database.Anime{Title: "Finished Show", TotalEpisodes: 12}
+		unknown := &database.Anime{Title: "Airing Show"}
+		for _, anime := range []*database.Anime{finished, unknown} {
+			if err := db.AddAnime(anime); err != nil {
+				t.Fatalf("Failed to add anime: %v", err)
+			}
+			tracking := &database.AnimeTracking{AnimeID: anime.ID, Tracker: "anilist", TrackerID: "101", Status: "watching", CurrentEpisode: 11}
+			if err := db.AddAnimeTracking(tracking); err != nil {
+				t.Fatalf("Failed to add tracking: %v", err)
+			}
+		}
+
+		syncMgr := NewSyncManager(db, manager)
+		syncMgr.SetAutoComplete(autoComplete)
+
+		// Test that finishing episode 12 of 12 completes the anime only when enabled
+		if err := syncMgr.SyncEpisodeProgress(finished.ID, 12); err != nil {
+			t.Fatalf("Failed to sync episode progress: %v", err)
+		}
+		want, wantStatus := StatusWatching, "watching"
+		if autoComplete {
+			want, wantStatus = StatusCompleted, "completed"
+		}
+		if len(anilist.statuses) != 1 || anilist.statuses[0] != want {
+			t.Errorf("auto_complete %v: expected status %s, got %v", autoComplete, want, anilist.statuses)
+		}
+		if autoComplete != (len(anilist.finished) == 1) {
+			t.Errorf("auto_complete %v: unexpected finish dates %v", autoComplete, anilist.finished)
+		}
+		tracking, err := db.GetAnimeTracking(finished.ID, "anilist")
+		if err != nil {
+			t.Fatalf("Failed to get tracking: %v", err)
+		}
+		if tracking.Status != wantStatus {
+			t.Errorf("auto_complete %v: expected local status %s, got %s", autoComplete, wantStatus, tracking.Status)
+		}
+
+		// Test that an unknown episode count never completes the anime
+		if err := syncMgr.SyncEpisodeProgress(unknown.ID, 12); err != nil {
+			t.Fatalf("Failed to sync episode progress: %v", err)
+		}
+		if last := anilist.statuses[len(anilist.statuses)-1]; last != StatusWatching {
+			t.Errorf("auto_complete %v: expected an unknown count to stay watching, got %s", autoComplete, last)
+		}
+	}
+}
+
+// scheduleTracker is a fakeTracker with an airing schedule
+type scheduleTracker struct {
+	fakeTracker
+	schedule []AiringEntry
+}
+
+func (f *scheduleTracker) GetAiringSchedule(ctx context.Context) ([]AiringEntry, error) {
+	return f.schedule, nil
+}
+
+func TestCheckNewEpisodes(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "pair-test.db"))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	airsAt := time.Date(2024, 5, 1, 15, 0, 0, 0, time.UTC)
+	anilist := &scheduleTracker{
+		fakeTracker: fakeTracker{name: "anilist"},
+		schedule:    []AiringEntry{{AnimeID: "101", Title: "Airing Show", Episode: 5, AiringAt: airsAt}},
+	}
+	manager := NewTrackerManager(db)
+	manager.RegisterTracker(anilist)
+
+	var notifications []string
+	syncMgr := NewSyncManager(db, manager)
+	syncMgr.SetNotifier(func(title, message string) error {
+		notifications = append(notifications, title+": "+message)
+		return nil
+	})
+
+	// The first check before the air time only records episode 4
+	if _, err := syncMgr.CheckNewEpisodes(context.Background(), airsAt.Add(-time.Hour)); err != nil {
+		t.Fatalf("Failed to check new episodes: %v", err)
+	}
+
+	// Cross the air time, then check again after the schedule moved on
+	if _, err := syncMgr.CheckNewEpisodes(context.Background(), airsAt.Add(time.Minute)); err != nil {
+		t.Fatalf("Failed to check new episodes: %v", err)
+	}
+	anilist.schedule[0].Episode = 6
+	anilist.schedule[0].AiringAt = airsAt.Add(7 * 24 * time.Hour)
+	if _, err := syncMgr.CheckNewEpisodes(context.Background(), airsAt.Add(time.Hour)); err != nil {
+		t.Fatalf("Failed to check new episodes: %v", err)
+	}
+
+	// Test that exactly one notification was sent for the new episode
+	if len(notifications) != 1 || notifications[0] != "Airing Show: Episode 5 is out" {
+		t.Errorf("Expected a single notification for episode 5, got %v", notifications)
+	}
+
+	// Test that the check is skipped while the trackers are busy
+	err = syncMgr.Exclusive(func() error {
+		_, err := syncMgr.CheckNewEpisodes(context.Background(), airsAt.Add(time.Hour))
+		return err
+	})
+	if !errors.Is(err, ErrSyncRunning) {
+		t.Errorf("Expected ErrSyncRunning during Exclusive, got %v", err)
+	}
+}
+
+func TestNextSyncDelay(t *testing.T) {
+	interval := 60 * time.Minute
+	low := time.Duration(float64(interval) * (1 - syncJitter))
+	high := time.Duration(float64(interval) * (1 + syncJitter))
+
+	// Test the extremes and the middle of the random range
+	for random, want := range map[float64]time.Duration{0: low, 0.5: interval} {
+		if got := nextSyncDelay(interval, func() float64 { return random }); got != want {
+			t.Errorf("Expected %v for %g, got %v", want, random, got)
+		}
+	}
+
+	// Test that real jitter stays within ±10% and varies
+	seen := make(map[time.Duration]bool)
+	for i := 0; i < 100; i++ {
+		delay := nextSyncDelay(interval, rand.Float64)
+		if delay < low || delay >= high {
+			t.Fatalf("Expected a delay in [%v, %v), got %v", low, high, delay)
+		}
+		seen[delay] = true
+	}
+	if len(seen) < 2 {
+		t.Errorf("Expected the delay to vary, got %v", seen)
+	}
+}
+
+func TestSyncIntervalFloor(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "pair-test.db"))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+	s := NewSyncManager(db, NewTrackerManager(db))
+
+	// Test that a short interval is raised to the default floor
+	if err := db.SetConfig(database.ConfigTrackerSyncInterval, "5"); err != nil {
+		t.Fatalf("Failed to set config: %v", err)
+	}
+	if got := s.syncInterval(); got != 15*time.Minute {
+		t.Errorf("Expected 15m, got %v", got)
+	}
+
+	// Test that the floor can be lowered
+	if err := db.SetConfig(database.ConfigTrackerMinSyncInterval, "2"); err != nil {
+		t.Fatalf("Failed to set config: %v", err)
+	}
+	if got := s.syncInterval(); got != 5*time.Minute {
+		t.Errorf("Expected 5m, got %v", got)
+	}
+}
+
+// blockingTracker is a fakeTracker whose pull runs until it is cancelled,
+// or returns stats right away when release is closed
+type blockingTracker struct {
+	fakeTracker
+	started chan struct{}
+	release chan struct{}
+}
+
+func (f *blockingTracker) SyncFromRemote(ctx context.Context, db *database.DB) (SyncStats, error) {
+	close(f.started)
+	select {
+	case <-ctx.Done():
+		return SyncStats{}, ctx.Err()
+	case <-f.release:
+		return SyncStats{Added: 2}, nil
+	}
+}
+
+func TestSyncStatusAndCancel(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "pair-test.db"))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	mock := &blockingTracker{fakeTracker: fakeTracker{name: "anilist"}, started: make(chan struct{}), release: make(chan struct{})}
+	manager := NewTrackerManager(db)
+	manager.RegisterTracker(mock)
+	s := NewSyncManager(db, manager)
+
+	// Test that a new manager is idle and has nothing to cancel
+	if status := s.Status(); status.State != SyncIdle || !status.LastRun.IsZero() {
+		t.Errorf("Expected an idle manager that never ran, got %+v", status)
+	}
+	if s.CancelCurrent() {
+		t.Errorf("Expected nothing to cancel")
+	}
+
+	// Test that a sync shows as running while the tracker is pulled
+	done := make(chan error)
+	go func() { done <- s.TriggerNow(context.Background()) }()
+	<-mock.started
+	if state := s.Status().State; state != SyncRunning {
+		t.Errorf("Expected a running sync, got %s", state)
+	}
+	if err := s.TriggerNow(context.Background()); !errors.Is(err, ErrSyncRunning) {
+		t.Errorf("Expected ErrSyncRunning for a second sync, got %v", err)
+	}
+
+	// Test that cancelling stops the long-running pull
+	if !s.CancelCurrent() {
+		t.Errorf("Expected the running sync to be cancelled")
+	}
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the sync to stop after cancelling")
+	}
+	status := s.Status()
+	if status.State != SyncIdle || status.LastRun.IsZero() || len(status.LastErrors) == 0 {
+		t.Errorf("Expected an idle manager with a cancelled run, got %+v", status)
+	}
+
+	// Test that a finished sync records its stats
+	mock.started = make(chan struct{})
+	close(mock.release)
+	if err := s.TriggerNow(context.Background()); err != nil {
+		t.Fatalf("Failed to sync: %v", err)
+	}
+	status = s.Status()
+	if status.State != SyncIdle || status.LastStats["anilist"].Added != 2 || len(status.LastErrors) != 0 {
+		t.Errorf("Expected 2 anime added without errors, got %+v", status)
+	}
+
+	// Test that a sync doesn't start while the trackers are used elsewhere
+	err = s.Exclusive(func() error {
+		return s.TriggerNow(context.Background())
+	})
+	if !errors.Is(err, ErrSyncRunning) {
+		t.Errorf("Expected ErrSyncRunning during Exclusive, got %v", err)
+	}
+}
+
+func TestSetTrackerEnabledDuringSync(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "pair-test.db"))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	manager := NewTrackerManager(db)
+	manager.RegisterTracker(&fakeTracker{name: "anilist"})
+	s := NewSyncManager(db, manager)
+
+	// Test that trackers can be switched while syncs run; run with -race
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			s.TriggerNow(context.Background())
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		s.SetTrackerEnabled("anilist", i%2 == 0)
+	}
+	<-done
+
+	s.SetTrackerEnabled("anilist", false)
+	if s.trackerEnabled("anilist") {
+		t.Errorf("Expected anilist to be switched off")
+	}
+}
+
+func TestWatchingNow(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "pair-test.db"))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	var shown []*WatchingNow
+	syncMgr := NewSyncManager(db, NewTrackerManager(db))
+	syncMgr.SetPresence(func(now *WatchingNow) error {
+		shown = append(shown, now)
+		return nil
+	})
+
+	// Test that nothing is playing at first and stopping then does nothing
+	if now, err := syncMgr.WatchingNow(); err != nil || now != nil {
+		t.Fatalf("Expected nothing playing, got %+v, %v", now, err)
+	}
+	if err := syncMgr.StopWatching(); err != nil {
+		t.Fatalf("Failed to stop watching: %v", err)
+	}
+	if len(shown) != 0 {
+		t.Errorf("Expected no presence updates, got %d", len(shown))
+	}
+
+	// Test that starting records the episode and shows it
+	if err := syncMgr.StartWatching(context.Background(), 7, 3.5); err != nil {
+		t.Fatalf("Failed to start watching: %v", err)
+	}
+	now, err := syncMgr.WatchingNow()
+	if err != nil {
+		t.Fatalf("Failed to get watching state: %v", err)
+	}
+	if now == nil || now.AnimeID != 7 || now.Episode != 3.5 || now.StartedAt.IsZero() {
+		t.Fatalf("Expected anime 7 episode 3.5 playing, got %+v", now)
+	}
+	if len(shown) != 1 || shown[0] == nil || shown[0].Episode != 3.5 {
+		t.Errorf("Expected the episode on the presence, got %+v", shown)
+	}
+
+	// Test that the state survives a new manager, as after a crash
+	if now, _ := NewSyncManager(db, NewTrackerManager(db)).WatchingNow(); now == nil || now.AnimeID != 7 {
+		t.Errorf("Expected the watching state to be kept in the database, got %+v", now)
+	}
+
+	// Test that stopping clears the state and the presence
+	if err := syncMgr.StopWatching(); err != nil {
+		t.Fatalf("Failed to stop watching: %v", err)
+	}
+	if now, err := syncMgr.WatchingNow(); err != nil || now != nil {
+		t.Errorf("Expected nothing playing after stopping, got %+v, %v", now, err)
+	}
+	if len(shown) != 2 || shown[1] != nil {
+		t.Errorf("Expected the presence to be cleared, got %+v", shown)
+	}
+}
@@ -0,0 +1,31 @@
+package tracker
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// DefaultRequestTimeout bounds a single tracker API request when none is configured
+const DefaultRequestTimeout = 15 * time.Second
+
+// withRequestTimeout derives a context for a single API request.
+// A non-positive timeout leaves the parent deadline in charge.
+func withRequestTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// cancelOnClose releases a request context once the response body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
@@ -0,0 +1,109 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// newSlowServer returns a server that only answers once the request is abandoned
+func newSlowServer(t *testing.T) *httptest.Server {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	t.Cleanup(func() {
+		close(release)
+		server.Close()
+	})
+	return server
+}
+
+func TestAnilistRequestTimeout(t *testing.T) {
+	server := newSlowServer(t)
+
+	tracker := NewAnilistTracker(t.TempDir())
+	tracker.apiURL = server.URL
+	tracker.token = &AnilistToken{AccessToken: "token", ExpiresAt: time.Now().Add(time.Hour)}
+	tracker.SetRequestTimeout(50 * time.Millisecond)
+
+	// Test that the request is aborted at the configured deadline
+	start := time.Now()
+	_, err := tracker.graphqlRequest(context.Background(), "query { Viewer { id } }", nil)
+	if err == nil {
+		t.Fatalf("Expected request to time out")
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected request to be aborted near 50ms, took %v", elapsed)
+	}
+}
+
+func TestMALRequestTimeout(t *testing.T) {
+	server := newSlowServer(t)
+
+	tracker := NewMALTracker(t.TempDir())
+	tracker.baseURL = server.URL
+	tracker.token = &MALToken{AccessToken: "token", TokenType: "Bearer", ExpiresAt: time.Now().Add(time.Hour)}
+	tracker.SetRequestTimeout(50 * time.Millisecond)
+
+	// Test that the request is aborted at the configured deadline
+	_, err := tracker.apiRequest(context.Background(), http.MethodGet, "/users/@me", nil, nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded error, got %v", err)
+	}
+}
+
+// redirectTransport sends every request to target, so requests to fixed
+// endpoints like the OAuth token URLs can be pointed at a test server
+type redirectTransport struct {
+	target *url.URL
+}
+
+func (rt redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req.URL.Scheme = rt.target.Scheme
+	req.URL.Host = rt.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestTokenRefreshTimeout(t *testing.T) {
+	server := newSlowServer(t)
+	target, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse server URL: %v", err)
+	}
+	client := &http.Client{Transport: redirectTransport{target}}
+
+	anilist := NewAnilistTracker(t.TempDir())
+	anilist.httpClient = client
+	anilist.token = &AnilistToken{RefreshToken: "refresh"}
+	anilist.SetRequestTimeout(50 * time.Millisecond)
+
+	mal := NewMALTracker(t.TempDir())
+	mal.httpClient = client
+	mal.token = &MALToken{RefreshToken: "refresh"}
+	mal.SetRequestTimeout(50 * time.Millisecond)
+
+	// Test that a stalled token endpoint doesn't hang the refresh
+	for name, refresh := range map[string]func(context.Context) error{
+		"anilist": anilist.refreshToken,
+		"mal":     mal.refreshToken,
+	} {
+		start := time.Now()
+		if err := refresh(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected %s refresh to time out, got %v", name, err)
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("Expected %s refresh to be aborted near 50ms, took %v", name, elapsed)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package tracker
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// encryptedTokenPrefix marks token files that were written encrypted.
+// Files without it are treated as legacy plaintext JSON.
+const encryptedTokenPrefix = "pair-enc:v1:"
+
+// DeriveTokenKey derives an AES-256 key for token encryption.
+// If passphrase is empty, a machine-specific secret is used instead.
+func DeriveTokenKey(passphrase string) []byte {
+	if passphrase == "" {
+		passphrase = machineSecret()
+	}
+	sum := sha256.Sum256([]byte("pair-token-key:" + passphrase))
+	return sum[:]
+}
+
+// machineSecret returns a value that is stable for this machine and user
+func machineSecret() string {
+	for _, path := range []string{"/etc/machine-id", "/var/lib/dbus/machine-id"} {
+		if data, err := os.ReadFile(path); err == nil {
+			if id := strings.TrimSpace(string(data)); id != "" {
+				return id
+			}
+		}
+	}
+
+	host, _ := os.Hostname()
+	home, _ := os.UserHomeDir()
+	return host + ":" + home
+}
+
+// encryptToken encrypts token data with AES-GCM
+func encryptToken(plaintext, key []byte) ([]byte, error) {
+	gcm, err := newTokenCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := gcm.Seal(nonce, nonce, plaintext, nil)
+	encoded := base64.StdEncoding.EncodeToString(sealed)
+	return []byte(encryptedTokenPrefix + encoded), nil
+}
+
+// decryptToken decrypts token data written by encryptToken
+func decryptToken(data, key []byte) ([]byte, error) {
+	gcm, err := newTokenCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded := strings.TrimSpace(strings.TrimPrefix(string(data), encryptedTokenPrefix))
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode encrypted token: %w", err)
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, fmt.Errorf("encrypted token is too short")
+	}
+
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt token: %w", err)
+	}
+	return plaintext, nil
+}
+
+// newTokenCipher creates the AES-GCM cipher used for tokens
+func newTokenCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// readTokenFile reads a token file, decrypting it if it was stored encrypted.
+// Plaintext files are returned as-is so they get encrypted on the next save.
+func readTokenFile(path string, key []byte) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.HasPrefix(data, []byte(encryptedTokenPrefix)) {
+		return data, nil
+	}
+	if key == nil {
+		return nil, fmt.Errorf("token file %s is encrypted but no encryption key is configured", path)
+	}
+	return decryptToken(data, key)
+}
+
+// writeTokenFile writes a token file, encrypting it when a key is set
+func writeTokenFile(path string, data, key []byte) error {
+	if key != nil {
+		encrypted, err := encryptToken(data, key)
+		if err != nil {
+			return err
+		}
+		data = encrypted
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// tokenFilenames are the login files trackers keep in the data directory
+var tokenFilenames = []string{anilistTokenFilename, malTokenFilename, malStateFilename}
+
+// MigrateTokenFiles moves the tracker login files older versions kept in
+// fromDir to toDir. Files already present in toDir are kept and the old copy
+// is left alone, so running it again does nothing.
+func MigrateTokenFiles(fromDir, toDir string) error {
+	if filepath.Clean(fromDir) == filepath.Clean(toDir) {
+		return nil
+	}
+
+	for _, name := range tokenFilenames {
+		from, to := filepath.Join(fromDir, name), filepath.Join(toDir, name)
+		if _, err := os.Stat(from); os.IsNotExist(err) {
+			continue
+		}
+		if _, err := os.Stat(to); err == nil {
+			continue
+		}
+
+		if err := os.MkdirAll(toDir, 0755); err != nil {
+			return fmt.Errorf("failed to create data directory: %w", err)
+		}
+		if err := os.Rename(from, to); err != nil {
+			// The directories may be on different filesystems
+			data, readErr := os.ReadFile(from)
+			if readErr != nil {
+				return fmt.Errorf("failed to move %s: %w", name, err)
+			}
+			if err := os.WriteFile(to, data, 0600); err != nil {
+				return fmt.Errorf("failed to move %s: %w", name, err)
+			}
+			if err := os.Remove(from); err != nil {
+				return fmt.Errorf("failed to remove old %s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
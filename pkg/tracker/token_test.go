@@ -0,0 +1,147 @@
+package tracker
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTokenEncryptionRoundTrip(t *testing.T) {
+	key := DeriveTokenKey("test passphrase")
+	plaintext := []byte(`{"access_token":"secret"}`)
+
+	// Test encrypting token data
+	encrypted, err := encryptToken(plaintext, key)
+	if err != nil {
+		t.Fatalf("Failed to encrypt token: %v", err)
+	}
+	if bytes.Contains(encrypted, []byte("secret")) {
+		t.Errorf("Expected encrypted token not to contain the plaintext")
+	}
+
+	// Test decrypting token data
+	decrypted, err := decryptToken(encrypted, key)
+	if err != nil {
+		t.Fatalf("Failed to decrypt token: %v", err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Errorf("Expected decrypted token '%s', got '%s'", plaintext, decrypted)
+	}
+
+	// Test decrypting with the wrong key
+	if _, err := decryptToken(encrypted, DeriveTokenKey("other passphrase")); err == nil {
+		t.Errorf("Expected error when decrypting with the wrong key")
+	}
+}
+
+func TestTokenPlaintextMigration(t *testing.T) {
+	configDir := t.TempDir()
+	tokenPath := filepath.Join(configDir, anilistTokenFilename)
+
+	// Write a legacy plaintext token
+	legacy := AnilistToken{
+		AccessToken:  "access",
+		RefreshToken: "refresh",
+		ExpiresAt:    time.Now().Add(time.Hour),
+	}
+	data, err := json.Marshal(legacy)
+	if err != nil {
+		t.Fatalf("Failed to marshal token: %v", err)
+	}
+	if err := os.WriteFile(tokenPath, data, 0600); err != nil {
+		t.Fatalf("Failed to write token: %v", err)
+	}
+
+	// Test loading the plaintext token with encryption enabled
+	tracker := NewAnilistTracker(configDir)
+	tracker.SetTokenEncryptionKey(DeriveTokenKey("test passphrase"))
+	if err := tracker.loadToken(); err != nil {
+		t.Fatalf("Failed to load plaintext token: %v", err)
+	}
+	if tracker.token.AccessToken != "access" {
+		t.Errorf("Expected access token 'access', got '%s'", tracker.token.AccessToken)
+	}
+
+	// Test that saving migrates the token to the encrypted format
+	if err := tracker.saveToken(); err != nil {
+		t.Fatalf("Failed to save token: %v", err)
+	}
+	stored, err := os.ReadFile(tokenPath)
+	if err != nil {
+		t.Fatalf("Failed to read token: %v", err)
+	}
+	if !bytes.HasPrefix(stored, []byte(encryptedTokenPrefix)) {
+		t.Errorf("Expected token file to be encrypted after save")
+	}
+
+	// Verify the encrypted token loads back
+	reloaded := NewAnilistTracker(configDir)
+	reloaded.SetTokenEncryptionKey(DeriveTokenKey("test passphrase"))
+	if err := reloaded.loadToken(); err != nil {
+		t.Fatalf("Failed to load encrypted token: %v", err)
+	}
+	if reloaded.token.RefreshToken != "refresh" {
+		t.Errorf("Expected refresh token 'refresh', got '%s'", reloaded.token.RefreshToken)
+	}
+
+	// Verify an encrypted token can't be read without a key
+	if err := NewAnilistTracker(configDir).loadToken(); err == nil {
+		t.Errorf("Expected error when loading encrypted token without a key")
+	}
+}
+
+// Test that logins kept in the config directory move to the data directory
+func TestMigrateTokenFiles(t *testing.T) {
+	configDir := t.TempDir()
+	dataDir := filepath.Join(t.TempDir(), "data")
+
+	if err := os.WriteFile(filepath.Join(configDir, anilistTokenFilename), []byte("anilist"), 0600); err != nil {
+		t.Fatalf("Failed to write token: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(configDir, malTokenFilename), []byte("old mal"), 0600); err != nil {
+		t.Fatalf("Failed to write token: %v", err)
+	}
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		t.Fatalf("Failed to create data directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dataDir, malTokenFilename), []byte("new mal"), 0600); err != nil {
+		t.Fatalf("Failed to write token: %v", err)
+	}
+
+	if err := MigrateTokenFiles(configDir, dataDir); err != nil {
+		t.Fatalf("Failed to migrate tokens: %v", err)
+	}
+
+	moved, err := os.ReadFile(filepath.Join(dataDir, anilistTokenFilename))
+	if err != nil {
+		t.Fatalf("Failed to read migrated token: %v", err)
+	}
+	if string(moved) != "anilist" {
+		t.Errorf("Expected migrated token 'anilist', got '%s'", moved)
+	}
+	if _, err := os.Stat(filepath.Join(configDir, anilistTokenFilename)); !os.IsNotExist(err) {
+		t.Errorf("Expected token to be removed from the config directory")
+	}
+
+	// A login already in the data directory wins
+	kept, err := os.ReadFile(filepath.Join(dataDir, malTokenFilename))
+	if err != nil {
+		t.Fatalf("Failed to read token: %v", err)
+	}
+	if string(kept) != "new mal" {
+		t.Errorf("Expected token 'new mal', got '%s'", kept)
+	}
+
+	// Running it again does nothing
+	if err := MigrateTokenFiles(configDir, dataDir); err != nil {
+		t.Fatalf("Failed to migrate tokens again: %v", err)
+	}
+
+	anilist := NewAnilistTracker(dataDir)
+	if filepath.Dir(anilist.tokenPath) != dataDir {
+		t.Errorf("Expected token in %s, got %s", dataDir, anilist.tokenPath)
+	}
+}
@@ -2,14 +2,118 @@ package tracker
 
 import (
 	"context"
-	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/wraient/pair/pkg/database"
+	"github.com/wraient/pair/pkg/httpclient"
 )
 
+// ErrNotInList is returned when an anime isn't on the user's list
+var ErrNotInList = errors.New("anime is not on the list")
+
+// ErrUnsupported is returned by optional tracker features the service doesn't offer
+var ErrUnsupported = errors.New("not supported by this tracker")
+
+// Errors wrapped by tracker API calls so callers can react to the cause
+var (
+	// ErrAuthExpired means the login is no longer valid and the user has to log in again
+	ErrAuthExpired = errors.New("login expired")
+	// ErrRateLimited means the service refused the request for being sent too often
+	ErrRateLimited = errors.New("rate limited")
+	// ErrRemoteNotFound means the requested anime or entry doesn't exist on the service
+	ErrRemoteNotFound = errors.New("not found")
+)
+
+// statusError returns the error matching an HTTP status code, or nil when
+// the status has no dedicated error
+func statusError(status int) error {
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrAuthExpired
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	case http.StatusNotFound:
+		return ErrRemoteNotFound
+	}
+	return nil
+}
+
+// apiError describes a failed API response. The error wraps the matching
+// error from statusError.
+func apiError(action string, status int, body []byte) error {
+	msg := fmt.Sprintf("%s (%d)", strings.TrimSpace(string(body)), status)
+	if action != "" {
+		msg = action + ": " + msg
+	}
+	if kind := statusError(status); kind != nil {
+		return fmt.Errorf("%s: %w", msg, kind)
+	}
+	return errors.New(msg)
+}
+
+// PartialListError is returned along with the entries that could be
+// fetched when part of a user's list couldn't be. Entries missing from such
+// a list must not be taken as deleted.
+type PartialListError struct {
+	Fetched int // number of entries returned
+	Err     error
+}
+
+func (e *PartialListError) Error() string {
+	return fmt.Sprintf("list incomplete after %d entries: %v", e.Fetched, e.Err)
+}
+
+func (e *PartialListError) Unwrap() error {
+	return e.Err
+}
+
+// SyncError is a failure during a sync with what was being done when it
+// happened, so failures can be grouped by tracker and shown per anime
+type SyncError struct {
+	Tracker string `json:"tracker,omitempty"` // tracker synced with, empty for failures not tied to one
+	Title   string `json:"title,omitempty"`   // anime concerned, empty for failures not about one anime
+	Op      string `json:"op"`                // what failed, as in "process remote entry"
+	Err     error  `json:"error"`
+}
+
+func (e SyncError) Error() string {
+	msg := "failed to " + e.Op
+	if e.Title != "" {
+		msg += " " + e.Title
+	}
+	if e.Tracker != "" {
+		msg = e.Tracker + ": " + msg
+	}
+	return fmt.Sprintf("%s: %v", msg, e.Err)
+}
+
+func (e SyncError) Unwrap() error {
+	return e.Err
+}
+
+// MarshalJSON encodes the error as its message, errors have no fields to encode
+func (e SyncError) MarshalJSON() ([]byte, error) {
+	type fields SyncError
+	message := ""
+	if e.Err != nil {
+		message = e.Err.Error()
+	}
+	return json.Marshal(struct {
+		fields
+		Err string `json:"error"`
+	}{fields(e), message})
+}
+
 // Status represents the watch status of an anime
 type Status string
 
@@ -22,6 +126,42 @@ const (
 	StatusPlanToWatch Status = "plan_to_watch"
 )
 
+// RemoteProgress converts local episode progress to the whole-episode count
+// remote trackers store. Fractional episodes such as recaps (12.5) round down
+// to the last full episode, so specials aren't pushed to trackers that only
+// count regular episodes.
+func RemoteProgress(episode float64) int {
+	if episode <= 0 {
+		return 0
+	}
+	return int(math.Floor(episode))
+}
+
+// MergeProgress returns the progress to store locally when taking remote
+// progress. The precise local value is kept when the remote only differs by
+// the fractional part it can't represent.
+func MergeProgress(local, remote float64) float64 {
+	if float64(RemoteProgress(local)) == remote {
+		return local
+	}
+	return remote
+}
+
+// AnimeURL returns the web page of an anime on a tracker, or "" when the
+// tracker has no pages or the ID is empty
+func AnimeURL(trackerName, id string) string {
+	if id == "" {
+		return ""
+	}
+	switch trackerName {
+	case "anilist":
+		return "https://anilist.co/anime/" + url.PathEscape(id)
+	case "mal":
+		return "https://myanimelist.net/anime/" + url.PathEscape(id)
+	}
+	return ""
+}
+
 // Tracker is the interface that must be implemented by all trackers
 type Tracker interface {
 	// Name returns the name of the tracker
@@ -42,6 +182,10 @@ type Tracker interface {
 	// GetUserAnimeList gets the user's anime list
 	GetUserAnimeList(ctx context.Context) ([]UserAnimeEntry, error)
 
+	// GetListEntry gets the current state of a single entry of the user's list.
+	// It returns ErrNotInList if the anime isn't on the list.
+	GetListEntry(ctx context.Context, id string) (*UserAnimeEntry, error)
+
 	// UpdateAnimeStatus updates the watch status of an anime
 	UpdateAnimeStatus(ctx context.Context, id string, status Status, episode float64, score float64) error
 
@@ -52,10 +196,163 @@ type Tracker interface {
 	SyncToRemote(ctx context.Context, db *database.DB) (SyncStats, error)
 }
 
+// Endpoint is implemented by remote trackers to expose the base URL of their API
+type Endpoint interface {
+	// APIURL returns the URL requests are sent to
+	APIURL() string
+}
+
+// Reachable reports whether the API of t can be reached, probing through proxyURL
+// when one is set. Trackers without an Endpoint and a nil prober are assumed reachable.
+func Reachable(ctx context.Context, prober *httpclient.Prober, t Tracker, proxyURL string) bool {
+	endpoint, ok := t.(Endpoint)
+	if prober == nil || !ok {
+		return true
+	}
+
+	addr := httpclient.ProbeAddr(endpoint.APIURL(), proxyURL)
+	if addr == "" {
+		return true
+	}
+	return prober.Reachable(ctx, addr)
+}
+
+// Discoverer is implemented by trackers that can suggest anime to watch
+type Discoverer interface {
+	// GetSeasonalAnime lists the most popular anime of a season
+	GetSeasonalAnime(ctx context.Context, year int, season string) ([]AnimeInfo, error)
+
+	// GetTrendingAnime lists the anime that are popular right now
+	GetTrendingAnime(ctx context.Context, limit int) ([]AnimeInfo, error)
+}
+
+// AiringEntry is the next episode of an airing anime
+type AiringEntry struct {
+	AnimeID  string
+	Title    string
+	Episode  int
+	AiringAt time.Time
+}
+
+// AiringScheduler is implemented by trackers that know when episodes air. It
+// is the optional interface callers assert to get an airing schedule provider.
+type AiringScheduler interface {
+	// GetAiringSchedule lists the next episode of each airing anime the user
+	// is watching, soonest first
+	GetAiringSchedule(ctx context.Context) ([]AiringEntry, error)
+}
+
+// FinishDateSetter is implemented by trackers whose list entries record when
+// the user finished an anime
+type FinishDateSetter interface {
+	// SetFinishDate sets the day a list entry was finished
+	SetFinishDate(ctx context.Context, id string, date time.Time) error
+}
+
+// NoteWriter is implemented by trackers whose list entries carry a note
+type NoteWriter interface {
+	// UpdateNotes replaces the note of a list entry, an empty note clearing it
+	UpdateNotes(ctx context.Context, id string, notes string) error
+}
+
+// RewatchFinisher is implemented by trackers whose list entries record that
+// an anime is being rewatched
+type RewatchFinisher interface {
+	// FinishRewatch ends the rewatch of a list entry, leaving it completed
+	FinishRewatch(ctx context.Context, id string) error
+}
+
+// ListDetailsUpdater is implemented by trackers whose list entries carry a
+// priority and tags
+type ListDetailsUpdater interface {
+	// UpdateListDetails sets the priority and tags of a list entry
+	UpdateListDetails(ctx context.Context, id string, priority int, tags []string) error
+}
+
+// DetailsBatcher is implemented by trackers that can get the details of
+// many anime with fewer requests than one per anime
+type DetailsBatcher interface {
+	// GetAnimeDetailsBatch gets the details of the given anime keyed by ID.
+	// Anime that aren't found are left out of the result.
+	GetAnimeDetailsBatch(ctx context.Context, ids []string) (map[string]*AnimeInfo, error)
+}
+
+// GetAnimeDetailsBatch gets the details of the given anime from t, using its
+// batch lookup when it has one and one GetAnimeDetails call per ID otherwise
+func GetAnimeDetailsBatch(ctx context.Context, t Tracker, ids []string) (map[string]*AnimeInfo, error) {
+	if batcher, ok := t.(DetailsBatcher); ok {
+		return batcher.GetAnimeDetailsBatch(ctx, ids)
+	}
+
+	details := make(map[string]*AnimeInfo, len(ids))
+	for _, id := range ids {
+		info, err := t.GetAnimeDetails(ctx, id)
+		if err != nil {
+			return details, fmt.Errorf("failed to get details of anime %s: %w", id, err)
+		}
+		details[id] = info
+	}
+	return details, nil
+}
+
+// RemoteEntryDeleter is implemented by trackers that can remove an anime
+// from the user's list. It is the optional interface callers assert before
+// deleting a remote entry; trackers without it keep entries on delete.
+type RemoteEntryDeleter interface {
+	// DeleteRemoteEntry removes the anime from the list. It returns
+	// ErrNotInList if the anime isn't on the list.
+	DeleteRemoteEntry(ctx context.Context, id string) error
+}
+
+// Pinger is implemented by trackers that log in to a remote service
+type Pinger interface {
+	// Ping checks that the service still accepts the stored login, using the
+	// cheapest authenticated request it has
+	Ping(ctx context.Context) error
+}
+
+// tokenStore is implemented by trackers that keep their login in a file
+type tokenStore interface {
+	tokenFile() string
+}
+
+// HasTokenFile reports whether t keeps its login in a file and that file exists
+func HasTokenFile(t Tracker) bool {
+	store, ok := t.(tokenStore)
+	if !ok {
+		return false
+	}
+	_, err := os.Stat(store.tokenFile())
+	return err == nil
+}
+
+// seasonalAnimeLimit is the number of anime fetched for a season
+const seasonalAnimeLimit = 50
+
+// Seasons lists the anime seasons in calendar order
+var Seasons = []string{"winter", "spring", "summer", "fall"}
+
+// CurrentSeason returns the year and anime season of the given time
+func CurrentSeason(now time.Time) (int, string) {
+	return now.Year(), Seasons[(int(now.Month())-1)/3]
+}
+
+// normalizeSeason lowercases a season name and checks that it is valid
+func normalizeSeason(season string) (string, error) {
+	season = strings.ToLower(strings.TrimSpace(season))
+	for _, valid := range Seasons {
+		if season == valid {
+			return season, nil
+		}
+	}
+	return "", fmt.Errorf("invalid season %q", season)
+}
+
 // AnimeInfo represents basic anime information from a tracker
 type AnimeInfo struct {
 	ID                string
-	Title             string
+	Title             string // the title the service prefers for the user
+	RomajiTitle       string
 	EnglishTitle      string
 	JapaneseTitle     string
 	AlternativeTitles []string
@@ -71,17 +368,134 @@ type AnimeInfo struct {
 	Genres            []string
 	Studios           []string
 	ImageURL          string
+	Relations         []RelatedAnime
+	// Rank and Popularity are MAL's rankings by score and by members, 0 when unranked
+	Rank       int
+	Popularity int
+	// MembersCount is the number of users with the anime on their list
+	MembersCount int
+	// Favourites is the number of Anilist users who favourited the anime
+	Favourites int
+	// MalID is the anime's MyAnimeList ID, when the service knows it
+	MalID string
+}
+
+// maxSearchPageSize is the most results Anilist and MAL return per search request
+const maxSearchPageSize = 50
+
+// searchPages collects up to limit search results, requesting pages of at
+// most maxSearchPageSize until a short page shows there are no more
+func searchPages(limit int, fetch func(page, perPage int) ([]AnimeInfo, error)) ([]AnimeInfo, error) {
+	perPage := min(limit, maxSearchPageSize)
+	var results []AnimeInfo
+	for page := 1; len(results) < limit; page++ {
+		batch, err := fetch(page, perPage)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, batch...)
+		if len(batch) < perPage {
+			break
+		}
+	}
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// Title languages for DisplayTitle
+const (
+	TitlePreferred = "preferred"
+	TitleRomaji    = "romaji"
+	TitleEnglish   = "english"
+	TitleNative    = "native"
+)
+
+// DisplayTitle returns the title in the given language. It falls back to the
+// service's preferred title when there is none in that language, and to any
+// known title when even that is missing.
+func (a AnimeInfo) DisplayTitle(lang string) string {
+	var title string
+	switch lang {
+	case TitleRomaji:
+		title = a.RomajiTitle
+	case TitleEnglish:
+		title = a.EnglishTitle
+	case TitleNative:
+		title = a.JapaneseTitle
+		if title == "" {
+			title = nativeTitle(a.AlternativeTitles)
+		}
+	}
+	if title != "" {
+		return title
+	}
+
+	for _, candidate := range append([]string{a.Title, a.RomajiTitle, a.EnglishTitle, a.JapaneseTitle}, a.AlternativeTitles...) {
+		if candidate != "" {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// nativeTitle returns the first title written in Japanese script
+func nativeTitle(titles []string) string {
+	for _, title := range titles {
+		for _, r := range title {
+			if unicode.In(r, unicode.Hiragana, unicode.Katakana, unicode.Han) {
+				return title
+			}
+		}
+	}
+	return ""
+}
+
+// RelatedAnime is an anime related to another one, such as a sequel
+type RelatedAnime struct {
+	AnimeInfo
+	RelationType string // SEQUEL, PREQUEL, SIDE_STORY, ...
+}
+
+// relationOrder ranks relation types for display; unknown types sort last
+var relationOrder = map[string]int{
+	"SEQUEL":      0,
+	"PREQUEL":     1,
+	"SIDE_STORY":  2,
+	"PARENT":      3,
+	"SPIN_OFF":    4,
+	"ALTERNATIVE": 5,
+}
+
+// SortRelations orders related anime by relation type, sequels first
+func SortRelations(relations []RelatedAnime) {
+	rank := func(relationType string) int {
+		if order, ok := relationOrder[relationType]; ok {
+			return order
+		}
+		return len(relationOrder)
+	}
+
+	sort.SliceStable(relations, func(i, j int) bool {
+		return rank(relations[i].RelationType) < rank(relations[j].RelationType)
+	})
 }
 
 // UserAnimeEntry represents an entry in a user's anime list
 type UserAnimeEntry struct {
 	AnimeInfo
-	Status      Status
-	Score       float64
-	Progress    float64
-	StartDate   time.Time
-	EndDate     time.Time
-	Notes       string
+	Status   Status
+	Score    float64
+	Progress float64
+	// Rewatching is set when a completed anime is being watched again
+	Rewatching bool
+	StartDate  time.Time
+	EndDate    time.Time
+	Notes      string
+	// Priority and Tags are only kept by trackers that support them, like MyAnimeList
+	Priority    int
+	Tags        []string
 	LastUpdated time.Time
 }
 
@@ -95,6 +509,21 @@ type SyncStats struct {
 	Details []string
 }
 
+// add accumulates the counts and details of other into s
+func (s *SyncStats) add(other SyncStats) {
+	s.Added += other.Added
+	s.Updated += other.Updated
+	s.Deleted += other.Deleted
+	s.Skipped += other.Skipped
+	s.Errors += other.Errors
+	s.Details = append(s.Details, other.Details...)
+}
+
+// lastSyncKey returns the config key holding the last sync time of a tracker
+func lastSyncKey(trackerName string) string {
+	return database.LastSyncKey(trackerName)
+}
+
 // TrackerManager manages all trackers
 type TrackerManager struct {
 	trackers map[string]Tracker
@@ -123,20 +552,24 @@ func (m *TrackerManager) GetTracker(name string) (Tracker, error) {
 	return tracker, nil
 }
 
+// Trackers returns the registered trackers sorted by name
+func (m *TrackerManager) Trackers() []Tracker {
+	trackers := make([]Tracker, 0, len(m.trackers))
+	for _, t := range m.trackers {
+		trackers = append(trackers, t)
+	}
+	sort.Slice(trackers, func(i, j int) bool {
+		return trackers[i].Name() < trackers[j].Name()
+	})
+	return trackers
+}
+
 // GetActiveTracker returns the currently active tracker
 func (m *TrackerManager) GetActiveTracker() (Tracker, error) {
 	// Get active tracker from config
-	activeTrackerName, err := m.db.GetConfig("active_tracker")
+	activeTrackerName, err := m.db.GetConfigOrDefault(database.ConfigActiveTracker)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			// Default to local tracker if not set
-			activeTrackerName = "local"
-			if err := m.db.SetConfig("active_tracker", "local"); err != nil {
-				return nil, fmt.Errorf("failed to set default active tracker: %w", err)
-			}
-		} else {
-			return nil, fmt.Errorf("failed to get active tracker from config: %w", err)
-		}
+		return nil, fmt.Errorf("failed to get active tracker from config: %w", err)
 	}
 
 	return m.GetTracker(activeTrackerName)
@@ -150,7 +583,7 @@ func (m *TrackerManager) SetActiveTracker(name string) error {
 	}
 
 	// Set active tracker in config
-	return m.db.SetConfig("active_tracker", name)
+	return m.db.SetConfig(database.ConfigActiveTracker, name)
 }
 
 // SyncAllFromRemote synchronizes all trackers from remote to local
@@ -192,3 +625,40 @@ func (m *TrackerManager) SyncAllToRemote(ctx context.Context) (map[string]SyncSt
 
 	return stats, nil
 }
+
+// ForceResync clears the last sync time of a tracker and runs a full sync in
+// both directions, so entries skipped by incremental syncs are reconciled
+func (m *TrackerManager) ForceResync(ctx context.Context, trackerName string) (SyncStats, error) {
+	var stats SyncStats
+
+	tracker, err := m.GetTracker(trackerName)
+	if err != nil {
+		return stats, err
+	}
+	if !tracker.IsAuthenticated() {
+		return stats, fmt.Errorf("tracker %q is not authenticated", trackerName)
+	}
+
+	if err := m.db.DeleteConfig(lastSyncKey(trackerName)); err != nil {
+		return stats, fmt.Errorf("failed to reset last sync time: %w", err)
+	}
+
+	fromStats, err := tracker.SyncFromRemote(ctx, m.db)
+	stats.add(fromStats)
+	if err != nil {
+		return stats, fmt.Errorf("failed to sync from %s: %w", trackerName, err)
+	}
+
+	// SyncFromRemote records a new sync time, clear it again so every local entry is pushed
+	if err := m.db.DeleteConfig(lastSyncKey(trackerName)); err != nil {
+		return stats, fmt.Errorf("failed to reset last sync time: %w", err)
+	}
+
+	toStats, err := tracker.SyncToRemote(ctx, m.db)
+	stats.add(toStats)
+	if err != nil {
+		return stats, fmt.Errorf("failed to sync to %s: %w", trackerName, err)
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,927 @@
+package tracker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/wraient/pair/pkg/database"
+	"github.com/wraient/pair/pkg/httpclient"
+)
+
+// newAnilistServer serves an empty Anilist list and counts list updates
+func newAnilistServer(t *testing.T, updates *int32) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Query string `json:"query"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+
+		switch {
+		case strings.Contains(req.Query, "SaveMediaListEntry"):
+			atomic.AddInt32(updates, 1)
+			w.Write([]byte(`{"data": {"SaveMediaListEntry": {"id": 1}}}`))
+		case strings.Contains(req.Query, "Viewer"):
+			w.Write([]byte(`{"data": {"Viewer": {"id": 1, "name": "test"}}}`))
+		default:
+			w.Write([]byte(`{"data": {"MediaListCollection": {"lists": []}}}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestForceResync(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "pair-test.db"))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	var updates int32
+	server := newAnilistServer(t, &updates)
+
+	anilist := NewAnilistTracker(t.TempDir())
+	anilist.apiURL = server.URL
+	anilist.token = &AnilistToken{AccessToken: "token", ExpiresAt: time.Now().Add(time.Hour)}
+
+	manager := NewTrackerManager(db)
+	manager.RegisterTracker(anilist)
+
+	anime := &database.Anime{Title: "Test Anime", TotalEpisodes: 12}
+	if err := db.AddAnime(anime); err != nil {
+		t.Fatalf("Failed to add anime: %v", err)
+	}
+	tracking := &database.AnimeTracking{AnimeID: anime.ID, Tracker: "anilist", TrackerID: "101", Status: "watching", CurrentEpisode: 3}
+	if err := db.AddAnimeTracking(tracking); err != nil {
+		t.Fatalf("Failed to add tracking: %v", err)
+	}
+
+	// Record a sync newer than the entry so incremental syncs skip it
+	if err := db.SetConfig("anilist_last_sync", time.Now().Add(time.Hour).Format(time.RFC3339)); err != nil {
+		t.Fatalf("Failed to set last sync time: %v", err)
+	}
+
+	stats, err := anilist.SyncToRemote(context.Background(), db)
+	if err != nil {
+		t.Fatalf("Failed to sync to remote: %v", err)
+	}
+	if stats.Skipped != 1 || atomic.LoadInt32(&updates) != 0 {
+		t.Fatalf("Expected entry to be skipped, got %d skipped and %d updates", stats.Skipped, updates)
+	}
+
+	// Test that a forced resync processes the skipped entry
+	stats, err = manager.ForceResync(context.Background(), "anilist")
+	if err != nil {
+		t.Fatalf("Failed to force resync: %v", err)
+	}
+	if stats.Updated != 1 || stats.Skipped != 0 {
+		t.Errorf("Expected 1 updated and 0 skipped, got %d updated and %d skipped", stats.Updated, stats.Skipped)
+	}
+	if got := atomic.LoadInt32(&updates); got != 1 {
+		t.Errorf("Expected 1 update sent to Anilist, got %d", got)
+	}
+
+	// Test resyncing an unknown tracker
+	if _, err := manager.ForceResync(context.Background(), "unknown"); err == nil {
+		t.Errorf("Expected error for unknown tracker")
+	}
+}
+
+func TestCurrentSeason(t *testing.T) {
+	tests := []struct {
+		month  time.Month
+		season string
+	}{
+		{time.January, "winter"},
+		{time.April, "spring"},
+		{time.August, "summer"},
+		{time.December, "fall"},
+	}
+	for _, tt := range tests {
+		year, season := CurrentSeason(time.Date(2024, tt.month, 10, 0, 0, 0, 0, time.UTC))
+		if year != 2024 || season != tt.season {
+			t.Errorf("Expected 2024 %s for %s, got %d %s", tt.season, tt.month, year, season)
+		}
+	}
+}
+
+func TestAnilistGetSeasonalAnime(t *testing.T) {
+	var variables map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Query     string                 `json:"query"`
+			Variables map[string]interface{} `json:"variables"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		if !strings.Contains(req.Query, "POPULARITY_DESC") {
+			t.Errorf("Expected seasonal query sorted by popularity")
+		}
+		variables = req.Variables
+		w.Write([]byte(`{"data": {"Page": {"media": [
+			{"id": 1, "title": {"userPreferred": "Popular Show", "english": "Popular Show"}, "format": "TV", "episodes": 12, "season": "SPRING", "seasonYear": 2024, "averageScore": 85}
+		]}}}`))
+	}))
+	defer server.Close()
+
+	anilist := NewAnilistTracker(t.TempDir())
+	anilist.apiURL = server.URL
+	anilist.token = &AnilistToken{AccessToken: "token", ExpiresAt: time.Now().Add(time.Hour)}
+
+	var discoverer Discoverer = anilist
+	results, err := discoverer.GetSeasonalAnime(context.Background(), 2024, "Spring")
+	if err != nil {
+		t.Fatalf("Failed to get seasonal anime: %v", err)
+	}
+	if variables["season"] != "SPRING" || variables["seasonYear"] != float64(2024) {
+		t.Errorf("Expected SPRING 2024 variables, got %v", variables)
+	}
+	if len(results) != 1 || results[0].Title != "Popular Show" || results[0].Season != "spring" || results[0].Rating != 8.5 {
+		t.Errorf("Unexpected seasonal results: %+v", results)
+	}
+
+	// Test rejecting an unknown season
+	if _, err := anilist.GetSeasonalAnime(context.Background(), 2024, "monsoon"); err == nil {
+		t.Errorf("Expected error for invalid season")
+	}
+}
+
+func TestMALGetSeasonalAnime(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/anime/season/2023/fall" {
+			t.Errorf("Expected seasonal path, got %s", r.URL.Path)
+		}
+		w.Write([]byte(`{"data": [
+			{"node": {"id": 5, "title": "Fall Show", "media_type": "tv", "num_episodes": 24, "start_season": {"year": 2023, "season": "fall"}}}
+		]}`))
+	}))
+	defer server.Close()
+
+	mal := NewMALTracker(t.TempDir())
+	mal.baseURL = server.URL
+	mal.token = &MALToken{AccessToken: "token", TokenType: "Bearer", ExpiresAt: time.Now().Add(time.Hour)}
+
+	results, err := mal.GetSeasonalAnime(context.Background(), 2023, "fall")
+	if err != nil {
+		t.Fatalf("Failed to get seasonal anime: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != "5" || results[0].Episodes != 24 {
+		t.Errorf("Unexpected seasonal results: %+v", results)
+	}
+}
+
+func TestMALGetTrendingAnime(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/anime/ranking" || r.URL.Query().Get("ranking_type") != "airing" {
+			t.Errorf("Expected airing ranking request, got %s", r.URL.String())
+		}
+		w.Write([]byte(`{"data": [
+			{"node": {"id": 7, "title": "Airing Show", "media_type": "tv", "num_episodes": 12}, "ranking": {"rank": 1}}
+		]}`))
+	}))
+	defer server.Close()
+
+	mal := NewMALTracker(t.TempDir())
+	mal.baseURL = server.URL
+	mal.token = &MALToken{AccessToken: "token", TokenType: "Bearer", ExpiresAt: time.Now().Add(time.Hour)}
+
+	results, err := mal.GetTrendingAnime(context.Background(), 10)
+	if err != nil {
+		t.Fatalf("Failed to get trending anime: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != "7" || results[0].Title != "Airing Show" {
+		t.Errorf("Unexpected trending results: %+v", results)
+	}
+}
+
+func TestAnimeRankingFields(t *testing.T) {
+	malServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.URL.Query().Get("fields"), "num_list_users") {
+			t.Errorf("Expected ranking fields to be requested, got %s", r.URL.Query().Get("fields"))
+		}
+		w.Write([]byte(`{"id": 5114, "title": "Ranked Show", "mean": 9.1, "rank": 2, "popularity": 3, "num_list_users": 3456789}`))
+	}))
+	defer malServer.Close()
+
+	mal := NewMALTracker(t.TempDir())
+	mal.baseURL = malServer.URL
+	mal.token = &MALToken{AccessToken: "token", TokenType: "Bearer", ExpiresAt: time.Now().Add(time.Hour)}
+
+	// Test that MAL rankings and members are parsed
+	anime, err := mal.GetAnimeDetails(context.Background(), "5114")
+	if err != nil {
+		t.Fatalf("Failed to get anime details: %v", err)
+	}
+	if anime.Rank != 2 || anime.Popularity != 3 || anime.MembersCount != 3456789 {
+		t.Errorf("Unexpected MAL rankings: rank %d, popularity %d, members %d", anime.Rank, anime.Popularity, anime.MembersCount)
+	}
+
+	anilistServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data": {"Page": {"media": [
+			{"id": 1, "title": {"userPreferred": "Popular Show"}, "popularity": 654321, "favourites": 12345}
+		]}}}`))
+	}))
+	defer anilistServer.Close()
+
+	anilist := NewAnilistTracker(t.TempDir())
+	anilist.apiURL = anilistServer.URL
+	anilist.token = &AnilistToken{AccessToken: "token", ExpiresAt: time.Now().Add(time.Hour)}
+
+	// Test that Anilist popularity counts members and favourites are kept
+	results, err := anilist.GetSeasonalAnime(context.Background(), 2024, "spring")
+	if err != nil {
+		t.Fatalf("Failed to get seasonal anime: %v", err)
+	}
+	if len(results) != 1 || results[0].MembersCount != 654321 || results[0].Favourites != 12345 {
+		t.Errorf("Unexpected Anilist results: %+v", results)
+	}
+}
+
+func TestMALGetListEntry(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/anime/5":
+			w.Write([]byte(`{"id": 5, "title": "Listed Show", "num_episodes": 12, "my_list_status": {
+				"status": "on_hold", "score": 7, "num_episodes_watched": 4, "updated_at": "2024-05-01T10:00:00+00:00"
+			}}`))
+		default:
+			w.Write([]byte(`{"id": 6, "title": "Unlisted Show", "num_episodes": 12}`))
+		}
+	}))
+	defer server.Close()
+
+	mal := NewMALTracker(t.TempDir())
+	mal.baseURL = server.URL
+	mal.token = &MALToken{AccessToken: "token", TokenType: "Bearer", ExpiresAt: time.Now().Add(time.Hour)}
+
+	entry, err := mal.GetListEntry(context.Background(), "5")
+	if err != nil {
+		t.Fatalf("Failed to get list entry: %v", err)
+	}
+	if entry.Status != StatusOnHold || entry.Progress != 4 || entry.Score != 7 {
+		t.Errorf("Unexpected list entry: %+v", entry)
+	}
+
+	// Test that an anime missing from the list is reported as such
+	if _, err := mal.GetListEntry(context.Background(), "6"); !errors.Is(err, ErrNotInList) {
+		t.Errorf("Expected ErrNotInList, got %v", err)
+	}
+}
+
+func TestRemoteProgress(t *testing.T) {
+	tests := []struct {
+		episode  float64
+		expected int
+	}{
+		{episode: 12, expected: 12},
+		{episode: 12.5, expected: 12},
+		{episode: 0.5, expected: 0},
+		{episode: 0, expected: 0},
+		{episode: -1, expected: 0},
+	}
+
+	for _, tt := range tests {
+		if got := RemoteProgress(tt.episode); got != tt.expected {
+			t.Errorf("Expected %g to push %d, got %d", tt.episode, tt.expected, got)
+		}
+	}
+
+	// Test that precise local progress survives a matching remote value
+	if got := MergeProgress(12.5, 12); got != 12.5 {
+		t.Errorf("Expected local 12.5 to be kept, got %g", got)
+	}
+	if got := MergeProgress(12.5, 13); got != 13 {
+		t.Errorf("Expected remote 13 to be taken, got %g", got)
+	}
+}
+
+func TestAnimeURL(t *testing.T) {
+	tests := []struct {
+		tracker  string
+		id       string
+		expected string
+	}{
+		{tracker: "anilist", id: "21", expected: "https://anilist.co/anime/21"},
+		{tracker: "mal", id: "5114", expected: "https://myanimelist.net/anime/5114"},
+		{tracker: "local", id: "3", expected: ""},
+		{tracker: "anilist", id: "", expected: ""},
+	}
+
+	// Test that each tracker gets its own page and others get none
+	for _, tt := range tests {
+		if got := AnimeURL(tt.tracker, tt.id); got != tt.expected {
+			t.Errorf("Expected %s %q to give %q, got %q", tt.tracker, tt.id, tt.expected, got)
+		}
+	}
+}
+
+func TestMALUpdateAnimeStatusFractional(t *testing.T) {
+	var watched string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		watched = r.PostForm.Get("num_watched_episodes")
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	mal := NewMALTracker(t.TempDir())
+	mal.baseURL = server.URL
+	mal.token = &MALToken{AccessToken: "token", TokenType: "Bearer", ExpiresAt: time.Now().Add(time.Hour)}
+
+	// Test that a recap episode pushes the last full episode
+	if err := mal.UpdateAnimeStatus(context.Background(), "5", StatusWatching, 12.5, 0); err != nil {
+		t.Fatalf("Failed to update anime status: %v", err)
+	}
+	if watched != "12" {
+		t.Errorf("Expected 12 watched episodes to be pushed, got '%s'", watched)
+	}
+}
+
+func TestMALListDetailsRoundTrip(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "pair-test.db"))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	var patched []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPatch {
+			r.ParseForm()
+			patched = append(patched, r.PostForm.Encode())
+			w.Write([]byte(`{}`))
+			return
+		}
+		w.Write([]byte(`{"id": 5, "title": "Listed Show", "num_episodes": 12, "my_list_status": {
+			"status": "watching", "num_episodes_watched": 4, "priority": 2, "tags": ["rewatch", "favorite"],
+			"updated_at": "2024-05-01T10:00:00+00:00"
+		}}`))
+	}))
+	defer server.Close()
+
+	mal := NewMALTracker(t.TempDir())
+	mal.baseURL = server.URL
+	mal.token = &MALToken{AccessToken: "token", TokenType: "Bearer", ExpiresAt: time.Now().Add(time.Hour)}
+
+	entry, err := mal.GetListEntry(context.Background(), "5")
+	if err != nil {
+		t.Fatalf("Failed to get list entry: %v", err)
+	}
+
+	// Store the entry and read it back
+	anime := &database.Anime{Title: entry.Title, TotalEpisodes: entry.Episodes}
+	if err := db.AddAnime(anime); err != nil {
+		t.Fatalf("Failed to add anime: %v", err)
+	}
+	tracking := &database.AnimeTracking{
+		AnimeID: anime.ID, Tracker: "mal", TrackerID: entry.ID, Status: string(entry.Status),
+		CurrentEpisode: entry.Progress, Priority: entry.Priority, Tags: entry.Tags,
+	}
+	if err := db.AddAnimeTracking(tracking); err != nil {
+		t.Fatalf("Failed to add tracking: %v", err)
+	}
+	stored, err := db.GetAnimeTracking(anime.ID, "mal")
+	if err != nil {
+		t.Fatalf("Failed to get tracking: %v", err)
+	}
+	if stored.Priority != 2 || strings.Join(stored.Tags, ",") != "rewatch,favorite" {
+		t.Errorf("Expected priority 2 and tags rewatch,favorite, got %d and %v", stored.Priority, stored.Tags)
+	}
+
+	// Test that an update without list details keeps the stored ones
+	update := &database.AnimeTracking{AnimeID: anime.ID, Tracker: "mal", TrackerID: entry.ID, Status: "completed", CurrentEpisode: 12}
+	if err := db.AddAnimeTracking(update); err != nil {
+		t.Fatalf("Failed to update tracking: %v", err)
+	}
+	if stored, err = db.GetAnimeTracking(anime.ID, "mal"); err != nil {
+		t.Fatalf("Failed to get tracking: %v", err)
+	}
+	if stored.Priority != 2 || len(stored.Tags) != 2 {
+		t.Errorf("Expected list details to be kept, got %d and %v", stored.Priority, stored.Tags)
+	}
+
+	// Test that the stored details are sent back in the PATCH body
+	if err := mal.UpdateListDetails(context.Background(), stored.TrackerID, stored.Priority, stored.Tags); err != nil {
+		t.Fatalf("Failed to update list details: %v", err)
+	}
+	if len(patched) != 1 || patched[0] != "priority=2&tags=rewatch%2Cfavorite" {
+		t.Errorf("Unexpected PATCH bodies: %v", patched)
+	}
+}
+
+func TestAnilistGetAiringSchedule(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Query string `json:"query"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+
+		if strings.Contains(req.Query, "Viewer") {
+			w.Write([]byte(`{"data": {"Viewer": {"id": 1, "name": "test"}}}`))
+			return
+		}
+		if !strings.Contains(req.Query, "nextAiringEpisode") {
+			t.Errorf("Expected the next airing episode to be queried")
+		}
+		w.Write([]byte(`{"data": {"MediaListCollection": {"lists": [{"entries": [
+			{"media": {"id": 1, "title": {"userPreferred": "Later Show"}, "nextAiringEpisode": {"airingAt": 1700090000, "episode": 5}}},
+			{"media": {"id": 2, "title": {"userPreferred": "Finished Show"}, "nextAiringEpisode": null}},
+			{"media": {"id": 3, "title": {"userPreferred": "Sooner Show"}, "nextAiringEpisode": {"airingAt": 1700000000, "episode": 11}}}
+		]}]}}}`))
+	}))
+	defer server.Close()
+
+	anilist := NewAnilistTracker(t.TempDir())
+	anilist.apiURL = server.URL
+	anilist.token = &AnilistToken{AccessToken: "token", ExpiresAt: time.Now().Add(time.Hour)}
+
+	schedule, err := anilist.GetAiringSchedule(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to get airing schedule: %v", err)
+	}
+
+	// Test that finished shows are skipped and the rest sorted by air time
+	if len(schedule) != 2 {
+		t.Fatalf("Expected 2 schedule entries, got %d", len(schedule))
+	}
+	if schedule[0].AnimeID != "3" || schedule[0].Episode != 11 || !schedule[0].AiringAt.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("Unexpected first entry: %+v", schedule[0])
+	}
+	if schedule[1].AnimeID != "1" || schedule[1].Title != "Later Show" {
+		t.Errorf("Unexpected second entry: %+v", schedule[1])
+	}
+}
+
+func TestOptionalInterfaces(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "pair-test.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	trackers := []Tracker{NewAnilistTracker(t.TempDir()), NewMALTracker(t.TempDir()), NewLocalTracker(db)}
+	expected := map[string][]bool{
+		// NoteWriter, RemoteEntryDeleter, Discoverer, AiringScheduler
+		"anilist": {true, true, true, true},
+		"mal":     {true, true, true, false},
+		"local":   {false, false, false, false},
+	}
+
+	// Test that each tracker opts into the optional features its service has
+	for _, tr := range trackers {
+		_, notes := tr.(NoteWriter)
+		_, deletes := tr.(RemoteEntryDeleter)
+		_, discovers := tr.(Discoverer)
+		_, schedules := tr.(AiringScheduler)
+		got := []bool{notes, deletes, discovers, schedules}
+		for i, want := range expected[tr.Name()] {
+			if got[i] != want {
+				t.Errorf("Expected %s optional interfaces %v, got %v", tr.Name(), expected[tr.Name()], got)
+				break
+			}
+		}
+	}
+}
+
+func TestAnilistGetAnimeDetailsBatch(t *testing.T) {
+	var chunks [][]int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Query     string `json:"query"`
+			Variables struct {
+				IDs []int `json:"ids"`
+			} `json:"variables"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		if !strings.Contains(req.Query, "id_in") {
+			t.Errorf("Expected a query by id_in")
+		}
+		chunks = append(chunks, req.Variables.IDs)
+
+		// Echo back every requested ID except 7, which doesn't exist
+		var media []string
+		for _, id := range req.Variables.IDs {
+			if id != 7 {
+				media = append(media, fmt.Sprintf(`{"id": %d, "title": {"userPreferred": "Show %d"}, "episodes": 12}`, id, id))
+			}
+		}
+		fmt.Fprintf(w, `{"data": {"Page": {"media": [%s]}}}`, strings.Join(media, ","))
+	}))
+	defer server.Close()
+
+	anilist := NewAnilistTracker(t.TempDir())
+	anilist.apiURL = server.URL
+	anilist.token = &AnilistToken{AccessToken: "token", ExpiresAt: time.Now().Add(time.Hour)}
+
+	ids := make([]string, 0, 120)
+	for i := 1; i <= 120; i++ {
+		ids = append(ids, strconv.Itoa(i))
+	}
+
+	details, err := GetAnimeDetailsBatch(context.Background(), anilist, ids)
+	if err != nil {
+		t.Fatalf("Failed to get anime details: %v", err)
+	}
+
+	// Test that the IDs are requested in chunks of at most 50
+	if len(chunks) != 3 || len(chunks[0]) != 50 || len(chunks[1]) != 50 || len(chunks[2]) != 20 {
+		sizes := make([]int, len(chunks))
+		for i, chunk := range chunks {
+			sizes[i] = len(chunk)
+		}
+		t.Errorf("Expected chunks of 50, 50 and 20, got %v", sizes)
+	}
+
+	// Test that results are keyed by ID and missing anime are left out
+	if len(details) != 119 {
+		t.Errorf("Expected 119 results, got %d", len(details))
+	}
+	if info := details["120"]; info == nil || info.Title != "Show 120" || info.Episodes != 12 {
+		t.Errorf("Unexpected details for 120: %+v", info)
+	}
+	if _, ok := details["7"]; ok {
+		t.Errorf("Expected missing anime to be left out")
+	}
+}
+
+func TestAnilistErrorKinds(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   error
+	}{
+		{"unauthorized", http.StatusUnauthorized, `{"errors": [{"message": "Invalid token", "status": 401}]}`, ErrAuthExpired},
+		{"invalid token message", http.StatusBadRequest, `{"errors": [{"message": "Invalid token", "status": 400}]}`, ErrAuthExpired},
+		{"rate limited", http.StatusTooManyRequests, `{"errors": [{"message": "Too Many Requests.", "status": 429}]}`, ErrRateLimited},
+		{"rate limited without body", http.StatusTooManyRequests, `Too Many Requests`, ErrRateLimited},
+		{"not found", http.StatusNotFound, `{"errors": [{"message": "Not Found.", "status": 404}]}`, ErrRemoteNotFound},
+		{"other error", http.StatusBadRequest, `{"errors": [{"message": "Validation error", "status": 400}]}`, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			anilist := NewAnilistTracker(t.TempDir())
+			anilist.apiURL = server.URL
+			anilist.token = &AnilistToken{AccessToken: "token", ExpiresAt: time.Now().Add(time.Hour)}
+
+			// Test that the condition is reported as the matching error
+			err := anilist.UpdateAnimeStatus(context.Background(), "1", StatusWatching, 1, 0)
+			if err == nil {
+				t.Fatal("Expected an error")
+			}
+			for _, kind := range []error{ErrAuthExpired, ErrRateLimited, ErrRemoteNotFound} {
+				if errors.Is(err, kind) != (kind == tt.want) {
+					t.Errorf("Expected errors.Is(err, %v) to be %v, got error %v", kind, kind == tt.want, err)
+				}
+			}
+		})
+	}
+}
+
+func TestMALErrorKinds(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		want   error
+	}{
+		{"unauthorized", http.StatusUnauthorized, ErrAuthExpired},
+		{"forbidden", http.StatusForbidden, ErrAuthExpired},
+		{"rate limited", http.StatusTooManyRequests, ErrRateLimited},
+		{"not found", http.StatusNotFound, ErrRemoteNotFound},
+		{"server error", http.StatusInternalServerError, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(`{"error": "failed"}`))
+			}))
+			defer server.Close()
+
+			mal := NewMALTracker(t.TempDir())
+			mal.baseURL = server.URL
+			mal.token = &MALToken{AccessToken: "token", TokenType: "Bearer", ExpiresAt: time.Now().Add(time.Hour)}
+
+			// Test both the shared request path and the status update path
+			_, detailsErr := mal.GetAnimeDetails(context.Background(), "1")
+			updateErr := mal.UpdateAnimeStatus(context.Background(), "1", StatusWatching, 1, 0)
+			for _, err := range []error{detailsErr, updateErr} {
+				if err == nil {
+					t.Fatal("Expected an error")
+				}
+				for _, kind := range []error{ErrAuthExpired, ErrRateLimited, ErrRemoteNotFound} {
+					if errors.Is(err, kind) != (kind == tt.want) {
+						t.Errorf("Expected errors.Is(err, %v) to be %v, got error %v", kind, kind == tt.want, err)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestDisplayTitle(t *testing.T) {
+	anime := AnimeInfo{
+		Title:         "Shingeki no Kyojin",
+		RomajiTitle:   "Shingeki no Kyojin",
+		EnglishTitle:  "Attack on Titan",
+		JapaneseTitle: "進撃の巨人",
+	}
+
+	// Test each language preference
+	tests := map[string]string{
+		TitlePreferred: "Shingeki no Kyojin",
+		TitleRomaji:    "Shingeki no Kyojin",
+		TitleEnglish:   "Attack on Titan",
+		TitleNative:    "進撃の巨人",
+		"":             "Shingeki no Kyojin",
+		"klingon":      "Shingeki no Kyojin",
+	}
+	for lang, want := range tests {
+		if got := anime.DisplayTitle(lang); got != want {
+			t.Errorf("Expected %q for %q, got %q", want, lang, got)
+		}
+	}
+
+	// Test falling back to the preferred title when a language is missing
+	sparse := AnimeInfo{Title: "Frieren", AlternativeTitles: []string{"Frieren: Beyond Journey's End", "葬送のフリーレン"}}
+	if got := sparse.DisplayTitle(TitleEnglish); got != "Frieren" {
+		t.Errorf("Expected fallback to Frieren, got %q", got)
+	}
+	if got := sparse.DisplayTitle(TitleRomaji); got != "Frieren" {
+		t.Errorf("Expected fallback to Frieren, got %q", got)
+	}
+
+	// Test that a native title is found among the alternatives
+	if got := sparse.DisplayTitle(TitleNative); got != "葬送のフリーレン" {
+		t.Errorf("Expected native alternative, got %q", got)
+	}
+
+	// Test falling back to any title when the preferred one is empty
+	untitled := AnimeInfo{EnglishTitle: "Only English"}
+	if got := untitled.DisplayTitle(TitleNative); got != "Only English" {
+		t.Errorf("Expected Only English, got %q", got)
+	}
+}
+
+func TestAnilistSearchPaging(t *testing.T) {
+	var pages []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Variables map[string]interface{} `json:"variables"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		page := int(req.Variables["page"].(float64))
+		perPage := int(req.Variables["perPage"].(float64))
+		pages = append(pages, fmt.Sprintf("%d/%d", page, perPage))
+
+		// The search has 70 results in total
+		var media []string
+		for id := (page-1)*perPage + 1; id <= page*perPage && id <= 70; id++ {
+			media = append(media, fmt.Sprintf(`{"id": %d, "title": {"userPreferred": "Show %d"}}`, id, id))
+		}
+		fmt.Fprintf(w, `{"data": {"Page": {"media": [%s]}}}`, strings.Join(media, ","))
+	}))
+	defer server.Close()
+
+	anilist := NewAnilistTracker(t.TempDir())
+	anilist.apiURL = server.URL
+	anilist.token = &AnilistToken{AccessToken: "token", ExpiresAt: time.Now().Add(time.Hour)}
+
+	// Test that a limit within one page makes a single request
+	results, err := anilist.SearchAnime(context.Background(), "show", 20)
+	if err != nil {
+		t.Fatalf("Failed to search anime: %v", err)
+	}
+	if len(results) != 20 || strings.Join(pages, ",") != "1/20" {
+		t.Errorf("Expected 20 results from one page, got %d from %v", len(results), pages)
+	}
+
+	// Test that larger limits are capped per request and paged until the results run out
+	pages = nil
+	results, err = anilist.SearchAnime(context.Background(), "show", 120)
+	if err != nil {
+		t.Fatalf("Failed to search anime: %v", err)
+	}
+	if len(results) != 70 || results[69].ID != "70" {
+		t.Errorf("Expected all 70 results, got %d", len(results))
+	}
+	if strings.Join(pages, ",") != "1/50,2/50" {
+		t.Errorf("Expected two pages of 50, got %v", pages)
+	}
+}
+
+func TestAnilistSyncRecordsMalID(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "pair-test.db"))
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	// An anime already synced from MAL
+	existing := &database.Anime{Title: "Fullmetal Alchemist: Brotherhood"}
+	if err := db.AddAnime(existing); err != nil {
+		t.Fatalf("Failed to add anime: %v", err)
+	}
+	if err := db.AddAnimeTracking(&database.AnimeTracking{AnimeID: existing.ID, Tracker: "mal", TrackerID: "5114", Status: "completed"}); err != nil {
+		t.Fatalf("Failed to add tracking: %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Query string `json:"query"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		if strings.Contains(req.Query, "Viewer") {
+			w.Write([]byte(`{"data": {"Viewer": {"id": 1, "name": "test"}}}`))
+			return
+		}
+		if !strings.Contains(req.Query, "idMal") {
+			t.Errorf("Expected idMal to be queried")
+		}
+		w.Write([]byte(`{"data": {"MediaListCollection": {"lists": [{"entries": [
+			{"media": {"id": 5114, "idMal": 5114, "title": {"userPreferred": "Hagane no Renkinjutsushi: FULLMETAL ALCHEMIST"}}, "status": "COMPLETED", "progress": 64, "updatedAt": 1700000000},
+			{"media": {"id": 21, "idMal": 21, "title": {"userPreferred": "ONE PIECE"}}, "status": "CURRENT", "progress": 1000, "updatedAt": 1700000000}
+		]}]}}}`))
+	}))
+	defer server.Close()
+
+	anilist := NewAnilistTracker(t.TempDir())
+	anilist.apiURL = server.URL
+	anilist.token = &AnilistToken{AccessToken: "token", ExpiresAt: time.Now().Add(time.Hour)}
+
+	if _, err := anilist.SyncFromRemote(context.Background(), db); err != nil {
+		t.Fatalf("Failed to sync from Anilist: %v", err)
+	}
+
+	// Test that the entry with a known MAL ID joins the existing anime
+	animes, err := db.GetAllAnime()
+	if err != nil {
+		t.Fatalf("Failed to get anime: %v", err)
+	}
+	if len(animes) != 2 {
+		t.Fatalf("Expected 2 anime, got %d", len(animes))
+	}
+	if _, err := db.GetAnimeTracking(existing.ID, "anilist"); err != nil {
+		t.Errorf("Expected the existing anime to be tracked on Anilist: %v", err)
+	}
+
+	// Test that the MAL ID of the new anime is recorded and finds it
+	added, err := db.GetAnimeByExternalID("21", "anilist")
+	if err != nil {
+		t.Fatalf("Failed to get added anime: %v", err)
+	}
+	if malID, err := db.GetAnimeMalID(added.ID); err != nil || malID != "21" {
+		t.Errorf("Expected MAL ID 21, got %q (%v)", malID, err)
+	}
+	if found, err := db.GetAnimeByMalID("21"); err != nil || found.ID != added.ID {
+		t.Errorf("Expected anime %d by MAL ID, got %+v (%v)", added.ID, found, err)
+	}
+}
+
+// newPagedMALServer serves a MAL list of three pages of one entry each.
+// The page at failOffset fails the given number of times first.
+func newPagedMALServer(t *testing.T, failOffset, failures int) (*httptest.Server, *[]string) {
+	var offsets []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		offset := r.URL.Query().Get("offset")
+		offsets = append(offsets, offset)
+		if offset == strconv.Itoa(failOffset) && failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error": "internal"}`))
+			return
+		}
+
+		n, _ := strconv.Atoi(offset)
+		next := ""
+		if n < 200 {
+			next = fmt.Sprintf(`"next": "%s/users/@me/animelist?offset=%d&limit=100"`, "http://"+r.Host, n+100)
+		}
+		fmt.Fprintf(w, `{"data": [{"node": {"id": %d, "title": "Show %d"}, "list_status": {"status": "watching"}}], "paging": {%s}}`, n/100+1, n/100+1, next)
+	}))
+	t.Cleanup(server.Close)
+	return server, &offsets
+}
+
+func TestMALUserListRetriesFailedPage(t *testing.T) {
+	server, offsets := newPagedMALServer(t, 100, 1)
+
+	mal := NewMALTracker(t.TempDir())
+	mal.baseURL = server.URL
+	mal.token = &MALToken{AccessToken: "token", TokenType: "Bearer", ExpiresAt: time.Now().Add(time.Hour)}
+	mal.pageRetryDelay = 0
+
+	// Test that page 2 failing once is retried at the same offset
+	entries, err := mal.GetUserAnimeList(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to get user anime list: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(entries))
+	}
+	for i, entry := range entries {
+		if expected := strconv.Itoa(i + 1); entry.ID != expected {
+			t.Errorf("Expected entry %d to be %s, got %s", i, expected, entry.ID)
+		}
+	}
+	if got := strings.Join(*offsets, ","); got != "0,100,100,200" {
+		t.Errorf("Expected offsets 0,100,100,200, got %s", got)
+	}
+}
+
+func TestMALUserListPartialPages(t *testing.T) {
+	server, _ := newPagedMALServer(t, 100, malPageRetries+1)
+
+	mal := NewMALTracker(t.TempDir())
+	mal.baseURL = server.URL
+	mal.token = &MALToken{AccessToken: "token", TokenType: "Bearer", ExpiresAt: time.Now().Add(time.Hour)}
+	mal.pageRetryDelay = 0
+
+	// Test that a page that keeps failing returns the pages before it
+	entries, err := mal.GetUserAnimeList(context.Background())
+	var partial *PartialListError
+	if !errors.As(err, &partial) {
+		t.Fatalf("Expected a PartialListError, got %v", err)
+	}
+	if len(entries) != 1 || partial.Fetched != 1 {
+		t.Errorf("Expected the first page only, got %d entries", len(entries))
+	}
+
+	// Test that strict paging fails the whole fetch
+	server, _ = newPagedMALServer(t, 100, malPageRetries+1)
+	mal.baseURL = server.URL
+	mal.SetStrictPaging(true)
+	entries, err = mal.GetUserAnimeList(context.Background())
+	if err == nil || errors.As(err, &partial) {
+		t.Errorf("Expected a plain error with strict paging, got %v", err)
+	}
+	if entries != nil {
+		t.Errorf("Expected no entries with strict paging, got %d", len(entries))
+	}
+}
+
+func TestTrackersSendUserAgent(t *testing.T) {
+	var agents []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		agents = append(agents, r.Header.Get("User-Agent"))
+		if r.URL.Path == "/" {
+			w.Write([]byte(`{"data": {"Viewer": {"id": 1, "name": "test"}}}`))
+			return
+		}
+		w.Write([]byte(`{"id": 1, "name": "test"}`))
+	}))
+	defer server.Close()
+
+	anilist := NewAnilistTracker(t.TempDir())
+	anilist.apiURL = server.URL
+	anilist.token = &AnilistToken{AccessToken: "token", ExpiresAt: time.Now().Add(time.Hour)}
+
+	mal := NewMALTracker(t.TempDir())
+	mal.baseURL = server.URL + "/v2"
+	mal.token = &MALToken{AccessToken: "token", TokenType: "Bearer", ExpiresAt: time.Now().Add(time.Hour)}
+
+	// Test that requests to both trackers identify pair
+	if err := anilist.Ping(context.Background()); err != nil {
+		t.Fatalf("Failed to ping Anilist: %v", err)
+	}
+	if err := mal.Ping(context.Background()); err != nil {
+		t.Fatalf("Failed to ping MAL: %v", err)
+	}
+	if len(agents) != 2 {
+		t.Fatalf("Expected 2 requests, got %d", len(agents))
+	}
+	for _, agent := range agents {
+		if agent != httpclient.DefaultUserAgent() {
+			t.Errorf("Expected User-Agent %s, got %q", httpclient.DefaultUserAgent(), agent)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package tracker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// watchingNowKey is the config key holding the episode being played
+const watchingNowKey = "watching_now"
+
+// WatchingNow is the episode the user is playing
+type WatchingNow struct {
+	AnimeID   int64     `json:"anime_id"`
+	Episode   float64   `json:"episode"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// Presence shows what the user is watching elsewhere, such as a Discord
+// status. It is called with nil when playback stops.
+type Presence func(now *WatchingNow) error
+
+// SetPresence sets where the episode being played is shown. A nil presence
+// only records it.
+func (s *SyncManager) SetPresence(presence Presence) {
+	s.presence = presence
+}
+
+// StartWatching records that an episode started playing, separately from
+// finishing it, and shows it on the presence. A state left behind by a
+// player that never stopped is replaced.
+func (s *SyncManager) StartWatching(ctx context.Context, animeID int64, episode float64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	now := &WatchingNow{AnimeID: animeID, Episode: episode, StartedAt: time.Now()}
+	data, err := json.Marshal(now)
+	if err != nil {
+		return fmt.Errorf("failed to encode watching state: %w", err)
+	}
+	if err := s.db.SetConfig(watchingNowKey, string(data)); err != nil {
+		return fmt.Errorf("failed to save watching state: %w", err)
+	}
+
+	if s.presence != nil {
+		return s.presence(now)
+	}
+	return nil
+}
+
+// StopWatching clears the episode being played and the presence. It does
+// nothing when nothing is playing.
+func (s *SyncManager) StopWatching() error {
+	now, err := s.WatchingNow()
+	if err != nil || now == nil {
+		return err
+	}
+
+	if err := s.db.DeleteConfig(watchingNowKey); err != nil {
+		return fmt.Errorf("failed to clear watching state: %w", err)
+	}
+	if s.presence != nil {
+		return s.presence(nil)
+	}
+	return nil
+}
+
+// WatchingNow returns the episode being played, or the one that was when
+// pair last exited without stopping it. It returns nil when nothing is.
+func (s *SyncManager) WatchingNow() (*WatchingNow, error) {
+	data, err := s.db.GetConfig(watchingNowKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get watching state: %w", err)
+	}
+	if data == "" {
+		return nil, nil
+	}
+
+	var now WatchingNow
+	if err := json.Unmarshal([]byte(data), &now); err != nil {
+		return nil, fmt.Errorf("failed to decode watching state: %w", err)
+	}
+	return &now, nil
+}
@@ -1,22 +1,45 @@
 package ui
 
 import (
+	"errors"
 	"fmt"
+	"math"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/wraient/pair/pkg/database"
 	"github.com/wraient/pair/pkg/tracker"
 )
 
-// ShowAnimeSearchResults displays search results in a CLI menu and returns the selected anime's ID
-func ShowAnimeSearchResults(results []tracker.AnimeInfo) (string, error) {
+// titleLanguage is the language anime titles are displayed in
+var titleLanguage = tracker.TitlePreferred
+
+// SetTitleLanguage sets the language anime titles are displayed in, one of
+// the tracker.Title* languages
+func SetTitleLanguage(lang string) {
+	titleLanguage = lang
+}
+
+// ErrNoResults is returned by ShowAnimeSearchResults and ShowAnimeList when
+// there is nothing to show, so callers can tell it apart from a failure
+var ErrNoResults = errors.New("no results found")
+
+// LoadMore is returned by ShowAnimeSearchResults when the user asks for more results
+const LoadMore = "load_more"
+
+// ShowAnimeSearchResults displays search results in a CLI menu and returns
+// the selected anime's ID. With more set, a "Load more results" item is
+// offered that returns LoadMore. Without results it returns ErrNoResults.
+func ShowAnimeSearchResults(results []tracker.AnimeInfo, more bool) (string, error) {
 	if len(results) == 0 {
-		return "", fmt.Errorf("no results found")
+		return "", ErrNoResults
 	}
 
 	items := make([]Pair, len(results))
 	for i, anime := range results {
 		// Create a display string with title and additional info
-		displayInfo := []string{anime.Title}
+		displayInfo := []string{anime.DisplayTitle(titleLanguage)}
 		if anime.Year > 0 {
 			displayInfo = append(displayInfo, fmt.Sprintf("(%d)", anime.Year))
 		}
@@ -32,6 +55,9 @@ func ShowAnimeSearchResults(results []tracker.AnimeInfo) (string, error) {
 			Value: anime.ID,
 		}
 	}
+	if more {
+		items = append(items, Pair{Label: "Load more results", Value: LoadMore})
+	}
 
 	selectedID, err := ShowCLIMenu(List, items)
 	if err != nil {
@@ -59,23 +85,14 @@ func ShowAnimeStatusSelection() (tracker.Status, error) {
 	return tracker.Status(status), nil
 }
 
-// ShowAnimeScoreSelection displays a menu to select anime score (0-10)
-func ShowAnimeScoreSelection() (float64, error) {
-	items := []Pair{
-		{Label: "10 - Masterpiece", Value: "10"},
-		{Label: "9 - Great", Value: "9"},
-		{Label: "8 - Very Good", Value: "8"},
-		{Label: "7 - Good", Value: "7"},
-		{Label: "6 - Fine", Value: "6"},
-		{Label: "5 - Average", Value: "5"},
-		{Label: "4 - Bad", Value: "4"},
-		{Label: "3 - Very Bad", Value: "3"},
-		{Label: "2 - Horrible", Value: "2"},
-		{Label: "1 - Appalling", Value: "1"},
-		{Label: "0 - No Score", Value: "0"},
+// ShowAnimeScoreSelection asks for a score in the given format. Formats with
+// decimals or a 100-point scale take a typed score, the others a pick from a list.
+func ShowAnimeScoreSelection(format tracker.ScoreFormat) (float64, error) {
+	if format.Decimal() || format.Max() > 10 {
+		return showScoreInput(format)
 	}
 
-	scoreStr, err := ShowCLIMenu(List, items)
+	scoreStr, err := ShowCLIMenu(List, scoreItems(format))
 	if err != nil {
 		return 0, fmt.Errorf("menu error: %w", err)
 	}
@@ -90,28 +107,185 @@ func ShowAnimeScoreSelection() (float64, error) {
 	return score, nil
 }
 
-// ShowAnimeList displays the user's anime list and returns the selected anime's ID
+// scoreLabels describe the scores of the 10-point scale
+var scoreLabels = map[int]string{
+	10: "Masterpiece", 9: "Great", 8: "Very Good", 7: "Good", 6: "Fine",
+	5: "Average", 4: "Bad", 3: "Very Bad", 2: "Horrible", 1: "Appalling", 0: "No Score",
+}
+
+// scoreItems lists the whole scores of a format from highest to lowest
+func scoreItems(format tracker.ScoreFormat) []Pair {
+	highest := int(format.Max())
+	items := make([]Pair, 0, highest+1)
+	for score := highest; score >= 0; score-- {
+		label := strconv.Itoa(score)
+		if highest == 10 || score == 0 {
+			label += " - " + scoreLabels[score]
+		}
+		items = append(items, Pair{Label: label, Value: strconv.Itoa(score)})
+	}
+	return items
+}
+
+// showScoreInput asks for a typed score until it fits the format
+func showScoreInput(format tracker.ScoreFormat) (float64, error) {
+	prompt := fmt.Sprintf("Score (0-%g, 0 for none)", format.Max())
+	for {
+		input, err := ShowTextInput(prompt, UserInput, nil)
+		if err != nil {
+			return 0, err
+		}
+
+		score, err := parseScore(input, format)
+		if errors.Is(err, tracker.ErrInvalidScore) {
+			prompt = fmt.Sprintf("%v, try again (0-%g)", err, format.Max())
+			continue
+		}
+		return score, err
+	}
+}
+
+// parseScore reads a typed score and checks it against the format
+func parseScore(input string, format tracker.ScoreFormat) (float64, error) {
+	score, err := strconv.ParseFloat(strings.TrimSpace(input), 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q is not a number", tracker.ErrInvalidScore, input)
+	}
+	if err := format.Validate(score); err != nil {
+		return 0, err
+	}
+	return score, nil
+}
+
+// ShowSeasonSelection lets the user pick an anime season around now, or type
+// in another one. An empty season means the selection was cancelled.
+func ShowSeasonSelection(now time.Time) (int, string, error) {
+	year, season := tracker.CurrentSeason(now)
+	index := 0
+	for i, s := range tracker.Seasons {
+		if s == season {
+			index = i
+		}
+	}
+
+	items := make([]Pair, 0, 5)
+	for _, offset := range []int{0, -1, 1} {
+		y, s := shiftSeason(year, index, offset)
+		label := fmt.Sprintf("%s %d", seasonLabel(s), y)
+		switch offset {
+		case 0:
+			label = "Current season - " + label
+		case -1:
+			label = "Previous season - " + label
+		case 1:
+			label = "Next season - " + label
+		}
+		items = append(items, Pair{Label: label, Value: fmt.Sprintf("%d %s", y, s)})
+	}
+	items = append(items, Pair{Label: "Choose season...", Value: "choose"}, Pair{Label: "Back", Value: "back"})
+
+	choice, err := ShowCLIMenu(List, items)
+	if err != nil {
+		return 0, "", fmt.Errorf("menu error: %w", err)
+	}
+
+	switch choice {
+	case "", "back":
+		return 0, "", nil
+	case "choose":
+		return showCustomSeasonSelection()
+	}
+
+	var y int
+	var s string
+	if _, err := fmt.Sscanf(choice, "%d %s", &y, &s); err != nil {
+		return 0, "", fmt.Errorf("invalid season: %w", err)
+	}
+	return y, s, nil
+}
+
+// shiftSeason moves offset seasons away from the season at index in year
+func shiftSeason(year, index, offset int) (int, string) {
+	index += offset
+	for index < 0 {
+		index += len(tracker.Seasons)
+		year--
+	}
+	for index >= len(tracker.Seasons) {
+		index -= len(tracker.Seasons)
+		year++
+	}
+	return year, tracker.Seasons[index]
+}
+
+// seasonLabel capitalizes a season name for display
+func seasonLabel(season string) string {
+	if season == "" {
+		return season
+	}
+	return strings.ToUpper(season[:1]) + season[1:]
+}
+
+// showCustomSeasonSelection asks for a year and a season
+func showCustomSeasonSelection() (int, string, error) {
+	input, err := ShowTextInput("Year", UserInput, nil)
+	if errors.Is(err, ErrCancelled) {
+		return 0, "", nil
+	}
+	if err != nil {
+		return 0, "", err
+	}
+
+	year, err := strconv.Atoi(strings.TrimSpace(input))
+	if err != nil || year < 1917 {
+		return 0, "", fmt.Errorf("invalid year %q", input)
+	}
+
+	items := make([]Pair, len(tracker.Seasons))
+	for i, season := range tracker.Seasons {
+		items[i] = Pair{Label: seasonLabel(season), Value: season}
+	}
+
+	season, err := ShowCLIMenu(List, items)
+	if err != nil {
+		return 0, "", fmt.Errorf("menu error: %w", err)
+	}
+
+	return year, season, nil
+}
+
+// FormatProgress shows how far into an anime the user is, as "3/12", or
+// "3/?" when the number of episodes isn't known yet
+func FormatProgress(progress float64, total int) string {
+	if total <= 0 {
+		return fmt.Sprintf("%g/?", progress)
+	}
+	return fmt.Sprintf("%g/%d", progress, total)
+}
+
+// ShowAnimeList displays the user's anime list and returns the selected
+// anime's ID. An empty list returns ErrNoResults.
 func ShowAnimeList(entries []tracker.UserAnimeEntry) (string, error) {
 	if len(entries) == 0 {
-		return "", fmt.Errorf("no entries found")
+		return "", ErrNoResults
 	}
 
 	items := make([]Pair, len(entries))
 	for i, entry := range entries {
 		// Create a display string with title and progress
 		var displayInfo []string
-		displayInfo = append(displayInfo, entry.Title)
+		displayInfo = append(displayInfo, entry.DisplayTitle(titleLanguage))
 
-		if entry.Episodes > 0 {
-			displayInfo = append(displayInfo, fmt.Sprintf("%d/%d", int(entry.Progress), entry.Episodes))
-		} else {
-			displayInfo = append(displayInfo, fmt.Sprintf("Progress: %d", int(entry.Progress)))
-		}
+		displayInfo = append(displayInfo, FormatProgress(entry.Progress, entry.Episodes))
 
 		if entry.Score > 0 {
 			displayInfo = append(displayInfo, fmt.Sprintf("Score: %.1f", entry.Score))
 		}
 
+		if len(entry.Tags) > 0 {
+			displayInfo = append(displayInfo, "Tags: "+strings.Join(entry.Tags, ", "))
+		}
+
 		items[i] = Pair{
 			Label: strings.Join(displayInfo, " - "),
 			Value: entry.ID,
@@ -144,12 +318,32 @@ func ShowMainMenu() (string, error) {
 	return action, nil
 }
 
-// ShowAnimeUpdateMenu displays a menu for updating anime status/progress
+// ShowAnimeUpdateMenu displays the details of an anime followed by a menu
+// for updating its status/progress
 func ShowAnimeUpdateMenu(anime *tracker.AnimeInfo) (string, error) {
+	if anime != nil {
+		fmt.Println(animeDetails(anime))
+	}
+
 	items := []Pair{
+		{Label: "Watch", Value: "watch"},
+		{Label: "Change Source", Value: "source"},
+		{Label: "Set Episode Offset", Value: "offset"},
 		{Label: "Update Status", Value: "status"},
 		{Label: "Update Progress", Value: "progress"},
 		{Label: "Update Score", Value: "score"},
+		{Label: "Mark Completed", Value: "complete"},
+		{Label: "Put On Hold", Value: "hold"},
+		{Label: "Drop", Value: "drop"},
+		{Label: "Edit Notes", Value: "notes"},
+		{Label: "Related Anime", Value: "related"},
+		{Label: "Open in Browser", Value: "browser"},
+		{Label: "Fix Tracker Link", Value: "relink"},
+		{Label: "Set Audio Preference", Value: "audio"},
+		{Label: "Refresh Filler List", Value: "fillers"},
+		{Label: "Fetch Episode List", Value: "episodes"},
+		{Label: "Reset Local Progress", Value: "reset"},
+		{Label: "Remove from Library", Value: "remove"},
 		{Label: "Back", Value: "back"},
 	}
 
@@ -161,30 +355,269 @@ func ShowAnimeUpdateMenu(anime *tracker.AnimeInfo) (string, error) {
 	return action, nil
 }
 
-// ShowEpisodeSelection displays a menu to select episode number
-func ShowEpisodeSelection(totalEpisodes int) (float64, error) {
+// ShowEpisodeSelection displays a menu to select episode number.
+// Episodes flagged in fillers are tagged as such and partially watched
+// episodes show their progress percentage from inProgress. When the number
+// of episodes isn't known the user types the episode number instead. It
+// returns ErrCancelled if that prompt is dismissed.
+func ShowEpisodeSelection(totalEpisodes int, fillers map[float64]bool, inProgress map[float64]float64) (float64, error) {
 	if totalEpisodes <= 0 {
-		totalEpisodes = 100 // Default max if total episodes unknown
+		input, err := ShowTextInput("Episode number", UserInput, nil)
+		if err != nil {
+			return 0, err
+		}
+		return parseEpisodeNumber(input)
 	}
 
+	episodeStr, err := ShowCLIEpisodeMenu(List, episodeSelectionItems(totalEpisodes, fillers, inProgress))
+	if err != nil {
+		return 0, fmt.Errorf("menu error: %w", err)
+	}
+
+	return parseEpisodeNumber(episodeStr)
+}
+
+// episodeSelectionItems builds the menu items for episodes 0 to totalEpisodes
+func episodeSelectionItems(totalEpisodes int, fillers map[float64]bool, inProgress map[float64]float64) []Pair {
 	items := make([]Pair, totalEpisodes+1)
 	for i := 0; i <= totalEpisodes; i++ {
+		label := fmt.Sprintf("Episode %d", i)
+		if fillers[float64(i)] {
+			label += " [Filler]"
+		}
+		label += progressSuffix(inProgress, float64(i))
+
 		items[i] = Pair{
-			Label: fmt.Sprintf("Episode %d", i),
+			Label: label,
 			Value: fmt.Sprintf("%d", i),
 		}
 	}
+	return items
+}
 
-	episodeStr, err := ShowCLIMenu(List, items)
+// parseEpisodeNumber reads an episode number picked or typed by the user
+func parseEpisodeNumber(value string) (float64, error) {
+	episode, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || episode < 0 || math.IsInf(episode, 0) || math.IsNaN(episode) {
+		return 0, fmt.Errorf("invalid episode: %q", value)
+	}
+	return episode, nil
+}
+
+// ShowEpisodeList displays the stored episodes of an anime with their titles
+// and returns the selected episode number. Episodes in fresh are marked as
+// new and thumbnails are shown when showImages is set.
+func ShowEpisodeList(episodes []*database.Episode, inProgress map[float64]float64, fresh map[float64]bool, showImages bool) (float64, error) {
+	if len(episodes) == 0 {
+		return 0, fmt.Errorf("no episodes available")
+	}
+
+	menuType := List
+	if showImages {
+		menuType = ListWithImage
+	}
+
+	episodeStr, err := OpenEpisodeMenu(menuType, episodeItems(episodes, inProgress, fresh, showImages))
 	if err != nil {
 		return 0, fmt.Errorf("menu error: %w", err)
 	}
 
-	var episode float64
-	_, err = fmt.Sscanf(episodeStr, "%f", &episode)
+	episode, err := strconv.ParseFloat(episodeStr, 64)
 	if err != nil {
 		return 0, fmt.Errorf("invalid episode: %w", err)
 	}
 
 	return episode, nil
 }
+
+// ShowSourceSelection displays the given sources with their language and returns
+// the source ID of the selected one, or "" if the menu was dismissed
+func ShowSourceSelection(sources []*database.Source) (string, error) {
+	if len(sources) == 0 {
+		return "", fmt.Errorf("no sources available")
+	}
+
+	items := make([]Pair, len(sources))
+	for i, source := range sources {
+		items[i] = Pair{
+			Label: fmt.Sprintf("%s [%s]", source.Name, source.Language),
+			Value: source.SourceID,
+		}
+	}
+
+	sourceID, err := OpenMenu(List, items)
+	if err != nil {
+		return "", fmt.Errorf("menu error: %w", err)
+	}
+
+	return sourceID, nil
+}
+
+// Choices returned by ShowSourceSearchSelection besides a source ID
+const (
+	AllSources        = "all_sources"
+	AllLanguages      = "all_languages"
+	PreferredLanguage = "preferred_language"
+)
+
+// ShowSourceSearchSelection lets the user pick a source to search, or all of
+// them at once. Sources not in searchable can't search and are greyed out.
+// filtered says whether the sources are limited to the preferred language,
+// and offers to switch between that and all languages.
+func ShowSourceSearchSelection(sources []*database.Source, searchable map[string]bool, filtered bool) (string, error) {
+	choice, err := OpenMenu(List, sourceSearchItems(sources, searchable, filtered))
+	if err != nil {
+		return "", fmt.Errorf("menu error: %w", err)
+	}
+
+	return choice, nil
+}
+
+// sourceSearchItems builds the menu items of ShowSourceSearchSelection
+func sourceSearchItems(sources []*database.Source, searchable map[string]bool, filtered bool) []Pair {
+	var items []Pair
+	count := 0
+	for _, source := range sources {
+		if searchable[source.SourceID] {
+			count++
+		}
+	}
+	if count > 1 {
+		items = append(items, Pair{Label: "Search all sources", Value: AllSources})
+	}
+	for _, source := range sources {
+		item := Pair{
+			Label: fmt.Sprintf("%s [%s]", source.Name, source.Language),
+			Value: source.SourceID,
+		}
+		if !searchable[source.SourceID] {
+			item.Label += " (no search)"
+			item.Disabled = true
+		}
+		items = append(items, item)
+	}
+	if filtered {
+		items = append(items, Pair{Label: "Show sources of all languages", Value: AllLanguages})
+	} else {
+		items = append(items, Pair{Label: "Only show sources in my language", Value: PreferredLanguage})
+	}
+	return items
+}
+
+// episodeItems builds the menu items for stored episodes, marking fillers,
+// new episodes and resume progress
+func episodeItems(episodes []*database.Episode, inProgress map[float64]float64, fresh map[float64]bool, showImages bool) []Pair {
+	items := make([]Pair, len(episodes))
+	for i, episode := range episodes {
+		label := fmt.Sprintf("Ep%g", episode.Number)
+		if episode.Title != "" {
+			label += " — " + episode.Title
+		}
+		if episode.IsFiller {
+			label += " [Filler]"
+		}
+		if fresh[episode.Number] {
+			label += " [New]"
+		}
+		label += progressSuffix(inProgress, episode.Number)
+
+		items[i] = Pair{
+			Label: label,
+			Value: strconv.FormatFloat(episode.Number, 'f', -1, 64),
+		}
+		if showImages {
+			items[i].Image = episode.ThumbnailURL
+		}
+	}
+	return items
+}
+
+// progressSuffix returns the resume marker for a partially watched episode
+func progressSuffix(inProgress map[float64]float64, number float64) string {
+	percent, ok := inProgress[number]
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf(" ▶ %.0f%%", percent)
+}
+
+// ShowAiringSchedule displays the next episode of each airing anime with a
+// countdown to its release
+func ShowAiringSchedule(schedule []tracker.AiringEntry, now time.Time) error {
+	if _, err := OpenMenu(List, airingItems(schedule, now)); err != nil {
+		return fmt.Errorf("menu error: %w", err)
+	}
+	return nil
+}
+
+// airingItems builds the schedule lines in the given order with a back option
+func airingItems(schedule []tracker.AiringEntry, now time.Time) []Pair {
+	items := make([]Pair, 0, len(schedule)+1)
+	for _, entry := range schedule {
+		items = append(items, Pair{
+			Label: fmt.Sprintf("%s - Ep %d %s", entry.Title, entry.Episode, countdown(entry.AiringAt.Sub(now))),
+			Value: entry.AnimeID,
+		})
+	}
+	items = append(items, Pair{Label: "Back", Value: "back"})
+	return items
+}
+
+// countdown describes how long until an episode airs, like "in 2d 4h"
+func countdown(d time.Duration) string {
+	if d <= 0 {
+		return "airing now"
+	}
+
+	days := int(d / (24 * time.Hour))
+	hours := int(d % (24 * time.Hour) / time.Hour)
+	minutes := int(d % time.Hour / time.Minute)
+	switch {
+	case days > 0:
+		return fmt.Sprintf("in %dd %dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("in %dh %dm", hours, minutes)
+	default:
+		return fmt.Sprintf("in %dm", minutes)
+	}
+}
+
+// ShowRelatedAnime displays related anime ordered by relation type and returns the selected anime's ID
+func ShowRelatedAnime(relations []tracker.RelatedAnime) (string, error) {
+	if len(relations) == 0 {
+		return "", fmt.Errorf("no related anime found")
+	}
+
+	selectedID, err := ShowCLIMenu(List, relatedAnimeItems(relations))
+	if err != nil {
+		return "", fmt.Errorf("menu error: %w", err)
+	}
+
+	return selectedID, nil
+}
+
+// relatedAnimeItems builds the menu items for related anime, sequels first
+func relatedAnimeItems(relations []tracker.RelatedAnime) []Pair {
+	sorted := make([]tracker.RelatedAnime, len(relations))
+	copy(sorted, relations)
+	tracker.SortRelations(sorted)
+
+	items := make([]Pair, 0, len(sorted)+1)
+	for _, related := range sorted {
+		displayInfo := []string{fmt.Sprintf("[%s] %s", related.RelationType, related.DisplayTitle(titleLanguage))}
+		if related.Type != "" {
+			displayInfo = append(displayInfo, related.Type)
+		}
+		if related.Episodes > 0 {
+			displayInfo = append(displayInfo, fmt.Sprintf("%d eps", related.Episodes))
+		}
+
+		items = append(items, Pair{
+			Label: strings.Join(displayInfo, " - "),
+			Value: related.ID,
+		})
+	}
+
+	items = append(items, Pair{Label: "Back", Value: "back"})
+	return items
+}
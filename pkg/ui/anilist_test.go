@@ -0,0 +1,231 @@
+package ui
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/wraient/pair/pkg/database"
+	"github.com/wraient/pair/pkg/tracker"
+)
+
+func TestRelatedAnimeItems(t *testing.T) {
+	relations := []tracker.RelatedAnime{
+		{AnimeInfo: tracker.AnimeInfo{ID: "3", Title: "Side Story"}, RelationType: "SIDE_STORY"},
+		{AnimeInfo: tracker.AnimeInfo{ID: "1", Title: "Season 0"}, RelationType: "PREQUEL"},
+		{AnimeInfo: tracker.AnimeInfo{ID: "2", Title: "Season 2", Type: "TV", Episodes: 12}, RelationType: "SEQUEL"},
+	}
+
+	items := relatedAnimeItems(relations)
+
+	// Test that results are ordered by relation type with a back option
+	expectedIDs := []string{"2", "1", "3", "back"}
+	if len(items) != len(expectedIDs) {
+		t.Fatalf("Expected %d items, got %d", len(expectedIDs), len(items))
+	}
+	for i, id := range expectedIDs {
+		if items[i].Value != id {
+			t.Errorf("Expected item %d to have value '%s', got '%s'", i, id, items[i].Value)
+		}
+	}
+
+	// Test that labels show the relation type and details
+	if items[0].Label != "[SEQUEL] Season 2 - TV - 12 eps" {
+		t.Errorf("Expected sequel label, got '%s'", items[0].Label)
+	}
+	if !strings.HasPrefix(items[1].Label, "[PREQUEL]") {
+		t.Errorf("Expected prequel label, got '%s'", items[1].Label)
+	}
+
+	// Test that the input order is left untouched
+	if relations[0].ID != "3" {
+		t.Errorf("Expected relations not to be reordered in place")
+	}
+}
+
+func TestRelatedAnimeSelectable(t *testing.T) {
+	relations := []tracker.RelatedAnime{
+		{AnimeInfo: tracker.AnimeInfo{ID: "2", Title: "Season 2"}, RelationType: "SEQUEL"},
+	}
+	m := model{items: relatedAnimeItems(relations)}
+	m.filterItems()
+
+	// Select the first entry like a user pressing enter
+	updated, _ := m.Update(runes("2"))
+	updated, _ = updated.Update(keyEnter())
+	if updated.(model).selected != "2" {
+		t.Errorf("Expected selected value '2', got '%s'", updated.(model).selected)
+	}
+}
+
+func TestEpisodeItems(t *testing.T) {
+	episodes := []*database.Episode{
+		{Number: 1, Title: "The Journey Begins", ThumbnailURL: "/tmp/ep1.jpg"},
+		{Number: 2, IsFiller: true},
+		{Number: 2.5, Title: "Recap", IsFiller: true},
+	}
+	inProgress := map[float64]float64{2.5: 35.4}
+
+	fresh := map[float64]bool{2: true}
+
+	items := episodeItems(episodes, inProgress, fresh, true)
+	if len(items) != 3 {
+		t.Fatalf("Expected 3 items, got %d", len(items))
+	}
+
+	// Test that titles, filler flags, new episodes and resume progress are rendered when present
+	expected := []Pair{
+		{Label: "Ep1 — The Journey Begins", Value: "1", Image: "/tmp/ep1.jpg"},
+		{Label: "Ep2 [Filler] [New]", Value: "2"},
+		{Label: "Ep2.5 — Recap [Filler] ▶ 35%", Value: "2.5"},
+	}
+	for i, want := range expected {
+		if items[i] != want {
+			t.Errorf("Expected item %+v, got %+v", want, items[i])
+		}
+	}
+
+	// Test that thumbnails are left out when previews are off
+	if items := episodeItems(episodes, nil, nil, false); items[0].Image != "" {
+		t.Errorf("Expected no image without previews, got '%s'", items[0].Image)
+	}
+}
+
+func TestParseScore(t *testing.T) {
+	// Test that decimals are accepted where the format allows them
+	if score, err := parseScore(" 8.5 ", tracker.ScorePoint10Decimal); err != nil || score != 8.5 {
+		t.Errorf("Expected score 8.5, got %v (%v)", score, err)
+	}
+
+	// Test that out-of-range, wrong-precision and non-numeric input is rejected
+	invalid := []struct {
+		input  string
+		format tracker.ScoreFormat
+	}{
+		{"8.55", tracker.ScorePoint10Decimal},
+		{"11", tracker.ScorePoint10Decimal},
+		{"85.5", tracker.ScorePoint100},
+		{"120", tracker.ScorePoint100},
+		{"great", tracker.ScorePoint100},
+	}
+	for _, tt := range invalid {
+		if _, err := parseScore(tt.input, tt.format); !errors.Is(err, tracker.ErrInvalidScore) {
+			t.Errorf("Expected %q to be rejected for %s, got %v", tt.input, tt.format, err)
+		}
+	}
+}
+
+func TestScoreItems(t *testing.T) {
+	// Test that integer formats keep the quick-pick list
+	items := scoreItems(tracker.ScorePoint10)
+	if len(items) != 11 || items[0].Label != "10 - Masterpiece" || items[10].Value != "0" {
+		t.Errorf("Unexpected 10-point items: %+v", items)
+	}
+
+	items = scoreItems(tracker.ScorePoint5)
+	if len(items) != 6 || items[0].Label != "5" || items[5].Label != "0 - No Score" {
+		t.Errorf("Unexpected 5-point items: %+v", items)
+	}
+}
+
+func TestSourceSearchItems(t *testing.T) {
+	sources := []*database.Source{
+		{SourceID: "1", Name: "Searchable", Language: "en"},
+		{SourceID: "2", Name: "Browse Only", Language: "en"},
+	}
+
+	// Test that sources without search are greyed out and not searched together
+	items := sourceSearchItems(sources, map[string]bool{"1": true}, false)
+	if len(items) != 3 {
+		t.Fatalf("Expected two sources and a language toggle, got %+v", items)
+	}
+	if items[0].Value != "1" || items[0].Disabled {
+		t.Errorf("Expected the searchable source first, got %+v", items[0])
+	}
+	if items[1].Value != "2" || !items[1].Disabled || !strings.Contains(items[1].Label, "(no search)") {
+		t.Errorf("Expected the browse-only source greyed out, got %+v", items[1])
+	}
+
+	// Test that several searchable sources can be searched at once
+	items = sourceSearchItems(sources, map[string]bool{"1": true, "2": true}, false)
+	if items[0].Value != AllSources {
+		t.Errorf("Expected to search all sources first, got %+v", items[0])
+	}
+}
+
+func TestFormatProgress(t *testing.T) {
+	tests := []struct {
+		progress float64
+		total    int
+		want     string
+	}{
+		{3, 12, "3/12"},
+		{0, 24, "0/24"},
+		{5, 0, "5/?"},
+		{7, -1, "7/?"},
+		{12.5, 0, "12.5/?"},
+	}
+
+	// Test that an unknown total is shown as a question mark
+	for _, tt := range tests {
+		if got := FormatProgress(tt.progress, tt.total); got != tt.want {
+			t.Errorf("Expected FormatProgress(%v, %d) to be '%s', got '%s'", tt.progress, tt.total, tt.want, got)
+		}
+	}
+}
+
+func TestParseEpisodeNumber(t *testing.T) {
+	// Test that typed episode numbers are accepted past any fixed limit
+	for input, want := range map[string]float64{"0": 0, "7": 7, " 250 ": 250, "12.5": 12.5} {
+		got, err := parseEpisodeNumber(input)
+		if err != nil {
+			t.Fatalf("Failed to parse episode %q: %v", input, err)
+		}
+		if got != want {
+			t.Errorf("Expected episode %v for %q, got %v", want, input, got)
+		}
+	}
+
+	// Test that anything that isn't an episode number is rejected
+	for _, input := range []string{"", "abc", "-1", "NaN", "Inf"} {
+		if _, err := parseEpisodeNumber(input); err == nil {
+			t.Errorf("Expected an error for %q", input)
+		}
+	}
+}
+
+func TestEpisodeSelectionItems(t *testing.T) {
+	items := episodeSelectionItems(3, map[float64]bool{2: true}, nil)
+
+	// Test that a known total lists every episode from 0
+	if len(items) != 4 {
+		t.Fatalf("Expected 4 items, got %d", len(items))
+	}
+	if items[3].Value != "3" {
+		t.Errorf("Expected last item to be episode 3, got '%s'", items[3].Value)
+	}
+	if items[2].Label != "Episode 2 [Filler]" {
+		t.Errorf("Expected filler label, got '%s'", items[2].Label)
+	}
+}
+
+func TestEmptyListsReturnNoResults(t *testing.T) {
+	// Test that empty inputs report ErrNoResults without showing a menu
+	for _, more := range []bool{false, true} {
+		id, err := ShowAnimeSearchResults(nil, more)
+		if !errors.Is(err, ErrNoResults) {
+			t.Errorf("Expected ErrNoResults for empty search results, got %v", err)
+		}
+		if id != "" {
+			t.Errorf("Expected no selection, got %q", id)
+		}
+	}
+
+	id, err := ShowAnimeList([]tracker.UserAnimeEntry{})
+	if !errors.Is(err, ErrNoResults) {
+		t.Errorf("Expected ErrNoResults for an empty list, got %v", err)
+	}
+	if id != "" {
+		t.Errorf("Expected no selection, got %q", id)
+	}
+}
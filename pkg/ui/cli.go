@@ -2,10 +2,12 @@ package ui
 
 import (
 	"errors"
+	"strconv"
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/wraient/pair/pkg/config"
 )
 
 var (
@@ -29,6 +31,9 @@ var (
 				Background(lipgloss.Color("#304878")).
 				Foreground(lipgloss.Color("#FFFFFF"))
 
+	disabledItemStyle = baseStyle.Copy().
+				Foreground(lipgloss.Color("#666666"))
+
 	// Footer style
 	footerStyle = baseStyle.Copy().
 			Foreground(lipgloss.Color("#666666")).
@@ -39,12 +44,33 @@ var (
 			Foreground(lipgloss.Color("#304878"))
 )
 
+// maxVisibleItems is how many list items are rendered around the cursor
+const maxVisibleItems = 20
+
 type model struct {
 	items    []Pair
 	cursor   int
 	selected string
 	search   string
 	filtered []Pair
+	// numeric is set for lists of numbered items, like episode pickers.
+	// Typing a number then jumps to that item instead of filtering labels,
+	// and jumpKey switches between jumping and filtering.
+	numeric  bool
+	jumpMode bool
+	jumpKey  string
+}
+
+// newModel creates a list model. numeric turns on jumping to typed numbers
+// and should only be set when every item value is a number.
+func newModel(items []Pair, numeric bool, jumpKey string) model {
+	return model{
+		items:    items,
+		filtered: items,
+		numeric:  numeric,
+		jumpMode: numeric,
+		jumpKey:  jumpKey,
+	}
 }
 
 func (m model) Init() tea.Cmd {
@@ -54,11 +80,17 @@ func (m model) Init() tea.Cmd {
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if m.numeric && m.jumpKey != "" && msg.String() == m.jumpKey {
+			m.jumpMode = !m.jumpMode
+			m.applySearch()
+			return m, nil
+		}
+
 		switch msg.String() {
 		case "ctrl+c", "esc":
 			return m, tea.Quit
 		case "enter":
-			if len(m.filtered) > 0 {
+			if len(m.filtered) > 0 && !m.filtered[m.cursor].Disabled {
 				m.selected = m.filtered[m.cursor].Value
 				return m, tea.Quit
 			}
@@ -74,14 +106,12 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "backspace":
 			if len(m.search) > 0 {
 				m.search = m.search[:len(m.search)-1]
-				m.filterItems()
-				m.cursor = 0
+				m.applySearch()
 			}
 		default:
 			if len(msg.String()) == 1 {
 				m.search += msg.String()
-				m.filterItems()
-				m.cursor = 0
+				m.applySearch()
 			}
 		}
 	}
@@ -89,6 +119,28 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// applySearch updates the list for the current search, either jumping to the
+// typed item number or filtering labels
+func (m *model) applySearch() {
+	target, err := strconv.ParseFloat(m.search, 64)
+	if !m.jumpMode || err != nil {
+		m.filterItems()
+		m.cursor = 0
+		return
+	}
+
+	// Land on the typed number, or the first item after it
+	m.filtered = m.items
+	m.cursor = len(m.items) - 1
+	for i, item := range m.items {
+		value, _ := strconv.ParseFloat(item.Value, 64)
+		if value >= target {
+			m.cursor = i
+			break
+		}
+	}
+}
+
 func (m *model) filterItems() {
 	if m.search == "" {
 		m.filtered = m.items
@@ -108,11 +160,15 @@ func (m model) View() string {
 	var s strings.Builder
 
 	// Header section with consistent spacing
+	searchLabel := "Search: "
+	if m.jumpMode {
+		searchLabel = "Jump to: "
+	}
 	header := lipgloss.JoinHorizontal(
 		lipgloss.Left,
 		headerStyle.Render("Select an item"),
 		"    ", // spacing between elements
-		searchStyle.Render("Search: "+m.search),
+		searchStyle.Render(searchLabel+m.search),
 	)
 	s.WriteString(header + "\n\n")
 
@@ -123,9 +179,13 @@ func (m model) View() string {
 	if len(m.filtered) == 0 {
 		s.WriteString(baseStyle.Render("No matches found"))
 	} else {
-		for i, item := range m.filtered {
+		start, end := m.visibleRange()
+		for i := start; i < end; i++ {
+			item := m.filtered[i]
 			if i == m.cursor {
 				s.WriteString(selectedItemStyle.Render(item.Label))
+			} else if item.Disabled {
+				s.WriteString(disabledItemStyle.Render(item.Label))
 			} else {
 				s.WriteString(normalItemStyle.Render(item.Label))
 			}
@@ -135,23 +195,58 @@ func (m model) View() string {
 
 	// Footer
 	s.WriteString("\n")
-	s.WriteString(footerStyle.Render("↑/↓ navigate • enter select • esc quit"))
+	footer := "↑/↓ navigate • enter select • esc quit"
+	if m.numeric && m.jumpKey != "" {
+		if m.jumpMode {
+			footer += " • " + m.jumpKey + " filter titles"
+		} else {
+			footer += " • " + m.jumpKey + " jump to number"
+		}
+	}
+	s.WriteString(footerStyle.Render(footer))
 
 	return s.String()
 }
 
+// visibleRange returns the window of filtered items to render, keeping the
+// cursor in view on long lists
+func (m model) visibleRange() (int, int) {
+	if len(m.filtered) <= maxVisibleItems {
+		return 0, len(m.filtered)
+	}
+
+	start := m.cursor - maxVisibleItems/2
+	if start < 0 {
+		start = 0
+	}
+	if start > len(m.filtered)-maxVisibleItems {
+		start = len(m.filtered) - maxVisibleItems
+	}
+	return start, start + maxVisibleItems
+}
+
 // ShowCLIMenu displays a CLI menu using Bubble Tea and returns the selected value
 func ShowCLIMenu(menuType MenuType, items []Pair) (string, error) {
+	return showCLIMenu(menuType, items, false)
+}
+
+// ShowCLIEpisodeMenu is ShowCLIMenu for lists whose values are episode
+// numbers, where typing a number jumps to that episode
+func ShowCLIEpisodeMenu(menuType MenuType, items []Pair) (string, error) {
+	return showCLIMenu(menuType, items, true)
+}
+
+// showCLIMenu displays a CLI menu, with number jumps when numeric is set
+func showCLIMenu(menuType MenuType, items []Pair, numeric bool) (string, error) {
 	var err error
+	if menuType == UserInput || menuType == UserInputWithDetails {
+		return ShowCLIInput("", menuType, items)
+	}
 	if len(items) == 0 {
 		return "", errors.New("no items to show")
 	}
 
-	initialModel := model{
-		items:    items,
-		filtered: items,
-		search:   "",
-	}
+	initialModel := newModel(items, numeric, config.Get().Keybindings.Episode.Jump)
 
 	p := tea.NewProgram(initialModel)
 	m, err := p.Run()
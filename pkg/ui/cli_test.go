@@ -0,0 +1,99 @@
+package ui
+
+import (
+	"strconv"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// numberedItems builds an episode-like list from 1 to n
+func numberedItems(n int) []Pair {
+	items := make([]Pair, n)
+	for i := range items {
+		value := strconv.Itoa(i + 1)
+		items[i] = Pair{Label: "Episode " + value, Value: value}
+	}
+	return items
+}
+
+func TestModelJumpToEpisode(t *testing.T) {
+	m := newModel(numberedItems(1100), true, "ctrl+g")
+	if !m.numeric || !m.jumpMode {
+		t.Fatalf("Expected a numeric list to start in jump mode")
+	}
+
+	var updated tea.Model = m
+	for _, key := range "1045" {
+		updated, _ = updated.Update(runes(string(key)))
+	}
+
+	// Test that the cursor lands on the typed episode with the full list kept
+	got := updated.(model)
+	if len(got.filtered) != 1100 {
+		t.Errorf("Expected the full list while jumping, got %d items", len(got.filtered))
+	}
+	if got.filtered[got.cursor].Value != "1045" {
+		t.Errorf("Expected cursor on episode 1045, got '%s'", got.filtered[got.cursor].Value)
+	}
+
+	// Test that the jumped-to episode is rendered
+	start, end := got.visibleRange()
+	if got.cursor < start || got.cursor >= end {
+		t.Errorf("Expected cursor %d within visible range %d-%d", got.cursor, start, end)
+	}
+
+	updated, _ = updated.Update(keyEnter())
+	if updated.(model).selected != "1045" {
+		t.Errorf("Expected selected value '1045', got '%s'", updated.(model).selected)
+	}
+}
+
+func TestModelJumpKeyFiltersLabels(t *testing.T) {
+	var updated tea.Model = newModel(numberedItems(30), true, "ctrl+g")
+
+	// Test that the jump key switches back to filtering labels
+	updated, _ = updated.Update(tea.KeyMsg{Type: tea.KeyCtrlG})
+	updated, _ = updated.Update(runes("2"))
+	updated, _ = updated.Update(runes("1"))
+
+	got := updated.(model)
+	if got.jumpMode {
+		t.Fatalf("Expected jump mode to be off")
+	}
+	if len(got.filtered) != 1 || got.filtered[0].Value != "21" {
+		t.Errorf("Expected only episode 21 to match, got %+v", got.filtered)
+	}
+}
+
+func TestModelNonNumericList(t *testing.T) {
+	m := newModel([]Pair{{Label: "Search", Value: "search"}, {Label: "Episode 2", Value: "2"}}, false, "ctrl+g")
+
+	// Test that lists with non-numeric values keep filtering by label
+	if m.numeric || m.jumpMode {
+		t.Errorf("Expected a non-numeric list to filter by label")
+	}
+
+	// Test that other menus with numeric values, like search results, filter too
+	var updated tea.Model = newModel(numberedItems(30), false, "ctrl+g")
+	updated, _ = updated.Update(runes("2"))
+	updated, _ = updated.Update(runes("1"))
+	if got := updated.(model); got.jumpMode || len(got.filtered) != 1 || got.filtered[0].Value != "21" {
+		t.Errorf("Expected only item 21 to match, got %+v", got.filtered)
+	}
+}
+
+func TestModelDisabledItem(t *testing.T) {
+	var updated tea.Model = newModel([]Pair{{Label: "Unavailable", Value: "off", Disabled: true}, {Label: "Available", Value: "on"}}, false, "")
+
+	// Test that a disabled item can't be picked
+	updated, _ = updated.Update(keyEnter())
+	if updated.(model).selected != "" {
+		t.Errorf("Expected nothing selected, got '%s'", updated.(model).selected)
+	}
+	updated, _ = updated.Update(tea.KeyMsg{Type: tea.KeyDown})
+	updated, _ = updated.Update(keyEnter())
+	if updated.(model).selected != "on" {
+		t.Errorf("Expected 'on' selected, got '%s'", updated.(model).selected)
+	}
+}
@@ -0,0 +1,162 @@
+package ui
+
+import (
+	"errors"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// ErrCancelled is returned when the user cancels a text input
+var ErrCancelled = errors.New("input cancelled")
+
+// inputModel is a single-line text field used for the UserInput menu types
+type inputModel struct {
+	prompt      string
+	value       string
+	suggestions []Pair
+	filtered    []Pair
+	details     bool
+	cursor      int // -1 when no suggestion is highlighted
+	submitted   bool
+	cancelled   bool
+	result      string
+}
+
+func newInputModel(prompt string, menuType MenuType, suggestions []Pair) inputModel {
+	if prompt == "" {
+		prompt = "Input"
+	}
+
+	m := inputModel{
+		prompt:      prompt,
+		suggestions: suggestions,
+		details:     menuType == UserInputWithDetails,
+		cursor:      -1,
+	}
+	m.filterSuggestions()
+	return m
+}
+
+func (m inputModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m inputModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	switch keyMsg.Type {
+	case tea.KeyCtrlC, tea.KeyEsc:
+		m.cancelled = true
+		return m, tea.Quit
+	case tea.KeyEnter:
+		m.submitted = true
+		if m.cursor >= 0 && m.cursor < len(m.filtered) {
+			m.result = m.filtered[m.cursor].Value
+		} else {
+			m.result = m.value
+		}
+		return m, tea.Quit
+	case tea.KeyTab:
+		if m.cursor >= 0 && m.cursor < len(m.filtered) {
+			m.value = m.filtered[m.cursor].Value
+			m.filterSuggestions()
+		}
+	case tea.KeyUp:
+		if m.cursor >= 0 {
+			m.cursor--
+		}
+	case tea.KeyDown:
+		if m.cursor < len(m.filtered)-1 {
+			m.cursor++
+		}
+	case tea.KeyBackspace:
+		if len(m.value) > 0 {
+			runes := []rune(m.value)
+			m.value = string(runes[:len(runes)-1])
+			m.filterSuggestions()
+		}
+	case tea.KeySpace:
+		m.value += " "
+		m.filterSuggestions()
+	case tea.KeyRunes:
+		m.value += string(keyMsg.Runes)
+		m.filterSuggestions()
+	}
+
+	return m, nil
+}
+
+// filterSuggestions narrows the suggestions down to those matching the typed text
+func (m *inputModel) filterSuggestions() {
+	m.cursor = -1
+	if m.value == "" {
+		m.filtered = m.suggestions
+		return
+	}
+
+	m.filtered = []Pair{}
+	valueLower := strings.ToLower(m.value)
+	for _, item := range m.suggestions {
+		if strings.Contains(strings.ToLower(m.suggestionText(item)), valueLower) {
+			m.filtered = append(m.filtered, item)
+		}
+	}
+}
+
+// suggestionText returns the text shown for a suggestion
+func (m inputModel) suggestionText(item Pair) string {
+	if m.details && item.Label != "" {
+		return item.Label
+	}
+	return item.Value
+}
+
+func (m inputModel) View() string {
+	var s strings.Builder
+
+	header := lipgloss.JoinHorizontal(
+		lipgloss.Left,
+		headerStyle.Render(m.prompt),
+		"    ",
+		searchStyle.Render("> "+m.value+"█"),
+	)
+	s.WriteString(header + "\n\n")
+
+	if len(m.filtered) > 0 {
+		s.WriteString(dividerStyle.Render(strings.Repeat("─", 50)) + "\n")
+		for i, item := range m.filtered {
+			if i == m.cursor {
+				s.WriteString(selectedItemStyle.Render(m.suggestionText(item)))
+			} else {
+				s.WriteString(normalItemStyle.Render(m.suggestionText(item)))
+			}
+			s.WriteString("\n")
+		}
+		s.WriteString("\n")
+	}
+
+	s.WriteString(footerStyle.Render("type to enter text • ↑/↓ suggestions • tab complete • enter submit • esc cancel"))
+
+	return s.String()
+}
+
+// ShowCLIInput displays a text field using Bubble Tea and returns the entered text.
+// It returns ErrCancelled if the user pressed escape.
+func ShowCLIInput(prompt string, menuType MenuType, suggestions []Pair) (string, error) {
+	p := tea.NewProgram(newInputModel(prompt, menuType, suggestions))
+	m, err := p.Run()
+	if err != nil {
+		return "", err
+	}
+
+	if m.(inputModel).cancelled {
+		return "", ErrCancelled
+	}
+
+	return m.(inputModel).result, nil
+}
@@ -0,0 +1,94 @@
+package ui
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// typeKeys feeds the given key messages through the model
+func typeKeys(m inputModel, keys ...tea.KeyMsg) inputModel {
+	for _, key := range keys {
+		updated, _ := m.Update(key)
+		m = updated.(inputModel)
+	}
+	return m
+}
+
+func runes(s string) tea.KeyMsg {
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+}
+
+func TestInputModelTyping(t *testing.T) {
+	m := newInputModel("Search", UserInput, nil)
+
+	// Test typing text with a space and a correction
+	m = typeKeys(m,
+		runes("one"),
+		tea.KeyMsg{Type: tea.KeySpace},
+		runes("pieca"),
+		tea.KeyMsg{Type: tea.KeyBackspace},
+		runes("e"),
+		tea.KeyMsg{Type: tea.KeyEnter},
+	)
+
+	if !m.submitted {
+		t.Errorf("Expected input to be submitted")
+	}
+	if m.result != "one piece" {
+		t.Errorf("Expected result 'one piece', got '%s'", m.result)
+	}
+}
+
+func TestInputModelCancel(t *testing.T) {
+	m := newInputModel("Search", UserInput, nil)
+
+	m = typeKeys(m, runes("naruto"), tea.KeyMsg{Type: tea.KeyEsc})
+
+	if !m.cancelled {
+		t.Errorf("Expected input to be cancelled")
+	}
+	if m.result != "" {
+		t.Errorf("Expected empty result after cancel, got '%s'", m.result)
+	}
+}
+
+func TestInputModelSuggestions(t *testing.T) {
+	suggestions := []Pair{
+		{Label: "Naruto - 220 eps", Value: "naruto"},
+		{Label: "Bleach - 366 eps", Value: "bleach"},
+	}
+	m := newInputModel("Search", UserInputWithDetails, suggestions)
+
+	// Test that typing filters the suggestions by their details
+	m = typeKeys(m, runes("366"))
+	if len(m.filtered) != 1 || m.filtered[0].Value != "bleach" {
+		t.Fatalf("Expected only 'bleach' to match, got %+v", m.filtered)
+	}
+
+	// Test picking a highlighted suggestion
+	m = typeKeys(m, tea.KeyMsg{Type: tea.KeyDown}, tea.KeyMsg{Type: tea.KeyEnter})
+	if m.result != "bleach" {
+		t.Errorf("Expected result 'bleach', got '%s'", m.result)
+	}
+}
+
+func TestInputModelTabCompletion(t *testing.T) {
+	suggestions := []Pair{{Label: "Recent", Value: "frieren"}}
+	m := newInputModel("Search", UserInput, suggestions)
+
+	m = typeKeys(m, runes("fri"), tea.KeyMsg{Type: tea.KeyDown}, tea.KeyMsg{Type: tea.KeyTab})
+	if m.value != "frieren" {
+		t.Errorf("Expected completed value 'frieren', got '%s'", m.value)
+	}
+
+	// Completion leaves the field editable before submitting
+	m = typeKeys(m, runes(" 2"), tea.KeyMsg{Type: tea.KeyEnter})
+	if m.result != "frieren 2" {
+		t.Errorf("Expected result 'frieren 2', got '%s'", m.result)
+	}
+}
+
+func keyEnter() tea.KeyMsg {
+	return tea.KeyMsg{Type: tea.KeyEnter}
+}
@@ -9,6 +9,10 @@ import (
 type Pair struct {
 	Label string
 	Value string
+	// Image is an optional thumbnail shown by ListWithImage menus
+	Image string
+	// Disabled items are shown greyed out and can't be picked
+	Disabled bool
 }
 
 // Define a custom type for menu kinds
@@ -40,3 +44,47 @@ func OpenMenu(menuType MenuType, items []Pair) (string, error) {
 
 	return output, err
 }
+
+// OpenEpisodeMenu is OpenMenu for lists whose values are episode numbers.
+// In the CLI typing a number jumps to that episode instead of filtering.
+func OpenEpisodeMenu(menuType MenuType, items []Pair) (string, error) {
+	conf := config.Get()
+
+	switch conf.UI.Mode {
+	case config.UIModeRofi:
+		return ShowRofiMenu(menuType, items)
+	case config.UIModeCLI:
+		return ShowCLIEpisodeMenu(menuType, items)
+	default:
+		return "", errors.New("unknown UI mode")
+	}
+}
+
+// ShowTextInput asks the user for free text, offering the given items as suggestions
+func ShowTextInput(prompt string, menuType MenuType, suggestions []Pair) (string, error) {
+	conf := config.Get()
+
+	switch conf.UI.Mode {
+	case config.UIModeRofi:
+		return ShowRofiInput(prompt, suggestions)
+	case config.UIModeCLI:
+		return ShowCLIInput(prompt, menuType, suggestions)
+	default:
+		return "", errors.New("unknown UI mode")
+	}
+}
+
+// ShowConfirm asks the user to confirm an action described by prompt.
+// Cancel comes first so that accepting the default is safe.
+func ShowConfirm(prompt string) (bool, error) {
+	items := []Pair{
+		{Label: "Cancel", Value: "no"},
+		{Label: prompt, Value: "yes"},
+	}
+
+	choice, err := OpenMenu(List, items)
+	if err != nil {
+		return false, err
+	}
+	return choice == "yes", nil
+}
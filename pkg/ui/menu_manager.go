@@ -103,6 +103,10 @@ func (mm *MenuManager) Show(menu *Menu) error {
 	if err != nil {
 		return fmt.Errorf("failed to show menu: %w", err)
 	}
+	// Closing the menu isn't an error, it's how the user quits
+	if selected == "" {
+		return nil
+	}
 
 	// Find selected item
 	var selectedItem *MenuItem
@@ -1,5 +1,95 @@
 package ui
 
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// ShowRofiMenu displays a rofi menu and returns the selected value
 func ShowRofiMenu(menuType MenuType, items []Pair) (string, error) {
-	return "", nil
-}
\ No newline at end of file
+	if menuType == UserInput || menuType == UserInputWithDetails {
+		return ShowRofiInput("", items)
+	}
+	if len(items) == 0 {
+		return "", errors.New("no items to show")
+	}
+
+	args := []string{"-dmenu", "-i", "-format", "i", "-p", "Select"}
+	if menuType == ListWithImage {
+		args = append(args, "-show-icons")
+	}
+
+	output, err := runRofi(args, items)
+	if errors.Is(err, ErrCancelled) {
+		return "", nil
+	}
+	if err != nil || output == "" {
+		return "", err
+	}
+
+	index, err := strconv.Atoi(output)
+	if err != nil || index < 0 || index >= len(items) {
+		return "", fmt.Errorf("invalid rofi selection: %q", output)
+	}
+
+	return items[index].Value, nil
+}
+
+// ShowRofiInput displays a rofi prompt that accepts arbitrary input, offering the items as suggestions.
+// It returns ErrCancelled if the prompt was dismissed.
+func ShowRofiInput(prompt string, suggestions []Pair) (string, error) {
+	if prompt == "" {
+		prompt = "Input"
+	}
+
+	output, err := runRofi([]string{"-dmenu", "-i", "-format", "s", "-p", prompt}, suggestions)
+	if err != nil {
+		return "", err
+	}
+
+	// Map a picked suggestion back to its value
+	for _, item := range suggestions {
+		if item.Label == output {
+			return item.Value, nil
+		}
+	}
+
+	return output, nil
+}
+
+// runRofi runs rofi with the given arguments and returns the trimmed output.
+// A dismissed rofi prompt returns ErrCancelled.
+func runRofi(args []string, items []Pair) (string, error) {
+	var input strings.Builder
+	for _, item := range items {
+		input.WriteString(strings.ReplaceAll(item.Label, "\n", " "))
+		switch {
+		case item.Image != "" && item.Disabled:
+			input.WriteString("\x00icon\x1f" + item.Image + "\x1fnonselectable\x1ftrue")
+		case item.Image != "":
+			input.WriteString("\x00icon\x1f" + item.Image)
+		case item.Disabled:
+			input.WriteString("\x00nonselectable\x1ftrue")
+		}
+		input.WriteString("\n")
+	}
+
+	cmd := exec.Command("rofi", args...)
+	cmd.Stdin = strings.NewReader(input.String())
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return "", ErrCancelled
+		}
+		return "", fmt.Errorf("failed to run rofi: %w", err)
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
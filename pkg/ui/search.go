@@ -0,0 +1,49 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrSearchCancelled is returned when the user cancels the search prompt
+	ErrSearchCancelled = fmt.Errorf("search %w", ErrCancelled)
+	// ErrEmptyQuery is returned for a search query without any text
+	ErrEmptyQuery = errors.New("search query is empty")
+)
+
+// ShowSearchPrompt asks the user for a search query, offering recent
+// queries as quick picks. Empty queries are rejected and the prompt is
+// shown again.
+func ShowSearchPrompt(history []string) (string, error) {
+	suggestions := make([]Pair, len(history))
+	for i, query := range history {
+		suggestions[i] = Pair{Label: query, Value: query}
+	}
+
+	for {
+		input, err := ShowTextInput("Search anime", UserInput, suggestions)
+		if errors.Is(err, ErrCancelled) {
+			return "", ErrSearchCancelled
+		}
+		if err != nil {
+			return "", err
+		}
+
+		query, err := validateSearchQuery(input)
+		if errors.Is(err, ErrEmptyQuery) {
+			continue
+		}
+		return query, err
+	}
+}
+
+// validateSearchQuery trims the query and rejects it if nothing is left
+func validateSearchQuery(input string) (string, error) {
+	query := strings.TrimSpace(input)
+	if query == "" {
+		return "", ErrEmptyQuery
+	}
+	return query, nil
+}
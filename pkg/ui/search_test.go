@@ -0,0 +1,30 @@
+package ui
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateSearchQuery(t *testing.T) {
+	// Test rejecting empty and whitespace-only queries
+	for _, input := range []string{"", "   ", "\t\n"} {
+		if _, err := validateSearchQuery(input); !errors.Is(err, ErrEmptyQuery) {
+			t.Errorf("Expected ErrEmptyQuery for %q, got %v", input, err)
+		}
+	}
+
+	// Test trimming a valid query
+	query, err := validateSearchQuery("  cowboy bebop \n")
+	if err != nil {
+		t.Fatalf("Failed to validate query: %v", err)
+	}
+	if query != "cowboy bebop" {
+		t.Errorf("Expected query 'cowboy bebop', got '%s'", query)
+	}
+}
+
+func TestSearchCancelledIsCancelled(t *testing.T) {
+	if !errors.Is(ErrSearchCancelled, ErrCancelled) {
+		t.Errorf("Expected ErrSearchCancelled to match ErrCancelled")
+	}
+}
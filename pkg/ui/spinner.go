@@ -0,0 +1,57 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// spinnerFrames are the animation frames of the spinner
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// ShowSpinner shows an animated spinner with a message on stderr.
+// The returned stop function clears the spinner and waits for it to exit;
+// it is safe to call more than once.
+func ShowSpinner(ctx context.Context, message string) (stop func()) {
+	stop, _ = startSpinner(ctx, os.Stderr, message, 100*time.Millisecond)
+	return stop
+}
+
+// startSpinner runs the spinner animation on w until stopped or ctx is done.
+// The returned channel is closed once the animation goroutine has exited.
+func startSpinner(ctx context.Context, w io.Writer, message string, interval time.Duration) (func(), <-chan struct{}) {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for frame := 0; ; frame++ {
+			fmt.Fprintf(w, "\r%s %s", spinnerFrames[frame%len(spinnerFrames)], message)
+
+			select {
+			case <-ctx.Done():
+				// Clear the spinner line so following output starts clean
+				fmt.Fprint(w, "\r\033[K")
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			cancel()
+			<-done
+		})
+	}
+
+	return stop, done
+}
@@ -0,0 +1,67 @@
+package ui
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a bytes.Buffer that is safe for concurrent use
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestSpinnerStop(t *testing.T) {
+	var out syncBuffer
+	stop, done := startSpinner(context.Background(), &out, "Syncing with Anilist…", time.Millisecond)
+
+	time.Sleep(10 * time.Millisecond)
+	stop()
+
+	// Test that the goroutine has exited once stop returns
+	select {
+	case <-done:
+	default:
+		t.Fatalf("Expected spinner goroutine to exit after stop")
+	}
+
+	if !strings.Contains(out.String(), "Syncing with Anilist…") {
+		t.Errorf("Expected spinner output to contain the message, got %q", out.String())
+	}
+	if !strings.HasSuffix(out.String(), "\r\033[K") {
+		t.Errorf("Expected spinner to clear its line on stop, got %q", out.String())
+	}
+
+	// Test that calling stop again is harmless
+	stop()
+}
+
+func TestSpinnerContextCancel(t *testing.T) {
+	var out syncBuffer
+	ctx, cancel := context.WithCancel(context.Background())
+	_, done := startSpinner(ctx, &out, "Syncing", time.Millisecond)
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("Expected spinner goroutine to exit after context cancel")
+	}
+}
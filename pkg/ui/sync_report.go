@@ -0,0 +1,194 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/wraient/pair/pkg/database"
+	"github.com/wraient/pair/pkg/tracker"
+)
+
+// ShowSyncReport displays a summary of a sync run per tracker, followed by
+// any errors that occurred grouped by tracker
+func ShowSyncReport(stats map[string]tracker.SyncStats, errs []tracker.SyncError) error {
+	if _, err := OpenMenu(List, syncReportItems(stats, errs)); err != nil {
+		return fmt.Errorf("menu error: %w", err)
+	}
+	return nil
+}
+
+// syncReportItems builds the report lines, one per tracker in name order,
+// a total across all trackers and one line per error, with the errors of
+// each tracker together
+func syncReportItems(stats map[string]tracker.SyncStats, errs []tracker.SyncError) []Pair {
+	names := make([]string, 0, len(stats))
+	for name := range stats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	items := make([]Pair, 0, len(names)+len(errs)+2)
+	var total tracker.SyncStats
+	for _, name := range names {
+		s := stats[name]
+		items = append(items, Pair{Label: name + ": " + syncStatsLabel(s), Value: name})
+
+		total.Added += s.Added
+		total.Updated += s.Updated
+		total.Deleted += s.Deleted
+		total.Skipped += s.Skipped
+		total.Errors += s.Errors
+	}
+	items = append(items, Pair{Label: "Total: " + syncStatsLabel(total), Value: "total"})
+
+	errs = append([]tracker.SyncError(nil), errs...)
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Tracker < errs[j].Tracker })
+	for i, err := range errs {
+		items = append(items, Pair{Label: "Error: " + err.Error(), Value: fmt.Sprintf("error-%d", i)})
+	}
+
+	items = append(items, Pair{Label: "Close", Value: "close"})
+	return items
+}
+
+// syncStatsLabel formats the counters of a single sync result
+func syncStatsLabel(s tracker.SyncStats) string {
+	return fmt.Sprintf("%d added, %d updated, %d deleted, %d skipped, %d errors",
+		s.Added, s.Updated, s.Deleted, s.Skipped, s.Errors)
+}
+
+// lastSyncLabel describes when a tracker last synced relative to now, as in
+// "anilist: last synced 3 hours ago", marked stale when that was longer ago
+// than interval
+func lastSyncLabel(name string, last time.Time, interval time.Duration, now time.Time) string {
+	if last.IsZero() {
+		return name + ": never synced"
+	}
+	label := name + ": last synced " + relativeTime(now.Sub(last))
+	if interval > 0 && now.Sub(last) > interval {
+		label += " (stale)"
+	}
+	return label
+}
+
+// relativeTime describes how long ago something happened in the largest
+// whole unit, from "just now" to days
+func relativeTime(d time.Duration) string {
+	plural := func(n int, unit string) string {
+		if n == 1 {
+			return fmt.Sprintf("1 %s ago", unit)
+		}
+		return fmt.Sprintf("%d %ss ago", n, unit)
+	}
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return plural(int(d/time.Minute), "minute")
+	case d < 24*time.Hour:
+		return plural(int(d/time.Hour), "hour")
+	default:
+		return plural(int(d/(24*time.Hour)), "day")
+	}
+}
+
+// Actions returned by ShowSyncStatus
+const (
+	SyncNow    = "sync_now"
+	CancelSync = "cancel_sync"
+)
+
+// ShowSyncStatus shows the state of the background sync and the result of
+// the last one. It returns SyncNow or CancelSync when the user picks that
+// action, or "" when they close the view.
+func ShowSyncStatus(status tracker.SyncStatus) (string, error) {
+	choice, err := OpenMenu(List, syncStatusItems(status))
+	if err != nil {
+		return "", fmt.Errorf("menu error: %w", err)
+	}
+	if choice != SyncNow && choice != CancelSync {
+		return "", nil
+	}
+	return choice, nil
+}
+
+// syncStatusItems builds the status lines followed by the actions that apply
+func syncStatusItems(status tracker.SyncStatus) []Pair {
+	var items []Pair
+	if status.State == tracker.SyncRunning {
+		items = append(items, Pair{Label: "Syncing now…", Value: "state"})
+	}
+	names := make([]string, 0, len(status.LastSynced))
+	for name := range status.LastSynced {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	now := time.Now()
+	for _, name := range names {
+		label := lastSyncLabel(name, status.LastSynced[name], status.Interval, now)
+		items = append(items, Pair{Label: label, Value: "last_sync_" + name})
+	}
+
+	if status.LastRun.IsZero() {
+		items = append(items, Pair{Label: "No sync has finished yet", Value: "last_run"})
+	} else {
+		items = append(items, Pair{Label: "Last sync: " + status.LastRun.Format("2006-01-02 15:04"), Value: "last_run"})
+
+		names := make([]string, 0, len(status.LastStats))
+		for name := range status.LastStats {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			items = append(items, Pair{Label: name + ": " + syncStatsLabel(status.LastStats[name]), Value: name})
+		}
+		for i, message := range status.LastErrors {
+			items = append(items, Pair{Label: "Error: " + message, Value: fmt.Sprintf("error-%d", i)})
+		}
+	}
+
+	if status.State == tracker.SyncRunning {
+		items = append(items, Pair{Label: "Cancel sync", Value: CancelSync})
+	} else {
+		items = append(items, Pair{Label: "Sync now", Value: SyncNow})
+	}
+	items = append(items, Pair{Label: "Close", Value: "close"})
+	return items
+}
+
+// ClearConflicts is returned by ShowSyncConflicts when the user clears the log
+const ClearConflicts = "clear_conflicts"
+
+// ShowSyncConflicts lists the values syncs overwrote, newest first. It
+// returns ClearConflicts when the user chooses to clear the log, or "" when
+// they close the view.
+func ShowSyncConflicts(conflicts []*database.SyncConflict) (string, error) {
+	choice, err := OpenMenu(List, syncConflictItems(conflicts))
+	if err != nil {
+		return "", fmt.Errorf("menu error: %w", err)
+	}
+	if choice != ClearConflicts {
+		return "", nil
+	}
+	return choice, nil
+}
+
+// syncConflictItems builds one line per conflict followed by the actions
+func syncConflictItems(conflicts []*database.SyncConflict) []Pair {
+	items := make([]Pair, 0, len(conflicts)+2)
+	for _, c := range conflicts {
+		label := fmt.Sprintf("%s %s (%s) %s: local %s, remote %s, kept %s",
+			c.CreatedAt.Local().Format("2006-01-02 15:04"), c.AnimeTitle, c.Tracker,
+			c.Field, c.LocalValue, c.RemoteValue, c.Winner)
+		items = append(items, Pair{Label: label, Value: fmt.Sprintf("conflict-%d", c.ID)})
+	}
+
+	if len(conflicts) == 0 {
+		items = append(items, Pair{Label: "No sync conflicts", Value: "empty"})
+	} else {
+		items = append(items, Pair{Label: "Clear log", Value: ClearConflicts})
+	}
+	items = append(items, Pair{Label: "Close", Value: "close"})
+	return items
+}
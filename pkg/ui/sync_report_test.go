@@ -0,0 +1,75 @@
+package ui
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/wraient/pair/pkg/tracker"
+)
+
+func TestSyncReportItems(t *testing.T) {
+	stats := map[string]tracker.SyncStats{
+		"MyAnimeList": {Added: 1, Updated: 2, Skipped: 4},
+		"AniList":     {Added: 3, Deleted: 1, Skipped: 2, Errors: 1},
+	}
+	errs := []tracker.SyncError{
+		{Tracker: "mal", Title: "Frieren", Op: "process remote entry", Err: errors.New("bad request")},
+		{Tracker: "anilist", Op: "sync", Err: errors.New("timeout")},
+	}
+
+	items := syncReportItems(stats, errs)
+
+	// Test that trackers are listed in name order, then the total, the
+	// errors of each tracker and close
+	expected := []string{
+		"AniList: 3 added, 0 updated, 1 deleted, 2 skipped, 1 errors",
+		"MyAnimeList: 1 added, 2 updated, 0 deleted, 4 skipped, 0 errors",
+		"Total: 4 added, 2 updated, 1 deleted, 6 skipped, 1 errors",
+		"Error: anilist: failed to sync: timeout",
+		"Error: mal: failed to process remote entry Frieren: bad request",
+		"Close",
+	}
+	if len(items) != len(expected) {
+		t.Fatalf("Expected %d items, got %d", len(expected), len(items))
+	}
+	for i, label := range expected {
+		if items[i].Label != label {
+			t.Errorf("Expected item %d to be '%s', got '%s'", i, label, items[i].Label)
+		}
+	}
+}
+
+func TestLastSyncLabel(t *testing.T) {
+	now, err := time.Parse(time.RFC3339, "2024-05-01T12:00:00Z")
+	if err != nil {
+		t.Fatalf("Failed to parse time: %v", err)
+	}
+
+	// Test that stored sync times fall into the expected buckets
+	tests := []struct {
+		stored   string
+		expected string
+	}{
+		{"2024-05-01T11:59:30Z", "anilist: last synced just now"},
+		{"2024-05-01T11:59:00Z", "anilist: last synced 1 minute ago"},
+		{"2024-05-01T11:15:00Z", "anilist: last synced 45 minutes ago"},
+		{"2024-05-01T09:00:00Z", "anilist: last synced 3 hours ago (stale)"},
+		{"2024-04-28T10:00:00Z", "anilist: last synced 3 days ago (stale)"},
+		{"2024-05-01T14:00:00+02:00", "anilist: last synced just now"},
+	}
+	for _, test := range tests {
+		last, err := time.Parse(time.RFC3339, test.stored)
+		if err != nil {
+			t.Fatalf("Failed to parse %s: %v", test.stored, err)
+		}
+		if label := lastSyncLabel("anilist", last, time.Hour, now); label != test.expected {
+			t.Errorf("Expected '%s' for %s, got '%s'", test.expected, test.stored, label)
+		}
+	}
+
+	// Test that a tracker that never synced says so
+	if label := lastSyncLabel("mal", time.Time{}, time.Hour, now); label != "mal: never synced" {
+		t.Errorf("Expected 'mal: never synced', got '%s'", label)
+	}
+}
@@ -0,0 +1,111 @@
+package ui
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/wraient/pair/pkg/tracker"
+)
+
+var (
+	// lineBreakTag matches <br>, <br/> and <br /> with the newline that
+	// usually follows them in Anilist descriptions
+	lineBreakTag = regexp.MustCompile(`(?i)<br\s*/?>\n?`)
+	// htmlTag matches opening and closing tags but not text like "<3"
+	htmlTag = regexp.MustCompile(`</?[a-zA-Z][a-zA-Z0-9]*(\s[^<>]*)?/?>`)
+	// spoiler matches Anilist's ~!hidden text!~
+	spoiler = regexp.MustCompile(`(?s)~!(.*?)!~`)
+	// centered matches Anilist's ~~~centered text~~~
+	centered = regexp.MustCompile(`(?s)~~~(.*?)~~~`)
+	// blankLines matches runs of more than one empty line
+	blankLines = regexp.MustCompile(`\n{3,}`)
+)
+
+// detailWidth is the width synopses are wrapped to
+const detailWidth = 80
+
+// CleanSynopsis turns an anime description with HTML tags and Anilist
+// markdown into plain text. Line breaks are kept, tags are removed but their
+// text is not, spoilers are marked rather than shown as raw markup and HTML
+// entities are decoded.
+func CleanSynopsis(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = lineBreakTag.ReplaceAllString(s, "\n")
+
+	s = spoiler.ReplaceAllString(s, "[spoiler: $1]")
+	s = centered.ReplaceAllString(s, "$1")
+	s = htmlTag.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	s = strings.Join(lines, "\n")
+
+	return strings.TrimSpace(blankLines.ReplaceAllString(s, "\n\n"))
+}
+
+// animeDetails describes an anime above its menu: the title, a line of
+// facts and the cleaned synopsis
+func animeDetails(anime *tracker.AnimeInfo) string {
+	var facts []string
+	if anime.Type != "" {
+		facts = append(facts, anime.Type)
+	}
+	if anime.Episodes > 0 {
+		facts = append(facts, fmt.Sprintf("%d episodes", anime.Episodes))
+	}
+	if anime.Year > 0 {
+		facts = append(facts, fmt.Sprint(anime.Year))
+	}
+
+	lines := []string{headerStyle.Render(anime.DisplayTitle(titleLanguage))}
+	if len(facts) > 0 {
+		lines = append(lines, footerStyle.Render(strings.Join(facts, " · ")))
+	}
+	if stats := animeStats(anime); len(stats) > 0 {
+		lines = append(lines, footerStyle.Render(strings.Join(stats, " · ")))
+	}
+	if synopsis := CleanSynopsis(anime.Synopsis); synopsis != "" {
+		lines = append(lines, "", baseStyle.Copy().Width(detailWidth).Render(synopsis))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// animeStats returns the rankings and audience of an anime that are known
+func animeStats(anime *tracker.AnimeInfo) []string {
+	var stats []string
+	if anime.Rank > 0 {
+		stats = append(stats, fmt.Sprintf("Ranked #%d", anime.Rank))
+	}
+	if anime.Popularity > 0 {
+		stats = append(stats, fmt.Sprintf("Popularity #%d", anime.Popularity))
+	}
+	if anime.MembersCount > 0 {
+		stats = append(stats, formatCount(anime.MembersCount)+" members")
+	}
+	if anime.Favourites > 0 {
+		stats = append(stats, formatCount(anime.Favourites)+" favourites")
+	}
+	return stats
+}
+
+// formatCount formats a count with thousands separators, as in 1,234,567
+func formatCount(n int) string {
+	if n < 0 {
+		return "-" + formatCount(-n)
+	}
+	digits := strconv.Itoa(n)
+	var b strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(digit)
+	}
+	return b.String()
+}
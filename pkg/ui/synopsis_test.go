@@ -0,0 +1,79 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wraient/pair/pkg/tracker"
+)
+
+func TestCleanSynopsis(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "line breaks",
+			input: "The hero fell.<br><br>\nFrieren lived on.<br>\n<br>\n(Source: Crunchyroll)",
+			want:  "The hero fell.\n\nFrieren lived on.\n\n(Source: Crunchyroll)",
+		},
+		{
+			name:  "inline tags",
+			input: "Based on the manga <i>Sousou no Frieren</i> by <b>Kanehito Yamada</b>.<br/>",
+			want:  "Based on the manga Sousou no Frieren by Kanehito Yamada.",
+		},
+		{
+			name:  "spoilers",
+			input: "Himmel is the hero. ~!Himmel dies of old age.!~ The journey begins.",
+			want:  "Himmel is the hero. [spoiler: Himmel dies of old age.] The journey begins.",
+		},
+		{
+			name:  "entities and links",
+			input: "Tom &amp; Jerry&#039;s <a href=\"https://anilist.co\">story</a>",
+			want:  "Tom & Jerry's story",
+		},
+		{
+			name:  "text that looks like markup",
+			input: "A story of love <3 and x < y > z",
+			want:  "A story of love <3 and x < y > z",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := CleanSynopsis(tt.input); got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestAnimeDetails(t *testing.T) {
+	anime := &tracker.AnimeInfo{Title: "Frieren", Type: "TV", Episodes: 28, Year: 2023, Synopsis: "<i>A long journey.</i>"}
+
+	// Test that the details show the facts and the cleaned synopsis
+	details := animeDetails(anime)
+	for _, want := range []string{"Frieren", "TV · 28 episodes · 2023", "A long journey."} {
+		if !strings.Contains(details, want) {
+			t.Errorf("Expected '%s' in details, got:\n%s", want, details)
+		}
+	}
+	if strings.Contains(details, "<i>") {
+		t.Errorf("Expected no tags in details, got:\n%s", details)
+	}
+}
+
+func TestAnimeStats(t *testing.T) {
+	anime := &tracker.AnimeInfo{Title: "Frieren", Rank: 1, Popularity: 150, MembersCount: 1234567, Favourites: 980}
+
+	// Test that known rankings are shown with readable counts
+	details := animeDetails(anime)
+	want := "Ranked #1 · Popularity #150 · 1,234,567 members · 980 favourites"
+	if !strings.Contains(details, want) {
+		t.Errorf("Expected '%s' in details, got:\n%s", want, details)
+	}
+
+	// Test that unknown rankings are left out
+	if stats := animeStats(&tracker.AnimeInfo{MembersCount: 1000}); len(stats) != 1 || stats[0] != "1,000 members" {
+		t.Errorf("Expected only the members count, got %v", stats)
+	}
+}
@@ -0,0 +1,28 @@
+// Package version reports which build of pair is running
+package version
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// Version is the release version, set at build time with
+// -ldflags "-X github.com/wraient/pair/pkg/version.Version=v1.2.3"
+var Version = "dev"
+
+// String describes the build: the version followed by the commit and Go
+// version when the binary carries build information
+func String() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return Version
+	}
+
+	s := Version
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" && len(setting.Value) >= 12 {
+			s += " (" + setting.Value[:12] + ")"
+		}
+	}
+	return fmt.Sprintf("%s, %s", s, info.GoVersion)
+}